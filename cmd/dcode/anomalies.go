@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/debug"
+	"github.com/takahirom/dialog-code/internal/events"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// anomalies counts what anomalies in the config file warn about
+type anomalies struct {
+	prompts    map[string][]time.Time // When each request was asked recently, by request
+	retries    []time.Time            // When answers recently failed or were sent again
+	suppressed int                    // Prompts skipped as repeats since one was handled
+	warned     map[string]time.Time   // When each anomaly was last warned about
+	mutex      sync.Mutex
+}
+
+// recent returns the times in times that are within window of now
+func recent(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	kept := times[:0]
+	for _, t := range times {
+		if now.Sub(t) < window {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// countPrompt counts the request described by info being asked, ending a
+// run of skipped prompts, and warns when it was asked more than
+// anomalies.repeated_prompts times recently
+func (p *PermissionHandler) countPrompt(info parser.DialogInfo) {
+	limit, window := p.config().Anomalies.RepeatedPrompts, p.config().Anomalies.Window()
+	request := strings.Join(strings.Fields(describeRequest(info)), " ")
+	now := p.now()
+
+	p.anomalies.mutex.Lock()
+	p.anomalies.suppressed = 0
+	if limit == 0 || request == "" {
+		p.anomalies.mutex.Unlock()
+		return
+	}
+	if p.anomalies.prompts == nil {
+		p.anomalies.prompts = map[string][]time.Time{}
+	}
+	for key, times := range p.anomalies.prompts {
+		if times = recent(times, now, window); len(times) == 0 {
+			delete(p.anomalies.prompts, key)
+		} else {
+			p.anomalies.prompts[key] = times
+		}
+	}
+	p.anomalies.prompts[request] = append(p.anomalies.prompts[request], now)
+	count := len(p.anomalies.prompts[request])
+	p.anomalies.mutex.Unlock()
+
+	if count > limit {
+		if p.redactor != nil {
+			request = p.redactor.Redact(request)
+		}
+		p.warnAnomaly(events.AnomalyRepeatedPrompt, request, count, fmt.Sprintf("%s was asked %d times in %s. Claude may be stuck retrying it.", request, count, window))
+	}
+}
+
+// countAnswerRetry counts an answer that failed to be typed into Claude or
+// was sent again, warning when there were more than anomalies.answer_retries
+// recently
+func (p *PermissionHandler) countAnswerRetry() {
+	limit, window := p.config().Anomalies.AnswerRetries, p.config().Anomalies.Window()
+	if limit == 0 {
+		return
+	}
+	now := p.now()
+
+	p.anomalies.mutex.Lock()
+	p.anomalies.retries = append(recent(p.anomalies.retries, now, window), now)
+	count := len(p.anomalies.retries)
+	p.anomalies.mutex.Unlock()
+
+	if count > limit {
+		p.warnAnomaly(events.AnomalyAnswerRetries, "", count, fmt.Sprintf("%d answers failed or were sent again in %s. Check that Claude is getting them, and the debug log for why.", count, window))
+	}
+}
+
+// countSuppressed counts a prompt skipped as a repeat, warning when more than
+// anomalies.suppressed_prompts were skipped in a row
+func (p *PermissionHandler) countSuppressed() {
+	limit := p.config().Anomalies.SuppressedPrompts
+	if limit == 0 {
+		return
+	}
+
+	p.anomalies.mutex.Lock()
+	p.anomalies.suppressed++
+	count := p.anomalies.suppressed
+	p.anomalies.mutex.Unlock()
+
+	if count > limit {
+		p.warnAnomaly(events.AnomalySuppressedPrompts, "", count, fmt.Sprintf("%d prompts in a row were skipped as repeats. If dialogs stopped appearing, run dcode debug dedup.", count))
+	}
+}
+
+// warnAnomaly warns about the anomaly kind, about subject if it concerns one
+// request, in the debug log, the event stream, and a notification, unless it
+// was warned about within anomalies.window_minutes
+func (p *PermissionHandler) warnAnomaly(kind, subject string, count int, message string) {
+	now := p.now()
+	key := kind + "|" + subject
+	p.anomalies.mutex.Lock()
+	if last, warned := p.anomalies.warned[key]; warned && now.Sub(last) < p.config().Anomalies.Window() {
+		p.anomalies.mutex.Unlock()
+		return
+	}
+	if p.anomalies.warned == nil {
+		p.anomalies.warned = map[string]time.Time{}
+	}
+	p.anomalies.warned[key] = now
+	p.anomalies.mutex.Unlock()
+
+	debug.Warn("anomaly", "anomaly", kind, "count", count, "message", message)
+	p.emit(events.Event{Type: events.Anomaly, Anomaly: kind, Count: count, Message: message})
+	if p.config().Anomalies.Notify && p.notificationCallback != nil {
+		go p.notificationCallback("dcode warning: " + message)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+// anomalyEvents returns the anomaly events written to recorder
+func anomalyEvents(t *testing.T, recorder *eventRecorder) []events.Event {
+	var anomalies []events.Event
+	for _, event := range recorder.Events(t) {
+		if event.Type == events.Anomaly {
+			anomalies = append(anomalies, event)
+		}
+	}
+	return anomalies
+}
+
+func TestRepeatedPromptWarns(t *testing.T) {
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.Anomalies.RepeatedPrompts = 2
+			cfg.Delays.DialogResetMs = 0
+		})
+	retryInARow(robot, 0, "make deploy", "make deploy", "make deploy", "make deploy")
+
+	anomalies := anomalyEvents(t, recorder)
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected one warning until the window passes, got %+v", anomalies)
+	}
+	if warning := anomalies[0]; warning.Anomaly != events.AnomalyRepeatedPrompt || warning.Count != 3 || !strings.Contains(warning.Message, "Bash: make deploy was asked 3 times") {
+		t.Errorf("Expected a warning about the repeated request, got %+v", warning)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); !strings.HasPrefix(notification, "dcode warning: Bash: make deploy was asked") {
+		t.Errorf("Expected a notification of the warning, got %q", notification)
+	}
+
+	retryInARow(robot, 30, "make deploy", "make deploy", "make deploy")
+	if anomalies := anomalyEvents(t, recorder); len(anomalies) != 2 {
+		t.Errorf("Expected another warning once the window passed, got %+v", anomalies)
+	}
+}
+
+func TestRepeatedPromptWarningOff(t *testing.T) {
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.Anomalies.RepeatedPrompts = 0
+			cfg.Delays.DialogResetMs = 0
+		})
+	retryInARow(robot, 0, "make deploy", "make deploy", "make deploy", "make deploy", "make deploy", "make deploy", "make deploy")
+
+	if anomalies := anomalyEvents(t, recorder); len(anomalies) != 0 {
+		t.Errorf("Expected no warning, got %+v", anomalies)
+	}
+}
+
+func TestAnswerRetriesWarn(t *testing.T) {
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.Anomalies.AnswerRetries = 1
+			cfg.Anomalies.Notify = false
+		}).
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(100 * time.Millisecond)
+
+	robot.app.handler.sendAnswer(robot.app.handler.snapshotPrompt(""), "1")
+	if anomalies := anomalyEvents(t, recorder); len(anomalies) != 0 {
+		t.Fatalf("Expected no warning for one retry, got %+v", anomalies)
+	}
+	robot.app.handler.sendAnswer(robot.app.handler.snapshotPrompt(""), "1")
+	anomalies := anomalyEvents(t, recorder)
+	if len(anomalies) != 1 || anomalies[0].Anomaly != events.AnomalyAnswerRetries || anomalies[0].Count != 2 {
+		t.Errorf("Expected a warning about the retries, got %+v", anomalies)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Errorf("Expected no notification with anomalies.notify off, got %q", notification)
+	}
+}
+
+func TestSuppressedPromptsWarn(t *testing.T) {
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.Anomalies.SuppressedPrompts = 2
+		}).
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertDialogCaptured()
+	for range 3 {
+		robot.ReceiveClaudeText("╭───────────────────────────────────────╮", "│ Do you want to proceed?               │")
+	}
+
+	anomalies := anomalyEvents(t, recorder)
+	if len(anomalies) != 1 || anomalies[0].Anomaly != events.AnomalySuppressedPrompts || !strings.Contains(anomalies[0].Message, "dcode debug dedup") {
+		t.Errorf("Expected a warning about the skipped prompts, got %+v", anomalies)
+	}
+}
@@ -1,43 +1,134 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	runtimedebug "runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/audit"
 	"github.com/takahirom/dialog-code/internal/choice"
+	"github.com/takahirom/dialog-code/internal/claudesettings"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/crash"
+	"github.com/takahirom/dialog-code/internal/debug"
+	"github.com/takahirom/dialog-code/internal/decisions"
+	"github.com/takahirom/dialog-code/internal/deduplication"
 	"github.com/takahirom/dialog-code/internal/dialog"
+	"github.com/takahirom/dialog-code/internal/events"
+	"github.com/takahirom/dialog-code/internal/gitinfo"
+	"github.com/takahirom/dialog-code/internal/i18n"
+	"github.com/takahirom/dialog-code/internal/redact"
+	"github.com/takahirom/dialog-code/internal/state"
+	"github.com/takahirom/dialog-code/internal/systemlog"
+	"github.com/takahirom/dialog-code/internal/telemetry"
+	"github.com/takahirom/dialog-code/internal/tracing"
+	"github.com/takahirom/dialog-code/internal/transcript"
 	"github.com/takahirom/dialog-code/internal/types"
+	"github.com/takahirom/dialog-code/internal/version"
+	"github.com/takahirom/dialog-code/pkg/parser"
 )
 
 // Constants for configuration
 const (
-	PTYBufferSize     = 1024 // Buffer size for PTY reading
-	ContextBufferSize = 50   // Buffer size for context lines
-	SubmitKey         = "\r" // Key sequence for submitting terminal input
+	PTYBufferSize       = 1024 // Buffer size for PTY reading
+	ContextBufferSize   = 50   // Buffer size for context lines
+	MaxDialogBoxLines   = 1000 // Context limit while a dialog box is open, in case its bottom border never arrives
+	DisplayBufferChunks = 256  // Pending PTY reads buffered for the display writer
+	SubmitKey           = "\r" // Key sequence for submitting terminal input
 )
 
 // PermissionCallback defines the callback for permission requests
 type PermissionCallback func(message string, buttons []string, defaultButton string) string
 
+// TitledPermissionCallback is a PermissionCallback that also sets the
+// dialog's title
+type TitledPermissionCallback func(title, message string, buttons []string, defaultButton string) string
+
+// TextInputCallback asks the user to type some text, returning false if they cancelled
+type TextInputCallback func(message string) (string, bool)
+
+// NotificationCallback posts a notification without waiting for the user
+type NotificationCallback func(message string)
+
+// RemoteCallback asks someone away from this machine through the backend in
+// remote, returning the number of the button picked or an error if nobody
+// answered within remote.TimeoutSeconds
+type RemoteCallback func(remote config.Remote, message string, buttons []string) (string, error)
+
+// EscalationCallback alerts the user about a dialog left unanswered through
+// the channels in cfg.Escalation other than a notification: a sound and ntfy
+type EscalationCallback func(cfg *config.Config, message string) error
+
+// TypedConfirmationPhrase must be typed to approve a dialog whose risk policy is "confirm"
+const TypedConfirmationPhrase = "approve"
+
+// TemporaryApprovalButtonFormat labels the dialog button that approves similar
+// requests for temporary_approval_minutes, in English
+var TemporaryApprovalButtonFormat = englishText.TemporaryApprovalButton
+
+// NeverAllowButton labels the dialog button that rejects the request and adds
+// it to the deny list in Claude's settings, with sync_settings, in English
+var NeverAllowButton = englishText.NeverAllowButton
+
+// AutoApprovalSummaryLines is how many of the latest approvals the dialog
+// shown at --max-auto-approvals lists
+const AutoApprovalSummaryLines = 10
+
+// DigestLines is how many of the latest decisions a --digest-minutes
+// notification lists
+const DigestLines = 10
+
+// AskSomeoneElseButton labels the dialog button that forwards the request to
+// the remote approval service, with remote.delegate, in English
+var AskSomeoneElseButton = englishText.AskSomeoneElseButton
+
+// DelegatedMessagePrefix starts the remote message for a request forwarded
+// with the AskSomeoneElseButton, in English
+var DelegatedMessagePrefix = englishText.DelegatedPrefix
+
+// Buttons of the dialog that offers to remember an answer as a rule, with
+// --remember-decisions, in English
+var (
+	RememberButton = englishText.RememberButton
+	NotNowButton   = englishText.NotNowButton
+)
+
+// decider names what answered a dialog without asking the user
+type decider struct {
+	rule   string // e.g. "deny rule 2" or "quiet_hours"; {rule} in reject_message
+	detail string // What the rule matched, such as its fields, for the decision log
+}
+
 // App represents the main application
 type App struct {
 	ptmx               *os.File
 	handler            *PermissionHandler
 	displayWriter      io.Writer
 	permissionCallback PermissionCallback
+	config             *config.Config
+	mode               *config.Mode // Set with SetMode; overrides the auto modes of every reloaded config
+	configMutex        sync.Mutex
 }
 
-// NewApp creates a new App instance
-func NewApp(ptmx *os.File, displayWriter io.Writer) *App {
+// NewApp creates a new App instance with the options in cfg
+func NewApp(ptmx *os.File, displayWriter io.Writer, cfg *config.Config) *App {
 	app := &App{
 		ptmx:          ptmx,
 		displayWriter: displayWriter,
+		config:        cfg,
 	}
-	app.handler = NewPermissionHandler(ptmx, app.requestPermission)
+	app.handler = NewPermissionHandler(ptmx, cfg, app.requestPermission)
 	return app
 }
 
@@ -48,6 +139,206 @@ func (a *App) SetPermissionCallback(callback PermissionCallback) {
 	a.handler.permissionCallback = callback
 }
 
+// SetTitledPermissionCallback sets the callback for permission requests,
+// titled as the dialog option says for each request's risk
+func (a *App) SetTitledPermissionCallback(callback TitledPermissionCallback) {
+	a.SetPermissionCallback(func(message string, buttons []string, defaultButton string) string {
+		return callback(a.config.Dialog.Title, message, buttons, defaultButton)
+	})
+	a.handler.titledCallback = callback
+}
+
+// SetTextInputCallback sets the callback used to ask for a typed confirmation
+func (a *App) SetTextInputCallback(callback TextInputCallback) {
+	a.handler.textInputCallback = callback
+}
+
+// SetNotificationCallback sets the callback used to tell the user about
+// requests rejected during quiet hours
+func (a *App) SetNotificationCallback(callback NotificationCallback) {
+	a.handler.notificationCallback = callback
+}
+
+// SetRemoteCallback sets the callback used for requests the risk policy
+// sends to the remote backend
+func (a *App) SetRemoteCallback(callback RemoteCallback) {
+	a.handler.remoteCallback = callback
+}
+
+// SetEscalationCallback sets the callback that plays a sound or pushes to
+// ntfy for a dialog left unanswered past escalation.after_seconds
+func (a *App) SetEscalationCallback(callback EscalationCallback) {
+	a.handler.escalationCallback = callback
+}
+
+// SetApprovalCache sets where approvals are remembered for --approval-cache-seconds
+func (a *App) SetApprovalCache(cache *approvals.Cache) {
+	a.handler.approvalCache = cache
+}
+
+// SetDecisionLog sets where requests answered without asking are logged for
+// "dcode explain"
+func (a *App) SetDecisionLog(log *decisions.Log) {
+	a.handler.decisionLog = log
+}
+
+// SetAuditLog sets where every answered prompt is recorded for --audit-log
+func (a *App) SetAuditLog(log *audit.Log) {
+	a.handler.auditLog = log
+}
+
+// SetTraceExporter sets where a trace of the steps of answering each prompt
+// is sent, for --otlp-endpoint
+func (a *App) SetTraceExporter(exporter *tracing.Exporter) {
+	a.handler.tracer = exporter
+}
+
+// SetSystemLog sets where every answered prompt is mirrored for --syslog
+func (a *App) SetSystemLog(logger *systemlog.Logger) {
+	a.handler.systemLog = logger
+}
+
+// SetEventStream sets where lifecycle events of each prompt are written for
+// --event-stream
+func (a *App) SetEventStream(stream *events.Stream) {
+	a.handler.eventStream = stream
+}
+
+// SetLiveEvents sets where lifecycle events of each prompt are published for
+// dcode tail
+func (a *App) SetLiveEvents(stream *events.Stream) {
+	a.handler.liveEvents = stream
+}
+
+// SetTranscript sets where Claude's output is saved with markers for each
+// prompt, for --transcript
+func (a *App) SetTranscript(t *transcript.Transcript) {
+	a.handler.transcript = t
+}
+
+// SetTelemetry sets the counters of how the session goes, for --telemetry
+func (a *App) SetTelemetry(counters *telemetry.Counters) {
+	a.handler.telemetry = counters
+}
+
+// SetScreenshots saves a screenshot of each dialog, taken with callback, to
+// dir, naming it in the answer's audit entry
+func (a *App) SetScreenshots(dir string, callback ScreenshotCallback) {
+	a.handler.screenshotDir = dir
+	a.handler.screenshotCallback = callback
+}
+
+// SetCrashDir sets the directory a report is written to if dcode crashes
+func (a *App) SetCrashDir(dir string) {
+	a.handler.crashDir = dir
+}
+
+// SetStateFile sets the file the session's mode, temporary approvals, and
+// counters are kept in, first restoring what an earlier dcode saved there,
+// which it returns
+func (a *App) SetStateFile(path string) (state.State, error) {
+	saved, err := state.Load(path, a.handler.now())
+	if err != nil {
+		a.handler.statePath = path
+		return state.State{}, err
+	}
+
+	if saved.Mode != "" {
+		if mode, err := config.ParseMode(saved.Mode); err == nil {
+			if err := a.SetMode(mode); err != nil {
+				debug.Warn("saved mode not restored", "mode", saved.Mode, "error", err)
+			}
+		}
+	}
+	a.handler.restoreState(saved)
+	a.handler.statePath = path
+	return saved, nil
+}
+
+// SetRulesFile sets the config file that --remember-decisions adds rules to
+func (a *App) SetRulesFile(path string) {
+	a.handler.rulesFile = path
+}
+
+// Reload replaces the app's options with cfg while it runs. Options that set
+// up the terminal, such as strip_colors, keep their values until a restart.
+func (a *App) Reload(cfg *config.Config) {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	a.reloadLocked(cfg)
+}
+
+// SetMode switches how dialogs no rule answers are handled for the rest of
+// the session, even when the config file is reloaded. It refuses a mode that
+// approves without asking while the organization policy isn't applied.
+func (a *App) SetMode(mode config.Mode) error {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if mode.Approves() && a.config.PolicyFailed {
+		return fmt.Errorf("refusing to switch to %s without the organization policy", mode)
+	}
+	a.mode = &mode
+	a.handler.autoPaused.Store(false)
+	cfg := *a.config
+	a.reloadLocked(&cfg)
+	a.handler.setSessionMode(mode.String())
+	return nil
+}
+
+// Panic rejects every request without asking, including a dialog waiting
+// for the user, until Resume
+func (a *App) Panic() {
+	a.handler.enterPanic()
+}
+
+// Resume goes back to handling requests as configured after Panic
+func (a *App) Resume() {
+	a.handler.leavePanic()
+}
+
+// DedupStats returns what the session remembers to tell repeated prompts
+// from new ones, with secrets in the prompts masked
+func (a *App) DedupStats() deduplication.Stats {
+	stats := a.handler.appState.Deduplicator.Snapshot()
+	if a.handler.redactor != nil {
+		for i := range stats.Prompts {
+			stats.Prompts[i].Key = a.handler.redactor.Redact(stats.Prompts[i].Key)
+		}
+		for i := range stats.Cooldowns {
+			stats.Cooldowns[i].Key = a.handler.redactor.Redact(stats.Cooldowns[i].Key)
+		}
+	}
+	return stats
+}
+
+// Panicked reports whether every request is being rejected after Panic
+func (a *App) Panicked() bool {
+	return a.handler.panicked.Load()
+}
+
+// Mode returns how dialogs no rule answers are currently handled
+func (a *App) Mode() config.Mode {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.handler.autoPaused.Load() {
+		return config.Mode{Name: config.ModeDialog}
+	}
+	return config.CurrentMode(a.config)
+}
+
+func (a *App) reloadLocked(cfg *config.Config) {
+	// A policy that stopped applying ends a mode that approves without asking
+	if a.mode != nil && a.mode.Approves() && cfg.PolicyFailed {
+		debug.Warn("left mode without the organization policy", "mode", a.mode.String())
+		a.mode = nil
+	}
+	if a.mode != nil {
+		a.mode.Apply(cfg)
+	}
+	a.config = cfg
+	a.handler.reload(cfg)
+}
+
 // requestPermission is the internal method that calls the external callback
 func (a *App) requestPermission(message string, buttons []string, defaultButton string) string {
 	if a.permissionCallback != nil {
@@ -65,20 +356,24 @@ func NewAppWithDialog(ptmx *os.File, displayWriter io.Writer, dialogInterface Di
 		return dialogInterface.Show(message, buttons, defaultButton)
 	}
 
+	cfg := defaultConfig()
 	app := &App{
 		ptmx:          ptmx,
-		handler:       NewPermissionHandler(ptmx, callback),
+		handler:       NewPermissionHandler(ptmx, cfg, callback),
 		displayWriter: displayWriter,
+		config:        cfg,
 	}
 	return app
 }
 
 // NewAppWithDialogAndTimeProvider creates a new App instance with custom dialog and time provider
 func NewAppWithDialogAndTimeProvider(ptmx *os.File, displayWriter io.Writer, dialogInterface DialogInterface, timeProvider TimeProvider) *App {
+	handler := NewPermissionHandlerWithDialogAndTimeProvider(ptmx, dialogInterface, timeProvider)
 	return &App{
 		ptmx:          ptmx,
-		handler:       NewPermissionHandlerWithDialogAndTimeProvider(ptmx, dialogInterface, timeProvider),
+		handler:       handler,
 		displayWriter: displayWriter,
+		config:        handler.config(),
 	}
 }
 
@@ -87,6 +382,32 @@ type DialogInterface interface {
 	Show(message string, buttons []string, defaultButton string) string
 }
 
+// TextInputDialog is implemented by dialogs that can also ask for text
+type TextInputDialog interface {
+	Prompt(message string) (string, bool)
+}
+
+// Notifier is implemented by dialogs that can also post notifications
+type Notifier interface {
+	Notify(message string)
+}
+
+// notificationCallbackFor returns the notifications of dialogInterface, or nil if it has none
+func notificationCallbackFor(dialogInterface DialogInterface) NotificationCallback {
+	if notifier, ok := dialogInterface.(Notifier); ok {
+		return notifier.Notify
+	}
+	return nil
+}
+
+// textInputCallbackFor returns the text prompt of dialogInterface, or nil if it has none
+func textInputCallbackFor(dialogInterface DialogInterface) TextInputCallback {
+	if textDialog, ok := dialogInterface.(TextInputDialog); ok {
+		return textDialog.Prompt
+	}
+	return nil
+}
+
 // TimeProvider defines the interface for time operations
 type TimeProvider interface {
 	Now() time.Time
@@ -125,14 +446,27 @@ func (t *FakeTimeProvider) SetTime(tm time.Time) {
 	t.FakeTime = tm
 }
 
-// FakeDialog implements DialogInterface for testing
+// Advance moves the fake time forward by d
+func (t *FakeTimeProvider) Advance(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.FakeTime = t.FakeTime.Add(d)
+}
+
+// FakeDialog implements DialogInterface, TextInputDialog, and Notifier for testing
 type FakeDialog struct {
-	mu              sync.RWMutex
-	CapturedMessage string
-	CapturedButtons []string
-	CapturedDefault string
-	ReturnChoice    string
-	TimeProvider    TimeProvider
+	mu                   sync.RWMutex
+	CapturedMessage      string
+	CapturedButtons      []string
+	CapturedDefault      string
+	CapturedPrompt       string
+	CapturedNotification string
+	ReturnChoice         string
+	ReturnChoices        []string      // Returned by the next dialogs, in order, before ReturnChoice
+	ReturnText           string        // Text typed into a text prompt
+	ReturnTextOK         bool          // Whether the text prompt was confirmed rather than cancelled
+	AnswerDelay          time.Duration // Fake time that passes before each dialog is answered
+	TimeProvider         TimeProvider
 }
 
 func (d *FakeDialog) Show(message string, buttons []string, defaultButton string) string {
@@ -142,10 +476,43 @@ func (d *FakeDialog) Show(message string, buttons []string, defaultButton string
 	copy(d.CapturedButtons, buttons)
 	d.CapturedDefault = defaultButton
 	returnChoice := d.ReturnChoice
+	if len(d.ReturnChoices) > 0 {
+		returnChoice, d.ReturnChoices = d.ReturnChoices[0], d.ReturnChoices[1:]
+	}
+	if fake, ok := d.TimeProvider.(*FakeTimeProvider); ok && d.AnswerDelay > 0 {
+		fake.Advance(d.AnswerDelay)
+	}
 	d.mu.Unlock()
 	return returnChoice
 }
 
+func (d *FakeDialog) Prompt(message string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.CapturedPrompt = message
+	return d.ReturnText, d.ReturnTextOK
+}
+
+func (d *FakeDialog) Notify(message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.CapturedNotification = message
+}
+
+// GetCapturedNotification returns the captured notification thread-safely
+func (d *FakeDialog) GetCapturedNotification() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.CapturedNotification
+}
+
+// GetCapturedPrompt returns the captured text prompt message thread-safely
+func (d *FakeDialog) GetCapturedPrompt() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.CapturedPrompt
+}
+
 // GetCapturedMessage returns the captured message thread-safely
 func (d *FakeDialog) GetCapturedMessage() string {
 	d.mu.RLock()
@@ -171,17 +538,280 @@ func (d *FakeDialog) GetCapturedDefault() string {
 }
 
 type PermissionHandler struct {
-	ptmx               *os.File
-	appState           *types.AppState
-	patterns           *types.RegexPatterns
-	contextLines       []string
-	waitingForInput    bool
-	timeProvider       TimeProvider
-	permissionCallback PermissionCallback
+	ptmx                 *os.File
+	appState             *types.AppState
+	options              atomic.Pointer[config.Config] // Read with config, since reload swaps it while prompts are answered
+	patterns             *types.RegexPatterns
+	redactor             *redact.Redactor // Masks secrets in dialog and notification text
+	contextLines         []string
+	drawnLines           []string // contextLines as drawn, with the colors that set a command's description apart
+	borders              parser.BorderNormalizer
+	boxDepth             int // Nesting depth of dialog boxes opened in contextLines
+	waitingForInput      bool
+	lineMutex            sync.Mutex  // Serializes processLine with the quiescence timer and reload
+	quiescenceTimer      *time.Timer // Finalizes a dialog whose bottom border never arrives
+	quiescenceRound      int         // Incremented whenever quiescenceTimer is replaced
+	timeProvider         TimeProvider
+	newRequestID         func() string                 // Makes the request ID of each prompt, or nil for audit.NewID
+	readGitInfo          func(dir string) gitinfo.Info // Reads the project's git context for the audit log, or nil for gitinfo.Read
+	permissionCallback   PermissionCallback
+	titledCallback       TitledPermissionCallback // Used instead of permissionCallback if set
+	textInputCallback    TextInputCallback
+	notificationCallback NotificationCallback
+	remoteCallback       RemoteCallback
+	escalationCallback   EscalationCallback
+	approvalCache        *approvals.Cache       // Approvals remembered for --approval-cache-seconds, or nil
+	temporaryApprovals   approvals.Temporary    // Granted with the "Approve for N minutes" button
+	rulesFile            string                 // Config file that remembered answers are added to, or ""
+	decisionLog          *decisions.Log         // Requests answered without asking, or nil
+	auditLog             *audit.Log             // Every answered prompt, with --audit-log, or nil
+	tracer               *tracing.Exporter      // Where each prompt's trace is sent, with --otlp-endpoint, or nil
+	systemLog            *systemlog.Logger      // Every answered prompt is mirrored here, with --syslog, or nil
+	responseTimes        responseTimes          // How long dialogs waited for the user this session
+	crashDir             string                 // Where a crash report is written, or "" for none
+	screenshotDir        string                 // Where each dialog's screenshot is saved, with --screenshot-dir, or ""
+	screenshotCallback   ScreenshotCallback     // Takes the screenshots saved to screenshotDir
+	eventStream          *events.Stream         // Lifecycle events of each prompt, with --event-stream, or nil
+	liveEvents           *events.Stream         // The same events, published to dcode tail on the control socket, or nil
+	transcript           *transcript.Transcript // Claude's output with markers for each prompt, with --transcript, or nil
+	telemetry            *telemetry.Counters    // Usage counts sent when the session ends, with --telemetry, or nil
+	anomalies            anomalies              // Repeats and retries counted to warn about, per anomalies in the config file
+	trace                *tracing.Trace         // Steps of answering the current prompt, or nil; guarded by traceMutex
+	traceMutex           sync.Mutex
+	autoApproved         []string // Requests approved without asking since the user last answered a dialog
+	autoApprovedMutex    sync.Mutex
+	digest               []string // Decisions made without asking since the last --digest-minutes notification
+	digestMutex          sync.Mutex
+	rejectedRequest      string // Request last rejected without asking, with spaces collapsed
+	rejectionCount       int    // Times rejectedRequest was rejected in a row
+	rejectionMutex       sync.Mutex
+	autoPaused           atomic.Bool   // Dialogs are shown instead of the auto mode after a rejection loop
+	statePath            string        // File the session's mode, temporary approvals, and counters are kept in, or ""
+	sessionMode          string        // Mode set with dcode mode, as config.Mode.String, or ""
+	stateMutex           sync.Mutex    // Guards sessionMode and serializes saveState
+	panicked             atomic.Bool   // Every request is rejected without asking, from dcode panic until dcode resume
+	panicSignal          chan struct{} // Closed by dcode panic to stop waiting for answers; guarded by panicMutex
+	panicMutex           sync.Mutex
+	dialogOpen           atomic.Bool // A dialog is waiting for an answer; cleared by whichever answers it first
+}
+
+// config returns the options in effect
+func (p *PermissionHandler) config() *config.Config {
+	return p.options.Load()
+}
+
+// reload switches to the options in cfg between lines of output, never while
+// a line is being handled
+func (p *PermissionHandler) reload(cfg *config.Config) {
+	p.lineMutex.Lock()
+	defer p.lineMutex.Unlock()
+
+	if p.autoPaused.Load() {
+		cfg = pausedConfig(cfg)
+	}
+	p.options.Store(cfg)
+	p.patterns = newRegexPatterns(cfg)
+	p.redactor = newRedactor(cfg)
+}
+
+// pauseAutoMode shows dialogs instead of answering them with --auto-approve,
+// --auto-reject, or a countdown, until the mode is set again
+func (p *PermissionHandler) pauseAutoMode() {
+	p.lineMutex.Lock()
+	defer p.lineMutex.Unlock()
+
+	p.autoPaused.Store(true)
+	p.options.Store(pausedConfig(p.config()))
+	p.saveState()
+}
+
+// enterPanic rejects every request without asking until leavePanic, starting
+// with a dialog waiting for the user, whose answer is then dropped
+func (p *PermissionHandler) enterPanic() {
+	if p.panicked.Swap(true) {
+		return
+	}
+	p.panicMutex.Lock()
+	close(p.panicSignalLocked())
+	p.panicMutex.Unlock()
+
+	if p.dialogOpen.CompareAndSwap(true, false) {
+		p.rejectPanicked()
+	}
+	if p.notificationCallback != nil {
+		go p.notificationCallback(p.text().Panicked)
+	}
+}
+
+// leavePanic goes back to handling requests as configured after enterPanic
+func (p *PermissionHandler) leavePanic() {
+	p.panicMutex.Lock()
+	defer p.panicMutex.Unlock()
+	if p.panicked.Swap(false) {
+		p.panicSignal = make(chan struct{})
+	}
+}
+
+// panicSignalLocked returns the channel closed by enterPanic; panicMutex
+// must be held
+func (p *PermissionHandler) panicSignalLocked() chan struct{} {
+	if p.panicSignal == nil {
+		p.panicSignal = make(chan struct{})
+	}
+	return p.panicSignal
+}
+
+// awaitAnswer returns the answer ask waits for, reporting false if dcode
+// panic rejected the dialog first. The answer given after that is dropped.
+func (p *PermissionHandler) awaitAnswer(ask func() string) (string, bool) {
+	p.panicMutex.Lock()
+	signal := p.panicSignalLocked()
+	p.panicMutex.Unlock()
+
+	p.dialogOpen.Store(true)
+	if p.panicked.Load() {
+		if p.dialogOpen.CompareAndSwap(true, false) {
+			p.rejectPanicked()
+		}
+		return "", false
+	}
+	if after := p.config().Escalation.AfterSeconds; after > 0 {
+		escalation := time.AfterFunc(time.Duration(after)*time.Second, func() {
+			defer p.recoverCrash()
+			p.escalate(time.Duration(after) * time.Second)
+		})
+		defer escalation.Stop()
+	}
+
+	answer := make(chan string, 1)
+	go func() {
+		defer p.recoverCrash()
+		answer <- ask()
+	}()
+	select {
+	case userChoice := <-answer:
+		return userChoice, p.dialogOpen.CompareAndSwap(true, false)
+	case <-signal:
+		return "", false
+	}
+}
+
+// escalate alerts the user through the channels in escalation that the
+// dialog shown waited long for an answer, unless it was answered meanwhile
+func (p *PermissionHandler) escalate(waited time.Duration) {
+	if !p.dialogOpen.Load() {
+		return
+	}
+	request := describeRequest(p.dialogInfo())
+	if p.redactor != nil {
+		request = p.redactor.Redact(request)
+	}
+	message := fmt.Sprintf("A dialog has waited %s for your answer: %s", waited, request)
+	debug.Info("escalating unanswered dialog", "waited", waited, "request", request)
+
+	if p.config().Escalation.Notify && p.notificationCallback != nil {
+		p.notificationCallback(message)
+	}
+	if p.escalationCallback != nil {
+		if err := p.escalationCallback(p.config(), message); err != nil {
+			debug.Warn("escalation failed", "error", err)
+		}
+	}
+}
+
+// rejectPanicked rejects the dialog after dcode panic, reporting whether it
+// did. Unlike other rejections, it doesn't count toward a rejection loop.
+func (p *PermissionHandler) rejectPanicked() bool {
+	if !p.panicked.Load() {
+		return false
+	}
+	info := p.dialogInfo()
+	maxChoice := findMaxRejectChoice(info.Choices)
+	p.decisionRecorder()(maxChoice)
+	id := p.logDecision(info, decider{rule: "panic"}, decisions.Rejected)
+	rejectMsg := p.buildRejectMessage(info, "panic", id, p.text().Panic)
+	prompt := p.snapshotPrompt("panic")
+
+	go func() {
+		defer p.recoverCrash()
+		time.Sleep(time.Duration(p.config().Delays.AutoRejectProcessMs) * time.Millisecond)
+		// Declining a folder trust prompt ends Claude, so nobody reads a message
+		if prompt.dialogType == types.DialogTypeFolderTrust {
+			if err := p.sendAnswer(prompt, maxChoice); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			return
+		}
+		p.writeRejection(prompt, maxChoice, rejectMsg)
+	}()
+	return true
+}
+
+// setSessionMode records mode, set with dcode mode, in the state file
+func (p *PermissionHandler) setSessionMode(mode string) {
+	p.stateMutex.Lock()
+	p.sessionMode = mode
+	p.stateMutex.Unlock()
+	p.saveState()
+}
+
+// restoreState picks up the temporary approvals, counters, and paused auto
+// mode in saved, left by an earlier dcode in the same session
+func (p *PermissionHandler) restoreState(saved state.State) {
+	p.stateMutex.Lock()
+	p.sessionMode = saved.Mode
+	p.stateMutex.Unlock()
+
+	for scope, until := range saved.TemporaryApprovals {
+		p.temporaryApprovals.Grant(scope, until)
+	}
+	p.autoApprovedMutex.Lock()
+	p.autoApproved = saved.AutoApproved
+	p.autoApprovedMutex.Unlock()
+	p.rejectionMutex.Lock()
+	p.rejectedRequest, p.rejectionCount = saved.RejectedRequest, saved.RejectionCount
+	p.rejectionMutex.Unlock()
+	if saved.AutoPaused {
+		p.pauseAutoMode()
+	}
+}
+
+// saveState writes the session's mode, temporary approvals, and counters to
+// the state file, if there is one, so a restarted dcode can restore them
+func (p *PermissionHandler) saveState() {
+	if p.statePath == "" {
+		return
+	}
+	saved := state.State{
+		SavedAt:            p.now(),
+		AutoPaused:         p.autoPaused.Load(),
+		TemporaryApprovals: p.temporaryApprovals.Active(p.now()),
+	}
+	p.autoApprovedMutex.Lock()
+	saved.AutoApproved = append([]string(nil), p.autoApproved...)
+	p.autoApprovedMutex.Unlock()
+	p.rejectionMutex.Lock()
+	saved.RejectedRequest, saved.RejectionCount = p.rejectedRequest, p.rejectionCount
+	p.rejectionMutex.Unlock()
+
+	p.stateMutex.Lock()
+	defer p.stateMutex.Unlock()
+	saved.Mode = p.sessionMode
+	if err := state.Save(p.statePath, saved); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save session state: %v\n", err)
+	}
+}
+
+// pausedConfig returns a copy of cfg in dialog mode
+func pausedConfig(cfg *config.Config) *config.Config {
+	paused := *cfg
+	config.Mode{Name: config.ModeDialog}.Apply(&paused)
+	return &paused
 }
 
 // buildDialogMessage constructs the dialog message from the permission prompt data using new clean format
 func (p *PermissionHandler) buildDialogMessage(promptLine string, contextLines []string, triggerReason string) string {
+	defer p.traceStep("build_message", p.now())
 	// Create timestamp for clean format
 	var timestamp string
 	if p.timeProvider != nil {
@@ -203,36 +833,210 @@ func (p *PermissionHandler) buildDialogMessage(promptLine string, contextLines [
 	}
 
 	// Use the new clean dialog message format
-	return choice.GetCleanDialogMessage(promptLine, contextLines, triggerReason, triggerLine, timestamp, regexPatterns)
+	message := choice.GetCleanDialogMessage(promptLine, contextLines, triggerReason, triggerLine, timestamp, regexPatterns)
+	if footer := p.dialogFooter(); footer != "" {
+		message += "\n\n" + footer
+	}
+	return message
 }
 
-// extractButtons extracts button labels from collected choices
-func (p *PermissionHandler) extractButtons() []string {
-	var buttons []string
-	for i := 1; i <= len(p.appState.Prompt.CollectedChoices); i++ {
-		key := fmt.Sprintf("%d", i)
-		if choice, exists := p.appState.Prompt.CollectedChoices[key]; exists {
-			// Extract button text after the number and period
-			parts := strings.SplitN(choice, ". ", 2)
-			if len(parts) > 1 {
-				buttons = append(buttons, parts[1])
-			} else {
-				buttons = append(buttons, choice)
+// dialogFooter returns the lines ending dialogs: the average response time,
+// with --show-response-time, and the prompt's request ID with dcode's
+// version, to quote in a bug report
+func (p *PermissionHandler) dialogFooter() string {
+	var lines []string
+	if footer := p.responseTimeFooter(); footer != "" {
+		lines = append(lines, footer)
+	}
+	build := "dcode " + version.Get().Short()
+	if id := p.appState.Prompt.RequestID; id != "" {
+		build = "Request " + id + " · " + build
+	}
+	return strings.Join(append(lines, build), "\n")
+}
+
+// responseTimeFooter returns the line ending dialogs with --show-response-time,
+// or "" if it's off or no dialog has been answered yet
+func (p *PermissionHandler) responseTimeFooter() string {
+	if !p.config().ShowResponseTime {
+		return ""
+	}
+	average, count := p.responseTimes.average()
+	if count == 0 {
+		return ""
+	}
+	dialogs := "dialogs"
+	if count == 1 {
+		dialogs = "dialog"
+	}
+	return fmt.Sprintf("Your average response time: %.1fs over %d %s", average.Seconds(), count, dialogs)
+}
+
+// askPermission shows a dialog through the permission callback with any
+// secrets in its text masked, returning the number of the button picked. How
+// long it waited and its screenshot are recorded in prompt, the prompt it
+// asks about for the audit log, unless that is nil.
+func (p *PermissionHandler) askPermission(prompt *promptSnapshot, message string, buttons []string, defaultButton string) (userChoice string) {
+	shown := p.now()
+	defer p.traceStep("show_dialog", shown)
+	defer func() {
+		if userChoice != "" {
+			waited := p.now().Sub(shown)
+			if prompt != nil {
+				prompt.waited += waited
 			}
+			p.responseTimes.add(waited)
+			p.resetAutoApprovals()
+			p.resetRejections()
+			p.saveState()
 		}
+	}()
+	if p.redactor != nil {
+		message = p.redactor.Redact(message)
+		buttons = p.redactor.RedactAll(buttons)
+		defaultButton = p.redactor.Redact(defaultButton)
+	}
+	if p.recordsEvents() {
+		p.emit(p.requestEvent(p.dialogInfo(), events.DialogShown))
+	}
+	if prompt != nil {
+		capture := p.startScreenshot(prompt)
+		defer p.finishScreenshot(capture, prompt)
+	}
+	if p.titledCallback != nil {
+		return p.titledCallback(p.dialogTitle(), message, buttons, defaultButton)
+	}
+	return p.permissionCallback(message, buttons, defaultButton)
+}
+
+// text returns what dcode writes in the language of the language option
+func (p *PermissionHandler) text() i18n.Messages {
+	return i18n.Get(p.config().Language, os.Getenv)
+}
+
+// dialogTitle returns the title of the dialog about the current prompt,
+// prefixed as dialog.risk_prefix says for its risk
+func (p *PermissionHandler) dialogTitle() string {
+	if p.appState.Prompt == nil {
+		return p.config().Dialog.Title
+	}
+	return p.config().Dialog.TitleFor(p.appState.Prompt.Info.Risk)
+}
+
+// responseTimes averages how long dialogs waited for the user to answer
+type responseTimes struct {
+	total time.Duration
+	count int
+	mutex sync.Mutex
+}
+
+// add counts a dialog answered after waited
+func (r *responseTimes) add(waited time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.total += waited
+	r.count++
+}
+
+// average returns the average wait and the number of dialogs answered
+func (r *responseTimes) average() (time.Duration, int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.count == 0 {
+		return 0, 0
+	}
+	return r.total / time.Duration(r.count), r.count
+}
+
+// extractButtons extracts button labels from the parsed dialog choices
+func (p *PermissionHandler) extractButtons() []string {
+	info := p.dialogInfo()
+	var buttons []string
+	for _, num := range info.ChoiceNumbers() {
+		buttons = append(buttons, info.Choices[num])
 	}
 	return buttons
 }
 
-func NewPermissionHandler(ptmx *os.File, permissionCallback PermissionCallback) *PermissionHandler {
-	return &PermissionHandler{
+// defaultButton returns the label of the choice under Claude's ❯ cursor so the
+// dialog's default matches plain Enter, falling back to the first button
+func (p *PermissionHandler) defaultButton(buttons []string) string {
+	info := p.dialogInfo()
+	if label, ok := info.Choices[info.DefaultChoice]; ok {
+		return label
+	}
+	if len(buttons) > 0 {
+		return buttons[0]
+	}
+	return ""
+}
+
+// dialogInfo returns the parsed dialog for the current prompt, falling back to
+// the prompt context if the box hasn't been completed yet
+func (p *PermissionHandler) dialogInfo() parser.DialogInfo {
+	if len(p.appState.Prompt.Info.RawContent) > 0 {
+		return p.appState.Prompt.Info
+	}
+	return p.parseDialog(p.appState.Prompt.Context)
+}
+
+// parseDialog parses dialog lines, keeping any streamed choices the parser didn't see
+func (p *PermissionHandler) parseDialog(lines []string) parser.DialogInfo {
+	info := parser.ParseDialog(lines)
+	for i, line := range info.RawContent {
+		info.RawContent[i] = p.patterns.StripAnsi(line)
+	}
+	for num, collected := range p.appState.Prompt.CollectedChoices {
+		if _, exists := info.Choices[num]; !exists {
+			info.Choices[num] = strings.TrimPrefix(collected, num+". ")
+		}
+	}
+	if info.DefaultChoice == "" {
+		info.DefaultChoice = p.appState.Prompt.DefaultChoice
+	}
+	return info
+}
+
+// newRegexPatterns creates the prompt patterns for the locales and extra
+// patterns selected in cfg
+func newRegexPatterns(cfg *config.Config) *types.RegexPatterns {
+	patterns, err := types.NewRegexPatternsWithPack(cfg.Patterns, cfg.Locales()...)
+	if err != nil {
+		// main validates the config, so fall back to English rather than failing here
+		return types.NewRegexPatterns()
+	}
+	return patterns
+}
+
+// newRedactor creates the secret redactor for the extra patterns and
+// redaction rules in cfg
+func newRedactor(cfg *config.Config) *redact.Redactor {
+	redactor, err := cfg.Redactor()
+	if err != nil {
+		// main validates the config, so fall back to the built-in patterns rather than failing here
+		redactor, _ = redact.New(nil)
+	}
+	return redactor
+}
+
+// defaultConfig returns a copy of the default options for handlers created without any
+func defaultConfig() *config.Config {
+	cfg := config.Default()
+	return &cfg
+}
+
+func NewPermissionHandler(ptmx *os.File, cfg *config.Config, permissionCallback PermissionCallback) *PermissionHandler {
+	handler := &PermissionHandler{
 		ptmx:               ptmx,
 		appState:           types.NewAppState(),
-		patterns:           types.NewRegexPatterns(),
+		patterns:           newRegexPatterns(cfg),
+		redactor:           newRedactor(cfg),
 		contextLines:       make([]string, 0, 10),
 		timeProvider:       &RealTimeProvider{},
 		permissionCallback: permissionCallback,
 	}
+	handler.options.Store(cfg)
+	return handler
 }
 
 // NewPermissionHandlerWithDialog creates a handler that uses dialog interface via callback wrapper
@@ -243,14 +1047,20 @@ func NewPermissionHandlerWithDialog(ptmx *os.File, dialogInterface DialogInterfa
 		return dialogInterface.Show(message, buttons, defaultButton)
 	}
 
-	return &PermissionHandler{
-		ptmx:               ptmx,
-		appState:           types.NewAppState(),
-		patterns:           types.NewRegexPatterns(),
-		contextLines:       make([]string, 0, 10),
-		timeProvider:       &RealTimeProvider{},
-		permissionCallback: callback,
+	cfg := defaultConfig()
+	handler := &PermissionHandler{
+		ptmx:                 ptmx,
+		appState:             types.NewAppState(),
+		patterns:             newRegexPatterns(cfg),
+		redactor:             newRedactor(cfg),
+		contextLines:         make([]string, 0, 10),
+		timeProvider:         &RealTimeProvider{},
+		permissionCallback:   callback,
+		textInputCallback:    textInputCallbackFor(dialogInterface),
+		notificationCallback: notificationCallbackFor(dialogInterface),
 	}
+	handler.options.Store(cfg)
+	return handler
 }
 
 // NewPermissionHandlerWithDialogAndTimeProvider creates a handler with dialog interface and time provider
@@ -261,24 +1071,53 @@ func NewPermissionHandlerWithDialogAndTimeProvider(ptmx *os.File, dialogInterfac
 		return dialogInterface.Show(message, buttons, defaultButton)
 	}
 
-	return &PermissionHandler{
-		ptmx:               ptmx,
-		appState:           types.NewAppState(),
-		patterns:           types.NewRegexPatterns(),
-		contextLines:       make([]string, 0, 10),
-		timeProvider:       timeProvider,
-		permissionCallback: callback,
+	cfg := defaultConfig()
+	handler := &PermissionHandler{
+		ptmx:                 ptmx,
+		appState:             types.NewAppState(),
+		patterns:             newRegexPatterns(cfg),
+		redactor:             newRedactor(cfg),
+		contextLines:         make([]string, 0, 10),
+		timeProvider:         timeProvider,
+		permissionCallback:   callback,
+		textInputCallback:    textInputCallbackFor(dialogInterface),
+		notificationCallback: notificationCallbackFor(dialogInterface),
 	}
+	handler.options.Store(cfg)
+	return handler
 }
 
 func (p *PermissionHandler) processLine(line string) {
+	p.lineMutex.Lock()
+	defer p.lineMutex.Unlock()
+
+	// Any output restarts the wait for the dialog to go quiet
+	p.stopQuiescenceTimer()
+	defer p.startQuiescenceTimer()
+
+	// Treat ASCII-art boxes (+, -, |) like Claude's usual Unicode boxes
+	line = p.borders.Normalize(line)
 	cleanLine := p.patterns.StripAnsi(line)
+	if p.transcript != nil && !strings.HasPrefix(cleanLine, "[DEBUG]") {
+		p.recordTranscriptLine(cleanLine)
+	}
 
 	// Collect context lines (always collect unless it's debug)
 	if len(strings.TrimSpace(cleanLine)) > 0 && !strings.HasPrefix(cleanLine, "[DEBUG]") {
 		p.contextLines = append(p.contextLines, cleanLine)
-		if len(p.contextLines) > ContextBufferSize { // Increase buffer for dialog boxes
-			p.contextLines = p.contextLines[1:]
+		p.drawnLines = append(p.drawnLines, line)
+		// Keep every line of an open dialog box so tall dialogs don't lose their header
+		limit := ContextBufferSize
+		if p.boxDepth > 0 {
+			limit = MaxDialogBoxLines
+		}
+		if len(p.contextLines) > limit {
+			p.contextLines = p.contextLines[len(p.contextLines)-limit:]
+			p.drawnLines = p.drawnLines[len(p.drawnLines)-limit:]
+		}
+		p.boxDepth += strings.Count(cleanLine, "╭") - strings.Count(cleanLine, "╰")
+		if p.boxDepth < 0 {
+			p.boxDepth = 0
 		}
 	}
 
@@ -287,11 +1126,24 @@ func (p *PermissionHandler) processLine(line string) {
 		return
 	}
 
-	// Check for permission prompt start - but only if we're inside a dialog box
-	// AND not in an input box (which has the "│ >" pattern)
-	if p.patterns.Permit.MatchString(line) && p.isInsideDialogBox(line) && !p.isInputBox(line) {
-		// Create a context-aware identifier for this prompt
-		// Include recent context lines to distinguish between different commands
+	// Acknowledge "Press Enter to continue" style prompts if enabled
+	if p.config().ContinuePrompts != config.ContinuePromptsIgnore && !p.appState.Prompt.Started && p.patterns.ContinuePrompt.MatchString(cleanLine) {
+		if p.shouldProcessPrompt(cleanLine) {
+			p.handleContinuePrompt(cleanLine)
+		}
+		return
+	}
+
+	// An "Are you sure?" prompt right after a dialog was answered confirms that answer
+	decision, hasDecision := p.appState.PendingConfirmation(p.now())
+	isConfirmation := hasDecision && p.patterns.ConfirmPrompt.MatchString(line)
+
+	// Check for permission prompt start - but only if we're inside a dialog box
+	// AND not in an input box, where the question is just text the user typed
+	isTrustPrompt := p.patterns.TrustPrompt.MatchString(line)
+	if (p.patterns.Permit.MatchString(line) || isTrustPrompt || isConfirmation) && p.isInsideDialogBox(line) && parser.ClassifyBox(p.openBoxLines()) != parser.BoxInput {
+		// Create a context-aware identifier for this prompt
+		// Include recent context lines to distinguish between different commands
 		contextIdentifier := ""
 		if len(p.contextLines) > 0 {
 			// Use the last few context lines to create a unique identifier
@@ -305,9 +1157,21 @@ func (p *PermissionHandler) processLine(line string) {
 		// Add timestamp to make each prompt unique
 		contextIdentifier += "|" + fmt.Sprintf("%d", p.timeProvider.Now().UnixNano())
 
-		if contextIdentifier != p.appState.Prompt.LastLine {
+		if isConfirmation {
+			// Continue the answered request instead of starting an unrelated one
+			if p.appState.ShouldProcessConfirmation(decision) {
+				p.appState.StartConfirmationCollection(line, decision, p.contextLines)
+				p.promptDetected()
+			}
+		} else if contextIdentifier != p.appState.Prompt.LastLine {
 			if p.shouldProcessPrompt(line) {
 				p.appState.StartPromptCollectionWithContext(line, contextIdentifier, p.contextLines)
+				p.promptDetected()
+				if isTrustPrompt {
+					p.appState.Prompt.DialogType = types.DialogTypeFolderTrust
+				} else if p.patterns.PlanPrompt.MatchString(line) {
+					p.appState.Prompt.DialogType = types.DialogTypePlanApproval
+				}
 			}
 		}
 		return
@@ -344,34 +1208,8 @@ func (p *PermissionHandler) isInsideDialogBox(line string) bool {
 	return false
 }
 
-// isInputBox checks if the current context indicates an input box
-// Input boxes have the pattern "│ >" which is different from dialog choices "│ ❯"
-func (p *PermissionHandler) isInputBox(line string) bool {
-	// Check if current line or recent context has input box pattern
-	// Note: Claude Code uses non-breaking space (U+00A0) around the ">"
-	if strings.Contains(line, "│ >") || strings.Contains(line, "│\u00a0>") || strings.Contains(line, "> Rejected") {
-		return true
-	}
-
-	// Check recent context (up to 3 lines back) for input box patterns
-	const contextLinesToCheck = 3
-	contextLen := len(p.contextLines)
-	startIdx := contextLen - contextLinesToCheck
-	if startIdx < 0 {
-		startIdx = 0
-	}
-
-	for i := startIdx; i < contextLen; i++ {
-		if strings.Contains(p.contextLines[i], "│ >") ||
-			strings.Contains(p.contextLines[i], "│\u00a0>") ||
-			strings.Contains(p.contextLines[i], "> Rejected") {
-			return true
-		}
-	}
-
-	return false
-}
-
+// shouldSkipLine reports whether a line can't start a prompt. Skipped diff lines
+// are still kept in the context so the parser can show them in the dialog.
 func (p *PermissionHandler) shouldSkipLine(cleanLine string) bool {
 	return strings.HasPrefix(strings.TrimSpace(cleanLine), "+") ||
 		strings.HasPrefix(strings.TrimSpace(cleanLine), "-") ||
@@ -382,7 +1220,42 @@ func (p *PermissionHandler) shouldSkipLine(cleanLine string) bool {
 }
 
 func (p *PermissionHandler) shouldProcessPrompt(line string) bool {
-	return p.appState.ShouldProcessPrompt(line, p.patterns)
+	if p.appState.ShouldProcessPrompt(line, p.patterns) {
+		return true
+	}
+	p.promptSuppressed(line)
+	p.countSuppressed()
+	return false
+}
+
+// promptSuppressed logs and emits why line, the start of a prompt, was
+// skipped as a repeat, with what the deduplication held at the time
+func (p *PermissionHandler) promptSuppressed(line string) {
+	stats := p.appState.Deduplicator.Snapshot()
+	reason := suppressedReason(stats, p.appState.Deduplicator.StripAnsi(line))
+	debug.Info("prompt skipped as a repeat", "reason", reason, "prompts", len(stats.Prompts), "cooldowns", len(stats.Cooldowns), "active_cooldowns", stats.ActiveCooldowns())
+	debug.Debug("deduplication state", "prompts", stats.Prompts, "cooldowns", stats.Cooldowns)
+	p.emit(events.Event{
+		Type:    events.Suppressed,
+		Message: reason,
+		Dedup:   &events.Dedup{Prompts: len(stats.Prompts), Cooldowns: len(stats.Cooldowns), ActiveCooldowns: stats.ActiveCooldowns()},
+	})
+}
+
+// suppressedReason explains why the prompt with key was skipped, given stats
+// taken right after
+func suppressedReason(stats deduplication.Stats, key string) string {
+	for _, cooldown := range stats.Cooldowns {
+		if cooldown.Key == types.DialogCooldownKey {
+			return fmt.Sprintf("a dialog was shown %s ago", cooldown.Age.Round(time.Millisecond))
+		}
+	}
+	for _, prompt := range stats.Prompts {
+		if prompt.Key == key {
+			return fmt.Sprintf("the same prompt was seen %s ago (%d times)", prompt.Age.Round(time.Millisecond), prompt.Count)
+		}
+	}
+	return "seen recently"
 }
 
 func (p *PermissionHandler) processChoice(line, cleanLine string) {
@@ -390,131 +1263,1142 @@ func (p *PermissionHandler) processChoice(line, cleanLine string) {
 
 	// Check if this is the end of choices
 	if strings.Contains(cleanLine, "╰") {
-		p.appState.Prompt.Started = false
+		p.finalizeDialog(p.drawn(p.currentBoxLines()))
+	}
+}
+
+// promptDetected notes that the prompt being collected just appeared, for
+// the audit log, gives it a request ID that debug log records are tagged
+// with until the next prompt appears, and starts its trace, dropping that of
+// an earlier prompt that was never answered
+func (p *PermissionHandler) promptDetected() {
+	p.appState.Prompt.DetectedAt = p.now()
+	p.appState.Prompt.RequestID = p.requestID()
+	debug.SetRequestID(p.appState.Prompt.RequestID)
+	p.emit(events.Event{Type: events.DialogDetected})
+	if p.tracer == nil {
+		return
+	}
+	p.traceMutex.Lock()
+	defer p.traceMutex.Unlock()
+	p.trace = tracing.NewTrace("answer_prompt", p.appState.Prompt.DetectedAt)
+}
+
+// traceStep adds a step of answering the current prompt, name, that ran from
+// start until now to its trace
+func (p *PermissionHandler) traceStep(name string, start time.Time) {
+	p.traceMutex.Lock()
+	trace := p.trace
+	p.traceMutex.Unlock()
+	trace.Record(name, start, p.now())
+}
+
+// finalizeDialog stops collecting choices and answers the dialog in boxLines
+func (p *PermissionHandler) finalizeDialog(boxLines []string) {
+	p.traceStep("detect", p.appState.Prompt.DetectedAt)
+	p.appState.Prompt.Started = false
+	p.appState.Prompt.Info = p.parseDialog(boxLines)
+	p.countPrompt(p.dialogInfo())
+
+	// Add a longer delay to ensure the prompt is fully rendered and processed
+	settling := p.now()
+	time.Sleep(time.Duration(p.config().Delays.ChoiceProcessingMs) * time.Millisecond)
+	p.traceStep("settle", settling)
+	defer p.traceStep("decide", p.now())
+
+	// After dcode panic nothing is approved, not even a folder under --trust-dir
+	if p.rejectPanicked() {
+		return
+	}
+	if p.appState.Prompt.DialogType == types.DialogTypeFolderTrust {
+		p.handleTrustPrompt()
+		return
+	}
+	// Nothing answers a forbidden request, not even a confirmation of an
+	// earlier answer
+	if p.rejectForbidden() {
+		return
+	}
+	if p.appState.Prompt.DialogType == types.DialogTypeConfirmation {
+		p.handleConfirmation()
+		return
+	}
+	if p.denyByRule() || p.denyByToolPolicy() {
+		return
+	}
+
+	bestChoice := p.chooseDefault()
+	if p.handleOutsideEditScope(bestChoice) {
+		return
+	}
+	if p.approveFollowUp() || p.approveByRule() || p.approveReadOnly() || p.approveFromCache() || p.approveTemporarily() {
+		return
+	}
+	p.handleByRisk(bestChoice)
+}
+
+// rejectForbidden rejects the dialog if it matches one of the forbid rules,
+// sending the rule's message to Claude, and reports whether it did
+func (p *PermissionHandler) rejectForbidden() bool {
+	rule, number, ok := p.forbidRule(p.dialogInfo())
+	if !ok {
+		return false
+	}
+
+	message := rule.Message
+	if message == "" {
+		message = p.text().ForbidRule
+	}
+	p.sendRejection(decider{ruleLabel(config.RuleListForbid, number, rule), describeRule(rule)}, message)
+	return true
+}
+
+// forbidRule returns the first forbid rule matching info and its number,
+// counting from 1
+func (p *PermissionHandler) forbidRule(info parser.DialogInfo) (config.DenyRule, int, bool) {
+	for i, rule := range p.config().Forbid {
+		if rule.Matches(info) {
+			return rule, i + 1, true
+		}
+	}
+	return config.DenyRule{}, 0, false
+}
+
+// denyByRule rejects the dialog if it matches one of the deny rules, sending
+// the rule's message to Claude, and reports whether it did
+func (p *PermissionHandler) denyByRule() bool {
+	info := p.dialogInfo()
+	for i, rule := range p.config().Deny {
+		if !rule.Matches(info) {
+			continue
+		}
+
+		message := rule.Message
+		if message == "" {
+			message = p.text().DenyRule
+		}
+		p.sendRejection(decider{ruleLabel(config.RuleListDeny, i+1, rule), describeRule(rule)}, message)
+		return true
+	}
+	return false
+}
+
+// ruleLabel names the rule with number in list, e.g. "deny rule 2", followed
+// by its name, if any, so Claude is told why it was rejected
+func ruleLabel(list string, number int, rule config.DenyRule) string {
+	label := fmt.Sprintf("%s rule %d", list, number)
+	if rule.Name != "" {
+		label += " '" + rule.Name + "'"
+	}
+	return label
+}
+
+// denyByToolPolicy rejects the dialog if --tool-policy denies its tool,
+// reporting whether it did
+func (p *PermissionHandler) denyByToolPolicy() bool {
+	tool := p.dialogInfo().ToolType
+	if p.config().ToolPolicy.Action(tool) != config.ToolPolicyDeny {
+		return false
+	}
+	p.sendRejection(decider{"tool_policy", tool + "=" + config.ToolPolicyDeny}, fmt.Sprintf(p.text().ToolPolicy, tool))
+	return true
+}
+
+// asksAboutTool reports whether --tool-policy says to ask about the tool of
+// the dialog described by info, even if it would be approved otherwise
+func (p *PermissionHandler) asksAboutTool(info parser.DialogInfo) bool {
+	return p.config().ToolPolicy.Action(info.ToolType) == config.ToolPolicyAsk
+}
+
+// handleOutsideEditScope handles the dialog if it edits files outside
+// edit_scope, reporting whether it did: the edit is rejected, or with
+// outside: dialog, left to the user even if it would be approved automatically
+func (p *PermissionHandler) handleOutsideEditScope(bestChoice string) bool {
+	if !p.editsOutsideScope(p.dialogInfo()) {
+		return false
+	}
+	if p.config().EditScope.Outside == config.EditScopeActionDialog {
+		p.askUser(bestChoice)
+		return true
+	}
+
+	dirs := strings.Join(p.editDirs(), ", ")
+	message := p.config().EditScope.Message
+	if message == "" {
+		message = fmt.Sprintf(p.text().EditScope, dirs)
+	}
+	p.sendRejection(decider{"edit_scope", "outside " + dirs}, message)
+	return true
+}
 
-		// Add a longer delay to ensure the prompt is fully rendered and processed
-		time.Sleep(ChoiceProcessingDelayMs * time.Millisecond)
+// editsOutsideScope reports whether info edits a file outside the edit_scope
+// directories. An edit whose target file wasn't found counts as outside.
+func (p *PermissionHandler) editsOutsideScope(info parser.DialogInfo) bool {
+	if !p.config().EditScope.Enabled() || !parser.IsFileEditTool(info.ToolType) {
+		return false
+	}
+	if len(info.FilePaths) == 0 {
+		return true
+	}
+
+	dirs := p.editDirs()
+	for _, path := range info.FilePaths {
+		// Claude shows paths relative to the directory it was started in
+		absPath, err := filepath.Abs(expandHome(path))
+		if err != nil || !isWithinDirs(absPath, dirs) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDirs returns the absolute directories edit_scope allows edits in
+func (p *PermissionHandler) editDirs() []string {
+	var dirs []string
+	if p.config().EditScope.RepoRoot {
+		if wd, err := os.Getwd(); err == nil {
+			if root := findRepoRoot(wd); root != "" {
+				dirs = append(dirs, root)
+			}
+		}
+	}
+	for _, dir := range p.config().EditScope.Dirs {
+		if absDir, err := filepath.Abs(expandHome(dir)); err == nil {
+			dirs = append(dirs, absDir)
+		}
+	}
+	return dirs
+}
+
+// findRepoRoot returns the closest directory at or above dir that contains
+// .git, or "" if dir isn't in a git repository
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// approveByRule answers the dialog with its approve-once choice if it matches
+// one of the approve rules, reporting whether it did. A truncated command is
+// never approved, since the hidden part could do anything.
+func (p *PermissionHandler) approveByRule() bool {
+	info := p.dialogInfo()
+	i := config.MatchingRule(p.config().Approve, info)
+	if info.Truncated || i < 0 {
+		return false
+	}
+	approveChoice := info.FirstChoice(parser.ChoiceApproveOnce)
+	if approveChoice == "" {
+		return false
+	}
+
+	p.autoApprove(decider{fmt.Sprintf("approve rule %d", i+1), describeRule(config.DenyRule{Rule: p.config().Approve[i]})}, approveChoice)
+	return true
+}
+
+// approveFollowUp answers a dialog asking again about the request approved
+// just before, within ConfirmationWindowSeconds, with its approve-once choice,
+// reporting whether it did. Like an "Are you sure?" confirmation, it continues
+// the earlier answer, so it isn't recorded as a new one.
+func (p *PermissionHandler) approveFollowUp() bool {
+	decision, ok := p.appState.RecentDecision(p.now())
+	if !ok || (decision.Kind != parser.ChoiceApproveOnce && decision.Kind != parser.ChoiceApproveAlways) {
+		return false
+	}
+	info := p.dialogInfo()
+	if info.Truncated || decision.Request != requestKey(info) {
+		return false
+	}
+	approveChoice := info.FirstChoice(parser.ChoiceApproveOnce)
+	if approveChoice == "" {
+		return false
+	}
+
+	p.logDecision(info, decider{"follow_up", "approved at " + decision.At.Format(time.TimeOnly)}, decisions.Approved)
+	prompt := p.snapshotPrompt("follow_up")
+	go func() {
+		defer p.recoverCrash()
+		time.Sleep(time.Duration(p.config().Delays.AutoApproveMs) * time.Millisecond)
+		if err := p.sendAnswer(prompt, approveChoice); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}()
+	return true
+}
+
+// approveReadOnly answers the dialog of a read-only tool with its approve-once
+// choice when --allow-read-only is set, reporting whether it did. Reads rated
+// high risk, such as of credentials, are still asked about.
+func (p *PermissionHandler) approveReadOnly() bool {
+	info := p.dialogInfo()
+	if !p.config().AllowReadOnly || !parser.IsReadOnlyTool(info.ToolType) || info.Risk == parser.RiskHigh || p.asksAboutTool(info) {
+		return false
+	}
+	approveChoice := info.FirstChoice(parser.ChoiceApproveOnce)
+	if approveChoice == "" {
+		return false
+	}
+
+	p.autoApprove(decider{rule: "allow_read_only"}, approveChoice)
+	return true
+}
+
+// approveFromCache answers the dialog with its approve-once choice if the user
+// approved the same request within --approval-cache-seconds, reporting whether it did
+func (p *PermissionHandler) approveFromCache() bool {
+	info := p.dialogInfo()
+	if !p.cachesApproval(info) || !p.approvalCache.Approved(requestKey(info), p.approvalCacheTTL(), p.now()) {
+		return false
+	}
+	approveChoice := info.FirstChoice(parser.ChoiceApproveOnce)
+	if approveChoice == "" {
+		return false
+	}
+
+	p.autoApprove(decider{rule: "approval_cache"}, approveChoice)
+	return true
+}
+
+// approveTemporarily answers the dialog with its approve-once choice if the
+// user approved a similar request with the temporary approval button less than
+// temporary_approval_minutes ago, reporting whether it did
+func (p *PermissionHandler) approveTemporarily() bool {
+	info := p.dialogInfo()
+	if !p.grantsTemporaryApproval(info) || !p.temporaryApprovals.Covers(approvals.Scope(info), p.now()) {
+		return false
+	}
+
+	p.autoApprove(decider{"temporary_approval", approvals.Scope(info)}, info.FirstChoice(parser.ChoiceApproveOnce))
+	return true
+}
+
+// grantsTemporaryApproval reports whether the dialog may be covered by a
+// temporary approval. Like cached approvals, truncated commands and high-risk
+// requests are always asked about.
+func (p *PermissionHandler) grantsTemporaryApproval(info parser.DialogInfo) bool {
+	return p.config().TemporaryApprovalMinutes > 0 && !info.Truncated && info.Risk != parser.RiskHigh &&
+		approvals.Scope(info) != "" && info.FirstChoice(parser.ChoiceApproveOnce) != "" && !p.editsOutsideScope(info)
+}
+
+// addTemporaryApprovalButton inserts the temporary approval button right after
+// the approve-once choice in buttons, returning the new buttons and the
+// button's 1-based number. Keeping the reject choices last means a dialog that
+// fails, which answers with the last button, still rejects.
+func (p *PermissionHandler) addTemporaryApprovalButton(buttons []string, info parser.DialogInfo) ([]string, int) {
+	approveChoice := info.FirstChoice(parser.ChoiceApproveOnce)
+	position := 0
+	for i, num := range info.ChoiceNumbers() {
+		if num == approveChoice {
+			position = i + 1
+		}
+	}
+
+	label := fmt.Sprintf(p.text().TemporaryApprovalButton, p.config().TemporaryApprovalMinutes)
+	withButton := append(append(append([]string{}, buttons[:position]...), label), buttons[position:]...)
+	return withButton, position + 1
+}
+
+// resolveTemporaryApproval maps the button number picked in a dialog that has
+// the temporary approval button at temporaryButton back to a dialog choice,
+// reporting whether the temporary approval button was picked
+func resolveTemporaryApproval(userChoice string, temporaryButton int, info parser.DialogInfo) (string, bool) {
+	userChoice, picked := resolveAddedButton(userChoice, temporaryButton)
+	if picked {
+		return info.FirstChoice(parser.ChoiceApproveOnce), true
+	}
+	return userChoice, false
+}
+
+// resolveAddedButton maps the button number picked in a dialog with a button
+// added at addedButton back to the numbering without it, reporting whether
+// the added button itself was picked
+func resolveAddedButton(userChoice string, addedButton int) (string, bool) {
+	number, err := strconv.Atoi(userChoice)
+	if err != nil {
+		return userChoice, false
+	}
+	switch {
+	case number == addedButton:
+		return userChoice, true
+	case number > addedButton:
+		return strconv.Itoa(number - 1), false
+	}
+	return userChoice, false
+}
+
+// offersNeverAllow reports whether the dialog gets the NeverAllowButton: with
+// sync_settings, when Claude's settings can deny exactly this request
+func (p *PermissionHandler) offersNeverAllow(info parser.DialogInfo) bool {
+	return p.config().SyncSettings && claudesettings.DenyEntry(info) != "" && findMaxRejectChoice(info.Choices) != ""
+}
+
+// addNeverAllowButton inserts label, the NeverAllowButton, right before the
+// last of buttons, returning the new buttons and the button's 1-based number.
+// A dialog that fails answers with the last button, which shouldn't deny
+// forever.
+func addNeverAllowButton(buttons []string, label string) ([]string, int) {
+	position := len(buttons) - 1
+	withButton := append(append(append([]string{}, buttons[:position]...), label), buttons[position:]...)
+	return withButton, position + 1
+}
+
+// syncSettings adds the user's answer to the dialog described by info to the
+// project's Claude settings, so Claude stops asking itself: an approval that
+// doesn't ask again to the allow list, and never to the deny list
+func (p *PermissionHandler) syncSettings(info parser.DialogInfo, label string, never bool) {
+	var list, entry string
+	switch {
+	case never:
+		list, entry = claudesettings.ListDeny, claudesettings.DenyEntry(info)
+	case parser.ClassifyChoice(label) == parser.ChoiceApproveAlways:
+		list, entry = claudesettings.ListAllow, claudesettings.AllowEntry(info)
+	}
+	if entry == "" {
+		return
+	}
+
+	path := claudesettings.ProjectPath(projectDir())
+	if _, err := claudesettings.AddPermission(path, list, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update Claude settings: %v\n", err)
+	}
+}
 
-		bestChoice := choice.GetBestChoiceFromState(p.appState, p.patterns)
-		p.handleUserChoice(bestChoice)
+// projectDir returns the git repository dcode was started in, or the
+// directory itself outside a repository
+func projectDir() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "."
 	}
+	if root := findRepoRoot(wd); root != "" {
+		return root
+	}
+	return wd
+}
+
+// requestKey identifies the request of the dialog described by info in this
+// project, for the approval cache and confirmations
+func requestKey(info parser.DialogInfo) string {
+	return approvals.Key(projectDir(), info)
 }
 
+// cachesApproval reports whether an approval of the dialog is remembered.
+// Truncated commands and high-risk requests are always asked about.
+func (p *PermissionHandler) cachesApproval(info parser.DialogInfo) bool {
+	return p.approvalCache != nil && p.config().ApprovalCacheSeconds > 0 && !info.Truncated && info.Risk != parser.RiskHigh
+}
+
+func (p *PermissionHandler) approvalCacheTTL() time.Duration {
+	return time.Duration(p.config().ApprovalCacheSeconds) * time.Second
+}
+
+// handleByRisk handles the dialog as the risk policy says for its risk level,
+// falling back to the usual handling when the action can't be taken
+func (p *PermissionHandler) handleByRisk(bestChoice string) {
+	info := p.dialogInfo()
+	switch p.config().Risk.Action(info.Risk) {
+	case config.RiskActionApprove:
+		// The hidden part of a truncated command wasn't rated
+		if approveChoice := info.FirstChoice(parser.ChoiceApproveOnce); approveChoice != "" && !info.Truncated && !p.asksAboutTool(info) {
+			p.autoApprove(decider{rule: fmt.Sprintf("risk policy for %s risk", info.Risk)}, approveChoice)
+			return
+		}
+	case config.RiskActionReject:
+		p.sendRejection(decider{rule: fmt.Sprintf("risk policy for %s risk", info.Risk)}, fmt.Sprintf(p.text().RiskReject, info.Risk, info.RiskReason))
+		return
+	case config.RiskActionConfirm:
+		// Rejecting without asking is already safe, and quiet hours reject too
+		if !p.config().AutoReject && !p.inQuietHours() {
+			p.showDialog(bestChoice, true, "")
+			return
+		}
+	case config.RiskActionRemote:
+		if !p.config().AutoReject && p.remoteCallback != nil {
+			p.askRemote()
+			return
+		}
+	}
+	p.handleUserChoice(bestChoice)
+}
+
+// askRemote asks about the dialog through the remote callback, rejecting it
+// if nobody answers in time. Quiet hours don't apply, since whoever answers
+// remotely chose to be asked.
+func (p *PermissionHandler) askRemote() {
+	record := p.decisionRecorder()
+	prompt := p.snapshotPrompt("")
+	message := p.buildDialogMessage(p.appState.Prompt.LastLine, p.appState.Prompt.Context, p.appState.Prompt.TriggerReason)
+	buttons := p.extractButtons()
+	go p.answerRemotely(prompt, message, buttons, record)
+}
+
+// answerRemotely sends message with the dialog's buttons to the remote
+// callback and answers prompt with the button picked, or rejects it if
+// nobody answers in time
+func (p *PermissionHandler) answerRemotely(prompt promptSnapshot, message string, buttons []string, record func(choice string)) {
+	info := prompt.info
+	maxChoice := findMaxRejectChoice(info.Choices)
+	remote := p.config().Remote
+	if p.redactor != nil {
+		message = p.redactor.Redact(message)
+		buttons = p.redactor.RedactAll(buttons)
+	}
+	var err error
+	userChoice, answered := p.awaitAnswer(func() string {
+		defer p.traceStep("ask_remote", p.now())
+		var userChoice string
+		userChoice, err = p.remoteCallback(remote, message, buttons)
+		return userChoice
+	})
+	if !answered {
+		return
+	}
+	if _, ok := info.Choices[userChoice]; err != nil || !ok {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: no remote answer: %v\n", err)
+		}
+		record(maxChoice)
+		id := p.logDecision(info, decider{"remote", "no answer from " + remote.NtfyURL}, decisions.Rejected)
+		prompt.decidedBy = "remote"
+		p.writeRejection(prompt, maxChoice, p.buildRejectMessage(info, "remote", id, p.text().RemoteTimeout))
+		return
+	}
+	if _, _, forbidden := p.forbidRule(info); forbidden && isApproval(info.Choices[userChoice]) {
+		userChoice = maxChoice
+	}
+	userChoice = p.safeChoice(info, userChoice)
+
+	if err := p.sendAnswer(prompt, userChoice); err != nil {
+		return
+	}
+	record(userChoice)
+	p.handleDialogCooldown()
+}
+
+// offersAskSomeoneElse reports whether dialogs get the AskSomeoneElseButton
+func (p *PermissionHandler) offersAskSomeoneElse() bool {
+	return p.config().Remote.Delegate && p.remoteCallback != nil
+}
+
+// startQuiescenceTimer arranges for the current dialog to be finalized if no
+// more output arrives within --dialog-quiescence-ms after its choices, which
+// happens when the bottom border scrolled away or was never drawn
+func (p *PermissionHandler) startQuiescenceTimer() {
+	if p.config().DialogQuiescenceMs <= 0 || !p.appState.Prompt.Started || len(p.appState.Prompt.CollectedChoices) == 0 {
+		return
+	}
+	round := p.quiescenceRound
+	p.quiescenceTimer = time.AfterFunc(time.Duration(p.config().DialogQuiescenceMs)*time.Millisecond, func() {
+		defer p.recoverCrash()
+		p.finalizeStalledDialog(round)
+	})
+}
+
+func (p *PermissionHandler) stopQuiescenceTimer() {
+	if p.quiescenceTimer != nil {
+		p.quiescenceTimer.Stop()
+		p.quiescenceTimer = nil
+	}
+	p.quiescenceRound++
+}
+
+// finalizeStalledDialog finalizes the dialog still open when the timer started
+// in round fired
+func (p *PermissionHandler) finalizeStalledDialog(round int) {
+	p.lineMutex.Lock()
+	defer p.lineMutex.Unlock()
+
+	// More output may have arrived just as the timer fired, replacing or stopping it
+	if p.quiescenceRound != round || !p.appState.Prompt.Started {
+		return
+	}
+	p.quiescenceTimer = nil
+	p.finalizeDialog(p.drawn(p.openBoxLines()))
+}
+
+// handleUserChoice approves the dialog if --auto-approve or --tool-policy
+// says to, and otherwise leaves it to the user
 func (p *PermissionHandler) handleUserChoice(bestChoice string) {
-	if *autoApprove {
-		errCh := p.sendAutoApprove(bestChoice)
+	tool := p.dialogInfo().ToolType
+	switch action := p.config().ToolPolicy.Action(tool); {
+	case action == config.ToolPolicyAllow:
+		p.autoApprove(decider{"tool_policy", tool + "=" + action}, bestChoice)
+		return
+	case p.config().AutoApprove && action != config.ToolPolicyAsk:
+		p.autoApprove(decider{rule: "auto_approve"}, bestChoice)
+		return
+	}
+	p.askUser(bestChoice)
+}
+
+// askUser leaves the dialog to the user: it is shown, counted down, or, when
+// nobody is asked, rejected
+func (p *PermissionHandler) askUser(bestChoice string) {
+	if p.config().AutoReject {
+		p.sendAutoReject()
+	} else if p.inQuietHours() {
+		p.rejectForQuietHours()
+	} else if p.config().AutoRejectWait > 0 {
+		p.sendAutoRejectWithWait(bestChoice)
+	} else if p.config().AutoApproveWait > 0 && bestChoice != "" {
+		p.sendAutoApproveWithWait(bestChoice)
+	} else {
+		p.showDialog(bestChoice, false, "")
+	}
+}
+
+// inQuietHours reports whether dialogs are currently replaced by the quiet hours action
+func (p *PermissionHandler) inQuietHours() bool {
+	return p.config().QuietHours.Active(p.now())
+}
+
+// rejectForQuietHours rejects the dialog instead of showing it, first posting
+// a notification if the quiet hours action is notify
+func (p *PermissionHandler) rejectForQuietHours() {
+	quietHours := p.config().QuietHours
+	if quietHours.Action == config.QuietHoursActionNotify && p.notificationCallback != nil {
+		request := "Claude's request"
+		if info := p.dialogInfo(); len(info.CommandLines) > 0 {
+			request = info.CommandLines[0]
+		}
+		if p.redactor != nil {
+			request = p.redactor.Redact(request)
+		}
+		go p.notificationCallback("Rejected during quiet hours: " + request)
+	}
+
+	message := quietHours.Message
+	if message == "" {
+		message = p.text().QuietHours
+	}
+	p.sendRejection(decider{"quiet_hours", strings.Join(quietHours.Windows, ", ")}, message)
+}
+
+// handleConfirmation answers an "Are you sure?" follow-up the same way as the
+// dialog it confirms: an approval is confirmed and a rejection is declined.
+// If the earlier answer can't be classified, or the follow-up names another
+// request, the user is asked as usual.
+func (p *PermissionHandler) handleConfirmation() {
+	decision, ok := p.appState.RecentDecision(p.now())
+	info := p.dialogInfo()
+
+	var answer string
+	switch {
+	case !ok:
+	case info.ToolType != "" && requestKey(info) != decision.Request:
+	case decision.Kind == parser.ChoiceApproveOnce || decision.Kind == parser.ChoiceApproveAlways:
+		answer = info.FirstChoice(parser.ChoiceApproveOnce)
+	case decision.Kind == parser.ChoiceReject:
+		answer = findMaxRejectChoice(info.Choices)
+	}
+	if answer == "" {
+		p.handleUserChoice(p.chooseDefault())
+		return
+	}
+
+	prompt := p.snapshotPrompt("confirmation")
+	go func() {
+		defer p.recoverCrash()
+		time.Sleep(time.Duration(p.config().Delays.AutoApproveMs) * time.Millisecond)
+		if err := p.sendAnswer(prompt, answer); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}()
+}
+
+// decisionRecorder captures the prompt being answered and returns a function
+// that records the answer once it is known, even if a new prompt has started
+func (p *PermissionHandler) decisionRecorder() func(choice string) {
+	serial, key, reason := p.appState.Prompt.Serial, p.appState.Prompt.LastLine, p.appState.Prompt.TriggerReason
+	info := p.dialogInfo()
+	request, choices := requestKey(info), info.Choices
+	return func(choice string) {
+		p.appState.RecordDecision(types.Decision{
+			Serial:        serial,
+			Key:           key,
+			Request:       request,
+			TriggerReason: reason,
+			Choice:        choice,
+			Kind:          parser.ClassifyChoice(choices[choice]),
+			At:            p.now(),
+		})
+	}
+}
+
+// now returns the current time from the time provider, if one is set
+func (p *PermissionHandler) now() time.Time {
+	if p.timeProvider == nil {
+		return time.Now()
+	}
+	return p.timeProvider.Now()
+}
+
+// requestID returns a new request ID for a prompt
+func (p *PermissionHandler) requestID() string {
+	if p.newRequestID == nil {
+		return audit.NewID()
+	}
+	return p.newRequestID()
+}
+
+// gitInfo returns the git context of dir at the time of the answer
+func (p *PermissionHandler) gitInfo(dir string) gitinfo.Info {
+	if p.readGitInfo == nil {
+		return gitinfo.Read(dir)
+	}
+	return p.readGitInfo(dir)
+}
+
+// handleTrustPrompt answers the folder trust dialog, auto-trusting folders under --trust-dir
+func (p *PermissionHandler) handleTrustPrompt() {
+	folder := ""
+	if info := p.dialogInfo(); len(info.FilePaths) > 0 {
+		folder = info.FilePaths[0]
+	}
+	trustChoice := choice.GetBestChoiceFromState(p.appState, p.patterns)
+
+	if isWithinDirs(folder, p.config().TrustDirs) {
+		prompt := p.snapshotPrompt("trust_dir")
 		go func() {
-			if err := <-errCh; err != nil {
-				// Log error but continue operation
+			defer p.recoverCrash()
+			time.Sleep(time.Duration(p.config().Delays.AutoApproveMs) * time.Millisecond)
+			if err := p.sendAnswer(prompt, trustChoice); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
 		}()
-	} else if *autoReject {
-		p.sendAutoReject()
-	} else if *autoRejectWait > 0 {
-		p.sendAutoRejectWithWait(bestChoice)
-	} else {
-		p.showDialog(bestChoice)
+		return
+	}
+
+	// Declining would end the session, so the prompt is left for the morning
+	if p.inQuietHours() {
+		return
+	}
+
+	prompt := p.snapshotPrompt("")
+	go func() {
+		defer p.recoverCrash()
+		text := p.text()
+		message := text.TrustFolder
+		if folder != "" {
+			message += "\n\n" + folder
+		}
+		message += "\n\n" + text.TrustFolderNote
+		buttons := p.extractButtons()
+		defaultButton := p.defaultButton(buttons)
+
+		if p.permissionCallback == nil {
+			return
+		}
+		userChoice := p.askPermission(&prompt, message, buttons, defaultButton)
+		if userChoice != "" {
+			if err := p.sendAnswer(prompt, userChoice); err != nil {
+				return
+			}
+			p.handleDialogCooldown()
+		}
+	}()
+}
+
+// currentBoxLines returns the context lines from the top border matching the
+// most recent bottom border, skipping over any boxes nested inside it
+func (p *PermissionHandler) currentBoxLines() []string {
+	depth := 0
+	for i := len(p.contextLines) - 1; i >= 0; i-- {
+		if strings.Contains(p.contextLines[i], "╰") {
+			depth++
+		}
+		if strings.Contains(p.contextLines[i], "╭") {
+			depth--
+			if depth <= 0 {
+				return p.contextLines[i:]
+			}
+		}
+	}
+	return p.contextLines
+}
+
+// drawn returns lines, the last of the context lines, as they were drawn
+func (p *PermissionHandler) drawn(lines []string) []string {
+	return p.drawnLines[len(p.drawnLines)-len(lines):]
+}
+
+// openBoxLines returns the context lines from the top border of the box that
+// is still open, or the recent context lines if no top border is in context
+func (p *PermissionHandler) openBoxLines() []string {
+	depth := 0
+	for i := len(p.contextLines) - 1; i >= 0; i-- {
+		depth += strings.Count(p.contextLines[i], "╰")
+		for n := strings.Count(p.contextLines[i], "╭"); n > 0; n-- {
+			if depth == 0 {
+				return p.contextLines[i:]
+			}
+			depth--
+		}
+	}
+	return p.contextLines[max(0, len(p.contextLines)-4):]
+}
+
+// isWithinDirs reports whether folder is one of dirs or below one of them
+func isWithinDirs(folder string, dirs []string) bool {
+	if folder == "" {
+		return false
+	}
+	folder = filepath.Clean(expandHome(folder))
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(filepath.Clean(expandHome(dir)), folder)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// handleContinuePrompt answers a non-choice continuation prompt with Enter
+func (p *PermissionHandler) handleContinuePrompt(cleanLine string) {
+	go func() {
+		defer p.recoverCrash()
+		if p.config().ContinuePrompts == config.ContinuePromptsDialog {
+			if p.permissionCallback == nil || p.inQuietHours() {
+				return
+			}
+			message := strings.TrimSpace(strings.Trim(cleanLine, "│ \t"))
+			if p.askPermission(nil, message, []string{"OK"}, "OK") == "" {
+				return
+			}
+		} else {
+			time.Sleep(time.Duration(p.config().Delays.AutoApproveMs) * time.Millisecond)
+		}
+
+		if !p.appState.Deduplicator.ClaimAnswer("", cleanLine) {
+			return
+		}
+		if err := p.writeToTerminal(SubmitKey); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}()
+}
+
+// autoApprove sends choice without asking because of by, logging a failure
+func (p *PermissionHandler) autoApprove(by decider, choice string) {
+	errCh := p.sendAutoApprove(by, choice)
+	go func() {
+		defer p.recoverCrash()
+		if err := <-errCh; err != nil {
+			// Log error but continue operation
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}()
+}
+
+func (p *PermissionHandler) sendAutoApprove(by decider, choice string) <-chan error {
+	errCh := make(chan error, 1)
+	// Forbidden dialogs are rejected before anything approves them; this
+	// guards every automatic approval in case a new path forgets to check
+	if p.rejectPanicked() || p.rejectForbidden() {
+		close(errCh)
+		return errCh
+	}
+
+	choice = p.safeChoice(p.dialogInfo(), choice)
+
+	if summary, capped := p.countAutoApproval(p.dialogInfo()); capped {
+		close(errCh)
+		p.showDialog(choice, false, summary)
+		return errCh
+	}
+
+	p.resetRejections()
+	p.saveState()
+	p.logDecision(p.dialogInfo(), by, decisions.Approved)
+	p.decisionRecorder()(choice)
+	prompt := p.snapshotPrompt(by.rule)
+	go func() {
+		defer p.recoverCrash()
+		defer close(errCh)
+		time.Sleep(time.Duration(p.config().Delays.AutoApproveMs) * time.Millisecond)
+		if err := p.sendAnswer(prompt, choice); err != nil {
+			errCh <- fmt.Errorf("auto-approve failed: %w", err)
+			return
+		}
+	}()
+	return errCh
+}
+
+func (p *PermissionHandler) sendAutoReject() {
+	p.sendRejection(decider{rule: "auto_reject"}, p.text().AutoReject)
+}
+
+// sendRejection rejects the dialog without asking because of by, sending
+// baseMessage and the rejected command to Claude
+func (p *PermissionHandler) sendRejection(by decider, baseMessage string) {
+	info := p.dialogInfo()
+	// Find the highest numbered choice (typically 2 or 3 for reject)
+	maxChoice := findMaxRejectChoice(info.Choices)
+	p.decisionRecorder()(maxChoice)
+	id := p.logDecision(info, by, decisions.Rejected)
+	prompt := p.snapshotPrompt(by.rule)
+	count, looping := p.countRejection(info)
+	p.saveState()
+	if looping {
+		p.breakRejectionLoop(prompt, maxChoice, count)
+		return
+	}
+	rejectMsg := p.buildRejectMessage(info, by.rule, id, baseMessage)
+
+	go func() {
+		defer p.recoverCrash()
+		time.Sleep(time.Duration(p.config().Delays.AutoRejectProcessMs) * time.Millisecond)
+		p.writeRejection(prompt, maxChoice, rejectMsg)
+	}()
+}
+
+// countRejection counts the rejection without asking of the request
+// described by info, returning how many times in a row it was rejected and
+// whether that is more than --rejection-loop-limit allows
+func (p *PermissionHandler) countRejection(info parser.DialogInfo) (int, bool) {
+	request := strings.Join(strings.Fields(describeRequest(info)), " ")
+	if p.redactor != nil {
+		request = p.redactor.Redact(request)
+	}
+
+	p.rejectionMutex.Lock()
+	defer p.rejectionMutex.Unlock()
+	if request != p.rejectedRequest {
+		p.rejectedRequest, p.rejectionCount = request, 0
+	}
+	p.rejectionCount++
+	limit := p.config().RejectionLoopLimit
+	return p.rejectionCount, limit > 0 && p.rejectionCount > limit
+}
+
+// resetRejections ends the run of rejections counted by countRejection,
+// after a request is approved or the user answered a dialog
+func (p *PermissionHandler) resetRejections() {
+	p.rejectionMutex.Lock()
+	defer p.rejectionMutex.Unlock()
+	p.rejectedRequest, p.rejectionCount = "", 0
+}
+
+// breakRejectionLoop stops Claude from retrying the request of prompt,
+// rejected count times in a row: it chooses maxChoice without a message, so
+// Claude waits for the user in the terminal, pauses the auto mode, and tells
+// the user why
+func (p *PermissionHandler) breakRejectionLoop(prompt promptSnapshot, maxChoice string, count int) {
+	go func() {
+		defer p.recoverCrash()
+		time.Sleep(time.Duration(p.config().Delays.AutoRejectProcessMs) * time.Millisecond)
+		if err := p.sendAnswer(prompt, maxChoice); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}()
+	go p.pauseAutoMode()
+
+	if p.notificationCallback != nil {
+		request := describeRequest(prompt.info)
+		if p.redactor != nil {
+			request = p.redactor.Redact(request)
+		}
+		go p.notificationCallback(fmt.Sprintf(p.text().RejectionLoop, request, count))
 	}
 }
 
-func (p *PermissionHandler) sendAutoApprove(choice string) <-chan error {
-	errCh := make(chan error, 1)
+func (p *PermissionHandler) sendAutoRejectWithWait(bestChoice string) {
+	info := p.dialogInfo()
+	timeoutChoice, tellClaude := p.timeoutChoice(info)
+	record := p.decisionRecorder()
+	prompt := p.snapshotPrompt("")
+
 	go func() {
-		defer close(errCh)
-		time.Sleep(AutoApproveDelayMs * time.Millisecond)
-		if err := p.writeToTerminal(choice); err != nil {
-			errCh <- fmt.Errorf("auto-approve failed: %w", err)
+		defer p.recoverCrash()
+		countdown := fmt.Sprintf(p.text().AutoRejectCountdown, p.config().AutoRejectWait)
+		if asked, userChoice, answered := p.waitForUser(prompt, countdown, p.config().AutoRejectWait); answered {
+			p.answerAfterCountdown(asked, userChoice, record)
 			return
 		}
+		// Timeout expired, proceed with auto-reject
+		record(timeoutChoice)
+		prompt.decidedBy = "auto_reject_wait"
+		if tellClaude {
+			p.writeAutoRejectChoice(prompt, timeoutChoice)
+			return
+		}
+		p.logDecision(info, decider{rule: "auto_reject_wait"}, decisions.Rejected)
+		if err := p.sendAnswer(prompt, timeoutChoice); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: auto-reject failed: %v\n", err)
+		}
 	}()
-	return errCh
 }
 
-func (p *PermissionHandler) sendAutoReject() {
-	// Find the highest numbered choice (typically 2 or 3 for reject)
-	maxChoice := "2"
-	for num := 3; num >= 2; num-- {
-		numStr := fmt.Sprintf("%d", num)
-		if _, exists := p.appState.Prompt.CollectedChoices[numStr]; exists {
-			maxChoice = numStr
-			break
-		}
+// chooseDefault returns the choice automatic approvals send, following
+// --default-choice. With --safe-choices, a choice that would widen Claude's
+// permission rules is replaced as never-dont-ask-again would pick.
+func (p *PermissionHandler) chooseDefault() string {
+	best := choice.ChooseFromState(p.appState, p.config().DefaultChoice, p.patterns)
+	if p.config().SafeChoices && choice.Widens(p.dialogInfo().Choices[best]) {
+		best = choice.ChooseFromState(p.appState, config.DefaultChoiceNeverAlways, p.patterns)
 	}
+	return best
+}
 
-	go func() {
-		time.Sleep(AutoRejectProcessDelayMs * time.Millisecond)
-		// Send the max choice number without newline (like dialog mode)
-		if err := p.writeToTerminal(maxChoice); err != nil {
-			return
+// safeChoice returns userChoice, or with --safe-choices, the approve-once
+// choice in its place if it would widen Claude's permission rules, so no
+// path, automatic or answered in a dialog, sends "don't ask again". Without
+// an approve-once choice, the request is rejected.
+func (p *PermissionHandler) safeChoice(info parser.DialogInfo, userChoice string) string {
+	if !p.config().SafeChoices || !choice.Widens(info.Choices[userChoice]) {
+		return userChoice
+	}
+	if once := info.FirstChoice(parser.ChoiceApproveOnce); once != "" {
+		return once
+	}
+	return findMaxRejectChoice(info.Choices)
+}
+
+// timeoutChoice returns the choice --auto-reject-wait-choice picks in the
+// dialog described by info when the countdown ends, reporting whether the
+// rejection message should be typed after it. A choice number that doesn't
+// exist or would approve falls back to the last reject choice.
+func (p *PermissionHandler) timeoutChoice(info parser.DialogInfo) (string, bool) {
+	switch choice := p.config().AutoRejectWaitChoice; choice {
+	case config.TimeoutChoiceNo:
+		if first := info.FirstChoice(parser.ChoiceReject); first != "" {
+			return first, false
 		}
+	case config.TimeoutChoiceTellClaude, "":
+	default:
+		if label, ok := info.Choices[choice]; ok && !isApproval(label) {
+			return choice, parser.ClassifyChoice(label) == parser.ChoiceReject
+		}
+	}
+	return findMaxRejectChoice(info.Choices), true
+}
 
-		// Wait for the choice to be processed
-		time.Sleep(AutoRejectChoiceDelayMs * time.Millisecond)
+// sendAutoApproveWithWait shows the dialog with a countdown and approves it
+// with bestChoice if the user doesn't answer in time
+func (p *PermissionHandler) sendAutoApproveWithWait(bestChoice string) {
+	info := p.dialogInfo()
+	record := p.decisionRecorder()
+	prompt := p.snapshotPrompt("")
 
-		// Now send the rejection message
-		rejectMsg := p.buildAutoRejectMessage()
-		if err := p.writeToTerminal(rejectMsg); err != nil {
+	go func() {
+		defer p.recoverCrash()
+		countdown := fmt.Sprintf(p.text().AutoApproveCountdown, p.config().AutoApproveWait)
+		if asked, userChoice, answered := p.waitForUser(prompt, countdown, p.config().AutoApproveWait); answered {
+			p.answerAfterCountdown(asked, userChoice, record)
 			return
 		}
-
-		// Send carriage return separately
-		time.Sleep(AutoRejectCRDelayMs * time.Millisecond)
-		if err := p.writeToTerminal(SubmitKey); err != nil {
-			// Carriage return failed, continue silently
+		// Timeout expired; forbidden requests were rejected before the dialog
+		record(bestChoice)
+		p.logDecision(info, decider{rule: "auto_approve_wait"}, decisions.Approved)
+		prompt.decidedBy = "auto_approve_wait"
+		if err := p.sendAnswer(prompt, bestChoice); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: auto-approve failed: %v\n", err)
+			return
 		}
+		p.handleDialogCooldown()
 	}()
 }
 
-func (p *PermissionHandler) sendAutoRejectWithWait(bestChoice string) {
-	maxChoice := findMaxRejectChoice(p.appState.Prompt.CollectedChoices)
-	waitDuration := time.Duration(*autoRejectWait) * time.Second
+// waitForUser shows the dialog about prompt with countdown above it and
+// returns the user's choice, with prompt updated by the dialog, reporting
+// false if seconds pass without one. A dialog rejected by dcode panic is
+// reported answered, with no choice.
+func (p *PermissionHandler) waitForUser(prompt promptSnapshot, countdown string, seconds int) (promptSnapshot, string, bool) {
+	type answer struct {
+		prompt promptSnapshot
+		choice string
+	}
+	userChoiceChan := make(chan answer, 1)
+	done := make(chan bool, 1)
 
+	// Show dialog with countdown in a separate goroutine, on its own copy of
+	// prompt, since it may still be open when the countdown ends
+	asked := prompt
 	go func() {
-		userChoiceChan := make(chan string, 1)
-		done := make(chan bool, 1)
-
-		// Show dialog with countdown in a separate goroutine
-		go func() {
-			baseMessage := p.buildDialogMessage(p.appState.Prompt.LastLine, p.appState.Prompt.Context, p.appState.Prompt.TriggerReason)
-			countdownMsg := fmt.Sprintf("This will auto-reject in %d seconds...\n\n%s", *autoRejectWait, baseMessage)
-			buttons := p.extractButtons()
-			defaultButton := ""
-			if len(buttons) > 0 {
-				defaultButton = buttons[0]
-			}
-
-			var userChoice string
-			if p.permissionCallback != nil {
-				userChoice = p.permissionCallback(countdownMsg, buttons, defaultButton)
-			} else {
-				// No permission callback set, cannot show dialog
-				userChoice = ""
-			}
-
-			select {
-			case userChoiceChan <- userChoice:
-			case <-done:
-				// Timeout already occurred, don't send
-			}
-		}()
+		defer p.recoverCrash()
+		baseMessage := p.buildDialogMessage(p.appState.Prompt.LastLine, p.appState.Prompt.Context, p.appState.Prompt.TriggerReason)
+		countdownMsg := countdown + "\n\n" + baseMessage
+		buttons := p.extractButtons()
+		defaultButton := p.defaultButton(buttons)
 
-		// Wait for either user choice or timeout
-		select {
-		case userChoice := <-userChoiceChan:
-			// User made a choice before timeout
-			close(done)
-			if err := p.writeToTerminal(userChoice); err != nil {
+		var userChoice string
+		if p.permissionCallback != nil {
+			var answered bool
+			userChoice, answered = p.awaitAnswer(func() string {
+				return p.askPermission(&asked, countdownMsg, buttons, defaultButton)
+			})
+			if !answered {
 				return
 			}
-			p.handleDialogCooldown()
+		} else {
+			// No permission callback set, cannot show dialog
+			userChoice = ""
+		}
 
-		case <-time.After(waitDuration):
-			// Timeout expired, proceed with auto-reject
-			close(done)
-			p.writeAutoRejectChoice(maxChoice)
+		select {
+		case userChoiceChan <- answer{asked, userChoice}:
+		case <-done:
+			// Timeout already occurred, don't send
 		}
 	}()
+
+	// Wait for either user choice or timeout
+	select {
+	case answered := <-userChoiceChan:
+		close(done)
+		return answered.prompt, answered.choice, true
+	case <-time.After(time.Duration(seconds) * time.Second):
+		close(done)
+		if p.permissionCallback != nil && !p.dialogOpen.CompareAndSwap(true, false) {
+			// dcode panic already rejected the dialog
+			return prompt, "", true
+		}
+		return prompt, "", false
+	}
+}
+
+// answerAfterCountdown answers prompt with the choice the user made before a
+// countdown ran out
+func (p *PermissionHandler) answerAfterCountdown(prompt promptSnapshot, userChoice string, record func(choice string)) {
+	if userChoice == "" {
+		return
+	}
+	info := prompt.info
+	if _, _, forbidden := p.forbidRule(info); forbidden && isApproval(info.Choices[userChoice]) {
+		userChoice = findMaxRejectChoice(info.Choices)
+	}
+	userChoice = p.safeChoice(info, userChoice)
+	if err := p.sendAnswer(prompt, userChoice); err != nil {
+		return
+	}
+	record(userChoice)
+	p.handleDialogCooldown()
+
+	if p.config().SyncSettings {
+		p.syncSettings(info, info.Choices[userChoice], false)
+	}
+	if p.config().RememberDecisions {
+		p.offerToRemember(info, info.Choices[userChoice])
+	}
 }
 
 // Dialog parsing constants
@@ -564,58 +2448,565 @@ func isValidCommandLine(line string) bool {
 	return true
 }
 
-// buildAutoRejectMessage creates auto-reject message with command details
-func (p *PermissionHandler) buildAutoRejectMessage() string {
-	// Get command details from dialog context using parseDialogBox
-	if len(p.appState.Prompt.Context) > 0 {
-		dialogInfo := choice.ParseDialogBox(p.appState.Prompt.Context, p.patterns)
+// buildAutoRejectMessage creates auto-reject message with the details of the
+// dialog described by info, logging the rejection
+func (p *PermissionHandler) buildAutoRejectMessage(info parser.DialogInfo) string {
+	id := p.logDecision(info, decider{rule: "auto_reject_wait"}, decisions.Rejected)
+	return p.buildRejectMessage(info, "auto_reject_wait", id, p.text().AutoReject)
+}
 
-		// Build command details from parsed dialog box
-		if len(dialogInfo.CommandDetails) > 0 {
-			var builder strings.Builder
+// logDecision records in the decision log that the dialog described by info
+// was answered without asking because of by, returning the entry's ID, or ""
+// if nothing was logged
+func (p *PermissionHandler) logDecision(info parser.DialogInfo, by decider, decision string) string {
+	request := describeRequest(info)
+	if p.redactor != nil {
+		request = p.redactor.Redact(request)
+	}
+	p.addToDigest(request, decision)
+	if p.decisionLog == nil {
+		return ""
+	}
 
-			for _, detail := range dialogInfo.CommandDetails {
-				if strings.TrimSpace(detail) == "" {
-					continue
-				}
+	entry := decisions.Entry{
+		ID:       decisions.NewID(),
+		Time:     p.now(),
+		Request:  request,
+		Risk:     describeRisk(info),
+		Decision: decision,
+		Rule:     by.rule,
+		Detail:   by.detail,
+	}
+	if err := p.decisionLog.Add(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to log decision: %v\n", err)
+		return ""
+	}
+	return entry.ID
+}
 
-				if builder.Len() > 0 {
-					builder.WriteString("\n")
-				}
-				builder.WriteString(strings.TrimSpace(detail))
-			}
+// addToDigest adds request, answered with decision without asking, to the
+// next --digest-minutes notification, which is posted that many minutes after
+// the first decision it lists
+func (p *PermissionHandler) addToDigest(request, decision string) {
+	if p.config().DigestMinutes <= 0 || p.notificationCallback == nil {
+		return
+	}
 
-			if builder.Len() > 0 {
-				return fmt.Sprintf("Rejected command:\n%s\n\n%s", builder.String(), AutoRejectBaseMessage)
-			}
+	p.digestMutex.Lock()
+	defer p.digestMutex.Unlock()
+	if len(p.digest) == 0 {
+		time.AfterFunc(time.Duration(p.config().DigestMinutes)*time.Minute, p.postDigest)
+	}
+	p.digest = append(p.digest, decision+": "+request)
+}
+
+// postDigest posts a notification summarizing the decisions made without
+// asking since the last one, if there were any
+func (p *PermissionHandler) postDigest() {
+	p.digestMutex.Lock()
+	digest := p.digest
+	p.digest = nil
+	p.digestMutex.Unlock()
+	if len(digest) == 0 || p.notificationCallback == nil {
+		return
+	}
+
+	counts := map[string]int{}
+	for _, line := range digest {
+		decision, _, _ := strings.Cut(line, ": ")
+		counts[decision]++
+	}
+	message := fmt.Sprintf("dcode answered %d requests without asking (%d approved, %d rejected):",
+		len(digest), counts[decisions.Approved], counts[decisions.Rejected])
+	if len(digest) > DigestLines {
+		message += fmt.Sprintf("\n… %d earlier", len(digest)-DigestLines)
+		digest = digest[len(digest)-DigestLines:]
+	}
+	for _, line := range digest {
+		message += "\n• " + line
+	}
+	p.notificationCallback(message)
+}
+
+// countAutoApproval counts the approval without asking of the request
+// described by info toward --max-auto-approvals. Once the cap is reached it
+// reports true instead, with a summary of the approvals for the dialog that
+// must be shown.
+func (p *PermissionHandler) countAutoApproval(info parser.DialogInfo) (string, bool) {
+	p.autoApprovedMutex.Lock()
+	defer p.autoApprovedMutex.Unlock()
+
+	limit := p.config().MaxAutoApprovals
+	if limit <= 0 {
+		return "", false
+	}
+	if len(p.autoApproved) < limit {
+		// Masked here, since the requests are kept in the session state file
+		request := describeRequest(info)
+		if p.redactor != nil {
+			request = p.redactor.Redact(request)
+		}
+		p.autoApproved = append(p.autoApproved, request)
+		return "", false
+	}
+
+	text := p.text()
+	summary := fmt.Sprintf(text.AutoApprovalLimit, len(p.autoApproved))
+	shown := p.autoApproved
+	if len(shown) > AutoApprovalSummaryLines {
+		summary += "\n" + fmt.Sprintf(text.AutoApprovalsEarlier, len(shown)-AutoApprovalSummaryLines)
+		shown = shown[len(shown)-AutoApprovalSummaryLines:]
+	}
+	for _, request := range shown {
+		summary += "\n• " + request
+	}
+	return summary + "\n\n" + text.AutoApprovalContinue, true
+}
+
+// resetAutoApprovals starts counting approvals without asking from zero,
+// after the user answered a dialog
+func (p *PermissionHandler) resetAutoApprovals() {
+	p.autoApprovedMutex.Lock()
+	defer p.autoApprovedMutex.Unlock()
+	p.autoApproved = nil
+}
+
+// describeRequest renders the dialog described by info as its tool and its
+// command or files, e.g. "Bash: go test ./..."
+func describeRequest(info parser.DialogInfo) string {
+	request := info.ToolType
+	if request == "" {
+		request = "Unknown tool"
+	}
+	if len(info.FilePaths) > 0 {
+		request += ": " + strings.Join(info.FilePaths, ", ")
+	} else if len(info.CommandLines) > 0 {
+		request += ": " + info.CommandLines[0]
+	}
+	return request
+}
+
+// describeRisk renders the rated risk of the dialog described by info with
+// its reason, e.g. "high (force push)"
+func describeRisk(info parser.DialogInfo) string {
+	if info.RiskReason == "" {
+		return info.Risk.String()
+	}
+	return info.Risk.String() + " (" + info.RiskReason + ")"
+}
+
+// buildRejectMessage prefixes baseMessage with the details of the command in
+// the dialog described by info and follows it with rule, which rejected the
+// request, and the decision log ID, if any. A reject_message template
+// replaces this format.
+func (p *PermissionHandler) buildRejectMessage(info parser.DialogInfo, rule, id, baseMessage string) string {
+	// Get command details from the parsed dialog box
+	var builder strings.Builder
+	for _, detail := range info.CommandLines {
+		if strings.TrimSpace(detail) == "" {
+			continue
+		}
+
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
 		}
+		builder.WriteString(strings.TrimSpace(detail))
+	}
+
+	if p.config().RejectMessage != "" {
+		return config.ExpandRejectMessage(p.config().RejectMessage, config.Rejection{
+			Tool:    info.ToolType,
+			Command: builder.String(),
+			Reason:  baseMessage,
+			Rule:    rule,
+			Time:    p.now().Format(time.RFC3339),
+			ID:      id,
+		})
+	}
+
+	text := p.text()
+	message := baseMessage + "\n\n" + fmt.Sprintf(text.RejectedBy, rule)
+	if id != "" {
+		message = baseMessage + "\n\n" + fmt.Sprintf(text.RejectedByExplained, rule, id)
+	}
+	if builder.Len() > 0 {
+		return fmt.Sprintf(text.RejectedCommand, builder.String()) + "\n\n" + message
 	}
 
-	return AutoRejectBaseMessage
+	return message
 }
 
-func (p *PermissionHandler) writeAutoRejectChoice(maxChoice string) {
+func (p *PermissionHandler) writeAutoRejectChoice(prompt promptSnapshot, maxChoice string) {
+	p.writeRejection(prompt, maxChoice, p.buildAutoRejectMessage(prompt.info))
+}
+
+// writeRejection selects the reject choice of prompt, then types rejectMsg
+// and submits it
+func (p *PermissionHandler) writeRejection(prompt promptSnapshot, maxChoice, rejectMsg string) {
 	// Send the max choice number without newline (like dialog mode)
-	if err := p.writeToTerminal(maxChoice); err != nil {
+	if err := p.sendAnswer(prompt, maxChoice); err != nil {
 		return
 	}
 
 	// Wait for the choice to be processed
-	time.Sleep(AutoRejectChoiceDelayMs * time.Millisecond)
+	time.Sleep(time.Duration(p.config().Delays.AutoRejectChoiceMs) * time.Millisecond)
 
 	// Now send the rejection message
-	rejectMsg := p.buildAutoRejectMessage()
 	if err := p.writeToTerminal(rejectMsg); err != nil {
 		return
 	}
 
 	// Send carriage return separately
-	time.Sleep(AutoRejectCRDelayMs * time.Millisecond)
+	time.Sleep(time.Duration(p.config().Delays.AutoRejectCRMs) * time.Millisecond)
 	if err := p.writeToTerminal(SubmitKey); err != nil {
 		// Carriage return failed, continue silently
 	}
 }
 
+// errAlreadyAnswered is returned by sendAnswer for a prompt that was already
+// answered
+var errAlreadyAnswered = errors.New("prompt already answered")
+
+// promptSnapshot is the prompt an answer was decided for. It is taken with
+// snapshotPrompt when the answer is decided, while Claude's output is being
+// handled, since the next prompt overwrites the prompt state while the answer
+// is still waiting to be sent.
+type promptSnapshot struct {
+	serial     int
+	requestID  string
+	detectedAt time.Time
+	dialogType types.DialogType
+	info       parser.DialogInfo
+	decidedBy  string        // Rule or option that answers without asking, or "" for the user
+	waited     time.Duration // How long dialogs about the prompt waited for the user to answer
+	screenshot string        // Image of the prompt's dialog, with --screenshot-dir, or ""
+}
+
+// snapshotPrompt returns the current prompt as a promptSnapshot, to be
+// answered because of decidedBy
+func (p *PermissionHandler) snapshotPrompt(decidedBy string) promptSnapshot {
+	return promptSnapshot{
+		serial:     p.appState.Prompt.Serial,
+		requestID:  p.appState.Prompt.RequestID,
+		detectedAt: p.appState.Prompt.DetectedAt,
+		dialogType: p.appState.Prompt.DialogType,
+		info:       p.dialogInfo(),
+		decidedBy:  decidedBy,
+	}
+}
+
+// identifier returns what tells the prompt's dialog apart from others: its
+// kind and its box, which stay the same when the prompt is re-rendered
+func (s promptSnapshot) identifier() string {
+	return string(s.dialogType) + "|" + strings.Join(s.info.RawContent, "\n")
+}
+
+// tag returns event marked as being about the prompt, for emit
+func (s promptSnapshot) tag(event events.Event) events.Event {
+	event.Prompt, event.RequestID, event.Kind = s.serial, s.requestID, string(s.dialogType)
+	return event
+}
+
+// sendAnswer types answer, a choice or key that answers prompt, into Claude.
+// Every answer goes through here, so each prompt is answered only once even
+// when two paths decide on it or it is re-rendered; input that follows an
+// answer, such as a rejection message, is written with writeToTerminal once
+// the answer was sent.
+func (p *PermissionHandler) sendAnswer(prompt promptSnapshot, answer string) error {
+	if !p.appState.Deduplicator.ClaimAnswer(strconv.Itoa(prompt.serial), prompt.identifier()) {
+		debug.Debug("not sending answer to a prompt already answered", "answer", answer, "prompt", prompt.serial)
+		p.countAnswerRetry()
+		return errAlreadyAnswered
+	}
+	decidedBy := answerDecidedBy(prompt)
+	if p.recordsEvents() {
+		event := p.requestEvent(prompt.info, events.Decision)
+		event.Choice = answer
+		event.Label = prompt.info.Choices[answer]
+		event.Action = answerAction(event.Label)
+		event.DecidedBy = decidedBy
+		p.emit(prompt.tag(event))
+	}
+	injecting := p.now()
+	if err := p.writeToTerminal(answer); err != nil {
+		p.emit(prompt.tag(events.Event{Type: events.Error, Choice: answer, Message: err.Error()}))
+		p.countAnswerRetry()
+		return err
+	}
+	p.traceStep("inject", injecting)
+	p.emit(prompt.tag(events.Event{Type: events.Injection, Choice: answer, LatencyMs: p.now().Sub(prompt.detectedAt).Milliseconds()}))
+	if p.auditLog != nil || p.tracer != nil || p.systemLog != nil {
+		entry := p.answerEntry(prompt, decidedBy, answer)
+		p.auditAnswer(entry)
+		p.logAnswer(entry)
+		p.endTrace(entry)
+	}
+	return nil
+}
+
+// answerEntry describes answer, just sent to prompt because of decidedBy, for
+// the audit log
+func (p *PermissionHandler) answerEntry(prompt promptSnapshot, decidedBy, answer string) audit.Entry {
+	info := prompt.info
+	request := describeRequest(info)
+	dialog := append([]string(nil), info.RawContent...)
+	if p.redactor != nil {
+		request = p.redactor.Redact(request)
+		for i, line := range dialog {
+			dialog[i] = p.redactor.Redact(line)
+		}
+	}
+	label := info.Choices[answer]
+	mode := p.modeName()
+
+	id := prompt.requestID
+	if id == "" {
+		id = audit.NewID()
+	}
+	now := p.now()
+	dir := projectDir()
+	git := p.gitInfo(dir)
+	if p.redactor != nil {
+		git.Repo = p.redactor.Redact(git.Repo)
+		git.Branch = p.redactor.Redact(git.Branch)
+	}
+	return audit.Entry{
+		ID:         id,
+		Time:       now,
+		Source:     audit.SourceWrapper,
+		Session:    os.Getpid(),
+		Dir:        dir,
+		Repo:       git.Repo,
+		Branch:     git.Branch,
+		Commit:     git.Commit,
+		Prompt:     string(prompt.dialogType),
+		Tool:       info.ToolType,
+		Request:    request,
+		Risk:       describeRisk(info),
+		Choice:     answer,
+		Label:      label,
+		Action:     answerAction(label),
+		DecidedBy:  decidedBy,
+		Mode:       mode,
+		LatencyMs:  now.Sub(prompt.detectedAt).Milliseconds(),
+		WaitMs:     prompt.waited.Milliseconds(),
+		Dialog:     dialog,
+		Screenshot: prompt.screenshot,
+	}
+}
+
+// answerAction returns whether the choice labelled label approves, rejects,
+// or otherwise answers a prompt, as audit.Approved, Rejected, or Answered
+func answerAction(label string) string {
+	switch parser.ClassifyChoice(label) {
+	case parser.ChoiceApproveOnce, parser.ChoiceApproveAlways:
+		return audit.Approved
+	case parser.ChoiceReject:
+		return audit.Rejected
+	}
+	return audit.Answered
+}
+
+// answerDecidedBy returns the rule or option that answered prompt without
+// asking, or audit.User
+func answerDecidedBy(prompt promptSnapshot) string {
+	if prompt.decidedBy == "" {
+		return audit.User
+	}
+	return prompt.decidedBy
+}
+
+// requestEvent returns an event of eventType naming the request of the
+// dialog described by info, with secrets masked
+func (p *PermissionHandler) requestEvent(info parser.DialogInfo, eventType string) events.Event {
+	request := describeRequest(info)
+	if p.redactor != nil {
+		request = p.redactor.Redact(request)
+	}
+	return events.Event{Type: eventType, Tool: info.ToolType, Request: request}
+}
+
+// modeName returns the mode prompts are answered in, or "panic" after dcode
+// panic
+func (p *PermissionHandler) modeName() string {
+	if p.panicked.Load() {
+		return "panic"
+	}
+	return config.CurrentMode(p.config()).String()
+}
+
+// recordsEvents reports whether events are written anywhere: to the
+// --event-stream, to dcode tail, as markers in the --transcript, or as
+// --telemetry counts
+func (p *PermissionHandler) recordsEvents() bool {
+	return p.eventStream != nil || p.liveEvents != nil || p.transcript != nil || p.telemetry != nil
+}
+
+// emit writes event about the current prompt to the --event-stream, dcode
+// tail, and the --transcript, and counts it for --telemetry, if any
+func (p *PermissionHandler) emit(event events.Event) {
+	if !p.recordsEvents() {
+		return
+	}
+	event.Time = p.now()
+	event.Session = os.Getpid()
+	if event.Prompt == 0 {
+		event.Prompt = p.appState.Prompt.Serial
+	}
+	if event.RequestID == "" {
+		event.RequestID = p.appState.Prompt.RequestID
+	}
+	if event.Kind == "" {
+		event.Kind = string(p.appState.Prompt.DialogType)
+	}
+	if err := p.eventStream.Emit(event); err != nil {
+		debug.Warn("failed to write event", "error", err)
+	}
+	p.liveEvents.Emit(event)
+	if err := p.transcript.Event(event); err != nil {
+		debug.Warn("failed to write transcript", "error", err)
+	}
+	if p.telemetry != nil {
+		p.telemetry.Record(event, p.modeName())
+	}
+}
+
+// recordTranscriptLine adds cleanLine, a line of Claude's output, to the
+// --transcript, with secrets masked
+func (p *PermissionHandler) recordTranscriptLine(cleanLine string) {
+	if p.redactor != nil {
+		cleanLine = p.redactor.Redact(cleanLine)
+	}
+	if err := p.transcript.Line(cleanLine); err != nil {
+		debug.Warn("failed to write transcript", "error", err)
+	}
+}
+
+// auditAnswer adds entry to the --audit-log file, if any
+func (p *PermissionHandler) auditAnswer(entry audit.Entry) {
+	if p.auditLog == nil {
+		return
+	}
+	if err := p.auditLog.Add(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// logAnswer mirrors entry to the --syslog system log, if any
+func (p *PermissionHandler) logAnswer(entry audit.Entry) {
+	if p.systemLog == nil {
+		return
+	}
+	if err := p.systemLog.Log(entry); err != nil {
+		debug.Warn("failed to write to the system log", "error", err)
+	}
+}
+
+// endTrace ends the current prompt's trace with the answer in entry and sends
+// it to the --otlp-endpoint collector
+func (p *PermissionHandler) endTrace(entry audit.Entry) {
+	p.traceMutex.Lock()
+	trace := p.trace
+	p.trace = nil
+	p.traceMutex.Unlock()
+	if trace == nil {
+		return
+	}
+
+	trace.SetAttribute("dcode.prompt", entry.Prompt)
+	trace.SetAttribute("dcode.request_id", entry.ID)
+	trace.SetAttribute("dcode.tool", entry.Tool)
+	trace.SetAttribute("dcode.choice", entry.Choice)
+	trace.SetAttribute("dcode.decision", entry.Action)
+	trace.SetAttribute("dcode.decided_by", entry.DecidedBy)
+	trace.SetAttribute("dcode.mode", entry.Mode)
+	spans := trace.End(entry.Time)
+	go func() {
+		defer p.recoverCrash()
+		ctx, cancel := context.WithTimeout(context.Background(), tracing.ExportTimeout)
+		defer cancel()
+		if err := p.tracer.Export(ctx, spans); err != nil {
+			debug.Warn("failed to export trace", "error", err)
+		}
+	}()
+}
+
+// recoverCrash, deferred at the start of a goroutine, writes a report of a
+// panic in it before letting the panic crash dcode
+func (p *PermissionHandler) recoverCrash() {
+	if recovered := recover(); recovered != nil {
+		if path := p.reportCrash(recovered, runtimedebug.Stack()); path != "" {
+			fmt.Fprintf(os.Stderr, "\r\ndcode crashed; please attach the report saved to %s to a bug report\r\n", path)
+		}
+		panic(recovered)
+	}
+}
+
+// reportCrash writes a report of a panic with recovered and stack, with
+// secrets masked, and notifies the user where it is. It returns the report's
+// path, or "" if there's no crash directory or the report couldn't be written.
+func (p *PermissionHandler) reportCrash(recovered any, stack []byte) string {
+	message := fmt.Sprint(recovered)
+	if p.redactor != nil {
+		message = p.redactor.Redact(message)
+	}
+	p.emit(events.Event{Type: events.Error, Message: "crashed: " + message})
+	if p.crashDir == "" {
+		return ""
+	}
+	report := crash.Report{
+		Time:    p.now(),
+		Version: version.Get().String(),
+		Panic:   message,
+		Stack:   string(stack),
+		State:   p.crashState(),
+		Context: p.recentOutput(),
+	}
+	if p.redactor != nil {
+		report.Context = p.redactor.RedactAll(report.Context)
+	}
+	path, err := crash.Write(p.crashDir, report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\r\ndcode crashed and couldn't save a crash report: %v\r\n", err)
+		return ""
+	}
+	if p.notificationCallback != nil {
+		p.notificationCallback("dcode crashed. The report is in " + path)
+	}
+	return path
+}
+
+// crashState describes the session's mode and the prompt being collected for
+// a crash report
+func (p *PermissionHandler) crashState() map[string]string {
+	prompt := p.appState.Prompt
+	choices := make([]string, 0, len(prompt.CollectedChoices))
+	for number := range prompt.CollectedChoices {
+		choices = append(choices, number)
+	}
+	sort.Strings(choices)
+	return map[string]string{
+		"mode":           config.CurrentMode(p.config()).String(),
+		"panicked":       strconv.FormatBool(p.panicked.Load()),
+		"auto_paused":    strconv.FormatBool(p.autoPaused.Load()),
+		"prompt":         string(prompt.DialogType),
+		"prompt_serial":  strconv.Itoa(prompt.Serial),
+		"prompt_started": strconv.FormatBool(prompt.Started),
+		"choices":        strings.Join(choices, ","),
+		"box_depth":      strconv.Itoa(p.boxDepth),
+	}
+}
+
+// recentOutput returns the last lines of Claude's output, unless the line
+// processor still holds them
+func (p *PermissionHandler) recentOutput() []string {
+	if !p.lineMutex.TryLock() {
+		return nil
+	}
+	defer p.lineMutex.Unlock()
+	return append([]string(nil), p.contextLines...)
+}
+
 func (p *PermissionHandler) writeToTerminal(text string) error {
 	_, err := p.ptmx.WriteString(text)
 	if err != nil {
@@ -627,66 +3018,203 @@ func (p *PermissionHandler) writeToTerminal(text string) error {
 
 func (p *PermissionHandler) handleDialogCooldown() {
 	// Set cooldown in deduplication manager
-	p.appState.Deduplicator.SetDialogCooldown("main_dialog")
+	p.appState.Deduplicator.SetDialogCooldown(types.DialogCooldownKey)
 
 	go func() {
-		time.Sleep(DialogResetDelayMs * time.Millisecond)
+		defer p.recoverCrash()
+		time.Sleep(time.Duration(p.config().Delays.DialogResetMs) * time.Millisecond)
 		p.appState.Prompt.JustShown = false
-		p.appState.Deduplicator.ClearCooldown("main_dialog")
+		p.appState.Deduplicator.ClearCooldown(types.DialogCooldownKey)
 	}()
 }
 
-func (p *PermissionHandler) showDialog(bestChoice string) {
+// showDialog asks the user to answer the dialog, with note, if any, above
+// the request. With typedConfirmation, an approval only goes through once the
+// user also types TypedConfirmationPhrase.
+func (p *PermissionHandler) showDialog(bestChoice string, typedConfirmation bool, note string) {
+	record := p.decisionRecorder()
+	info := p.dialogInfo()
+	prompt := p.snapshotPrompt("")
 	go func() {
+		defer p.recoverCrash()
 		message := p.buildDialogMessage(p.appState.Prompt.LastLine, p.appState.Prompt.Context, p.appState.Prompt.TriggerReason)
+		if note != "" {
+			message = note + "\n\n" + message
+		}
 		buttons := p.extractButtons()
-		defaultButton := ""
-		if len(buttons) > 0 {
-			defaultButton = buttons[0]
+		defaultButton := p.defaultButton(buttons)
+		temporaryButton := 0
+		if !typedConfirmation && p.grantsTemporaryApproval(info) {
+			buttons, temporaryButton = p.addTemporaryApprovalButton(buttons, info)
+		}
+		neverButton := 0
+		if p.offersNeverAllow(info) {
+			buttons, neverButton = addNeverAllowButton(buttons, p.text().NeverAllowButton)
+		}
+		delegateButton := 0
+		if p.offersAskSomeoneElse() {
+			buttons = append(buttons, p.text().AskSomeoneElseButton)
+			delegateButton = len(buttons)
 		}
 
 		var userChoice string
 		if p.permissionCallback != nil {
-			userChoice = p.permissionCallback(message, buttons, defaultButton)
+			var answered bool
+			userChoice, answered = p.awaitAnswer(func() string {
+				return p.askPermission(&prompt, message, buttons, defaultButton)
+			})
+			if !answered {
+				return
+			}
 		} else {
 			// No permission callback set, cannot show dialog
 			userChoice = ""
 		}
 
+		if delegateButton > 0 {
+			if _, delegated := resolveAddedButton(userChoice, delegateButton); delegated {
+				p.answerRemotely(prompt, p.text().DelegatedPrefix+message, p.extractButtons(), record)
+				return
+			}
+		}
+		never := false
+		if neverButton > 0 {
+			userChoice, never = resolveAddedButton(userChoice, neverButton)
+		}
+		temporary := false
+		if never {
+			userChoice = findMaxRejectChoice(info.Choices)
+		} else if temporaryButton > 0 {
+			userChoice, temporary = resolveTemporaryApproval(userChoice, temporaryButton, info)
+		}
+
+		if typedConfirmation && isApproval(info.Choices[userChoice]) && !p.confirmTyped(info) {
+			userChoice = findMaxRejectChoice(info.Choices)
+		}
+		if _, _, forbidden := p.forbidRule(info); forbidden && isApproval(info.Choices[userChoice]) {
+			userChoice = findMaxRejectChoice(info.Choices)
+		}
+		userChoice = p.safeChoice(info, userChoice)
+
 		if userChoice != "" {
-			if err := p.writeToTerminal(userChoice); err != nil {
+			if err := p.sendAnswer(prompt, userChoice); err != nil {
 				return
 			}
 
+			record(userChoice)
 			p.handleDialogCooldown()
+
+			if p.config().SyncSettings {
+				p.syncSettings(info, info.Choices[userChoice], never)
+			}
+			if temporary {
+				until := p.now().Add(time.Duration(p.config().TemporaryApprovalMinutes) * time.Minute)
+				p.temporaryApprovals.Grant(approvals.Scope(info), until)
+				p.saveState()
+			}
+			if isApproval(info.Choices[userChoice]) && p.cachesApproval(info) {
+				if err := p.approvalCache.Add(requestKey(info), p.approvalCacheTTL(), p.now()); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to cache approval: %v\n", err)
+				}
+			}
+			if p.config().RememberDecisions && !never && !temporary {
+				p.offerToRemember(info, info.Choices[userChoice])
+			}
 		}
 	}()
 }
 
-// findMaxRejectChoice finds the highest numbered choice for auto-reject (typically 2 or 3)
+// offerToRemember asks whether to add the answer labeled label, given in the
+// dialog described by info, to the config file as a rule matching the same
+// request. Answers that already last, such as "don't ask again", and
+// approvals of high-risk requests aren't offered.
+func (p *PermissionHandler) offerToRemember(info parser.DialogInfo, label string) {
+	if p.rulesFile == "" || p.permissionCallback == nil {
+		return
+	}
+	text := p.text()
+	list, question := config.RuleListDeny, text.RememberRejection
+	switch kind := parser.ClassifyChoice(label); {
+	case kind == parser.ChoiceApproveOnce && info.Risk != parser.RiskHigh:
+		list, question = config.RuleListApprove, text.RememberApproval
+	case kind != parser.ChoiceReject:
+		return
+	}
+	rule, ok := config.ExactRule(info)
+	if !ok {
+		return
+	}
+
+	message := fmt.Sprintf(question, describeRequest(info), p.rulesFile)
+	if p.askPermission(nil, message, []string{text.NotNowButton, text.RememberButton}, text.NotNowButton) != "2" {
+		return
+	}
+	if _, err := config.AddRule(p.rulesFile, list, config.DenyRule{Rule: rule}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remember the answer: %v\n", err)
+	}
+}
+
+// isApproval reports whether the choice labeled label approves the request
+func isApproval(label string) bool {
+	kind := parser.ClassifyChoice(label)
+	return kind == parser.ChoiceApproveOnce || kind == parser.ChoiceApproveAlways
+}
+
+// confirmTyped asks the user to type TypedConfirmationPhrase to approve the
+// risky dialog described by info, reporting whether they did
+func (p *PermissionHandler) confirmTyped(info parser.DialogInfo) bool {
+	if p.textInputCallback == nil {
+		return false
+	}
+
+	text := p.text()
+	risk := fmt.Sprintf(text.RiskLevel, info.Risk)
+	if info.RiskReason != "" {
+		risk += " (" + info.RiskReason + ")"
+	}
+	message := fmt.Sprintf(text.TypedConfirmation, risk, TypedConfirmationPhrase)
+	typed, ok := p.textInputCallback(message)
+	return ok && strings.EqualFold(strings.TrimSpace(typed), TypedConfirmationPhrase)
+}
+
+// findMaxRejectChoice finds the choice for auto-reject: the last choice classified
+// as a rejection, or else the highest numbered choice (typically 2 or 3)
 func findMaxRejectChoice(choices map[string]string) string {
+	info := parser.DialogInfo{Choices: choices}
+	if num := info.LastChoice(parser.ChoiceReject); num != "" {
+		return num
+	}
+
 	maxChoice := "2"
-	for num := 3; num >= 2; num-- {
-		numStr := fmt.Sprintf("%d", num)
-		if _, exists := choices[numStr]; exists {
-			maxChoice = numStr
-			break
+	if numbers := info.ChoiceNumbers(); len(numbers) > 0 {
+		if last := numbers[len(numbers)-1]; last != "1" {
+			maxChoice = last
 		}
 	}
 	return maxChoice
 }
 
 // isUserInputPattern checks if the output contains patterns indicating user input
+// (a choice number of any length, or the enter key)
 func isUserInputPattern(output string) bool {
-	return strings.Contains(output, "1") ||
-		strings.Contains(output, "2") ||
-		strings.Contains(output, "3") ||
+	return strings.ContainsAny(output, "0123456789") ||
 		strings.Contains(output, "\n") ||
 		strings.Contains(output, "\r\n")
 }
 
-// Run starts the application
-func (a *App) Run() error {
+// Run starts the application. A crash while reading Claude's output is
+// returned as an error once its report is written.
+func (a *App) Run() (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			path := a.handler.reportCrash(recovered, runtimedebug.Stack())
+			if path == "" {
+				panic(recovered)
+			}
+			err = fmt.Errorf("dcode crashed; please attach the report saved to %s to a bug report", path)
+		}
+	}()
+
 	// Initialize dialog globals
 	dialog.SetPtmxGlobal(a.ptmx)
 	dialog.InitGlobals()
@@ -695,15 +3223,13 @@ func (a *App) Run() error {
 	buffer := make([]byte, PTYBufferSize)
 	var lineBuffer []byte
 
-	// Create a pipe to process data
-	pipeReader, pipeWriter := io.Pipe()
-	defer pipeWriter.Close()
-
-	// Start output handling from pipe
-	go func() {
-		defer pipeReader.Close()
-		_, _ = io.Copy(a.displayWriter, pipeReader)
-	}()
+	// Buffer display output so a stalled terminal can't block PTY reads
+	policy, err := dialog.ParseBackpressurePolicy(a.config.DisplayBackpressure)
+	if err != nil {
+		return err
+	}
+	output := dialog.NewBufferedWriter(a.displayWriter, DisplayBufferChunks, policy)
+	defer output.Close()
 
 	for {
 		n, err := a.ptmx.Read(buffer)
@@ -714,8 +3240,8 @@ func (a *App) Run() error {
 			return fmt.Errorf("PTY read error: %w", err)
 		}
 
-		// Write to pipe for output
-		pipeWriter.Write(buffer[:n])
+		// Queue output for display
+		output.Write(buffer[:n])
 
 		// Check for user input during wait period by monitoring PTY output changes
 		if a.handler.waitingForInput && n > 0 {
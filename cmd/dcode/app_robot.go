@@ -6,8 +6,22 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/decisions"
+	"github.com/takahirom/dialog-code/internal/events"
+	"github.com/takahirom/dialog-code/internal/systemlog"
+	"github.com/takahirom/dialog-code/internal/telemetry"
+	"github.com/takahirom/dialog-code/internal/tracing"
+	"github.com/takahirom/dialog-code/internal/transcript"
 )
 
+// TestRequestID is the request ID the robot gives every prompt, so dialog
+// messages can be compared exactly
+const TestRequestID = "0123abcd"
+
 // AppRobot provides a fluent interface for testing app functionality
 type AppRobot struct {
 	t            *testing.T
@@ -35,6 +49,7 @@ func NewAppRobot(t *testing.T) *AppRobot {
 	}
 
 	app := NewAppWithDialogAndTimeProvider(tmpFile, os.Stdout, fakeDialog, fakeTimeProvider)
+	app.handler.newRequestID = func() string { return TestRequestID }
 
 	robot := &AppRobot{
 		t:            t,
@@ -134,6 +149,14 @@ func (r *AppRobot) AssertButtonCount(expected int) *AppRobot {
 	return r
 }
 
+// AssertDefaultButton verifies the default button passed to the dialog
+func (r *AppRobot) AssertDefaultButton(expectedText string) *AppRobot {
+	if actual := r.dialog.GetCapturedDefault(); actual != expectedText {
+		r.t.Errorf("Default button: expected '%s', got '%s'", expectedText, actual)
+	}
+	return r
+}
+
 // SetDialogChoice sets the choice that FakeDialog will return
 func (r *AppRobot) SetDialogChoice(choice string) *AppRobot {
 	r.dialog.mu.Lock()
@@ -142,6 +165,34 @@ func (r *AppRobot) SetDialogChoice(choice string) *AppRobot {
 	return r
 }
 
+// SetAnswerDelay makes the fake time pass by delay while each dialog waits
+// for an answer
+func (r *AppRobot) SetAnswerDelay(delay time.Duration) *AppRobot {
+	r.dialog.mu.Lock()
+	r.dialog.AnswerDelay = delay
+	r.dialog.mu.Unlock()
+	return r
+}
+
+// SetDialogChoices sets the buttons picked in the next dialogs, in order;
+// later dialogs get the SetDialogChoice choice
+func (r *AppRobot) SetDialogChoices(choices ...string) *AppRobot {
+	r.dialog.mu.Lock()
+	r.dialog.ReturnChoices = choices
+	r.dialog.mu.Unlock()
+	return r
+}
+
+// ClearCapturedDialog forgets the last dialog, so that a later one can be asserted on
+func (r *AppRobot) ClearCapturedDialog() *AppRobot {
+	r.dialog.mu.Lock()
+	r.dialog.CapturedMessage = ""
+	r.dialog.CapturedButtons = nil
+	r.dialog.CapturedDefault = ""
+	r.dialog.mu.Unlock()
+	return r
+}
+
 // GetCapturedMessage returns the captured dialog message for custom assertions
 func (r *AppRobot) GetCapturedMessage() string {
 	return r.dialog.GetCapturedMessage()
@@ -185,11 +236,150 @@ func (r *AppRobot) GetCapturedMessageWithoutTimestamp() string {
 	return actualMessage
 }
 
+// SetTypedText sets the text the user types into a text prompt
+func (r *AppRobot) SetTypedText(text string) *AppRobot {
+	r.dialog.mu.Lock()
+	r.dialog.ReturnText = text
+	r.dialog.ReturnTextOK = true
+	r.dialog.mu.Unlock()
+	return r
+}
+
+// AssertTextPromptContains verifies that a text prompt was shown containing expectedText
+func (r *AppRobot) AssertTextPromptContains(expectedText string) *AppRobot {
+	if prompt := r.dialog.GetCapturedPrompt(); !strings.Contains(prompt, expectedText) {
+		r.t.Errorf("Expected text prompt to contain %q, got: %q", expectedText, prompt)
+	}
+	return r
+}
+
+// AssertNoTextPrompt verifies that no text prompt was shown
+func (r *AppRobot) AssertNoTextPrompt() *AppRobot {
+	if prompt := r.dialog.GetCapturedPrompt(); prompt != "" {
+		r.t.Errorf("Expected no text prompt, got: %q", prompt)
+	}
+	return r
+}
+
+// UseApprovalCache makes the app remember approvals in cache
+func (r *AppRobot) UseApprovalCache(cache *approvals.Cache) *AppRobot {
+	r.app.SetApprovalCache(cache)
+	return r
+}
+
+// UseRemote makes the app send requests the risk policy routes remotely to callback
+func (r *AppRobot) UseRemote(callback RemoteCallback) *AppRobot {
+	r.app.SetRemoteCallback(callback)
+	return r
+}
+
+// UseEscalation makes the app alert callback about dialogs left unanswered
+func (r *AppRobot) UseEscalation(callback EscalationCallback) *AppRobot {
+	r.app.SetEscalationCallback(callback)
+	return r
+}
+
+// UseDecisionLog makes the app log requests answered without asking to log
+func (r *AppRobot) UseDecisionLog(log *decisions.Log) *AppRobot {
+	r.app.SetDecisionLog(log)
+	return r
+}
+
+// UseAuditLog makes the app record every answered prompt in log
+func (r *AppRobot) UseAuditLog(log *audit.Log) *AppRobot {
+	r.app.SetAuditLog(log)
+	return r
+}
+
+// UseSystemLog makes the app mirror every answered prompt to logger
+func (r *AppRobot) UseSystemLog(logger *systemlog.Logger) *AppRobot {
+	r.app.SetSystemLog(logger)
+	return r
+}
+
+// UseEventStream makes the app write lifecycle events to stream
+func (r *AppRobot) UseEventStream(stream *events.Stream) *AppRobot {
+	r.app.SetEventStream(stream)
+	return r
+}
+
+// UseTranscript makes the app save Claude's output with markers to t
+func (r *AppRobot) UseTranscript(t *transcript.Transcript) *AppRobot {
+	r.app.SetTranscript(t)
+	return r
+}
+
+// UseTelemetry makes the app count what happens to prompts in counters
+func (r *AppRobot) UseTelemetry(counters *telemetry.Counters) *AppRobot {
+	r.app.SetTelemetry(counters)
+	return r
+}
+
+// UseCrashDir makes the app write a crash report to dir
+func (r *AppRobot) UseCrashDir(dir string) *AppRobot {
+	r.app.SetCrashDir(dir)
+	return r
+}
+
+// UseScreenshots makes the app save a screenshot of each dialog, taken with
+// callback, to dir
+func (r *AppRobot) UseScreenshots(dir string, callback ScreenshotCallback) *AppRobot {
+	r.app.SetScreenshots(dir, callback)
+	return r
+}
+
+// UseTraceExporter makes the app send a trace of answering each prompt to exporter
+func (r *AppRobot) UseTraceExporter(exporter *tracing.Exporter) *AppRobot {
+	r.app.SetTraceExporter(exporter)
+	return r
+}
+
+// UseRulesFile makes the app add remembered answers to the config file at path
+func (r *AppRobot) UseRulesFile(path string) *AppRobot {
+	r.app.SetRulesFile(path)
+	return r
+}
+
+// LeaveDialogsUnanswered makes dialogs wait for an answer that never comes,
+// as when the user is away, while still capturing them
+func (r *AppRobot) LeaveDialogsUnanswered() *AppRobot {
+	unanswered := make(chan struct{})
+	r.t.Cleanup(func() { close(unanswered) })
+	r.app.SetPermissionCallback(func(message string, buttons []string, defaultButton string) string {
+		r.dialog.Show(message, buttons, defaultButton)
+		<-unanswered
+		return ""
+	})
+	return r
+}
+
 // SetAutoRejectWait sets the auto-reject timeout for testing
 // This allows AppRobot to test auto-reject functionality
 func (r *AppRobot) SetAutoRejectWait(seconds int) *AppRobot {
-	// TODO: Add mutex protection for thread-safe access to global autoRejectWait
-	*autoRejectWait = seconds
+	return r.Configure(func(cfg *config.Config) { cfg.AutoRejectWait = seconds })
+}
+
+// Configure changes the app's options before it receives any text
+func (r *AppRobot) Configure(configure func(cfg *config.Config)) *AppRobot {
+	handler := r.app.handler
+	configure(handler.config())
+	if err := handler.config().Validate(); err != nil {
+		r.t.Fatalf("Invalid config: %v", err)
+	}
+	handler.patterns = newRegexPatterns(handler.config())
+	handler.redactor = newRedactor(handler.config())
+	return r
+}
+
+// ReloadConfig reloads the app with a copy of its config changed by configure,
+// as when the config file is edited while dcode runs
+func (r *AppRobot) ReloadConfig(configure func(cfg *config.Config)) *AppRobot {
+	cfg := *r.app.handler.config()
+	configure(&cfg)
+	if err := cfg.Validate(); err != nil {
+		r.t.Fatalf("Invalid config: %v", err)
+	}
+	r.app.Reload(&cfg)
 	return r
 }
 
@@ -208,13 +398,6 @@ func (r *AppRobot) TriggerAutoReject(bestChoice string) *AppRobot {
 	return r
 }
 
-// RestoreAutoRejectWait restores the original auto-reject timeout (for cleanup)
-func (r *AppRobot) RestoreAutoRejectWait(originalTimeout int) *AppRobot {
-	// TODO: Add mutex protection for thread-safe access to global autoRejectWait
-	*autoRejectWait = originalTimeout
-	return r
-}
-
 // GetTerminalOutput returns all content written to the terminal (tmpFile)
 func (r *AppRobot) GetTerminalOutput() string {
 	// Seek to beginning and read all content
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
 )
 
 func TestAppWithDialogIntegration(t *testing.T) {
@@ -132,7 +135,9 @@ Bash command
   rm test-file
   Remove test file
 
-Do you want to proceed?`
+Do you want to proceed?
+
+Request 0123abcd · dcode dev`
 	robot.AssertExactFormatSnapshotTest(expectedMessage)
 }
 
@@ -180,7 +185,9 @@ Bash command
   rm not-found-file
   Test dialog message for data collection
 
-Do you want to proceed?`
+Do you want to proceed?
+
+Request 0123abcd · dcode dev`
 
 	// This assertion should fail, demonstrating the problem
 	if actualMessage == expectedMessage {
@@ -282,15 +289,11 @@ func TestCountdownMessagePositionWithAppRobot(t *testing.T) {
 		"╰─────────────────────────────────────────────────────────────────────────────╯",
 	}
 
-	// Store original timeout to restore later
-	originalTimeout := *autoRejectWait
-
 	robot := NewAppRobot(t).
 		SetAutoRejectWait(5).
 		ReceiveClaudeText(realDialogLines...).
 		AssertDialogCaptured().
-		TriggerAutoReject("1").
-		RestoreAutoRejectWait(originalTimeout)
+		TriggerAutoReject("1")
 
 	// Get the captured message from auto-reject dialog
 	capturedMessage := robot.GetCapturedMessage()
@@ -330,14 +333,9 @@ func TestAutoRejectMessageWithFlag(t *testing.T) {
 		"╰─────────────────────────────────────────────────────────────────────────────╯",
 	}
 
-	// Store original flag value to restore later
-	originalAutoReject := *autoReject
-	defer func() { *autoReject = originalAutoReject }()
-
-	// Enable --auto-reject flag to trigger automatic rejection
-	*autoReject = true
-
+	// Enable --auto-reject to trigger automatic rejection
 	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoReject = true }).
 		ReceiveClaudeText(realDialogLines...)
 
 	// Wait for auto-reject goroutines to complete
@@ -375,14 +373,9 @@ func TestAutoRejectMessageWithCommandDetails(t *testing.T) {
 		"╰─────────────────────────────────────────────────────────────────────────────╯",
 	}
 
-	// Store original flag value
-	originalAutoReject := *autoReject
-	defer func() { *autoReject = originalAutoReject }()
-
-	// Enable --auto-reject flag
-	*autoReject = true
-
+	// Enable --auto-reject to trigger automatic rejection
 	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoReject = true }).
 		ReceiveClaudeText(realDialogLines...)
 
 	// Wait for auto-reject goroutines to complete 
@@ -422,14 +415,9 @@ func TestAutoRejectMessageCleanOutput(t *testing.T) {
 		"╰─────────────────────────────────────────────────────────────────────────────╯",
 	}
 
-	// Store original flag value
-	originalAutoReject := *autoReject
-	defer func() { *autoReject = originalAutoReject }()
-
-	// Enable --auto-reject flag
-	*autoReject = true
-
+	// Enable --auto-reject to trigger automatic rejection
 	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoReject = true }).
 		ReceiveClaudeText(realDialogLines...)
 
 	// Wait for auto-reject goroutines to complete
@@ -484,14 +472,9 @@ func TestAutoRejectMessageComplexDialog(t *testing.T) {
 		"╰─────────────────────────────────────────────────────────────────────────────╯",
 	}
 
-	// Store original flag value
-	originalAutoReject := *autoReject
-	defer func() { *autoReject = originalAutoReject }()
-
-	// Enable --auto-reject flag
-	*autoReject = true
-
+	// Enable --auto-reject to trigger automatic rejection
 	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoReject = true }).
 		ReceiveClaudeText(complexDialogLines...)
 
 	// Wait for auto-reject goroutines to complete
@@ -536,14 +519,9 @@ func TestAutoRejectMessageRealWorldPipeIssue(t *testing.T) {
 		"╰─────────────────────────────────────────────────────────────────────────────╯",
 	}
 
-	// Store original flag value
-	originalAutoReject := *autoReject
-	defer func() { *autoReject = originalAutoReject }()
-
-	// Enable --auto-reject flag
-	*autoReject = true
-
+	// Enable --auto-reject to trigger automatic rejection
 	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoReject = true }).
 		ReceiveClaudeText(realWorldDialogLines...)
 
 	// Wait for auto-reject goroutines to complete
@@ -709,7 +687,7 @@ func TestBuildAutoRejectMessageDebug(t *testing.T) {
 	handler.appState.Prompt.Context = testContext
 	
 	// Call buildAutoRejectMessage directly and examine result
-	result := handler.buildAutoRejectMessage()
+	result := handler.buildAutoRejectMessage(handler.dialogInfo())
 	t.Logf("buildAutoRejectMessage result: %q", result)
 	
 	// Debug: Process each line and show what gets included
@@ -822,3 +800,161 @@ func TestSerenaMCPDialogDetection(t *testing.T) {
 		t.Errorf("❌ Missing tool parameters in captured message") 
 	}
 }
+
+func TestAppWithAsciiBoxDialog(t *testing.T) {
+	// Some terminals/locales render Claude's boxes with +, -, | instead of ╭│╰
+	asciiDialogLines := []string{
+		"⏺ Bash(rm test-file)",
+		"",
+		"+-----------------------------------------------------------------------------+",
+		"| Bash command                                                                |",
+		"|                                                                             |",
+		"|   rm test-file                                                              |",
+		"|   Remove test file                                                          |",
+		"|                                                                             |",
+		"| Do you want to proceed?                                                     |",
+		"| ❯ 1. Yes                                                                    |",
+		"|   2. No                                                                     |",
+		"+-----------------------------------------------------------------------------+",
+	}
+
+	robot := NewAppRobot(t).
+		ReceiveClaudeText(asciiDialogLines...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("Bash command").
+		AssertDialogTextContains("rm test-file").
+		AssertButtonCount(2).
+		AssertButton(0, "Yes").
+		AssertButton(1, "No")
+
+	if strings.Contains(robot.GetCapturedMessage(), "|") {
+		t.Errorf("ASCII borders leaked into dialog message: %q", robot.GetCapturedMessage())
+	}
+}
+
+func TestAppWithEditDiffDialog(t *testing.T) {
+	editDiffLines := []string{
+		"⏺ Update(main.go)",
+		"",
+		"╭──────────────────────────────────────────────────────────────╮",
+		"│ Edit file                                                    │",
+		"│ ╭──────────────────────────────────────────────────────────╮ │",
+		"│ │ main.go                                                  │ │",
+		"│ │                                                          │ │",
+		"│ │ 10  func answer() int {                                  │ │",
+		"│ │ 11 -  return 41                                          │ │",
+		"│ │ 11 +  return 42                                          │ │",
+		"│ │ 12  }                                                    │ │",
+		"│ ╰──────────────────────────────────────────────────────────╯ │",
+		"│ Do you want to make this edit to main.go?                    │",
+		"│ ❯ 1. Yes                                                     │",
+		"│   2. No, and tell Claude what to do differently (esc)        │",
+		"╰──────────────────────────────────────────────────────────────╯",
+	}
+
+	NewAppRobot(t).
+		ReceiveClaudeText(editDiffLines...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("Edit file").
+		AssertDialogTextContains("-  return 41").
+		AssertDialogTextContains("+  return 42").
+		AssertDialogTextContains("Do you want to make this edit to main.go?")
+}
+
+func TestAppWithDialogNestedInTaskOutput(t *testing.T) {
+	nestedLines := []string{
+		"⏺ Task(Clean up test files)",
+		"╭──────────────────────────────────────────────╮",
+		"│ Task output                                  │",
+		"│   Looking for leftover files                 │",
+		"│ ╭──────────────────────────────────────────╮ │",
+		"│ │ Bash command                             │ │",
+		"│ │                                          │ │",
+		"│ │   rm test-file                           │ │",
+		"│ │                                          │ │",
+		"│ │ Do you want to proceed?                  │ │",
+		"│ │ ❯ 1. Yes                                 │ │",
+		"│ │   2. No                                  │ │",
+		"│ ╰──────────────────────────────────────────╯ │",
+		"╰──────────────────────────────────────────────╯",
+	}
+
+	robot := NewAppRobot(t).
+		ReceiveClaudeText(nestedLines...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("Bash command\n\n  rm test-file").
+		AssertButtonCount(2).
+		AssertButton(0, "Yes").
+		AssertButton(1, "No")
+
+	if strings.Contains(robot.GetCapturedMessage(), "Looking for leftover files") {
+		t.Errorf("Task output outside the permission dialog leaked into the message: %q", robot.GetCapturedMessage())
+	}
+}
+
+func TestAppWithDialogTallerThanContextBuffer(t *testing.T) {
+	tallDialogLines := []string{
+		"⏺ Write(/tmp/generated.txt)",
+		"╭──────────────────────────────────────────────╮",
+		"│ Create file                                  │",
+		"│   file_path: /tmp/generated.txt              │",
+	}
+	for i := 0; i < ContextBufferSize+10; i++ {
+		tallDialogLines = append(tallDialogLines, fmt.Sprintf("│   line %d of generated content              │", i))
+	}
+	tallDialogLines = append(tallDialogLines,
+		"│ Do you want to create generated.txt?         │",
+		"│ ❯ 1. Yes                                     │",
+		"│   2. No                                      │",
+		"╰──────────────────────────────────────────────╯",
+	)
+
+	NewAppRobot(t).
+		ReceiveClaudeText(tallDialogLines...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("Trigger text: ⏺ Write(/tmp/generated.txt)").
+		AssertDialogTextContains("Create file").
+		AssertDialogTextContains("file_path: /tmp/generated.txt").
+		AssertButtonCount(2)
+}
+
+func TestAppDefaultButtonFollowsCursor(t *testing.T) {
+	cursorLines := []string{
+		"⏺ Bash(ls)",
+		"╭──────────────────────────────────────────────╮",
+		"│ Bash command                                 │",
+		"│   ls                                         │",
+		"│ Do you want to proceed?                      │",
+		"│   1. Yes                                     │",
+		"│ ❯ 2. No, and tell Claude what to do (esc)    │",
+		"╰──────────────────────────────────────────────╯",
+	}
+
+	NewAppRobot(t).
+		ReceiveClaudeText(cursorLines...).
+		AssertDialogCaptured().
+		AssertButton(0, "Yes").
+		AssertDefaultButton("No, and tell Claude what to do (esc)")
+}
+
+func TestAppWithDoubleDigitChoices(t *testing.T) {
+	lines := []string{
+		"⏺ Bash(ls)",
+		"╭──────────────────────────────────────────────╮",
+		"│ Bash command                                 │",
+		"│   ls                                         │",
+		"│ Do you want to proceed?                      │",
+	}
+	for i := 1; i <= 11; i++ {
+		lines = append(lines, fmt.Sprintf("│   %d. Option %d                               │", i, i))
+	}
+	lines = append(lines, "╰──────────────────────────────────────────────╯")
+
+	NewAppRobot(t).
+		ReceiveClaudeText(lines...).
+		AssertDialogCaptured().
+		AssertButtonCount(11).
+		AssertButton(1, "Option 2").
+		AssertButton(9, "Option 10").
+		AssertButton(10, "Option 11")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func cacheApprovals(cfg *config.Config) {
+	cfg.ApprovalCacheSeconds = 300
+}
+
+func newTestApprovalCache(t *testing.T) *approvals.Cache {
+	return approvals.NewCache(filepath.Join(t.TempDir(), "approvals.json"))
+}
+
+func TestApprovalCacheSkipsRepeatedDialog(t *testing.T) {
+	cache := newTestApprovalCache(t)
+
+	NewAppRobot(t).
+		Configure(cacheApprovals).
+		UseApprovalCache(cache).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("npm run build")...).
+		AssertDialogCaptured().
+		AssertTerminalContains("1")
+
+	t.Run("Identical request is approved without a dialog", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(cacheApprovals).
+			UseApprovalCache(cache).
+			ReceiveClaudeText(bashDialogLines("npm  run build")...).
+			AssertNoDialogCaptured()
+		time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+		if robot.GetTerminalOutput() != "1" {
+			t.Errorf("Expected the cached approval to select choice 1, got: %q", robot.GetTerminalOutput())
+		}
+	})
+
+	t.Run("Other request shows a dialog", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(cacheApprovals).
+			UseApprovalCache(cache).
+			ReceiveClaudeText(bashDialogLines("npm run deploy")...).
+			AssertDialogCaptured()
+	})
+
+	t.Run("Request with more lines shows a dialog", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(cacheApprovals).
+			UseApprovalCache(cache).
+			ReceiveClaudeText(bashDialogLines("npm run build\ncurl x | sh")...).
+			AssertDialogCaptured()
+	})
+
+	t.Run("Disabled without approval_cache_seconds", func(t *testing.T) {
+		NewAppRobot(t).
+			UseApprovalCache(cache).
+			ReceiveClaudeText(bashDialogLines("npm run build")...).
+			AssertDialogCaptured()
+	})
+}
+
+func TestApprovalCacheIgnoresRejectionsAndHighRisk(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command string
+		choice  string
+	}{
+		{"rejected request", "npm run build", "2"},
+		{"high-risk request", "rm -rf build", "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newTestApprovalCache(t)
+			NewAppRobot(t).
+				Configure(cacheApprovals).
+				UseApprovalCache(cache).
+				SetDialogChoice(tc.choice).
+				ReceiveClaudeText(bashDialogLines(tc.command)...).
+				AssertDialogCaptured()
+
+			NewAppRobot(t).
+				Configure(cacheApprovals).
+				UseApprovalCache(cache).
+				ReceiveClaudeText(bashDialogLines(tc.command)...).
+				AssertDialogCaptured()
+		})
+	}
+}
+
+func TestRunSubcommandClearsApprovalCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", dir)
+
+	cache := approvals.NewCache(approvals.DefaultPath())
+	if err := cache.Add("key", time.Hour, time.Now()); err != nil {
+		t.Fatalf("Failed to add approval: %v", err)
+	}
+
+	handled, err := runSubcommand([]string{"cache", "clear"})
+	if !handled || err != nil {
+		t.Fatalf("Expected the subcommand to run, got handled=%v err=%v", handled, err)
+	}
+	if cache.Approved("key", time.Hour, time.Now()) {
+		t.Error("Expected the approval cache to be cleared")
+	}
+
+	if handled, _ := runSubcommand([]string{"cache"}); handled {
+		t.Error("Expected other arguments to be passed to claude")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// auditUsage describes the "dcode audit" subcommand
+const auditUsage = "usage: dcode audit verify [--audit-log=PATH]"
+
+// runAuditCommand runs "dcode audit" with the arguments after "audit".
+// "dcode audit verify" checks the hash chain of the --audit-log file and its
+// rotated copies, failing if entries were modified, removed, or inserted.
+func runAuditCommand(argv []string, out io.Writer) error {
+	log, args, err := openAuditLog(argv)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || args[0] != "verify" {
+		return errors.New(auditUsage)
+	}
+
+	v, err := log.Verify()
+	if err != nil {
+		return err
+	}
+	if v.Unchained > 0 {
+		fmt.Fprintf(out, "%d entries from before the chain began can't be verified\n", v.Unchained)
+	}
+	for _, problem := range v.Problems {
+		fmt.Fprintln(out, problem)
+	}
+	if !v.OK() {
+		return fmt.Errorf("the audit log failed verification with %d problem(s)", len(v.Problems))
+	}
+	switch {
+	case v.Entries == 0:
+		fmt.Fprintln(out, "No chained entries to verify")
+	case v.FirstSeq > 1:
+		fmt.Fprintf(out, "Verified entries %d to %d; earlier ones were rotated away\n", v.FirstSeq, v.LastSeq)
+	default:
+		fmt.Fprintf(out, "Verified all %d entries\n", v.Entries)
+	}
+	return nil
+}
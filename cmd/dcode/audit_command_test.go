@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+func TestAuditVerify(t *testing.T) {
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	path := historyLog(t,
+		audit.Entry{ID: "0000aaaa", Time: day, Tool: "Bash", Request: "Bash: git push --force", Action: audit.Rejected, DecidedBy: "deny rule 1"},
+		audit.Entry{ID: "0000bbbb", Time: day.Add(time.Hour), Tool: "Bash", Request: "Bash: go test ./...", Action: audit.Approved, DecidedBy: audit.User},
+	)
+
+	var out bytes.Buffer
+	if err := runAuditCommand([]string{"--audit-log=" + path, "verify"}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out.String() != "Verified all 2 entries\n" {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+
+	// Turn the rejection into an approval
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), `"action":"rejected"`, `"action":"approved"`, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	err = runAuditCommand([]string{"--audit-log=" + path, "verify"}, &out)
+	if err == nil || !strings.Contains(out.String(), "entry 1 (0000aaaa) was modified") {
+		t.Errorf("Expected the modified entry to fail verification, got %v:\n%s", err, out.String())
+	}
+
+	if err := runAuditCommand([]string{"--audit-log=" + path}, &out); err == nil || err.Error() != auditUsage {
+		t.Errorf("Expected usage, got %v", err)
+	}
+}
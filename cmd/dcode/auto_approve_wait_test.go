@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/decisions"
+)
+
+// autoApproveWait counts down seconds before approving
+func autoApproveWait(seconds int) func(cfg *config.Config) {
+	return func(cfg *config.Config) { cfg.AutoApproveWait = seconds }
+}
+
+func TestAutoApproveWaitApprovesWithoutAnswer(t *testing.T) {
+	log := decisionLog(t)
+	robot := NewAppRobot(t).
+		Configure(autoApproveWait(1)).
+		UseDecisionLog(log).
+		LeaveDialogsUnanswered().
+		ReceiveClaudeText(bashDialogLines("npm install")...).
+		AssertDialogTextContains("This will auto-approve in 1 seconds...")
+	time.Sleep(1200 * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the approve choice after the countdown, got: %q", output)
+	}
+	entries, err := log.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Rule != "auto_approve_wait" || entries[0].Decision != decisions.Approved {
+		t.Errorf("Expected an auto_approve_wait approval to be logged, got %+v", entries)
+	}
+}
+
+func TestAutoApproveWaitLetsUserVeto(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(autoApproveWait(5)).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("npm install")...).
+		AssertDialogTextContains("This will auto-approve in 5 seconds...").
+		AssertTerminalContains("2")
+
+	if output := robot.GetTerminalOutput(); strings.Contains(output, "1") {
+		t.Errorf("Expected only the user's answer, got: %q", output)
+	}
+}
+
+func TestAutoApproveWaitKeepsDenyRules(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(autoApproveWait(1)).
+		Configure(func(cfg *config.Config) {
+			cfg.Deny = []config.DenyRule{{Rule: config.Rule{Command: "^npm install"}}}
+		}).
+		ReceiveClaudeText(bashDialogLines("npm install")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	robot.AssertTerminalContains(DenyRuleBaseMessage)
+}
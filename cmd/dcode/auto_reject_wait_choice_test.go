@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// threeWayDialogLines is a Bash dialog with both a plain "No" and a
+// "No, and tell Claude" choice
+func threeWayDialogLines(command string) []string {
+	lines := bashDialogLines(command)
+	return append(lines[:len(lines)-2],
+		"│   2. No                                      │",
+		"│   3. No, and tell Claude what to do (esc)    │",
+		"╰──────────────────────────────────────────────╯",
+	)
+}
+
+// autoRejectWaitChoice counts down one second, then picks choice
+func autoRejectWaitChoice(choice string) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		cfg.AutoRejectWait = 1
+		cfg.AutoRejectWaitChoice = choice
+	}
+}
+
+// countdownWaitTime covers a one-second countdown and typing the rejection message
+const countdownWaitTime = 1000*time.Millisecond + (config.DefaultAutoRejectChoiceDelayMs+100)*time.Millisecond
+
+func TestAutoRejectWaitChoice(t *testing.T) {
+	testCases := []struct {
+		name          string
+		choice        string
+		expected      string
+		expectMessage bool
+	}{
+		{"tell Claude", config.TimeoutChoiceTellClaude, "3", true},
+		{"plain no", config.TimeoutChoiceNo, "2", false},
+		{"reject number", "2", "2", true},
+		{"approve number falls back", "1", "3", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			robot := NewAppRobot(t).
+				Configure(autoRejectWaitChoice(tc.choice)).
+				LeaveDialogsUnanswered().
+				ReceiveClaudeText(threeWayDialogLines("npm publish")...).
+				AssertDialogCaptured()
+			time.Sleep(countdownWaitTime)
+
+			output := robot.GetTerminalOutput()
+			if !strings.HasPrefix(output, tc.expected) {
+				t.Errorf("Expected choice %s first, got: %q", tc.expected, output)
+			}
+			if hasMessage := strings.Contains(output, AutoRejectBaseMessage); hasMessage != tc.expectMessage {
+				t.Errorf("Expected rejection message=%v, got: %q", tc.expectMessage, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/redact"
+)
+
+// configUsage describes the "dcode config" subcommand
+const configUsage = "usage: dcode config show [--config=PATH] [dcode options]"
+
+// Where a setting's value came from, as "dcode config show" names it
+const (
+	SourceDefault        = "default"
+	SourceFile           = "file"
+	SourceFlag           = "flag"
+	SourceClaudeSettings = "claude settings"
+	SourcePolicy         = "policy"
+)
+
+// secretSettings are masked by "dcode config show" whatever their value
+var secretSettings = []string{"remote.token", "digest.slack_webhook"}
+
+// setting is one option of the config, named by its path in the config
+// file, e.g. "remote.ntfy_url"
+type setting struct {
+	Name   string
+	Value  string // The value as YAML on one line
+	Source string // Where the value came from, e.g. SourceFile or "file + policy"
+}
+
+// runConfigCommand runs "dcode config show" with the arguments after
+// "config show": it prints every option in effect with the config file and
+// dcode options in argv, and where each value came from
+func runConfigCommand(argv []string, out io.Writer) error {
+	var layers []configLayer
+	// Each layer is recorded before the next changes cfg, as the layers
+	// share its maps
+	record := func(cfg config.Config, source string) error {
+		settings, err := flattenConfig(cfg)
+		layers = append(layers, configLayer{settings, source})
+		return err
+	}
+	if err := record(config.Default(), SourceDefault); err != nil {
+		return err
+	}
+	cfg, path, err := loadConfigFile(argv)
+	if err != nil {
+		return err
+	}
+	if err := record(cfg, SourceFile); err != nil {
+		return err
+	}
+	args, err := applyFlags(&cfg, argv)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return errors.New(configUsage)
+	}
+	if err := record(cfg, SourceFlag); err != nil {
+		return err
+	}
+	if cfg.ImportClaudeSettings {
+		if err := importClaudeSettings(&cfg); err != nil {
+			return err
+		}
+	}
+	if err := record(cfg, SourceClaudeSettings); err != nil {
+		return err
+	}
+	if err := applyPolicy(&cfg, nil); err != nil {
+		return err
+	}
+	if err := record(cfg, SourcePolicy); err != nil {
+		return err
+	}
+
+	var inFile map[string]bool
+	if path != "" {
+		if inFile, err = fileSettingNames(path); err != nil {
+			return err
+		}
+	}
+	settings := configSettings(layers)
+
+	if path == "" {
+		fmt.Fprintln(out, "Config file: none")
+	} else {
+		fmt.Fprintf(out, "Config file: %s\n", path)
+	}
+	redactor := newRedactor(&cfg)
+	width := 0
+	for _, s := range settings {
+		width = max(width, len(s.Name)+len(s.Value)+2)
+	}
+	for _, s := range settings {
+		if s.Source == SourceDefault && inFile[s.Name] {
+			// Set in the file to its default value
+			s.Source = SourceFile
+		}
+		line := fmt.Sprintf("%s: %s", s.Name, maskSetting(s.Name, s.Value, redactor))
+		fmt.Fprintf(out, "%-*s  # %s\n", width, line, s.Source)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(out, "\nThis configuration is invalid, so dcode won't start with it: %v\n", err)
+	}
+	return nil
+}
+
+// configLayer is the config as a source left it
+type configLayer struct {
+	settings []setting
+	source   string
+}
+
+// configSettings returns the settings of the last of layers, the config as
+// each source left it, each with the source of the last layer that changed
+// it. Sources that changed a value the file or a flag set are added to it,
+// as with rules added by the policy to the file's.
+func configSettings(layers []configLayer) []setting {
+	var values []map[string]string
+	for _, layer := range layers {
+		byName := map[string]string{}
+		for _, s := range layer.settings {
+			byName[s.Name] = s.Value
+		}
+		values = append(values, byName)
+	}
+
+	settings := append([]setting(nil), layers[len(layers)-1].settings...)
+	for i := range settings {
+		name := settings[i].Name
+		source := layers[0].source
+		for j := 1; j < len(layers); j++ {
+			if values[j][name] == values[j-1][name] {
+				continue
+			}
+			switch layers[j].source {
+			case SourceFile, SourceFlag:
+				source = layers[j].source
+			default:
+				source += " + " + layers[j].source
+			}
+		}
+		settings[i].Source = strings.TrimPrefix(source, SourceDefault+" + ")
+	}
+	return settings
+}
+
+// flattenConfig returns the options of cfg in the order of the config
+// file's reference, with blocks such as remote flattened into
+// "remote.ntfy_url"
+func flattenConfig(cfg config.Config) ([]setting, error) {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return nil, err
+	}
+	var settings []setting
+	flattenNode(&node, "", &settings)
+	return settings, nil
+}
+
+// flattenNode adds the leaves of node, at name, to settings
+func flattenNode(node *yaml.Node, name string, settings *[]setting) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		flattenNode(node.Content[0], name, settings)
+		return
+	}
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 && !isRuleMap(name) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if name != "" {
+				key = name + "." + key
+			}
+			flattenNode(node.Content[i+1], key, settings)
+		}
+		return
+	}
+	*settings = append(*settings, setting{Name: name, Value: oneLineYAML(node)})
+}
+
+// isRuleMap reports whether the option at name is a map whose entries are
+// values rather than options, such as tool_policy's tools
+func isRuleMap(name string) bool {
+	return name == "tool_policy"
+}
+
+// oneLineYAML returns node as YAML on one line
+func oneLineYAML(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value == "" {
+			return `""`
+		}
+		return node.Value
+	}
+	flow := *node
+	setFlowStyle(&flow)
+	data, err := yaml.Marshal(&flow)
+	if err != nil {
+		return "?"
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// setFlowStyle writes node and everything in it in YAML's flow style
+func setFlowStyle(node *yaml.Node) {
+	node.Style |= yaml.FlowStyle
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+// fileSettingNames returns the names of the options set in the config file
+// at path, as flattenConfig names them
+func fileSettingNames(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var settings []setting
+	flattenNode(&node, "", &settings)
+	names := map[string]bool{}
+	for _, s := range settings {
+		names[s.Name] = true
+	}
+	return names, nil
+}
+
+// maskSetting returns value, the value of the setting name, with secrets
+// masked: the whole value of secretSettings, the topic of an ntfy URL, and
+// whatever redactor masks
+func maskSetting(name, value string, redactor *redact.Redactor) string {
+	if value == `""` {
+		return value
+	}
+	for _, secret := range secretSettings {
+		if name == secret {
+			return redact.Mask
+		}
+	}
+	if name == "remote.ntfy_url" {
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			return u.Scheme + "://" + u.Host + "/" + redact.Mask
+		}
+	}
+	return redactor.Redact(value)
+}
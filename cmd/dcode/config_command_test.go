@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runConfigShow runs "dcode config show" with args, returning its output
+func runConfigShow(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runConfigCommand(args, &out)
+	return out.String(), err
+}
+
+func TestConfigShowSources(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `auto_reject_wait: 30
+strip_colors: false
+tool_policy:
+  Bash: ask
+remote:
+  ntfy_url: https://ntfy.sh/my-secret-topic
+  token: tk_0123456789
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runConfigShow(t, "--config="+path, "--auto-reject", "--tool-policy=Read=allow")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		setting, source, found := strings.Cut(line, "  # ")
+		if found {
+			lines[strings.TrimSpace(setting)] = source
+		}
+	}
+	for setting, source := range map[string]string{
+		"auto_reject: true":                           "flag",
+		"auto_reject_wait: 30":                        "file",
+		"strip_colors: false":                         "file",
+		"auto_approve: false":                         "default",
+		"tool_policy: {Bash: ask, Read: allow}":       "flag",
+		"remote.ntfy_url: https://ntfy.sh/[REDACTED]": "file",
+		"remote.token: [REDACTED]":                    "file",
+		"remote.timeout_seconds: 600":                 "default",
+	} {
+		if lines[setting] != source {
+			t.Errorf("Expected %q from %s, got %q in:\n%s", setting, source, lines[setting], output)
+		}
+	}
+	if !strings.HasPrefix(output, "Config file: "+path+"\n") {
+		t.Errorf("Expected the config file to be named, got:\n%s", output)
+	}
+	for _, secret := range []string{"my-secret-topic", "tk_0123456789"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %q to be masked in:\n%s", secret, output)
+		}
+	}
+
+	if _, err := runConfigShow(t, "--config="+path, "--resume"); err == nil || err.Error() != configUsage {
+		t.Errorf("Expected the usage for Claude arguments, got %v", err)
+	}
+}
+
+func TestConfigSettingsAddedByRuleSources(t *testing.T) {
+	layer := func(source, approve string) configLayer {
+		return configLayer{[]setting{{Name: "approve", Value: approve}, {Name: "debug", Value: "false"}}, source}
+	}
+	settings := configSettings([]configLayer{
+		layer(SourceDefault, "[]"),
+		layer(SourceFile, "[{tool: Read}]"),
+		layer(SourceFlag, "[{tool: Read}]"),
+		layer(SourceClaudeSettings, "[{tool: Read}, {tool: Edit}]"),
+		layer(SourcePolicy, "[{tool: Read}, {tool: Edit}, {tool: Grep}]"),
+	})
+	if settings[0].Source != "file + claude settings + policy" || settings[1].Source != SourceDefault {
+		t.Errorf("Unexpected sources: %+v", settings)
+	}
+
+	settings = configSettings([]configLayer{layer(SourceDefault, "[]"), layer(SourceFile, "[]"), layer(SourcePolicy, "[{tool: Grep}]")})
+	if settings[0].Source != SourcePolicy {
+		t.Errorf("Expected rules only the policy set to come from it, got %+v", settings)
+	}
+}
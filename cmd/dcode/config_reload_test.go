@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestReloadConfigAppliesNewRules(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.Delays.DialogResetMs = 0
+		}).
+		ReceiveClaudeText(bashDialogLines("npm publish")...).
+		AssertDialogCaptured()
+
+	robot.ClearCapturedDialog().
+		ReloadConfig(func(cfg *config.Config) {
+			cfg.Deny = []config.DenyRule{{Rule: config.Rule{Command: `^npm publish`}, Message: "Publishing is done by CI."}}
+		}).
+		ReceiveClaudeText(askedAs("npm publish --tag next", "Do you want to publish?")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	robot.AssertTerminalContains("Publishing is done by CI.")
+}
+
+func TestReloadConfigAppliesNewPatterns(t *testing.T) {
+	question := "Shall I run this command?"
+
+	robot := NewAppRobot(t).
+		ReceiveClaudeText(askedAs("make", question)...).
+		AssertNoDialogCaptured()
+
+	robot.ReloadConfig(func(cfg *config.Config) {
+		cfg.Patterns.Permit = []string{`Shall I run`}
+	}).
+		ReceiveClaudeText(askedAs("make install", question)...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("make install")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/claudesettings"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/debug"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// Keep the user's own config file out of the test
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "auto_reject: true\nauto_reject_wait: 5\ncontinue_prompts: auto\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	t.Run("Defaults without a config file", func(t *testing.T) {
+		cfg, args, err := loadConfig([]string{"--debug", "--allow-read-only", "-p", "hello"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !cfg.Debug || !cfg.AllowReadOnly || cfg.AutoReject {
+			t.Errorf("Expected only debug and allow-read-only to be set, got %+v", cfg)
+		}
+		if !reflect.DeepEqual(args, []string{"-p", "hello"}) {
+			t.Errorf("Expected claude args to pass through, got %q", args)
+		}
+	})
+
+	t.Run("Flags override the config file", func(t *testing.T) {
+		cfg, args, err := loadConfig([]string{"--config=" + path, "--auto-reject-wait=10", "--temporary-approval-minutes=15", "--sync-settings", "--delays=auto_approve_ms=20,auto_reject_cr_ms=1500", "--audit-log=~/audit.jsonl", "--log-level=warn", "--log-format=json", "--log-dir=/var/log/dcode", "--syslog=local0", "--event-stream=3", "--escalate-after=5", "--transcript=runs/", "--screenshot-dir=~/shots", "--telemetry=https://stats.example.com/dcode", "--resume"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !cfg.AutoReject || cfg.AutoRejectWait != 10 || cfg.ContinuePrompts != "auto" || cfg.TemporaryApprovalMinutes != 15 || !cfg.SyncSettings ||
+			cfg.Delays.AutoApproveMs != 20 || cfg.Delays.AutoRejectCRMs != 1500 || cfg.AuditLog != "~/audit.jsonl" ||
+			cfg.LogLevel != "warn" || cfg.LogFormat != "json" || cfg.LogDir != "/var/log/dcode" || cfg.Syslog != "local0" || cfg.EventStream != "3" ||
+			cfg.Escalation.AfterSeconds != 5 || cfg.Transcript != "runs/" || cfg.ScreenshotDir != "~/shots" || cfg.Telemetry != "https://stats.example.com/dcode" {
+			t.Errorf("Expected file values with the flag applied, got %+v", cfg)
+		}
+		if !reflect.DeepEqual(args, []string{"--resume"}) {
+			t.Errorf("Expected only claude args, got %q", args)
+		}
+	})
+
+	t.Run("Telemetry can be turned off", func(t *testing.T) {
+		telemetryPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(telemetryPath, []byte("telemetry: https://stats.example.com/dcode\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, _, err := loadConfig([]string{"--config=" + telemetryPath, "--no-telemetry"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cfg.Telemetry != "" {
+			t.Errorf("Expected --no-telemetry to turn telemetry off, got %q", cfg.Telemetry)
+		}
+	})
+
+	t.Run("Default config file is read", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		if err := os.Mkdir(filepath.Join(dir, "dcode"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "dcode", "config.yaml"), []byte("auto_approve: true\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, _, err := loadConfig(nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !cfg.AutoApprove {
+			t.Error("Expected auto_approve from the default config file")
+		}
+	})
+
+	t.Run("Missing explicit config file is an error", func(t *testing.T) {
+		if _, _, err := loadConfig([]string{"--config=" + filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Tool policy flag adds to the config file", func(t *testing.T) {
+		policyPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(policyPath, []byte("tool_policy:\n  Bash: ask\n  Read: allow\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, _, err := loadConfig([]string{"--config=" + policyPath, "--tool-policy=Bash=allow,WebFetch=deny"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := config.ToolPolicy{"Bash": "allow", "Read": "allow", "WebFetch": "deny"}
+		if !reflect.DeepEqual(cfg.ToolPolicy, expected) {
+			t.Errorf("Expected %v, got %v", expected, cfg.ToolPolicy)
+		}
+	})
+
+	t.Run("Tool-scoped auto modes add to the tool policy", func(t *testing.T) {
+		cfg, _, err := loadConfig([]string{"--tool-policy=Bash=ask", "--auto-approve-tools=Edit,Write", "--auto-reject-tools=WebFetch"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := config.ToolPolicy{"Bash": "ask", "Edit": "allow", "Write": "allow", "WebFetch": "deny"}
+		if !reflect.DeepEqual(cfg.ToolPolicy, expected) {
+			t.Errorf("Expected %v, got %v", expected, cfg.ToolPolicy)
+		}
+	})
+
+	t.Run("Auto-approve patterns add approve rules", func(t *testing.T) {
+		rulesPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(rulesPath, []byte("approve:\n  - tool: Read\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, _, err := loadConfig([]string{"--config=" + rulesPath, "--auto-approve-pattern=^go test", "--auto-approve-pattern=^npm run lint$"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := []config.Rule{{Tool: "Read"}, {Command: "^go test"}, {Command: "^npm run lint$"}}
+		if !reflect.DeepEqual(cfg.Approve, expected) {
+			t.Errorf("Expected %v, got %v", expected, cfg.Approve)
+		}
+	})
+
+	t.Run("Auto-reject patterns add deny rules", func(t *testing.T) {
+		cfg, _, err := loadConfig([]string{"--auto-reject-pattern=^git push", "--auto-reject-pattern=^rm -rf"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := []config.DenyRule{{Rule: config.Rule{Command: "^git push"}}, {Rule: config.Rule{Command: "^rm -rf"}}}
+		if !reflect.DeepEqual(cfg.Deny, expected) {
+			t.Errorf("Expected %v, got %v", expected, cfg.Deny)
+		}
+	})
+
+	t.Run("Invalid flag value is an error", func(t *testing.T) {
+		if _, _, err := loadConfig([]string{"--continue-prompts=always"}); err == nil {
+			t.Error("Expected an error")
+		}
+		if _, _, err := loadConfig([]string{"--quiet-hours=23:00"}); err == nil {
+			t.Error("Expected an error for a quiet hours window without an end")
+		}
+		if _, _, err := loadConfig([]string{"--tool-policy=Bash=sometimes"}); err == nil {
+			t.Error("Expected an error for an unknown tool policy action")
+		}
+		if _, _, err := loadConfig([]string{"--auto-approve-pattern=go test ("}); err == nil {
+			t.Error("Expected an error for an invalid auto-approve pattern")
+		}
+		if _, _, err := loadConfig([]string{"--auto-approve-pattern="}); err == nil {
+			t.Error("Expected an error for an empty auto-approve pattern")
+		}
+		if _, _, err := loadConfig([]string{"--auto-approve-tools=Edit,,Write"}); err == nil {
+			t.Error("Expected an error for an empty tool name")
+		}
+		if _, _, err := loadConfig([]string{"--auto-reject-pattern=[a-"}); err == nil {
+			t.Error("Expected an error for an invalid auto-reject pattern")
+		}
+		if _, _, err := loadConfig([]string{"--delays=auto_approve=20"}); err == nil {
+			t.Error("Expected an error for an unknown delay")
+		}
+		if _, _, err := loadConfig([]string{"--reject-message=Rejected {cmd}"}); err == nil {
+			t.Error("Expected an error for an unknown reject message placeholder")
+		}
+	})
+}
+
+func TestLogPath(t *testing.T) {
+	cfg := config.Default()
+	if got := logPath(&cfg, "audit.jsonl"); got != "audit.jsonl" {
+		t.Errorf("Expected a path in the working directory without log_dir, got %q", got)
+	}
+
+	cfg.LogDir = "/var/log/dcode"
+	if got := logPath(&cfg, "audit.jsonl"); got != "/var/log/dcode/audit.jsonl" {
+		t.Errorf("Expected a relative path in log_dir, got %q", got)
+	}
+	if got := logPath(&cfg, "/tmp/audit.jsonl"); got != "/tmp/audit.jsonl" {
+		t.Errorf("Expected an absolute path to be kept, got %q", got)
+	}
+	if got := logOptions(&cfg).Path; got != "/var/log/dcode/"+debug.DefaultPath {
+		t.Errorf("Expected the debug log in log_dir, got %q", got)
+	}
+}
+
+func TestLoadConfigImportsClaudeSettings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := chdirToRepo(t)
+	if _, err := claudesettings.AddPermission(claudesettings.ProjectPath(repo), claudesettings.ListDeny, "Bash(git push:*)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := claudesettings.AddPermission(claudesettings.LocalPath(repo), claudesettings.ListAllow, "Bash(make)"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := loadConfig(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(cfg.Approve) != 0 || len(cfg.Deny) != 0 {
+		t.Errorf("Expected Claude settings to be ignored by default, got %+v and %+v", cfg.Approve, cfg.Deny)
+	}
+
+	cfg, _, err = loadConfig([]string{"--import-claude-settings"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(cfg.Approve) != 1 || cfg.Approve[0].Command != "^make$" {
+		t.Errorf("Expected the allow entry as an approve rule, got %+v", cfg.Approve)
+	}
+	if len(cfg.Deny) != 1 || !cfg.Deny[0].Matches(parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"git push -f"}}) {
+		t.Errorf("Expected the deny entry as a deny rule, got %+v", cfg.Deny)
+	}
+}
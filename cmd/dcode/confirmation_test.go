@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+var confirmationDialogLines = []string{
+	"⏺ Bash(rm -rf build)",
+	"╭──────────────────────────────────────────────╮",
+	"│ Bash command                                 │",
+	"│   rm -rf build                               │",
+	"│ Do you want to proceed?                      │",
+	"│ ❯ 1. Yes                                     │",
+	"│   2. Yes, and don't ask again for rm commands │",
+	"│   3. No, and tell Claude what to do (esc)    │",
+	"╰──────────────────────────────────────────────╯",
+}
+
+var areYouSureLines = []string{
+	"╭──────────────────────────────────────────────╮",
+	"│ Are you sure you want to delete build?       │",
+	"│ ❯ 1. Yes                                     │",
+	"│   2. No                                      │",
+	"╰──────────────────────────────────────────────╯",
+}
+
+func TestConfirmationFollowsApproval(t *testing.T) {
+	robot := NewAppRobot(t).
+		SetDialogChoice("1").
+		ReceiveClaudeText(confirmationDialogLines...).
+		AssertDialogCaptured().
+		ReceiveClaudeText(areYouSureLines...).
+		// The confirmation is answered without a second dialog
+		AssertButtonCount(3)
+
+	if output := robot.GetTerminalOutput(); output != "11" {
+		t.Errorf("Expected the approval to be confirmed, got: %q", output)
+	}
+}
+
+func TestConfirmationFollowsRejection(t *testing.T) {
+	robot := NewAppRobot(t).
+		SetDialogChoice("3").
+		ReceiveClaudeText(confirmationDialogLines...).
+		AssertDialogCaptured().
+		ReceiveClaudeText(areYouSureLines...).
+		AssertButtonCount(3)
+
+	if output := robot.GetTerminalOutput(); output != "32" {
+		t.Errorf("Expected the confirmation to be declined, got: %q", output)
+	}
+}
+
+func TestConfirmationOfForbiddenRequestIsRejected(t *testing.T) {
+	robot := NewAppRobot(t).
+		SetDialogChoice("1").
+		ReceiveClaudeText(confirmationDialogLines...).
+		AssertDialogCaptured()
+
+	robot.ReloadConfig(func(cfg *config.Config) {
+		cfg.Forbid = []config.DenyRule{{Rule: config.Rule{Command: `^rm -rf`}, Message: "Deleting is forbidden."}}
+	}).
+		ReceiveClaudeText(
+			"╭──────────────────────────────────────────────╮",
+			"│ Bash command                                 │",
+			"│   rm -rf build                               │",
+			"│ Are you sure you want to delete build?       │",
+			"│ ❯ 1. Yes                                     │",
+			"│   2. No                                      │",
+			"╰──────────────────────────────────────────────╯",
+		)
+	time.Sleep(denyRuleWaitTime)
+
+	robot.AssertTerminalContains("Deleting is forbidden.")
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "12") {
+		t.Errorf("Expected the confirmation to be declined, got: %q", output)
+	}
+}
+
+func TestConfirmationWithoutRecentDecisionIsIgnored(t *testing.T) {
+	robot := NewAppRobot(t).
+		ReceiveClaudeText(areYouSureLines...).
+		AssertNoDialogCaptured()
+
+	if output := robot.GetTerminalOutput(); output != "" {
+		t.Errorf("Expected no input for an unrelated confirmation, got: %q", output)
+	}
+}
+
+func TestConfirmationAfterWindowIsIgnored(t *testing.T) {
+	robot := NewAppRobot(t).
+		SetDialogChoice("1").
+		ReceiveClaudeText(confirmationDialogLines...).
+		AssertDialogCaptured().
+		SetFakeTime(time.Date(2023, 1, 1, 12, 1, 0, 0, time.UTC)).
+		ReceiveClaudeText(areYouSureLines...)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected only the first answer, got: %q", output)
+	}
+}
+
+func TestFollowUpDialogForApprovedRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		answer   string
+		command  string
+		at       time.Duration
+		expected string
+	}{
+		{"same request", "1", "rm -rf build", 5 * time.Second, "11"},
+		{"after rejection", "2", "rm -rf build", 5 * time.Second, "22"},
+		{"other request", "1", "rm -rf dist", 5 * time.Second, "11"},
+		{"after window", "1", "rm -rf build", time.Minute, "11"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			robot := NewAppRobot(t).
+				Configure(func(cfg *config.Config) { cfg.Delays.DialogResetMs = 0 }).
+				SetDialogChoice(tc.answer).
+				ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+				AssertDialogCaptured().
+				ClearCapturedDialog().
+				SetFakeTime(after(tc.at)).
+				ReceiveClaudeText(askedAs(tc.command, "Do you want to run it now?")...)
+			time.Sleep(50 * time.Millisecond)
+
+			if output := robot.GetTerminalOutput(); output != tc.expected {
+				t.Errorf("Expected %q, got: %q", tc.expected, output)
+			}
+			if tc.name == "same request" {
+				robot.AssertNoDialogCaptured()
+			} else {
+				robot.AssertDialogCaptured()
+			}
+		})
+	}
+}
+
+func TestConfirmationAfterAnotherPromptIsIgnored(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.Delays.DialogResetMs = 0 }).
+		SetDialogChoice("1").
+		ReceiveClaudeText(confirmationDialogLines...).
+		AssertDialogCaptured().
+		LeaveDialogsUnanswered().
+		ReceiveClaudeText(askedAs("ls", "Do you want to run ls?")...).
+		AssertDialogTextContains("ls").
+		ReceiveClaudeText(areYouSureLines...)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the confirmation not to be answered for another prompt, got: %q", output)
+	}
+}
+
+func TestConfirmationOfAnotherRequestAsks(t *testing.T) {
+	robot := NewAppRobot(t).
+		SetDialogChoice("1").
+		ReceiveClaudeText(confirmationDialogLines...).
+		AssertDialogCaptured().
+		SetDialogChoice("2").
+		ReceiveClaudeText(
+			"╭──────────────────────────────────────────────╮",
+			"│ Bash command                                 │",
+			"│   rm -rf dist                                │",
+			"│ Are you sure you want to delete dist?        │",
+			"│ ❯ 1. Yes                                     │",
+			"│   2. No                                      │",
+			"╰──────────────────────────────────────────────╯",
+		).
+		AssertButtonCount(2)
+
+	if output := robot.GetTerminalOutput(); output != "12" {
+		t.Errorf("Expected the user's answer to the confirmation, got: %q", output)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestContinuePrompts(t *testing.T) {
+	continueLines := []string{
+		"Login successful.",
+		"Press Enter to continue…",
+	}
+
+	t.Run("Ignored by default", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			ReceiveClaudeText(continueLines...).
+			AssertNoDialogCaptured()
+
+		if strings.Contains(robot.GetTerminalOutput(), SubmitKey) {
+			t.Errorf("Expected no Enter to be sent, got: %q", robot.GetTerminalOutput())
+		}
+	})
+
+	t.Run("Auto mode sends Enter", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(func(cfg *config.Config) { cfg.ContinuePrompts = config.ContinuePromptsAuto }).
+			ReceiveClaudeText(continueLines...).
+			AssertNoDialogCaptured()
+
+		if robot.GetTerminalOutput() != SubmitKey {
+			t.Errorf("Expected exactly one Enter to be sent, got: %q", robot.GetTerminalOutput())
+		}
+	})
+
+	t.Run("Dialog mode shows OK dialog before sending Enter", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(func(cfg *config.Config) { cfg.ContinuePrompts = config.ContinuePromptsDialog }).
+			ReceiveClaudeText(continueLines...).
+			AssertDialogText("Press Enter to continue…").
+			AssertButtonCount(1).
+			AssertButton(0, "OK").
+			AssertTerminalContains(SubmitKey)
+	})
+
+	t.Run("Re-rendered prompt is acknowledged once", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(func(cfg *config.Config) { cfg.ContinuePrompts = config.ContinuePromptsAuto }).
+			ReceiveClaudeText("Press any key to continue", "Press any key to continue")
+		time.Sleep(100 * time.Millisecond)
+
+		if count := strings.Count(robot.GetTerminalOutput(), SubmitKey); count != 1 {
+			t.Errorf("Expected one Enter for a re-rendered prompt, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// crashReports returns the contents of the crash reports in dir
+func crashReports(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, string(data))
+	}
+	return reports
+}
+
+func TestCrashReportMasksSecrets(t *testing.T) {
+	dir := t.TempDir()
+	robot := NewAppRobot(t).
+		UseCrashDir(dir).
+		ReceiveClaudeText("Connecting with psql postgres://app:hunter2@db/prod", "Reading the schema")
+
+	path := robot.app.handler.reportCrash("unexpected line: postgres://app:hunter2@db/prod", []byte("goroutine 1 [running]:\n"))
+	if path == "" {
+		t.Fatal("Expected a report to be written")
+	}
+	reports := crashReports(t, dir)
+	if len(reports) != 1 {
+		t.Fatalf("Expected one report, got %d", len(reports))
+	}
+	report := reports[0]
+	for _, expected := range []string{
+		"Panic:    unexpected line: postgres://app:[REDACTED]@db/prod\n",
+		"  mode: dialog\n",
+		"  Connecting with psql postgres://app:[REDACTED]@db/prod\n  Reading the schema\n",
+		"Stack:\ngoroutine 1 [running]:\n",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "hunter2") {
+		t.Errorf("Expected the password to be masked, got:\n%s", report)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); !strings.Contains(notification, path) {
+		t.Errorf("Expected a notification pointing to %s, got %q", path, notification)
+	}
+}
+
+func TestCrashInGoroutineIsReported(t *testing.T) {
+	dir := t.TempDir()
+	robot := NewAppRobot(t).UseCrashDir(dir)
+
+	recovered := make(chan any)
+	go func() {
+		defer func() { recovered <- recover() }()
+		defer robot.app.handler.recoverCrash()
+		panic("parser failed")
+	}()
+
+	if r := <-recovered; r != "parser failed" {
+		t.Errorf("Expected the panic to continue after the report, got %v", r)
+	}
+	if reports := crashReports(t, dir); len(reports) != 1 || !strings.Contains(reports[0], "Panic:    parser failed\n") {
+		t.Errorf("Expected a report of the panic, got %q", reports)
+	}
+}
+
+func TestCrashWithoutCrashDir(t *testing.T) {
+	robot := NewAppRobot(t)
+	if path := robot.app.handler.reportCrash("parser failed", nil); path != "" {
+		t.Errorf("Expected no report without a crash directory, got %s", path)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Errorf("Expected no notification, got %q", notification)
+	}
+}
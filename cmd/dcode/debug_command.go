@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/control"
+	"github.com/takahirom/dialog-code/internal/deduplication"
+)
+
+// debugUsage describes the "dcode debug" subcommand
+const debugUsage = "usage: dcode debug dedup [--pid=PID]"
+
+// controlDedupCommand is the control socket command that reports what a
+// session remembers to tell repeated prompts from new ones, as JSON
+const controlDedupCommand = "dedup"
+
+// runDebugCommand runs "dcode debug" with the arguments after "debug". "dcode
+// debug dedup" prints the prompts a running session has seen recently and
+// its cooldowns, which hold off dialogs for prompts taken as repeats. --pid
+// picks the session when more than one is running.
+func runDebugCommand(argv []string, dir string, out io.Writer) error {
+	var topic string
+	pid := 0
+	for _, arg := range argv {
+		if value, found := strings.CutPrefix(strings.TrimLeft(arg, "-"), "pid="); found && strings.HasPrefix(arg, "-") {
+			number, err := strconv.Atoi(value)
+			if err != nil || number <= 0 {
+				return fmt.Errorf("invalid pid: %s", value)
+			}
+			pid = number
+		} else if topic == "" && !strings.HasPrefix(arg, "-") {
+			topic = arg
+		} else {
+			return errors.New(debugUsage)
+		}
+	}
+	if topic != controlDedupCommand {
+		return errors.New(debugUsage)
+	}
+
+	pid, err := sessionPID(dir, pid)
+	if err != nil {
+		return err
+	}
+	reply, err := control.Send(control.SocketPath(dir, pid), controlDedupCommand)
+	if err != nil {
+		return fmt.Errorf("dcode session %d: %w", pid, err)
+	}
+	var stats deduplication.Stats
+	if err := json.Unmarshal([]byte(reply), &stats); err != nil {
+		return fmt.Errorf("dcode session %d: unexpected reply: %w", pid, err)
+	}
+	printDedupStats(pid, stats, out)
+	return nil
+}
+
+// printDedupStats writes stats of the session with process ID pid to out
+func printDedupStats(pid int, stats deduplication.Stats, out io.Writer) {
+	fmt.Fprintf(out, "Session %d: %d recent prompts, %d of %d cooldowns running\n", pid, len(stats.Prompts), stats.ActiveCooldowns(), len(stats.Cooldowns))
+	if len(stats.Prompts) > 0 {
+		fmt.Fprintln(out, "Prompts:")
+	}
+	for _, prompt := range stats.Prompts {
+		fmt.Fprintf(out, "  %8s ago  %3dx  %s\n", prompt.Age.Round(time.Millisecond), prompt.Count, truncateText(prompt.Key, 100))
+	}
+	if len(stats.Cooldowns) > 0 {
+		fmt.Fprintln(out, "Cooldowns:")
+	}
+	for _, cooldown := range stats.Cooldowns {
+		state := "over"
+		if cooldown.Remaining > 0 {
+			state = cooldown.Remaining.Round(time.Millisecond).String() + " left"
+		}
+		fmt.Fprintf(out, "  %8s ago  %-10s  %s\n", cooldown.Age.Round(time.Millisecond), state, truncateText(cooldown.Key, 100))
+	}
+}
+
+// truncateText shortens text to at most limit characters, marking the cut
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestDialogTitleMarksRisk(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{"low risk", "ls -la", "dcode · Claude"},
+		{"medium risk", "rm notes.txt", "🟡 dcode · Claude"},
+		{"high risk", "git push --force", "🔴 dcode · Claude"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var title string
+			robot := NewAppRobot(t).Configure(func(cfg *config.Config) {
+				cfg.Dialog = config.DialogStyle{Title: "dcode · Claude", RiskPrefix: config.RiskPrefixes{Medium: "🟡 ", High: "🔴 "}}
+			})
+			robot.app.SetTitledPermissionCallback(func(t, message string, buttons []string, defaultButton string) string {
+				title = t
+				return "1"
+			})
+			robot.ReceiveClaudeText(bashDialogLines(tc.command)...).
+				AssertTerminalContains("1")
+
+			if title != tc.expected {
+				t.Errorf("Expected the title %q, got %q", tc.expected, title)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/debug"
+	"github.com/takahirom/dialog-code/internal/remote"
+)
+
+// digestUsage describes the "dcode digest" subcommand
+const digestUsage = "usage: dcode digest [--date=YYYY-MM-DD] [--send] [dcode options]"
+
+// DigestTimeoutSec is how long delivering a digest may take
+const DigestTimeoutSec = 30
+
+// digestMarkerPrefix starts the names of the files that record which day's
+// digest was sent, so only one of several sessions sends it
+const digestMarkerPrefix = "digest-sent-"
+
+// runDigestCommand runs "dcode digest" with the arguments after "digest". It
+// prints the digest of the --audit-log entries of the day before now, or of
+// --date, or with --send delivers it as digest in the config file says.
+func runDigestCommand(argv []string, now time.Time, out io.Writer) error {
+	cfg, args, err := loadConfig(argv)
+	if err != nil {
+		return err
+	}
+	day := startOfDay(now).AddDate(0, 0, -1)
+	send := false
+	for _, arg := range joinOptionValues(args, "date") {
+		if arg == "-send" || arg == "--send" {
+			send = true
+		} else if value, found := strings.CutPrefix(strings.TrimLeft(arg, "-"), "date="); found && strings.HasPrefix(arg, "-") {
+			day, err = time.ParseInLocation(historyDateFormat, value, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid date %q: use YYYY-MM-DD", value)
+			}
+		} else {
+			return errors.New(digestUsage)
+		}
+	}
+	if cfg.AuditLog == "" {
+		return errors.New("no audit log to summarize; set audit_log in the config file or pass --audit-log=PATH")
+	}
+	if send && !cfg.Digest.Ntfy && cfg.Digest.SlackWebhook == "" {
+		return errors.New("nowhere to send the digest; set digest.ntfy or digest.slack_webhook in the config file")
+	}
+
+	entries, err := newAuditLog(&cfg).Entries(audit.Filter{Since: day, Until: day.AddDate(0, 0, 1)})
+	if err != nil {
+		return err
+	}
+	text := composeDigest(day, entries)
+	if !send {
+		fmt.Fprintln(out, text)
+		return nil
+	}
+	if err := deliverDigest(&cfg, text); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Sent the digest for %s\n", day.Format(historyDateFormat))
+	return nil
+}
+
+// composeDigest summarizes entries, the answers given on day, in a few lines
+// for a chat message or notification
+func composeDigest(day time.Time, entries []audit.Entry) string {
+	title := "dcode digest for " + day.Format("Mon 2 Jan 2006")
+	if len(entries) == 0 {
+		return title + "\nNo answers"
+	}
+
+	stats := summarizeHistory(entries, TopStatsCount)
+	var text strings.Builder
+	fmt.Fprintln(&text, title)
+	fmt.Fprintf(&text, "%d answers: %d approved, %d rejected, %d other\n", stats.Total, stats.Approved, stats.Rejected, stats.Answered)
+	fmt.Fprintf(&text, "%d asked in a dialog, %d answered by rules and modes\n", stats.Responses, stats.Total-stats.Responses)
+
+	projects := map[string]*decisionStat{}
+	rejected := map[string]*decisionStat{}
+	deciders := map[string]string{}
+	for _, entry := range entries {
+		if entry.Dir != "" {
+			countIn(projects, filepath.Base(entry.Dir), entry.Action)
+		}
+		if entry.Action == audit.Rejected {
+			countIn(rejected, entry.Request, entry.Action)
+			deciders[entry.Request] = entry.DecidedBy
+		}
+	}
+	if len(projects) > 0 {
+		var names []string
+		for _, project := range ranked(projects, 0, TopStatsCount) {
+			names = append(names, fmt.Sprintf("%s %d", project.Name, project.Total))
+		}
+		fmt.Fprintf(&text, "Projects: %s\n", strings.Join(names, ", "))
+	}
+
+	if len(rejected) > 0 {
+		fmt.Fprint(&text, "Rejected:")
+		for _, request := range ranked(rejected, 0, TopStatsCount) {
+			fmt.Fprintf(&text, "\n  %dx %s (%s)", request.Total, truncateText(request.Name, 100), deciders[request.Name])
+		}
+		if len(rejected) > TopStatsCount {
+			fmt.Fprintf(&text, "\n  and %d more", len(rejected)-TopStatsCount)
+		}
+	}
+	return strings.TrimSuffix(text.String(), "\n")
+}
+
+// deliverDigest sends text to everywhere digest in cfg names
+func deliverDigest(cfg *config.Config, text string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DigestTimeoutSec*time.Second)
+	defer cancel()
+	title, _, _ := strings.Cut(text, "\n")
+	var errs []error
+	if cfg.Digest.Ntfy {
+		errs = append(errs, remote.Ntfy{TopicURL: cfg.Remote.NtfyURL, Token: cfg.Remote.Token}.Summary(ctx, title, text, cfg.Digest.Email))
+	}
+	if cfg.Digest.SlackWebhook != "" {
+		errs = append(errs, remote.Slack{WebhookURL: cfg.Digest.SlackWebhook}.Post(ctx, text))
+	}
+	return errors.Join(errs...)
+}
+
+// scheduleDigest sends the digest cfg configures every day while the session
+// runs, first catching up on the latest one if no session has sent it yet.
+// Sent digests are recorded in dir.
+func scheduleDigest(cfg *config.Config, dir string) {
+	if cfg.Digest.Time == "" || dir == "" {
+		return
+	}
+	go func() {
+		for {
+			due := cfg.Digest.Next(time.Now().AddDate(0, 0, -1))
+			sendDailyDigest(cfg, dir, due)
+			time.Sleep(time.Until(due.AddDate(0, 0, 1)))
+		}
+	}()
+}
+
+// sendDailyDigest sends the digest due at due, of the day before, unless
+// another session in dir already did or there's nothing to report
+func sendDailyDigest(cfg *config.Config, dir string, due time.Time) {
+	day := startOfDay(due).AddDate(0, 0, -1)
+	claimed, err := claimDigest(dir, day)
+	if err != nil {
+		debug.Warn("failed to record the digest", "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	entries, err := newAuditLog(cfg).Entries(audit.Filter{Since: day, Until: day.AddDate(0, 0, 1)})
+	if err != nil {
+		debug.Warn("failed to read the audit log for the digest", "error", err)
+		return
+	}
+	if len(entries) == 0 {
+		debug.Info("no answers for the digest", "day", day.Format(historyDateFormat))
+		return
+	}
+	if err := deliverDigest(cfg, composeDigest(day, entries)); err != nil {
+		debug.Warn("failed to send the digest", "error", err)
+		return
+	}
+	debug.Info("sent the digest", "day", day.Format(historyDateFormat), "answers", len(entries))
+}
+
+// claimDigest records in dir that the digest of day is being sent,
+// reporting false if it already was, and forgets earlier days
+func claimDigest(dir string, day time.Time) (bool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return false, err
+	}
+	marker := digestMarkerPrefix + day.Format(historyDateFormat)
+	file, err := os.OpenFile(filepath.Join(dir, marker), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	file.Close()
+
+	old, _ := filepath.Glob(filepath.Join(dir, digestMarkerPrefix+"*"))
+	for _, path := range old {
+		if filepath.Base(path) < marker {
+			os.Remove(path)
+		}
+	}
+	return true, nil
+}
+
+// startOfDay returns midnight at the start of t's day
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// digestEntries are answers given in two projects on 10 March 2025
+func digestEntries() []audit.Entry {
+	night := time.Date(2025, 3, 10, 1, 0, 0, 0, time.Local)
+	return []audit.Entry{
+		{ID: "0000aaaa", Time: night, Dir: "/work/app", Tool: "Bash", Request: "Bash: go test ./...", Action: audit.Approved, DecidedBy: "approve rule 1"},
+		{ID: "0000bbbb", Time: night.Add(time.Hour), Dir: "/work/app", Tool: "Bash", Request: "Bash: git push --force", Action: audit.Rejected, DecidedBy: "deny rule 2"},
+		{ID: "0000cccc", Time: night.Add(2 * time.Hour), Dir: "/work/app", Tool: "Bash", Request: "Bash: git push --force", Action: audit.Rejected, DecidedBy: "deny rule 2"},
+		{ID: "0000dddd", Time: night.Add(3 * time.Hour), Dir: "/work/lib", Tool: "Edit", Request: "Edit: README.md", Action: audit.Approved, DecidedBy: audit.User, WaitMs: 4000},
+	}
+}
+
+// fakeSlack is a Slack incoming webhook that keeps what's posted to it
+type fakeSlack struct {
+	texts []string
+	mutex sync.Mutex
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var message map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.texts = append(f.texts, message["text"])
+}
+
+func (f *fakeSlack) Texts() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.texts...)
+}
+
+func TestComposeDigest(t *testing.T) {
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+
+	expected := `dcode digest for Mon 10 Mar 2025
+4 answers: 2 approved, 2 rejected, 0 other
+1 asked in a dialog, 3 answered by rules and modes
+Projects: app 3, lib 1
+Rejected:
+  2x Bash: git push --force (deny rule 2)`
+	if digest := composeDigest(day, digestEntries()); digest != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, digest)
+	}
+	if digest := composeDigest(day, nil); digest != "dcode digest for Mon 10 Mar 2025\nNo answers" {
+		t.Errorf("Expected a digest without answers, got:\n%s", digest)
+	}
+}
+
+func TestRunDigestCommand(t *testing.T) {
+	path := historyLog(t, digestEntries()...)
+	morning := time.Date(2025, 3, 11, 8, 0, 0, 0, time.Local)
+
+	var out bytes.Buffer
+	if err := runDigestCommand([]string{"--audit-log=" + path}, morning, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "dcode digest for Mon 10 Mar 2025\n4 answers") {
+		t.Errorf("Expected the previous day's digest, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runDigestCommand([]string{"--audit-log=" + path, "--date", "2025-03-11"}, morning, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "No answers") {
+		t.Errorf("Expected no answers on --date, got:\n%s", out.String())
+	}
+
+	if err := runDigestCommand([]string{"--audit-log=" + path, "--send"}, morning, &out); err == nil || !strings.Contains(err.Error(), "nowhere to send") {
+		t.Errorf("Expected an error without a destination, got %v", err)
+	}
+	if err := runDigestCommand([]string{"--audit-log=" + path, "--date=yesterday"}, morning, &out); err == nil {
+		t.Error("Expected an invalid date to be an error")
+	}
+	if err := runDigestCommand(nil, morning, &out); err == nil || !strings.Contains(err.Error(), "no audit log") {
+		t.Errorf("Expected an error without an audit log, got %v", err)
+	}
+}
+
+func TestRunDigestCommandSends(t *testing.T) {
+	path := historyLog(t, digestEntries()...)
+	slack := &fakeSlack{}
+	server := httptest.NewServer(slack)
+	defer server.Close()
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("digest:\n  slack_webhook: "+server.URL+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runDigestCommand([]string{"--config=" + configFile, "--audit-log=" + path, "--send"}, time.Date(2025, 3, 11, 8, 0, 0, 0, time.Local), &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if texts := slack.Texts(); len(texts) != 1 || !strings.HasPrefix(texts[0], "dcode digest for Mon 10 Mar 2025") {
+		t.Errorf("Expected the digest to be posted, got %q", texts)
+	}
+	if out.String() != "Sent the digest for 2025-03-10\n" {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+}
+
+func TestSendDailyDigestOnce(t *testing.T) {
+	path := historyLog(t, digestEntries()...)
+	slack := &fakeSlack{}
+	server := httptest.NewServer(slack)
+	defer server.Close()
+	cfg := config.Default()
+	cfg.AuditLog = path
+	cfg.Digest = config.Digest{Time: "08:00", SlackWebhook: server.URL}
+	dir := t.TempDir()
+	due := time.Date(2025, 3, 11, 8, 0, 0, 0, time.Local)
+
+	// Two sessions wake up for the same digest
+	sendDailyDigest(&cfg, dir, due)
+	sendDailyDigest(&cfg, dir, due)
+	if texts := slack.Texts(); len(texts) != 1 {
+		t.Fatalf("Expected the digest to be sent once, got %q", texts)
+	}
+
+	// A day without answers sends nothing, and forgets the earlier day
+	sendDailyDigest(&cfg, dir, due.AddDate(0, 0, 1))
+	if texts := slack.Texts(); len(texts) != 1 {
+		t.Errorf("Expected no digest without answers, got %q", texts)
+	}
+	markers, _ := filepath.Glob(filepath.Join(dir, digestMarkerPrefix+"*"))
+	if len(markers) != 1 || filepath.Base(markers[0]) != digestMarkerPrefix+"2025-03-11" {
+		t.Errorf("Expected only the latest day to be recorded, got %v", markers)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// digestEvery auto-approves everything except make clean, which is denied,
+// with a digest every minutes
+func digestEvery(minutes int) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		capAutoApprovals(0)(cfg)
+		cfg.DigestMinutes = minutes
+		cfg.Deny = []config.DenyRule{{Rule: config.Rule{Command: `^make clean`}}}
+	}
+}
+
+func TestDigestSummarizesDecisions(t *testing.T) {
+	robot := NewAppRobot(t).Configure(digestEvery(15))
+	approveInARow(robot, 0, "make", "make clean", "make lint")
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Fatalf("Expected no notification before the digest is due, got: %q", notification)
+	}
+
+	robot.app.handler.postDigest()
+	notification := robot.dialog.GetCapturedNotification()
+	for _, expected := range []string{
+		"dcode answered 3 requests without asking (2 approved, 1 rejected):",
+		"• approved: Bash: make\n• rejected: Bash: make clean\n• approved: Bash: make lint",
+	} {
+		if !strings.Contains(notification, expected) {
+			t.Errorf("Expected the digest to contain %q, got: %q", expected, notification)
+		}
+	}
+
+	t.Run("Each digest starts over", func(t *testing.T) {
+		robot.dialog.Notify("")
+		robot.app.handler.postDigest()
+		if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+			t.Errorf("Expected no digest without new decisions, got: %q", notification)
+		}
+
+		approveInARow(robot, 5, "make test")
+		robot.app.handler.postDigest()
+		if notification := robot.dialog.GetCapturedNotification(); !strings.HasPrefix(notification, "dcode answered 1 requests") {
+			t.Errorf("Expected a digest of the new decision only, got: %q", notification)
+		}
+	})
+}
+
+func TestNoDigestByDefault(t *testing.T) {
+	robot := NewAppRobot(t).Configure(digestEvery(0))
+	approveInARow(robot, 0, "make", "make clean")
+
+	robot.app.handler.postDigest()
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Errorf("Expected no digest, got: %q", notification)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/claudesettings"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/control"
+	"github.com/takahirom/dialog-code/internal/crash"
+	"github.com/takahirom/dialog-code/internal/state"
+)
+
+// doctorUsage describes the "dcode doctor" subcommand
+const doctorUsage = "usage: dcode doctor [--config=PATH] [dcode options]"
+
+// doctorDialogScript shows a dialog the way dcode does, closing it by itself
+// so the check doesn't wait for anyone
+const doctorDialogScript = `display dialog "dcode doctor: dialogs work" with title "Claude Permission" buttons {"OK"} default button "OK" giving up after 3`
+
+// Results of a doctor check
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "FAIL"
+)
+
+// doctorCheck is the result of checking one thing dcode depends on
+type doctorCheck struct {
+	Name   string
+	Status string // DoctorOK, DoctorWarn or DoctorFail
+	Detail string
+	Fix    string // What to do about a warning or failure
+}
+
+// doctorEnv is what "dcode doctor" inspects, replaced in tests
+type doctorEnv struct {
+	GOOS       string
+	LookPath   func(file string) (string, error)
+	RunScript  func(script string) ([]byte, error) // Runs AppleScript with osascript
+	IsTerminal func(fd int) bool
+	TermSize   func(fd int) (int, int, error)
+	Getenv     func(key string) string
+	Home       string
+	ProjectDir string
+}
+
+// doctorPath is a directory dcode writes to, or "" if it's unknown
+type doctorPath struct {
+	Name string
+	Dir  string
+}
+
+// systemDoctorEnv returns the environment dcode runs in
+func systemDoctorEnv() doctorEnv {
+	home, _ := os.UserHomeDir()
+	return doctorEnv{
+		GOOS:     runtime.GOOS,
+		LookPath: exec.LookPath,
+		RunScript: func(script string) ([]byte, error) {
+			return exec.Command("osascript", "-e", script).CombinedOutput()
+		},
+		IsTerminal: term.IsTerminal,
+		TermSize:   term.GetSize,
+		Getenv:     os.Getenv,
+		Home:       home,
+		ProjectDir: projectDir(),
+	}
+}
+
+// runDoctorCommand runs "dcode doctor" with the arguments after "doctor"
+func runDoctorCommand(argv []string, out io.Writer) error {
+	return runDoctor(argv, systemDoctorEnv(), out)
+}
+
+// runDoctor checks everything dcode needs to show dialogs and keep its
+// files in env, with the config and dcode options in argv, and prints each
+// result to out with a fix for anything wrong. It fails if any check does.
+func runDoctor(argv []string, env doctorEnv, out io.Writer) error {
+	cfg, args, err := loadConfig(argv)
+	if err == nil && len(args) > 0 {
+		return errors.New(doctorUsage)
+	}
+
+	checks := []doctorCheck{checkConfig(argv, err)}
+	if err != nil {
+		cfg = config.Default()
+	}
+	checks = append(checks, checkClaude(env))
+	checks = append(checks, checkDialogs(env)...)
+	checks = append(checks, checkTerminal(env))
+	checks = append(checks, checkClaudeSettings(env)...)
+	checks = append(checks, checkPaths(&cfg, env)...)
+
+	failed := 0
+	for _, check := range checks {
+		fmt.Fprintf(out, "%-4s  %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Fix != "" {
+			fmt.Fprintf(out, "      fix: %s\n", check.Fix)
+		}
+		if check.Status == DoctorFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("dcode doctor found %d problem(s)", failed)
+	}
+	return nil
+}
+
+// checkConfig reports whether the config file and options in argv, which
+// loading failed with err, are valid
+func checkConfig(argv []string, err error) doctorCheck {
+	path, explicit := configPath(argv)
+	check := doctorCheck{Name: "config", Status: DoctorOK, Detail: path}
+	if _, statErr := os.Stat(path); errors.Is(statErr, fs.ErrNotExist) && !explicit {
+		check.Detail = "no config file, using the defaults"
+	}
+	if err != nil {
+		check.Status = DoctorFail
+		check.Detail = err.Error()
+		check.Fix = fmt.Sprintf("correct %s or the dcode options given; \"dcode rules\" lists the rules it holds", path)
+	}
+	return check
+}
+
+// checkClaude reports whether dcode can find the claude command to run
+func checkClaude(env doctorEnv) doctorCheck {
+	path, err := env.LookPath("claude")
+	if err != nil {
+		return doctorCheck{Name: "claude", Status: DoctorFail, Detail: "claude command not found",
+			Fix: "install Claude Code and make sure claude is on your PATH"}
+	}
+	return doctorCheck{Name: "claude", Status: DoctorOK, Detail: path}
+}
+
+// checkDialogs reports whether osascript is there and can show a dialog.
+// Without it every request is rejected, so it only fails on macOS; elsewhere
+// dcode only works with the remote backend or without dialogs.
+func checkDialogs(env doctorEnv) []doctorCheck {
+	path, err := env.LookPath("osascript")
+	if err != nil {
+		check := doctorCheck{Name: "osascript", Status: DoctorWarn, Detail: "osascript not found; dialogs can't be shown",
+			Fix: "use --auto-reject, --auto-approve or the remote risk policy action on this system"}
+		if env.GOOS == "darwin" {
+			check.Status = DoctorFail
+			check.Fix = "make sure /usr/bin is on your PATH"
+		}
+		return []doctorCheck{check}
+	}
+
+	checks := []doctorCheck{{Name: "osascript", Status: DoctorOK, Detail: path}}
+	output, err := env.RunScript(doctorDialogScript)
+	if err == nil {
+		return append(checks, doctorCheck{Name: "dialogs", Status: DoctorOK, Detail: "a test dialog was shown"})
+	}
+
+	message := strings.TrimSpace(string(output))
+	if message == "" {
+		message = err.Error()
+	}
+	check := doctorCheck{Name: "dialogs", Status: DoctorFail, Detail: "test dialog failed: " + message}
+	switch {
+	case strings.Contains(message, "-1743"):
+		check.Fix = "allow your terminal to control other apps in System Settings > Privacy & Security > Automation"
+	case strings.Contains(message, "-1713") || strings.Contains(message, "-10827"):
+		check.Fix = "run dcode from a terminal in a logged-in desktop session, not over SSH"
+	default:
+		check.Fix = "run osascript -e 'display dialog \"test\"' in this terminal to see why"
+	}
+	return append(checks, check)
+}
+
+// checkTerminal reports whether dcode runs in a terminal it can mirror
+// Claude's screen to
+func checkTerminal(env doctorEnv) doctorCheck {
+	check := doctorCheck{Name: "terminal", Status: DoctorWarn}
+	switch {
+	case !env.IsTerminal(int(os.Stdin.Fd())) || !env.IsTerminal(int(os.Stdout.Fd())):
+		check.Detail = "stdin or stdout isn't a terminal"
+		check.Fix = "run dcode directly in a terminal; piped input only suits non-interactive use"
+	case env.Getenv("TERM") == "" || env.Getenv("TERM") == "dumb":
+		check.Detail = fmt.Sprintf("TERM is %q", env.Getenv("TERM"))
+		check.Fix = "set TERM to your terminal's type, such as xterm-256color"
+	default:
+		width, height, err := env.TermSize(int(os.Stdout.Fd()))
+		if err != nil {
+			check.Detail = "terminal size unknown: " + err.Error()
+			check.Fix = "use a terminal that reports its size; Claude's screen may wrap wrongly"
+			break
+		}
+		check.Status = DoctorOK
+		check.Detail = fmt.Sprintf("%s, %dx%d", env.Getenv("TERM"), width, height)
+	}
+	return check
+}
+
+// checkClaudeSettings reports whether Claude's settings files can be read
+// and which hooks they run. dcode doesn't need any, but a hook that decides
+// on tool use answers requests before a dialog is shown.
+func checkClaudeSettings(env doctorEnv) []doctorCheck {
+	var checks []doctorCheck
+	for _, path := range []string{claudesettings.UserPath(env.Home), claudesettings.ProjectPath(env.ProjectDir), claudesettings.LocalPath(env.ProjectDir)} {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		check := doctorCheck{Name: "settings", Status: DoctorOK, Detail: path + ": no hooks"}
+		events, err := claudesettings.Hooks(path)
+		switch {
+		case err != nil:
+			check.Status = DoctorFail
+			check.Detail = err.Error()
+			check.Fix = "correct the file; Claude ignores settings it can't parse"
+		case slices.Contains(events, "PreToolUse") || slices.Contains(events, "PermissionRequest"):
+			check.Status = DoctorWarn
+			check.Detail = fmt.Sprintf("%s: hooks for %s", path, strings.Join(events, ", "))
+			check.Fix = "PreToolUse and PermissionRequest hooks can approve or deny requests without a dialog; make sure that's intended"
+		case len(events) > 0:
+			check.Detail = fmt.Sprintf("%s: hooks for %s", path, strings.Join(events, ", "))
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{Name: "settings", Status: DoctorOK, Detail: "no Claude settings files"})
+	}
+	return checks
+}
+
+// checkPaths reports whether dcode can write the files it keeps, as cfg
+// configures them
+func checkPaths(cfg *config.Config, env doctorEnv) []doctorCheck {
+	paths := []doctorPath{
+		{"cache", parentDir(approvals.DefaultPath())},
+		{"session state", parentDir(state.DefaultPath(env.ProjectDir))},
+		{"control", control.DefaultDir()},
+		{"crash reports", crash.DefaultDir()},
+	}
+	if cfg.Debug || cfg.LogLevel != "" {
+		paths = append(paths, doctorPath{"debug log", filepath.Dir(logOptions(cfg).Path)})
+	}
+	if cfg.AuditLog != "" {
+		paths = append(paths, doctorPath{"audit log", filepath.Dir(logPath(cfg, cfg.AuditLog))})
+	}
+
+	var checks []doctorCheck
+	for _, path := range paths {
+		check := doctorCheck{Name: path.Name, Status: DoctorOK, Detail: path.Dir}
+		if path.Dir == "" {
+			check.Status = DoctorWarn
+			check.Detail = "directory unknown"
+			check.Fix = "set HOME, or XDG_CACHE_HOME, so dcode can keep its files"
+		} else if err := checkWritable(path.Dir); err != nil {
+			check.Status = DoctorFail
+			check.Detail = err.Error()
+			check.Fix = fmt.Sprintf("make %s writable, or point dcode somewhere else with --log-dir or the config file", path.Dir)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkWritable returns an error unless a file can be created in dir, or in
+// its nearest existing parent if dcode would create dir itself
+func checkWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) || filepath.Dir(dir) == dir {
+			return err
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	file, err := os.CreateTemp(dir, ".dcode-doctor-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// parentDir returns the directory holding the file at path, or "" if path
+// is unknown
+func parentDir(path string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Dir(path)
+}
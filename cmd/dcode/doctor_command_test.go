@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/claudesettings"
+)
+
+// healthyDoctorEnv returns an environment on macOS where every check passes,
+// with its files kept in temporary directories
+func healthyDoctorEnv(t *testing.T) doctorEnv {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	return doctorEnv{
+		GOOS:       "darwin",
+		LookPath:   func(file string) (string, error) { return "/usr/bin/" + file, nil },
+		RunScript:  func(script string) ([]byte, error) { return []byte("button returned:OK, gave up:false\n"), nil },
+		IsTerminal: func(fd int) bool { return true },
+		TermSize:   func(fd int) (int, int, error) { return 120, 40, nil },
+		Getenv:     func(key string) string { return map[string]string{"TERM": "xterm-256color"}[key] },
+		Home:       t.TempDir(),
+		ProjectDir: t.TempDir(),
+	}
+}
+
+// runDoctorWith runs "dcode doctor" with args in env, returning its output
+func runDoctorWith(t *testing.T, env doctorEnv, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runDoctor(args, env, &out)
+	return out.String(), err
+}
+
+func TestDoctorPasses(t *testing.T) {
+	output, err := runDoctorWith(t, healthyDoctorEnv(t))
+	if err != nil {
+		t.Fatalf("Expected every check to pass, got %v:\n%s", err, output)
+	}
+	for _, expected := range []string{
+		"ok    config: no config file, using the defaults",
+		"ok    claude: /usr/bin/claude",
+		"ok    dialogs: a test dialog was shown",
+		"ok    terminal: xterm-256color, 120x40",
+		"ok    settings: no Claude settings files",
+		"ok    crash reports: ",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "fix:") {
+		t.Errorf("Expected no fixes, got:\n%s", output)
+	}
+}
+
+func TestDoctorReportsDialogProblems(t *testing.T) {
+	env := healthyDoctorEnv(t)
+	env.RunScript = func(script string) ([]byte, error) {
+		return []byte("execution error: Not authorized to send Apple events to System Events. (-1743)\n"), errors.New("exit status 1")
+	}
+
+	output, err := runDoctorWith(t, env)
+	if err == nil || !strings.Contains(err.Error(), "1 problem") {
+		t.Errorf("Expected one problem, got %v", err)
+	}
+	if !strings.Contains(output, "FAIL  dialogs: test dialog failed: execution error") || !strings.Contains(output, "Privacy & Security > Automation") {
+		t.Errorf("Expected the automation permission fix, got:\n%s", output)
+	}
+
+	env.LookPath = func(file string) (string, error) {
+		if file == "osascript" {
+			return "", errors.New("not found")
+		}
+		return "/usr/local/bin/" + file, nil
+	}
+	if output, _ = runDoctorWith(t, env); !strings.Contains(output, "FAIL  osascript: osascript not found") {
+		t.Errorf("Expected a missing osascript to fail on macOS, got:\n%s", output)
+	}
+	env.GOOS = "linux"
+	if output, err = runDoctorWith(t, env); err != nil || !strings.Contains(output, "warn  osascript: osascript not found") {
+		t.Errorf("Expected only a warning elsewhere, got %v:\n%s", err, output)
+	}
+}
+
+func TestDoctorReportsTerminalProblems(t *testing.T) {
+	env := healthyDoctorEnv(t)
+	env.Getenv = func(key string) string { return "" }
+	if output, _ := runDoctorWith(t, env); !strings.Contains(output, `warn  terminal: TERM is ""`) {
+		t.Errorf("Expected a warning about TERM, got:\n%s", output)
+	}
+
+	env.IsTerminal = func(fd int) bool { return false }
+	if output, _ := runDoctorWith(t, env); !strings.Contains(output, "warn  terminal: stdin or stdout isn't a terminal") {
+		t.Errorf("Expected a warning about the terminal, got:\n%s", output)
+	}
+}
+
+func TestDoctorReportsConfigAndPathProblems(t *testing.T) {
+	env := healthyDoctorEnv(t)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("mode: sometimes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runDoctorWith(t, env, "--config="+config); err == nil || !strings.Contains(output, "FAIL  config: ") || !strings.Contains(output, "fix: correct "+config) {
+		t.Errorf("Expected the invalid config to fail, got %v:\n%s", err, output)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runDoctorWith(t, env, "--audit-log="+filepath.Join(file, "logs", "audit.jsonl"))
+	if err == nil || !strings.Contains(output, "FAIL  audit log: ") {
+		t.Errorf("Expected the audit log path to fail, got %v:\n%s", err, output)
+	}
+
+	if _, err := runDoctorWith(t, env, "--unknown"); err == nil || !strings.Contains(err.Error(), "usage: dcode doctor") {
+		t.Errorf("Expected usage for an unknown argument, got %v", err)
+	}
+}
+
+func TestDoctorReportsClaudeSettings(t *testing.T) {
+	env := healthyDoctorEnv(t)
+	local := claudesettings.LocalPath(env.ProjectDir)
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte(`{"hooks": {"PreToolUse": [{"matcher": "Bash", "hooks": []}]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(claudesettings.ProjectPath(env.ProjectDir), []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runDoctorWith(t, env)
+	if err == nil || !strings.Contains(output, "FAIL  settings: invalid settings file") {
+		t.Errorf("Expected the invalid settings file to fail, got %v:\n%s", err, output)
+	}
+	if !strings.Contains(output, "warn  settings: "+local+": hooks for PreToolUse") {
+		t.Errorf("Expected a warning about the PreToolUse hook, got:\n%s", output)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+func TestPromptIsAnsweredOnce(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoApprove = true }).
+		ReceiveClaudeText(bashDialogLines("npm install")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	// A second path deciding on the same prompt, such as the quiescence timer
+	// racing the box's bottom border, must not type another answer
+	if err := robot.app.handler.sendAnswer(robot.app.handler.snapshotPrompt(""), "1"); err != errAlreadyAnswered {
+		t.Errorf("Expected the answered prompt to be refused, got: %v", err)
+	}
+	robot.app.handler.sendAutoApprove(decider{rule: "test"}, "1")
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected a single answer, got: %q", output)
+	}
+}
+
+func TestNewPromptIsAnswered(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoApprove = true
+			cfg.Delays.DialogResetMs = 0
+		})
+	approveInARow(robot, 0, "make", "make lint")
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); output != "11" {
+		t.Errorf("Expected each prompt to be answered, got: %q", output)
+	}
+}
+
+func TestAnswerTellsItsPromptAfterNextPromptAppeared(t *testing.T) {
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoApprove = true
+			cfg.Delays.DialogResetMs = 0
+		})
+	ids := []string{"first", "second"}
+	robot.app.handler.newRequestID = func() string {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	}
+	// The second prompt appears before the first one's answer is sent
+	robot.ReceiveClaudeText(askedAs("make", "Do you want to run make?")...)
+	robot.SetFakeTime(after(time.Minute)).
+		ReceiveClaudeText(askedAs("make lint", "Do you want to run make lint?")...)
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	var injected []string
+	for _, event := range recorder.Events(t) {
+		if event.Type == events.Injection {
+			injected = append(injected, event.RequestID)
+		}
+	}
+	if !reflect.DeepEqual(injected, []string{"first", "second"}) {
+		t.Errorf("Expected each answer tagged with its own prompt, got %v", injected)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// writeDialogLines returns a Write permission dialog for path
+func writeDialogLines(path string) []string {
+	return []string{
+		"⏺ Write(" + path + ")",
+		"╭──────────────────────────────────────────────╮",
+		"│ Create file                                  │",
+		fmt.Sprintf("│   file_path: %-32s│", path),
+		"│ Do you want to create this file?             │",
+		"│ ❯ 1. Yes                                     │",
+		"│   2. No, and tell Claude what to do (esc)    │",
+		"╰──────────────────────────────────────────────╯",
+	}
+}
+
+// chdirToRepo changes into a new git repository for the test and returns it
+func chdirToRepo(t *testing.T) string {
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(repo)
+	return repo
+}
+
+func TestEditScopeAllowsEditsInRepo(t *testing.T) {
+	repo := chdirToRepo(t)
+
+	for _, path := range []string{"src/main.go", filepath.Join(repo, "README.md")} {
+		NewAppRobot(t).
+			Configure(func(cfg *config.Config) { cfg.EditScope.RepoRoot = true }).
+			ReceiveClaudeText(writeDialogLines(path)...).
+			AssertDialogCaptured()
+	}
+}
+
+func TestEditScopeDeniesEditsOutside(t *testing.T) {
+	repo := chdirToRepo(t)
+
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.EditScope.RepoRoot = true }).
+		ReceiveClaudeText(writeDialogLines("../outside.txt")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the reject choice first, got: %q", output)
+	}
+	robot.AssertTerminalContains("outside the directories you may edit (" + repo + ")")
+}
+
+func TestEditScopeDialogOverridesApproval(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoApprove = true
+			cfg.EditScope = config.EditScope{Dirs: []string{t.TempDir()}, Outside: config.EditScopeActionDialog}
+		}).
+		SetDialogChoice("2").
+		ReceiveClaudeText(writeDialogLines("/tmp/notes.txt")...).
+		AssertDialogCaptured()
+
+	if output := robot.GetTerminalOutput(); output != "2" {
+		t.Errorf("Expected only the user's choice, got: %q", output)
+	}
+}
+
+func TestEditScopeIgnoresOtherTools(t *testing.T) {
+	chdirToRepo(t)
+
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.EditScope.RepoRoot = true }).
+		Configure(allowReadOnly).
+		ReceiveClaudeText(readDialogLines("/tmp/notes.txt")...).
+		AssertNoDialogCaptured()
+}
+
+func TestFindRepoRoot(t *testing.T) {
+	repo := t.TempDir()
+	nested := filepath.Join(repo, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".git"), []byte("gitdir: elsewhere\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if root := findRepoRoot(nested); root != repo {
+		t.Errorf("Expected %q, got %q", repo, root)
+	}
+}
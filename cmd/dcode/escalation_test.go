@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// escalationRecorder collects the messages of escalations
+type escalationRecorder struct {
+	messages []string
+	mutex    sync.Mutex
+}
+
+func (r *escalationRecorder) escalate(cfg *config.Config, message string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *escalationRecorder) Messages() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+func TestUnansweredDialogIsEscalated(t *testing.T) {
+	recorder := &escalationRecorder{}
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.Escalation.AfterSeconds = 1 }).
+		UseEscalation(recorder.escalate).
+		LeaveDialogsUnanswered().
+		ReceiveClaudeText(bashDialogLines("git push")...).
+		AssertDialogCaptured()
+	if messages := recorder.Messages(); len(messages) != 0 {
+		t.Fatalf("Expected no escalation before after_seconds, got %v", messages)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	expected := "A dialog has waited 1s for your answer: Bash: git push"
+	if messages := recorder.Messages(); len(messages) != 1 || messages[0] != expected {
+		t.Errorf("Expected one escalation %q, got %v", expected, messages)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); notification != expected {
+		t.Errorf("Expected the escalation to be posted as a notification, got %q", notification)
+	}
+}
+
+func TestAnsweredDialogIsNotEscalated(t *testing.T) {
+	recorder := &escalationRecorder{}
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.Escalation.AfterSeconds = 1
+			cfg.Escalation.Notify = false
+		}).
+		UseEscalation(recorder.escalate).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("git push")...).
+		AssertTerminalContains("1")
+
+	time.Sleep(1200 * time.Millisecond)
+	if messages := recorder.Messages(); len(messages) != 0 {
+		t.Errorf("Expected no escalation for an answered dialog, got %v", messages)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); strings.Contains(notification, "waited") {
+		t.Errorf("Expected no notification, got %q", notification)
+	}
+}
+
+func TestEscalationWithoutNotification(t *testing.T) {
+	recorder := &escalationRecorder{}
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.Escalation.AfterSeconds = 1
+			cfg.Escalation.Notify = false
+		}).
+		UseEscalation(recorder.escalate).
+		LeaveDialogsUnanswered().
+		ReceiveClaudeText(bashDialogLines("git push")...)
+
+	time.Sleep(1200 * time.Millisecond)
+	if len(recorder.Messages()) != 1 {
+		t.Errorf("Expected the other channels to be alerted, got %v", recorder.Messages())
+	}
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Errorf("Expected no notification with notify off, got %q", notification)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+// eventRecorder collects the lines of an event stream
+type eventRecorder struct {
+	buffer bytes.Buffer
+	mutex  sync.Mutex
+}
+
+func (r *eventRecorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.buffer.Write(p)
+}
+
+// Events returns the events written so far
+func (r *eventRecorder) Events(t *testing.T) []events.Event {
+	t.Helper()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var written []events.Event
+	for _, line := range strings.Split(strings.TrimSpace(r.buffer.String()), "\n") {
+		var event events.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected a JSON object per line, got %q: %v", line, err)
+		}
+		written = append(written, event)
+	}
+	return written
+}
+
+// eventTypes returns the type of each event
+func eventTypes(written []events.Event) []string {
+	var types []string
+	for _, event := range written {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestEventStreamFollowsAnswerPickedInDialog(t *testing.T) {
+	recorder := &eventRecorder{}
+	NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(100 * time.Millisecond)
+
+	written := recorder.Events(t)
+	expected := []string{events.DialogDetected, events.DialogShown, events.Decision, events.Injection}
+	if !reflect.DeepEqual(eventTypes(written), expected) {
+		t.Fatalf("Expected events %q, got %+v", expected, written)
+	}
+	for _, event := range written {
+		if event.Prompt != written[0].Prompt || event.Kind != "permission" || event.Session == 0 {
+			t.Errorf("Expected every event to name the same permission prompt, got %+v", event)
+		}
+	}
+	if shown := written[1]; shown.Tool != "Bash" || shown.Request != "Bash: go test ./..." {
+		t.Errorf("Expected the dialog's request, got %+v", shown)
+	}
+	if decision := written[2]; decision.Choice != "1" || decision.Label != "Yes" || decision.Action != audit.Approved || decision.DecidedBy != audit.User {
+		t.Errorf("Expected the user's approval, got %+v", decision)
+	}
+	if injection := written[3]; injection.Choice != "1" {
+		t.Errorf("Expected the choice to be injected, got %+v", injection)
+	}
+}
+
+func TestEventStreamFollowsAutomaticAnswer(t *testing.T) {
+	recorder := &eventRecorder{}
+	NewAppRobot(t).
+		UseEventStream(events.NewStream(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoReject = true
+		}).
+		ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	written := recorder.Events(t)
+	expected := []string{events.DialogDetected, events.Decision, events.Injection}
+	if !reflect.DeepEqual(eventTypes(written), expected) {
+		t.Fatalf("Expected events %q, got %+v", expected, written)
+	}
+	if decision := written[1]; decision.Choice != "2" || decision.Action != audit.Rejected || decision.DecidedBy != "auto_reject" {
+		t.Errorf("Expected the automatic rejection, got %+v", decision)
+	}
+}
+
+func TestEventStreamReportsCrash(t *testing.T) {
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).UseEventStream(events.NewStream(recorder))
+
+	robot.app.handler.reportCrash("parser failed at postgres://app:hunter2@db/prod", nil)
+
+	written := recorder.Events(t)
+	if len(written) != 1 || written[0].Type != events.Error || written[0].Message != "crashed: parser failed at postgres://app:[REDACTED]@db/prod" {
+		t.Errorf("Expected a masked error event, got %+v", written)
+	}
+}
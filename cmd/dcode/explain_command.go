@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/takahirom/dialog-code/internal/decisions"
+)
+
+// explainUsage describes the "dcode explain" subcommand
+const explainUsage = "usage: dcode explain [ID]"
+
+// RecentDecisionCount is how many decisions "dcode explain" lists without an ID
+const RecentDecisionCount = 20
+
+// runExplainCommand runs "dcode explain" with the arguments after "explain":
+// without an ID it lists the latest requests answered without asking, and
+// with one it shows what decided that request
+func runExplainCommand(argv []string, out io.Writer) error {
+	path := decisions.DefaultPath()
+	if path == "" {
+		return errors.New("cache directory is unknown")
+	}
+	log := decisions.NewLog(path)
+
+	switch len(argv) {
+	case 0:
+		entries, err := log.Entries()
+		if err != nil {
+			return err
+		}
+		listDecisions(entries, out)
+		return nil
+	case 1:
+		entry, ok, err := log.Find(argv[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no decision with ID %s; run \"dcode explain\" to list recent ones", argv[0])
+		}
+		explainDecision(entry, out)
+		return nil
+	}
+	return errors.New(explainUsage)
+}
+
+// listDecisions prints the last RecentDecisionCount of entries, newest first
+func listDecisions(entries []decisions.Entry, out io.Writer) {
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No decisions logged")
+		return
+	}
+	for i := len(entries) - 1; i >= 0 && i >= len(entries)-RecentDecisionCount; i-- {
+		entry := entries[i]
+		fmt.Fprintf(out, "%s  %s  %-8s  %s  %s\n", entry.ID, entry.Time.Format("2006-01-02 15:04:05"), entry.Decision, entry.Rule, entry.Request)
+	}
+}
+
+// explainDecision prints everything logged about entry
+func explainDecision(entry decisions.Entry, out io.Writer) {
+	rule := entry.Rule
+	if entry.Detail != "" {
+		rule += ": " + entry.Detail
+	}
+
+	fmt.Fprintf(out, "ID:       %s\n", entry.ID)
+	fmt.Fprintf(out, "Time:     %s\n", entry.Time.Format("2006-01-02 15:04:05 -0700"))
+	fmt.Fprintf(out, "Request:  %s\n", entry.Request)
+	fmt.Fprintf(out, "Risk:     %s\n", entry.Risk)
+	fmt.Fprintf(out, "Decision: %s without asking\n", entry.Decision)
+	fmt.Fprintf(out, "Rule:     %s\n", rule)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/decisions"
+)
+
+// decisionLog returns the decision log "dcode explain" reads, in a temporary
+// cache directory
+func decisionLog(t *testing.T) *decisions.Log {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	return decisions.NewLog(decisions.DefaultPath())
+}
+
+// runExplain runs "dcode explain" with args, returning its output
+func runExplain(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runExplainCommand(args, &out)
+	return out.String(), err
+}
+
+func TestExplainRejection(t *testing.T) {
+	log := decisionLog(t)
+	robot := NewAppRobot(t).
+		UseDecisionLog(log).
+		Configure(func(cfg *config.Config) {
+			cfg.Deny = []config.DenyRule{{Rule: config.Rule{Tool: "Bash", Command: `^git push`}}}
+		}).
+		ReceiveClaudeText(bashDialogLines("git push --force")...)
+	time.Sleep(denyRuleWaitTime)
+
+	entries, err := log.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one logged decision, got %+v, %v", entries, err)
+	}
+	id := entries[0].ID
+	robot.AssertTerminalContains("Rejected by deny rule 1 (run `dcode explain " + id + "` for details).")
+
+	output, err := runExplain(t, id)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{
+		"ID:       " + id,
+		"Time:     2023-01-01 12:00:00 +0000",
+		"Request:  Bash: git push --force",
+		"Risk:     high (force push)",
+		"Decision: rejected without asking",
+		"Rule:     deny rule 1: tool=Bash command='^git push'",
+	} {
+		if !strings.Contains(output, expected+"\n") {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+}
+
+func TestExplainListsRecentDecisions(t *testing.T) {
+	log := decisionLog(t)
+	if output, err := runExplain(t); err != nil || output != "No decisions logged\n" {
+		t.Errorf("Expected an empty log, got %q, %v", output, err)
+	}
+
+	NewAppRobot(t).
+		UseDecisionLog(log).
+		Configure(func(cfg *config.Config) {
+			cfg.Approve = []config.Rule{{Tool: "Bash", Command: `^go test`}}
+		}).
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		ReceiveClaudeText(askedAs("go vet ./...", "Do you want to run go vet?")...)
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	output, err := runExplain(t)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "approved  approve rule 1  Bash: go test ./...") {
+		t.Errorf("Expected the approval, got:\n%s", output)
+	}
+}
+
+func TestExplainUnknownID(t *testing.T) {
+	decisionLog(t)
+	if _, err := runExplain(t, "0000ffff"); err == nil || !strings.Contains(err.Error(), "no decision with ID 0000ffff") {
+		t.Errorf("Expected an unknown ID error, got %v", err)
+	}
+	if _, err := runExplain(t, "a", "b"); err == nil || err.Error() != explainUsage {
+		t.Errorf("Expected the usage, got %v", err)
+	}
+}
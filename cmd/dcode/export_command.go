@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// exportUsage describes the "dcode export" subcommand
+const exportUsage = "usage: dcode export [--format=csv|json] [--tool=NAME] [--decision=approved|rejected|answered] [--since=YYYY-MM-DD|AGO] [--until=YYYY-MM-DD|AGO] [--project=DIR] [--branch=NAME] [--limit=N]"
+
+// Formats "dcode export" writes
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// exportColumns heads the columns of a CSV export, in the order exportRecord
+// writes them
+var exportColumns = []string{"id", "time", "source", "session", "project", "repo", "branch", "commit", "prompt", "tool", "request", "risk", "choice", "label", "decision", "decided_by", "mode", "latency_ms", "wait_ms", "dialog"}
+
+// runExportCommand runs "dcode export" with the arguments after "export",
+// writing every entry of the --audit-log file the options select, oldest
+// first, as CSV or with --format=json as a JSON array. --limit keeps only the
+// latest entries.
+func runExportCommand(argv []string, out io.Writer) error {
+	log, args, err := openAuditLog(argv)
+	if err != nil {
+		return err
+	}
+
+	format := ExportFormatCSV
+	var options []string
+	for _, arg := range joinOptionValues(args, "format") {
+		name, value, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "format" || !strings.HasPrefix(arg, "-") {
+			options = append(options, arg)
+			continue
+		}
+		if value != ExportFormatCSV && value != ExportFormatJSON {
+			return fmt.Errorf("invalid format %q: use csv or json", value)
+		}
+		format = value
+	}
+	filter, limit, err := parseHistoryOptions(options, exportUsage)
+	if err != nil {
+		return err
+	}
+	entries, err := log.Entries(filter)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if format == ExportFormatJSON {
+		return exportJSON(entries, out)
+	}
+	return exportCSV(entries, out)
+}
+
+// exportCSV writes entries as CSV with a header row. A dialog's lines are
+// joined with newlines in one field.
+func exportCSV(entries []audit.Entry, out io.Writer) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(exportColumns); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := w.Write(exportRecord(entry)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// exportRecord returns the fields of entry under exportColumns
+func exportRecord(entry audit.Entry) []string {
+	return []string{
+		entry.ID,
+		entry.Time.Format(time.RFC3339),
+		entry.Source,
+		strconv.Itoa(entry.Session),
+		entry.Dir,
+		entry.Repo,
+		entry.Branch,
+		entry.Commit,
+		entry.Prompt,
+		entry.Tool,
+		entry.Request,
+		entry.Risk,
+		entry.Choice,
+		entry.Label,
+		entry.Action,
+		entry.DecidedBy,
+		entry.Mode,
+		strconv.FormatInt(entry.LatencyMs, 10),
+		strconv.FormatInt(entry.WaitMs, 10),
+		strings.Join(entry.Dialog, "\n"),
+	}
+}
+
+// exportJSON writes entries as an indented JSON array, in the audit log's
+// format
+func exportJSON(entries []audit.Entry, out io.Writer) error {
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	encoded, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(encoded))
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// runExport runs "dcode export" with args, returning its output
+func runExport(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runExportCommand(args, &out)
+	return out.String(), err
+}
+
+var (
+	exportedRejection = audit.Entry{
+		ID: "0000aaaa", Time: time.Now().Add(-3 * 24 * time.Hour).Truncate(time.Second), Source: audit.SourceWrapper, Session: 42,
+		Dir: "/work/app", Prompt: "permission", Tool: "Bash", Request: "Bash: git push --force", Risk: "high (force push)",
+		Choice: "3", Label: "No", Action: audit.Rejected, DecidedBy: "deny rule 1", Mode: "dialog", LatencyMs: 250, WaitMs: 200,
+		Dialog: []string{"│ Bash command │", "│ ❯ 1. Yes, \"now\" │"},
+	}
+	exportedApproval = audit.Entry{
+		ID: "0000bbbb", Time: time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Second), Source: audit.SourceWrapper, Session: 43,
+		Dir: "/work/lib", Prompt: "permission", Tool: "Read", Request: "Read: go.mod", Risk: "low",
+		Choice: "1", Label: "Yes", Action: audit.Approved, DecidedBy: audit.User, Mode: "dialog", LatencyMs: 4100,
+	}
+)
+
+func TestExportCSV(t *testing.T) {
+	path := historyLog(t, exportedApproval, exportedRejection)
+
+	output, err := runExport(t, "--audit-log="+path, "--since", "7d", "--format", "csv")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v:\n%s", err, output)
+	}
+	if len(records) != 2 || !reflect.DeepEqual(records[0], exportColumns) {
+		t.Fatalf("Expected a header and the rejection within 7 days, got %q", records)
+	}
+	expected := []string{
+		"0000aaaa", exportedRejection.Time.Format(time.RFC3339), "wrapper", "42", "/work/app", "", "", "", "permission", "Bash",
+		"Bash: git push --force", "high (force push)", "3", "No", "rejected", "deny rule 1", "dialog", "250", "200",
+		"│ Bash command │\n│ ❯ 1. Yes, \"now\" │",
+	}
+	if !reflect.DeepEqual(records[1], expected) {
+		t.Errorf("Expected %q, got %q", expected, records[1])
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	path := historyLog(t, exportedApproval, exportedRejection)
+
+	output, err := runExport(t, "--audit-log="+path, "--format=json")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var entries []audit.Entry
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		t.Fatalf("Expected a JSON array, got %v:\n%s", err, output)
+	}
+	if len(entries) != 2 || entries[0].ID != "0000bbbb" || !reflect.DeepEqual(entries[1].Dialog, exportedRejection.Dialog) {
+		t.Errorf("Expected both entries oldest first, got %+v", entries)
+	}
+
+	output, err = runExport(t, "--audit-log="+path, "--format=json", "--tool=Python")
+	if err != nil || strings.TrimSpace(output) != "[]" {
+		t.Errorf("Expected an empty array, got %q, %v", output, err)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	path := historyLog(t)
+	if _, err := runExport(t, "--audit-log="+path, "--format=xml"); err == nil || !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("Expected a format error, got %v", err)
+	}
+	if _, err := runExport(t, "--audit-log="+path, "--since=lately"); err == nil || !strings.Contains(err.Error(), "invalid since date") {
+		t.Errorf("Expected a date error, got %v", err)
+	}
+	if _, err := runExport(t, "--audit-log="+path, "everything"); err == nil || err.Error() != exportUsage {
+		t.Errorf("Expected the usage, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestForbidRuleOverridesEveryApproval(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoApprove = true
+			cfg.Approve = []config.Rule{{Tool: "Bash"}}
+			cfg.Risk = config.RiskPolicy{Low: config.RiskActionApprove, Medium: config.RiskActionApprove, High: config.RiskActionApprove}
+			cfg.Forbid = []config.DenyRule{{Rule: config.Rule{Tool: "Bash", Command: `^terraform destroy`}}}
+		}).
+		ReceiveClaudeText(bashDialogLines("terraform destroy -auto-approve")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the forbid rule to reject, got: %q", output)
+	}
+	robot.AssertTerminalContains(ForbidRuleBaseMessage)
+}
+
+func TestForbidRuleTakesPrecedenceOverDenyRule(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.Deny = []config.DenyRule{{Rule: config.Rule{Tool: "Bash"}, Message: "Ask before running commands."}}
+			cfg.Forbid = []config.DenyRule{{
+				Rule:    config.Rule{Command: `psql .*prod`},
+				Message: "Production databases are off limits.",
+			}}
+		}).
+		ReceiveClaudeText(bashDialogLines("psql -h prod.db.internal")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	robot.AssertTerminalContains("Production databases are off limits.")
+}
+
+func TestForbidRuleLeavesOtherDialogs(t *testing.T) {
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.Forbid = []config.DenyRule{{Rule: config.Rule{Command: `^terraform destroy`}}}
+		}).
+		ReceiveClaudeText(bashDialogLines("terraform plan")...).
+		AssertDialogCaptured()
+}
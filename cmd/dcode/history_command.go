@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// historyUsage describes the "dcode history" subcommand
+const historyUsage = `usage: dcode history [--tool=NAME] [--decision=approved|rejected|answered] [--since=YYYY-MM-DD|AGO] [--until=YYYY-MM-DD|AGO] [--project=DIR] [--branch=NAME] [--limit=N]
+       dcode history ID`
+
+// RecentHistoryCount is how many entries "dcode history" lists without --limit
+const RecentHistoryCount = 20
+
+// historyDateFormat is the format of --since and --until
+const historyDateFormat = "2006-01-02"
+
+// historyOptionNames are the options parseHistoryOptions reads, each taking
+// a value
+var historyOptionNames = []string{"tool", "decision", "since", "until", "project", "branch", "limit"}
+
+// runHistoryCommand runs "dcode history" with the arguments after "history":
+// with an ID it shows that entry of the --audit-log file with its dialog,
+// and otherwise lists the latest entries the options select. --config and
+// --audit-log apply.
+func runHistoryCommand(argv []string, out io.Writer) error {
+	log, args, err := openAuditLog(argv)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 && !strings.HasPrefix(args[0], "-") {
+		entry, ok, err := log.Find(args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no history entry with ID %s; run \"dcode history\" to list recent ones", args[0])
+		}
+		showHistoryEntry(entry, out)
+		return nil
+	}
+
+	filter, limit, err := parseHistoryOptions(args, historyUsage)
+	if err != nil {
+		return err
+	}
+	if limit == 0 {
+		limit = RecentHistoryCount
+	}
+	entries, err := log.Entries(filter)
+	if err != nil {
+		return err
+	}
+	listHistory(entries, limit, out)
+	return nil
+}
+
+// openAuditLog returns the audit log set with --audit-log in argv or in the
+// config file, and the arguments in argv that aren't dcode flags
+func openAuditLog(argv []string) (*audit.Log, []string, error) {
+	cfg, args, err := loadConfig(argv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.AuditLog == "" {
+		return nil, nil, errors.New("no audit log to read; set audit_log in the config file or pass --audit-log=PATH")
+	}
+	return newAuditLog(&cfg), args, nil
+}
+
+// parseHistoryOptions returns the entries the "dcode history" options in args
+// select and the --limit given, or 0. Anything else is an error showing usage.
+func parseHistoryOptions(args []string, usage string) (audit.Filter, int, error) {
+	var filter audit.Filter
+	limit := 0
+	for _, arg := range joinOptionValues(args, historyOptionNames...) {
+		name, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !ok || !strings.HasPrefix(arg, "-") {
+			return filter, 0, errors.New(usage)
+		}
+		switch name {
+		case "tool":
+			filter.Tool = value
+		case "decision":
+			if value != audit.Approved && value != audit.Rejected && value != audit.Answered {
+				return filter, 0, fmt.Errorf("invalid decision %q: use approved, rejected, or answered", value)
+			}
+			filter.Action = value
+		case "since", "until":
+			if ago, ok := parseAgo(value); ok {
+				if name == "since" {
+					filter.Since = time.Now().Add(-ago)
+				} else {
+					filter.Until = time.Now().Add(-ago)
+				}
+				continue
+			}
+			day, err := time.ParseInLocation(historyDateFormat, value, time.Local)
+			if err != nil {
+				return filter, 0, fmt.Errorf("invalid %s date %q: use YYYY-MM-DD or a time ago such as 7d or 12h", name, value)
+			}
+			if name == "since" {
+				filter.Since = day
+			} else {
+				// The whole day is included
+				filter.Until = day.AddDate(0, 0, 1)
+			}
+		case "project":
+			dir, err := filepath.Abs(expandHome(value))
+			if err != nil {
+				return filter, 0, err
+			}
+			filter.Dir = dir
+		case "branch":
+			filter.Branch = value
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return filter, 0, fmt.Errorf("invalid limit %q", value)
+			}
+			limit = n
+		default:
+			return filter, 0, errors.New(usage)
+		}
+	}
+	return filter, limit, nil
+}
+
+// parseAgo returns the time ago written as a number of weeks, days, hours, or
+// minutes, e.g. "2w", "7d", "12h", or "30m"
+func parseAgo(value string) (time.Duration, bool) {
+	units := map[byte]time.Duration{'w': 7 * 24 * time.Hour, 'd': 24 * time.Hour, 'h': time.Hour, 'm': time.Minute}
+	if value == "" {
+		return 0, false
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// joinOptionValues returns args with each option in names written as
+// "--name value" rewritten as "--name=value"
+func joinOptionValues(args []string, names ...string) []string {
+	var joined []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && slices.Contains(names, name) && i+1 < len(args) {
+			arg += "=" + args[i+1]
+			i++
+		}
+		joined = append(joined, arg)
+	}
+	return joined
+}
+
+// listHistory prints the last limit of entries, newest first
+func listHistory(entries []audit.Entry, limit int, out io.Writer) {
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No history entries")
+		return
+	}
+	for i := len(entries) - 1; i >= 0 && i >= len(entries)-limit; i-- {
+		entry := entries[i]
+		fmt.Fprintf(out, "%s  %s  %-8s  %s  %s  (%s)\n", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Action, entry.DecidedBy, entry.Request, entry.Dir)
+	}
+}
+
+// describeGitContext returns the branch and commit entry was answered at and
+// the repository, e.g. "main at 9fceb02d0ae5 (https://github.com/o/r.git)"
+func describeGitContext(entry audit.Entry) string {
+	commit := entry.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	context := "detached at " + commit
+	if entry.Branch != "" {
+		context = entry.Branch + " at " + commit
+	}
+	if entry.Repo != "" {
+		context += " (" + entry.Repo + ")"
+	}
+	return context
+}
+
+// showHistoryEntry prints everything recorded about entry, ending with the
+// dialog as Claude showed it
+func showHistoryEntry(entry audit.Entry, out io.Writer) {
+	fmt.Fprintf(out, "ID:       %s\n", entry.ID)
+	fmt.Fprintf(out, "Time:     %s\n", entry.Time.Local().Format("2006-01-02 15:04:05 -0700"))
+	fmt.Fprintf(out, "Project:  %s\n", entry.Dir)
+	if entry.Commit != "" {
+		fmt.Fprintf(out, "Git:      %s\n", describeGitContext(entry))
+	}
+	fmt.Fprintf(out, "Session:  %d (%s mode)\n", entry.Session, entry.Mode)
+	fmt.Fprintf(out, "Request:  %s\n", entry.Request)
+	fmt.Fprintf(out, "Risk:     %s\n", entry.Risk)
+	fmt.Fprintf(out, "Answer:   %s. %s (%s)\n", entry.Choice, entry.Label, entry.Action)
+	fmt.Fprintf(out, "Decided:  by %s after %dms\n", entry.DecidedBy, entry.LatencyMs)
+	if entry.Screenshot != "" {
+		fmt.Fprintf(out, "Screen:   %s\n", entry.Screenshot)
+	}
+	if len(entry.Dialog) > 0 {
+		fmt.Fprintln(out, "Dialog:")
+		for _, line := range entry.Dialog {
+			fmt.Fprintln(out, "  "+line)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// historyLog returns an --audit-log file holding entries
+func historyLog(t *testing.T, entries ...audit.Entry) string {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := auditLogPath(t)
+	log := audit.NewLog(path)
+	for _, entry := range entries {
+		if err := log.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// runHistory runs "dcode history" with args, returning its output
+func runHistory(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runHistoryCommand(args, &out)
+	return out.String(), err
+}
+
+func TestHistoryFilters(t *testing.T) {
+	day := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	path := historyLog(t,
+		audit.Entry{ID: "0000aaaa", Time: day, Dir: "/work/app", Tool: "Bash", Request: "Bash: git push --force", Action: audit.Rejected, DecidedBy: "deny rule 1"},
+		audit.Entry{ID: "0000bbbb", Time: day.Add(time.Hour), Dir: "/work/app", Tool: "Read", Request: "Read: /work/app/go.mod", Action: audit.Approved, DecidedBy: "allow_read_only"},
+		audit.Entry{ID: "0000cccc", Time: day.AddDate(0, 0, 1), Dir: "/work/lib", Branch: "release/1.2", Tool: "Bash", Request: "Bash: go test ./...", Action: audit.Approved, DecidedBy: audit.User},
+	)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"Newest first", nil, []string{"0000cccc", "0000bbbb", "0000aaaa"}},
+		{"Tool", []string{"--tool=bash"}, []string{"0000cccc", "0000aaaa"}},
+		{"Decision", []string{"--decision=approved"}, []string{"0000cccc", "0000bbbb"}},
+		{"Date", []string{"--since=2025-03-10", "--until=2025-03-10"}, []string{"0000bbbb", "0000aaaa"}},
+		{"Project", []string{"--project=/work/lib"}, []string{"0000cccc"}},
+		{"Branch", []string{"--branch", "release/1.2"}, []string{"0000cccc"}},
+		{"Limit", []string{"--limit=1"}, []string{"0000cccc"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := runHistory(t, append([]string{"--audit-log=" + path}, test.args...)...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(output), "\n")
+			if len(lines) != len(test.expected) {
+				t.Fatalf("Expected %v, got:\n%s", test.expected, output)
+			}
+			for i, id := range test.expected {
+				if !strings.HasPrefix(lines[i], id+"  ") {
+					t.Errorf("Expected %s on line %d, got:\n%s", id, i+1, output)
+				}
+			}
+		})
+	}
+
+	if output, err := runHistory(t, "--audit-log="+path, "--tool=Edit"); err != nil || output != "No history entries\n" {
+		t.Errorf("Expected no entries, got %q, %v", output, err)
+	}
+}
+
+func TestHistoryShowsDialog(t *testing.T) {
+	path := historyLog(t, audit.Entry{
+		ID: "0000aaaa", Time: time.Now(), Session: 4242, Dir: "/work/app", Mode: "auto-reject-wait=30",
+		Repo: "https://github.com/team/app.git", Branch: "release/1.2", Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8",
+		Request: "Bash: git push --force", Risk: "high (force push)",
+		Choice: "2", Label: "No", Action: audit.Rejected, DecidedBy: "deny rule 1", LatencyMs: 812,
+		Dialog: []string{"╭───────────────╮", "│ Bash command  │", "│ ❯ 1. Yes      │", "│   2. No       │", "╰───────────────╯"},
+	})
+
+	output, err := runHistory(t, "--audit-log="+path, "0000aaaa")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{
+		"ID:       0000aaaa",
+		"Project:  /work/app",
+		"Git:      release/1.2 at 9fceb02d0ae5 (https://github.com/team/app.git)",
+		"Session:  4242 (auto-reject-wait=30 mode)",
+		"Request:  Bash: git push --force",
+		"Answer:   2. No (rejected)",
+		"Decided:  by deny rule 1 after 812ms",
+		"Dialog:\n  ╭───────────────╮\n  │ Bash command  │\n  │ ❯ 1. Yes      │",
+	} {
+		if !strings.Contains(output, expected+"\n") {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+}
+
+func TestHistoryTimeAgo(t *testing.T) {
+	path := historyLog(t,
+		audit.Entry{ID: "0000aaaa", Time: time.Now().Add(-3 * 24 * time.Hour), Tool: "Bash", Action: audit.Rejected},
+		audit.Entry{ID: "0000bbbb", Time: time.Now().Add(-2 * time.Hour), Tool: "Read", Action: audit.Approved},
+	)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Since days ago", []string{"--since=1d"}, "0000bbbb"},
+		{"Until days ago", []string{"--until=2d"}, "0000aaaa"},
+		{"Separate value", []string{"--since", "1w", "--until", "30m", "--tool", "Bash"}, "0000aaaa"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := runHistory(t, append([]string{"--audit-log=" + path}, test.args...)...)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], test.expected) {
+				t.Errorf("Expected only %s, got:\n%s", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestHistoryErrors(t *testing.T) {
+	path := historyLog(t)
+	if _, err := runHistory(t); err == nil || !strings.Contains(err.Error(), "no audit log") {
+		t.Errorf("Expected a missing audit log error, got %v", err)
+	}
+	if _, err := runHistory(t, "--audit-log="+path, "0000ffff"); err == nil || !strings.Contains(err.Error(), "no history entry with ID 0000ffff") {
+		t.Errorf("Expected an unknown ID error, got %v", err)
+	}
+	if _, err := runHistory(t, "--audit-log="+path, "--since=yesterday"); err == nil || !strings.Contains(err.Error(), "invalid since date") {
+		t.Errorf("Expected a date error, got %v", err)
+	}
+	if _, err := runHistory(t, "--audit-log="+path, "--decision=maybe"); err == nil || !strings.Contains(err.Error(), "invalid decision") {
+		t.Errorf("Expected a decision error, got %v", err)
+	}
+	if _, err := runHistory(t, "--audit-log="+path, "a", "b"); err == nil || err.Error() != historyUsage {
+		t.Errorf("Expected the usage, got %v", err)
+	}
+}
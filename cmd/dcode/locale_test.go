@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestJapanesePermissionPrompt(t *testing.T) {
+	japaneseDialogLines := []string{
+		"⏺ Bash(rm test-file)",
+		"",
+		"╭─────────────────────────────────────────────────────────────╮",
+		"│ Bash コマンド                                               │",
+		"│                                                             │",
+		"│   rm test-file                                              │",
+		"│                                                             │",
+		"│ 続行しますか？                                              │",
+		"│ ❯ 1. はい                                                   │",
+		"│   2. いいえ、Claudeに別の方法を伝える (esc)                 │",
+		"╰─────────────────────────────────────────────────────────────╯",
+	}
+
+	t.Run("Not detected without the Japanese locale", func(t *testing.T) {
+		NewAppRobot(t).
+			ReceiveClaudeText(japaneseDialogLines...).
+			AssertNoDialogCaptured()
+	})
+
+	t.Run("Detected with --locale=ja", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(func(cfg *config.Config) { cfg.Locale = "ja" }).
+			ReceiveClaudeText(japaneseDialogLines...).
+			AssertDialogCaptured().
+			AssertDialogTextContains("rm test-file").
+			AssertDialogTextContains("続行しますか？").
+			AssertButtonCount(2).
+			AssertButton(0, "はい")
+	})
+}
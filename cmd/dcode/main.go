@@ -2,12 +2,18 @@ package main
 
 import (
 	"bufio"
-	"flag"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
@@ -16,93 +22,100 @@ import (
 	"github.com/creack/pty"
 	"golang.org/x/term"
 
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/claudesettings"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/control"
+	"github.com/takahirom/dialog-code/internal/crash"
 	"github.com/takahirom/dialog-code/internal/debug"
+	"github.com/takahirom/dialog-code/internal/decisions"
 	"github.com/takahirom/dialog-code/internal/dialog"
+	"github.com/takahirom/dialog-code/internal/events"
+	"github.com/takahirom/dialog-code/internal/i18n"
+	"github.com/takahirom/dialog-code/internal/policy"
+	"github.com/takahirom/dialog-code/internal/remote"
+	"github.com/takahirom/dialog-code/internal/state"
+	"github.com/takahirom/dialog-code/internal/systemlog"
+	"github.com/takahirom/dialog-code/internal/telemetry"
+	"github.com/takahirom/dialog-code/internal/tracing"
+	"github.com/takahirom/dialog-code/internal/transcript"
+	"github.com/takahirom/dialog-code/internal/types"
+	"github.com/takahirom/dialog-code/internal/version"
 )
 
 const (
-	// Timing constants for cooldowns and delays
-	DialogCooldownMs        = 500
-	AutoApproveDelayMs      = 100
-	DialogResetDelayMs      = 3000
-	CharDelayMs             = 10
-	LineProcessDelayMs      = 100
-	FinalDelayMs            = 500
-	PromptDuplicationSec    = 5
-	ChoiceProcessingDelayMs = 300
-
-	// Auto-reject timing constants
-	AutoRejectChoiceDelayMs  = 500
-	AutoRejectCRDelayMs      = 6000
-	AutoRejectProcessDelayMs = 500
-
-	// Auto-reject base message
-	AutoRejectBaseMessage = "The command was automatically rejected. If using Task tools, please restart them. Otherwise, try a different command."
+	// Timing constants for cooldowns and delays; see config.Delays for the configurable ones
+	DialogCooldownMs       = 500
+	PromptDuplicationSec   = 5
+	ConfigPollIntervalMs   = 2000
+	ModeFilePollIntervalMs = 500
+	PolicyFetchTimeoutSec  = 10
+	EscalationTimeoutSec   = 10
 )
 
+// englishText is what dcode writes in English; the language option picks
+// the text of each session
+var englishText = i18n.Get(i18n.English, os.Getenv)
+
+// Messages sent to Claude, in English
 var (
-	autoApprove            = flag.Bool("auto-approve", false, "Automatically approve all prompts without showing dialogs")
-	autoReject             = flag.Bool("auto-reject", false, "Automatically reject unauthorized commands without showing dialogs")
-	autoRejectWait         = flag.Int("auto-reject-wait", 0, "Auto-reject with N seconds wait for user intervention (0 = disabled)")
-	stripColors            = flag.Bool("strip-colors", false, "Remove ANSI color codes from output")
-	preventScrollbackClear = flag.Bool("prevent-scrollback-clear", true, "Prevent scrollback history clear control sequences")
-	debugFlag              = flag.Bool("debug", false, "Enable debug logging to debug_output.log")
+	// Auto-reject base message
+	AutoRejectBaseMessage = englishText.AutoReject
+
+	// Message sent for a deny rule without its own message
+	DenyRuleBaseMessage = englishText.DenyRule
+
+	// Message sent for a forbid rule without its own message
+	ForbidRuleBaseMessage = englishText.ForbidRule
+
+	// Message sent for a tool denied by --tool-policy; %s is the tool name
+	ToolPolicyBaseMessage = englishText.ToolPolicy
+
+	// Message sent for every request after dcode panic
+	PanicBaseMessage = englishText.Panic
+
+	// Message sent when nobody answers a request sent to the remote backend
+	RemoteTimeoutBaseMessage = englishText.RemoteTimeout
+
+	// Message sent for an edit outside edit_scope without its own message; %s lists the allowed directories
+	EditScopeBaseMessage = englishText.EditScope
 )
 
 func main() {
+	if handled, err := runSubcommand(os.Args[1:]); handled {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Parse only known flags, pass everything else to claude
-	var args []string
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
-		if arg == "-auto-approve" || arg == "--auto-approve" {
-			*autoApprove = true
-		} else if arg == "-auto-reject" || arg == "--auto-reject" {
-			*autoReject = true
-		} else if strings.HasPrefix(arg, "-auto-reject-wait=") || strings.HasPrefix(arg, "--auto-reject-wait=") {
-			// Parse --auto-reject-wait=N format
-			parts := strings.SplitN(arg, "=", 2)
-			if len(parts) == 2 {
-				if waitTime, err := strconv.Atoi(parts[1]); err == nil && waitTime >= 0 {
-					*autoRejectWait = waitTime
-				} else {
-					fmt.Fprintf(os.Stderr, "Invalid auto-reject-wait value: %s\n", parts[1])
-					os.Exit(1)
-				}
-			}
-		} else if strings.HasPrefix(arg, "-prevent-scrollback-clear=") || strings.HasPrefix(arg, "--prevent-scrollback-clear=") {
-			// Parse --prevent-scrollback-clear=true/false format
-			parts := strings.SplitN(arg, "=", 2)
-			if len(parts) == 2 && parts[1] != "" {
-				if parts[1] == "true" {
-					*preventScrollbackClear = true
-				} else if parts[1] == "false" {
-					*preventScrollbackClear = false
-				} else {
-					fmt.Fprintf(os.Stderr, "Invalid prevent-scrollback-clear value: %s (must be true or false)\n", parts[1])
-					os.Exit(1)
-				}
-			} else {
-				fmt.Fprintf(os.Stderr, "prevent-scrollback-clear flag requires a value (true or false)\n")
-				os.Exit(1)
-			}
-		} else if arg == "-prevent-scrollback-clear" || arg == "--prevent-scrollback-clear" {
-			*preventScrollbackClear = true
-		} else if arg == "-strip-colors" || arg == "--strip-colors" {
-			*stripColors = true
-		} else if arg == "-debug" || arg == "--debug" {
-			*debugFlag = true
-		} else {
-			args = append(args, arg)
+	argv := claudeArgv(os.Args[1:])
+	if firstRun(argv) && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		path, _ := configPath(argv)
+		if err := runSetup(path, true, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: setup failed: %v\n", err)
 		}
 	}
+	cfg, args, err := loadConfig(argv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	// Check if stdin is a pipe/file vs interactive terminal
 	stat, _ := os.Stdin.Stat()
 	isPipe := (stat.Mode() & os.ModeCharDevice) == 0
 
-	// Enable debug logging if debug flag is set
-	if *debugFlag {
-		debug.Enable()
+	// Enable debug logging if debug flag or a log level is set
+	if cfg.Debug || cfg.LogLevel != "" {
+		debug.SetRedact(newRedactor(&cfg).Redact)
+		if err := debug.Configure(logOptions(&cfg)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: debug log unavailable: %v\n", err)
+		}
+		debug.Info("starting", "version", version.Get().String(), "pid", os.Getpid())
 	}
 
 	cmd := exec.Command("claude", args...)
@@ -157,19 +170,19 @@ func main() {
 				// Send the text character by character
 				for _, char := range line {
 					ptmx.WriteString(string(char))
-					time.Sleep(CharDelayMs * time.Millisecond)
+					time.Sleep(time.Duration(cfg.Delays.InputCharMs) * time.Millisecond)
 				}
 				// Then send Enter key - try different approaches
-				time.Sleep(LineProcessDelayMs * time.Millisecond)
+				time.Sleep(time.Duration(cfg.Delays.InputLineMs) * time.Millisecond)
 				ptmx.WriteString("\n")
 				ptmx.Sync()
-				time.Sleep(FinalDelayMs * time.Millisecond)
+				time.Sleep(time.Duration(cfg.Delays.InputSubmitMs) * time.Millisecond)
 			}
 		}()
 	} else {
-		// For interactive input, use direct copy
+		// For interactive input, use direct copy, watching for the PanicKey
 		go func() {
-			_, _ = io.Copy(ptmx, os.Stdin)
+			_, _ = io.Copy(hotkeyWriter{w: ptmx, key: PanicKey, onKey: panicFromKey}, os.Stdin)
 		}()
 	}
 
@@ -177,27 +190,705 @@ func main() {
 	var displayWriter io.Writer = os.Stdout
 
 	// Apply scrollback clear filter by default
-	if *preventScrollbackClear {
+	if cfg.PreventScrollbackClear {
 		displayWriter = dialog.NewScrollbackClearFilterWriter(displayWriter)
 	}
 
-	if *stripColors {
+	if cfg.StripColors {
 		displayWriter = dialog.NewColorStripWriter(displayWriter)
 	}
 
 	// Create and run the app
-	app := NewApp(ptmx, displayWriter)
+	app := NewApp(ptmx, displayWriter, &cfg)
 
 	// Initialize dialog at application level (outside of app core)
 	simpleDialog := dialog.NewSimpleOSDialog()
+	simpleDialog.Title = cfg.Dialog.Title
+	simpleDialog.App = cfg.Dialog.App
 
 	// Set up permission callback to use the simple dialog
-	app.SetPermissionCallback(func(message string, buttons []string, defaultButton string) string {
-		return simpleDialog.Show(message, buttons, defaultButton)
+	app.SetTitledPermissionCallback(simpleDialog.ShowTitled)
+	app.SetTextInputCallback(simpleDialog.Prompt)
+	app.SetNotificationCallback(simpleDialog.Notify)
+	app.SetRemoteCallback(func(r config.Remote, message string, buttons []string) (string, error) {
+		return askRemote(r, cfg.Dialog.Title, message, buttons)
 	})
+	app.SetEscalationCallback(escalate)
+	if path, _ := configPath(argv); path != "" {
+		app.SetRulesFile(path)
+	}
 
-	if err := app.Run(); err != nil {
+	if path := approvals.DefaultPath(); path != "" {
+		app.SetApprovalCache(approvals.NewCache(path))
+	}
+	if path := decisions.DefaultPath(); path != "" {
+		app.SetDecisionLog(decisions.NewLog(path))
+	}
+	app.SetCrashDir(crash.DefaultDir())
+	if cfg.AuditLog != "" {
+		app.SetAuditLog(newAuditLog(&cfg))
+	}
+	if cfg.ScreenshotDir != "" {
+		if runtime.GOOS != "darwin" {
+			fmt.Fprintln(os.Stderr, "Warning: dialog screenshots are only taken on macOS")
+		}
+		app.SetScreenshots(expandHome(cfg.ScreenshotDir), dialog.CaptureWindow)
+	}
+	scheduleDigest(&cfg, parentDir(approvals.DefaultPath()))
+	if cfg.OTLPEndpoint != "" {
+		app.SetTraceExporter(tracing.NewExporter(cfg.OTLPEndpoint))
+	}
+	if cfg.EventStream != "" {
+		if stream, err := events.Open(expandHome(cfg.EventStream)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: events not written: %v\n", err)
+		} else {
+			defer stream.Close()
+			app.SetEventStream(stream)
+		}
+	}
+	if cfg.Transcript != "" {
+		if t, err := transcript.Open(transcriptPath(&cfg, time.Now())); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: transcript not saved: %v\n", err)
+		} else {
+			t.Start(time.Now(), os.Getpid(), projectDir())
+			defer t.End(time.Now())
+			app.SetTranscript(t)
+		}
+	}
+	var counters *telemetry.Counters
+	if cfg.Telemetry != "" {
+		counters = telemetry.NewCounters(dialogBackends(&cfg))
+		app.SetTelemetry(counters)
+	}
+	if cfg.Syslog != "" {
+		if logger, err := systemlog.Dial(cfg.Syslog); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: answers not mirrored to syslog: %v\n", err)
+		} else {
+			defer logger.Close()
+			app.SetSystemLog(logger)
+		}
+	}
+	restoreState(app)
+	if panicStarted(control.DefaultDir()) {
+		app.Panic()
+		fmt.Fprintln(os.Stderr, "dcode: rejecting every request after dcode panic; run dcode resume to stop")
+	}
+
+	watchConfig(app, argv)
+	if cfg.ModeFile {
+		watchModeFile(app, filepath.Join(projectDir(), config.ModeFileName))
+	}
+
+	// Let "dcode mode" switch modes while the session runs, and "dcode tail"
+	// follow its events
+	if server, err := control.Listen(control.SocketPath(control.DefaultDir(), os.Getpid()), controlHandler(app)); err != nil {
+		debug.Warn("control socket unavailable", "error", err)
+	} else {
+		defer server.Close()
+		app.SetLiveEvents(events.NewStream(server))
+	}
+
+	err = app.Run()
+	sendTelemetry(&cfg, counters)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "App error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// dialogBackends names the ways prompts can be answered with cfg, for
+// --telemetry
+func dialogBackends(cfg *config.Config) []string {
+	backends := []string{"osascript"}
+	if cfg.Remote.NtfyURL != "" {
+		backends = append(backends, "ntfy")
+	}
+	return backends
+}
+
+// sendTelemetry sends the counts of the session to the --telemetry URL, if
+// telemetry is on
+func sendTelemetry(cfg *config.Config, counters *telemetry.Counters) {
+	if counters == nil {
+		return
+	}
+	report := counters.Report()
+	debug.Info("sending telemetry", "url", cfg.Telemetry, "report", report)
+	if err := telemetry.Send(context.Background(), cfg.Telemetry, report); err != nil {
+		debug.Warn("failed to send telemetry", "error", err)
+	}
+}
+
+// restoreState keeps the session's state in the state file for the current
+// directory, first restoring what a dcode restarted in the last few hours left
+// there, and says so if that changed the mode
+func restoreState(app *App) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	path := state.DefaultPath(dir)
+	if path == "" {
+		return
+	}
+
+	saved, err := app.SetStateFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring the saved session state: %v\n", err)
+		return
+	}
+	if saved.Mode != "" || saved.AutoPaused {
+		fmt.Fprintf(os.Stderr, "dcode: restored %s mode from the previous run in this directory; change it with dcode mode\n", app.Mode())
+	}
+}
+
+// panicFromKey runs dcode panic when the PanicKey is typed
+func panicFromKey() {
+	go func() {
+		if err := runPanicCommand(control.DefaultDir(), io.Discard); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: dcode panic failed: %v\r\n", err)
+		}
+	}()
+}
+
+// loadConfig reads the config file given with --config, or the default one if
+// it exists, then applies the dcode flags in argv on top of it. Arguments that
+// aren't dcode flags are returned to be passed to claude.
+func loadConfig(argv []string) (config.Config, []string, error) {
+	cfg, _, err := loadConfigFile(argv)
+	if err != nil {
+		return cfg, nil, err
+	}
+	args, err := applyFlags(&cfg, argv)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if cfg.ImportClaudeSettings {
+		if err := importClaudeSettings(&cfg); err != nil {
+			return cfg, nil, err
+		}
+	}
+	if err := applyPolicy(&cfg, nil); err != nil {
+		return cfg, nil, err
+	}
+	return cfg, args, cfg.Validate()
+}
+
+// loadConfigFile reads the config file given with --config in argv, or the
+// default one, returning the defaults if the default one doesn't exist, and
+// the path of the file read, or ""
+func loadConfigFile(argv []string) (config.Config, string, error) {
+	path, explicit := configPath(argv)
+	if path == "" {
+		return config.Default(), "", nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil && !explicit && errors.Is(err, fs.ErrNotExist) {
+		return config.Default(), "", nil
+	}
+	return cfg, path, err
+}
+
+// applyFlags applies the dcode flags in argv to cfg, returning the other
+// arguments
+func applyFlags(cfg *config.Config, argv []string) ([]string, error) {
+	var args []string
+	for _, arg := range argv {
+		handled, err := applyFlag(cfg, arg)
+		if err != nil {
+			return nil, err
+		}
+		if !handled {
+			args = append(args, arg)
+		}
+	}
+	return args, nil
+}
+
+// applyPolicy adds the rules of the organization policy configured in cfg,
+// fetched with client, to cfg. A policy that can't be fetched or verified
+// stops dcode from running in a mode that approves without asking, such as
+// --auto-approve, which would otherwise approve what the policy forbids.
+// Otherwise dcode warns, asks as usual, and records the failure in cfg so
+// dcode mode can't switch to such a mode later.
+func applyPolicy(cfg *config.Config, client *http.Client) error {
+	if !cfg.Policy.Enabled() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), PolicyFetchTimeoutSec*time.Second)
+	defer cancel()
+
+	p, err := policy.Fetch(ctx, client, cfg.Policy)
+	if err != nil {
+		if mode := config.CurrentMode(cfg); mode.Approves() {
+			return fmt.Errorf("refusing to run with --%s without the organization policy: %w", mode, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: organization policy not applied: %v\n", err)
+		cfg.PolicyFailed = true
+		return nil
+	}
+	p.Apply(cfg)
+	debug.Info("applied organization policy", "url", cfg.Policy.URL)
+	return nil
+}
+
+// importClaudeSettings adds the allow and deny lists of the project's Claude
+// settings to the approve and deny rules in cfg, after the rules already
+// there
+func importClaudeSettings(cfg *config.Config) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	imported, err := claudesettings.Import(projectDir(), cwd)
+	if err != nil {
+		return err
+	}
+	for _, entry := range imported.Skipped {
+		debug.Info("skipped Claude permission without a matching dcode rule", "permission", entry)
+	}
+	cfg.Approve = append(cfg.Approve, imported.Approve...)
+	cfg.Deny = append(cfg.Deny, imported.Deny...)
+	return nil
+}
+
+// configPath returns the config file given with --config in argv, reporting
+// that it was given, or else the default one
+func configPath(argv []string) (string, bool) {
+	path, explicit := config.DefaultPath(), false
+	for _, arg := range argv {
+		if strings.HasPrefix(arg, "-config=") || strings.HasPrefix(arg, "--config=") {
+			path, explicit = strings.SplitN(arg, "=", 2)[1], true
+		}
+	}
+	return path, explicit
+}
+
+// watchConfig reloads the config file and the dcode flags in argv into app
+// whenever the file changes. An invalid file leaves the current options in
+// place.
+func watchConfig(app *App, argv []string) {
+	path, _ := configPath(argv)
+	if path == "" {
+		return
+	}
+
+	go config.Watch(path, ConfigPollIntervalMs*time.Millisecond, nil, func() {
+		cfg, _, err := loadConfig(argv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: config not reloaded: %v\n", err)
+			return
+		}
+		debug.SetRedact(newRedactor(&cfg).Redact)
+		app.Reload(&cfg)
+		debug.Info("reloaded config", "path", path)
+	})
+}
+
+// logOptions returns the debug log options set in cfg. --debug without a
+// log_level logs every level.
+func logOptions(cfg *config.Config) debug.Options {
+	file := cfg.LogFile
+	if file == "" {
+		file = debug.DefaultPath
+	}
+	options := debug.Options{
+		Path:     logPath(cfg, file),
+		Level:    slog.LevelDebug,
+		JSON:     cfg.LogFormat == config.LogFormatJSON,
+		Rotation: cfg.LogRotation.Rotation(),
+	}
+	if cfg.LogLevel != "" {
+		// Validate already rejected an unknown level
+		options.Level, _ = debug.ParseLevel(cfg.LogLevel)
+	}
+	return options
+}
+
+// newAuditLog returns the audit log set in cfg, rotated as cfg says
+func newAuditLog(cfg *config.Config) *audit.Log {
+	log := audit.NewLog(logPath(cfg, cfg.AuditLog))
+	log.SetRotation(cfg.LogRotation.Rotation())
+	log.SetRedact(newRedactor(cfg).Redact)
+	return log
+}
+
+// logPath returns where the log at path is written: in cfg.LogDir unless
+// path is absolute
+func logPath(cfg *config.Config, path string) string {
+	path = expandHome(path)
+	if cfg.LogDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(expandHome(cfg.LogDir), path)
+}
+
+// transcriptPath returns the file the --transcript of a session started at
+// start is saved to. Like the logs, a relative path is in cfg.LogDir.
+func transcriptPath(cfg *config.Config, start time.Time) string {
+	target := logPath(cfg, cfg.Transcript)
+	if strings.HasSuffix(cfg.Transcript, "/") && !strings.HasSuffix(target, "/") {
+		target += "/"
+	}
+	return transcript.Path(target, start, os.Getpid())
+}
+
+// watchModeFile switches app to the mode written to the file at path each
+// time the file changes
+func watchModeFile(app *App, path string) {
+	go config.Watch(path, ModeFilePollIntervalMs*time.Millisecond, nil, func() {
+		if err := applyModeFile(app, path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: mode not switched: %v\n", err)
+		}
+	})
+}
+
+// applyModeFile switches app to the mode named in the file at path, doing
+// nothing if the file is missing or empty. Claude can write the file, so it
+// can't switch to a mode that approves requests without asking; that takes
+// "dcode mode".
+func applyModeFile(app *App, path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && strings.TrimSpace(string(data)) == "") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	mode, err := config.ParseMode(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if mode.Approves() {
+		return fmt.Errorf("%s can't switch to %s; use dcode mode", path, mode)
+	}
+	if err := app.SetMode(mode); err != nil {
+		return err
+	}
+	debug.Info("switched mode", "mode", mode.String(), "path", path)
+	return nil
+}
+
+// askRemote posts a request titled title to the ntfy topic in r and waits
+// for a button to be pressed, giving up after r.TimeoutSeconds
+func askRemote(r config.Remote, title, message string, buttons []string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	number, err := remote.Ntfy{TopicURL: r.NtfyURL, Token: r.Token}.Ask(ctx, title, message, buttons)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(number), nil
+}
+
+// escalate plays the sound and pushes to the ntfy topic cfg.Escalation asks
+// for, to reach a user who left a dialog unanswered
+func escalate(cfg *config.Config, message string) error {
+	var errs []error
+	switch sound := cfg.Escalation.Sound; sound {
+	case "":
+	case config.SoundBell:
+		fmt.Fprint(os.Stderr, "\a")
+	default:
+		if err := dialog.PlaySound(sound); err != nil {
+			errs = append(errs, fmt.Errorf("failed to play %s: %w", sound, err))
+		}
+	}
+	if cfg.Escalation.Ntfy {
+		ctx, cancel := context.WithTimeout(context.Background(), EscalationTimeoutSec*time.Second)
+		defer cancel()
+		errs = append(errs, remote.Ntfy{TopicURL: cfg.Remote.NtfyURL, Token: cfg.Remote.Token}.Notify(ctx, cfg.Dialog.Title, message))
+	}
+	return errors.Join(errs...)
+}
+
+// addToolPolicy adds the entries of policy to cfg's tool policy, replacing
+// those for the same tools
+func addToolPolicy(cfg *config.Config, policy config.ToolPolicy) {
+	if cfg.ToolPolicy == nil {
+		cfg.ToolPolicy = config.ToolPolicy{}
+	}
+	for tool, action := range policy {
+		cfg.ToolPolicy[tool] = action
+	}
+}
+
+// applyFlag sets the option of cfg named by the dcode flag arg, reporting
+// whether arg was a dcode flag
+func applyFlag(cfg *config.Config, arg string) (bool, error) {
+	if arg == "-auto-approve" || arg == "--auto-approve" {
+		cfg.AutoApprove = true
+	} else if arg == "-allow-read-only" || arg == "--allow-read-only" {
+		cfg.AllowReadOnly = true
+	} else if arg == "-sync-settings" || arg == "--sync-settings" {
+		cfg.SyncSettings = true
+	} else if arg == "-import-claude-settings" || arg == "--import-claude-settings" {
+		cfg.ImportClaudeSettings = true
+	} else if arg == "-show-response-time" || arg == "--show-response-time" {
+		cfg.ShowResponseTime = true
+	} else if arg == "-remember-decisions" || arg == "--remember-decisions" {
+		cfg.RememberDecisions = true
+	} else if arg == "-mode-file" || arg == "--mode-file" {
+		cfg.ModeFile = true
+	} else if arg == "-safe-choices" || arg == "--safe-choices" {
+		cfg.SafeChoices = true
+	} else if arg == "-auto-reject" || arg == "--auto-reject" {
+		cfg.AutoReject = true
+	} else if strings.HasPrefix(arg, "-auto-reject-wait=") || strings.HasPrefix(arg, "--auto-reject-wait=") {
+		// Parse --auto-reject-wait=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if waitTime, err := strconv.Atoi(parts[1]); err == nil && waitTime >= 0 {
+			cfg.AutoRejectWait = waitTime
+		} else {
+			return true, fmt.Errorf("Invalid auto-reject-wait value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-auto-reject-wait-choice=") || strings.HasPrefix(arg, "--auto-reject-wait-choice=") {
+		// Parse --auto-reject-wait-choice=tell_claude/no/N format
+		parts := strings.SplitN(arg, "=", 2)
+		if !config.ValidTimeoutChoice(parts[1]) {
+			return true, fmt.Errorf("Invalid auto-reject-wait-choice value: %s (must be tell_claude, no, or a choice number)", parts[1])
+		}
+		cfg.AutoRejectWaitChoice = parts[1]
+	} else if strings.HasPrefix(arg, "-default-choice=") || strings.HasPrefix(arg, "--default-choice=") {
+		// Parse --default-choice=STRATEGY/N format
+		parts := strings.SplitN(arg, "=", 2)
+		if !config.ValidDefaultChoice(parts[1]) {
+			return true, fmt.Errorf("Invalid default-choice value: %s (must be best, safest, first, never-dont-ask-again, or a choice number)", parts[1])
+		}
+		cfg.DefaultChoice = parts[1]
+	} else if strings.HasPrefix(arg, "-auto-approve-wait=") || strings.HasPrefix(arg, "--auto-approve-wait=") {
+		// Parse --auto-approve-wait=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if waitTime, err := strconv.Atoi(parts[1]); err == nil && waitTime >= 0 {
+			cfg.AutoApproveWait = waitTime
+		} else {
+			return true, fmt.Errorf("Invalid auto-approve-wait value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-escalate-after=") || strings.HasPrefix(arg, "--escalate-after=") {
+		// Parse --escalate-after=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if seconds, err := strconv.Atoi(parts[1]); err == nil && seconds >= 0 {
+			cfg.Escalation.AfterSeconds = seconds
+		} else {
+			return true, fmt.Errorf("Invalid escalate-after value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-reject-message=") || strings.HasPrefix(arg, "--reject-message=") {
+		// Parse --reject-message=TEMPLATE format; Validate checks the placeholders
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.RejectMessage = parts[1]
+	} else if strings.HasPrefix(arg, "-audit-log=") || strings.HasPrefix(arg, "--audit-log=") {
+		// Parse --audit-log=PATH format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.AuditLog = parts[1]
+	} else if strings.HasPrefix(arg, "-log-file=") || strings.HasPrefix(arg, "--log-file=") {
+		// Parse --log-file=PATH format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.LogFile = parts[1]
+	} else if strings.HasPrefix(arg, "-log-dir=") || strings.HasPrefix(arg, "--log-dir=") {
+		// Parse --log-dir=DIR format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.LogDir = parts[1]
+	} else if strings.HasPrefix(arg, "-log-level=") || strings.HasPrefix(arg, "--log-level=") {
+		// Parse --log-level=LEVEL format; Validate checks the level
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.LogLevel = parts[1]
+	} else if strings.HasPrefix(arg, "-log-format=") || strings.HasPrefix(arg, "--log-format=") {
+		// Parse --log-format=text|json format; Validate checks the format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.LogFormat = parts[1]
+	} else if strings.HasPrefix(arg, "-otlp-endpoint=") || strings.HasPrefix(arg, "--otlp-endpoint=") {
+		// Parse --otlp-endpoint=URL format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.OTLPEndpoint = parts[1]
+	} else if strings.HasPrefix(arg, "-event-stream=") || strings.HasPrefix(arg, "--event-stream=") {
+		// Parse --event-stream=PATH|FD format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.EventStream = parts[1]
+	} else if strings.HasPrefix(arg, "-transcript=") || strings.HasPrefix(arg, "--transcript=") {
+		// Parse --transcript=PATH|DIR/ format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.Transcript = parts[1]
+	} else if strings.HasPrefix(arg, "-screenshot-dir=") || strings.HasPrefix(arg, "--screenshot-dir=") {
+		// Parse --screenshot-dir=DIR format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.ScreenshotDir = parts[1]
+	} else if strings.HasPrefix(arg, "-telemetry=") || strings.HasPrefix(arg, "--telemetry=") {
+		// Parse --telemetry=URL format
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.Telemetry = parts[1]
+	} else if arg == "-no-telemetry" || arg == "--no-telemetry" {
+		cfg.Telemetry = ""
+	} else if strings.HasPrefix(arg, "-syslog=") || strings.HasPrefix(arg, "--syslog=") {
+		// Parse --syslog=FACILITY format; Validate checks the facility
+		parts := strings.SplitN(arg, "=", 2)
+		cfg.Syslog = parts[1]
+	} else if strings.HasPrefix(arg, "-prevent-scrollback-clear=") || strings.HasPrefix(arg, "--prevent-scrollback-clear=") {
+		// Parse --prevent-scrollback-clear=true/false format
+		parts := strings.SplitN(arg, "=", 2)
+		if parts[1] == "true" {
+			cfg.PreventScrollbackClear = true
+		} else if parts[1] == "false" {
+			cfg.PreventScrollbackClear = false
+		} else if parts[1] == "" {
+			return true, fmt.Errorf("prevent-scrollback-clear flag requires a value (true or false)")
+		} else {
+			return true, fmt.Errorf("Invalid prevent-scrollback-clear value: %s (must be true or false)", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-continue-prompts=") || strings.HasPrefix(arg, "--continue-prompts=") {
+		// Parse --continue-prompts=ignore/auto/dialog format
+		parts := strings.SplitN(arg, "=", 2)
+		switch parts[1] {
+		case config.ContinuePromptsIgnore, config.ContinuePromptsAuto, config.ContinuePromptsDialog:
+			cfg.ContinuePrompts = parts[1]
+		default:
+			return true, fmt.Errorf("Invalid continue-prompts value: %s (must be ignore, auto, or dialog)", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-trust-dir=") || strings.HasPrefix(arg, "--trust-dir=") {
+		// Parse --trust-dir=PATH format (repeatable)
+		parts := strings.SplitN(arg, "=", 2)
+		if parts[1] == "" {
+			return true, fmt.Errorf("trust-dir flag requires a folder path")
+		}
+		cfg.TrustDirs = append(cfg.TrustDirs, parts[1])
+	} else if strings.HasPrefix(arg, "-edit-dir=") || strings.HasPrefix(arg, "--edit-dir=") {
+		// Parse --edit-dir=PATH format (repeatable)
+		parts := strings.SplitN(arg, "=", 2)
+		if parts[1] == "" {
+			return true, fmt.Errorf("edit-dir flag requires a folder path")
+		}
+		cfg.EditScope.Dirs = append(cfg.EditScope.Dirs, parts[1])
+	} else if strings.HasPrefix(arg, "-display-backpressure=") || strings.HasPrefix(arg, "--display-backpressure=") {
+		// Parse --display-backpressure=block/drop format
+		parts := strings.SplitN(arg, "=", 2)
+		if _, err := dialog.ParseBackpressurePolicy(parts[1]); err != nil {
+			return true, fmt.Errorf("Invalid display-backpressure value: %s (must be block or drop)", parts[1])
+		}
+		cfg.DisplayBackpressure = parts[1]
+	} else if strings.HasPrefix(arg, "-locale=") || strings.HasPrefix(arg, "--locale=") {
+		// Parse --locale=ja[,...] format
+		parts := strings.SplitN(arg, "=", 2)
+		if _, err := types.NewRegexPatternsForLocales(strings.Split(parts[1], ",")...); err != nil {
+			return true, fmt.Errorf("Invalid locale value: %v", err)
+		}
+		cfg.Locale = parts[1]
+	} else if strings.HasPrefix(arg, "-language=") || strings.HasPrefix(arg, "--language=") {
+		// Parse --language=en/ja/auto format
+		parts := strings.SplitN(arg, "=", 2)
+		if !i18n.Valid(parts[1]) {
+			return true, fmt.Errorf("Invalid language value: %s (must be %s, or auto)", parts[1], strings.Join(i18n.Languages(), ", "))
+		}
+		cfg.Language = parts[1]
+	} else if strings.HasPrefix(arg, "-dialog-quiescence-ms=") || strings.HasPrefix(arg, "--dialog-quiescence-ms=") {
+		// Parse --dialog-quiescence-ms=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if quiescence, err := strconv.Atoi(parts[1]); err == nil && quiescence >= 0 {
+			cfg.DialogQuiescenceMs = quiescence
+		} else {
+			return true, fmt.Errorf("Invalid dialog-quiescence-ms value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-approval-cache-seconds=") || strings.HasPrefix(arg, "--approval-cache-seconds=") {
+		// Parse --approval-cache-seconds=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if seconds, err := strconv.Atoi(parts[1]); err == nil && seconds >= 0 {
+			cfg.ApprovalCacheSeconds = seconds
+		} else {
+			return true, fmt.Errorf("Invalid approval-cache-seconds value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-temporary-approval-minutes=") || strings.HasPrefix(arg, "--temporary-approval-minutes=") {
+		// Parse --temporary-approval-minutes=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if minutes, err := strconv.Atoi(parts[1]); err == nil && minutes >= 0 {
+			cfg.TemporaryApprovalMinutes = minutes
+		} else {
+			return true, fmt.Errorf("Invalid temporary-approval-minutes value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-max-auto-approvals=") || strings.HasPrefix(arg, "--max-auto-approvals=") {
+		// Parse --max-auto-approvals=N format
+		parts := strings.SplitN(arg, "=", 2)
+		if limit, err := strconv.Atoi(parts[1]); err == nil && limit >= 0 {
+			cfg.MaxAutoApprovals = limit
+		} else {
+			return true, fmt.Errorf("Invalid max-auto-approvals value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-digest-minutes=") || strings.HasPrefix(arg, "--digest-minutes=") {
+		// Parse --digest-minutes=M format
+		parts := strings.SplitN(arg, "=", 2)
+		if minutes, err := strconv.Atoi(parts[1]); err == nil && minutes >= 0 {
+			cfg.DigestMinutes = minutes
+		} else {
+			return true, fmt.Errorf("Invalid digest-minutes value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-rejection-loop-limit=") || strings.HasPrefix(arg, "--rejection-loop-limit=") {
+		// Parse --rejection-loop-limit=K format
+		parts := strings.SplitN(arg, "=", 2)
+		if limit, err := strconv.Atoi(parts[1]); err == nil && limit >= 0 {
+			cfg.RejectionLoopLimit = limit
+		} else {
+			return true, fmt.Errorf("Invalid rejection-loop-limit value: %s", parts[1])
+		}
+	} else if strings.HasPrefix(arg, "-tool-policy=") || strings.HasPrefix(arg, "--tool-policy=") {
+		// Parse --tool-policy=TOOL=ACTION[,...] format, adding to the config file's policy
+		parts := strings.SplitN(arg, "=", 2)
+		policy, err := config.ParseToolPolicy(parts[1])
+		if err != nil {
+			return true, err
+		}
+		addToolPolicy(cfg, policy)
+	} else if strings.HasPrefix(arg, "-delays=") || strings.HasPrefix(arg, "--delays=") {
+		// Parse --delays=NAME=MS[,...] format, overriding the config file's delays
+		parts := strings.SplitN(arg, "=", 2)
+		if err := cfg.Delays.Set(parts[1]); err != nil {
+			return true, err
+		}
+	} else if strings.HasPrefix(arg, "-auto-approve-tools=") || strings.HasPrefix(arg, "--auto-approve-tools=") {
+		// Parse --auto-approve-tools=TOOL[,...] format as tool_policy allow entries
+		parts := strings.SplitN(arg, "=", 2)
+		policy, err := config.ToolPolicyFor(parts[1], config.ToolPolicyAllow)
+		if err != nil {
+			return true, err
+		}
+		addToolPolicy(cfg, policy)
+	} else if strings.HasPrefix(arg, "-auto-reject-tools=") || strings.HasPrefix(arg, "--auto-reject-tools=") {
+		// Parse --auto-reject-tools=TOOL[,...] format as tool_policy deny entries
+		parts := strings.SplitN(arg, "=", 2)
+		policy, err := config.ToolPolicyFor(parts[1], config.ToolPolicyDeny)
+		if err != nil {
+			return true, err
+		}
+		addToolPolicy(cfg, policy)
+	} else if strings.HasPrefix(arg, "-auto-approve-pattern=") || strings.HasPrefix(arg, "--auto-approve-pattern=") {
+		// Parse --auto-approve-pattern=REGEX format (repeatable) as an approve rule on the command; the regex is checked by Validate
+		parts := strings.SplitN(arg, "=", 2)
+		if parts[1] == "" {
+			return true, fmt.Errorf("auto-approve-pattern flag requires a regular expression")
+		}
+		cfg.Approve = append(cfg.Approve, config.Rule{Command: parts[1]})
+	} else if strings.HasPrefix(arg, "-auto-reject-pattern=") || strings.HasPrefix(arg, "--auto-reject-pattern=") {
+		// Parse --auto-reject-pattern=REGEX format (repeatable) as a deny rule on the command; the regex is checked by Validate
+		parts := strings.SplitN(arg, "=", 2)
+		if parts[1] == "" {
+			return true, fmt.Errorf("auto-reject-pattern flag requires a regular expression")
+		}
+		cfg.Deny = append(cfg.Deny, config.DenyRule{Rule: config.Rule{Command: parts[1]}})
+	} else if strings.HasPrefix(arg, "-quiet-hours=") || strings.HasPrefix(arg, "--quiet-hours=") {
+		// Parse --quiet-hours=HH:MM-HH:MM[,...] format; the windows are checked by Validate
+		parts := strings.SplitN(arg, "=", 2)
+		if parts[1] == "" {
+			return true, fmt.Errorf("quiet-hours flag requires a time window such as 23:00-07:00")
+		}
+		cfg.QuietHours.Windows = strings.Split(parts[1], ",")
+	} else if strings.HasPrefix(arg, "-config=") || strings.HasPrefix(arg, "--config=") {
+		// Already read by loadConfig
+	} else if arg == "-prevent-scrollback-clear" || arg == "--prevent-scrollback-clear" {
+		cfg.PreventScrollbackClear = true
+	} else if arg == "-strip-colors" || arg == "--strip-colors" {
+		cfg.StripColors = true
+	} else if arg == "-debug" || arg == "--debug" {
+		cfg.Debug = true
+	} else {
+		return false, nil
+	}
+	return true, nil
+}
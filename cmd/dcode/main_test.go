@@ -96,11 +96,10 @@ func TestSendAutoRejectWithWait_MaxChoiceSelection(t *testing.T) {
 		appState:           appState,
 		permissionCallback: callback,
 	}
+	handler.options.Store(defaultConfig())
 
 	// Set a short timeout for testing
-	originalTimeout := *autoRejectWait
-	*autoRejectWait = 1 // 1 second
-	defer func() { *autoRejectWait = originalTimeout }()
+	handler.config().AutoRejectWait = 1 // 1 second
 
 	// This test verifies the function runs without panic
 	// The actual dialog interaction is difficult to test without complex mocking
@@ -151,6 +150,7 @@ func TestHandleDialogCooldown(t *testing.T) {
 		appState:           appState,
 		permissionCallback: callback,
 	}
+	handler.options.Store(defaultConfig())
 
 	// Verify JustShown is initially true
 	if !appState.Prompt.JustShown {
@@ -182,6 +182,16 @@ func TestFindMaxRejectChoice(t *testing.T) {
 			},
 			expected: "3",
 		},
+		{
+			name: "selects the reject choice rather than the highest number",
+			choices: map[string]string{
+				"1": "Yes",
+				"2": "Yes, allow all edits during this session",
+				"3": "Yes, and don't ask again",
+				"4": "No, and tell Claude what to do differently (esc)",
+			},
+			expected: "4",
+		},
 		{
 			name: "selects choice 2 when 3 not available",
 			choices: map[string]string{
@@ -190,6 +200,17 @@ func TestFindMaxRejectChoice(t *testing.T) {
 			},
 			expected: "2",
 		},
+		{
+			name: "selects the highest double-digit choice",
+			choices: map[string]string{
+				"1":  "Option 1",
+				"2":  "Option 2",
+				"9":  "Option 9",
+				"10": "Option 10",
+				"11": "Option 11",
+			},
+			expected: "11",
+		},
 		{
 			name: "defaults to 2 when only choice 1 exists",
 			choices: map[string]string{
@@ -252,11 +273,10 @@ func TestSendAutoRejectWithWait_DialogAfterTimeout(t *testing.T) {
 		appState:           appState,
 		permissionCallback: callback,
 	}
+	handler.options.Store(defaultConfig())
 
 	// Use very short timeout to trigger race condition
-	originalTimeout := *autoRejectWait
-	*autoRejectWait = 1 // 1 second timeout
-	defer func() { *autoRejectWait = originalTimeout }()
+	handler.config().AutoRejectWait = 1 // 1 second timeout
 
 	// Test should not panic even when dialog completes after timeout
 	handler.sendAutoRejectWithWait("1")
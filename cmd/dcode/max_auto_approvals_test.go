@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// capAutoApprovals auto-approves everything, but at most limit times in a row
+func capAutoApprovals(limit int) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		cfg.AutoApprove = true
+		cfg.MaxAutoApprovals = limit
+		cfg.Delays.DialogResetMs = 0
+		cfg.Delays.ChoiceProcessingMs = 0
+		cfg.Delays.AutoApproveMs = 0
+	}
+}
+
+// approveInARow sends one dialog per command, each asked differently so none
+// is taken for a redraw, waiting for each automatic approval
+func approveInARow(robot *AppRobot, minute int, commands ...string) {
+	for i, command := range commands {
+		robot.SetFakeTime(after(time.Duration(minute+i) * time.Minute)).
+			ReceiveClaudeText(askedAs(command, "Do you want to run "+command+"?")...)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestMaxAutoApprovalsForcesDialog(t *testing.T) {
+	robot := NewAppRobot(t).Configure(capAutoApprovals(2))
+	approveInARow(robot, 0, "make", "make lint")
+	robot.AssertNoDialogCaptured()
+	if output := robot.GetTerminalOutput(); output != "11" {
+		t.Fatalf("Expected two automatic approvals, got: %q", output)
+	}
+
+	robot.SetDialogChoice("1")
+	approveInARow(robot, 2, "make deploy")
+	robot.AssertDialogCaptured().
+		AssertDialogTextContains("dcode approved 2 requests in a row without asking").
+		AssertDialogTextContains("• Bash: make\n• Bash: make lint").
+		AssertDialogTextContains("make deploy")
+
+	t.Run("Answering the dialog starts a new count", func(t *testing.T) {
+		robot.ClearCapturedDialog()
+		approveInARow(robot, 3, "make test", "make docs")
+		robot.AssertNoDialogCaptured()
+		if output := robot.GetTerminalOutput(); output != "11111" {
+			t.Errorf("Expected approvals to resume, got: %q", output)
+		}
+	})
+}
+
+func TestMaxAutoApprovalsSummarizesLatest(t *testing.T) {
+	robot := NewAppRobot(t).Configure(capAutoApprovals(AutoApprovalSummaryLines + 2))
+	var commands []string
+	for i := 0; i <= AutoApprovalSummaryLines+2; i++ {
+		commands = append(commands, "make step"+string(rune('a'+i)))
+	}
+	approveInARow(robot, 0, commands...)
+
+	robot.AssertDialogCaptured().
+		AssertDialogTextContains("… 2 earlier\n• Bash: make stepc")
+}
+
+func TestNoMaxAutoApprovalsByDefault(t *testing.T) {
+	robot := NewAppRobot(t).Configure(capAutoApprovals(0))
+	approveInARow(robot, 0, "make", "make lint", "make test")
+	robot.AssertNoDialogCaptured()
+	if output := robot.GetTerminalOutput(); output != "111" {
+		t.Errorf("Expected every request approved, got: %q", output)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/control"
+)
+
+// modeUsage describes the "dcode mode" subcommand
+const modeUsage = `usage: dcode mode [dialog|auto-approve|auto-reject|auto-reject-wait[=SECONDS]|auto-approve-wait[=SECONDS]] [--pid=PID]`
+
+// controlModeCommand is the control socket command that reports or switches
+// a session's mode
+const controlModeCommand = "mode"
+
+// runModeCommand runs "dcode mode" with the arguments after "mode": it
+// switches a running session to the mode given, or prints its mode, writing
+// the result to out. --pid picks the session when more than one is running.
+func runModeCommand(argv []string, dir string, out io.Writer) error {
+	var mode string
+	pid := 0
+	for _, arg := range argv {
+		if value, found := strings.CutPrefix(strings.TrimLeft(arg, "-"), "pid="); found && strings.HasPrefix(arg, "-") {
+			number, err := strconv.Atoi(value)
+			if err != nil || number <= 0 {
+				return fmt.Errorf("invalid pid: %s", value)
+			}
+			pid = number
+		} else if mode == "" && !strings.HasPrefix(arg, "-") {
+			mode = arg
+		} else {
+			return errors.New(modeUsage)
+		}
+	}
+	if mode != "" {
+		if _, err := config.ParseMode(mode); err != nil {
+			return err
+		}
+	}
+
+	pid, err := sessionPID(dir, pid)
+	if err != nil {
+		return err
+	}
+
+	reply, err := control.Send(control.SocketPath(dir, pid), strings.TrimSpace(controlModeCommand+" "+mode))
+	if err != nil {
+		return fmt.Errorf("dcode session %d: %w", pid, err)
+	}
+	fmt.Fprintf(out, "Session %d: %s\n", pid, reply)
+	return nil
+}
+
+// sessionPID returns pid, or if it's 0 the only session running in dir
+func sessionPID(dir string, pid int) (int, error) {
+	if pid != 0 {
+		return pid, nil
+	}
+	pids, err := control.Sessions(dir)
+	if err != nil {
+		return 0, err
+	}
+	switch len(pids) {
+	case 0:
+		return 0, errors.New("no running dcode session found")
+	case 1:
+		return pids[0], nil
+	default:
+		return 0, fmt.Errorf("%d dcode sessions are running (PIDs %s); pick one with --pid=PID", len(pids), joinPIDs(pids))
+	}
+}
+
+// controlHandler answers commands sent to app's control socket
+func controlHandler(app *App) control.Handler {
+	return func(command string) (string, error) {
+		name, argument, _ := strings.Cut(command, " ")
+		switch {
+		case name == controlPanicCommand:
+			app.Panic()
+			return "rejecting every request", nil
+		case name == controlResumeCommand:
+			app.Resume()
+			return "resumed " + app.Mode().String(), nil
+		case name == controlDedupCommand:
+			data, err := json.Marshal(app.DedupStats())
+			return string(data), err
+		case name != controlModeCommand:
+			return "", fmt.Errorf("unknown command: %s", name)
+		case argument == "" && app.Panicked():
+			return app.Mode().String() + ", but rejecting every request until dcode resume", nil
+		case argument == "":
+			return app.Mode().String(), nil
+		case app.Panicked():
+			return "", errors.New("every request is rejected after dcode panic; run dcode resume first")
+		}
+		mode, err := config.ParseMode(argument)
+		if err != nil {
+			return "", err
+		}
+		if err := app.SetMode(mode); err != nil {
+			return "", err
+		}
+		return "switched to " + app.Mode().String(), nil
+	}
+}
+
+func joinPIDs(pids []int) string {
+	texts := make([]string, len(pids))
+	for i, pid := range pids {
+		texts[i] = strconv.Itoa(pid)
+	}
+	return strings.Join(texts, ", ")
+}
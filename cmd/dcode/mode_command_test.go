@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/control"
+)
+
+// newControlDir returns a directory for sessions' sockets that doesn't exist
+// yet, so it's made private like the one dcode uses
+func newControlDir(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "dcode")
+}
+
+// listenForControl serves robot's app on a control socket for pid in dir
+func listenForControl(t *testing.T, robot *AppRobot, dir string, pid int) {
+	t.Helper()
+	server, err := control.Listen(control.SocketPath(dir, pid), controlHandler(robot.app))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+}
+
+// runMode runs "dcode mode" with args against the sessions in dir
+func runMode(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	var out strings.Builder
+	err := runModeCommand(args, dir, &out)
+	return out.String(), err
+}
+
+func TestModeCommandSwitchesSession(t *testing.T) {
+	dir := newControlDir(t)
+	robot := NewAppRobot(t)
+	listenForControl(t, robot, dir, 1234)
+
+	output, err := runMode(t, dir, "auto-approve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "Session 1234: switched to auto-approve\n" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+
+	robot.ReceiveClaudeText(bashDialogLines("npm install")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the dialog to be approved, got: %q", output)
+	}
+}
+
+func TestModeCommandReportsMode(t *testing.T) {
+	dir := newControlDir(t)
+	robot := NewAppRobot(t).SetAutoRejectWait(10)
+	listenForControl(t, robot, dir, 1234)
+
+	output, err := runMode(t, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "Session 1234: auto-reject-wait=10\n" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+}
+
+func TestModeSurvivesConfigReload(t *testing.T) {
+	robot := NewAppRobot(t)
+	robot.app.SetMode(config.Mode{Name: config.ModeAutoReject})
+	robot.ReloadConfig(func(cfg *config.Config) { cfg.AutoApprove = true })
+
+	if mode := robot.app.Mode().String(); mode != config.ModeAutoReject {
+		t.Errorf("Expected the switched mode to stay, got %s", mode)
+	}
+}
+
+func TestModeCommandPicksSession(t *testing.T) {
+	dir := newControlDir(t)
+	first, second := NewAppRobot(t), NewAppRobot(t)
+	listenForControl(t, first, dir, 100)
+	listenForControl(t, second, dir, 200)
+
+	if _, err := runMode(t, dir, "auto-reject"); err == nil || !strings.Contains(err.Error(), "--pid") {
+		t.Errorf("Expected an error asking for --pid, got %v", err)
+	}
+	if _, err := runMode(t, dir, "auto-reject", "--pid=200"); err != nil {
+		t.Fatal(err)
+	}
+	if mode := second.app.Mode().String(); mode != config.ModeAutoReject {
+		t.Errorf("Expected the picked session to switch, got %s", mode)
+	}
+	if mode := first.app.Mode().String(); mode != config.ModeDialog {
+		t.Errorf("Expected the other session to stay, got %s", mode)
+	}
+}
+
+func TestModeCommandErrors(t *testing.T) {
+	dir := newControlDir(t)
+	if _, err := runMode(t, dir, "auto-approve"); err == nil || !strings.Contains(err.Error(), "no running dcode session") {
+		t.Errorf("Expected an error without sessions, got %v", err)
+	}
+	if _, err := runMode(t, dir, "yolo"); err == nil || !strings.Contains(err.Error(), "invalid mode") {
+		t.Errorf("Expected an invalid mode error, got %v", err)
+	}
+	if _, err := runMode(t, dir, "dialog", "auto-approve"); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("Expected the usage, got %v", err)
+	}
+}
+
+func TestModeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.ModeFileName)
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+		wantErr  bool
+	}{
+		{"no file", "", "auto-reject-wait=10", false},
+		{"empty", "\n", "auto-reject-wait=10", false},
+		{"mode", "dialog\n", "dialog", false},
+		{"mode with seconds", "auto-reject-wait=5", "auto-reject-wait=5", false},
+		{"approving mode", "auto-approve\n", "auto-reject-wait=10", true},
+		{"unknown mode", "ask", "auto-reject-wait=10", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			robot := NewAppRobot(t).SetAutoRejectWait(10)
+			if tc.name != "no file" {
+				if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := applyModeFile(robot.app, path)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if mode := robot.app.Mode(); mode.String() != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, mode)
+			}
+		})
+	}
+}
+
+func TestModeCommandRefusesApprovalWithoutPolicy(t *testing.T) {
+	dir := newControlDir(t)
+	robot := NewAppRobot(t).Configure(func(cfg *config.Config) { cfg.PolicyFailed = true })
+	listenForControl(t, robot, dir, 1234)
+
+	for _, mode := range []string{"auto-approve", "auto-approve-wait=30"} {
+		if _, err := runMode(t, dir, mode); err == nil || !strings.Contains(err.Error(), "organization policy") {
+			t.Errorf("Expected %s to be refused without the policy, got %v", mode, err)
+		}
+	}
+	if mode := robot.app.Mode().String(); mode != config.ModeDialog {
+		t.Errorf("Expected the session to keep asking, got %s", mode)
+	}
+	if _, err := runMode(t, dir, "auto-reject"); err != nil {
+		t.Errorf("Expected modes that don't approve to be allowed, got %v", err)
+	}
+}
+
+func TestReloadWithoutPolicyLeavesApprovingMode(t *testing.T) {
+	robot := NewAppRobot(t)
+	if err := robot.app.SetMode(config.Mode{Name: config.ModeAutoApprove}); err != nil {
+		t.Fatal(err)
+	}
+	// The config file itself doesn't approve; only the switched mode did
+	robot.ReloadConfig(func(cfg *config.Config) { cfg.AutoApprove, cfg.PolicyFailed = false, true })
+
+	if mode := robot.app.Mode().String(); mode != config.ModeDialog {
+		t.Errorf("Expected the session to go back to asking, got %s", mode)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/takahirom/dialog-code/internal/control"
+)
+
+// Control socket commands that start and stop rejecting every request
+const (
+	controlPanicCommand  = "panic"
+	controlResumeCommand = "resume"
+)
+
+// PanicKey typed in a session's terminal (Ctrl+]) runs dcode panic instead of
+// reaching Claude
+const PanicKey = 0x1d
+
+// runPanicCommand runs "dcode panic": every session in dir, and every one
+// started until "dcode resume", rejects all requests without asking,
+// including dialogs already waiting for an answer
+func runPanicCommand(dir string, out io.Writer) error {
+	if err := control.PrepareDir(dir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(control.PanicPath(dir), nil, 0o600); err != nil {
+		return err
+	}
+	if err := sendToSessions(dir, controlPanicCommand, out); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Rejecting every request until dcode resume")
+	return nil
+}
+
+// runResumeCommand runs "dcode resume", undoing "dcode panic" in every
+// session in dir
+func runResumeCommand(dir string, out io.Writer) error {
+	if err := os.Remove(control.PanicPath(dir)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := sendToSessions(dir, controlResumeCommand, out); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Handling requests as configured again")
+	return nil
+}
+
+// sendToSessions sends command to every session in dir, writing each reply
+// to out. A session that doesn't answer is reported without stopping the
+// others.
+func sendToSessions(dir, command string, out io.Writer) error {
+	pids, err := control.Sessions(dir)
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		reply, err := control.Send(control.SocketPath(dir, pid), command)
+		if err != nil {
+			reply = err.Error()
+		}
+		fmt.Fprintf(out, "Session %d: %s\n", pid, reply)
+	}
+	return nil
+}
+
+// panicStarted reports whether "dcode panic" was run for the sessions in dir
+// without a "dcode resume" since
+func panicStarted(dir string) bool {
+	_, err := os.Stat(control.PanicPath(dir))
+	return err == nil
+}
+
+// hotkeyWriter writes what the user types to w, calling onKey instead of
+// writing each key byte
+type hotkeyWriter struct {
+	w     io.Writer
+	key   byte
+	onKey func()
+}
+
+func (h hotkeyWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, h.key)
+		if i < 0 {
+			i = len(b)
+		}
+		if i > 0 {
+			if _, err := h.w.Write(b[:i]); err != nil {
+				return 0, err
+			}
+		}
+		if i < len(b) {
+			h.onKey()
+			i++
+		}
+		b = b[i:]
+	}
+	return n, nil
+}
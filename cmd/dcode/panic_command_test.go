@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/control"
+)
+
+func TestPanicCommandRejectsUntilResume(t *testing.T) {
+	dir := newControlDir(t)
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoApprove = true
+			cfg.Delays.DialogResetMs = 0
+		})
+	listenForControl(t, robot, dir, 1234)
+
+	var out strings.Builder
+	if err := runPanicCommand(dir, &out); err != nil {
+		t.Fatal(err)
+	}
+	if output := out.String(); output != "Session 1234: rejecting every request\nRejecting every request until dcode resume\n" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+	if !panicStarted(dir) {
+		t.Error("Expected the panic file to be created")
+	}
+
+	robot.ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") || !strings.Contains(output, PanicBaseMessage) {
+		t.Errorf("Expected the dialog to be rejected, got: %q", output)
+	}
+	if _, err := runMode(t, dir, "auto-approve"); err == nil {
+		t.Error("Expected dcode mode to be refused until dcode resume")
+	}
+
+	out.Reset()
+	if err := runResumeCommand(dir, &out); err != nil {
+		t.Fatal(err)
+	}
+	if output := out.String(); output != "Session 1234: resumed auto-approve\nHandling requests as configured again\n" {
+		t.Errorf("Unexpected output: %q", output)
+	}
+	if panicStarted(dir) {
+		t.Error("Expected the panic file to be removed")
+	}
+
+	robot.SetFakeTime(after(time.Minute)).
+		ReceiveClaudeText(askedAs("npm install", "Do you want to run npm install?")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+	if output := robot.GetTerminalOutput(); !strings.HasSuffix(output, "1") {
+		t.Errorf("Expected the dialog to be approved after resuming, got: %q", output)
+	}
+}
+
+func TestPanicRejectsWaitingDialog(t *testing.T) {
+	robot := NewAppRobot(t).
+		LeaveDialogsUnanswered().
+		ReceiveClaudeText(bashDialogLines("git push --force")...).
+		AssertDialogCaptured()
+
+	robot.app.Panic()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") || !strings.Contains(output, PanicBaseMessage) {
+		t.Errorf("Expected the waiting dialog to be rejected, got: %q", output)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); !strings.Contains(notification, "dcode resume") {
+		t.Errorf("Expected a notification about dcode resume, got: %q", notification)
+	}
+}
+
+func TestPanicDeclinesTrustedFolder(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.TrustDirs = []string{"/Users/test/git"} })
+	robot.app.Panic()
+
+	robot.ReceiveClaudeText(trustDialogLines...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); output != "2" {
+		t.Errorf("Expected the trust prompt to be declined, got: %q", output)
+	}
+}
+
+func TestPanicFileExists(t *testing.T) {
+	dir := t.TempDir()
+	if panicStarted(dir) {
+		t.Fatal("Expected no panic before dcode panic")
+	}
+	if err := os.WriteFile(control.PanicPath(dir), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !panicStarted(dir) {
+		t.Error("Expected the panic file to be found")
+	}
+}
+
+func TestHotkeyWriter(t *testing.T) {
+	var out strings.Builder
+	presses := 0
+	w := hotkeyWriter{w: &out, key: PanicKey, onKey: func() { presses++ }}
+
+	n, err := w.Write([]byte("ab\x1dcd\x1d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("Expected every byte to be reported written, got %d", n)
+	}
+	if out.String() != "abcd" || presses != 2 {
+		t.Errorf("Expected the keys to be removed and seen twice, got %q and %d", out.String(), presses)
+	}
+}
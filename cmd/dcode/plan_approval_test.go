@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+var planDialogLines = []string{
+	"╭───────────────────────────────────────────────────────────────────────╮",
+	"│ Ready to code?                                                        │",
+	"│                                                                       │",
+	"│ Here is Claude's plan:                                                │",
+	"│ ╭───────────────────────────────────────────────────────────────────╮ │",
+	"│ │ - Add a --verbose flag                                            │ │",
+	"│ │ - Update the README                                               │ │",
+	"│ ╰───────────────────────────────────────────────────────────────────╯ │",
+	"│                                                                       │",
+	"│ Would you like to proceed?                                            │",
+	"│                                                                       │",
+	"│ ❯ 1. Yes, and auto-accept edits                                       │",
+	"│   2. Yes, and manually approve edits                                  │",
+	"│   3. No, keep planning                                                │",
+	"╰───────────────────────────────────────────────────────────────────────╯",
+}
+
+func TestPlanApprovalShowsDialog(t *testing.T) {
+	NewAppRobot(t).
+		SetDialogChoice("3").
+		ReceiveClaudeText(planDialogLines...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("Reason: Plan approval").
+		AssertDialogTextContains("Add a --verbose flag").
+		AssertDialogTextContains("Would you like to proceed?").
+		AssertButtonCount(3).
+		AssertButton(0, "Yes, and auto-accept edits").
+		AssertButton(1, "Yes, and manually approve edits").
+		AssertButton(2, "No, keep planning").
+		AssertTerminalContains("3")
+}
+
+func TestPlanApprovalAutoApprovePrefersManualEdits(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.AutoApprove = true }).
+		ReceiveClaudeText(planDialogLines...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	// Auto-accepting edits would bypass every later permission dialog
+	if robot.GetTerminalOutput() != "2" {
+		t.Errorf("Expected plan approval to select choice 2, got: %q", robot.GetTerminalOutput())
+	}
+}
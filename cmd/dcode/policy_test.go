@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestApplyPolicyWithoutVerifiedPolicy(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		autoApprove bool
+		expectError bool
+	}{
+		{"auto-approve refuses to run", true, true},
+		{"dialogs continue with a warning", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.AutoApprove = tc.autoApprove
+			cfg.Policy = config.PolicySource{URL: server.URL + "/policy.yaml", PublicKey: "ssh-ed25519 AAAA"}
+
+			err := applyPolicy(&cfg, server.Client())
+			if tc.expectError && (err == nil || !strings.Contains(err.Error(), "--auto-approve")) {
+				t.Errorf("Expected an error about --auto-approve, got %v", err)
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if len(cfg.Forbid) != 0 || len(cfg.Deny) != 0 || len(cfg.Approve) != 0 {
+				t.Error("Expected no rules to be added")
+			}
+		})
+	}
+}
+
+func TestApplyPolicyDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.AutoApprove = true
+	if err := applyPolicy(&cfg, nil); err != nil {
+		t.Errorf("Expected no error without a policy, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/i18n"
+)
+
+// quietAtNoon sets quiet hours around the robots' fake time of 12:00
+func quietAtNoon(action string) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		cfg.QuietHours = config.QuietHours{Windows: []string{"11:30-13:00"}, Action: action}
+	}
+}
+
+func TestQuietHoursRejectWithoutDialog(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(quietAtNoon(config.QuietHoursActionDeny)).
+		ReceiveClaudeText(bashDialogLines("npm run deploy")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the reject choice first, got: %q", output)
+	}
+	robot.AssertTerminalContains(i18n.Get(i18n.English, nil).QuietHours)
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Errorf("Expected no notification, got: %q", notification)
+	}
+}
+
+func TestQuietHoursNotifyAndReject(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(quietAtNoon(config.QuietHoursActionNotify)).
+		Configure(func(cfg *config.Config) { cfg.QuietHours.Message = "Nobody is around; leave deploys for tomorrow." }).
+		ReceiveClaudeText(bashDialogLines("npm run deploy")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	robot.AssertTerminalContains("Nobody is around; leave deploys for tomorrow.")
+	if notification := robot.dialog.GetCapturedNotification(); !strings.Contains(notification, "npm run deploy") {
+		t.Errorf("Expected a notification naming the command, got: %q", notification)
+	}
+}
+
+func TestQuietHoursKeepRules(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(quietAtNoon(config.QuietHoursActionDeny)).
+		Configure(configureGoTestRule).
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the approve rule to still apply, got: %q", output)
+	}
+}
+
+func TestQuietHoursAllowDialogsOutsideWindows(t *testing.T) {
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.QuietHours.Windows = []string{"23:00-07:00"} }).
+		ReceiveClaudeText(bashDialogLines("npm run deploy")...).
+		AssertDialogCaptured()
+}
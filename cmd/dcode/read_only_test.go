@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// readDialogLines returns a Read permission dialog for path
+func readDialogLines(path string) []string {
+	return []string{
+		"⏺ Read(" + path + ")",
+		"╭──────────────────────────────────────────────╮",
+		"│ Read file                                    │",
+		fmt.Sprintf("│   %-43s│", path),
+		"│ Do you want to proceed?                      │",
+		"│ ❯ 1. Yes                                     │",
+		"│   2. Yes, during this session                │",
+		"│   3. No, and tell Claude what to do (esc)    │",
+		"╰──────────────────────────────────────────────╯",
+	}
+}
+
+func allowReadOnly(cfg *config.Config) {
+	cfg.AllowReadOnly = true
+}
+
+func TestAllowReadOnlyApprovesReads(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(allowReadOnly).
+		ReceiveClaudeText(readDialogLines("/repo/README.md")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if robot.GetTerminalOutput() != "1" {
+		t.Errorf("Expected the read to be approved once with choice 1, got: %q", robot.GetTerminalOutput())
+	}
+}
+
+func TestAllowReadOnlyKeepsDialogs(t *testing.T) {
+	t.Run("Commands", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(allowReadOnly).
+			ReceiveClaudeText(bashDialogLines("ls -la")...).
+			AssertDialogCaptured()
+	})
+
+	t.Run("Reads of credentials", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(allowReadOnly).
+			ReceiveClaudeText(readDialogLines("~/.ssh/id_rsa")...).
+			AssertDialogCaptured()
+	})
+
+	t.Run("Without the flag", func(t *testing.T) {
+		NewAppRobot(t).
+			ReceiveClaudeText(readDialogLines("/repo/README.md")...).
+			AssertDialogCaptured()
+	})
+}
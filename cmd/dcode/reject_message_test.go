@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestRejectMessageTemplate(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.RejectMessage = "[{time}] {tool} `{command}` stopped by {rule}: {reason} File it in TODO.md."
+			cfg.Deny = []config.DenyRule{
+				{Rule: config.Rule{Command: `^make`}},
+				{Rule: config.Rule{Command: `^git push`}, Message: "Pushing is done by CI."},
+			}
+		}).
+		ReceiveClaudeText(bashDialogLines("git push origin main")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	expected := "2[2023-01-01T12:00:00Z] Bash `git push origin main\nRun the command` stopped by deny rule 2: Pushing is done by CI. File it in TODO.md."
+	if output := robot.GetTerminalOutput(); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestRejectMessageTemplateNamesTheOption(t *testing.T) {
+	testCases := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		command   string
+		rule      string
+	}{
+		{"auto-reject", func(cfg *config.Config) { cfg.AutoReject = true }, "make build", "auto_reject"},
+		{"risk policy", riskPolicy(config.RiskActionDialog, config.RiskActionDialog, config.RiskActionReject), "git push --force", "risk policy for high risk"},
+		{"forbid rule", func(cfg *config.Config) {
+			cfg.Forbid = []config.DenyRule{{Rule: config.Rule{Command: `^terraform`}}}
+		}, "terraform destroy", "forbid rule 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			robot := NewAppRobot(t).
+				Configure(func(cfg *config.Config) {
+					tc.configure(cfg)
+					cfg.RejectMessage = "Rejected by {rule}"
+				}).
+				ReceiveClaudeText(bashDialogLines(tc.command)...)
+			time.Sleep(denyRuleWaitTime)
+
+			if output := robot.GetTerminalOutput(); !strings.HasSuffix(output, "Rejected by "+tc.rule) {
+				t.Errorf("Expected the rule %q in the message, got %q", tc.rule, output)
+			}
+		})
+	}
+}
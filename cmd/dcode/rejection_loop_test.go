@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// denyForcePush auto-rejects everything, denies force pushes with a rule,
+// and rejects without delays
+func denyForcePush(cfg *config.Config) {
+	cfg.AutoReject = true
+	cfg.Deny = []config.DenyRule{{Rule: config.Rule{Command: `git push .*--force`}, Message: "Never force push."}}
+	cfg.Delays.DialogResetMs = 0
+	cfg.Delays.AutoRejectProcessMs = 0
+	cfg.Delays.AutoRejectChoiceMs = 0
+	cfg.Delays.AutoRejectCRMs = 0
+}
+
+// retryInARow sends one dialog per command, each with a new question so
+// none is taken for a redraw, waiting for each to be answered
+func retryInARow(robot *AppRobot, minute int, commands ...string) {
+	for i, command := range commands {
+		robot.SetFakeTime(after(time.Duration(minute+i) * time.Minute)).
+			ReceiveClaudeText(askedAs(command, fmt.Sprintf("Do you want to run it (attempt %d)?", minute+i))...)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestRejectionLoopStopsRejecting(t *testing.T) {
+	robot := NewAppRobot(t).Configure(denyForcePush)
+	retryInARow(robot, 0, "git push --force", "git push  --force", "git push --force")
+	if output := robot.GetTerminalOutput(); strings.Count(output, "Never force push.") != 3 {
+		t.Fatalf("Expected three rejections with the message, got: %q", output)
+	}
+
+	retryInARow(robot, 3, "git push --force")
+	if output := robot.GetTerminalOutput(); !strings.HasSuffix(output, "\r2") || strings.Count(output, "Never force push.") != 3 {
+		t.Errorf("Expected only the reject choice for the fourth rejection, got: %q", output)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); !strings.Contains(notification, "Bash: git push --force was rejected 4 times in a row") {
+		t.Errorf("Expected a notification about the loop, got: %q", notification)
+	}
+	if mode := robot.app.Mode(); mode.Name != config.ModeDialog {
+		t.Errorf("Expected the auto mode to be paused, got %s", mode)
+	}
+
+	t.Run("Dialogs are shown until the mode is set", func(t *testing.T) {
+		robot.SetDialogChoice("1")
+		retryInARow(robot, 4, "make")
+		robot.AssertDialogCaptured()
+
+		robot.app.SetMode(config.Mode{Name: config.ModeAutoReject})
+		robot.ClearCapturedDialog()
+		retryInARow(robot, 5, "make lint")
+		robot.AssertNoDialogCaptured()
+	})
+}
+
+func TestRejectionLoopCountsOnlyRejectionsInARow(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(denyForcePush).
+		Configure(func(cfg *config.Config) { cfg.RejectionLoopLimit = 2 })
+	retryInARow(robot, 0, "git push --force", "git push --force", "npm publish", "git push --force", "git push --force")
+
+	if output := robot.GetTerminalOutput(); strings.Count(output, "Never force push.") != 4 {
+		t.Errorf("Expected every rejection to get the message, got: %q", output)
+	}
+	if notification := robot.dialog.GetCapturedNotification(); notification != "" {
+		t.Errorf("Expected no notification, got: %q", notification)
+	}
+}
+
+func TestRejectionLoopLimitDisabled(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(denyForcePush).
+		Configure(func(cfg *config.Config) { cfg.RejectionLoopLimit = 0 })
+	retryInARow(robot, 0, "git push --force", "git push --force", "git push --force", "git push --force")
+
+	if output := robot.GetTerminalOutput(); strings.Count(output, "Never force push.") != 4 {
+		t.Errorf("Expected every rejection to get the message, got: %q", output)
+	}
+}
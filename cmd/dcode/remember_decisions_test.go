@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// rememberDecisions turns on --remember-decisions
+func rememberDecisions(cfg *config.Config) {
+	cfg.RememberDecisions = true
+}
+
+// loadRulesFile reads the config file at path after the follow-up dialog had
+// time to write it
+func loadRulesFile(t *testing.T, path string) config.Config {
+	t.Helper()
+	time.Sleep(100 * time.Millisecond)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", path, err)
+	}
+	return cfg
+}
+
+func TestRememberApproval(t *testing.T) {
+	path := emptyConfigFile(t)
+	robot := NewAppRobot(t).
+		Configure(rememberDecisions).
+		UseRulesFile(path).
+		SetDialogChoices("1", "2").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+
+	cfg := loadRulesFile(t, path)
+	expected := []config.Rule{{Tool: "Bash", Command: `^go test \./\.\.\.$`}}
+	if !reflect.DeepEqual(cfg.Approve, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, cfg.Approve)
+	}
+	robot.AssertDialogTextContains("Always approve this request without asking?").
+		AssertDialogTextContains("Bash: go test ./...").
+		AssertDialogTextContains(path).
+		AssertButton(0, NotNowButton).
+		AssertButton(1, RememberButton).
+		AssertDefaultButton(NotNowButton)
+}
+
+func TestRememberRejection(t *testing.T) {
+	path := emptyConfigFile(t)
+	NewAppRobot(t).
+		Configure(rememberDecisions).
+		UseRulesFile(path).
+		SetDialogChoices("2", "2").
+		ReceiveClaudeText(writeDialogLines("/repo/.env")...).
+		AssertDialogTextContains("Always reject this request without asking?")
+
+	cfg := loadRulesFile(t, path)
+	if len(cfg.Deny) != 1 || cfg.Deny[0].Tool != "Write" || !strings.Contains(cfg.Deny[0].File, `/repo/\.env`) {
+		t.Errorf("Expected a deny rule for the file, got %+v", cfg.Deny)
+	}
+}
+
+func TestRememberNotNow(t *testing.T) {
+	path := emptyConfigFile(t)
+	NewAppRobot(t).
+		Configure(rememberDecisions).
+		UseRulesFile(path).
+		SetDialogChoices("1", "1").
+		ReceiveClaudeText(bashDialogLines("go vet ./...")...).
+		AssertDialogTextContains("Always approve")
+
+	if cfg := loadRulesFile(t, path); len(cfg.Approve) != 0 || len(cfg.Deny) != 0 {
+		t.Errorf("Expected no rules, got %+v and %+v", cfg.Approve, cfg.Deny)
+	}
+}
+
+func TestRememberIsNotOffered(t *testing.T) {
+	testCases := []struct {
+		name   string
+		lines  []string
+		choice string
+	}{
+		{"don't ask again", askAgainDialogLines("go test ./..."), "2"},
+		{"high-risk approval", bashDialogLines("rm -rf build"), "1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := emptyConfigFile(t)
+			robot := NewAppRobot(t).
+				Configure(rememberDecisions).
+				UseRulesFile(path).
+				SetDialogChoices(tc.choice, "2").
+				ReceiveClaudeText(tc.lines...)
+
+			loadRulesFile(t, path)
+			if message := robot.GetCapturedMessage(); strings.Contains(message, "Always") {
+				t.Errorf("Expected no follow-up, got %q", message)
+			}
+		})
+	}
+}
+
+func TestRememberNeedsTheOption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	robot := NewAppRobot(t).
+		UseRulesFile(path).
+		SetDialogChoices("1", "2").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...)
+
+	time.Sleep(100 * time.Millisecond)
+	if message := robot.GetCapturedMessage(); strings.Contains(message, "Always") {
+		t.Errorf("Expected no follow-up, got %q", message)
+	}
+}
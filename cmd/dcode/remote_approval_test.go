@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// fakeRemote answers remote requests with answer, or err, and captures the
+// last request it was sent
+type fakeRemote struct {
+	mu      sync.Mutex
+	answer  string
+	err     error
+	remote  config.Remote
+	message string
+	buttons []string
+}
+
+func (f *fakeRemote) ask(remote config.Remote, message string, buttons []string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remote, f.message, f.buttons = remote, message, buttons
+	return f.answer, f.err
+}
+
+func (f *fakeRemote) asked() (string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.message, f.buttons
+}
+
+// escalateHighRisk approves low risk, asks about medium risk in a dialog, and
+// sends high risk to a remote ntfy topic
+func escalateHighRisk(cfg *config.Config) {
+	riskPolicy(config.RiskActionApprove, config.RiskActionDialog, config.RiskActionRemote)(cfg)
+	cfg.Remote.NtfyURL = "https://ntfy.example.com/dcode-topic"
+	cfg.Remote.TimeoutSeconds = 1800
+}
+
+func TestRemoteApprovesHighRisk(t *testing.T) {
+	remote := &fakeRemote{answer: "1"}
+	robot := NewAppRobot(t).
+		Configure(escalateHighRisk).
+		UseRemote(remote.ask).
+		ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertNoDialogCaptured()
+
+	message, buttons := remote.asked()
+	if !strings.Contains(message, "rm -rf build") {
+		t.Errorf("Expected the command in the remote message, got: %q", message)
+	}
+	if len(buttons) != 2 || buttons[0] != "Yes" {
+		t.Errorf("Expected the dialog's buttons, got: %v", buttons)
+	}
+	if remote.remote.TimeoutSeconds != 1800 {
+		t.Errorf("Expected the remote timeout, got: %d", remote.remote.TimeoutSeconds)
+	}
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the remote answer, got: %q", output)
+	}
+}
+
+func TestRemoteRejectsWithoutAnswer(t *testing.T) {
+	remote := &fakeRemote{err: context.DeadlineExceeded}
+	robot := NewAppRobot(t).
+		Configure(escalateHighRisk).
+		UseRemote(remote.ask).
+		ReceiveClaudeText(bashDialogLines("git push --force")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoRejectChoiceDelayMs + 100) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the reject choice first, got: %q", output)
+	}
+	robot.AssertTerminalContains("nobody approved it remotely")
+}
+
+func TestRemoteLeavesOtherRisksAlone(t *testing.T) {
+	remote := &fakeRemote{answer: "1"}
+	NewAppRobot(t).
+		Configure(escalateHighRisk).
+		UseRemote(remote.ask).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("rm notes.txt")...).
+		AssertDialogCaptured().
+		AssertTerminalContains("2")
+
+	if message, _ := remote.asked(); message != "" {
+		t.Errorf("Expected medium risk not to be sent remotely, got: %q", message)
+	}
+}
+
+func TestRemoteFallsBackToDialogWithoutCallback(t *testing.T) {
+	NewAppRobot(t).
+		Configure(escalateHighRisk).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertDialogCaptured().
+		AssertTerminalContains("2")
+}
+
+// delegate adds the "Ask someone else" button, forwarding to a remote ntfy topic
+func delegate(cfg *config.Config) {
+	cfg.Remote.NtfyURL = "https://ntfy.example.com/dcode-topic"
+	cfg.Remote.Delegate = true
+}
+
+func TestAskSomeoneElseForwardsTheRequest(t *testing.T) {
+	remote := &fakeRemote{answer: "1"}
+	robot := NewAppRobot(t).
+		Configure(delegate).
+		UseRemote(remote.ask).
+		SetDialogChoice("3").
+		ReceiveClaudeText(bashDialogLines("kubectl apply -f prod.yaml")...).
+		AssertDialogCaptured().
+		AssertButtonCount(3).
+		AssertButton(2, AskSomeoneElseButton)
+	time.Sleep(100 * time.Millisecond)
+
+	message, buttons := remote.asked()
+	if !strings.HasPrefix(message, DelegatedMessagePrefix) || !strings.Contains(message, "kubectl apply -f prod.yaml") {
+		t.Errorf("Expected the delegated request in the remote message, got: %q", message)
+	}
+	if len(buttons) != 2 {
+		t.Errorf("Expected the dialog's buttons without the added one, got: %v", buttons)
+	}
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the remote answer, got: %q", output)
+	}
+}
+
+func TestAskSomeoneElseRejectsWithoutAnswer(t *testing.T) {
+	remote := &fakeRemote{err: context.DeadlineExceeded}
+	robot := NewAppRobot(t).
+		Configure(delegate).
+		UseRemote(remote.ask).
+		SetDialogChoice("3").
+		ReceiveClaudeText(bashDialogLines("kubectl apply -f prod.yaml")...).
+		AssertDialogCaptured()
+	time.Sleep((config.DefaultAutoRejectChoiceDelayMs + 100) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the reject choice first, got: %q", output)
+	}
+	robot.AssertTerminalContains("nobody approved it remotely")
+}
+
+func TestAskSomeoneElseKeepsLocalAnswers(t *testing.T) {
+	remote := &fakeRemote{answer: "1"}
+	NewAppRobot(t).
+		Configure(delegate).
+		UseRemote(remote.ask).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("kubectl apply -f prod.yaml")...).
+		AssertDialogCaptured().
+		AssertTerminalContains("2")
+
+	if message, _ := remote.asked(); message != "" {
+		t.Errorf("Expected nothing sent remotely, got: %q", message)
+	}
+}
+
+func TestAskSomeoneElseNeedsDelegate(t *testing.T) {
+	remote := &fakeRemote{answer: "1"}
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.Remote.NtfyURL = "https://ntfy.example.com/dcode-topic" }).
+		UseRemote(remote.ask).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("kubectl apply -f prod.yaml")...).
+		AssertDialogCaptured().
+		AssertButtonCount(2)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/events"
+	"github.com/takahirom/dialog-code/internal/version"
+)
+
+func TestRequestIDTiesDialogToAuditLogAndEvents(t *testing.T) {
+	log := audit.NewLog(auditLogPath(t))
+	recorder := &eventRecorder{}
+	robot := NewAppRobot(t).
+		UseAuditLog(log).
+		UseEventStream(events.NewStream(recorder)).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(100 * time.Millisecond)
+
+	if message := robot.GetCapturedMessage(); !strings.HasSuffix(message, "\n\nRequest "+TestRequestID+" · dcode "+version.Get().Short()) {
+		t.Errorf("Expected the dialog to end with the request ID and version, got %q", message)
+	}
+	entries, err := log.Entries(audit.Filter{})
+	if err != nil || len(entries) != 1 || entries[0].ID != TestRequestID {
+		t.Errorf("Expected the audit entry to have the request ID, got %+v, %v", entries, err)
+	}
+	for _, event := range recorder.Events(t) {
+		if event.RequestID != TestRequestID {
+			t.Errorf("Expected every event to have the request ID, got %+v", event)
+		}
+	}
+}
+
+func TestRequestIDDiffersBetweenPrompts(t *testing.T) {
+	robot := NewAppRobot(t)
+	robot.app.handler.newRequestID = nil
+
+	robot.app.handler.promptDetected()
+	first := robot.app.handler.appState.Prompt.RequestID
+	robot.app.handler.promptDetected()
+	second := robot.app.handler.appState.Prompt.RequestID
+	if first == "" || first == second {
+		t.Errorf("Expected a new request ID for each prompt, got %q and %q", first, second)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestAuditLogRecordsResponseTime(t *testing.T) {
+	log := audit.NewLog(auditLogPath(t))
+	NewAppRobot(t).
+		UseAuditLog(log).
+		SetAnswerDelay(4 * time.Second).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := log.Entries(audit.Filter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %+v, %v", entries, err)
+	}
+	if entries[0].WaitMs != 4000 || entries[0].LatencyMs < entries[0].WaitMs {
+		t.Errorf("Expected the dialog to have waited 4000ms within the latency, got %d of %d", entries[0].WaitMs, entries[0].LatencyMs)
+	}
+}
+
+func TestAuditLogRecordsResponseTimeDuringCountdown(t *testing.T) {
+	log := audit.NewLog(auditLogPath(t))
+	NewAppRobot(t).
+		UseAuditLog(log).
+		Configure(autoApproveWait(30)).
+		SetAnswerDelay(2 * time.Second).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("npm install")...).
+		AssertTerminalContains("2")
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := log.Entries(audit.Filter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %+v, %v", entries, err)
+	}
+	if entries[0].WaitMs != 2000 {
+		t.Errorf("Expected the countdown dialog to have waited 2000ms, got %d", entries[0].WaitMs)
+	}
+}
+
+func TestAuditLogRecordsNoResponseTimeWithoutDialog(t *testing.T) {
+	log := audit.NewLog(auditLogPath(t))
+	NewAppRobot(t).
+		UseAuditLog(log).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoReject = true
+		}).
+		ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	entries, err := log.Entries(audit.Filter{})
+	if err != nil || len(entries) != 1 || entries[0].WaitMs != 0 {
+		t.Errorf("Expected one entry that waited for no one, got %+v, %v", entries, err)
+	}
+}
+
+func TestDialogShowsAverageResponseTime(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.ShowResponseTime = true
+			cfg.Delays.DialogResetMs = 0
+		}).
+		SetAnswerDelay(3 * time.Second).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertDialogCaptured()
+	if message := robot.GetCapturedMessage(); strings.Contains(message, "response time") {
+		t.Errorf("Expected no response time before any dialog was answered, got:\n%s", message)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	robot.ClearCapturedDialog().
+		SetAnswerDelay(5 * time.Second).
+		ReceiveClaudeText(askedAs("go vet ./...", "Do you want to run vet?")...).
+		AssertDialogTextContains("Your average response time: 3.0s over 1 dialog").
+		ClearCapturedDialog()
+	time.Sleep(50 * time.Millisecond)
+	robot.ReceiveClaudeText(askedAs("go build ./...", "Do you want to build?")...).
+		AssertDialogTextContains("Your average response time: 4.0s over 2 dialogs")
+}
+
+func TestDialogHidesResponseTimeByDefault(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.Delays.DialogResetMs = 0 }).
+		SetAnswerDelay(3 * time.Second).
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertDialogCaptured().
+		ClearCapturedDialog()
+	time.Sleep(50 * time.Millisecond)
+	robot.ReceiveClaudeText(askedAs("go vet ./...", "Do you want to run vet?")...).
+		AssertDialogCaptured()
+	if message := robot.GetCapturedMessage(); strings.Contains(message, "response time") {
+		t.Errorf("Expected no response time without show_response_time, got:\n%s", message)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// riskPolicy configures the actions for low, medium, and high risk dialogs
+func riskPolicy(low, medium, high string) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Risk = config.RiskPolicy{Low: low, Medium: medium, High: high}
+	}
+}
+
+func TestRiskPolicyApprovesLowRisk(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(riskPolicy(config.RiskActionApprove, config.RiskActionDialog, config.RiskActionDialog)).
+		ReceiveClaudeText(bashDialogLines("ls -la")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if robot.GetTerminalOutput() != "1" {
+		t.Errorf("Expected low risk to be approved with choice 1, got: %q", robot.GetTerminalOutput())
+	}
+}
+
+func TestRiskPolicyShowsDialogForMediumRisk(t *testing.T) {
+	NewAppRobot(t).
+		Configure(riskPolicy(config.RiskActionApprove, config.RiskActionDialog, config.RiskActionReject)).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("rm notes.txt")...).
+		AssertDialogCaptured().
+		AssertTerminalContains("2")
+}
+
+func TestRiskPolicyRejectsHighRisk(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(riskPolicy(config.RiskActionApprove, config.RiskActionDialog, config.RiskActionReject)).
+		ReceiveClaudeText(bashDialogLines("git push --force")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoRejectProcessDelayMs + config.DefaultAutoRejectChoiceDelayMs + 100) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the reject choice first, got: %q", output)
+	}
+	robot.AssertTerminalContains("high risk (force push)")
+}
+
+func TestRiskPolicyTypedConfirmation(t *testing.T) {
+	confirmHigh := riskPolicy(config.RiskActionDialog, config.RiskActionDialog, config.RiskActionConfirm)
+
+	t.Run("Approval goes through when the phrase is typed", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(confirmHigh).
+			SetDialogChoice("1").
+			SetTypedText("Approve ").
+			ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+			AssertDialogCaptured().
+			AssertTextPromptContains("high risk (recursive delete)").
+			AssertTextPromptContains(`Type "approve"`).
+			AssertTerminalContains("1")
+	})
+
+	t.Run("Approval is turned into a rejection without the phrase", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(confirmHigh).
+			SetDialogChoice("1").
+			SetTypedText("yes").
+			ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+			AssertDialogCaptured()
+
+		if output := robot.GetTerminalOutput(); output != "2" {
+			t.Errorf("Expected the reject choice, got: %q", output)
+		}
+	})
+
+	t.Run("Cancelled prompt rejects", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(confirmHigh).
+			SetDialogChoice("1").
+			ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+			AssertDialogCaptured()
+
+		if output := robot.GetTerminalOutput(); output != "2" {
+			t.Errorf("Expected the reject choice, got: %q", output)
+		}
+	})
+
+	t.Run("Rejection needs no confirmation", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(confirmHigh).
+			SetDialogChoice("2").
+			ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+			AssertDialogCaptured().
+			AssertNoTextPrompt().
+			AssertTerminalContains("2")
+	})
+
+	t.Run("Other risk levels need no confirmation", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(confirmHigh).
+			SetDialogChoice("1").
+			ReceiveClaudeText(bashDialogLines("ls -la")...).
+			AssertDialogCaptured().
+			AssertNoTextPrompt().
+			AssertTerminalContains("1")
+	})
+}
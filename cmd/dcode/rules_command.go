@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// rulesUsage describes the "dcode rules" subcommands
+const rulesUsage = `usage: dcode rules list
+       dcode rules add approve|deny|forbid [--tool=NAME] [--command=REGEX] [--file=REGEX] [--name=TEXT] [--message=TEXT]
+       dcode rules remove approve|deny|forbid NUMBER
+       dcode rules test "TOOL: COMMAND OR FILE"`
+
+// runRulesCommand runs "dcode rules" with the arguments after "rules",
+// writing its report to out. --config and the other dcode flags apply.
+func runRulesCommand(argv []string, out io.Writer) error {
+	cfg, args, err := loadConfig(argv)
+	if err != nil {
+		return err
+	}
+	path, _ := configPath(argv)
+	if len(args) == 0 {
+		return errors.New(rulesUsage)
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		listRules(&cfg, out)
+		return nil
+	case args[0] == "add" && len(args) >= 2:
+		return addRule(path, args[1], args[2:], out)
+	case args[0] == "remove" && len(args) == 3:
+		number, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid rule number: %s", args[2])
+		}
+		if path == "" {
+			return errors.New("config file location is unknown; use --config=PATH")
+		}
+		if err := config.RemoveRule(path, args[1], number); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Removed %s rule %d from %s\n", args[1], number, path)
+		return nil
+	case args[0] == "test" && len(args) == 2:
+		info, err := parser.ParseRequest(args[1])
+		if err != nil {
+			return err
+		}
+		testRules(&cfg, info, args[1], out)
+		return nil
+	}
+	return errors.New(rulesUsage)
+}
+
+// listRules prints every rule in the order dialogs are checked against them
+func listRules(cfg *config.Config, out io.Writer) {
+	lists := map[string][]config.DenyRule{
+		config.RuleListForbid: cfg.Forbid,
+		config.RuleListDeny:   cfg.Deny,
+	}
+	for _, rule := range cfg.Approve {
+		lists[config.RuleListApprove] = append(lists[config.RuleListApprove], config.DenyRule{Rule: rule})
+	}
+
+	found := false
+	for _, list := range config.RuleLists {
+		for i, rule := range lists[list] {
+			fmt.Fprintf(out, "%s %d: %s\n", list, i+1, describeRule(rule))
+			found = true
+		}
+	}
+	if !found {
+		fmt.Fprintln(out, "No rules")
+	}
+}
+
+// addRule adds the rule described by the flags in argv to the list named
+// list in the config file at path
+func addRule(path, list string, argv []string, out io.Writer) error {
+	var rule config.DenyRule
+	for _, arg := range argv {
+		name, value, found := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !found || !strings.HasPrefix(arg, "-") {
+			return errors.New(rulesUsage)
+		}
+		switch name {
+		case "tool":
+			rule.Tool = value
+		case "command":
+			rule.Command = value
+		case "file":
+			rule.File = value
+		case "name":
+			rule.Name = value
+		case "message":
+			rule.Message = value
+		default:
+			return fmt.Errorf("unknown rule field: %s (must be tool, command, file, name, or message)", name)
+		}
+	}
+	if path == "" {
+		return errors.New("config file location is unknown; use --config=PATH")
+	}
+
+	number, err := config.AddRule(path, list, rule)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %s rule %d to %s: %s\n", list, number, path, describeRule(rule))
+	return nil
+}
+
+// testRules prints which rule matches the request described by info and what
+// dcode would do with it
+func testRules(cfg *config.Config, info parser.DialogInfo, request string, out io.Writer) {
+	rule, decision := explainRequest(cfg, info)
+	if rule == "" {
+		rule = "none"
+	}
+
+	fmt.Fprintf(out, "Request:  %s\n", request)
+	fmt.Fprintf(out, "Risk:     %s\n", describeRisk(info))
+	fmt.Fprintf(out, "Rule:     %s\n", rule)
+	fmt.Fprintf(out, "Decision: %s\n", decision)
+}
+
+// explainRequest returns the rule that decides the request described by info
+// under cfg, if any, and what dcode would do with it. It follows the same
+// order as finalizeDialog, leaving out approvals that depend on earlier
+// answers, such as cached and temporary ones.
+func explainRequest(cfg *config.Config, info parser.DialogInfo) (string, string) {
+	p := NewPermissionHandler(nil, cfg, nil)
+
+	for i, rule := range cfg.Forbid {
+		if rule.Matches(info) {
+			return fmt.Sprintf("forbid rule %d: %s", i+1, describeRule(rule)), "reject without asking; nothing can approve it"
+		}
+	}
+	for i, rule := range cfg.Deny {
+		if rule.Matches(info) {
+			return fmt.Sprintf("deny rule %d: %s", i+1, describeRule(rule)), "reject without asking"
+		}
+	}
+	toolAction := cfg.ToolPolicy.Action(info.ToolType)
+	if toolAction == config.ToolPolicyDeny {
+		return "tool_policy: " + info.ToolType + "=deny", "reject without asking"
+	}
+	if p.editsOutsideScope(info) {
+		if cfg.EditScope.Outside == config.EditScopeActionDialog {
+			return "edit_scope", explainAskUser(p) + ", since the file is outside edit_scope"
+		}
+		return "edit_scope", "reject without asking, since the file is outside edit_scope"
+	}
+	if !info.Truncated {
+		if i := config.MatchingRule(cfg.Approve, info); i >= 0 {
+			return fmt.Sprintf("approve rule %d: %s", i+1, describeRule(config.DenyRule{Rule: cfg.Approve[i]})), "approve without asking"
+		}
+	}
+	if cfg.AllowReadOnly && parser.IsReadOnlyTool(info.ToolType) && info.Risk != parser.RiskHigh && toolAction != config.ToolPolicyAsk {
+		return "--allow-read-only", "approve without asking"
+	}
+
+	riskPolicy := fmt.Sprintf("risk policy for %s risk", info.Risk)
+	switch cfg.Risk.Action(info.Risk) {
+	case config.RiskActionApprove:
+		if !info.Truncated && toolAction != config.ToolPolicyAsk {
+			return riskPolicy, "approve without asking"
+		}
+	case config.RiskActionReject:
+		return riskPolicy, "reject without asking"
+	case config.RiskActionConfirm:
+		if !cfg.AutoReject && !p.inQuietHours() {
+			return riskPolicy, "show a dialog that only approves after typing \"" + TypedConfirmationPhrase + "\""
+		}
+	case config.RiskActionRemote:
+		if !cfg.AutoReject {
+			return riskPolicy, fmt.Sprintf("ask through %s, rejecting after %d seconds without an answer", cfg.Remote.NtfyURL, cfg.Remote.TimeoutSeconds)
+		}
+	}
+	switch {
+	case toolAction == config.ToolPolicyAllow:
+		return "tool_policy: " + info.ToolType + "=allow", "approve without asking"
+	case toolAction == config.ToolPolicyAsk:
+		return "tool_policy: " + info.ToolType + "=ask", explainAskUser(p)
+	case cfg.AutoApprove:
+		return "--auto-approve", "approve without asking"
+	}
+	return "", explainAskUser(p)
+}
+
+// explainAskUser describes what askUser would do
+func explainAskUser(p *PermissionHandler) string {
+	switch {
+	case p.config().AutoReject:
+		return "reject without asking (--auto-reject)"
+	case p.inQuietHours():
+		return "reject without asking (quiet hours)"
+	case p.config().AutoRejectWait > 0:
+		return fmt.Sprintf("show a dialog, rejecting after %d seconds without an answer", p.config().AutoRejectWait)
+	case p.config().AutoApproveWait > 0:
+		return fmt.Sprintf("show a dialog, approving after %d seconds without an answer", p.config().AutoApproveWait)
+	}
+	return "show a dialog"
+}
+
+// describeRule renders the fields rule sets, quoting regular expressions as
+// they would be written in the config file
+func describeRule(rule config.DenyRule) string {
+	var fields []string
+	if rule.Tool != "" {
+		fields = append(fields, "tool="+rule.Tool)
+	}
+	if rule.Command != "" {
+		fields = append(fields, "command='"+rule.Command+"'")
+	}
+	if rule.File != "" {
+		fields = append(fields, "file='"+rule.File+"'")
+	}
+	if rule.Name != "" {
+		fields = append(fields, "name="+strconv.Quote(rule.Name))
+	}
+	if rule.Message != "" {
+		fields = append(fields, strconv.Quote(rule.Message))
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// emptyConfigFile returns the path of an empty config file
+func emptyConfigFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// runRules runs "dcode rules" with the config file at path, returning its output
+func runRules(t *testing.T, path string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := runRulesCommand(append([]string{"--config=" + path}, args...), &out)
+	return out.String(), err
+}
+
+func TestRulesAddListRemove(t *testing.T) {
+	path := emptyConfigFile(t)
+
+	output, err := runRules(t, path, "list")
+	if err != nil || output != "No rules\n" {
+		t.Fatalf("Expected no rules, got %q, %v", output, err)
+	}
+
+	steps := [][]string{
+		{"add", "approve", "--tool=Bash", `--command=^go test ./...$`},
+		{"add", "deny", `--command=git push .*--force`, "--name=no force pushes", "--message=Never force push."},
+		{"add", "forbid", "--file=^/etc/"},
+	}
+	for _, step := range steps {
+		if _, err := runRules(t, path, step...); err != nil {
+			t.Fatalf("Expected %v to succeed, got %v", step, err)
+		}
+	}
+
+	output, _ = runRules(t, path, "list")
+	expected := `forbid 1: file='^/etc/'
+deny 1: command='git push .*--force' name="no force pushes" "Never force push."
+approve 1: tool=Bash command='^go test ./...$'
+`
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+
+	if _, err := runRules(t, path, "remove", "deny", "1"); err != nil {
+		t.Fatalf("Expected remove to succeed, got %v", err)
+	}
+	if output, _ = runRules(t, path, "list"); strings.Contains(output, "deny") {
+		t.Errorf("Expected the deny rule to be removed, got:\n%s", output)
+	}
+}
+
+func TestRulesCommandErrors(t *testing.T) {
+	path := emptyConfigFile(t)
+	testCases := [][]string{
+		{},
+		{"show"},
+		{"add", "deny", "--flag=x"},
+		{"add", "deny", "rm"},
+		{"add", "deny"},
+		{"remove", "deny", "one"},
+		{"remove", "deny", "1"},
+		{"test", "rm -rf build"},
+	}
+	for _, args := range testCases {
+		if _, err := runRules(t, path, args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("Expected the config file to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestRulesTest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "approve:\n  - tool: Bash\n    command: '^go test'\ndeny:\n  - command: 'rm -rf'\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	output, err := runRules(t, path, "test", "Bash: rm -rf build")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := `Request:  Bash: rm -rf build
+Risk:     high (recursive delete)
+Rule:     deny rule 1: command='rm -rf'
+Decision: reject without asking
+`
+	if output != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+}
+
+func TestExplainRequest(t *testing.T) {
+	testCases := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		request   string
+		rule      string
+		decision  string
+	}{
+		{"no rule", func(cfg *config.Config) {}, "Bash: make", "", "show a dialog"},
+		{"forbid before deny", func(cfg *config.Config) {
+			cfg.Deny = []config.DenyRule{{Rule: config.Rule{Tool: "Bash"}}}
+			cfg.Forbid = []config.DenyRule{{Rule: config.Rule{Command: `^terraform destroy`}}}
+		}, "Bash: terraform destroy", "forbid rule 1: command='^terraform destroy'", "reject without asking; nothing can approve it"},
+		{"approve rule", func(cfg *config.Config) {
+			cfg.Approve = []config.Rule{{Tool: "Bash", Command: `^go test`}}
+		}, "Bash: go test ./...", "approve rule 1: tool=Bash command='^go test'", "approve without asking"},
+		{"read-only", allowReadOnly, "Read: /repo/README.md", "--allow-read-only", "approve without asking"},
+		{"outside edit scope", func(cfg *config.Config) {
+			cfg.EditScope.Dirs = []string{"/repo"}
+		}, "Edit: /etc/hosts", "edit_scope", "reject without asking, since the file is outside edit_scope"},
+		{"risk policy", riskPolicy(config.RiskActionDialog, config.RiskActionDialog, config.RiskActionConfirm),
+			"Bash: sudo reboot", "risk policy for high risk", `show a dialog that only approves after typing "approve"`},
+		{"remote", escalateHighRisk, "Bash: git push --force", "risk policy for high risk",
+			"ask through https://ntfy.example.com/dcode-topic, rejecting after 1800 seconds without an answer"},
+		{"tool policy deny", func(cfg *config.Config) {
+			cfg.ToolPolicy = config.ToolPolicy{"WebFetch": config.ToolPolicyDeny}
+		}, "WebFetch: https://example.com", "tool_policy: WebFetch=deny", "reject without asking"},
+		{"tool policy ask", func(cfg *config.Config) {
+			cfg.AutoApprove = true
+			cfg.ToolPolicy = config.ToolPolicy{"Bash": config.ToolPolicyAsk}
+		}, "Bash: make", "tool_policy: Bash=ask", "show a dialog"},
+		{"auto-approve", func(cfg *config.Config) { cfg.AutoApprove = true }, "Bash: make", "--auto-approve", "approve without asking"},
+		{"auto-reject-wait", func(cfg *config.Config) { cfg.AutoRejectWait = 30 }, "Bash: make", "", "show a dialog, rejecting after 30 seconds without an answer"},
+		{"auto-approve-wait", func(cfg *config.Config) { cfg.AutoApproveWait = 30 }, "Bash: make", "", "show a dialog, approving after 30 seconds without an answer"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			tc.configure(&cfg)
+			info, err := parser.ParseRequest(tc.request)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			rule, decision := explainRequest(&cfg, info)
+			if rule != tc.rule {
+				t.Errorf("Expected rule %q, got %q", tc.rule, rule)
+			}
+			if decision != tc.decision {
+				t.Errorf("Expected decision %q, got %q", tc.decision, decision)
+			}
+		})
+	}
+}
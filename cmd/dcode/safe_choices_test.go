@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func safeChoices(cfg *config.Config) {
+	cfg.SafeChoices = true
+}
+
+func TestSafeChoicesApproveOnceInsteadOfDontAskAgain(t *testing.T) {
+	t.Run("Answered in the dialog", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(safeChoices).
+			SetDialogChoice("2").
+			ReceiveClaudeText(askAgainDialogLines("go test ./pkg/...")...).
+			AssertDialogCaptured()
+		time.Sleep(100 * time.Millisecond)
+
+		if output := robot.GetTerminalOutput(); output != "1" {
+			t.Errorf("Expected the approve-once choice, got: %q", output)
+		}
+	})
+
+	t.Run("Picked by default_choice", func(t *testing.T) {
+		robot := NewAppRobot(t).
+			Configure(func(cfg *config.Config) {
+				safeChoices(cfg)
+				cfg.AutoApprove = true
+				cfg.DefaultChoice = "2"
+			}).
+			ReceiveClaudeText(askAgainDialogLines("go test ./pkg/...")...).
+			AssertNoDialogCaptured()
+		time.Sleep(100 * time.Millisecond)
+
+		if output := robot.GetTerminalOutput(); output != "1" {
+			t.Errorf("Expected the approve-once choice, got: %q", output)
+		}
+	})
+}
+
+func TestSafeChoicesLeaveClaudeSettingsAlone(t *testing.T) {
+	repo := chdirToRepo(t)
+
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			safeChoices(cfg)
+			syncSettings(cfg)
+		}).
+		SetDialogChoice("2").
+		ReceiveClaudeText(askAgainDialogLines("go test ./pkg/...")...).
+		AssertTerminalContains("1")
+
+	if _, err := os.Stat(filepath.Join(repo, ".claude")); !os.IsNotExist(err) {
+		t.Errorf("Expected no Claude settings to be written, got: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/debug"
+)
+
+// ScreenshotDelayMs is how long after a dialog is shown its screenshot is
+// taken, so the dialog is drawn by then
+const ScreenshotDelayMs = 500
+
+// ScreenshotCallback saves a screenshot of the dialog being shown to path
+type ScreenshotCallback func(path string) error
+
+// screenshot is a screenshot of a dialog, taken once the dialog has been
+// shown for ScreenshotDelayMs
+type screenshot struct {
+	path  string
+	timer *time.Timer
+	done  chan struct{} // Closed once the screenshot was taken or failed
+	err   error
+}
+
+// startScreenshot schedules a screenshot of the dialog about to be shown for
+// prompt, or returns nil without --screenshot-dir
+func (p *PermissionHandler) startScreenshot(prompt *promptSnapshot) *screenshot {
+	if p.screenshotDir == "" || p.screenshotCallback == nil {
+		return nil
+	}
+	name := fmt.Sprintf("dialog-%s-%s.png", p.now().Format("20060102-150405"), prompt.requestID)
+	capture := &screenshot{path: filepath.Join(p.screenshotDir, name), done: make(chan struct{})}
+	capture.timer = time.AfterFunc(ScreenshotDelayMs*time.Millisecond, func() {
+		defer close(capture.done)
+		if capture.err = os.MkdirAll(p.screenshotDir, 0o700); capture.err == nil {
+			capture.err = p.screenshotCallback(capture.path)
+		}
+	})
+	return capture
+}
+
+// finishScreenshot waits for capture, of the dialog just answered for
+// prompt, and names it in prompt for the audit log. A dialog answered before
+// its screenshot was taken has none.
+func (p *PermissionHandler) finishScreenshot(capture *screenshot, prompt *promptSnapshot) {
+	if capture == nil {
+		return
+	}
+	if capture.timer.Stop() {
+		debug.Info("dialog answered before its screenshot was taken")
+		return
+	}
+	<-capture.done
+	if capture.err != nil {
+		debug.Warn("failed to take a screenshot of the dialog", "error", capture.err)
+		return
+	}
+	prompt.screenshot = capture.path
+}
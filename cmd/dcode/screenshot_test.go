@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// fakeScreenshot writes a placeholder image to path
+func fakeScreenshot(path string) error {
+	return os.WriteFile(path, []byte("png"), 0o600)
+}
+
+func TestScreenshotIsNamedInAuditEntry(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "screenshots")
+	log := audit.NewLog(auditLogPath(t))
+	robot := NewAppRobot(t).
+		UseAuditLog(log).
+		UseScreenshots(dir, fakeScreenshot)
+	robot.app.SetPermissionCallback(func(message string, buttons []string, defaultButton string) string {
+		time.Sleep(2 * ScreenshotDelayMs * time.Millisecond)
+		return "1"
+	})
+	robot.ReceiveClaudeText(bashDialogLines("go test ./...")...)
+	time.Sleep(2 * ScreenshotDelayMs * time.Millisecond)
+
+	entries, err := log.Entries(audit.Filter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %+v, %v", entries, err)
+	}
+	screenshot := entries[0].Screenshot
+	if filepath.Dir(screenshot) != dir || !strings.Contains(filepath.Base(screenshot), TestRequestID) {
+		t.Errorf("Expected a screenshot in %s named after the request, got %q", dir, screenshot)
+	}
+	if _, err := os.Stat(screenshot); err != nil {
+		t.Errorf("Expected the screenshot to be saved, got %v", err)
+	}
+}
+
+func TestScreenshotSkippedForDialogAnsweredAtOnce(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "screenshots")
+	log := audit.NewLog(auditLogPath(t))
+	NewAppRobot(t).
+		UseAuditLog(log).
+		UseScreenshots(dir, fakeScreenshot).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(2 * ScreenshotDelayMs * time.Millisecond)
+
+	entries, err := log.Entries(audit.Filter{})
+	if err != nil || len(entries) != 1 || entries[0].Screenshot != "" {
+		t.Errorf("Expected an entry without a screenshot, got %+v, %v", entries, err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected no screenshot to be taken, got %v", err)
+	}
+}
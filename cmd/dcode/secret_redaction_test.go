@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestDialogMasksSecrets(t *testing.T) {
+	robot := NewAppRobot(t).
+		ReceiveClaudeText(bashDialogLines("psql postgres://app:hunter2@db/prod")...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("postgres://app:[REDACTED]@db/prod")
+
+	if strings.Contains(robot.GetCapturedMessage(), "hunter2") {
+		t.Errorf("Expected the password to be masked, got: %q", robot.GetCapturedMessage())
+	}
+}
+
+func TestDialogMasksConfiguredSecretPatterns(t *testing.T) {
+	lines := bashDialogLines("ssh deploy@host --ticket CUST-123456")
+	lines = append(lines[:6], "│ ❯ 1. Yes, for CUST-123456                    │", lines[7], lines[8])
+
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.SecretPatterns = []string{`\bCUST-[0-9]{6}\b`} }).
+		ReceiveClaudeText(lines...).
+		AssertDialogCaptured().
+		AssertDialogTextContains("--ticket [REDACTED]").
+		AssertButton(0, "Yes, for [REDACTED]").
+		AssertDefaultButton("Yes, for [REDACTED]")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// restartWith returns a new robot that restores the state file at path, as
+// dcode does when restarted in the same directory
+func restartWith(t *testing.T, path string, configure func(cfg *config.Config)) *AppRobot {
+	robot := NewAppRobot(t).Configure(configure)
+	if _, err := robot.app.SetStateFile(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return robot
+}
+
+func TestSessionStateKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	first := restartWith(t, path, func(cfg *config.Config) {})
+	first.app.SetMode(config.Mode{Name: config.ModeAutoRejectWait, WaitSeconds: 20})
+
+	second := restartWith(t, path, func(cfg *config.Config) {})
+	if mode := second.app.Mode(); mode.String() != "auto-reject-wait=20" {
+		t.Errorf("Expected the mode to be restored, got %s", mode)
+	}
+}
+
+func TestSessionStateKeepsRejectionCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	limitTwo := func(cfg *config.Config) {
+		denyForcePush(cfg)
+		cfg.RejectionLoopLimit = 2
+	}
+	retryInARow(restartWith(t, path, limitTwo), 0, "git push --force", "git push --force")
+
+	second := restartWith(t, path, limitTwo)
+	retryInARow(second, 2, "git push --force")
+	if output := second.GetTerminalOutput(); output != "2" {
+		t.Errorf("Expected the loop to be stopped after the restart, got: %q", output)
+	}
+	if notification := second.dialog.GetCapturedNotification(); !strings.Contains(notification, "3 times in a row") {
+		t.Errorf("Expected a notification about the loop, got: %q", notification)
+	}
+	if mode := restartWith(t, path, limitTwo).app.Mode(); mode.Name != config.ModeDialog {
+		t.Errorf("Expected the auto mode to stay paused, got %s", mode)
+	}
+}
+
+func TestSessionStateWithoutFile(t *testing.T) {
+	robot := restartWith(t, filepath.Join(t.TempDir(), "state.json"), func(cfg *config.Config) { cfg.AutoReject = true })
+	if mode := robot.app.Mode(); mode.Name != config.ModeAutoReject {
+		t.Errorf("Expected the configured mode, got %s", mode)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// setupUsage describes the "dcode setup" subcommand
+const setupUsage = "usage: dcode setup [--config=PATH]"
+
+// setupSkippedFile, in the cache directory, records that the setup offered on
+// the first run was declined, so it isn't offered again
+const setupSkippedFile = "setup-skipped"
+
+// setupAnswers are the choices made during setup
+type setupAnswers struct {
+	NtfyURL              string // Topic high-risk requests are sent to, or "" to ask only in dialogs
+	Wait                 int    // Seconds a dialog waits before it's answered for the user (0 = forever)
+	ApproveOnTimeout     bool   // Approve rather than reject when Wait runs out
+	AllowReadOnly        bool
+	ImportClaudeSettings bool
+}
+
+// runSetupCommand runs "dcode setup" with the arguments after "setup": it
+// asks a few questions on in and writes the config file from the answers. It
+// won't replace a config file that exists.
+func runSetupCommand(argv []string, in io.Reader, out io.Writer) error {
+	path, _ := configPath(argv)
+	for _, arg := range argv {
+		if !strings.HasPrefix(arg, "-config=") && !strings.HasPrefix(arg, "--config=") {
+			return errors.New(setupUsage)
+		}
+	}
+	if path == "" {
+		return errors.New("config file location is unknown; use --config=PATH")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists; edit it, or use dcode rules and dcode config show", path)
+	}
+	return runSetup(path, false, in, out)
+}
+
+// firstRun reports whether setup should be offered before starting Claude:
+// dcode was given no --config, has no config file, and wasn't told to skip it
+func firstRun(argv []string) bool {
+	path, explicit := configPath(argv)
+	if explicit || path == "" {
+		return false
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	skipped := setupSkippedPath()
+	if skipped == "" {
+		return false
+	}
+	_, err := os.Stat(skipped)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// setupSkippedPath returns where declining the first-run setup is recorded,
+// or "" if the cache directory is unknown
+func setupSkippedPath() string {
+	dir := parentDir(approvals.DefaultPath())
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, setupSkippedFile)
+}
+
+// runSetup asks the setup questions on in and writes the config file at
+// path. On the first run the user may decline, which is recorded so the
+// questions aren't asked again.
+func runSetup(path string, first bool, in io.Reader, out io.Writer) error {
+	p := &prompter{in: bufio.NewScanner(in), out: out}
+	if first {
+		fmt.Fprintf(out, "dcode has no config file yet. A few questions will write one to %s.\n", path)
+		setUp, err := p.yes("Set up dcode now?", true)
+		if err != nil {
+			return err
+		}
+		if !setUp {
+			if err := recordSetupSkipped(); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "Using the defaults. Run dcode setup to answer the questions later.")
+			return nil
+		}
+	}
+
+	answers, err := askSetup(p)
+	if err != nil {
+		return err
+	}
+	data, err := composeSetup(answers)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s. Edit it any time; dcode config show prints what's in effect.\n", path)
+	return nil
+}
+
+// askSetup asks the setup questions through p
+func askSetup(p *prompter) (setupAnswers, error) {
+	var answers setupAnswers
+	backend, err := p.choose("How should dcode ask you about Claude's requests?", []string{
+		"In a dialog on this computer",
+		"In a dialog, and send high-risk requests to your phone with ntfy",
+	}, 1)
+	if err != nil {
+		return answers, err
+	}
+	for backend == 2 && answers.NtfyURL == "" {
+		topic, err := p.ask("ntfy topic URL, e.g. https://ntfy.sh/a-long-random-name", "")
+		if err != nil {
+			return answers, err
+		}
+		if _, err := composeSetup(setupAnswers{NtfyURL: topic}); err != nil || topic == "" {
+			fmt.Fprintln(p.out, "Enter an http or https URL ending in the topic's name.")
+			continue
+		}
+		answers.NtfyURL = topic
+	}
+
+	if answers.Wait, err = p.number("Seconds a dialog waits for you before dcode answers it (0 waits until you do)", 0); err != nil {
+		return answers, err
+	}
+	if answers.Wait > 0 {
+		action, err := p.choose("What should dcode answer when nobody does in time?", []string{
+			"Reject, and tell Claude to try something else",
+			"Approve",
+		}, 1)
+		if err != nil {
+			return answers, err
+		}
+		answers.ApproveOnTimeout = action == 2
+	}
+
+	if answers.AllowReadOnly, err = p.yes("Approve read-only tools such as Read and Grep without asking?", false); err != nil {
+		return answers, err
+	}
+	if answers.ImportClaudeSettings, err = p.yes("Also follow the allow and deny lists in the project's .claude/settings.json?", true); err != nil {
+		return answers, err
+	}
+	return answers, nil
+}
+
+// composeSetup returns the config file answers describe, checked like any
+// other config file
+func composeSetup(answers setupAnswers) ([]byte, error) {
+	var text strings.Builder
+	fmt.Fprintln(&text, "# Written by dcode setup. Every option is described in the README's Config File section.")
+	if answers.NtfyURL != "" {
+		fmt.Fprintln(&text, "# Ask about high-risk requests through ntfy instead of a dialog")
+		fmt.Fprintln(&text, "risk:\n  high: remote\nremote:")
+		fmt.Fprintf(&text, "  ntfy_url: %s\n", yamlScalar(answers.NtfyURL))
+	}
+	if answers.Wait > 0 {
+		fmt.Fprintln(&text, "# Seconds a dialog waits before it's answered for you")
+		if answers.ApproveOnTimeout {
+			fmt.Fprintf(&text, "auto_approve_wait: %d\n", answers.Wait)
+		} else {
+			fmt.Fprintf(&text, "auto_reject_wait: %d\n", answers.Wait)
+		}
+	}
+	fmt.Fprintln(&text, "# Approve Read, Grep, and other read-only tools without asking")
+	fmt.Fprintf(&text, "allow_read_only: %t\n", answers.AllowReadOnly)
+	fmt.Fprintln(&text, "# Use the project's Claude permission lists as rules too")
+	fmt.Fprintf(&text, "import_claude_settings: %t\n", answers.ImportClaudeSettings)
+
+	cfg := config.Default()
+	decoder := yaml.NewDecoder(strings.NewReader(text.String()))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(text.String()), nil
+}
+
+// yamlScalar returns value as a YAML scalar, quoted if it needs to be
+func yamlScalar(value string) string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return strconv.Quote(value)
+	}
+	return string(bytes.TrimSuffix(data, []byte("\n")))
+}
+
+// recordSetupSkipped records that the first-run setup was declined
+func recordSetupSkipped() error {
+	path := setupSkippedPath()
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, nil, 0o600)
+}
+
+// prompter asks questions on a terminal, one answer per line. An empty answer
+// takes the default.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prints question with its default, if any, and returns the answer
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		fmt.Fprintln(p.out)
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("setup cancelled; nothing was written")
+	}
+	if answer := strings.TrimSpace(p.in.Text()); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// yes asks a yes or no question
+func (p *prompter) yes(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.ask(question, hint)
+		if err != nil {
+			return false, err
+		}
+		if answer == hint {
+			return def, nil
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "Answer y or n.")
+	}
+}
+
+// number asks for a whole number of zero or more
+func (p *prompter) number(question string, def int) (int, error) {
+	for {
+		answer, err := p.ask(question, strconv.Itoa(def))
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 0 {
+			return n, nil
+		}
+		fmt.Fprintln(p.out, "Enter a whole number, 0 or more.")
+	}
+}
+
+// choose lists options, numbered from 1, and returns the number picked
+func (p *prompter) choose(question string, options []string, def int) (int, error) {
+	fmt.Fprintln(p.out, question)
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		answer, err := p.ask("Choice", strconv.Itoa(def))
+		if err != nil {
+			return 0, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n, nil
+		}
+		fmt.Fprintf(p.out, "Enter a number from 1 to %d.\n", len(options))
+	}
+}
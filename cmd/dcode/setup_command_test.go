@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func TestSetup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcode", "config.yaml")
+	// ntfy, an invalid topic then a valid one, 30 seconds, approve, the
+	// default for read-only tools, and no Claude settings
+	input := "2\nftp://example.com\nhttps://ntfy.sh/dcode-test\n30\n2\n\nn\n"
+	var out bytes.Buffer
+	if err := runSetupCommand([]string{"--config=" + path}, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Expected no error, got %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Enter an http or https URL") {
+		t.Errorf("Expected the invalid topic to be asked again, got:\n%s", out.String())
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Expected the written config to load, got %v", err)
+	}
+	if cfg.Remote.NtfyURL != "https://ntfy.sh/dcode-test" || cfg.Risk.High != config.RiskActionRemote || cfg.Risk.Low != config.RiskActionDialog {
+		t.Errorf("Expected high-risk requests to go to ntfy, got %+v %+v", cfg.Remote, cfg.Risk)
+	}
+	if cfg.AutoApproveWait != 30 || cfg.AutoRejectWait != 0 || cfg.AllowReadOnly || cfg.ImportClaudeSettings {
+		t.Errorf("Unexpected options: %+v", cfg)
+	}
+
+	err = runSetupCommand([]string{"--config=" + path}, strings.NewReader(input), &out)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an existing config file to be kept, got %v", err)
+	}
+}
+
+func TestSetupDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := runSetup(path, false, strings.NewReader("\n\n\n\n"), &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Remote.NtfyURL != "" || cfg.AutoRejectWait != 0 || cfg.AllowReadOnly || !cfg.ImportClaudeSettings {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestSetupCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := runSetup(path, false, strings.NewReader("1\n"), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected setup to be cancelled at the end of input, got %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("Expected no config file to be written")
+	}
+}
+
+func TestFirstRun(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if !firstRun(nil) {
+		t.Fatal("Expected setup to be offered without a config file")
+	}
+	if firstRun([]string{"--config=" + filepath.Join(t.TempDir(), "config.yaml")}) {
+		t.Error("Expected no setup with --config")
+	}
+
+	var out bytes.Buffer
+	if err := runSetup(config.DefaultPath(), true, strings.NewReader("n\n"), &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Run dcode setup") {
+		t.Errorf("Expected how to set up later, got:\n%s", out.String())
+	}
+	if firstRun(nil) {
+		t.Error("Expected declined setup not to be offered again")
+	}
+	if _, err := os.Stat(config.DefaultPath()); err == nil {
+		t.Error("Expected no config file after declining")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// A dialog whose bottom border scrolled away before it was read
+var dialogWithoutBottomBorder = []string{
+	"⏺ Bash(ls)",
+	"╭──────────────────────────────────────────────╮",
+	"│ Bash command                                 │",
+	"│   ls                                         │",
+	"│ Do you want to proceed?                      │",
+	"│ ❯ 1. Yes                                     │",
+	"│   2. No, and tell Claude what to do (esc)    │",
+}
+
+func TestStalledDialogIsFinalizedAfterQuiescence(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.DialogQuiescenceMs = 100 }).
+		SetDialogChoice("2").
+		ReceiveClaudeText(dialogWithoutBottomBorder...)
+	time.Sleep((100 + config.DefaultChoiceProcessingDelayMs + 100) * time.Millisecond)
+
+	robot.AssertDialogCaptured().
+		AssertDialogTextContains("ls").
+		AssertButtonCount(2).
+		AssertButton(1, "No, and tell Claude what to do (esc)").
+		AssertTerminalContains("2")
+}
+
+func TestStalledDialogWaitsWhileOutputContinues(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.DialogQuiescenceMs = 300 }).
+		ReceiveClaudeText(dialogWithoutBottomBorder...)
+
+	// Output keeps arriving, so the dialog isn't finalized early
+	robot.ReceiveClaudeText("│                                              │")
+	robot.AssertNoDialogCaptured()
+
+	// The bottom border arrives and finalizes the dialog exactly once
+	robot.ReceiveClaudeText("╰──────────────────────────────────────────────╯")
+	time.Sleep((300 + config.DefaultChoiceProcessingDelayMs + 100) * time.Millisecond)
+
+	robot.AssertDialogCaptured().
+		AssertButtonCount(2)
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected a single answer, got: %q", output)
+	}
+}
+
+func TestStalledDialogQuiescenceDisabled(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.DialogQuiescenceMs = 0 }).
+		ReceiveClaudeText(dialogWithoutBottomBorder...)
+	time.Sleep(200 * time.Millisecond)
+
+	robot.AssertNoDialogCaptured()
+}
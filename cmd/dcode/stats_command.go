@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// statsUsage describes the "dcode stats" subcommand
+const statsUsage = "usage: dcode stats [--json] [--tool=NAME] [--decision=approved|rejected|answered] [--since=YYYY-MM-DD|AGO] [--until=YYYY-MM-DD|AGO] [--project=DIR] [--branch=NAME] [--limit=N]"
+
+// TopStatsCount is how many hours and requests "dcode stats" ranks without --limit
+const TopStatsCount = 5
+
+// historyStats summarizes audit log entries
+type historyStats struct {
+	Total                int            `json:"total"`
+	Approved             int            `json:"approved"`
+	Rejected             int            `json:"rejected"`
+	Answered             int            `json:"answered"`
+	AverageLatencyMs     int64          `json:"average_latency_ms"`      // Over every answer
+	AverageUserLatencyMs int64          `json:"average_user_latency_ms"` // Over answers picked in a dialog
+	Responses            int            `json:"responses"`               // Answers whose dialogs waited for the user
+	AverageResponseMs    int64          `json:"average_response_ms"`     // How long those dialogs waited, on average
+	Tools                []decisionStat `json:"tools"`                   // Most answered first
+	BusiestHours         []hourStat     `json:"busiest_hours"`           // Local hours with the most answers
+	TopRequests          []decisionStat `json:"top_requests"`            // Requests answered more than once, most first
+}
+
+// decisionStat counts the answers for one tool or request
+type decisionStat struct {
+	Name     string `json:"name"`
+	Total    int    `json:"total"`
+	Approved int    `json:"approved"`
+	Rejected int    `json:"rejected"`
+	Answered int    `json:"answered"`
+}
+
+// hourStat counts the answers given in one local hour of the day
+type hourStat struct {
+	Hour  int `json:"hour"`
+	Total int `json:"total"`
+}
+
+// add counts an answer with action
+func (s *decisionStat) add(action string) {
+	s.Total++
+	switch action {
+	case audit.Approved:
+		s.Approved++
+	case audit.Rejected:
+		s.Rejected++
+	default:
+		s.Answered++
+	}
+}
+
+// runStatsCommand runs "dcode stats" with the arguments after "stats",
+// summarizing the entries of the --audit-log file the options select as a
+// table, or as JSON with --json
+func runStatsCommand(argv []string, out io.Writer) error {
+	log, args, err := openAuditLog(argv)
+	if err != nil {
+		return err
+	}
+
+	asJSON := false
+	var options []string
+	for _, arg := range args {
+		if arg == "-json" || arg == "--json" {
+			asJSON = true
+		} else {
+			options = append(options, arg)
+		}
+	}
+	filter, limit, err := parseHistoryOptions(options, statsUsage)
+	if err != nil {
+		return err
+	}
+	if limit == 0 {
+		limit = TopStatsCount
+	}
+	entries, err := log.Entries(filter)
+	if err != nil {
+		return err
+	}
+
+	stats := summarizeHistory(entries, limit)
+	if asJSON {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+	printStats(stats, out)
+	return nil
+}
+
+// summarizeHistory counts entries, ranking the busiest hours, earliest first
+// among equals, and the requests answered more than once, up to limit of each
+func summarizeHistory(entries []audit.Entry, limit int) historyStats {
+	var total decisionStat
+	var latency, userLatency, response int64
+	userAnswers, responses := 0, 0
+	tools := map[string]*decisionStat{}
+	requests := map[string]*decisionStat{}
+	var hours [24]int
+
+	for _, entry := range entries {
+		total.add(entry.Action)
+		latency += entry.LatencyMs
+		if entry.DecidedBy == audit.User {
+			userLatency += entry.LatencyMs
+			userAnswers++
+		}
+		if entry.WaitMs > 0 {
+			response += entry.WaitMs
+			responses++
+		}
+		tool := entry.Tool
+		if tool == "" {
+			tool = entry.Prompt
+		}
+		countIn(tools, tool, entry.Action)
+		countIn(requests, entry.Request, entry.Action)
+		hours[entry.Time.Local().Hour()]++
+	}
+
+	stats := historyStats{
+		Total:        total.Total,
+		Approved:     total.Approved,
+		Rejected:     total.Rejected,
+		Answered:     total.Answered,
+		Tools:        ranked(tools, 0, len(tools)),
+		TopRequests:  ranked(requests, 2, limit),
+		BusiestHours: []hourStat{},
+	}
+	if total.Total > 0 {
+		stats.AverageLatencyMs = latency / int64(total.Total)
+	}
+	if userAnswers > 0 {
+		stats.AverageUserLatencyMs = userLatency / int64(userAnswers)
+	}
+	if responses > 0 {
+		stats.Responses = responses
+		stats.AverageResponseMs = response / int64(responses)
+	}
+	for hour, count := range hours {
+		if count > 0 {
+			stats.BusiestHours = append(stats.BusiestHours, hourStat{Hour: hour, Total: count})
+		}
+	}
+	sort.SliceStable(stats.BusiestHours, func(i, j int) bool {
+		return stats.BusiestHours[i].Total > stats.BusiestHours[j].Total
+	})
+	if len(stats.BusiestHours) > limit {
+		stats.BusiestHours = stats.BusiestHours[:limit]
+	}
+	return stats
+}
+
+// countIn counts an answer with action for name in counts
+func countIn(counts map[string]*decisionStat, name, action string) {
+	if counts[name] == nil {
+		counts[name] = &decisionStat{Name: name}
+	}
+	counts[name].add(action)
+}
+
+// ranked returns up to limit of counts with at least minimum answers, most
+// answered first and then by name
+func ranked(counts map[string]*decisionStat, minimum, limit int) []decisionStat {
+	result := []decisionStat{}
+	for _, stat := range counts {
+		if stat.Total >= minimum {
+			result = append(result, *stat)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// printStats prints stats as tables
+func printStats(stats historyStats, out io.Writer) {
+	if stats.Total == 0 {
+		fmt.Fprintln(out, "No history entries")
+		return
+	}
+
+	fmt.Fprintf(out, "Answers:  %d (%d approved, %d rejected, %d other)\n", stats.Total, stats.Approved, stats.Rejected, stats.Answered)
+	fmt.Fprintf(out, "Latency:  %dms on average, %dms for answers picked in a dialog\n", stats.AverageLatencyMs, stats.AverageUserLatencyMs)
+	if stats.Responses > 0 {
+		fmt.Fprintf(out, "Response: %dms on average for the %d dialogs you answered\n", stats.AverageResponseMs, stats.Responses)
+	}
+
+	fmt.Fprintln(out)
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Tool\tApproved\tRejected\tOther\tTotal")
+	for _, tool := range stats.Tools {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\n", tool.Name, tool.Approved, tool.Rejected, tool.Answered, tool.Total)
+	}
+	table.Flush()
+
+	var hours []string
+	for _, hour := range stats.BusiestHours {
+		hours = append(hours, fmt.Sprintf("%02d:00 (%d)", hour.Hour, hour.Total))
+	}
+	fmt.Fprintf(out, "\nBusiest hours:  %s\n", strings.Join(hours, ", "))
+
+	if len(stats.TopRequests) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nRepeated requests:")
+	for _, request := range stats.TopRequests {
+		fmt.Fprintf(out, "  %4d  %s  (%d approved, %d rejected)\n", request.Total, request.Name, request.Approved, request.Rejected)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// statsEntries are answers to three requests over two hours
+func statsEntries() []audit.Entry {
+	morning := time.Date(2025, 3, 10, 9, 15, 0, 0, time.Local)
+	return []audit.Entry{
+		{ID: "01", Time: morning, Tool: "Bash", Request: "Bash: go test ./...", Action: audit.Approved, DecidedBy: "approve rule 1", LatencyMs: 100},
+		{ID: "02", Time: morning.Add(time.Minute), Tool: "Bash", Request: "Bash: go test ./...", Action: audit.Approved, DecidedBy: "approve rule 1", LatencyMs: 100},
+		{ID: "03", Time: morning.Add(2 * time.Minute), Tool: "Bash", Request: "Bash: go test ./...", Action: audit.Approved, DecidedBy: audit.User, LatencyMs: 3000, WaitMs: 2500},
+		{ID: "04", Time: morning.Add(time.Hour), Tool: "Bash", Request: "Bash: git push --force", Action: audit.Rejected, DecidedBy: "deny rule 1", LatencyMs: 800},
+		{ID: "05", Time: morning.Add(time.Hour), Tool: "Bash", Request: "Bash: git push --force", Action: audit.Rejected, DecidedBy: audit.User, LatencyMs: 5000, WaitMs: 4500},
+		{ID: "06", Time: morning.Add(time.Hour), Tool: "Read", Request: "Read: go.mod", Action: audit.Approved, DecidedBy: "allow_read_only", LatencyMs: 0},
+		{ID: "07", Time: morning.Add(3 * time.Hour), Prompt: "plan_approval", Request: "Unknown tool", Action: audit.Answered, DecidedBy: audit.User, LatencyMs: 1000, WaitMs: 800},
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	stats := summarizeHistory(statsEntries(), TopStatsCount)
+
+	if stats.Total != 7 || stats.Approved != 4 || stats.Rejected != 2 || stats.Answered != 1 {
+		t.Errorf("Expected the answers to be counted, got %+v", stats)
+	}
+	if stats.AverageLatencyMs != 10000/7 || stats.AverageUserLatencyMs != 3000 {
+		t.Errorf("Expected the average latencies, got %d and %d", stats.AverageLatencyMs, stats.AverageUserLatencyMs)
+	}
+	if stats.Responses != 3 || stats.AverageResponseMs != 2600 {
+		t.Errorf("Expected the average response time over 3 dialogs, got %d over %d", stats.AverageResponseMs, stats.Responses)
+	}
+	tools := []decisionStat{
+		{Name: "Bash", Total: 5, Approved: 3, Rejected: 2},
+		{Name: "Read", Total: 1, Approved: 1},
+		{Name: "plan_approval", Total: 1, Answered: 1},
+	}
+	if !reflect.DeepEqual(stats.Tools, tools) {
+		t.Errorf("Expected counts by tool %+v, got %+v", tools, stats.Tools)
+	}
+	hours := []hourStat{{Hour: 9, Total: 3}, {Hour: 10, Total: 3}, {Hour: 12, Total: 1}}
+	if !reflect.DeepEqual(stats.BusiestHours, hours) {
+		t.Errorf("Expected the busiest hours %+v, got %+v", hours, stats.BusiestHours)
+	}
+	requests := []decisionStat{
+		{Name: "Bash: go test ./...", Total: 3, Approved: 3},
+		{Name: "Bash: git push --force", Total: 2, Rejected: 2},
+	}
+	if !reflect.DeepEqual(stats.TopRequests, requests) {
+		t.Errorf("Expected only repeated requests %+v, got %+v", requests, stats.TopRequests)
+	}
+
+	if limited := summarizeHistory(statsEntries(), 1); len(limited.TopRequests) != 1 || len(limited.BusiestHours) != 1 || len(limited.Tools) != 3 {
+		t.Errorf("Expected --limit to rank one hour and request, got %+v", limited)
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	path := historyLog(t, statsEntries()...)
+
+	var out strings.Builder
+	if err := runStatsCommand([]string{"--audit-log=" + path}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{
+		"Answers:  7 (4 approved, 2 rejected, 1 other)\n",
+		"Response: 2600ms on average for the 3 dialogs you answered\n",
+		"Tool           Approved  Rejected  Other  Total\nBash           3         2         0      5\n",
+		"Busiest hours:  09:00 (3), 10:00 (3), 12:00 (1)\n",
+		"     3  Bash: go test ./...  (3 approved, 0 rejected)\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runStatsCommand([]string{"--audit-log=" + path, "--json", "--tool=Read"}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var stats historyStats
+	if err := json.Unmarshal([]byte(out.String()), &stats); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", out.String(), err)
+	}
+	if stats.Total != 1 || len(stats.Tools) != 1 || stats.Tools[0].Name != "Read" || len(stats.TopRequests) != 0 {
+		t.Errorf("Expected only the Read answer, got %+v", stats)
+	}
+
+	out.Reset()
+	if err := runStatsCommand([]string{"--audit-log=" + path, "--tool=Edit"}, &out); err != nil || out.String() != "No history entries\n" {
+		t.Errorf("Expected no entries, got %q, %v", out.String(), err)
+	}
+	if err := runStatsCommand([]string{"--audit-log=" + path, "extra"}, &out); err == nil || err.Error() != statsUsage {
+		t.Errorf("Expected the usage, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/control"
+	"github.com/takahirom/dialog-code/internal/version"
+)
+
+// RunCommand starts Claude like running dcode without a subcommand, for
+// Claude arguments that would otherwise name one, e.g. "dcode run history"
+const RunCommand = "run"
+
+// HookCommand is another name for RunCommand. dcode has no hook mode of its
+// own, since it answers the prompts Claude draws rather than running as one
+// of Claude's hooks, so "dcode hook" starts Claude like "dcode run"; to pass
+// Claude a prompt that is just "hook", use "dcode run hook"
+const HookCommand = "hook"
+
+// subcommand is a command dcode runs itself instead of starting Claude
+type subcommand struct {
+	name    string // Words that run it, e.g. "cache clear"
+	alias   string // Another word that runs it, e.g. "--version", or ""
+	usage   string // Its arguments, for dcode help
+	summary string // What it does, for dcode help
+	exact   bool   // Takes no arguments; with any, they are passed to Claude instead
+	run     func(args []string, out io.Writer) error
+}
+
+// subcommands returns dcode's own subcommands, in the order dcode help lists
+// them
+func subcommands() []subcommand {
+	dir := control.DefaultDir()
+	return []subcommand{
+		{name: "help", summary: "List dcode's subcommands", exact: true, run: func(args []string, out io.Writer) error {
+			return runHelpCommand(out)
+		}},
+		{name: "version", alias: "--version", summary: "Show dcode's build and Claude's version", exact: true, run: func(args []string, out io.Writer) error {
+			return runVersionCommand(claudeVersion, out)
+		}},
+		{name: "setup", usage: "[--config=PATH]", summary: "Answer a few questions to write the config file", run: func(args []string, out io.Writer) error {
+			return runSetupCommand(args, os.Stdin, out)
+		}},
+		{name: "config show", usage: "[dcode options]", summary: "Show the options in effect and where each was set", run: runConfigCommand},
+		{name: "doctor", usage: "[dcode options]", summary: "Check that dialogs can be shown and dcode's files written", run: runDoctorCommand},
+		{name: "rules", usage: "list|add|remove|test ...", summary: "Edit the rules in the config file and test what they do", run: runRulesCommand},
+		{name: "explain", usage: "[ID]", summary: "Show why requests were answered without asking", run: runExplainCommand},
+		{name: "history", usage: "[filters] [ID]", summary: "Search the audit log", run: runHistoryCommand},
+		{name: "stats", usage: "[--json] [filters]", summary: "Summarize the answers in the audit log", run: runStatsCommand},
+		{name: "export", usage: "[--format=csv|json] [filters]", summary: "Export audit log entries as a report", run: runExportCommand},
+		{name: "audit", usage: "verify", summary: "Check the audit log's hash chain", run: runAuditCommand},
+		{name: "digest", usage: "[--date=YYYY-MM-DD] [--send]", summary: "Summarize a day's answers", run: func(args []string, out io.Writer) error {
+			return runDigestCommand(args, time.Now(), out)
+		}},
+		{name: "mode", usage: "[MODE] [--pid=PID]", summary: "Show or switch the mode of running sessions", run: func(args []string, out io.Writer) error {
+			return runModeCommand(args, dir, out)
+		}},
+		{name: "tail", usage: "[--all] [--pid=PID]", summary: "Follow a running session's decisions", run: func(args []string, out io.Writer) error {
+			return runTailCommand(args, dir, out)
+		}},
+		{name: "debug", usage: "dedup [--pid=PID]", summary: "Show why a running session skipped prompts", run: func(args []string, out io.Writer) error {
+			return runDebugCommand(args, dir, out)
+		}},
+		{name: "panic", summary: "Reject every request in every running session", exact: true, run: func(args []string, out io.Writer) error {
+			return runPanicCommand(dir, out)
+		}},
+		{name: "resume", summary: "Undo dcode panic", exact: true, run: func(args []string, out io.Writer) error {
+			return runResumeCommand(dir, out)
+		}},
+		{name: "uninstall", usage: "[--all] [--dry-run]", summary: "Remove dcode's cache and, with --all, its config", run: func(args []string, out io.Writer) error {
+			return runUninstallCommand(args, dir, out)
+		}},
+		{name: "cache clear", summary: "Forget the approvals cached with --approval-cache-seconds", exact: true, run: func(args []string, out io.Writer) error {
+			return runCacheClearCommand(out)
+		}},
+	}
+}
+
+// runSubcommand runs the dcode subcommand argv names, such as "dcode cache
+// clear", "dcode rules", or "dcode explain", reporting whether it named one.
+// Other arguments start Claude.
+func runSubcommand(argv []string) (bool, error) {
+	for _, command := range subcommands() {
+		words := strings.Fields(command.name)
+		if command.alias != "" && len(argv) > 0 && argv[0] == command.alias {
+			words = argv[:1]
+		}
+		if len(argv) < len(words) || !slices.Equal(argv[:len(words)], words) {
+			continue
+		}
+		if command.exact && len(argv) > len(words) {
+			continue
+		}
+		return true, command.run(argv[len(words):], os.Stdout)
+	}
+	return false, nil
+}
+
+// claudeArgv returns the dcode options and Claude arguments in argv, the
+// arguments dcode was started with, without the "run" or "hook" that may
+// precede them
+func claudeArgv(argv []string) []string {
+	if len(argv) > 0 && (argv[0] == RunCommand || argv[0] == HookCommand) {
+		return argv[1:]
+	}
+	return argv
+}
+
+// runHelpCommand lists dcode's subcommands
+func runHelpCommand(out io.Writer) error {
+	fmt.Fprintln(out, "usage: dcode [run|hook] [dcode options] [claude arguments]")
+	fmt.Fprintln(out, "       dcode COMMAND [arguments]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Without a command, or with run or hook, which does the same, dcode starts Claude and answers its prompts. Commands:")
+	for _, command := range subcommands() {
+		fmt.Fprintf(out, "  %-37s %s\n", strings.TrimSpace(command.name+" "+command.usage), command.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "dcode options such as --auto-reject are described at https://github.com/takahirom/dialog-code.")
+	return nil
+}
+
+// runCacheClearCommand runs "dcode cache clear", forgetting every approval
+// cached with --approval-cache-seconds
+func runCacheClearCommand(out io.Writer) error {
+	path := approvals.DefaultPath()
+	if path == "" {
+		return errors.New("cache directory is unknown")
+	}
+	if err := approvals.NewCache(path).Clear(); err != nil {
+		return fmt.Errorf("failed to clear the approval cache: %w", err)
+	}
+	fmt.Fprintln(out, "Cleared the approval cache")
+	return nil
+}
+
+// runVersionCommand runs "dcode version", printing dcode's build and the
+// version of Claude, read with claude
+func runVersionCommand(claude func() (string, error), out io.Writer) error {
+	fmt.Fprintln(out, version.Get())
+	claudeVersion, err := claude()
+	if err != nil {
+		fmt.Fprintf(out, "claude: unknown (%v)\n", err)
+		return nil
+	}
+	fmt.Fprintf(out, "claude: %s\n", claudeVersion)
+	return nil
+}
+
+// claudeVersion returns what "claude --version" prints
+func claudeVersion() (string, error) {
+	output, err := exec.Command("claude", "--version").Output()
+	return strings.TrimSpace(string(output)), err
+}
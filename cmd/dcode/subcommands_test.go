@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/version"
+)
+
+func TestHelpListsSubcommands(t *testing.T) {
+	var out bytes.Buffer
+	if err := runHelpCommand(&out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, command := range subcommands() {
+		if !strings.Contains(out.String(), "\n  "+command.name) {
+			t.Errorf("Expected %q in:\n%s", command.name, out.String())
+		}
+	}
+}
+
+func TestArgumentsPassedToClaude(t *testing.T) {
+	for _, argv := range [][]string{
+		nil,
+		{"--resume"},
+		{"run", "history"},
+		{"panic", "about the failing test"},
+		{"help", "me write a parser"},
+		{"cache"},
+		{""},
+		{"--version", "--json"},
+	} {
+		if handled, _ := runSubcommand(argv); handled {
+			t.Errorf("Expected %q to be passed to claude", argv)
+		}
+	}
+
+	tests := []struct {
+		argv     []string
+		expected []string
+	}{
+		{[]string{"--auto-reject", "-p", "hi"}, []string{"--auto-reject", "-p", "hi"}},
+		{[]string{"run", "history"}, []string{"history"}},
+		{[]string{"run", "--auto-reject", "run"}, []string{"--auto-reject", "run"}},
+		{[]string{"hook", "--auto-reject"}, []string{"--auto-reject"}},
+	}
+	for _, test := range tests {
+		if argv := claudeArgv(test.argv); !reflect.DeepEqual(argv, test.expected) {
+			t.Errorf("claudeArgv(%q) = %q, expected %q", test.argv, argv, test.expected)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	var out bytes.Buffer
+	claude := func() (string, error) { return "1.0.51 (Claude Code)", nil }
+	if err := runVersionCommand(claude, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := version.Get().String() + "\nclaude: 1.0.51 (Claude Code)\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	missing := func() (string, error) { return "", errors.New("executable file not found in $PATH") }
+	if err := runVersionCommand(missing, &out); err != nil || !strings.Contains(out.String(), "claude: unknown (executable file not found") {
+		t.Errorf("Expected Claude's version to be unknown, got %q, %v", out.String(), err)
+	}
+}
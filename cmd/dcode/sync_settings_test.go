@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// askAgainDialogLines returns a Bash dialog offering to stop asking about
+// similar commands: 1 approves, 2 approves without asking again, 3 rejects
+func askAgainDialogLines(command string) []string {
+	lines := bashDialogLines(command)
+	return append(lines[:7:7],
+		fmt.Sprintf("│ %-45s│", "  2. Yes, and don't ask again this session"),
+		fmt.Sprintf("│ %-45s│", "  3. No, and tell Claude what to do (esc)"),
+		lines[8])
+}
+
+func syncSettings(cfg *config.Config) {
+	cfg.SyncSettings = true
+}
+
+// readClaudeSettings returns the project's Claude settings file in repo
+func readClaudeSettings(t *testing.T, repo string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(repo, ".claude", "settings.json"))
+	if err != nil {
+		t.Fatalf("Expected Claude settings to be written: %v", err)
+	}
+	return string(data)
+}
+
+func TestSyncSettingsAllowsAfterDontAskAgain(t *testing.T) {
+	repo := chdirToRepo(t)
+
+	NewAppRobot(t).
+		Configure(syncSettings).
+		SetDialogChoice("2").
+		ReceiveClaudeText(askAgainDialogLines("go test ./pkg/...")...).
+		AssertButtonCount(4).
+		AssertButton(2, NeverAllowButton).
+		AssertTerminalContains("2")
+
+	if settings := readClaudeSettings(t, repo); !strings.Contains(settings, `"allow": [
+      "Bash(go test:*)"
+    ]`) {
+		t.Errorf("Expected the command prefix to be allowed, got:\n%s", settings)
+	}
+}
+
+func TestSyncSettingsDeniesAfterNeverAllow(t *testing.T) {
+	repo := chdirToRepo(t)
+
+	robot := NewAppRobot(t).
+		Configure(syncSettings).
+		SetDialogChoice("3").
+		ReceiveClaudeText(askAgainDialogLines("git push --force")...)
+
+	if output := robot.GetTerminalOutput(); output != "3" {
+		t.Errorf("Expected the reject choice, got: %q", output)
+	}
+	if settings := readClaudeSettings(t, repo); !strings.Contains(settings, `"deny": [
+      "Bash(git push --force)"
+    ]`) {
+		t.Errorf("Expected the command to be denied, got:\n%s", settings)
+	}
+}
+
+func TestSyncSettingsLeavesSettingsAloneOtherwise(t *testing.T) {
+	t.Run("Approve once", func(t *testing.T) {
+		repo := chdirToRepo(t)
+		NewAppRobot(t).
+			Configure(syncSettings).
+			ReceiveClaudeText(askAgainDialogLines("go vet ./...")...).
+			AssertTerminalContains("1")
+		if _, err := os.Stat(filepath.Join(repo, ".claude")); !os.IsNotExist(err) {
+			t.Errorf("Expected no Claude settings, got %v", err)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		repo := chdirToRepo(t)
+		NewAppRobot(t).
+			SetDialogChoice("2").
+			ReceiveClaudeText(askAgainDialogLines("go vet ./...")...).
+			AssertButtonCount(3)
+		if _, err := os.Stat(filepath.Join(repo, ".claude")); !os.IsNotExist(err) {
+			t.Errorf("Expected no Claude settings, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/systemlog"
+)
+
+// syslogRecorder stands in for the system log, recording each message with
+// its severity
+type syslogRecorder struct {
+	messages []string
+	mutex    sync.Mutex
+}
+
+func (r *syslogRecorder) Info(message string) error    { return r.add("info", message) }
+func (r *syslogRecorder) Notice(message string) error  { return r.add("notice", message) }
+func (r *syslogRecorder) Warning(message string) error { return r.add("warning", message) }
+func (r *syslogRecorder) Close() error                 { return nil }
+
+func (r *syslogRecorder) add(severity, message string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages = append(r.messages, severity+": "+message)
+	return nil
+}
+
+func (r *syslogRecorder) Messages() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+func TestSyslogMirrorsAnswerPickedInDialog(t *testing.T) {
+	recorder := &syslogRecorder{}
+	NewAppRobot(t).
+		UseSystemLog(systemlog.New(recorder)).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(100 * time.Millisecond)
+
+	messages := recorder.Messages()
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], `notice: decision=approved tool=Bash request="Bash: go test ./..." choice=1 decided_by=user`) {
+		t.Errorf("Expected the approval as a notice, got %q", messages)
+	}
+}
+
+func TestSyslogMirrorsAutomaticRejection(t *testing.T) {
+	recorder := &syslogRecorder{}
+	NewAppRobot(t).
+		UseSystemLog(systemlog.New(recorder)).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoReject = true
+		}).
+		ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	messages := recorder.Messages()
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "warning: decision=rejected") || !strings.Contains(messages[0], "decided_by=auto_reject ") || !strings.Contains(messages[0], "mode=auto-reject ") {
+		t.Errorf("Expected the rejection as a warning, got %q", messages)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/control"
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+// tailUsage describes the "dcode tail" subcommand
+const tailUsage = "usage: dcode tail [--all] [--pid=PID]"
+
+// runTailCommand runs "dcode tail" with the arguments after "tail". It
+// prints each answer a running session gives as it's given, with warnings and
+// errors, until the session ends; --all prints every event of each prompt.
+// --pid picks the session when more than one is running.
+func runTailCommand(argv []string, dir string, out io.Writer) error {
+	all := false
+	pid := 0
+	for _, arg := range argv {
+		if arg == "-all" || arg == "--all" {
+			all = true
+		} else if value, found := strings.CutPrefix(strings.TrimLeft(arg, "-"), "pid="); found && strings.HasPrefix(arg, "-") {
+			number, err := strconv.Atoi(value)
+			if err != nil || number <= 0 {
+				return fmt.Errorf("invalid pid: %s", value)
+			}
+			pid = number
+		} else {
+			return errors.New(tailUsage)
+		}
+	}
+
+	pid, err := sessionPID(dir, pid)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Following dcode session %d; press Ctrl-C to stop\n", pid)
+	err = control.Subscribe(control.SocketPath(dir, pid), func(line string) {
+		var event events.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return
+		}
+		if text := formatTailEvent(event, all); text != "" {
+			fmt.Fprintln(out, text)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("dcode session %d: %w", pid, err)
+	}
+	fmt.Fprintf(out, "dcode session %d ended\n", pid)
+	return nil
+}
+
+// formatTailEvent returns the line dcode tail prints for event, or "" for an
+// event only printed with all
+func formatTailEvent(event events.Event, all bool) string {
+	prefix := fmt.Sprintf("%s  #%-4d", event.Time.Local().Format("15:04:05"), event.Prompt)
+	request := event.Request
+	if request == "" {
+		request = event.Kind
+	}
+	switch event.Type {
+	case events.Decision:
+		return fmt.Sprintf("%s %-9s %s  (%s. %s, by %s)", prefix, event.Action, request, event.Choice, event.Label, event.DecidedBy)
+	case events.Error:
+		return fmt.Sprintf("%s %-9s %s", prefix, "error", event.Message)
+	case events.Anomaly:
+		return fmt.Sprintf("%s %-9s %s", prefix, "warning", event.Message)
+	}
+	if !all {
+		return ""
+	}
+	switch event.Type {
+	case events.DialogDetected:
+		return fmt.Sprintf("%s %-9s %s prompt %s", prefix, "detected", event.Kind, event.RequestID)
+	case events.DialogShown:
+		return fmt.Sprintf("%s %-9s %s", prefix, "asking", request)
+	case events.Suppressed:
+		return fmt.Sprintf("%s %-9s %s", prefix, "skipped", event.Message)
+	case events.Injection:
+		return fmt.Sprintf("%s %-9s choice %s, %dms after the prompt appeared", prefix, "sent", event.Choice, event.LatencyMs)
+	}
+	return fmt.Sprintf("%s %s", prefix, event.Type)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+	"github.com/takahirom/dialog-code/internal/control"
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+func TestTailCommandFollowsDecisions(t *testing.T) {
+	dir := newControlDir(t)
+	robot := NewAppRobot(t).SetDialogChoice("1")
+	server, err := control.Listen(control.SocketPath(dir, 1234), controlHandler(robot.app))
+	if err != nil {
+		t.Fatal(err)
+	}
+	robot.app.SetLiveEvents(events.NewStream(server))
+
+	out := &eventRecorder{}
+	done := make(chan error, 1)
+	go func() {
+		done <- runTailCommand(nil, dir, out)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	robot.ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(100 * time.Millisecond)
+	server.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Expected dcode tail to end with the session, got %v", err)
+	}
+
+	out.mutex.Lock()
+	output := out.buffer.String()
+	out.mutex.Unlock()
+	for _, expected := range []string{"Following dcode session 1234", "approved  Bash: go test ./...  (1. Yes, by user)", "dcode session 1234 ended"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "asking") {
+		t.Errorf("Expected only decisions without --all, got:\n%s", output)
+	}
+}
+
+func TestFormatTailEvent(t *testing.T) {
+	at := time.Date(2025, 1, 1, 9, 30, 12, 0, time.Local)
+	decision := events.Event{Time: at, Type: events.Decision, Prompt: 7, Kind: "permission", Request: "Bash: git push --force", Choice: "3", Label: "No", Action: audit.Rejected, DecidedBy: "deny rule 2"}
+	if line := formatTailEvent(decision, false); line != "09:30:12  #7    rejected  Bash: git push --force  (3. No, by deny rule 2)" {
+		t.Errorf("Unexpected decision line %q", line)
+	}
+	anomaly := events.Event{Time: at, Type: events.Anomaly, Anomaly: events.AnomalyAnswerRetries, Message: "4 answers failed"}
+	if line := formatTailEvent(anomaly, false); !strings.HasSuffix(line, "warning   4 answers failed") {
+		t.Errorf("Expected warnings to be printed, got %q", line)
+	}
+
+	shown := events.Event{Time: at, Type: events.DialogShown, Prompt: 7, Request: "Bash: git push --force"}
+	if line := formatTailEvent(shown, false); line != "" {
+		t.Errorf("Expected shown dialogs to be left out without --all, got %q", line)
+	}
+	if line := formatTailEvent(shown, true); line != "09:30:12  #7    asking    Bash: git push --force" {
+		t.Errorf("Unexpected shown dialog line %q", line)
+	}
+}
+
+func TestTailCommandUsage(t *testing.T) {
+	var out strings.Builder
+	if err := runTailCommand([]string{"--follow"}, t.TempDir(), &out); err == nil || err.Error() != tailUsage {
+		t.Errorf("Expected usage, got %v", err)
+	}
+	if err := runTailCommand(nil, t.TempDir(), &out); err == nil || !strings.Contains(err.Error(), "no running dcode session") {
+		t.Errorf("Expected no session to be found, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/telemetry"
+)
+
+func TestTelemetryCountsPrompts(t *testing.T) {
+	counters := telemetry.NewCounters([]string{"osascript"})
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			cfg.Deny = []config.DenyRule{{Name: "no force push", Rule: config.Rule{Tool: "Bash", Command: `^git push`}}}
+		}).
+		UseTelemetry(counters).
+		ReceiveClaudeText(bashDialogLines("git push --force")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	report := counters.Report()
+	if report.Prompts["permission"] != 1 || report.Decisions["rejected"] != 1 || report.DecidedBy["deny rule"] != 1 || report.Modes[config.ModeDialog] != 1 {
+		t.Errorf("Expected the rejected prompt to be counted, got %+v", report)
+	}
+	payload, _ := json.Marshal(report)
+	for _, private := range []string{"git push", "no force push"} {
+		if strings.Contains(string(payload), private) {
+			t.Errorf("Expected the report to leave out %q, got %s", private, payload)
+		}
+	}
+}
+
+func TestDialogBackends(t *testing.T) {
+	cfg := config.Default()
+	if backends := dialogBackends(&cfg); strings.Join(backends, ",") != "osascript" {
+		t.Errorf("Expected only osascript, got %v", backends)
+	}
+	cfg.Remote.NtfyURL = "https://ntfy.sh/topic"
+	if backends := dialogBackends(&cfg); strings.Join(backends, ",") != "osascript,ntfy" {
+		t.Errorf("Expected ntfy as well, got %v", backends)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+func approveTemporarily(cfg *config.Config) {
+	cfg.TemporaryApprovalMinutes = 15
+	cfg.Delays.DialogResetMs = 0
+}
+
+func TestTemporaryApprovalButton(t *testing.T) {
+	t.Run("Button follows the approve choice", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(approveTemporarily).
+			SetDialogChoice("3").
+			ReceiveClaudeText(bashDialogLines("go test ./...")...).
+			AssertButtonCount(3).
+			AssertButton(0, "Yes").
+			AssertButton(1, "Approve for 15 minutes").
+			AssertButton(2, "No, and tell Claude what to do (esc)").
+			AssertTerminalContains("2")
+	})
+
+	t.Run("No button by default", func(t *testing.T) {
+		NewAppRobot(t).
+			ReceiveClaudeText(bashDialogLines("go test ./...")...).
+			AssertButtonCount(2)
+	})
+
+	t.Run("No button for high-risk requests", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(approveTemporarily).
+			ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+			AssertButtonCount(2)
+	})
+
+	t.Run("No button for chained commands", func(t *testing.T) {
+		NewAppRobot(t).
+			Configure(approveTemporarily).
+			ReceiveClaudeText(bashDialogLines("go test ./... && make")...).
+			AssertButtonCount(2)
+	})
+}
+
+// after returns the robots' fake start time plus d
+func after(d time.Duration) time.Time {
+	return time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC).Add(d)
+}
+
+// askedAs returns a Bash dialog for command asking question, since repeating
+// the same question within a few seconds is ignored as a redraw
+func askedAs(command, question string) []string {
+	lines := bashDialogLines(command)
+	lines[5] = fmt.Sprintf("│ %-45s│", question)
+	return lines
+}
+
+func TestTemporaryApprovalCoversSimilarRequests(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(approveTemporarily).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertDialogCaptured().
+		ClearCapturedDialog()
+
+	robot.SetFakeTime(after(time.Minute)).
+		ReceiveClaudeText(askedAs("go test ./pkg/parser", "Do you want to run the tests?")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+	if output := robot.GetTerminalOutput(); output != "11" {
+		t.Errorf("Expected both requests to be approved with choice 1, got: %q", output)
+	}
+
+	t.Run("Other command prefix shows a dialog", func(t *testing.T) {
+		robot.SetFakeTime(after(2 * time.Minute)).
+			ReceiveClaudeText(askedAs("go vet ./...", "Do you want to run vet?")...).
+			AssertDialogCaptured().
+			ClearCapturedDialog()
+	})
+
+	t.Run("Expired approval shows a dialog", func(t *testing.T) {
+		robot.SetFakeTime(after(15 * time.Minute)).
+			ReceiveClaudeText(askedAs("go test ./internal/...", "Do you want to test again?")...).
+			AssertDialogCaptured()
+	})
+}
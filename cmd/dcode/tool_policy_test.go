@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// toolPolicy configures --tool-policy with text such as "Bash=ask"
+func toolPolicy(t *testing.T, text string) func(cfg *config.Config) {
+	policy, err := config.ParseToolPolicy(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(cfg *config.Config) { cfg.ToolPolicy = policy }
+}
+
+func TestToolPolicyDenies(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(toolPolicy(t, "Bash=deny")).
+		ReceiveClaudeText(bashDialogLines("make build")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the reject choice first, got: %q", output)
+	}
+	robot.AssertTerminalContains("The Bash tool is not allowed in this session.")
+}
+
+func TestToolPolicyAllows(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(toolPolicy(t, "Bash=allow,Write=ask")).
+		ReceiveClaudeText(bashDialogLines("make build")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected the approve choice, got: %q", output)
+	}
+}
+
+func TestToolPolicyAsksDespiteAutoApprove(t *testing.T) {
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			toolPolicy(t, "Bash=ask")(cfg)
+			cfg.AutoApprove = true
+		}).
+		SetDialogChoice("2").
+		ReceiveClaudeText(bashDialogLines("make build")...).
+		AssertDialogCaptured().
+		AssertTerminalContains("2")
+}
+
+func TestToolPolicyAsksDespiteRiskPolicy(t *testing.T) {
+	NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			toolPolicy(t, "Bash=ask")(cfg)
+			riskPolicy(config.RiskActionApprove, config.RiskActionApprove, config.RiskActionDialog)(cfg)
+		}).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("ls -la")...).
+		AssertDialogCaptured()
+}
+
+func TestToolPolicyLeavesOtherToolsAlone(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			toolPolicy(t, "Bash=ask")(cfg)
+			cfg.AutoApprove = true
+		}).
+		ReceiveClaudeText(writeDialogLines("/repo/notes.txt")...).
+		AssertNoDialogCaptured()
+	time.Sleep((config.DefaultAutoApproveDelayMs + 50) * time.Millisecond)
+
+	if output := robot.GetTerminalOutput(); output != "1" {
+		t.Errorf("Expected --auto-approve to approve other tools, got: %q", output)
+	}
+}
+
+func TestToolPolicyComesAfterDenyRules(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) {
+			toolPolicy(t, "Bash=allow")(cfg)
+			cfg.Deny = []config.DenyRule{{Rule: config.Rule{Command: `^rm `}}}
+		}).
+		ReceiveClaudeText(bashDialogLines("rm notes.txt")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	if output := robot.GetTerminalOutput(); !strings.HasPrefix(output, "2") {
+		t.Errorf("Expected the deny rule to reject, got: %q", output)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/tracing"
+)
+
+// exportedSpan is a span as an OTLP collector receives it
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+}
+
+// attribute returns the value of the span's attribute key
+func (s exportedSpan) attribute(key string) string {
+	for _, attribute := range s.Attributes {
+		if attribute.Key == key {
+			return attribute.Value.StringValue
+		}
+	}
+	return ""
+}
+
+// traceCollector starts an OTLP collector and returns an exporter sending
+// to it and a function returning the spans it received so far
+func traceCollector(t *testing.T) (*tracing.Exporter, func() []exportedSpan) {
+	var mutex sync.Mutex
+	var spans []exportedSpan
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Expected an OTLP JSON request, got %v", err)
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, resource := range request.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				spans = append(spans, scope.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return tracing.NewExporter(server.URL), func() []exportedSpan {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]exportedSpan(nil), spans...)
+	}
+}
+
+func TestTraceOfDialogAnswer(t *testing.T) {
+	exporter, received := traceCollector(t)
+	NewAppRobot(t).
+		UseTraceExporter(exporter).
+		SetDialogChoice("1").
+		ReceiveClaudeText(bashDialogLines("go test ./...")...).
+		AssertTerminalContains("1")
+	time.Sleep(200 * time.Millisecond)
+
+	spans := received()
+	if len(spans) == 0 {
+		t.Fatal("Expected a trace to be exported")
+	}
+	root := spans[0]
+	if root.Name != "answer_prompt" || root.ParentSpanID != "" {
+		t.Fatalf("Expected the root span first, got %+v", root)
+	}
+	for key, expected := range map[string]string{
+		"dcode.tool":       "Bash",
+		"dcode.decision":   "approved",
+		"dcode.decided_by": "user",
+		"dcode.choice":     "1",
+		"dcode.mode":       config.ModeDialog,
+	} {
+		if value := root.attribute(key); value != expected {
+			t.Errorf("Expected %s=%s, got %q", key, expected, value)
+		}
+	}
+
+	steps := map[string]bool{}
+	for _, span := range spans[1:] {
+		if span.TraceID != root.TraceID || span.ParentSpanID != root.SpanID {
+			t.Errorf("Expected every step under the root, got %+v", span)
+		}
+		steps[span.Name] = true
+	}
+	for _, step := range []string{"detect", "settle", "decide", "build_message", "show_dialog", "inject"} {
+		if !steps[step] {
+			t.Errorf("Expected a %s step, got %v", step, steps)
+		}
+	}
+}
+
+func TestTraceOfAutomaticAnswer(t *testing.T) {
+	exporter, received := traceCollector(t)
+	NewAppRobot(t).
+		UseTraceExporter(exporter).
+		Configure(func(cfg *config.Config) {
+			cfg.AutoReject = true
+		}).
+		ReceiveClaudeText(bashDialogLines("rm -rf build")...).
+		AssertNoDialogCaptured()
+	time.Sleep(denyRuleWaitTime)
+
+	spans := received()
+	if len(spans) == 0 {
+		t.Fatal("Expected a trace to be exported")
+	}
+	if root := spans[0]; root.attribute("dcode.decision") != "rejected" || root.attribute("dcode.decided_by") != "auto_reject" {
+		t.Errorf("Expected the automatic rejection, got %+v", root)
+	}
+	for _, span := range spans[1:] {
+		if span.Name == "show_dialog" {
+			t.Errorf("Expected no dialog step, got %+v", span)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+var trustDialogLines = []string{
+	"╭───────────────────────────────────────────────────────────────────────╮",
+	"│                                                                       │",
+	"│ Do you trust the files in this folder?                                │",
+	"│                                                                       │",
+	"│ /Users/test/git/dialog-code                                           │",
+	"│                                                                       │",
+	"│ Claude Code may read files in this folder. Reading untrusted files    │",
+	"│ may lead Claude Code to behave in unexpected ways.                    │",
+	"│                                                                       │",
+	"│ ❯ 1. Yes, proceed                                                     │",
+	"│   2. No, exit                                                         │",
+	"│                                                                       │",
+	"│   Enter to confirm · Esc to exit                                      │",
+	"╰───────────────────────────────────────────────────────────────────────╯",
+}
+
+func TestTrustPromptShowsDedicatedDialog(t *testing.T) {
+	robot := NewAppRobot(t).
+		SetDialogChoice("2").
+		ReceiveClaudeText(trustDialogLines...).
+		AssertDialogTextContains("Do you trust the files in this folder?").
+		AssertDialogTextContains("/Users/test/git/dialog-code").
+		AssertButtonCount(2).
+		AssertButton(0, "Yes, proceed").
+		AssertButton(1, "No, exit").
+		AssertTerminalContains("2")
+
+	// The trust dialog should not use the tool permission format
+	if strings.Contains(robot.GetCapturedMessage(), "Trigger text:") {
+		t.Errorf("Trust dialog should not use the permission message format: %q", robot.GetCapturedMessage())
+	}
+}
+
+func TestTrustPromptAutoTrustsConfiguredDirs(t *testing.T) {
+	robot := NewAppRobot(t).
+		Configure(func(cfg *config.Config) { cfg.TrustDirs = []string{"/Users/test/git"} }).
+		ReceiveClaudeText(trustDialogLines...).
+		AssertNoDialogCaptured()
+
+	if robot.GetTerminalOutput() != "1" {
+		t.Errorf("Expected trusted folder to select choice 1, got: %q", robot.GetTerminalOutput())
+	}
+}
+
+func TestIsWithinDirs(t *testing.T) {
+	testCases := []struct {
+		folder   string
+		dirs     []string
+		expected bool
+	}{
+		{"/Users/test/project", []string{"/Users/test/project"}, true},
+		{"/Users/test/project/sub", []string{"/Users/test/project"}, true},
+		{"/Users/test/project-other", []string{"/Users/test/project"}, false},
+		{"/Users/test", []string{"/Users/test/project"}, false},
+		{"/Users/test/project", nil, false},
+		{"", []string{"/"}, false},
+	}
+
+	for _, tc := range testCases {
+		if result := isWithinDirs(tc.folder, tc.dirs); result != tc.expected {
+			t.Errorf("isWithinDirs(%q, %v) = %v, want %v", tc.folder, tc.dirs, result, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/control"
+)
+
+// uninstallUsage describes the "dcode uninstall" subcommand
+const uninstallUsage = "usage: dcode uninstall [--all] [--dry-run] [--config=PATH]"
+
+// uninstallPath is a file or directory of dcode's that uninstalling removes
+// or keeps
+type uninstallPath struct {
+	path string
+	what string // What it holds, e.g. "audit log"
+}
+
+// runUninstallCommand runs "dcode uninstall" with the arguments after
+// "uninstall": it removes the files dcode keeps for itself, its cache and
+// the directory of control sockets in controlDir, and with --all the config
+// file and its rules as well. Logs the config names are kept. Nothing is
+// removed while sessions are running, and --dry-run only reports.
+func runUninstallCommand(argv []string, controlDir string, out io.Writer) error {
+	all, dryRun := false, false
+	var rest []string
+	for _, arg := range argv {
+		switch arg {
+		case "-all", "--all":
+			all = true
+		case "-dry-run", "--dry-run":
+			dryRun = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	// An invalid config file mustn't stop it from being removed
+	cfg, configFile, err := loadConfigFile(rest)
+	if err != nil && !all {
+		return err
+	}
+	if args, err := applyFlags(&cfg, rest); err != nil || len(args) > 0 {
+		return errors.New(uninstallUsage)
+	}
+
+	pids, err := control.Sessions(controlDir)
+	if err != nil {
+		return err
+	}
+	if len(pids) > 0 {
+		return fmt.Errorf("dcode is running as process %s; quit those sessions first", joinPIDs(pids))
+	}
+
+	var remove, keep []uninstallPath
+	if dir := parentDir(approvals.DefaultPath()); dir != "" {
+		remove = append(remove, uninstallPath{dir, "approval cache, decision log, session state, and crash reports"})
+	}
+	remove = append(remove, uninstallPath{controlDir, "control sockets and the dcode panic flag"})
+	if configFile == "" {
+		configFile, _ = configPath(rest)
+	}
+	if configFile != "" {
+		config := uninstallPath{configFile, "config file and its rules"}
+		if all {
+			remove = append(remove, config)
+		} else {
+			keep = append(keep, config)
+		}
+	}
+	for _, log := range []uninstallPath{
+		{cfg.AuditLog, "audit log"},
+		{cfg.LogFile, "debug log"},
+		{cfg.Transcript, "transcripts"},
+		{cfg.EventStream, "event stream"},
+		{cfg.ScreenshotDir, "screenshots"},
+	} {
+		if log.path != "" {
+			keep = append(keep, uninstallPath{logPath(&cfg, log.path), log.what})
+		}
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	removed := 0
+	for _, item := range remove {
+		if _, err := os.Lstat(item.path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if inside := keptInside(item.path, keep); inside != nil {
+			fmt.Fprintf(out, "Kept %s (%s), which holds the %s\n", item.path, item.what, inside.what)
+			continue
+		}
+		if !dryRun {
+			if err := os.RemoveAll(item.path); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(out, "%s %s (%s)\n", verb, item.path, item.what)
+		removed++
+	}
+	if all && !dryRun && configFile != "" {
+		// Removes the config directory only if nothing else is in it
+		os.Remove(filepath.Dir(configFile))
+	}
+	if removed == 0 {
+		fmt.Fprintln(out, "Nothing to remove")
+	}
+
+	for _, item := range keep {
+		if _, err := os.Stat(item.path); err == nil {
+			fmt.Fprintf(out, "Kept %s (%s)\n", item.path, item.what)
+		}
+	}
+	if _, err := os.Stat(configFile); err == nil && !all {
+		fmt.Fprintln(out, "Run dcode uninstall --all to remove the config file as well.")
+	}
+	if cfg.SyncSettings {
+		fmt.Fprintln(out, "Rules added to .claude/settings.json with sync_settings stay; they are Claude's own now.")
+	}
+	if executable, err := os.Executable(); err == nil && !dryRun {
+		fmt.Fprintf(out, "To finish, delete dcode itself: rm %s\n", executable)
+	}
+	return nil
+}
+
+// keptInside returns the first of keep inside dir, or nil if none is
+func keptInside(dir string, keep []uninstallPath) *uninstallPath {
+	for i, item := range keep {
+		rel, err := filepath.Rel(dir, item.path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return &keep[i]
+		}
+	}
+	return nil
+}
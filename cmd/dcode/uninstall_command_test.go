@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/internal/control"
+)
+
+// uninstallHome sets up dcode's cache, control directory, and a config file
+// naming auditLog in fresh XDG directories, returning the control directory.
+// The cache lives in cacheHome.
+func uninstallHome(t *testing.T, cacheHome, auditLog string) string {
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := approvals.NewCache(approvals.DefaultPath()).Add("key", 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	controlDir := filepath.Join(t.TempDir(), "dcode")
+	if err := runPanicCommand(controlDir, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(config.DefaultPath()), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.DefaultPath(), []byte("audit_log: "+auditLog+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(auditLog, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return controlDir
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestUninstall(t *testing.T) {
+	auditLog := filepath.Join(t.TempDir(), "audit.jsonl")
+	controlDir := uninstallHome(t, t.TempDir(), auditLog)
+	cacheDir := filepath.Dir(approvals.DefaultPath())
+
+	var out bytes.Buffer
+	if err := runUninstallCommand([]string{"--dry-run"}, controlDir, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Would remove "+cacheDir) || !exists(cacheDir) || !exists(control.PanicPath(controlDir)) {
+		t.Errorf("Expected a dry run to only report, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runUninstallCommand(nil, controlDir, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{
+		"Removed " + cacheDir + " (",
+		"Removed " + controlDir + " (",
+		"Kept " + config.DefaultPath() + " (config file and its rules)",
+		"Kept " + auditLog + " (audit log)",
+		"Run dcode uninstall --all",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+	if exists(cacheDir) || exists(controlDir) || !exists(config.DefaultPath()) || !exists(auditLog) {
+		t.Errorf("Expected only the cache and control directory to be removed, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runUninstallCommand([]string{"--all"}, controlDir, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if exists(filepath.Dir(config.DefaultPath())) || !exists(auditLog) {
+		t.Errorf("Expected the config directory to be removed and the audit log kept, got:\n%s", out.String())
+	}
+}
+
+func TestUninstallKeepsLogsInCache(t *testing.T) {
+	cacheHome := t.TempDir()
+	cacheDir := filepath.Join(cacheHome, "dcode")
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	controlDir := uninstallHome(t, cacheHome, filepath.Join(cacheDir, "audit.jsonl"))
+
+	var out bytes.Buffer
+	if err := runUninstallCommand(nil, controlDir, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !exists(filepath.Join(cacheDir, "audit.jsonl")) || !strings.Contains(out.String(), "Kept "+cacheDir+" (") {
+		t.Errorf("Expected the cache holding the audit log to be kept, got:\n%s", out.String())
+	}
+}
+
+func TestUninstallRefusesWhileRunning(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	controlDir := newControlDir(t)
+	server, err := control.Listen(control.SocketPath(controlDir, 4242), func(string) (string, error) { return "", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	err = runUninstallCommand(nil, controlDir, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "4242") {
+		t.Errorf("Expected running sessions to stop it, got %v", err)
+	}
+	if _, err := os.Stat(controlDir); err != nil {
+		t.Errorf("Expected nothing to be removed, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	github.com/creack/pty v1.1.24
 	golang.org/x/term v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,134 @@
+// Package approvals remembers the requests the user approved in a dialog, so
+// an identical request made again shortly after is approved without asking.
+// Approvals are kept in a file shared by every dcode process. Temporary
+// approvals, which cover similar requests too, last only for the session.
+package approvals
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// Cache stores approval times keyed by Key in a JSON file
+type Cache struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewCache returns a cache stored at path. The file is created on the first
+// approval.
+func NewCache(path string) *Cache {
+	return &Cache{path: path}
+}
+
+// DefaultPath returns ~/.cache/dcode/approvals.json, honoring $XDG_CACHE_HOME,
+// or "" if the cache directory is unknown
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dcode", "approvals.json")
+}
+
+// Key identifies what a dialog asks to do in the project at dir: its tool,
+// every line of its command with whitespace normalized, its target paths, and
+// any diff it previews. The same request in another project has another key,
+// since a command like "make" runs whatever that project says. Bash
+// descriptions are left out, since Claude words them differently each time.
+func Key(dir string, info parser.DialogInfo) string {
+	parts := []string{dir, info.ToolType}
+	for _, line := range info.Command() {
+		parts = append(parts, strings.Join(strings.Fields(line), " "))
+	}
+	parts = append(parts, info.FilePaths...)
+	parts = append(parts, parser.FormatDiff(info.Diff)...)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Approved reports whether key was approved less than ttl before now
+func (c *Cache) Approved(key string, ttl time.Duration, now time.Time) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	approvedAt, ok := c.load()[key]
+	return ok && now.Sub(approvedAt) < ttl
+}
+
+// Add records that key was approved at now, dropping approvals older than ttl
+func (c *Cache) Add(key string, ttl time.Duration, now time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entries := c.load()
+	for existing, approvedAt := range entries {
+		if now.Sub(approvedAt) >= ttl {
+			delete(entries, existing)
+		}
+	}
+	entries[key] = now
+	return c.save(entries)
+}
+
+// Clear forgets every approval
+func (c *Cache) Clear() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// load reads the stored approvals. A missing or unreadable file is treated as
+// empty, so a corrupt cache only costs a dialog.
+func (c *Cache) load() map[string]time.Time {
+	entries := make(map[string]time.Time)
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return make(map[string]time.Time)
+	}
+	return entries
+}
+
+// save writes entries through a temporary file, so other dcode processes never
+// read a partial cache
+func (c *Cache) save(entries map[string]time.Time) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".approvals-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,137 @@
+package approvals
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+func TestKey(t *testing.T) {
+	goTest := parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./...", "Run all tests"}, Described: true}
+
+	testCases := []struct {
+		name  string
+		info  parser.DialogInfo
+		equal bool
+	}{
+		{"same request", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./...", "Run all tests"}, Described: true}, true},
+		{"different description", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./...", "Run the test suite"}, Described: true}, true},
+		{"more lines", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./...", "rm -rf ~", "Run all tests"}, Described: true}, false},
+		{"more lines in one", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./...\nrm -rf ~"}}, false},
+		{"description not told apart", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./...", "rm -rf ~"}}, false},
+		{"different whitespace", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go  test   ./... "}}, true},
+		{"different command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./pkg/..."}}, false},
+		{"different tool", parser.DialogInfo{ToolType: "Edit", CommandLines: []string{"go test ./..."}}, false},
+		{"different file", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./..."}, FilePaths: []string{"/tmp/x"}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if equal := Key("/work/app", tc.info) == Key("/work/app", goTest); equal != tc.equal {
+				t.Errorf("Expected keys equal: %v, got %v", tc.equal, equal)
+			}
+		})
+	}
+}
+
+func TestKeyIncludesEditDetails(t *testing.T) {
+	edit := func(added string) parser.DialogInfo {
+		return parser.DialogInfo{
+			ToolType:     "Edit",
+			CommandLines: []string{"main.go"},
+			FilePaths:    []string{"main.go"},
+			Diff:         []parser.DiffHunk{{Lines: []parser.DiffLine{{Op: parser.DiffAdded, Text: added}}}},
+		}
+	}
+	if Key("/work/app", edit("fmt.Println(1)")) == Key("/work/app", edit("os.Exit(1)")) {
+		t.Error("Expected edits with different diffs to have different keys")
+	}
+}
+
+func TestKeyIncludesProject(t *testing.T) {
+	build := parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"make"}}
+	if Key("/work/app", build) == Key("/work/other", build) {
+		t.Error("Expected the same request in different projects to have different keys")
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcode", "approvals.json")
+	cache := NewCache(path)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ttl := 5 * time.Minute
+
+	if cache.Approved("a", ttl, now) {
+		t.Fatal("Expected an empty cache")
+	}
+	if err := cache.Add("a", ttl, now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		key      string
+		at       time.Time
+		expected bool
+	}{
+		{"approved key", "a", now.Add(2 * time.Minute), true},
+		{"other key", "b", now.Add(2 * time.Minute), false},
+		{"expired", "a", now.Add(ttl), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := cache.Approved(tc.key, ttl, tc.at); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+
+	t.Run("Shared by other processes", func(t *testing.T) {
+		if !NewCache(path).Approved("a", ttl, now) {
+			t.Error("Expected a new cache on the same file to see the approval")
+		}
+	})
+
+	t.Run("Expired approvals are dropped", func(t *testing.T) {
+		if err := cache.Add("b", ttl, now.Add(10*time.Minute)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if entries := cache.load(); len(entries) != 1 {
+			t.Errorf("Expected only the new approval, got %v", entries)
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		if err := cache.Clear(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cache.Approved("b", ttl, now.Add(10*time.Minute)) {
+			t.Error("Expected the cache to be empty")
+		}
+		if err := cache.Clear(); err != nil {
+			t.Errorf("Expected clearing an empty cache to succeed, got %v", err)
+		}
+	})
+}
+
+func TestCacheIgnoresCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approvals.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCache(path)
+	now := time.Now()
+	if cache.Approved("a", time.Minute, now) {
+		t.Error("Expected a corrupt cache to approve nothing")
+	}
+	if err := cache.Add("a", time.Minute, now); err != nil {
+		t.Fatalf("Expected the corrupt cache to be replaced, got %v", err)
+	}
+	if !cache.Approved("a", time.Minute, now) {
+		t.Error("Expected the approval to be stored")
+	}
+}
@@ -0,0 +1,113 @@
+package approvals
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// prefixWords is how many words of a command make up its prefix: the program
+// and, usually, its subcommand
+const prefixWords = 2
+
+// shellOperators chain or redirect commands; a command containing one could do
+// anything after its prefix, so it is never approved temporarily
+var shellOperators = []string{";", "&", "|", ">", "<", "`", "$(", "\n"}
+
+// Temporary holds approvals that cover every similar request until they
+// expire. Its zero value holds none.
+type Temporary struct {
+	mutex  sync.Mutex
+	grants map[string]time.Time
+}
+
+// Scope describes the requests similar to info: the same tool with the same
+// command prefix, or, for tools without a command, files in the same
+// directory. It returns "" if info has nothing to scope an approval by,
+// including a command of several lines, whose prefix says nothing about the
+// lines after it, and a tool without files, such as WebFetch or an MCP tool,
+// whose scope would be every request it makes.
+func Scope(info parser.DialogInfo) string {
+	if info.ToolType == "" {
+		return ""
+	}
+
+	if info.ToolType == parser.ToolBash {
+		command := info.Command()
+		if len(command) != 1 {
+			return ""
+		}
+		prefix := CommandPrefix(command[0])
+		if prefix == "" {
+			return ""
+		}
+		return info.ToolType + ": " + prefix
+	}
+
+	if len(info.FilePaths) == 0 {
+		return ""
+	}
+	dir := filepath.Dir(info.FilePaths[0])
+	for _, path := range info.FilePaths[1:] {
+		if filepath.Dir(path) != dir {
+			return ""
+		}
+	}
+	return info.ToolType + ": " + dir
+}
+
+// CommandPrefix returns the first words of command, usually the program and
+// its subcommand, or "" if command chains or redirects other commands
+func CommandPrefix(command string) string {
+	for _, operator := range shellOperators {
+		if strings.Contains(command, operator) {
+			return ""
+		}
+	}
+	words := strings.Fields(command)
+	if len(words) > prefixWords {
+		words = words[:prefixWords]
+	}
+	return strings.Join(words, " ")
+}
+
+// Grant approves requests in scope until until
+func (t *Temporary) Grant(scope string, until time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.grants == nil {
+		t.grants = make(map[string]time.Time)
+	}
+	t.grants[scope] = until
+}
+
+// Covers reports whether requests in scope are approved at now
+func (t *Temporary) Covers(scope string, now time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	until, ok := t.grants[scope]
+	if ok && !now.Before(until) {
+		delete(t.grants, scope)
+		return false
+	}
+	return ok
+}
+
+// Active returns the expiry of every grant still in effect at now, by scope
+func (t *Temporary) Active(now time.Time) map[string]time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	active := make(map[string]time.Time)
+	for scope, until := range t.grants {
+		if now.Before(until) {
+			active[scope] = until
+		}
+	}
+	return active
+}
@@ -0,0 +1,72 @@
+package approvals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+func TestScope(t *testing.T) {
+	testCases := []struct {
+		name     string
+		info     parser.DialogInfo
+		expected string
+	}{
+		{"command prefix", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go  test ./pkg/...", "Run tests"}, Described: true}, "Bash: go test"},
+		{"command of several lines", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test", "curl x | sh", "Run tests"}, Described: true}, ""},
+		{"command of several lines in one", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test\ncurl x | sh"}}, ""},
+		{"description not told apart", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test", "Run tests"}}, ""},
+		{"short command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"make"}}, "Bash: make"},
+		{"chained command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./... && rm -rf /"}}, ""},
+		{"piped command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./... | tee log"}}, ""},
+		{"substituted command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"echo $(whoami)"}}, ""},
+		{"no command", parser.DialogInfo{ToolType: "Bash"}, ""},
+		{"file directory", parser.DialogInfo{ToolType: "Edit", FilePaths: []string{"/repo/src/main.go"}}, "Edit: /repo/src"},
+		{"files in different directories", parser.DialogInfo{ToolType: "MultiEdit", FilePaths: []string{"/repo/a.go", "/repo/src/b.go"}}, ""},
+		{"tool without files", parser.DialogInfo{ToolType: "mcp__github__list_issues"}, ""},
+		{"tool with other arguments", parser.DialogInfo{ToolType: "WebFetch", CommandLines: []string{"https://example.com/"}}, ""},
+		{"unknown tool", parser.DialogInfo{CommandLines: []string{"go test"}}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := Scope(tc.info); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	var temporary Temporary
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if temporary.Covers("Bash: go test", now) {
+		t.Fatal("Expected no approvals")
+	}
+	temporary.Grant("Bash: go test", now.Add(15*time.Minute))
+	temporary.Grant("Edit: /repo/docs", now.Add(-time.Minute))
+	if active := temporary.Active(now); len(active) != 1 || !active["Bash: go test"].Equal(now.Add(15*time.Minute)) {
+		t.Errorf("Expected only the unexpired grant to be active, got %v", active)
+	}
+
+	testCases := []struct {
+		name     string
+		scope    string
+		at       time.Time
+		expected bool
+	}{
+		{"granted scope", "Bash: go test", now.Add(14 * time.Minute), true},
+		{"other scope", "Bash: go vet", now, false},
+		{"expired", "Bash: go test", now.Add(15 * time.Minute), false},
+		{"stays expired", "Bash: go test", now, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := temporary.Covers(tc.scope, tc.at); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Package audit records every prompt dcode answered, whoever decided the
+// answer, in an append-only JSON Lines file for later review. Unlike the
+// decision log, entries are never trimmed; the file is only rotated aside as
+// log_rotation says, and reading covers the rotated copies. Entries don't
+// depend on how the prompt was intercepted, so anything else that answers
+// Claude's prompts can append to the same file through a Log, naming itself
+// in Source. Each entry is chained to the one before it by its hash, so
+// Verify can tell whether entries were modified or removed since.
+// "dcode history" searches it.
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/logfile"
+)
+
+// SourceWrapper is the Source of entries written by dcode wrapping Claude's
+// terminal
+const SourceWrapper = "wrapper"
+
+// Actions an Entry can record
+const (
+	Approved = "approved"
+	Rejected = "rejected"
+	Answered = "answered" // A choice that neither approves nor rejects, such as a plan option
+)
+
+// User is the DecidedBy of answers picked in a dialog
+const User = "user"
+
+// Entry records one answered prompt
+type Entry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`                 // When the answer was sent
+	Source     string    `json:"source"`               // What answered, e.g. SourceWrapper
+	Session    int       `json:"session"`              // Process ID of the dcode that answered
+	Dir        string    `json:"dir"`                  // Project the session runs in
+	Repo       string    `json:"repo,omitempty"`       // The project's origin remote, without credentials
+	Branch     string    `json:"branch,omitempty"`     // Branch checked out in the project, or "" if detached
+	Commit     string    `json:"commit,omitempty"`     // Commit checked out in the project
+	Prompt     string    `json:"prompt"`               // Kind of prompt, e.g. "permission" or "folder_trust"
+	Tool       string    `json:"tool"`                 // Tool requested, e.g. "Bash", or "" if the prompt names none
+	Request    string    `json:"request"`              // Tool and command or files, with secrets masked
+	Risk       string    `json:"risk"`                 // Rated risk and its reason, e.g. "high (force push)"
+	Choice     string    `json:"choice"`               // Choice number sent
+	Label      string    `json:"label"`                // The choice's text, e.g. "Yes"
+	Action     string    `json:"action"`               // Approved, Rejected, or Answered
+	DecidedBy  string    `json:"decided_by"`           // User, or the rule or option that answered without asking
+	Mode       string    `json:"mode"`                 // Session mode when answered, e.g. "auto-reject-wait=30"
+	LatencyMs  int64     `json:"latency_ms"`           // From the prompt appearing to the answer being sent
+	WaitMs     int64     `json:"wait_ms"`              // How long dialogs waited for the user to answer; 0 if none was shown
+	Dialog     []string  `json:"dialog"`               // The prompt's lines as Claude showed them, with secrets masked
+	Screenshot string    `json:"screenshot,omitempty"` // Image of the dialog as the user saw it, with --screenshot-dir
+	Seq        int64     `json:"seq,omitempty"`        // Numbers the entries of the log from 1, across rotated copies
+	Prev       string    `json:"prev,omitempty"`       // Hash of the entry before, or "" for the first
+	Hash       string    `json:"hash,omitempty"`       // Hash of this entry's line before this field; always last
+}
+
+// Filter selects entries; its zero value selects every entry
+type Filter struct {
+	Tool   string    // Entries for this tool, ignoring case
+	Action string    // Entries with this Action
+	Dir    string    // Entries from sessions in this project
+	Branch string    // Entries answered with this branch checked out
+	Since  time.Time // Entries at or after this time
+	Until  time.Time // Entries before this time
+}
+
+// Match reports whether entry is selected by f
+func (f Filter) Match(entry Entry) bool {
+	switch {
+	case f.Tool != "" && !strings.EqualFold(f.Tool, entry.Tool):
+		return false
+	case f.Action != "" && f.Action != entry.Action:
+		return false
+	case f.Dir != "" && filepath.Clean(f.Dir) != filepath.Clean(entry.Dir):
+		return false
+	case f.Branch != "" && f.Branch != entry.Branch:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// NewID returns a short random ID for an entry
+func NewID() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Log appends entries to a file
+type Log struct {
+	path     string
+	rotation logfile.Rotation
+	redact   func(string) string // Masks text in entries read, or nil
+	mutex    sync.Mutex
+}
+
+// NewLog returns a log stored at path. The file is created on the first entry.
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// SetRotation sets when the log is rotated and which rotated copies are kept
+func (l *Log) SetRotation(r logfile.Rotation) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rotation = r
+}
+
+// SetRedact masks text in the entries read with redact, so entries written
+// before a pattern was added, or by another tool, don't show what it matches.
+// Entries are written as given.
+func (l *Log) SetRedact(redact func(string) string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.redact = redact
+}
+
+// Add appends entry to the log as a single write, chained to the entry
+// before it by its hash. Processes appending to the same log take turns, so
+// their entries never interleave and the chain stays in order.
+func (l *Log) Add(entry Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	headFile, err := os.OpenFile(l.path+HeadSuffix, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer headFile.Close()
+	if err := lockFile(headFile); err != nil {
+		return err
+	}
+	last, err := readHead(headFile)
+	if err != nil {
+		return err
+	}
+	line, next, err := chainLine(entry, last)
+	if err != nil {
+		return err
+	}
+
+	if _, err := logfile.Rotate(l.path, l.rotation, time.Now(), len(line)+1); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return writeHead(headFile, next)
+}
+
+// Find returns the entry with id, or false if the log has none
+func (l *Log) Find(id string) (Entry, bool, error) {
+	entries, err := l.Entries(Filter{})
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == id {
+			return entries[i], true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Entries returns the entries in the log and its rotated copies selected by
+// filter, oldest first. A missing log has none, and lines that can't be read
+// are skipped.
+func (l *Log) Entries(filter Filter) ([]Entry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	paths, err := logfile.Backups(l.path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, path := range append(paths, l.path) {
+		entries, err = readEntries(path, filter, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.redact != nil {
+		for i := range entries {
+			entries[i] = entries[i].redacted(l.redact)
+		}
+	}
+	return entries, nil
+}
+
+// redacted returns entry with the text that can hold secrets masked by
+// redact. Dir is kept, as --project selects entries by it.
+func (entry Entry) redacted(redact func(string) string) Entry {
+	entry.Repo = redact(entry.Repo)
+	entry.Branch = redact(entry.Branch)
+	entry.Request = redact(entry.Request)
+	entry.Risk = redact(entry.Risk)
+	entry.Label = redact(entry.Label)
+	if entry.Dialog != nil {
+		dialog := make([]string, len(entry.Dialog))
+		for i, line := range entry.Dialog {
+			dialog[i] = redact(line)
+		}
+		entry.Dialog = dialog
+	}
+	return entry
+}
+
+// readEntries appends the entries in the file at path selected by filter to
+// entries. A missing file has none.
+func readEntries(path string, filter Filter, entries []Entry) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var entry Entry
+		if len(line) > 0 && json.Unmarshal(line, &entry) == nil && filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/logfile"
+)
+
+var (
+	now    = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	first  = Entry{ID: "0000aaaa", Time: now, Source: SourceWrapper, Session: 42, Dir: "/repo", Prompt: "permission", Tool: "Bash", Request: "Bash: git push --force", Risk: "high (force push)", Choice: "3", Label: "No", Action: Rejected, DecidedBy: "deny rule 1", Mode: "dialog", LatencyMs: 250, Dialog: []string{"│ Bash command │", "│ ❯ 1. Yes │"}}
+	second = Entry{ID: "0000bbbb", Time: now.Add(24 * time.Hour), Source: SourceWrapper, Session: 43, Dir: "/other", Branch: "release/1.2", Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8", Prompt: "permission", Tool: "Read", Request: "Read: /other/go.mod", Risk: "low", Choice: "1", Label: "Yes", Action: Approved, DecidedBy: User, Mode: "dialog", LatencyMs: 4100}
+)
+
+// withoutChain returns entries with the fields chaining them cleared
+func withoutChain(entries ...Entry) []Entry {
+	for i := range entries {
+		entries[i].Seq, entries[i].Prev, entries[i].Hash = 0, "", ""
+	}
+	return entries
+}
+
+func TestLogAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcode", "audit.jsonl")
+	log := NewLog(path)
+
+	if entries, err := log.Entries(Filter{}); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected an empty log, got %v, %v", entries, err)
+	}
+	if err := log.Add(first); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Another process appending to the same file
+	if err := NewLog(path).Add(second); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	entries, err := log.Entries(Filter{})
+	if err != nil || !reflect.DeepEqual(withoutChain(entries...), []Entry{first, second}) {
+		t.Fatalf("Expected both entries in order, got %+v, %v", entries, err)
+	}
+	if entry, ok, err := log.Find("0000bbbb"); err != nil || !ok || !reflect.DeepEqual(withoutChain(entry)[0], second) {
+		t.Errorf("Expected to find the second entry, got %+v, %v, %v", entry, ok, err)
+	}
+	if _, ok, err := log.Find("ffffffff"); err != nil || ok {
+		t.Errorf("Expected no entry, got %v, %v", ok, err)
+	}
+}
+
+func TestLogReadsRotatedCopies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path)
+	// Every entry takes the log past a byte, so each is rotated aside by the next
+	log.SetRotation(logfile.Rotation{MaxBytes: 1})
+
+	for _, entry := range []Entry{first, second, first} {
+		if err := log.Add(entry); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if backups, err := logfile.Backups(path); err != nil || len(backups) != 2 {
+		t.Fatalf("Expected two rotated copies, got %v, %v", backups, err)
+	}
+	entries, err := log.Entries(Filter{})
+	if err != nil || !reflect.DeepEqual(withoutChain(entries...), []Entry{first, second, first}) {
+		t.Errorf("Expected every entry in order, got %+v, %v", entries, err)
+	}
+}
+
+func TestLogSkipsUnreadableLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte("not json\n{\"id\":\"0000aaaa\",\"action\":\"approved\"}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewLog(path).Entries(Filter{})
+	if err != nil || len(entries) != 1 || entries[0].Action != Approved {
+		t.Errorf("Expected the readable entry, got %+v, %v", entries, err)
+	}
+}
+
+func TestLogRedactsEntriesRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path)
+	if err := log.Add(first); err != nil {
+		t.Fatal(err)
+	}
+	log.SetRedact(func(text string) string { return strings.ReplaceAll(text, "--force", "[FLAG]") })
+
+	entry, ok, err := log.Find(first.ID)
+	if err != nil || !ok {
+		t.Fatalf("Expected the entry, got %v, %v", ok, err)
+	}
+	if entry.Request != "Bash: git push [FLAG]" || entry.Dir != first.Dir || !reflect.DeepEqual(entry.Dialog, first.Dialog) {
+		t.Errorf("Expected the request to be masked, got %+v", entry)
+	}
+	if v, err := log.Verify(); err != nil || !v.OK() {
+		t.Errorf("Expected the chain to verify as written, got %+v, %v", v, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"Everything", Filter{}, []string{"0000aaaa", "0000bbbb"}},
+		{"Tool ignoring case", Filter{Tool: "bash"}, []string{"0000aaaa"}},
+		{"Action", Filter{Action: Approved}, []string{"0000bbbb"}},
+		{"Project", Filter{Dir: "/repo/"}, []string{"0000aaaa"}},
+		{"Branch", Filter{Branch: "release/1.2"}, []string{"0000bbbb"}},
+		{"Since", Filter{Since: now.Add(time.Hour)}, []string{"0000bbbb"}},
+		{"Until", Filter{Until: now.Add(time.Hour)}, []string{"0000aaaa"}},
+		{"Until is exclusive", Filter{Until: now}, nil},
+		{"Combined", Filter{Tool: "Bash", Action: Approved}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ids []string
+			for _, entry := range []Entry{first, second} {
+				if test.filter.Match(entry) {
+					ids = append(ids, entry.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, ids)
+			}
+		})
+	}
+}
+
+func TestEntryFormat(t *testing.T) {
+	line, err := json.Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(line, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"id", "time", "source", "session", "dir", "prompt", "tool", "request", "risk", "choice", "label", "action", "decided_by", "mode", "latency_ms", "dialog"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("Expected field %q in %s", name, line)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/takahirom/dialog-code/internal/logfile"
+)
+
+// HeadSuffix is appended to the log's path to get the file holding the
+// number and hash of the last entry written, so entries removed from the end
+// can be noticed
+const HeadSuffix = ".head"
+
+// hashField ends every chained line: the hash of the line before it
+var hashField = regexp.MustCompile(`,"hash":"([0-9a-f]{64})"}$`)
+
+// head is the last entry written to a chained log
+type head struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// Verification is what Verify found checking a log's hash chain
+type Verification struct {
+	Entries   int      // Chained entries checked
+	FirstSeq  int64    // Number of the first chained entry kept, or 0 if there are none
+	LastSeq   int64    // Number of the last chained entry
+	Unchained int      // Entries written before the chain began
+	Problems  []string // Signs of entries modified, removed, or inserted
+}
+
+// OK reports whether the chain is intact
+func (v Verification) OK() bool {
+	return len(v.Problems) == 0
+}
+
+// chainLine returns line, entry's JSON, numbered after last and ending with
+// the hash of everything before it and the last entry's hash
+func chainLine(entry Entry, last head) ([]byte, head, error) {
+	entry.Seq = last.Seq + 1
+	entry.Prev = last.Hash
+	entry.Hash = ""
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, head{}, err
+	}
+	hash := lineHash(line[:len(line)-1])
+	line = append(line[:len(line)-1], fmt.Sprintf(`,"hash":%q}`, hash)...)
+	return line, head{Seq: entry.Seq, Hash: hash}, nil
+}
+
+// lineHash returns the hash of a line up to where its hash field starts
+func lineHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// readHead returns the head recorded in file, or the zero head for an empty
+// file or a new log
+func readHead(file *os.File) (head, error) {
+	data, err := io.ReadAll(file)
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return head{}, err
+	}
+	var h head
+	if err := json.Unmarshal(data, &h); err != nil {
+		return head{}, fmt.Errorf("invalid %s: %w", file.Name(), err)
+	}
+	return h, nil
+}
+
+// writeHead replaces what file holds with h
+func writeHead(file *os.File, h head) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(append(data, '\n'), 0)
+	return err
+}
+
+// Verify checks the hash chain of the log and its rotated copies. It finds
+// entries that were modified, removed, or inserted, including entries removed
+// from the end, which the head file still counts. Entries at the start may be
+// missing without a problem, since rotation deletes old copies.
+func (l *Log) Verify() (Verification, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var v Verification
+	paths, err := logfile.Backups(l.path)
+	if err != nil {
+		return v, err
+	}
+	var last head
+	for _, path := range append(paths, l.path) {
+		if last, err = verifyFile(path, last, &v); err != nil {
+			return v, err
+		}
+	}
+
+	data, err := os.ReadFile(l.path + HeadSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		if v.Entries > 0 {
+			v.Problems = append(v.Problems, fmt.Sprintf("%s is missing, so entries removed from the end can't be noticed", filepath.Base(l.path+HeadSuffix)))
+		}
+		return v, nil
+	}
+	if err != nil {
+		return v, err
+	}
+	var recorded head
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		v.Problems = append(v.Problems, fmt.Sprintf("%s is unreadable: %v", filepath.Base(l.path+HeadSuffix), err))
+		return v, nil
+	}
+	switch {
+	case recorded.Seq > last.Seq:
+		v.Problems = append(v.Problems, fmt.Sprintf("the log ends at entry %d, but %d were written: entries were removed from the end", last.Seq, recorded.Seq))
+	case recorded != last:
+		v.Problems = append(v.Problems, fmt.Sprintf("the last entry, %d, isn't the one written last (entry %d)", last.Seq, recorded.Seq))
+	}
+	return v, nil
+}
+
+// verifyFile checks the chain through the file at path, which continues
+// from last, adding what it finds to v, and returns the file's last entry
+func verifyFile(path string, last head, v *Verification) (head, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return last, nil
+	}
+	if err != nil {
+		return last, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if len(line) > 0 {
+			last = verifyLine(line, fmt.Sprintf("%s:%d", filepath.Base(path), number), last, v)
+		}
+		if errors.Is(err, io.EOF) {
+			return last, nil
+		}
+		if err != nil {
+			return last, err
+		}
+	}
+}
+
+// verifyLine checks that line, at where, follows last in the chain, adding
+// what it finds to v, and returns the entry it holds as the new last
+func verifyLine(line []byte, where string, last head, v *Verification) head {
+	var entry Entry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		v.Problems = append(v.Problems, where+": unreadable entry")
+		return last
+	}
+	match := hashField.FindSubmatchIndex(line)
+	if match == nil {
+		if v.Entries == 0 {
+			v.Unchained++
+		} else {
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: entry %s was inserted without a hash", where, entry.ID))
+		}
+		return last
+	}
+
+	current := head{Seq: entry.Seq, Hash: string(line[match[2]:match[3]])}
+	if lineHash(line[:match[0]]) != current.Hash {
+		v.Problems = append(v.Problems, fmt.Sprintf("%s: entry %d (%s) was modified", where, entry.Seq, entry.ID))
+	}
+	if v.Entries == 0 {
+		v.FirstSeq = entry.Seq
+	} else {
+		switch {
+		case entry.Seq > last.Seq+1:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: entries %d to %d were removed", where, last.Seq+1, entry.Seq-1))
+		case entry.Seq <= last.Seq:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: entry %d (%s) was inserted or repeated after entry %d", where, entry.Seq, entry.ID, last.Seq))
+		case entry.Prev != last.Hash:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: entry %d (%s) doesn't follow the entry before it, which was replaced", where, entry.Seq, entry.ID))
+		}
+	}
+	v.Entries++
+	v.LastSeq = entry.Seq
+	return current
+}
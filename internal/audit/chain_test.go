@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/logfile"
+)
+
+// chainedLog returns the path of a log holding count entries
+func chainedLog(t *testing.T, count int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path)
+	for i := 0; i < count; i++ {
+		entry := first
+		entry.ID = fmt.Sprintf("%08x", i+1)
+		if err := log.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// editLines rewrites the lines of the file at path with edit
+func editLines(t *testing.T, path string, edit func(lines []string) []string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := edit(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyIntactChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path)
+	log.SetRotation(logfile.Rotation{MaxBytes: 1})
+	for _, entry := range []Entry{first, second, first} {
+		if err := log.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v, err := log.Verify()
+	if err != nil || !v.OK() || v.Entries != 3 || v.FirstSeq != 1 || v.LastSeq != 3 {
+		t.Errorf("Expected an intact chain of 3 entries across rotated copies, got %+v, %v", v, err)
+	}
+}
+
+func TestVerifyFindsTampering(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(t *testing.T, path string)
+		expected string
+	}{
+		{"Modified entry", func(t *testing.T, path string) {
+			editLines(t, path, func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"action":"rejected"`, `"action":"approved"`, 1)
+				return lines
+			})
+		}, "audit.jsonl:2: entry 2 (00000002) was modified"},
+		{"Removed entry", func(t *testing.T, path string) {
+			editLines(t, path, func(lines []string) []string { return append(lines[:1], lines[2:]...) })
+		}, "audit.jsonl:2: entries 2 to 2 were removed"},
+		{"Truncated end", func(t *testing.T, path string) {
+			editLines(t, path, func(lines []string) []string { return lines[:2] })
+		}, "the log ends at entry 2, but 3 were written"},
+		{"Inserted entry", func(t *testing.T, path string) {
+			editLines(t, path, func(lines []string) []string {
+				return append(lines[:2], append([]string{`{"id":"ffffffff","action":"approved"}`}, lines[2:]...)...)
+			})
+		}, "audit.jsonl:3: entry ffffffff was inserted without a hash"},
+		{"Replaced entry", func(t *testing.T, path string) {
+			// A whole entry rewritten with a hash that matches its new content
+			editLines(t, path, func(lines []string) []string {
+				line, _, _ := chainLine(second, head{Seq: 1, Hash: strings.Repeat("0", 64)})
+				lines[1] = string(line)
+				return lines
+			})
+		}, "audit.jsonl:3: entry 3 (00000003) doesn't follow the entry before it"},
+		{"Head removed", func(t *testing.T, path string) {
+			os.Remove(path + HeadSuffix)
+		}, "audit.jsonl.head is missing"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := chainedLog(t, 3)
+			test.tamper(t, path)
+
+			v, err := NewLog(path).Verify()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if v.OK() || !strings.Contains(strings.Join(v.Problems, "\n"), test.expected) {
+				t.Errorf("Expected a problem containing %q, got %q", test.expected, v.Problems)
+			}
+		})
+	}
+}
+
+func TestVerifyAllowsEarlierEntries(t *testing.T) {
+	// Entries from before the chain began, and a rotated copy deleted since
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"id":"00000000","action":"approved"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	log := NewLog(path)
+	log.SetRotation(logfile.Rotation{MaxBytes: 1})
+	for _, entry := range []Entry{first, second, first} {
+		if err := log.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := logfile.Backups(path)
+	if err != nil || len(backups) != 3 {
+		t.Fatalf("Expected three rotated copies, got %v, %v", backups, err)
+	}
+	os.Remove(backups[0])
+	os.Remove(backups[1])
+
+	v, err := log.Verify()
+	if err != nil || !v.OK() || v.FirstSeq != 2 || v.LastSeq != 3 {
+		t.Errorf("Expected the chain to be intact from entry 2, got %+v, %v", v, err)
+	}
+
+	path = filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"id":"00000000","action":"approved"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewLog(path).Add(first); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := NewLog(path).Verify(); err != nil || !v.OK() || v.Unchained != 1 || v.Entries != 1 {
+		t.Errorf("Expected an entry from before the chain to be allowed, got %+v, %v", v, err)
+	}
+}
+
+func TestChainFromSeveralWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	var wg sync.WaitGroup
+	for writer := 0; writer < 4; writer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each Log stands for another dcode process
+			log := NewLog(path)
+			for i := 0; i < 10; i++ {
+				if err := log.Add(second); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, err := NewLog(path).Verify()
+	if err != nil || !v.OK() || v.Entries != 40 {
+		t.Errorf("Expected 40 chained entries, got %+v, %v", v, err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile waits for an exclusive lock on file, shared with other processes,
+// which is released when file is closed
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
@@ -1,11 +1,12 @@
 package choice
 
 import (
-	"fmt"
 	"strings"
 
+	"github.com/takahirom/dialog-code/internal/config"
 	"github.com/takahirom/dialog-code/internal/debug"
 	"github.com/takahirom/dialog-code/internal/types"
+	"github.com/takahirom/dialog-code/pkg/parser"
 )
 
 // cleanDialogText removes pipe characters, unicode whitespace, and dialog box decorations from text
@@ -15,13 +16,14 @@ func cleanDialogText(text string) string {
 	return strings.TrimSpace(cleanText)
 }
 
-// GetBestChoice determines the best choice number based on collected choices
+// GetBestChoice determines the best choice number based on collected choices.
+// Approving once is preferred; "don't ask again" style choices are only picked
+// as a last resort so a single approval never becomes a permanent rule.
 func GetBestChoice(choices map[string]string, regexPatterns *types.RegexPatterns) string {
-	// For Claude permissions: Priority is "Allow" > first available choice
-	for num, text := range choices {
-		if regexPatterns.ChoiceYes.MatchString(text) {
-			return num
-		}
+	info := choiceInfo(choices)
+
+	if num := info.FirstChoice(parser.ChoiceApproveOnce); num != "" {
+		return num
 	}
 
 	// Look for "Add a new rule" as second choice (often choice 1)
@@ -31,24 +33,84 @@ func GetBestChoice(choices map[string]string, regexPatterns *types.RegexPatterns
 		}
 	}
 
-	// Fallback to the first available choice
-	for num := 1; num <= 10; num++ {
-		numStr := fmt.Sprintf("%d", num)
-		if _, exists := choices[numStr]; exists {
-			return numStr
-		}
+	// Fallback to the first available choice, skipping approve-always choices
+	if num := info.FirstChoice(parser.ChoiceUnknown); num != "" {
+		return num
+	}
+	if numbers := info.ChoiceNumbers(); len(numbers) > 0 {
+		return numbers[0]
 	}
 
 	// Ultimate fallback
-	debug.Printf("[DEBUG] GetBestChoice: No valid choice found in %v, returning default \"1\"\n", choices)
+	debug.Debug("no valid choice found; choosing 1", "choices", choices)
 	return "1"
 }
 
+// Choose returns the choice number strategy, a config.DefaultChoice value or
+// a choice number, picks from choices. A number that isn't one of the choices
+// falls back to GetBestChoice.
+func Choose(choices map[string]string, strategy string, regexPatterns *types.RegexPatterns) string {
+	info := choiceInfo(choices)
+
+	switch strategy {
+	case config.DefaultChoiceBest, "":
+	case config.DefaultChoiceFirst:
+		if numbers := info.ChoiceNumbers(); len(numbers) > 0 {
+			return numbers[0]
+		}
+	case config.DefaultChoiceSafest:
+		if num := info.FirstChoice(parser.ChoiceApproveOnce); num != "" {
+			return num
+		}
+		if num := info.LastChoice(parser.ChoiceReject); num != "" {
+			return num
+		}
+	case config.DefaultChoiceNeverAlways:
+		for _, kind := range []parser.ChoiceKind{parser.ChoiceApproveOnce, parser.ChoiceUnknown} {
+			for _, num := range info.ChoiceNumbers() {
+				if parser.ClassifyChoice(info.Choices[num]) == kind && !Widens(info.Choices[num]) {
+					return num
+				}
+			}
+		}
+		if num := info.LastChoice(parser.ChoiceReject); num != "" {
+			return num
+		}
+	default:
+		if _, ok := choices[strategy]; ok {
+			return strategy
+		}
+	}
+	return GetBestChoice(choices, regexPatterns)
+}
+
+// Widens reports whether the choice labeled label changes Claude's own
+// permission rules beyond the request, as "don't ask again" and "Add a new
+// rule" do
+func Widens(label string) bool {
+	return parser.ClassifyChoice(label) == parser.ChoiceApproveAlways || strings.Contains(label, "Add a new rule")
+}
+
+// choiceInfo builds a DialogInfo from collected choices ("1. Yes") so they can be classified
+func choiceInfo(choices map[string]string) parser.DialogInfo {
+	info := parser.DialogInfo{Choices: make(map[string]string, len(choices))}
+	for num, text := range choices {
+		info.Choices[num] = strings.TrimPrefix(text, num+". ")
+	}
+	return info
+}
+
 // GetBestChoiceFromState determines the best choice number based on app state
 func GetBestChoiceFromState(state *types.AppState, regexPatterns *types.RegexPatterns) string {
 	return GetBestChoice(state.Prompt.CollectedChoices, regexPatterns)
 }
 
+// ChooseFromState returns the choice number strategy picks from the choices
+// collected in state
+func ChooseFromState(state *types.AppState, strategy string, regexPatterns *types.RegexPatterns) string {
+	return Choose(state.Prompt.CollectedChoices, strategy, regexPatterns)
+}
+
 // GetContextualMessage builds a more informative dialog message with context
 func GetContextualMessage(prompt string, context []string, regexPatterns *types.RegexPatterns) string {
 	// Remove pipe characters and extra whitespace from the main prompt
@@ -103,167 +165,11 @@ func GetContextualMessageWithReason(prompt string, context []string, triggerReas
 	return message
 }
 
-// safeStripAnsi safely strips ANSI codes with nil checks
-func safeStripAnsi(text string, regexPatterns *types.RegexPatterns) string {
-	if regexPatterns != nil && regexPatterns.AnsiEscape != nil {
-		return regexPatterns.StripAnsi(text)
-	}
-	return text
-}
-
-// extractTriggerText finds the trigger text from context or fallback line
-func extractTriggerText(context []string, triggerLine string, regexPatterns *types.RegexPatterns) string {
-	// Extract trigger text from context (first line that looks like trigger)
-	if len(context) > 0 {
-		for _, line := range context {
-			cleanLine := safeStripAnsi(line, regexPatterns)
-			cleanLine = strings.TrimSpace(cleanLine)
-			if strings.HasPrefix(cleanLine, "⏺") {
-				return cleanLine
-			}
-		}
-	}
-	
-	// Fallback to triggerLine if no trigger found in context
-	if triggerLine != "" {
-		triggerText := safeStripAnsi(triggerLine, regexPatterns)
-		triggerText = cleanDialogText(triggerText) // Clean pipe characters and decorations
-		return strings.TrimSpace(triggerText)
-	}
-	
-	return ""
-}
-
-// DialogBoxInfo holds parsed dialog box information
-type DialogBoxInfo struct {
-	CommandType    string
-	CommandDetails []string
-	QuestionLine   string
-}
-
-// parseDialogBox extracts command information from dialog box context
-func parseDialogBox(context []string, regexPatterns *types.RegexPatterns) DialogBoxInfo {
-	// Extract command information from context (contains the full dialog box)
-	dialogText := ""
-	for _, line := range context {
-		if strings.Contains(line, "╭") || strings.Contains(line, "│") || strings.Contains(line, "╰") {
-			dialogText += line + "\n"
-		}
-	}
-	
-	// Parse the dialog box content to extract command type and details
-	info := DialogBoxInfo{
-		CommandDetails: []string{},
-	}
-	
-	lines := strings.Split(dialogText, "\n")
-	inDialog := false
-	
-	for _, line := range lines {
-		cleanLine := safeStripAnsi(line, regexPatterns)
-		cleanLine = strings.Trim(cleanLine, "│ \t╭╮╰╯─")
-		cleanLine = strings.TrimSpace(cleanLine)
-		
-		if strings.Contains(line, "╭") || strings.Contains(line, "┌") {
-			inDialog = true
-			continue
-		}
-		if strings.Contains(line, "╰") || strings.Contains(line, "└") {
-			inDialog = false
-			continue
-		}
-		
-		if !inDialog || cleanLine == "" {
-			continue
-		}
-		
-		// Detect command type (first non-empty line in dialog)
-		if info.CommandType == "" && cleanLine != "" {
-			info.CommandType = cleanDialogText(cleanLine) // Additional cleaning
-			continue
-		}
-		
-		// Check if this line contains the question
-		if strings.Contains(cleanLine, "Do you want to proceed?") || 
-		   strings.Contains(cleanLine, "proceed?") ||
-		   strings.Contains(cleanLine, "continue?") {
-			info.QuestionLine = cleanDialogText(cleanLine) // Additional cleaning
-			continue
-		}
-		
-		// Skip choice lines (starting with numbers or bullets)
-		if strings.HasPrefix(cleanLine, "1.") || strings.HasPrefix(cleanLine, "2.") || 
-		   strings.HasPrefix(cleanLine, "3.") || strings.HasPrefix(cleanLine, "❯") ||
-		   strings.HasPrefix(cleanLine, "•") {
-			continue
-		}
-		
-		// Collect command details
-		if cleanLine != "" {
-			info.CommandDetails = append(info.CommandDetails, cleanDialogText(cleanLine)) // Additional cleaning
-		}
-	}
-	
-	return info
-}
-
-// formatCleanMessage builds the final clean dialog message format
-func formatCleanMessage(triggerText, timestamp, triggerReason string, dialogInfo DialogBoxInfo) string {
-	var messageParts []string
-	
-	// Add trigger information
-	if triggerText != "" {
-		messageParts = append(messageParts, "Trigger text: "+triggerText)
-	}
-	
-	// Add timestamp
-	if timestamp != "" {
-		messageParts = append(messageParts, "Trigger timestamp: "+timestamp)
-	}
-	
-	// Add reason
-	if triggerReason != "" {
-		messageParts = append(messageParts, "Reason: "+triggerReason)
-	}
-	
-	// Add separator
-	messageParts = append(messageParts, "───────────────────────────────────")
-	
-	// Add command type
-	if dialogInfo.CommandType != "" {
-		messageParts = append(messageParts, dialogInfo.CommandType)
-		messageParts = append(messageParts, "") // Empty line
-	}
-	
-	// Add command details with proper indentation
-	for _, detail := range dialogInfo.CommandDetails {
-		messageParts = append(messageParts, "  "+detail)
-	}
-	
-	if len(dialogInfo.CommandDetails) > 0 {
-		messageParts = append(messageParts, "") // Empty line after details
-	}
-	
-	// Add the question
-	questionLine := dialogInfo.QuestionLine
-	if questionLine == "" {
-		questionLine = "Do you want to proceed?"
-	}
-	messageParts = append(messageParts, questionLine)
-	
-	return strings.Join(messageParts, "\n")
-}
-
 // GetCleanDialogMessage creates a clean, organized dialog message format
 // This function extracts context information and presents it in a structured way
-// without the "Context:" header for dialog display
+// without the "Context:" header for dialog display. The formatting lives in
+// pkg/parser so other tools can reuse it; prompt and regexPatterns are kept for
+// compatibility.
 func GetCleanDialogMessage(prompt string, context []string, triggerReason string, triggerLine string, timestamp string, regexPatterns *types.RegexPatterns) string {
-	triggerText := extractTriggerText(context, triggerLine, regexPatterns)
-	dialogInfo := parseDialogBox(context, regexPatterns)
-	return formatCleanMessage(triggerText, timestamp, triggerReason, dialogInfo)
-}
-
-// ParseDialogBox extracts command information from dialog box context (public wrapper)
-func ParseDialogBox(context []string, regexPatterns *types.RegexPatterns) DialogBoxInfo {
-	return parseDialogBox(context, regexPatterns)
+	return parser.CleanMessage(context, triggerLine, triggerReason, timestamp)
 }
@@ -0,0 +1,36 @@
+package choice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/types"
+)
+
+func FuzzGetCleanDialogMessage(f *testing.F) {
+	f.Add("│   rm test-file   │", "⏺ Bash(rm test-file)\n╭───╮\n│ Bash command │\n│ Do you want to proceed? │\n╰───╯")
+	f.Add("", "")
+	f.Add("\x1b[31m│", "╭\n│ │ 1 - x │ │\n╰\n\xff")
+
+	patterns := types.NewRegexPatterns()
+	f.Fuzz(func(t *testing.T, triggerLine, context string) {
+		message := GetCleanDialogMessage(triggerLine, strings.Split(context, "\n"), "reason", triggerLine, "123", patterns)
+		if !strings.Contains(message, "───") {
+			t.Errorf("Unexpected message format: %q", message)
+		}
+	})
+}
+
+func FuzzGetBestChoice(f *testing.F) {
+	f.Add("1. Yes", "2. No")
+	f.Add("1. Yes, and don't ask again", "")
+	f.Add("\xff", "2. はい、")
+
+	patterns := types.NewRegexPatterns()
+	f.Fuzz(func(t *testing.T, first, second string) {
+		choices := map[string]string{"1": first, "2": second}
+		if best := GetBestChoice(choices, patterns); best != "1" && best != "2" {
+			t.Errorf("GetBestChoice returned %q, not one of the choices", best)
+		}
+	})
+}
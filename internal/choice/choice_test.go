@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/takahirom/dialog-code/internal/config"
 	"github.com/takahirom/dialog-code/internal/types"
 )
 
@@ -55,6 +56,64 @@ func TestGetBestChoice(t *testing.T) {
 	})
 }
 
+func TestChoose(t *testing.T) {
+	patterns := types.NewRegexPatterns()
+	bash := map[string]string{
+		"1": "1. Yes",
+		"2": "2. Yes, and don't ask again for npm commands",
+		"3": "3. No, and tell Claude what to do differently (esc)",
+	}
+	alwaysOnly := map[string]string{
+		"1": "1. Yes, and don't ask again this session",
+		"2": "2. Add a new rule",
+		"3": "3. No",
+	}
+
+	testCases := []struct {
+		name     string
+		choices  map[string]string
+		strategy string
+		expected string
+	}{
+		{"best", bash, config.DefaultChoiceBest, "1"},
+		{"unset", alwaysOnly, "", "2"},
+		{"first", alwaysOnly, config.DefaultChoiceFirst, "1"},
+		{"safest approves once", bash, config.DefaultChoiceSafest, "1"},
+		{"safest rejects otherwise", alwaysOnly, config.DefaultChoiceSafest, "3"},
+		{"never don't ask again", bash, config.DefaultChoiceNeverAlways, "1"},
+		{"never don't ask again rejects otherwise", alwaysOnly, config.DefaultChoiceNeverAlways, "3"},
+		{"choice number", bash, "2", "2"},
+		{"missing choice number", bash, "5", "1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := Choose(tc.choices, tc.strategy, patterns); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestWidens(t *testing.T) {
+	testCases := []struct {
+		label    string
+		expected bool
+	}{
+		{"Yes", false},
+		{"Yes, and don't ask again for npm commands", true},
+		{"Yes, allow all edits during this session", true},
+		{"Add a new rule", true},
+		{"No, and tell Claude what to do differently (esc)", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			if result := Widens(tc.label); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
 func TestGetBestChoiceFromState(t *testing.T) {
 	state := types.NewAppState()
 	patterns := types.NewRegexPatterns()
@@ -105,3 +164,48 @@ func TestGetContextualMessage(t *testing.T) {
 		t.Error("Message should contain permission-related context")
 	}
 }
+
+func TestGetBestChoice_Semantic(t *testing.T) {
+	patterns := types.NewRegexPatterns()
+
+	testCases := []struct {
+		name     string
+		choices  map[string]string
+		expected string
+	}{
+		{
+			name: "never picks don't ask again when a one-time approval exists",
+			choices: map[string]string{
+				"1": "1. Yes, and don't ask again for rm commands",
+				"2": "2. Yes",
+				"3": "3. No, and tell Claude what to do differently (esc)",
+			},
+			expected: "2",
+		},
+		{
+			name: "plan approval prefers manually approving edits",
+			choices: map[string]string{
+				"1": "1. Yes, and auto-accept edits",
+				"2": "2. Yes, and manually approve edits",
+				"3": "3. No, keep planning",
+			},
+			expected: "2",
+		},
+		{
+			name: "falls back to approve-always only when nothing else approves",
+			choices: map[string]string{
+				"1": "1. Yes, and don't ask again",
+				"2": "2. No",
+			},
+			expected: "1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := GetBestChoice(tc.choices, patterns); result != tc.expected {
+				t.Errorf("Expected choice %s, got %q", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package claudesettings reads and updates the permission rules in Claude
+// Code's settings files, such as a project's .claude/settings.json, so Claude
+// itself stops asking about requests the user has already decided on.
+package claudesettings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Permission lists in a settings file
+const (
+	ListAllow = "allow"
+	ListDeny  = "deny"
+)
+
+// UserPath returns the settings file that applies to every project of the
+// user, in $CLAUDE_CONFIG_DIR or else ~/.claude under home
+func UserPath(home string) string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "settings.json")
+	}
+	return filepath.Join(home, ".claude", "settings.json")
+}
+
+// ProjectPath returns the settings file shared by everyone working on the
+// project in dir
+func ProjectPath(dir string) string {
+	return filepath.Join(dir, ".claude", "settings.json")
+}
+
+// LocalPath returns the settings file of the project in dir that only applies
+// to this checkout
+func LocalPath(dir string) string {
+	return filepath.Join(dir, ".claude", "settings.local.json")
+}
+
+// AddPermission adds entry to the permissions list named list in the
+// settings file at path, creating the file if it doesn't exist, and reports
+// whether it did; an entry already in the list is left alone. Other settings
+// are kept.
+func AddPermission(path, list, entry string) (bool, error) {
+	settings, err := read(path)
+	if err != nil {
+		return false, err
+	}
+
+	permissions, ok := settings["permissions"].(map[string]any)
+	if settings["permissions"] == nil {
+		permissions, ok = map[string]any{}, true
+		settings["permissions"] = permissions
+	}
+	if !ok {
+		return false, fmt.Errorf("invalid settings file %s: permissions must be an object", path)
+	}
+	entries, ok := permissions[list].([]any)
+	if permissions[list] != nil && !ok {
+		return false, fmt.Errorf("invalid settings file %s: permissions.%s must be a list", path, list)
+	}
+	for _, existing := range entries {
+		if existing == entry {
+			return false, nil
+		}
+	}
+	permissions[list] = append(entries, entry)
+
+	return true, write(path, settings)
+}
+
+// Hooks returns the events, such as PreToolUse, that the settings file at
+// path runs hooks for, sorted. A missing file has none.
+func Hooks(path string) ([]string, error) {
+	settings, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if settings["hooks"] == nil {
+		return nil, nil
+	}
+	hooks, ok := settings["hooks"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid settings file %s: hooks must be an object", path)
+	}
+
+	var events []string
+	for event, matchers := range hooks {
+		if list, ok := matchers.([]any); ok && len(list) > 0 {
+			events = append(events, event)
+		}
+	}
+	sort.Strings(events)
+	return events, nil
+}
+
+// read parses the settings file at path, or returns no settings if it
+// doesn't exist
+func read(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]any{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// write saves settings to path the way Claude Code formats the file
+func write(path string, settings map[string]any) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false) // Commands often contain & and >
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(settings); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
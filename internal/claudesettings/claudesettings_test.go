@@ -0,0 +1,95 @@
+package claudesettings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddPermission(t *testing.T) {
+	path := ProjectPath(t.TempDir())
+
+	added, err := AddPermission(path, ListAllow, "Bash(go test:*)")
+	if err != nil || !added {
+		t.Fatalf("Expected the entry to be added to a new file, got %v, %v", added, err)
+	}
+	if added, err = AddPermission(path, ListAllow, "Bash(go test:*)"); err != nil || added {
+		t.Errorf("Expected an existing entry to be left alone, got %v, %v", added, err)
+	}
+	if _, err := AddPermission(path, ListDeny, "Bash(rm -rf build && ls)"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	expected := `{
+  "permissions": {
+    "allow": [
+      "Bash(go test:*)"
+    ],
+    "deny": [
+      "Bash(rm -rf build && ls)"
+    ]
+  }
+}
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestAddPermissionKeepsOtherSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	content := `{"model": "opus", "permissions": {"allow": ["Read"], "defaultMode": "plan"}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AddPermission(path, ListAllow, "WebSearch"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, kept := range []string{`"model": "opus"`, `"defaultMode": "plan"`, `"Read",`, `"WebSearch"`} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("Expected %s in:\n%s", kept, data)
+		}
+	}
+}
+
+func TestAddPermissionRejectsInvalidFiles(t *testing.T) {
+	for _, content := range []string{`{`, `{"permissions": []}`, `{"permissions": {"allow": "Read"}}`} {
+		path := filepath.Join(t.TempDir(), "settings.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AddPermission(path, ListAllow, "Read"); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+		if data, _ := os.ReadFile(path); string(data) != content {
+			t.Errorf("Expected %s to be unchanged, got %s", content, data)
+		}
+	}
+}
+
+func TestHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if events, err := Hooks(path); err != nil || len(events) != 0 {
+		t.Errorf("Expected no hooks without a file, got %v, %v", events, err)
+	}
+
+	content := `{"hooks": {"PreToolUse": [{"matcher": "Bash", "hooks": [{"type": "command", "command": "check.sh"}]}], "Stop": [], "Notification": [{"hooks": []}]}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events, err := Hooks(path)
+	if err != nil || strings.Join(events, ",") != "Notification,PreToolUse" {
+		t.Errorf("Expected hooks for Notification and PreToolUse, got %v, %v", events, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"hooks": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Hooks(path); err == nil {
+		t.Error("Expected an error for hooks that aren't an object")
+	}
+}
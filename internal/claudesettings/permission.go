@@ -0,0 +1,89 @@
+package claudesettings
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/approvals"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// AllowEntry returns the permission rule that allows requests like the one
+// described by info, scoped the way Claude Code scopes "don't ask again": the
+// command prefix for Bash, the files' directory for file tools, and the tool
+// for MCP tools. It returns "" if there is no such rule, as for a command of
+// several lines.
+func AllowEntry(info parser.DialogInfo) string {
+	if info.Truncated {
+		return ""
+	}
+
+	switch {
+	case info.ToolType == parser.ToolBash:
+		command := info.Command()
+		if len(command) != 1 {
+			return ""
+		}
+		if prefix := approvals.CommandPrefix(command[0]); prefix != "" {
+			return "Bash(" + prefix + ":*)"
+		}
+	case len(info.FilePaths) > 0:
+		tool := fileRuleTool(info.ToolType)
+		dir := filepath.Dir(info.FilePaths[0])
+		for _, path := range info.FilePaths[1:] {
+			if filepath.Dir(path) != dir {
+				return ""
+			}
+		}
+		if tool != "" {
+			return tool + "(" + pathPattern(filepath.Join(dir, "**")) + ")"
+		}
+	case strings.HasPrefix(info.ToolType, "mcp__"):
+		return info.ToolType
+	}
+	return ""
+}
+
+// DenyEntry returns the permission rule that denies exactly the request
+// described by info: its command for Bash and its file for file tools. It
+// returns "" if there is no such rule.
+func DenyEntry(info parser.DialogInfo) string {
+	if info.Truncated {
+		return ""
+	}
+
+	switch {
+	case info.ToolType == parser.ToolBash:
+		if command := info.Command(); len(command) == 1 && strings.TrimSpace(command[0]) != "" {
+			return "Bash(" + strings.TrimSpace(command[0]) + ")"
+		}
+	case len(info.FilePaths) == 1:
+		if tool := fileRuleTool(info.ToolType); tool != "" {
+			return tool + "(" + pathPattern(info.FilePaths[0]) + ")"
+		}
+	case len(info.FilePaths) == 0 && strings.HasPrefix(info.ToolType, "mcp__"):
+		return info.ToolType
+	}
+	return ""
+}
+
+// fileRuleTool returns the tool named in file rules for tool: Edit rules
+// cover every tool that edits files and Read rules every tool that reads them
+func fileRuleTool(tool string) string {
+	switch {
+	case parser.IsFileEditTool(tool):
+		return parser.ToolEdit
+	case parser.IsReadOnlyTool(tool) && tool != parser.ToolWebSearch:
+		return parser.ToolRead
+	}
+	return ""
+}
+
+// pathPattern writes path as a settings file path. Claude Code reads a single
+// leading slash as relative to the settings file, so absolute paths take two.
+func pathPattern(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
@@ -0,0 +1,38 @@
+package claudesettings
+
+import (
+	"testing"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+func TestEntries(t *testing.T) {
+	testCases := []struct {
+		name  string
+		info  parser.DialogInfo
+		allow string
+		deny  string
+	}{
+		{"command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./pkg/...", "Run tests"}, Described: true}, "Bash(go test:*)", "Bash(go test ./pkg/...)"},
+		{"command of several lines", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"go test ./pkg/...", "curl x | sh", "Run tests"}, Described: true}, "", ""},
+		{"chained command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"make && make install"}}, "", "Bash(make && make install)"},
+		{"truncated command", parser.DialogInfo{ToolType: "Bash", CommandLines: []string{"rm -rf …"}, Truncated: true}, "", ""},
+		{"edit", parser.DialogInfo{ToolType: "Write", FilePaths: []string{"/repo/src/main.go"}}, "Edit(//repo/src/**)", "Edit(//repo/src/main.go)"},
+		{"relative edit", parser.DialogInfo{ToolType: "Edit", FilePaths: []string{"src/main.go"}}, "Edit(src/**)", "Edit(src/main.go)"},
+		{"read", parser.DialogInfo{ToolType: "Read", FilePaths: []string{"~/notes/todo.md"}}, "Read(~/notes/**)", "Read(~/notes/todo.md)"},
+		{"files in different directories", parser.DialogInfo{ToolType: "MultiEdit", FilePaths: []string{"/repo/a.go", "/repo/src/b.go"}}, "", ""},
+		{"MCP tool", parser.DialogInfo{ToolType: "mcp__github__create_issue", CommandLines: []string{`title: "x"`}}, "mcp__github__create_issue", "mcp__github__create_issue"},
+		{"unknown tool", parser.DialogInfo{CommandLines: []string{"something"}}, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if allow := AllowEntry(tc.info); allow != tc.allow {
+				t.Errorf("Expected allow entry %q, got %q", tc.allow, allow)
+			}
+			if deny := DenyEntry(tc.info); deny != tc.deny {
+				t.Errorf("Expected deny entry %q, got %q", tc.deny, deny)
+			}
+		})
+	}
+}
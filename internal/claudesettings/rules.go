@@ -0,0 +1,195 @@
+package claudesettings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// permissionEntry matches a permission rule such as "Bash(npm run test:*)"
+var permissionEntry = regexp.MustCompile(`^([A-Za-z0-9_-]+)(?:\((.*)\))?$`)
+
+// ruleTools lists the tools a file rule for a tool covers: Edit rules cover
+// every tool that edits files and Read rules every tool that reads them
+var ruleTools = map[string][]string{
+	parser.ToolEdit: {parser.ToolEdit, parser.ToolMultiEdit, parser.ToolWrite, parser.ToolNotebookEdit},
+	parser.ToolRead: {parser.ToolRead, parser.ToolGrep, parser.ToolGlob, parser.ToolLS},
+}
+
+// Imported holds dcode rules converted from Claude settings
+type Imported struct {
+	Approve []config.Rule
+	Deny    []config.DenyRule
+	Skipped []string // Entries dcode can't express, such as WebFetch domains
+}
+
+// Import converts the allow and deny lists of the project settings files in
+// root into dcode rules. Relative paths in file rules are resolved against
+// cwd, the directory Claude runs in. Missing files are skipped.
+func Import(root, cwd string) (Imported, error) {
+	var imported Imported
+	for _, path := range []string{ProjectPath(root), LocalPath(root)} {
+		settings, err := read(path)
+		if err != nil {
+			return Imported{}, err
+		}
+		permissions, _ := settings["permissions"].(map[string]any)
+
+		for _, entry := range stringList(permissions[ListDeny]) {
+			rules, ok := entryRules(entry, root, cwd, true)
+			if !ok {
+				imported.Skipped = append(imported.Skipped, entry)
+				continue
+			}
+			message := fmt.Sprintf("The command matches %s in the deny list of %s. Do not retry it; try a different approach.", entry, filepath.Base(path))
+			for _, rule := range rules {
+				imported.Deny = append(imported.Deny, config.DenyRule{Rule: rule, Message: message})
+			}
+		}
+		for _, entry := range stringList(permissions[ListAllow]) {
+			rules, ok := entryRules(entry, root, cwd, false)
+			if !ok {
+				imported.Skipped = append(imported.Skipped, entry)
+				continue
+			}
+			imported.Approve = append(imported.Approve, rules...)
+		}
+	}
+	return imported, nil
+}
+
+// stringList returns the strings in a JSON list, ignoring anything else
+func stringList(value any) []string {
+	items, _ := value.([]any)
+	var strs []string
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+// entryRules converts a permission entry into the dcode rules matching the
+// same requests, reporting whether it could. Allow entries never match
+// chained commands; deny entries also match a command chained after another.
+func entryRules(entry, root, cwd string, deny bool) ([]config.Rule, bool) {
+	matches := permissionEntry.FindStringSubmatch(strings.TrimSpace(entry))
+	if matches == nil {
+		return nil, false
+	}
+	tool, spec := matches[1], matches[2]
+	tools := ruleTools[tool]
+	if tools == nil {
+		tools = []string{tool}
+	}
+
+	var rules []config.Rule
+	switch {
+	case spec == "" || spec == "*":
+		// An MCP server entry covers all of its tools, which a rule can't say
+		if strings.HasPrefix(tool, "mcp__") && strings.Count(tool, "__") < 2 {
+			return nil, false
+		}
+		for _, tool := range tools {
+			rules = append(rules, config.Rule{Tool: tool})
+		}
+	case tool == parser.ToolBash:
+		rules = append(rules, config.Rule{Tool: tool, Command: commandPattern(spec, deny)})
+	case ruleTools[tool] != nil:
+		file, ok := filePattern(spec, root, cwd)
+		if !ok {
+			return nil, false
+		}
+		for _, tool := range tools {
+			rules = append(rules, config.Rule{Tool: tool, File: file})
+		}
+	default:
+		return nil, false
+	}
+	return rules, true
+}
+
+// commandPattern converts a Bash rule, an exact command or a prefix ending
+// in ":*", to a regular expression matching the command
+func commandPattern(spec string, deny bool) string {
+	prefix, isPrefix := strings.CutSuffix(spec, ":*")
+	quoted := regexp.QuoteMeta(strings.TrimSpace(prefix))
+
+	if deny {
+		if isPrefix {
+			return `(?:^|[;&|(]\s*)` + quoted + `(?:[\s;&|)]|$)`
+		}
+		return `(?:^|[;&|(]\s*)` + quoted + `\s*(?:[;&|)]|$)`
+	}
+	if isPrefix {
+		return `^` + quoted + "(?:\\s[^;&|<>`$\\n]*)?$"
+	}
+	return `^` + quoted + `$`
+}
+
+// filePattern converts a gitignore-style file rule to a regular expression
+// matching the absolute path, or a path relative to cwd, of a file it covers.
+// "//path" is absolute, "~/path" is in the home directory, "/path" is in the
+// project, and other paths are relative to cwd.
+func filePattern(spec, root, cwd string) (string, bool) {
+	var path string
+	switch {
+	case strings.HasPrefix(spec, "//"):
+		path = spec[1:]
+	case strings.HasPrefix(spec, "~/"):
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = home + spec[1:]
+	case strings.HasPrefix(spec, "/"):
+		path = root + spec
+	default:
+		path = cwd + "/" + strings.TrimPrefix(spec, "./")
+	}
+
+	if relative, ok := strings.CutPrefix(path, cwd+"/"); ok {
+		return `^(?:` + regexp.QuoteMeta(cwd+"/") + `)?` + globPattern(relative) + `$`, true
+	}
+	return `^` + globPattern(path) + `$`, true
+}
+
+// globPattern converts a glob to a regular expression. A path without
+// wildcards also covers everything under it, as it may name a directory.
+func globPattern(glob string) string {
+	if !strings.ContainsAny(glob, "*?") {
+		return regexp.QuoteMeta(strings.TrimSuffix(glob, "/")) + `(?:/.*)?`
+	}
+
+	var b strings.Builder
+	for rest := glob; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "**/"):
+			b.WriteString(`(?:.*/)?`)
+			rest = rest[3:]
+		case strings.HasPrefix(rest, "**"):
+			b.WriteString(`.*`)
+			rest = rest[2:]
+		case rest[0] == '*':
+			b.WriteString(`[^/]*`)
+			rest = rest[1:]
+		case rest[0] == '?':
+			b.WriteString(`[^/]`)
+			rest = rest[1:]
+		default:
+			end := strings.IndexAny(rest, "*?")
+			if end < 0 {
+				end = len(rest)
+			}
+			b.WriteString(regexp.QuoteMeta(rest[:end]))
+			rest = rest[end:]
+		}
+	}
+	return b.String()
+}
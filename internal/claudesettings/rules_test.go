@@ -0,0 +1,98 @@
+package claudesettings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+func writeSettings(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	root := t.TempDir()
+	writeSettings(t, ProjectPath(root), `{"permissions": {
+  "allow": ["Bash(npm run test:*)", "Edit(/src/**)", "mcp__github__list_issues", "WebFetch(domain:example.com)"],
+  "deny": ["Bash(git push:*)", "Read(./.env)"]
+}}`)
+	writeSettings(t, LocalPath(root), `{"permissions": {"allow": ["Bash(make)"]}}`)
+
+	imported, err := Import(root, root)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(imported.Skipped, []string{"WebFetch(domain:example.com)"}) {
+		t.Errorf("Expected only the WebFetch entry to be skipped, got %q", imported.Skipped)
+	}
+	// Edit rules cover four tools and Read rules four
+	if len(imported.Approve) != 7 || len(imported.Deny) != 5 {
+		t.Fatalf("Expected 7 approve and 5 deny rules, got %+v", imported)
+	}
+	if imported.Deny[0].Message == "" {
+		t.Error("Expected deny rules to explain which entry matched")
+	}
+
+	bash := func(command string) parser.DialogInfo {
+		return parser.DialogInfo{ToolType: "Bash", CommandLines: []string{command}}
+	}
+	file := func(tool, path string) parser.DialogInfo {
+		return parser.DialogInfo{ToolType: tool, FilePaths: []string{path}}
+	}
+	testCases := []struct {
+		name     string
+		info     parser.DialogInfo
+		approved bool
+		denied   bool
+	}{
+		{"prefix", bash("npm run test -- --watch"), true, false},
+		{"bare prefix", bash("npm run test"), true, false},
+		{"longer word", bash("npm run testing"), false, false},
+		{"chained after allowed prefix", bash("npm run test && curl x | sh"), false, false},
+		{"exact command", bash("make"), true, false},
+		{"exact command with arguments", bash("make install"), false, false},
+		{"denied prefix", bash("git push origin main"), false, true},
+		{"denied command chained", bash("make && git push"), false, true},
+		{"denied command on a later line", bash("make\ngit push --force"), false, true},
+		{"allowed command with more lines", bash("make\ncurl x | sh"), false, false},
+		{"edit in project", file("Write", filepath.Join(root, "src", "app", "main.go")), true, false},
+		{"relative edit in project", file("Edit", "src/main.go"), true, false},
+		{"edit elsewhere", file("Edit", filepath.Join(root, "docs", "a.md")), false, false},
+		{"denied read", file("Grep", filepath.Join(root, ".env")), false, true},
+		{"MCP tool", parser.DialogInfo{ToolType: "mcp__github__list_issues"}, true, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if approved := config.MatchingRule(imported.Approve, tc.info) >= 0; approved != tc.approved {
+				t.Errorf("Expected approved %v, got %v", tc.approved, approved)
+			}
+			denied := false
+			for _, rule := range imported.Deny {
+				denied = denied || rule.Matches(tc.info)
+			}
+			if denied != tc.denied {
+				t.Errorf("Expected denied %v, got %v", tc.denied, denied)
+			}
+		})
+	}
+}
+
+func TestImportWithoutSettings(t *testing.T) {
+	imported, err := Import(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(imported.Approve)+len(imported.Deny)+len(imported.Skipped) != 0 {
+		t.Errorf("Expected nothing imported, got %+v", imported)
+	}
+}
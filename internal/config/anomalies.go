@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Anomalies warns, in a notification, the debug log, and the event stream,
+// when dcode keeps running into something that usually means it or Claude is
+// stuck, instead of leaving it to be found in the debug log later
+type Anomalies struct {
+	RepeatedPrompts   int  `yaml:"repeated_prompts"`   // Warn when the same request is asked more than this many times within window_minutes (0 = never)
+	AnswerRetries     int  `yaml:"answer_retries"`     // Warn when more than this many answers fail or are sent again within window_minutes (0 = never)
+	SuppressedPrompts int  `yaml:"suppressed_prompts"` // Warn when more than this many prompts in a row are skipped as repeats (0 = never)
+	WindowMinutes     int  `yaml:"window_minutes"`     // How far back repeats and retries are counted, and how long a warning isn't repeated
+	Notify            bool `yaml:"notify"`             // Post a notification as well
+}
+
+// DefaultAnomalies warns about a request asked more than 5 times or more
+// than 3 answer retries in 10 minutes, and more than 20 prompts skipped in a
+// row
+func DefaultAnomalies() Anomalies {
+	return Anomalies{
+		RepeatedPrompts:   5,
+		AnswerRetries:     3,
+		SuppressedPrompts: 20,
+		WindowMinutes:     10,
+		Notify:            true,
+	}
+}
+
+// Window returns WindowMinutes as a duration
+func (a Anomalies) Window() time.Duration {
+	return time.Duration(a.WindowMinutes) * time.Minute
+}
+
+// validate reports a negative threshold, or a window too short to count in
+func (a Anomalies) validate() error {
+	for _, option := range []struct {
+		name  string
+		value int
+	}{
+		{"repeated_prompts", a.RepeatedPrompts},
+		{"answer_retries", a.AnswerRetries},
+		{"suppressed_prompts", a.SuppressedPrompts},
+	} {
+		if option.value < 0 {
+			return fmt.Errorf("invalid anomalies.%s value: %d (must not be negative)", option.name, option.value)
+		}
+	}
+	if a.WindowMinutes < 1 {
+		return fmt.Errorf("invalid anomalies.window_minutes value: %d (must be at least 1)", a.WindowMinutes)
+	}
+	return nil
+}
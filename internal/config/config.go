@@ -0,0 +1,390 @@
+// Package config holds dcode's options and loads them from a YAML file.
+// Command-line flags are applied on top of the loaded values.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/takahirom/dialog-code/internal/debug"
+	"github.com/takahirom/dialog-code/internal/dialog"
+	"github.com/takahirom/dialog-code/internal/i18n"
+	"github.com/takahirom/dialog-code/internal/systemlog"
+	"github.com/takahirom/dialog-code/internal/types"
+)
+
+// Continuation prompt handling modes for continue_prompts
+const (
+	ContinuePromptsIgnore = "ignore" // Leave the prompt for the user
+	ContinuePromptsAuto   = "auto"   // Send Enter automatically
+	ContinuePromptsDialog = "dialog" // Show an OK dialog, then send Enter
+)
+
+// Debug log record formats for log_format
+const (
+	LogFormatText = "text" // key=value pairs
+	LogFormatJSON = "json" // One JSON object per record
+)
+
+// Choices auto_reject_wait_choice can pick when the countdown ends, besides a
+// choice number
+const (
+	TimeoutChoiceTellClaude = "tell_claude" // The last reject choice, followed by the rejection message
+	TimeoutChoiceNo         = "no"          // The first reject choice, such as a plain "No", without a message
+)
+
+// Strategies default_choice can pick the choice an automatic approval sends
+// by, besides a choice number
+const (
+	DefaultChoiceBest        = "best"                 // Approve once, then "Add a new rule", then the first choice that isn't "don't ask again"
+	DefaultChoiceSafest      = "safest"               // Approve once, or else reject
+	DefaultChoiceFirst       = "first"                // The first choice, whatever it says
+	DefaultChoiceNeverAlways = "never-dont-ask-again" // Like best, but never "don't ask again" or "Add a new rule"; reject if nothing else is left
+)
+
+// Default delays, in milliseconds
+const (
+	DefaultAutoApproveDelayMs       = 100
+	DefaultChoiceProcessingDelayMs  = 300
+	DefaultDialogResetDelayMs       = 3000
+	DefaultAutoRejectProcessDelayMs = 500
+	DefaultAutoRejectChoiceDelayMs  = 500
+	DefaultAutoRejectCRDelayMs      = 6000
+	DefaultInputCharDelayMs         = 10
+	DefaultInputLineDelayMs         = 100
+	DefaultInputSubmitDelayMs       = 500
+)
+
+// DefaultDialogQuiescenceMs is how long output must stall before a dialog
+// without its bottom border is answered
+const DefaultDialogQuiescenceMs = 1500
+
+// DefaultRejectionLoopLimit is how many times in a row the same request can be
+// rejected without asking before dcode stops answering it
+const DefaultRejectionLoopLimit = 3
+
+// Config holds every dcode option. Keys in config.yaml match the
+// command-line flags, with underscores instead of dashes.
+type Config struct {
+	AutoApprove              bool              `yaml:"auto_approve"`
+	AllowReadOnly            bool              `yaml:"allow_read_only"` // Approve read-only tools such as Read and Grep without asking
+	AutoReject               bool              `yaml:"auto_reject"`
+	AutoRejectWait           int               `yaml:"auto_reject_wait"`        // Seconds to wait for the user before auto-rejecting (0 = disabled)
+	AutoRejectWaitChoice     string            `yaml:"auto_reject_wait_choice"` // What auto_reject_wait picks: a TimeoutChoice value or a choice number
+	AutoApproveWait          int               `yaml:"auto_approve_wait"`       // Seconds to wait for the user before auto-approving (0 = disabled)
+	DefaultChoice            string            `yaml:"default_choice"`          // How automatic approvals pick a choice: a DefaultChoice value or a choice number
+	RejectMessage            string            `yaml:"reject_message"`          // Template for what Claude is told about a rejection; see ExpandRejectMessage
+	StripColors              bool              `yaml:"strip_colors"`
+	PreventScrollbackClear   bool              `yaml:"prevent_scrollback_clear"`
+	Debug                    bool              `yaml:"debug"`
+	LogFile                  string            `yaml:"log_file"`   // Where the debug log is written; debug.DefaultPath if ""
+	LogLevel                 string            `yaml:"log_level"`  // Turns the log on with debug, info, warn, or error records and above; Debug logs every level
+	LogFormat                string            `yaml:"log_format"` // text or json
+	LogDir                   string            `yaml:"log_dir"`    // Directory relative log_file and audit_log paths are in; the working directory if ""
+	LogRotation              LogRotation       `yaml:"log_rotation"`
+	ContinuePrompts          string            `yaml:"continue_prompts"`
+	DisplayBackpressure      string            `yaml:"display_backpressure"`
+	Locale                   string            `yaml:"locale"`   // Comma-separated locales detected in addition to English
+	Language                 string            `yaml:"language"` // Language of what dcode writes in dialogs and to Claude: an i18n language, or auto for the environment's
+	TrustDirs                []string          `yaml:"trust_dirs"`
+	DialogQuiescenceMs       int               `yaml:"dialog_quiescence_ms"`
+	ApprovalCacheSeconds     int               `yaml:"approval_cache_seconds"`     // Approve requests identical to one approved in a dialog this recently (0 = disabled)
+	MaxAutoApprovals         int               `yaml:"max_auto_approvals"`         // Show a dialog after this many approvals in a row without asking (0 = no limit)
+	DigestMinutes            int               `yaml:"digest_minutes"`             // Notify of requests answered without asking at most this often (0 = never)
+	RejectionLoopLimit       int               `yaml:"rejection_loop_limit"`       // Stop rejecting a request rejected this many times in a row (0 = never)
+	TemporaryApprovalMinutes int               `yaml:"temporary_approval_minutes"` // Window of the dialog's "Approve for N minutes" button (0 = no button)
+	Patterns                 types.PatternPack `yaml:"patterns"`                   // Extra prompt phrases detected in every locale
+	Delays                   Delays            `yaml:"delays"`
+	Approve                  []Rule            `yaml:"approve"` // Dialogs approved without asking, unless their command was truncated
+	Deny                     []DenyRule        `yaml:"deny"`    // Dialogs rejected without asking; checked before Approve
+	Forbid                   []DenyRule        `yaml:"forbid"`  // Dialogs always rejected; no flag, rule, or answer in a dialog can approve them
+	ToolPolicy               ToolPolicy        `yaml:"tool_policy"`
+	Risk                     RiskPolicy        `yaml:"risk"`
+	Remote                   Remote            `yaml:"remote"`
+	Escalation               Escalation        `yaml:"escalation"`
+	Anomalies                Anomalies         `yaml:"anomalies"`
+	Digest                   Digest            `yaml:"digest"` // Summary of the previous day's answers sent once a day; read at startup
+	QuietHours               QuietHours        `yaml:"quiet_hours"`
+	EditScope                EditScope         `yaml:"edit_scope"`
+	Dialog                   DialogStyle       `yaml:"dialog"`                 // Title and application of dialogs and notifications
+	Policy                   PolicySource      `yaml:"policy"`                 // Organization policy added to Approve, Deny, Forbid, and ToolPolicy at startup
+	PolicyFailed             bool              `yaml:"-"`                      // Policy couldn't be fetched or verified, so no mode may approve without asking
+	SecretPatterns           []string          `yaml:"secret_patterns"`        // Masked in dialogs and logs, in addition to common secret formats
+	RedactionRules           []RedactionRule   `yaml:"redaction_rules"`        // Masked like SecretPatterns, each with its own mask
+	SyncSettings             bool              `yaml:"sync_settings"`          // Add "don't ask again" and "never" answers to the project's .claude/settings.json
+	ImportClaudeSettings     bool              `yaml:"import_claude_settings"` // Add the project's Claude permission lists to Approve and Deny
+	RememberDecisions        bool              `yaml:"remember_decisions"`     // After a dialog is answered, offer to add the answer as an approve or deny rule
+	ShowResponseTime         bool              `yaml:"show_response_time"`     // End dialogs with how long the user took to answer them on average this session
+	ModeFile                 bool              `yaml:"mode_file"`              // Switch to the mode written to ModeFileName in the project; read at startup
+	SafeChoices              bool              `yaml:"safe_choices"`           // Never send a "don't ask again" or "Add a new rule" choice, even one picked in a dialog
+	AuditLog                 string            `yaml:"audit_log"`              // Append every answered prompt to this JSON Lines file; read at startup
+	ScreenshotDir            string            `yaml:"screenshot_dir"`         // Save a screenshot of each dialog to this directory, named in its audit entry; macOS only; read at startup
+	OTLPEndpoint             string            `yaml:"otlp_endpoint"`          // Send a trace of answering each prompt to this OpenTelemetry collector; read at startup
+	EventStream              string            `yaml:"event_stream"`           // Write each prompt's lifecycle events as NDJSON to this file or descriptor number; read at startup
+	Syslog                   string            `yaml:"syslog"`                 // Mirror every answered prompt to the system log under this facility, e.g. "local0"; read at startup
+	Transcript               string            `yaml:"transcript"`             // Save Claude's output with markers for each prompt to this file, or a new file in this directory if it ends with "/"; read at startup
+	Telemetry                string            `yaml:"telemetry"`              // Send anonymous usage counts to this URL when the session ends; off unless set; read at startup
+}
+
+// Delays tunes the pauses around answering Claude's prompts, in milliseconds
+type Delays struct {
+	AutoApproveMs       int `yaml:"auto_approve_ms"`        // Before sending an automatic answer
+	ChoiceProcessingMs  int `yaml:"choice_processing_ms"`   // After a dialog's choices have been read
+	DialogResetMs       int `yaml:"dialog_reset_ms"`        // Before another dialog may be shown
+	AutoRejectProcessMs int `yaml:"auto_reject_process_ms"` // Before sending the reject choice
+	AutoRejectChoiceMs  int `yaml:"auto_reject_choice_ms"`  // Between the reject choice and the rejection message
+	AutoRejectCRMs      int `yaml:"auto_reject_cr_ms"`      // Between the rejection message and Enter
+	InputCharMs         int `yaml:"input_char_ms"`          // Between the characters of a line piped to dcode
+	InputLineMs         int `yaml:"input_line_ms"`          // Between a piped line and its Enter
+	InputSubmitMs       int `yaml:"input_submit_ms"`        // After a piped line's Enter
+}
+
+// Default returns the options used when neither the config file nor a flag sets them
+func Default() Config {
+	return Config{
+		PreventScrollbackClear: true,
+		ContinuePrompts:        ContinuePromptsIgnore,
+		AutoRejectWaitChoice:   TimeoutChoiceTellClaude,
+		DefaultChoice:          DefaultChoiceBest,
+		DisplayBackpressure:    "block",
+		Locale:                 types.DefaultLocale,
+		Language:               i18n.English,
+		DialogQuiescenceMs:     DefaultDialogQuiescenceMs,
+		RejectionLoopLimit:     DefaultRejectionLoopLimit,
+		Risk:                   DefaultRiskPolicy(),
+		Remote:                 DefaultRemote(),
+		Escalation:             DefaultEscalation(),
+		Anomalies:              DefaultAnomalies(),
+		LogRotation:            DefaultLogRotation(),
+		QuietHours:             DefaultQuietHours(),
+		EditScope:              DefaultEditScope(),
+		Dialog:                 DefaultDialogStyle(),
+		Delays: Delays{
+			AutoApproveMs:       DefaultAutoApproveDelayMs,
+			ChoiceProcessingMs:  DefaultChoiceProcessingDelayMs,
+			DialogResetMs:       DefaultDialogResetDelayMs,
+			AutoRejectProcessMs: DefaultAutoRejectProcessDelayMs,
+			AutoRejectChoiceMs:  DefaultAutoRejectChoiceDelayMs,
+			AutoRejectCRMs:      DefaultAutoRejectCRDelayMs,
+			InputCharMs:         DefaultInputCharDelayMs,
+			InputLineMs:         DefaultInputLineDelayMs,
+			InputSubmitMs:       DefaultInputSubmitDelayMs,
+		},
+	}
+}
+
+// ValidTimeoutChoice reports whether choice is a TimeoutChoice value or a
+// positive choice number
+func ValidTimeoutChoice(choice string) bool {
+	switch choice {
+	case TimeoutChoiceTellClaude, TimeoutChoiceNo:
+		return true
+	}
+	number, err := strconv.Atoi(choice)
+	return err == nil && number > 0 && strconv.Itoa(number) == choice
+}
+
+// ValidDefaultChoice reports whether choice is a DefaultChoice value or a
+// positive choice number
+func ValidDefaultChoice(choice string) bool {
+	switch choice {
+	case DefaultChoiceBest, DefaultChoiceSafest, DefaultChoiceFirst, DefaultChoiceNeverAlways:
+		return true
+	}
+	return ValidTimeoutChoice(choice) && choice != TimeoutChoiceTellClaude && choice != TimeoutChoiceNo
+}
+
+// DefaultPath returns ~/.config/dcode/config.yaml, honoring $XDG_CONFIG_HOME,
+// or "" if the home directory is unknown
+func DefaultPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "dcode", "config.yaml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "dcode", "config.yaml")
+}
+
+// Load reads the YAML file at path on top of the defaults. Unknown keys,
+// values of the wrong type, and invalid regular expressions are rejected
+// with a *SchemaError giving the line of each, so mistakes don't go
+// unnoticed. If the file doesn't exist, the defaults are returned with an
+// error matching fs.ErrNotExist.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if problems := checkSchema(&doc); len(problems) > 0 {
+		return cfg, &SchemaError{Path: path, Problems: problems}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Default(), fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate reports the first option with an invalid value
+func (c Config) Validate() error {
+	switch c.ContinuePrompts {
+	case ContinuePromptsIgnore, ContinuePromptsAuto, ContinuePromptsDialog:
+	default:
+		return fmt.Errorf("invalid continue_prompts value: %s (must be ignore, auto, or dialog)", c.ContinuePrompts)
+	}
+	if !ValidTimeoutChoice(c.AutoRejectWaitChoice) {
+		return fmt.Errorf("invalid auto_reject_wait_choice value: %s (must be tell_claude, no, or a choice number)", c.AutoRejectWaitChoice)
+	}
+	if !ValidDefaultChoice(c.DefaultChoice) {
+		return fmt.Errorf("invalid default_choice value: %s (must be best, safest, first, never-dont-ask-again, or a choice number)", c.DefaultChoice)
+	}
+	if _, err := dialog.ParseBackpressurePolicy(c.DisplayBackpressure); err != nil {
+		return fmt.Errorf("invalid display_backpressure value: %w", err)
+	}
+	if _, err := types.NewRegexPatternsWithPack(c.Patterns, c.Locales()...); err != nil {
+		return fmt.Errorf("invalid patterns or locale: %w", err)
+	}
+	if !i18n.Valid(c.Language) {
+		return fmt.Errorf("invalid language value: %s (must be %s, or auto)", c.Language, strings.Join(i18n.Languages(), ", "))
+	}
+	if _, err := c.Redactor(); err != nil {
+		return err
+	}
+	if err := validateRejectMessage(c.RejectMessage); err != nil {
+		return err
+	}
+	if err := c.ToolPolicy.validate(); err != nil {
+		return err
+	}
+	if err := c.Risk.validate(); err != nil {
+		return err
+	}
+	if err := c.Remote.validate(); err != nil {
+		return err
+	}
+	if c.Risk.Uses(RiskActionRemote) && c.Remote.NtfyURL == "" {
+		return errors.New("risk action remote needs remote.ntfy_url")
+	}
+	if c.AutoApproveWait > 0 && c.AutoRejectWait > 0 {
+		return errors.New("auto_approve_wait and auto_reject_wait can't both be set")
+	}
+	if c.Remote.Delegate && c.Remote.NtfyURL == "" {
+		return errors.New("remote.delegate needs remote.ntfy_url")
+	}
+	if err := c.Escalation.validate(c); err != nil {
+		return err
+	}
+	if err := c.Digest.validate(c); err != nil {
+		return err
+	}
+	if err := c.Anomalies.validate(); err != nil {
+		return err
+	}
+	if c.LogLevel != "" {
+		if _, err := debug.ParseLevel(c.LogLevel); err != nil {
+			return fmt.Errorf("invalid log_level value: %s (must be debug, info, warn, or error)", c.LogLevel)
+		}
+	}
+	switch c.LogFormat {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid log_format value: %s (must be text or json)", c.LogFormat)
+	}
+	if err := c.LogRotation.validate(); err != nil {
+		return err
+	}
+	if c.OTLPEndpoint != "" {
+		u, err := url.Parse(c.OTLPEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid otlp_endpoint value: %s (must be an http or https URL, e.g. http://localhost:4318)", c.OTLPEndpoint)
+		}
+	}
+	if c.ScreenshotDir != "" && c.AuditLog == "" {
+		return errors.New("screenshot_dir needs audit_log, whose entries name the screenshots")
+	}
+	if c.Telemetry != "" {
+		u, err := url.Parse(c.Telemetry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid telemetry value: %s (must be an http or https URL)", c.Telemetry)
+		}
+	}
+	if c.Syslog != "" && !systemlog.ValidFacility(c.Syslog) {
+		return fmt.Errorf("invalid syslog value: %s (must be a syslog facility such as user, auth, or local0 to local7)", c.Syslog)
+	}
+	if err := c.QuietHours.validate(); err != nil {
+		return err
+	}
+	if err := c.Policy.validate(); err != nil {
+		return err
+	}
+	if err := c.EditScope.validate(); err != nil {
+		return err
+	}
+	if err := c.Dialog.validate(); err != nil {
+		return err
+	}
+	for i, rule := range c.Approve {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid approve rule %d: %w", i+1, err)
+		}
+	}
+	for i, rule := range c.Deny {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid deny rule %d: %w", i+1, err)
+		}
+	}
+	for i, rule := range c.Forbid {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid forbid rule %d: %w", i+1, err)
+		}
+	}
+
+	nonNegative := []struct {
+		name  string
+		value int
+	}{
+		{"auto_reject_wait", c.AutoRejectWait},
+		{"auto_approve_wait", c.AutoApproveWait},
+		{"dialog_quiescence_ms", c.DialogQuiescenceMs},
+		{"approval_cache_seconds", c.ApprovalCacheSeconds},
+		{"temporary_approval_minutes", c.TemporaryApprovalMinutes},
+		{"max_auto_approvals", c.MaxAutoApprovals},
+		{"digest_minutes", c.DigestMinutes},
+		{"rejection_loop_limit", c.RejectionLoopLimit},
+	}
+	for _, option := range nonNegative {
+		if option.value < 0 {
+			return fmt.Errorf("invalid %s value: %d (must not be negative)", option.name, option.value)
+		}
+	}
+	for _, delay := range c.Delays.named() {
+		if *delay.value < 0 {
+			return fmt.Errorf("invalid delays.%s value: %d (must not be negative)", delay.name, *delay.value)
+		}
+	}
+	return nil
+}
+
+// Locales returns the locales selected with Locale
+func (c Config) Locales() []string {
+	return strings.Split(c.Locale, ",")
+}
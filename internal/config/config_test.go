@@ -0,0 +1,224 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfig writes content to a config.yaml in a temporary directory
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestDefault(t *testing.T) {
+	cfg := Default()
+	if !cfg.PreventScrollbackClear {
+		t.Error("Expected scrollback clear prevention to be on by default")
+	}
+	if cfg.ContinuePrompts != ContinuePromptsIgnore {
+		t.Errorf("Expected continue_prompts %q, got %q", ContinuePromptsIgnore, cfg.ContinuePrompts)
+	}
+	if cfg.Delays.AutoRejectCRMs != DefaultAutoRejectCRDelayMs {
+		t.Errorf("Expected auto_reject_cr_ms %d, got %d", DefaultAutoRejectCRDelayMs, cfg.Delays.AutoRejectCRMs)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected defaults to be valid, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+auto_reject: true
+auto_reject_wait: 5
+prevent_scrollback_clear: false
+continue_prompts: dialog
+locale: ja
+trust_dirs:
+  - /Users/test/git
+patterns:
+  permit:
+    - Shall I run
+delays:
+  auto_reject_cr_ms: 400
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := Default()
+	expected.AutoReject = true
+	expected.AutoRejectWait = 5
+	expected.PreventScrollbackClear = false
+	expected.ContinuePrompts = ContinuePromptsDialog
+	expected.Locale = "ja"
+	expected.TrustDirs = []string{"/Users/test/git"}
+	expected.Patterns.Permit = []string{"Shall I run"}
+	expected.Delays.AutoRejectCRMs = 400
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, cfg)
+	}
+}
+
+func TestLoadPartialRiskPolicy(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "risk:\n  high: confirm\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := RiskPolicy{Low: RiskActionDialog, Medium: RiskActionDialog, High: RiskActionConfirm}
+	if cfg.Risk != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cfg.Risk)
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	cfg, err := Load(writeConfig(t, ""))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadRejectsInvalidFile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "auto_aprove: true\n"},
+		{"unknown nested key", "delays:\n  auto_approve: 100\n"},
+		{"wrong type", "auto_reject_wait: soon\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, tc.content)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr bool
+	}{
+		{"defaults", func(cfg *Config) {}, false},
+		{"all locales", func(cfg *Config) { cfg.Locale = "en,ja" }, false},
+		{"continue prompts", func(cfg *Config) { cfg.ContinuePrompts = "always" }, true},
+		{"display backpressure", func(cfg *Config) { cfg.DisplayBackpressure = "skip" }, true},
+		{"unknown locale", func(cfg *Config) { cfg.Locale = "fr" }, true},
+		{"japanese", func(cfg *Config) { cfg.Language = "ja" }, false},
+		{"language from environment", func(cfg *Config) { cfg.Language = "auto" }, false},
+		{"unknown language", func(cfg *Config) { cfg.Language = "fr" }, true},
+		{"invalid pattern", func(cfg *Config) { cfg.Patterns.Permit = []string{"("} }, true},
+		{"empty pattern", func(cfg *Config) { cfg.Patterns.ConfirmPrompt = []string{""} }, true},
+		{"negative wait", func(cfg *Config) { cfg.AutoRejectWait = -1 }, true},
+		{"approve wait", func(cfg *Config) { cfg.AutoApproveWait = 10 }, false},
+		{"numbered wait choice", func(cfg *Config) { cfg.AutoRejectWaitChoice = "2" }, false},
+		{"plain no wait choice", func(cfg *Config) { cfg.AutoRejectWaitChoice = "no" }, false},
+		{"unknown wait choice", func(cfg *Config) { cfg.AutoRejectWaitChoice = "maybe" }, true},
+		{"zero wait choice", func(cfg *Config) { cfg.AutoRejectWaitChoice = "0" }, true},
+		{"safest default choice", func(cfg *Config) { cfg.DefaultChoice = "safest" }, false},
+		{"numbered default choice", func(cfg *Config) { cfg.DefaultChoice = "3" }, false},
+		{"unknown default choice", func(cfg *Config) { cfg.DefaultChoice = "no" }, true},
+		{"approve and reject wait", func(cfg *Config) { cfg.AutoApproveWait = 10; cfg.AutoRejectWait = 10 }, true},
+		{"negative delay", func(cfg *Config) { cfg.Delays.DialogResetMs = -1 }, true},
+		{"negative temporary approval", func(cfg *Config) { cfg.TemporaryApprovalMinutes = -1 }, true},
+		{"negative max auto approvals", func(cfg *Config) { cfg.MaxAutoApprovals = -1 }, true},
+		{"negative digest minutes", func(cfg *Config) { cfg.DigestMinutes = -1 }, true},
+		{"negative rejection loop limit", func(cfg *Config) { cfg.RejectionLoopLimit = -1 }, true},
+		{"risk actions", func(cfg *Config) { cfg.Risk = RiskPolicy{Low: "approve", Medium: "dialog", High: "confirm"} }, false},
+		{"unknown risk action", func(cfg *Config) { cfg.Risk.High = "block" }, true},
+		{"remote risk action", func(cfg *Config) { cfg.Risk.High = "remote"; cfg.Remote.NtfyURL = "https://ntfy.sh/dcode-x7" }, false},
+		{"remote risk action without a service", func(cfg *Config) { cfg.Risk.High = "remote" }, true},
+		{"remote URL without a topic", func(cfg *Config) { cfg.Remote.NtfyURL = "https://ntfy.sh/" }, true},
+		{"zero remote timeout", func(cfg *Config) { cfg.Remote.TimeoutSeconds = 0 }, true},
+		{"delegation", func(cfg *Config) { cfg.Remote.Delegate = true; cfg.Remote.NtfyURL = "https://ntfy.sh/dcode-x7" }, false},
+		{"delegation without a service", func(cfg *Config) { cfg.Remote.Delegate = true }, true},
+		{"escalation", func(cfg *Config) { cfg.Escalation.AfterSeconds = 60; cfg.AutoRejectWait = 120 }, false},
+		{"negative escalation", func(cfg *Config) { cfg.Escalation.AfterSeconds = -1 }, true},
+		{"escalation after the countdown", func(cfg *Config) { cfg.Escalation.AfterSeconds = 60; cfg.AutoApproveWait = 60 }, true},
+		{"escalation to ntfy without a service", func(cfg *Config) { cfg.Escalation.AfterSeconds = 60; cfg.Escalation.Ntfy = true }, true},
+		{"anomaly warnings off", func(cfg *Config) { cfg.Anomalies.RepeatedPrompts = 0; cfg.Anomalies.SuppressedPrompts = 0 }, false},
+		{"negative anomaly threshold", func(cfg *Config) { cfg.Anomalies.AnswerRetries = -1 }, true},
+		{"no anomaly window", func(cfg *Config) { cfg.Anomalies.WindowMinutes = 0 }, true},
+		{"screenshots", func(cfg *Config) { cfg.ScreenshotDir = "~/dcode-screenshots"; cfg.AuditLog = "audit.jsonl" }, false},
+		{"screenshots without an audit log", func(cfg *Config) { cfg.ScreenshotDir = "~/dcode-screenshots" }, true},
+		{"digest", func(cfg *Config) {
+			cfg.Digest = Digest{Time: "08:00", SlackWebhook: "https://hooks.example.com/x"}
+			cfg.AuditLog = "audit.jsonl"
+		}, false},
+		{"digest without an audit log", func(cfg *Config) { cfg.Digest = Digest{Time: "08:00", SlackWebhook: "https://hooks.example.com/x"} }, true},
+		{"digest at an invalid time", func(cfg *Config) {
+			cfg.Digest = Digest{Time: "8am", SlackWebhook: "https://hooks.example.com/x"}
+			cfg.AuditLog = "audit.jsonl"
+		}, true},
+		{"digest without a delivery", func(cfg *Config) { cfg.Digest = Digest{Time: "08:00"}; cfg.AuditLog = "audit.jsonl" }, true},
+		{"digest email without ntfy", func(cfg *Config) {
+			cfg.Digest = Digest{Time: "08:00", Email: "team@example.com", SlackWebhook: "https://hooks.example.com/x"}
+			cfg.AuditLog = "audit.jsonl"
+		}, true},
+		{"OTLP endpoint", func(cfg *Config) { cfg.OTLPEndpoint = "http://localhost:4318" }, false},
+		{"log level and format", func(cfg *Config) { cfg.LogLevel = "warn"; cfg.LogFormat = "json" }, false},
+		{"unknown log level", func(cfg *Config) { cfg.LogLevel = "verbose" }, true},
+		{"unknown log format", func(cfg *Config) { cfg.LogFormat = "xml" }, true},
+		{"negative log backups", func(cfg *Config) { cfg.LogRotation.MaxBackups = -1 }, true},
+		{"syslog facility", func(cfg *Config) { cfg.Syslog = "local0" }, false},
+		{"unknown syslog facility", func(cfg *Config) { cfg.Syslog = "local9" }, true},
+		{"OTLP endpoint without a scheme", func(cfg *Config) { cfg.OTLPEndpoint = "localhost:4318" }, true},
+		{"telemetry URL", func(cfg *Config) { cfg.Telemetry = "https://stats.example.com/dcode" }, false},
+		{"telemetry without a scheme", func(cfg *Config) { cfg.Telemetry = "stats.example.com" }, true},
+		{"policy", func(cfg *Config) { cfg.Policy.URL = "https://x.io/p"; cfg.Policy.PublicKey = "ssh-ed25519 A" }, false},
+		{"policy over http", func(cfg *Config) { cfg.Policy.URL = "http://x.io/p"; cfg.Policy.PublicKey = "ssh-ed25519 A" }, true},
+		{"policy without a key", func(cfg *Config) { cfg.Policy.URL = "https://example.com/dcode.yaml" }, true},
+		{"policy key without a URL", func(cfg *Config) { cfg.Policy.PublicKey = "ssh-ed25519 AAAA" }, true},
+		{"edit scope", func(cfg *Config) { cfg.EditScope = EditScope{RepoRoot: true, Outside: "dialog"} }, false},
+		{"secret patterns", func(cfg *Config) { cfg.SecretPatterns = []string{`\bCUST-[0-9]{6}\b`} }, false},
+		{"invalid secret pattern", func(cfg *Config) { cfg.SecretPatterns = []string{"("} }, true},
+		{"redaction rules", func(cfg *Config) {
+			cfg.RedactionRules = []RedactionRule{{Name: "customer ID", Pattern: `\bCUST-[0-9]{6}\b`, Mask: "[CUSTOMER]"}, {Pattern: `\.corp\.example\.com\b`}}
+		}, false},
+		{"invalid redaction rule", func(cfg *Config) { cfg.RedactionRules = []RedactionRule{{Name: "host", Pattern: "("}} }, true},
+		{"redaction rule without a pattern", func(cfg *Config) { cfg.RedactionRules = []RedactionRule{{Name: "host", Mask: "[HOST]"}} }, true},
+		{"unknown edit scope action", func(cfg *Config) { cfg.EditScope.Outside = "ask" }, true},
+		{"dialog style", func(cfg *Config) {
+			cfg.Dialog = DialogStyle{Title: "dcode", App: "Terminal", RiskPrefix: RiskPrefixes{High: "🔴 "}}
+		}, false},
+		{"empty dialog title", func(cfg *Config) { cfg.Dialog.Title = " " }, true},
+		{"dialog title on two lines", func(cfg *Config) { cfg.Dialog.Title = "dcode\nClaude" }, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Default()
+			tc.modify(&cfg)
+			if err := cfg.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Expected error: %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
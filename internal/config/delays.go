@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// namedDelay is one of the Delays with its key in the config file
+type namedDelay struct {
+	name  string
+	value *int
+}
+
+// named returns every delay in d with its key under delays in the config
+// file, in the order they are declared
+func (d *Delays) named() []namedDelay {
+	return []namedDelay{
+		{"auto_approve_ms", &d.AutoApproveMs},
+		{"choice_processing_ms", &d.ChoiceProcessingMs},
+		{"dialog_reset_ms", &d.DialogResetMs},
+		{"auto_reject_process_ms", &d.AutoRejectProcessMs},
+		{"auto_reject_choice_ms", &d.AutoRejectChoiceMs},
+		{"auto_reject_cr_ms", &d.AutoRejectCRMs},
+		{"input_char_ms", &d.InputCharMs},
+		{"input_line_ms", &d.InputLineMs},
+		{"input_submit_ms", &d.InputSubmitMs},
+	}
+}
+
+// Set parses a comma-separated list of NAME=MS pairs, such as
+// "auto_approve_ms=50,auto_reject_cr_ms=1000", into d. NAME is a key under
+// delays in the config file; dashes may be used instead of underscores.
+func (d *Delays) Set(text string) error {
+	delays := d.named()
+	for _, pair := range strings.Split(text, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		name = strings.ReplaceAll(name, "-", "_")
+		ms, err := strconv.Atoi(value)
+		if !found || err != nil || ms < 0 {
+			return fmt.Errorf("invalid delays entry: %q (must be NAME=MS with MS not negative)", pair)
+		}
+
+		known := false
+		for _, delay := range delays {
+			if delay.name == name {
+				*delay.value, known = ms, true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown delay: %s (must be one of %s)", name, delayNames(delays))
+		}
+	}
+	return nil
+}
+
+// delayNames lists the names of delays for an error message
+func delayNames(delays []namedDelay) string {
+	names := make([]string, len(delays))
+	for i, delay := range delays {
+		names[i] = delay.name
+	}
+	return strings.Join(names, ", ")
+}
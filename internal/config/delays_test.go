@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestDelaysSet(t *testing.T) {
+	testCases := []struct {
+		text  string
+		check func(Delays) bool
+		valid bool
+	}{
+		{"auto_approve_ms=50", func(d Delays) bool { return d.AutoApproveMs == 50 && d.AutoRejectCRMs == DefaultAutoRejectCRDelayMs }, true},
+		{"auto-reject-cr-ms=1000, input_char_ms=0", func(d Delays) bool { return d.AutoRejectCRMs == 1000 && d.InputCharMs == 0 }, true},
+		{"auto_approve_ms", nil, false},
+		{"auto_approve_ms=-1", nil, false},
+		{"auto_approve_ms=fast", nil, false},
+		{"keystroke_ms=10", nil, false},
+		{"", nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			delays := Default().Delays
+			err := delays.Set(tc.text)
+			if (err == nil) != tc.valid {
+				t.Fatalf("Expected valid=%v, got %v", tc.valid, err)
+			}
+			if tc.valid && !tc.check(delays) {
+				t.Errorf("Unexpected delays: %+v", delays)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/dialog"
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// DefaultDialogTitle is the title of dcode's dialogs and notifications
+const DefaultDialogTitle = dialog.DefaultTitle
+
+// DialogStyle is how dcode's dialogs and notifications present themselves,
+// so they can be told apart from those of other automation
+type DialogStyle struct {
+	Title      string       `yaml:"title"`       // Title of dialogs and notifications
+	App        string       `yaml:"app"`         // Application that shows them, e.g. "Terminal", or "" for osascript itself; read at startup
+	RiskPrefix RiskPrefixes `yaml:"risk_prefix"` // Put before the title of dialogs about requests of each risk level
+}
+
+// RiskPrefixes are the prefixes of dialog titles for each risk level, such
+// as an emoji
+type RiskPrefixes struct {
+	Low    string `yaml:"low"`
+	Medium string `yaml:"medium"`
+	High   string `yaml:"high"`
+}
+
+// DefaultDialogStyle titles every dialog DefaultDialogTitle
+func DefaultDialogStyle() DialogStyle {
+	return DialogStyle{Title: DefaultDialogTitle}
+}
+
+// TitleFor returns the title of a dialog about a request rated level
+func (d DialogStyle) TitleFor(level parser.RiskLevel) string {
+	prefix := d.RiskPrefix.Low
+	switch level {
+	case parser.RiskHigh:
+		prefix = d.RiskPrefix.High
+	case parser.RiskMedium:
+		prefix = d.RiskPrefix.Medium
+	}
+	return prefix + d.Title
+}
+
+// validate reports a missing title, or a title or application name that
+// can't be shown on one line
+func (d DialogStyle) validate() error {
+	if strings.TrimSpace(d.Title) == "" {
+		return errors.New("dialog.title must not be empty")
+	}
+	for _, value := range []string{d.Title, d.App, d.RiskPrefix.Low, d.RiskPrefix.Medium, d.RiskPrefix.High} {
+		if strings.ContainsAny(value, "\r\n") {
+			return errors.New("dialog.title, dialog.app, and dialog.risk_prefix must each fit on one line")
+		}
+	}
+	return nil
+}
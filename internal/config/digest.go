@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Digest sends a summary of the previous day's answers, read from the audit
+// log, once a day, so a team running Claude overnight starts the day knowing
+// what was approved and rejected
+type Digest struct {
+	Time         string `yaml:"time"`          // Local time of day to send it, "HH:MM", or "" for never
+	Ntfy         bool   `yaml:"ntfy"`          // Push it to remote.ntfy_url
+	Email        string `yaml:"email"`         // Have the ntfy server email it to this address as well
+	SlackWebhook string `yaml:"slack_webhook"` // Post it to this Slack incoming webhook URL
+}
+
+// Next returns the first time at or after now that the digest is due
+func (d Digest) Next(now time.Time) time.Time {
+	at, err := time.Parse("15:04", d.Time)
+	if err != nil {
+		return time.Time{}
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if next.Before(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// validate reports an invalid time or webhook, or a digest cfg can't compile
+// or deliver
+func (d Digest) validate(cfg Config) error {
+	if d.Time == "" {
+		return nil
+	}
+	if _, err := time.Parse("15:04", d.Time); err != nil {
+		return fmt.Errorf("invalid digest.time value: %s (must be HH:MM)", d.Time)
+	}
+	if cfg.AuditLog == "" {
+		return errors.New("digest needs audit_log, which it summarizes")
+	}
+	if !d.Ntfy && d.SlackWebhook == "" {
+		return errors.New("digest needs digest.ntfy or digest.slack_webhook to be delivered")
+	}
+	if d.Ntfy && cfg.Remote.NtfyURL == "" {
+		return errors.New("digest.ntfy needs remote.ntfy_url")
+	}
+	if d.Email != "" && (!d.Ntfy || !strings.Contains(d.Email, "@")) {
+		return fmt.Errorf("invalid digest.email value: %s (must be an address, and needs digest.ntfy)", d.Email)
+	}
+	if d.SlackWebhook != "" {
+		u, err := url.Parse(d.SlackWebhook)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid digest.slack_webhook value: %s (must be an https URL)", d.SlackWebhook)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestNext(t *testing.T) {
+	digest := Digest{Time: "08:00"}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 3, day, hour, minute, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{"before the time", at(10, 7, 30), at(10, 8, 0)},
+		{"at the time", at(10, 8, 0), at(10, 8, 0)},
+		{"after the time", at(10, 8, 1), at(11, 8, 0)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if next := digest.Next(tc.now); !next.Equal(tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, next)
+			}
+		})
+	}
+
+	if !(Digest{}).Next(at(10, 7, 0)).IsZero() {
+		t.Error("Expected no time without digest.time")
+	}
+}
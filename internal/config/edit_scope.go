@@ -0,0 +1,38 @@
+package config
+
+import "fmt"
+
+// Actions EditScope can take for an edit outside its directories
+const (
+	EditScopeActionDeny   = "deny"   // Reject without asking
+	EditScopeActionDialog = "dialog" // Always ask, even when the edit would be approved automatically
+)
+
+// EditScope keeps file-modifying tools (Write, Edit, MultiEdit, NotebookEdit)
+// to some directories. Edits of files anywhere else are handled with Outside.
+// Deny rules are checked first.
+type EditScope struct {
+	RepoRoot bool     `yaml:"repo_root"` // Allow the git repository dcode was started in
+	Dirs     []string `yaml:"dirs"`      // Other allowed directories; ~ is expanded
+	Outside  string   `yaml:"outside"`
+	Message  string   `yaml:"message"` // Sent to Claude when an edit is denied
+}
+
+// DefaultEditScope doesn't restrict edits
+func DefaultEditScope() EditScope {
+	return EditScope{Outside: EditScopeActionDeny}
+}
+
+// Enabled reports whether edits are restricted at all
+func (e EditScope) Enabled() bool {
+	return e.RepoRoot || len(e.Dirs) > 0
+}
+
+// validate reports an unknown action
+func (e EditScope) validate() error {
+	switch e.Outside {
+	case EditScopeActionDeny, EditScopeActionDialog:
+		return nil
+	}
+	return fmt.Errorf("invalid edit_scope.outside value: %s (must be deny or dialog)", e.Outside)
+}
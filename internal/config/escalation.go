@@ -0,0 +1,46 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SoundBell rings the terminal bell instead of playing a sound file
+const SoundBell = "bell"
+
+// Escalation alerts the user again, through other channels, when a dialog
+// is left unanswered for a while, so someone who stepped away can still
+// answer it before Claude gives up or a countdown answers it for them
+type Escalation struct {
+	AfterSeconds int    `yaml:"after_seconds"` // Alert once a dialog has waited this long (0 = never)
+	Sound        string `yaml:"sound"`         // macOS sound name such as "Glass", a sound file, SoundBell, or "" for none
+	Notify       bool   `yaml:"notify"`        // Post a notification
+	Ntfy         bool   `yaml:"ntfy"`          // Push a notification to remote.ntfy_url
+}
+
+// DefaultEscalation never alerts, but posts a notification once
+// after_seconds is set
+func DefaultEscalation() Escalation {
+	return Escalation{Notify: true}
+}
+
+// validate reports a negative threshold, or one that a countdown in cfg
+// always beats
+func (e Escalation) validate(cfg Config) error {
+	if e.AfterSeconds < 0 {
+		return errors.New("invalid escalation.after_seconds value: must not be negative")
+	}
+	if e.AfterSeconds == 0 {
+		return nil
+	}
+	if cfg.AutoRejectWait > 0 && e.AfterSeconds >= cfg.AutoRejectWait {
+		return fmt.Errorf("invalid escalation.after_seconds value: %d must be shorter than auto_reject_wait (%d)", e.AfterSeconds, cfg.AutoRejectWait)
+	}
+	if cfg.AutoApproveWait > 0 && e.AfterSeconds >= cfg.AutoApproveWait {
+		return fmt.Errorf("invalid escalation.after_seconds value: %d must be shorter than auto_approve_wait (%d)", e.AfterSeconds, cfg.AutoApproveWait)
+	}
+	if e.Ntfy && cfg.Remote.NtfyURL == "" {
+		return errors.New("escalation.ntfy needs remote.ntfy_url")
+	}
+	return nil
+}
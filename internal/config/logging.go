@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/logfile"
+)
+
+// Default log_rotation limits: a debug or audit log takes at most about 60 MB
+const (
+	DefaultLogMaxSizeMB  = 10
+	DefaultLogMaxBackups = 5
+)
+
+// LogRotation keeps the debug log and the audit log from growing without
+// bound. A log is moved aside with the time in its name when it's rotated.
+type LogRotation struct {
+	MaxSizeMB  int  `yaml:"max_size_mb"`  // Rotate a log before it grows past this size; 0 never rotates by size
+	Daily      bool `yaml:"daily"`        // Also rotate a log on the first write of each day
+	MaxBackups int  `yaml:"max_backups"`  // Rotated copies kept of each log; 0 keeps them all
+	MaxAgeDays int  `yaml:"max_age_days"` // Delete rotated copies last written longer ago than this; 0 keeps them regardless of age
+}
+
+// DefaultLogRotation rotates logs at DefaultLogMaxSizeMB, keeping
+// DefaultLogMaxBackups rotated copies
+func DefaultLogRotation() LogRotation {
+	return LogRotation{MaxSizeMB: DefaultLogMaxSizeMB, MaxBackups: DefaultLogMaxBackups}
+}
+
+// Rotation returns the rotation r describes
+func (r LogRotation) Rotation() logfile.Rotation {
+	return logfile.Rotation{
+		MaxBytes:   int64(r.MaxSizeMB) << 20,
+		Daily:      r.Daily,
+		MaxBackups: r.MaxBackups,
+		MaxAge:     time.Duration(r.MaxAgeDays) * 24 * time.Hour,
+	}
+}
+
+// validate reports a negative limit
+func (r LogRotation) validate() error {
+	if r.MaxSizeMB < 0 || r.MaxBackups < 0 || r.MaxAgeDays < 0 {
+		return errors.New("invalid log_rotation value: limits can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Modes a running session can be switched to, each matching the flag of
+// the same name
+const (
+	ModeDialog          = "dialog"            // Ask in a dialog
+	ModeAutoApprove     = "auto-approve"      // --auto-approve
+	ModeAutoReject      = "auto-reject"       // --auto-reject
+	ModeAutoRejectWait  = "auto-reject-wait"  // --auto-reject-wait=N; "auto-reject-wait=N" sets the seconds
+	ModeAutoApproveWait = "auto-approve-wait" // --auto-approve-wait=N; "auto-approve-wait=N" sets the seconds
+)
+
+// ModeFileName is the file in the project that, with mode_file, switches the
+// mode when Claude or anyone else writes a mode name to it
+const ModeFileName = ".dcode-mode"
+
+// DefaultModeWaitSeconds is the countdown of the wait modes when a mode
+// switch doesn't give one and the config doesn't either
+const DefaultModeWaitSeconds = 30
+
+// Mode is how dialogs that no rule or policy answers are handled: shown,
+// approved, rejected, or counted down. It replaces the auto_approve,
+// auto_reject, auto_reject_wait, and auto_approve_wait options.
+type Mode struct {
+	Name        string
+	WaitSeconds int // For ModeAutoRejectWait and ModeAutoApproveWait
+}
+
+// ParseMode parses a mode name such as "auto-approve" or "auto-reject-wait=10"
+func ParseMode(text string) (Mode, error) {
+	name, wait, hasWait := strings.Cut(strings.TrimSpace(text), "=")
+	switch name {
+	case ModeDialog, ModeAutoApprove, ModeAutoReject:
+		if !hasWait {
+			return Mode{Name: name}, nil
+		}
+	case ModeAutoRejectWait, ModeAutoApproveWait:
+		if !hasWait {
+			return Mode{Name: name}, nil
+		}
+		if seconds, err := strconv.Atoi(wait); err == nil && seconds > 0 {
+			return Mode{Name: name, WaitSeconds: seconds}, nil
+		}
+	}
+	return Mode{}, fmt.Errorf("invalid mode: %s (must be dialog, auto-approve, auto-reject, auto-reject-wait[=SECONDS], or auto-approve-wait[=SECONDS])", text)
+}
+
+// Approves reports whether the mode approves requests without an answer
+// from the user
+func (m Mode) Approves() bool {
+	return m.Name == ModeAutoApprove || m.Name == ModeAutoApproveWait
+}
+
+// CurrentMode returns the mode cfg's options amount to
+func CurrentMode(cfg *Config) Mode {
+	switch {
+	case cfg.AutoReject:
+		return Mode{Name: ModeAutoReject}
+	case cfg.AutoApprove:
+		return Mode{Name: ModeAutoApprove}
+	case cfg.AutoRejectWait > 0:
+		return Mode{Name: ModeAutoRejectWait, WaitSeconds: cfg.AutoRejectWait}
+	case cfg.AutoApproveWait > 0:
+		return Mode{Name: ModeAutoApproveWait, WaitSeconds: cfg.AutoApproveWait}
+	}
+	return Mode{Name: ModeDialog}
+}
+
+// Apply sets cfg's options for the mode. A wait mode without seconds keeps
+// the countdown cfg already has for it, if any.
+func (m Mode) Apply(cfg *Config) {
+	rejectWait, approveWait := cfg.AutoRejectWait, cfg.AutoApproveWait
+	cfg.AutoApprove, cfg.AutoReject, cfg.AutoRejectWait, cfg.AutoApproveWait = false, false, 0, 0
+	switch m.Name {
+	case ModeAutoApprove:
+		cfg.AutoApprove = true
+	case ModeAutoReject:
+		cfg.AutoReject = true
+	case ModeAutoRejectWait:
+		cfg.AutoRejectWait = m.waitSeconds(rejectWait)
+	case ModeAutoApproveWait:
+		cfg.AutoApproveWait = m.waitSeconds(approveWait)
+	}
+}
+
+// waitSeconds returns the mode's countdown, or else configured, or else
+// DefaultModeWaitSeconds
+func (m Mode) waitSeconds(configured int) int {
+	switch {
+	case m.WaitSeconds > 0:
+		return m.WaitSeconds
+	case configured > 0:
+		return configured
+	}
+	return DefaultModeWaitSeconds
+}
+
+// String returns the mode as ParseMode accepts it
+func (m Mode) String() string {
+	if m.WaitSeconds > 0 {
+		return fmt.Sprintf("%s=%d", m.Name, m.WaitSeconds)
+	}
+	return m.Name
+}
@@ -0,0 +1,66 @@
+package config
+
+import "testing"
+
+func TestParseMode(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected Mode
+		valid    bool
+	}{
+		{"dialog", Mode{Name: ModeDialog}, true},
+		{"auto-approve", Mode{Name: ModeAutoApprove}, true},
+		{" auto-reject ", Mode{Name: ModeAutoReject}, true},
+		{"auto-reject-wait", Mode{Name: ModeAutoRejectWait}, true},
+		{"auto-reject-wait=10", Mode{Name: ModeAutoRejectWait, WaitSeconds: 10}, true},
+		{"auto-approve-wait=5", Mode{Name: ModeAutoApproveWait, WaitSeconds: 5}, true},
+		{"auto-reject-wait=0", Mode{}, false},
+		{"auto-approve=10", Mode{}, false},
+		{"yolo", Mode{}, false},
+		{"", Mode{}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			mode, err := ParseMode(tc.text)
+			if (err == nil) != tc.valid {
+				t.Fatalf("Expected valid=%v, got %v", tc.valid, err)
+			}
+			if mode != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, mode)
+			}
+		})
+	}
+}
+
+func TestModeApply(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     string
+		before   func(cfg *Config)
+		expected string
+	}{
+		{"auto-approve replaces auto-reject", "auto-approve", func(cfg *Config) { cfg.AutoReject = true }, "auto-approve"},
+		{"dialog clears the auto modes", "dialog", func(cfg *Config) { cfg.AutoApprove = true; cfg.AutoRejectWait = 10 }, "dialog"},
+		{"wait keeps the configured seconds", "auto-reject-wait", func(cfg *Config) { cfg.AutoRejectWait = 10 }, "auto-reject-wait=10"},
+		{"wait without configured seconds", "auto-reject-wait", func(cfg *Config) {}, "auto-reject-wait=30"},
+		{"wait with seconds", "auto-approve-wait=5", func(cfg *Config) { cfg.AutoRejectWait = 10 }, "auto-approve-wait=5"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Default()
+			tc.before(&cfg)
+			mode, err := ParseMode(tc.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mode.Apply(&cfg)
+
+			if result := CurrentMode(&cfg).String(); result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Expected a valid config, got %v", err)
+			}
+		})
+	}
+}
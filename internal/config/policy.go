@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PolicySource locates an organization-wide policy: approve, deny, and
+// forbid rules and a tool policy published by a team, added to every
+// member's own config. The policy must carry a detached signature made with
+// "ssh-keygen -Y sign -n dcode-policy" by the key in PublicKey.
+type PolicySource struct {
+	URL          string `yaml:"url"`           // HTTPS URL of the policy YAML
+	SignatureURL string `yaml:"signature_url"` // HTTPS URL of its signature; URL with ".sig" appended if empty
+	PublicKey    string `yaml:"public_key"`    // "ssh-ed25519 AAAA..." line of the signing key
+}
+
+// Enabled reports whether a policy is configured
+func (p PolicySource) Enabled() bool {
+	return p.URL != ""
+}
+
+// SignatureLocation returns the URL of the policy's signature
+func (p PolicySource) SignatureLocation() string {
+	if p.SignatureURL != "" {
+		return p.SignatureURL
+	}
+	return p.URL + ".sig"
+}
+
+// validate reports a URL that isn't HTTPS or a policy without a public key
+func (p PolicySource) validate() error {
+	if !p.Enabled() {
+		if p.SignatureURL != "" || p.PublicKey != "" {
+			return errors.New("invalid policy: url is required")
+		}
+		return nil
+	}
+	for _, field := range []struct{ name, value string }{{"url", p.URL}, {"signature_url", p.SignatureLocation()}} {
+		u, err := url.Parse(field.value)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid policy.%s value: %s (must be an https URL)", field.name, field.value)
+		}
+	}
+	if !strings.HasPrefix(p.PublicKey, "ssh-ed25519 ") {
+		return errors.New("invalid policy.public_key value: must be an ssh-ed25519 public key")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Actions QuietHours can take instead of showing a dialog
+const (
+	QuietHoursActionDeny   = "deny"   // Reject with the quiet hours message
+	QuietHoursActionNotify = "notify" // Post a notification, then reject
+)
+
+// QuietHours lists times of day, in local time, when no dialog is shown.
+// Dialogs that would be shown are answered with Action instead, so an
+// overnight run doesn't wake anyone. Deny and approve rules still apply.
+type QuietHours struct {
+	Windows []string `yaml:"windows"` // "HH:MM-HH:MM"; a window ending before it starts spans midnight
+	Action  string   `yaml:"action"`
+	Message string   `yaml:"message"` // Sent to Claude instead of the quiet hours message in the language option
+}
+
+// DefaultQuietHours has no quiet hours
+func DefaultQuietHours() QuietHours {
+	return QuietHours{Action: QuietHoursActionDeny}
+}
+
+// Active reports whether now falls in one of the windows
+func (q QuietHours) Active(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	for _, window := range q.Windows {
+		start, end, err := parseQuietWindow(window)
+		if err != nil {
+			continue
+		}
+		if start <= end && minute >= start && minute < end {
+			return true
+		}
+		if start > end && (minute >= start || minute < end) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate reports the first invalid window or an unknown action
+func (q QuietHours) validate() error {
+	for _, window := range q.Windows {
+		if _, _, err := parseQuietWindow(window); err != nil {
+			return fmt.Errorf("invalid quiet_hours.windows value: %s (%w)", window, err)
+		}
+	}
+	switch q.Action {
+	case QuietHoursActionDeny, QuietHoursActionNotify:
+	default:
+		return fmt.Errorf("invalid quiet_hours.action value: %s (must be deny or notify)", q.Action)
+	}
+	return nil
+}
+
+var errQuietWindowFormat = errors.New("must be HH:MM-HH:MM")
+
+// parseQuietWindow parses "HH:MM-HH:MM" into minutes after midnight
+func parseQuietWindow(window string) (int, int, error) {
+	from, to, found := strings.Cut(window, "-")
+	if !found {
+		return 0, 0, errQuietWindowFormat
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, errQuietWindowFormat
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, errQuietWindowFormat
+	}
+	if start.Equal(end) {
+		return 0, 0, errors.New("start and end must differ")
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
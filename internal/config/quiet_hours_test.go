@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursActive(t *testing.T) {
+	quietHours := QuietHours{Windows: []string{"23:00-07:00", "12:00-13:00"}, Action: QuietHoursActionDeny}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{"start of an overnight window", at(23, 0), true},
+		{"after midnight", at(3, 30), true},
+		{"end of an overnight window", at(7, 0), false},
+		{"daytime", at(9, 0), false},
+		{"inside a daytime window", at(12, 59), true},
+		{"end of a daytime window", at(13, 0), false},
+		{"just before an overnight window", at(22, 59), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := quietHours.Active(tc.now); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+
+	if (QuietHours{}).Active(at(3, 0)) {
+		t.Error("Expected no quiet hours without windows")
+	}
+}
+
+func TestLoadQuietHours(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "quiet_hours:\n  windows: ['23:00-07:00']\n  action: notify\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid quiet hours, got %v", err)
+	}
+	if len(cfg.QuietHours.Windows) != 1 || cfg.QuietHours.Action != QuietHoursActionNotify {
+		t.Errorf("Unexpected quiet hours: %+v", cfg.QuietHours)
+	}
+}
+
+func TestValidateQuietHours(t *testing.T) {
+	testCases := []struct {
+		name       string
+		quietHours QuietHours
+		wantErr    bool
+	}{
+		{"defaults", DefaultQuietHours(), false},
+		{"notify", QuietHours{Windows: []string{"22:30-06:15"}, Action: QuietHoursActionNotify}, false},
+		{"missing end", QuietHours{Windows: []string{"23:00"}, Action: QuietHoursActionDeny}, true},
+		{"invalid time", QuietHours{Windows: []string{"23:00-25:00"}, Action: QuietHoursActionDeny}, true},
+		{"empty window", QuietHours{Windows: []string{"08:00-08:00"}, Action: QuietHoursActionDeny}, true},
+		{"unknown action", QuietHours{Action: "approve"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.QuietHours = tc.quietHours
+			if err := cfg.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Expected error: %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
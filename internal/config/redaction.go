@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/takahirom/dialog-code/internal/redact"
+)
+
+// RedactionRule masks text that isn't a secret but mustn't leave the machine
+// either, such as customer IDs or internal host names, everywhere dcode
+// writes or sends it
+type RedactionRule struct {
+	Name    string `yaml:"name"`    // Names the rule in errors
+	Pattern string `yaml:"pattern"` // Regular expression; a (?P<secret>...) group masks only that part of the match
+	Mask    string `yaml:"mask"`    // Replaces each match, e.g. "[CUSTOMER]", or "" for [REDACTED]
+}
+
+// Redactor returns the redactor for common secret formats, SecretPatterns,
+// and RedactionRules
+func (c Config) Redactor() (*redact.Redactor, error) {
+	redactor, err := redact.New(c.SecretPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret_patterns: %w", err)
+	}
+	for i, rule := range c.RedactionRules {
+		mask := rule.Mask
+		if mask == "" {
+			mask = redact.Mask
+		}
+		if err := redactor.Add(rule.Pattern, mask); err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %d %q: %w", i+1, rule.Name, err)
+		}
+	}
+	return redactor, nil
+}
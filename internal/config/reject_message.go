@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Placeholders a reject_message template may contain
+const (
+	RejectPlaceholderTool    = "{tool}"    // Tool name from the dialog header, e.g. "Bash"
+	RejectPlaceholderCommand = "{command}" // The rejected command and its description, one per line, as in the dialog
+	RejectPlaceholderReason  = "{reason}"  // dcode's own message explaining the rejection
+	RejectPlaceholderRule    = "{rule}"    // What rejected the request, e.g. "deny rule 2" or "quiet_hours"
+	RejectPlaceholderTime    = "{time}"    // When the request was rejected, in RFC 3339 format
+	RejectPlaceholderID      = "{id}"      // The rejection's ID for "dcode explain", or "" if it wasn't logged
+)
+
+var rejectPlaceholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// Rejection describes a rejected request for a reject_message template
+type Rejection struct {
+	Tool    string
+	Command string
+	Reason  string
+	Rule    string
+	Time    string
+	ID      string
+}
+
+// ExpandRejectMessage replaces the placeholders in template with the fields
+// of rejection
+func ExpandRejectMessage(template string, rejection Rejection) string {
+	return strings.NewReplacer(
+		RejectPlaceholderTool, rejection.Tool,
+		RejectPlaceholderCommand, rejection.Command,
+		RejectPlaceholderReason, rejection.Reason,
+		RejectPlaceholderRule, rejection.Rule,
+		RejectPlaceholderTime, rejection.Time,
+		RejectPlaceholderID, rejection.ID,
+	).Replace(template)
+}
+
+// validateRejectMessage reports the first unknown placeholder in template
+func validateRejectMessage(template string) error {
+	for _, placeholder := range rejectPlaceholderPattern.FindAllString(template, -1) {
+		switch placeholder {
+		case RejectPlaceholderTool, RejectPlaceholderCommand, RejectPlaceholderReason, RejectPlaceholderRule, RejectPlaceholderTime, RejectPlaceholderID:
+		default:
+			return fmt.Errorf("invalid reject_message placeholder: %s (must be {tool}, {command}, {reason}, {rule}, {time}, or {id})", placeholder)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import "testing"
+
+func TestExpandRejectMessage(t *testing.T) {
+	rejection := Rejection{
+		Tool:    "Bash",
+		Command: "git push --force",
+		Reason:  "The command matched a deny rule.",
+		Rule:    "deny rule 2",
+		Time:    "2025-01-01T12:00:00Z",
+		ID:      "3fa9c2e1",
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"every placeholder", "{time} {rule}: {tool} `{command}` ({reason})", "2025-01-01T12:00:00Z deny rule 2: Bash `git push --force` (The command matched a deny rule.)"},
+		{"log ID", "See dcode explain {id}", "See dcode explain 3fa9c2e1"},
+		{"repeated placeholder", "{tool}/{tool}", "Bash/Bash"},
+		{"no placeholders", "Ask the user in chat instead.", "Ask the user in chat instead."},
+		{"other braces", "Use {} or {{tool}}", "Use {} or {Bash}"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := ExpandRejectMessage(tc.template, rejection); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidateRejectMessage(t *testing.T) {
+	testCases := []struct {
+		template string
+		valid    bool
+	}{
+		{"", true},
+		{"Rejected {command} at {time} by {rule} ({tool}): {reason} [{id}]", true},
+		{"Use {} in JSON", true},
+		{"Rejected {cmd}", false},
+	}
+	for _, tc := range testCases {
+		cfg := Default()
+		cfg.RejectMessage = tc.template
+		if err := cfg.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Expected valid=%v for %q, got %v", tc.valid, tc.template, err)
+		}
+	}
+}
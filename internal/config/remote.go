@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultRemoteTimeoutSeconds is how long the remote risk action waits for
+// an answer: long enough to reach someone away from the computer
+const DefaultRemoteTimeoutSeconds = 600
+
+// Remote configures the approval service asked by the remote risk action.
+// Requests are posted to an ntfy topic with a button for each choice, and
+// the answers come back on the same topic with "-reply" appended.
+type Remote struct {
+	NtfyURL        string `yaml:"ntfy_url"`        // Topic URL, e.g. https://ntfy.sh/a-long-random-topic
+	Token          string `yaml:"token"`           // Access token for protected topics
+	TimeoutSeconds int    `yaml:"timeout_seconds"` // Rejects after this long without an answer
+	Delegate       bool   `yaml:"delegate"`        // Add an "Ask someone else" button to dialogs that forwards the request
+}
+
+// DefaultRemote has no approval service
+func DefaultRemote() Remote {
+	return Remote{TimeoutSeconds: DefaultRemoteTimeoutSeconds}
+}
+
+// validate reports an invalid topic URL or timeout
+func (r Remote) validate() error {
+	if r.NtfyURL != "" {
+		u, err := url.Parse(r.NtfyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return fmt.Errorf("invalid remote.ntfy_url value: %s (must be an http or https topic URL)", r.NtfyURL)
+		}
+	}
+	if r.TimeoutSeconds <= 0 {
+		return errors.New("invalid remote.timeout_seconds value: must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// Actions a RiskPolicy can take for a risk level
+const (
+	RiskActionDialog  = "dialog"  // Handle the dialog as the other options say
+	RiskActionApprove = "approve" // Approve without asking, unless the command was truncated
+	RiskActionConfirm = "confirm" // Ask, and require typing a confirmation to approve
+	RiskActionReject  = "reject"  // Reject without asking
+	RiskActionRemote  = "remote"  // Ask through the Remote approval service instead of a dialog
+)
+
+// RiskPolicy chooses how dialogs are handled for each risk level detected by
+// the parser. Deny and approve rules are checked first.
+type RiskPolicy struct {
+	Low    string `yaml:"low"`
+	Medium string `yaml:"medium"`
+	High   string `yaml:"high"`
+}
+
+// DefaultRiskPolicy handles every dialog as the other options say
+func DefaultRiskPolicy() RiskPolicy {
+	return RiskPolicy{Low: RiskActionDialog, Medium: RiskActionDialog, High: RiskActionDialog}
+}
+
+// Action returns the action for level
+func (r RiskPolicy) Action(level parser.RiskLevel) string {
+	switch level {
+	case parser.RiskHigh:
+		return r.High
+	case parser.RiskMedium:
+		return r.Medium
+	}
+	return r.Low
+}
+
+// validate reports the first level with an unknown action
+func (r RiskPolicy) validate() error {
+	for _, level := range []parser.RiskLevel{parser.RiskLow, parser.RiskMedium, parser.RiskHigh} {
+		switch action := r.Action(level); action {
+		case RiskActionDialog, RiskActionApprove, RiskActionConfirm, RiskActionReject, RiskActionRemote:
+		default:
+			return fmt.Errorf("invalid risk.%s value: %s (must be dialog, approve, confirm, reject, or remote)", level, action)
+		}
+	}
+	return nil
+}
+
+// Uses reports whether any risk level takes action
+func (r RiskPolicy) Uses(action string) bool {
+	return r.Low == action || r.Medium == action || r.High == action
+}
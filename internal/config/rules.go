@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// Rule matches permission dialogs by the tool and the command or files they
+// ask about. Every field that is set must match.
+type Rule struct {
+	Tool    string `yaml:"tool"`    // Tool name from the dialog header, e.g. "Bash" or "mcp__github__create_issue"
+	Command string `yaml:"command"` // Regular expression searched for in the command; anchor it, e.g. "^go test ./...$"
+	File    string `yaml:"file"`    // Regular expression every target file path must match
+}
+
+// DenyRule is a Rule whose matching dialogs are rejected without asking
+type DenyRule struct {
+	Rule    `yaml:",inline"`
+	Name    string `yaml:"name"`    // Why the rule exists, e.g. "no force pushes"; Claude is told it was rejected by it
+	Message string `yaml:"message"` // Sent to Claude with the rejection; a generic message if empty
+}
+
+// Matches reports whether the dialog described by info matches the rule.
+// Patterns are searched for anywhere in the command or path, so a command
+// pattern only covers the whole command when anchored with ^ and $. A command
+// of several lines never matches, since lines after one the pattern was
+// written for could do anything. An invalid regular expression never matches;
+// Validate reports it.
+func (r Rule) Matches(info parser.DialogInfo) bool {
+	return r.matches(info, func(command []string) bool {
+		return len(command) == 1 && matchString(r.Command, command[0])
+	})
+}
+
+// Matches reports whether the dialog described by info matches the rule. A
+// command pattern may match any line of the command, or all of it, so a
+// command can't slip past behind a harmless first line.
+func (r DenyRule) Matches(info parser.DialogInfo) bool {
+	return r.matches(info, func(command []string) bool {
+		if matchString(r.Command, strings.Join(command, "\n")) {
+			return true
+		}
+		for _, line := range command {
+			if matchString(r.Command, line) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// matches reports whether info matches the rule, with matchCommand deciding
+// whether the lines of its command match the command pattern
+func (r Rule) matches(info parser.DialogInfo, matchCommand func(command []string) bool) bool {
+	if r.Tool != "" && r.Tool != info.ToolType {
+		return false
+	}
+	if r.Command != "" {
+		if command := info.Command(); len(command) == 0 || !matchCommand(command) {
+			return false
+		}
+	}
+	if r.File != "" {
+		if len(info.FilePaths) == 0 {
+			return false
+		}
+		for _, path := range info.FilePaths {
+			if !matchString(r.File, path) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validate reports an empty rule, which would match every dialog, or an
+// invalid regular expression
+func (r Rule) validate() error {
+	if r.Tool == "" && r.Command == "" && r.File == "" {
+		return errors.New("rule must set tool, command, or file")
+	}
+	for _, pattern := range []string{r.Command, r.File} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchingRule returns the index of the first rule matching info, or -1
+func MatchingRule(rules []Rule, info parser.DialogInfo) int {
+	for i, rule := range rules {
+		if rule.Matches(info) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ExactRule returns a rule matching the request described by info and
+// requests identical to it: the same tool with the same command or files. It
+// reports false if info can't be matched exactly, such as when its command
+// was truncated or spans several lines, which approve rules never match.
+func ExactRule(info parser.DialogInfo) (Rule, bool) {
+	if info.ToolType == "" || info.Truncated {
+		return Rule{}, false
+	}
+
+	rule := Rule{Tool: info.ToolType}
+	if len(info.FilePaths) > 0 {
+		quoted := make([]string, len(info.FilePaths))
+		for i, path := range info.FilePaths {
+			quoted[i] = regexp.QuoteMeta(path)
+		}
+		rule.File = "^(?:" + strings.Join(quoted, "|") + ")$"
+	} else if command := info.Command(); len(command) > 1 {
+		return Rule{}, false
+	} else if len(command) == 1 && strings.TrimSpace(command[0]) != "" {
+		rule.Command = "^" + regexp.QuoteMeta(command[0]) + "$"
+	} else if info.ToolType == parser.ToolBash {
+		return Rule{}, false
+	}
+	return rule, true
+}
+
+func matchString(pattern, s string) bool {
+	matched, err := regexp.MatchString(pattern, s)
+	return err == nil && matched
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule lists in the config file
+const (
+	RuleListApprove = "approve"
+	RuleListDeny    = "deny"
+	RuleListForbid  = "forbid"
+)
+
+// RuleLists names the rule lists in the order dialogs are checked against them
+var RuleLists = []string{RuleListForbid, RuleListDeny, RuleListApprove}
+
+// AddRule appends rule to the rule list named list in the config file at
+// path, creating the file if it doesn't exist, and returns the rule's number.
+// Comments and other options in the file are kept. Approve rules can't have
+// a name or message.
+func AddRule(path, list string, rule DenyRule) (int, error) {
+	if list == RuleListApprove && (rule.Name != "" || rule.Message != "") {
+		return 0, errors.New("approve rules have no name or message")
+	}
+	if err := rule.validate(); err != nil {
+		return 0, err
+	}
+
+	doc, err := readRuleDocument(path)
+	if err != nil {
+		return 0, err
+	}
+	rules, err := ruleListNode(doc, list, true)
+	if err != nil {
+		return 0, err
+	}
+	rules.Content = append(rules.Content, ruleNode(rule))
+	if err := writeRuleDocument(path, doc); err != nil {
+		return 0, err
+	}
+	return len(rules.Content), nil
+}
+
+// RemoveRule removes the rule numbered number, counting from 1, from the rule
+// list named list in the config file at path
+func RemoveRule(path, list string, number int) error {
+	doc, err := readRuleDocument(path)
+	if err != nil {
+		return err
+	}
+	rules, err := ruleListNode(doc, list, false)
+	if err != nil {
+		return err
+	}
+	if rules == nil || number < 1 || number > len(rules.Content) {
+		return fmt.Errorf("%s has no %s rule %d", path, list, number)
+	}
+	rules.Content = append(rules.Content[:number-1], rules.Content[number:]...)
+	return writeRuleDocument(path, doc)
+}
+
+// readRuleDocument parses the config file at path, or returns an empty
+// document if it doesn't exist
+func readRuleDocument(path string) (*yaml.Node, error) {
+	empty := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		return empty, nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config file %s: options must be a mapping", path)
+	}
+	return &doc, nil
+}
+
+// ruleListNode returns the sequence holding the rule list named list, adding
+// an empty one if it is missing and create is set, or nil otherwise
+func ruleListNode(doc *yaml.Node, list string, create bool) (*yaml.Node, error) {
+	switch list {
+	case RuleListApprove, RuleListDeny, RuleListForbid:
+	default:
+		return nil, fmt.Errorf("unknown rule list: %s (must be approve, deny, or forbid)", list)
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != list {
+			continue
+		}
+		value := root.Content[i+1]
+		if value.Tag == "!!null" {
+			// "deny:" with nothing after it
+			*value = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		if value.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("%s must be a list of rules", list)
+		}
+		return value, nil
+	}
+
+	if !create {
+		return nil, nil
+	}
+	rules := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: list}, rules)
+	return rules, nil
+}
+
+// ruleNode renders the fields rule sets, quoting regular expressions so their
+// backslashes stay as written
+func ruleNode(rule DenyRule) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	fields := []struct {
+		key, value string
+		style      yaml.Style
+	}{
+		{"tool", rule.Tool, 0},
+		{"command", rule.Command, yaml.SingleQuotedStyle},
+		{"file", rule.File, yaml.SingleQuotedStyle},
+		{"name", rule.Name, 0},
+		{"message", rule.Message, 0},
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.value, Style: field.style})
+	}
+	return node
+}
+
+// writeRuleDocument writes doc to path unless the options in it are invalid
+func writeRuleDocument(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	cfg := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
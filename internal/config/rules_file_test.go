@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddRule(t *testing.T) {
+	path := writeConfig(t, `# Team defaults
+auto_reject_wait: 30
+deny:
+  - command: 'rm -rf /'
+`)
+
+	number, err := AddRule(path, RuleListDeny, DenyRule{Rule: Rule{Tool: "Bash", Command: `git push .*--force\b`}, Message: "Never force push."})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if number != 2 {
+		t.Errorf("Expected rule 2, got %d", number)
+	}
+	if _, err := AddRule(path, RuleListApprove, DenyRule{Rule: Rule{Command: `^go test ./...$`}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# Team defaults") {
+		t.Errorf("Expected comments to be kept, got:\n%s", data)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.AutoRejectWait != 30 || len(cfg.Deny) != 2 || len(cfg.Approve) != 1 {
+		t.Fatalf("Unexpected config after adding rules: %+v", cfg)
+	}
+	if cfg.Deny[1].Command != `git push .*--force\b` || cfg.Deny[1].Message != "Never force push." {
+		t.Errorf("Unexpected added deny rule: %+v", cfg.Deny[1])
+	}
+	if cfg.Approve[0].Command != `^go test ./...$` {
+		t.Errorf("Unexpected added approve rule: %+v", cfg.Approve[0])
+	}
+}
+
+func TestAddRuleCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcode", "config.yaml")
+	if _, err := AddRule(path, RuleListForbid, DenyRule{Rule: Rule{Command: `^terraform destroy`}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(cfg.Forbid) != 1 || cfg.Forbid[0].Command != `^terraform destroy` {
+		t.Errorf("Unexpected forbid rules: %+v", cfg.Forbid)
+	}
+}
+
+func TestAddRuleRejectsInvalidRules(t *testing.T) {
+	path := writeConfig(t, "deny:\n")
+	testCases := []struct {
+		name string
+		list string
+		rule DenyRule
+	}{
+		{"unknown list", "allow", DenyRule{Rule: Rule{Tool: "Bash"}}},
+		{"empty rule", RuleListDeny, DenyRule{Message: "no"}},
+		{"invalid pattern", RuleListDeny, DenyRule{Rule: Rule{Command: `rm (`}}},
+		{"approve rule with message", RuleListApprove, DenyRule{Rule: Rule{Tool: "Bash"}, Message: "ok"}},
+		{"approve rule with name", RuleListApprove, DenyRule{Rule: Rule{Tool: "Bash"}, Name: "tests"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := AddRule(path, tc.list, tc.rule); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "deny:\n" {
+		t.Errorf("Expected the file to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestRemoveRule(t *testing.T) {
+	path := writeConfig(t, `approve:
+  - tool: Bash
+    command: '^go test'
+  - tool: Read
+`)
+
+	if err := RemoveRule(path, RuleListApprove, 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(cfg.Approve) != 1 || cfg.Approve[0].Tool != "Read" {
+		t.Errorf("Expected only the Read rule to be left, got %+v", cfg.Approve)
+	}
+
+	for _, tc := range []struct {
+		list   string
+		number int
+	}{{RuleListApprove, 2}, {RuleListApprove, 0}, {RuleListDeny, 1}} {
+		if err := RemoveRule(path, tc.list, tc.number); err == nil {
+			t.Errorf("Expected an error removing %s rule %d", tc.list, tc.number)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxSuggestionDistance is how many letters an unknown option may differ by
+// from a known one for the known one to be suggested, up to half its length
+const MaxSuggestionDistance = 3
+
+// regexOptions are the options whose values are regular expressions, with
+// list indexes left out; every option under patterns is one too
+var regexOptions = map[string]bool{
+	"approve.command":         true,
+	"approve.file":            true,
+	"deny.command":            true,
+	"deny.file":               true,
+	"forbid.command":          true,
+	"forbid.file":             true,
+	"secret_patterns":         true,
+	"redaction_rules.pattern": true,
+}
+
+// listIndex matches the list indexes in an option's name, e.g. "[2]"
+var listIndex = regexp.MustCompile(`\[\d+\]`)
+
+// Problem is a mistake in the config file: an unknown option, a value of the
+// wrong type, or an invalid regular expression
+type Problem struct {
+	Line    int
+	Option  string // Where in the file, e.g. "approve[2].command"
+	Message string
+}
+
+// String returns the problem with its line number
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// SchemaError reports every Problem found in a config file
+type SchemaError struct {
+	Path     string
+	Problems []Problem
+}
+
+// Error lists the problems in the order they appear in the file
+func (e *SchemaError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.String()
+	}
+	return fmt.Sprintf("invalid config file %s: %s", e.Path, strings.Join(problems, "; "))
+}
+
+// checkSchema returns the problems in doc, a parsed config file, in the
+// order they appear. Decoding alone stops at the first mistake and doesn't
+// check regular expressions, so this finds them all up front.
+func checkSchema(doc *yaml.Node) []Problem {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	var problems []Problem
+	checkNode(doc.Content[0], reflect.TypeOf(Config{}), "", &problems)
+	slices.SortStableFunc(problems, func(a, b Problem) int { return a.Line - b.Line })
+	return problems
+}
+
+// checkNode adds to problems the mistakes in node, the value of option,
+// which is decoded into a t
+func checkNode(node *yaml.Node, t reflect.Type, option string, problems *[]Problem) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	report := func(format string, args ...any) {
+		*problems = append(*problems, Problem{Line: node.Line, Option: option, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			report("%s must be a set of options, not %s", describeOption(option), describeNode(node))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, found := fields[key.Value]
+			if !found {
+				*problems = append(*problems, Problem{Line: key.Line, Option: joinOption(option, key.Value), Message: unknownOption(joinOption(option, key.Value), key.Value, fields)})
+				continue
+			}
+			checkNode(value, field, joinOption(option, key.Value), problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			report("%s must be a mapping, not %s", option, describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkNode(node.Content[i+1], t.Elem(), joinOption(option, node.Content[i].Value), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			report("%s must be a list, not %s", option, describeNode(node))
+			return
+		}
+		for i, item := range node.Content {
+			checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", option, i+1), problems)
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			report("%s must be true or false, not %s", option, describeNode(node))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			report("%s must be a whole number, not %s", option, describeNode(node))
+		}
+	case reflect.Float32, reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			report("%s must be a number, not %s", option, describeNode(node))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			report("%s must be text, not %s", option, describeNode(node))
+			return
+		}
+		if isRegexOption(option) {
+			if _, err := regexp.Compile(node.Value); err != nil {
+				report("%s is not a valid regular expression: %v", option, err)
+			}
+		}
+	}
+}
+
+// yamlFields returns the types of the fields of struct type t by their keys
+// in the config file, including those of inlined structs
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-":
+			continue
+		case slices.Contains(strings.Split(options, ","), "inline"):
+			for key, inlined := range yamlFields(field.Type) {
+				fields[key] = inlined
+			}
+			continue
+		case name == "":
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// unknownOption describes option, named key in its block, which isn't one
+// of fields, suggesting the closest that is
+func unknownOption(option, key string, fields map[string]reflect.Type) string {
+	message := "unknown option " + option
+	best, bestDistance := "", min(MaxSuggestionDistance, len(key)/2)+1
+	for name := range fields {
+		distance := editDistance(key, name)
+		if distance < bestDistance || (distance == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	if best != "" {
+		message += fmt.Sprintf(" (did you mean %s?)", best)
+	}
+	return message
+}
+
+// editDistance returns how many letters must be inserted, deleted, or
+// replaced to turn a into b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// isRegexOption reports whether option holds a regular expression
+func isRegexOption(option string) bool {
+	option = listIndex.ReplaceAllString(option, "")
+	return regexOptions[option] || strings.HasPrefix(option, "patterns.")
+}
+
+// joinOption returns the name of key inside option
+func joinOption(option, key string) string {
+	if option == "" {
+		return key
+	}
+	return option + "." + key
+}
+
+// describeOption returns option's name, or "the file" for the whole file
+func describeOption(option string) string {
+	if option == "" {
+		return "the file"
+	}
+	return option
+}
+
+// describeNode describes the value node holds, for saying it's the wrong type
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
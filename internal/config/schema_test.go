@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLoadReportsSchemaProblems(t *testing.T) {
+	path := writeConfig(t, `auto_rejct_wait: 30
+auto_approve: yes
+delays:
+  auto_approve_ms: soon
+approve:
+  - tool: Bash
+    command: '^go (test'
+  - tool: Read
+    fiel: '\.go$'
+tool_policy: [Bash]
+secret_patterns:
+  - 'token-[0-9]+'
+  - '[unclosed'
+patterns:
+  permit: ['Shall I (run']
+`)
+	_, err := Load(path)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Expected a *SchemaError, got %v", err)
+	}
+
+	var lines []int
+	var options []string
+	for _, problem := range schemaErr.Problems {
+		lines = append(lines, problem.Line)
+		options = append(options, problem.Option)
+	}
+	expectedLines := []int{1, 2, 4, 7, 9, 10, 13, 15}
+	expectedOptions := []string{"auto_rejct_wait", "auto_approve", "delays.auto_approve_ms", "approve[1].command", "approve[2].fiel", "tool_policy", "secret_patterns[2]", "patterns.permit[1]"}
+	if !reflect.DeepEqual(lines, expectedLines) || !reflect.DeepEqual(options, expectedOptions) {
+		t.Errorf("Expected problems at lines %v in %v, got %v in %v:\n%v", expectedLines, expectedOptions, lines, options, err)
+	}
+
+	for i, expected := range []string{
+		"line 1: unknown option auto_rejct_wait (did you mean auto_reject_wait?)",
+		`line 2: auto_approve must be true or false, not "yes"`,
+		`line 4: delays.auto_approve_ms must be a whole number, not "soon"`,
+	} {
+		if message := schemaErr.Problems[i].String(); message != expected {
+			t.Errorf("Expected %q, got %q", expected, message)
+		}
+	}
+	if message := schemaErr.Problems[4].Message; message != "unknown option approve[2].fiel (did you mean file?)" {
+		t.Errorf("Expected the rule's misspelled field to be suggested, got %q", message)
+	}
+}
+
+func TestLoadAcceptsValidSchema(t *testing.T) {
+	path := writeConfig(t, `deny:
+  - command: '^git push (-f|--force)'
+    name: no force pushes
+redaction_rules:
+  - name: hosts
+    pattern: '\bcorp\.example\.com\b'
+tool_policy:
+  WebFetch: deny
+quiet_hours:
+  windows: ['23:00-07:00']
+`)
+	if _, err := Load(path); err != nil {
+		t.Errorf("Expected a valid file to load, got %v", err)
+	}
+}
+
+func TestUnknownOptionSuggestions(t *testing.T) {
+	fields := yamlFields(reflect.TypeOf(Config{}))
+	for key, expected := range map[string]string{
+		"auto_aprove": "unknown option auto_aprove (did you mean auto_approve?)",
+		"deyn":        "unknown option deyn (did you mean deny?)",
+		"zzz":         "unknown option zzz",
+		"colour":      "unknown option colour",
+	} {
+		if message := unknownOption(key, key, fields); message != expected {
+			t.Errorf("unknownOption(%q) = %q, expected %q", key, message, expected)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions a ToolPolicy can set for a tool
+const (
+	ToolPolicyAllow = "allow" // Approve as --auto-approve would, for this tool only
+	ToolPolicyAsk   = "ask"   // Ask, even with --auto-approve, --allow-read-only, or a risk policy that approves
+	ToolPolicyDeny  = "deny"  // Reject without asking, like a deny rule
+)
+
+// ToolPolicy maps tool names from the dialog header, such as "Bash" or
+// "WebFetch", to an action. Tools it doesn't name are handled as the other
+// options say. Forbid and deny rules are checked first, and approve rules
+// and remembered approvals still apply to tools set to ask.
+type ToolPolicy map[string]string
+
+// Action returns the action for tool, or "" if the policy doesn't name it
+func (t ToolPolicy) Action(tool string) string {
+	if tool == "" {
+		return ""
+	}
+	return t[tool]
+}
+
+// ParseToolPolicy parses a comma-separated list of TOOL=ACTION pairs, such
+// as "Bash=ask,Read=allow,WebFetch=deny"
+func ParseToolPolicy(text string) (ToolPolicy, error) {
+	policy := ToolPolicy{}
+	for _, pair := range strings.Split(text, ",") {
+		tool, action, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || tool == "" {
+			return nil, fmt.Errorf("invalid tool policy entry: %q (must be TOOL=ACTION)", pair)
+		}
+		policy[tool] = action
+	}
+	return policy, policy.validate()
+}
+
+// ToolPolicyFor returns a policy setting action for each tool in the
+// comma-separated list tools, such as "Edit,Write"
+func ToolPolicyFor(tools, action string) (ToolPolicy, error) {
+	policy := ToolPolicy{}
+	for _, tool := range strings.Split(tools, ",") {
+		tool = strings.TrimSpace(tool)
+		if tool == "" {
+			return nil, fmt.Errorf("invalid tool list: %q (must be comma-separated tool names)", tools)
+		}
+		policy[tool] = action
+	}
+	return policy, policy.validate()
+}
+
+// validate reports the first tool with an unknown action
+func (t ToolPolicy) validate() error {
+	for tool, action := range t {
+		switch action {
+		case ToolPolicyAllow, ToolPolicyAsk, ToolPolicyDeny:
+		default:
+			return fmt.Errorf("invalid tool_policy value for %s: %s (must be allow, ask, or deny)", tool, action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseToolPolicy(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected ToolPolicy
+		valid    bool
+	}{
+		{"Bash=ask,Edit=ask,Read=allow,WebFetch=deny", ToolPolicy{"Bash": "ask", "Edit": "ask", "Read": "allow", "WebFetch": "deny"}, true},
+		{" Bash=ask , mcp__github__create_issue=deny", ToolPolicy{"Bash": "ask", "mcp__github__create_issue": "deny"}, true},
+		{"Bash=ask,Bash=deny", ToolPolicy{"Bash": "deny"}, true},
+		{"Bash", nil, false},
+		{"=allow", nil, false},
+		{"Bash=maybe", nil, false},
+		{"", nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			policy, err := ParseToolPolicy(tc.text)
+			if (err == nil) != tc.valid {
+				t.Fatalf("Expected valid=%v, got %v", tc.valid, err)
+			}
+			if tc.valid && !reflect.DeepEqual(policy, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, policy)
+			}
+		})
+	}
+}
+
+func TestToolPolicyFor(t *testing.T) {
+	testCases := []struct {
+		tools    string
+		expected ToolPolicy
+		valid    bool
+	}{
+		{"Edit", ToolPolicy{"Edit": "allow"}, true},
+		{"Edit, Write,MultiEdit", ToolPolicy{"Edit": "allow", "Write": "allow", "MultiEdit": "allow"}, true},
+		{"Edit,", nil, false},
+		{"", nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.tools, func(t *testing.T) {
+			policy, err := ToolPolicyFor(tc.tools, ToolPolicyAllow)
+			if (err == nil) != tc.valid {
+				t.Fatalf("Expected valid=%v, got %v", tc.valid, err)
+			}
+			if tc.valid && !reflect.DeepEqual(policy, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, policy)
+			}
+		})
+	}
+}
+
+func TestToolPolicyAction(t *testing.T) {
+	policy := ToolPolicy{"Bash": ToolPolicyAsk, "Read": ToolPolicyAllow}
+	if action := policy.Action("Bash"); action != ToolPolicyAsk {
+		t.Errorf("Expected ask for Bash, got %q", action)
+	}
+	if action := policy.Action("Edit"); action != "" {
+		t.Errorf("Expected no action for Edit, got %q", action)
+	}
+	if action := ToolPolicy(nil).Action("Bash"); action != "" {
+		t.Errorf("Expected no action without a policy, got %q", action)
+	}
+}
+
+func TestLoadToolPolicy(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "tool_policy:\n  Bash: ask\n  WebFetch: deny\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(cfg.ToolPolicy, ToolPolicy{"Bash": "ask", "WebFetch": "deny"}) {
+		t.Errorf("Unexpected tool policy: %v", cfg.ToolPolicy)
+	}
+
+	cfg.ToolPolicy["Read"] = "always"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// fileStamp identifies one version of a file
+type fileStamp struct {
+	exists  bool
+	modTime int64
+	size    int64
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// Watch calls changed each time the file at path is created, modified, or
+// removed, checking every interval until stop is closed. Polling works on
+// every platform and with editors that replace the file when saving.
+func Watch(path string, interval time.Duration, stop <-chan struct{}, changed func()) {
+	last := stampFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if stamp := stampFile(path); stamp != last {
+				last = stamp
+				changed()
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	changes := make(chan struct{}, 10)
+	stop := make(chan struct{})
+	defer close(stop)
+	go Watch(path, 10*time.Millisecond, stop, func() { changes <- struct{}{} })
+
+	expectChange := func(step string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a change after %s", step)
+		}
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-changes:
+		t.Fatal("Expected no change before the file is written")
+	default:
+	}
+
+	if err := os.WriteFile(path, []byte("auto_approve: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("creating the file")
+
+	if err := os.WriteFile(path, []byte("auto_approve: false\nlocale: ja\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("modifying the file")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("removing the file")
+}
@@ -0,0 +1,263 @@
+// Package control lets another process talk to a running dcode session over
+// a Unix socket, for example to switch between dialog and auto modes
+// without restarting Claude. Each session listens on its own socket, named
+// after its process ID, in a directory only the user can access.
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// socketSuffix ends the name of every session's socket
+const socketSuffix = ".sock"
+
+// panicFileName is the file in a session directory that dcode panic creates
+// and dcode resume removes
+const panicFileName = "panic"
+
+// dialTimeout bounds connecting to and talking with a session
+const dialTimeout = 2 * time.Second
+
+// SubscribeCommand asks a session for every line it publishes from then on,
+// instead of one reply, until either side hangs up
+const SubscribeCommand = "subscribe"
+
+// publishTimeout bounds sending a published line to one subscriber, so a
+// stalled subscriber can't hold up the session
+const publishTimeout = time.Second
+
+// Handler answers one command line sent to a session. An error is sent
+// back to the client prefixed with "error: ".
+type Handler func(command string) (string, error)
+
+// DefaultDir returns $XDG_RUNTIME_DIR/dcode, or a per-user directory in the
+// system's temporary directory
+func DefaultDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "dcode")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("dcode-%d", os.Getuid()))
+}
+
+// PrepareDir creates dir, where sessions' sockets and the panic file are
+// kept, if it doesn't exist. It refuses a dir that isn't the current user's
+// own with mode 0700, such as one another user made first in the temporary
+// directory: whoever can write there could stand in for a session.
+func PrepareDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !info.IsDir():
+		return fmt.Errorf("%s is not a directory", dir)
+	case !ownedByUser(info):
+		return fmt.Errorf("%s belongs to another user", dir)
+	case info.Mode().Perm() != 0o700:
+		return fmt.Errorf("%s must have mode 0700, not %04o", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// SocketPath returns the socket of the session with process ID pid in dir
+func SocketPath(dir string, pid int) string {
+	return filepath.Join(dir, strconv.Itoa(pid)+socketSuffix)
+}
+
+// PanicPath returns the file whose existence makes every session in dir,
+// including ones started later, reject all requests
+func PanicPath(dir string) string {
+	return filepath.Join(dir, panicFileName)
+}
+
+// Server serves commands on a session's socket, and publishes lines to the
+// clients that subscribed
+type Server struct {
+	listener    net.Listener
+	path        string
+	subscribers map[net.Conn]bool // Guarded by mutex
+	mutex       sync.Mutex
+}
+
+// Listen creates the socket at path, replacing a stale one, and serves each
+// command line sent to it with handle until Close
+func Listen(path string, handle Handler) (*Server, error) {
+	if err := PrepareDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Server{listener: listener, path: path, subscribers: map[net.Conn]bool{}}
+	go s.serve(handle)
+	return s, nil
+}
+
+// Close stops serving, hangs up on subscribers, and removes the socket
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	os.Remove(s.path)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for conn := range s.subscribers {
+		conn.Close()
+		delete(s.subscribers, conn)
+	}
+	return err
+}
+
+// Write publishes p, one or more whole lines, to every subscriber, dropping
+// subscribers it can't be sent to. It never fails, so a Server can be
+// written to like a log.
+func (s *Server) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for conn := range s.subscribers {
+		conn.SetWriteDeadline(time.Now().Add(publishTimeout))
+		if _, err := conn.Write(p); err != nil {
+			conn.Close()
+			delete(s.subscribers, conn)
+		}
+	}
+	return len(p), nil
+}
+
+// subscribe adds conn, which sent SubscribeCommand, to the subscribers until
+// the client hangs up
+func (s *Server) subscribe(conn net.Conn, reader *bufio.Reader) {
+	conn.SetDeadline(time.Time{})
+	s.mutex.Lock()
+	s.subscribers[conn] = true
+	s.mutex.Unlock()
+
+	io.Copy(io.Discard, reader)
+	s.mutex.Lock()
+	delete(s.subscribers, conn)
+	s.mutex.Unlock()
+}
+
+func (s *Server) serve(handle Handler) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(dialTimeout))
+			reader := bufio.NewReader(conn)
+			command, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.TrimSpace(command) == SubscribeCommand {
+				s.subscribe(conn, reader)
+				return
+			}
+			reply, err := handle(strings.TrimSpace(command))
+			if err != nil {
+				reply = "error: " + err.Error()
+			}
+			fmt.Fprintln(conn, reply)
+		}()
+	}
+}
+
+// Send sends command to the session listening at path and returns its reply
+func Send(path, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	reply = strings.TrimSuffix(reply, "\n")
+	if message, found := strings.CutPrefix(reply, "error: "); found {
+		return "", errors.New(message)
+	}
+	return reply, nil
+}
+
+// Subscribe sends SubscribeCommand to the session listening at path and calls
+// handle with each line the session publishes, returning once the session
+// ends or the connection fails
+func Subscribe(path string, handle func(line string)) error {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, SubscribeCommand); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		// A session too old to publish replies with an error instead
+		if message, found := strings.CutPrefix(scanner.Text(), "error: "); found {
+			return errors.New(message)
+		}
+		handle(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// Sessions returns the process IDs of the sessions with a socket in dir,
+// in increasing order, removing sockets nobody listens on anymore
+func Sessions(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		name, found := strings.CutSuffix(entry.Name(), socketSuffix)
+		pid, err := strconv.Atoi(name)
+		if !found || err != nil {
+			continue
+		}
+		conn, err := net.DialTimeout("unix", SocketPath(dir, pid), dialTimeout)
+		if err != nil {
+			os.Remove(SocketPath(dir, pid))
+			continue
+		}
+		conn.Close()
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids, nil
+}
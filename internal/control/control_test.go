@@ -0,0 +1,190 @@
+package control
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dcode")
+	server, err := Listen(SocketPath(dir, 42), func(command string) (string, error) {
+		if command == "fail" {
+			return "", errors.New("something went wrong")
+		}
+		return "got " + command, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	reply, err := Send(SocketPath(dir, 42), "mode auto-approve")
+	if err != nil || reply != "got mode auto-approve" {
+		t.Errorf("Expected the handler's reply, got %q, %v", reply, err)
+	}
+	if _, err := Send(SocketPath(dir, 42), "fail"); err == nil || err.Error() != "something went wrong" {
+		t.Errorf("Expected the handler's error, got %v", err)
+	}
+
+	info, err := os.Stat(SocketPath(dir, 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected the socket to be private, got %v", perm)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dcode")
+	for _, pid := range []int{300, 20} {
+		server, err := Listen(SocketPath(dir, pid), func(string) (string, error) { return "", nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+	}
+	closed, err := Listen(SocketPath(dir, 7), func(string) (string, error) { return "", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave the socket file behind, as a crash would
+	closed.listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	closed.listener.Close()
+	if _, err := os.Stat(SocketPath(dir, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pids, err := Sessions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pids, []int{20, 300}) {
+		t.Errorf("Expected the live sessions, got %v", pids)
+	}
+	if _, err := os.Stat(SocketPath(dir, 7)); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale socket to be removed, got %v", err)
+	}
+
+	if pids, err := Sessions(filepath.Join(dir, "missing")); err != nil || len(pids) != 0 {
+		t.Errorf("Expected no sessions without a directory, got %v, %v", pids, err)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dcode")
+	server, err := Listen(SocketPath(dir, 1), func(string) (string, error) { return "", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	if _, err := Send(SocketPath(dir, 1), "mode"); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("Expected the socket to be gone, got %v", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dcode")
+	server, err := Listen(SocketPath(dir, 42), func(string) (string, error) { return "", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Subscribe(SocketPath(dir, 42), func(line string) { lines <- line })
+	}()
+	for deadline := time.Now().Add(dialTimeout); ; time.Sleep(10 * time.Millisecond) {
+		server.mutex.Lock()
+		subscribed := len(server.subscribers) == 1
+		server.mutex.Unlock()
+		if subscribed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the client to subscribe")
+		}
+	}
+
+	server.Write([]byte("first\nsecond\n"))
+	for _, expected := range []string{"first", "second"} {
+		if line := <-lines; line != expected {
+			t.Errorf("Expected %q, got %q", expected, line)
+		}
+	}
+	if reply, err := Send(SocketPath(dir, 42), "mode"); err != nil || reply != "" {
+		t.Errorf("Expected commands to be answered while a client subscribes, got %q, %v", reply, err)
+	}
+
+	server.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the subscription to end without an error, got %v", err)
+		}
+	case <-time.After(dialTimeout):
+		t.Error("Expected the subscription to end with the session")
+	}
+}
+
+func TestSubscribeToOldSession(t *testing.T) {
+	dir := t.TempDir()
+	path := SocketPath(dir, 42)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("error: unknown command: subscribe\n"))
+	}()
+
+	err = Subscribe(path, func(line string) { t.Errorf("Expected no lines, got %q", line) })
+	if err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("Expected the session's error, got %v", err)
+	}
+}
+
+func TestPrepareDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dcode")
+	if err := PrepareDir(dir); err != nil {
+		t.Fatalf("Expected a new directory to be accepted, got %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Fatalf("Expected a private directory, got %v, %v", info, err)
+	}
+
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrepareDir(dir); err == nil {
+		t.Error("Expected a directory others can write to to be refused")
+	}
+	if _, err := Listen(SocketPath(dir, 1), func(string) (string, error) { return "", nil }); err == nil {
+		t.Error("Expected Listen to refuse the directory")
+	}
+
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrepareDir(link); err == nil {
+		t.Error("Expected a symbolic link to be refused")
+	}
+}
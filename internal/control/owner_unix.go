@@ -0,0 +1,15 @@
+//go:build !windows && !plan9
+
+package control
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByUser reports whether the file described by info belongs to the
+// current user
+func ownedByUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
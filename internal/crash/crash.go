@@ -0,0 +1,102 @@
+// Package crash writes a report when dcode panics, with the stack, the
+// session's state, and the last lines of Claude's output, so that a crash can
+// be filed as a bug report. Callers mask secrets in what they put in a report.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxReports is how many reports are kept; older ones are deleted
+const MaxReports = 10
+
+// fileTimeFormat stamps report file names, sorting them oldest first
+const fileTimeFormat = "20060102-150405.000"
+
+// Report describes one crash
+type Report struct {
+	Time    time.Time
+	Version string            // The dcode build that crashed, e.g. "dcode 1.2.0 (commit ...)"
+	Panic   string            // The value dcode panicked with
+	Stack   string            // Stack of the goroutine that panicked
+	State   map[string]string // The session's mode and prompt, by name
+	Context []string          // The last lines of Claude's output
+}
+
+// DefaultDir returns the directory reports are written to, or "" if the
+// user's cache directory is unknown
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dcode", "crashes")
+}
+
+// String returns the report as text to attach to a bug report
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "dcode crash report")
+	fmt.Fprintf(&b, "Time:     %s\n", r.Time.Format(time.RFC3339))
+	if r.Version != "" {
+		fmt.Fprintf(&b, "Version:  %s\n", r.Version)
+	}
+	fmt.Fprintf(&b, "Platform: %s/%s, %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	fmt.Fprintf(&b, "Panic:    %s\n", r.Panic)
+
+	if len(r.State) > 0 {
+		fmt.Fprintln(&b, "\nState:")
+		names := make([]string, 0, len(r.State))
+		for name := range r.State {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "  %s: %s\n", name, r.State[name])
+		}
+	}
+
+	fmt.Fprintln(&b, "\nRecent output:")
+	if len(r.Context) == 0 {
+		fmt.Fprintln(&b, "  (none)")
+	}
+	for _, line := range r.Context {
+		fmt.Fprintln(&b, "  "+line)
+	}
+
+	fmt.Fprintln(&b, "\nStack:")
+	fmt.Fprint(&b, r.Stack)
+	return b.String()
+}
+
+// Write saves r in dir, creating it if needed, deletes all but the newest
+// MaxReports there, and returns the report's path
+func Write(dir string, r Report) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash-"+r.Time.Local().Format(fileTimeFormat)+".txt")
+	if err := os.WriteFile(path, []byte(r.String()), 0o600); err != nil {
+		return "", err
+	}
+	prune(dir)
+	return path, nil
+}
+
+// prune deletes all but the newest MaxReports reports in dir
+func prune(dir string) {
+	reports, err := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+	if err != nil || len(reports) <= MaxReports {
+		return
+	}
+	sort.Strings(reports)
+	for _, report := range reports[:len(reports)-MaxReports] {
+		os.Remove(report)
+	}
+}
@@ -0,0 +1,65 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var report = Report{
+	Time:    time.Date(2025, 1, 1, 9, 30, 12, 0, time.UTC),
+	Version: "dcode 1.2.0 (commit 9fceb02d0ae5)",
+	Panic:   "runtime error: index out of range [3] with length 3",
+	Stack:   "goroutine 1 [running]:\nmain.main()\n",
+	State:   map[string]string{"mode": "dialog", "prompt": "permission"},
+	Context: []string{"╭───╮", "│ Bash command │"},
+}
+
+func TestReportString(t *testing.T) {
+	text := report.String()
+	for _, expected := range []string{
+		"dcode crash report\nTime:     2025-01-01T09:30:12Z\nVersion:  dcode 1.2.0 (commit 9fceb02d0ae5)\n",
+		"Panic:    runtime error: index out of range [3] with length 3\n",
+		"\nState:\n  mode: dialog\n  prompt: permission\n",
+		"\nRecent output:\n  ╭───╮\n  │ Bash command │\n",
+		"\nStack:\ngoroutine 1 [running]:\nmain.main()\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, text)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crashes")
+
+	path, err := Write(dir, report)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != report.String() {
+		t.Errorf("Expected the report in %s, got %q, %v", path, data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a private file, got %v, %v", info, err)
+	}
+}
+
+func TestWriteKeepsNewestReports(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < MaxReports+2; i++ {
+		r := report
+		r.Time = report.Time.Add(time.Duration(i) * time.Second)
+		if _, err := Write(dir, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports, _ := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+	if len(reports) != MaxReports || strings.Contains(strings.Join(reports, " "), report.Time.Local().Format(fileTimeFormat)) {
+		t.Errorf("Expected the newest %d reports, got %q", MaxReports, reports)
+	}
+}
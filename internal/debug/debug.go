@@ -1,113 +1,223 @@
+// Package debug writes dcode's diagnostic log: leveled, structured records
+// through log/slog, appended to a file chosen with --log-file and rotated as
+// log_rotation says. Logging is off
+// until Configure or Enable is called, and secrets are masked in every record
+// once SetRedact is. Printf and Println remain for older call sites and log
+// their text as a message at LevelDebug.
 package debug
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"io"
+	"log/slog"
+	"strings"
 	"sync"
-)
+	"sync/atomic"
 
-var (
-	instance *Logger
-	once     sync.Once
+	"github.com/takahirom/dialog-code/internal/logfile"
 )
 
-// Logger handles debug logging with singleton pattern
-type Logger struct {
-	enabled bool
-	file    *os.File
-	mutex   sync.Mutex
-}
+// DefaultPath is the log file used when Options doesn't name one
+const DefaultPath = "debug_output.log"
 
-// GetLogger returns the singleton logger instance
-func GetLogger() *Logger {
-	once.Do(func() {
-		instance = &Logger{
-			enabled: false,
-			file:    nil,
-		}
-	})
-	return instance
+// Options chooses where the log is written and which records it keeps
+type Options struct {
+	Path     string           // File records are appended to, or "" for DefaultPath
+	Level    slog.Level       // Records below this level are dropped
+	JSON     bool             // Write each record as a JSON object instead of key=value text
+	Rotation logfile.Rotation // When the file is rotated and which rotated copies are kept
 }
 
-// Enable turns on debug logging and creates the debug file
-func (l *Logger) Enable() error {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
+var (
+	logger atomic.Pointer[slog.Logger] // nil while logging is off
+	file   *logfile.Writer             // Guarded by mutex
+	redact func(string) string         // Masks secrets, or nil; guarded by mutex
+	mutex  sync.Mutex
 
-	if l.enabled {
-		return nil
-	}
+	requestID atomic.Pointer[string] // Added to every record while a prompt is handled
+)
 
-	file, err := os.OpenFile("debug_output.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// Configure turns logging on as options describe, replacing any earlier
+// configuration
+func Configure(options Options) error {
+	path := options.Path
+	if path == "" {
+		path = DefaultPath
+	}
+	opened, err := logfile.Open(path, options.Rotation)
 	if err != nil {
 		return err
 	}
 
-	l.file = file
-	l.enabled = true
+	mutex.Lock()
+	defer mutex.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = opened
+	logger.Store(slog.New(redactHandler{newHandler(opened, options)}))
 	return nil
 }
 
-// Disable turns off debug logging and closes the file
-func (l *Logger) Disable() {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
+// newHandler returns a handler writing records selected by options to w
+func newHandler(w io.Writer, options Options) slog.Handler {
+	handlerOptions := &slog.HandlerOptions{Level: options.Level}
+	if options.JSON {
+		return slog.NewJSONHandler(w, handlerOptions)
+	}
+	return slog.NewTextHandler(w, handlerOptions)
+}
 
-	if !l.enabled {
-		return
+// Enable turns on logging of every level to DefaultPath, as --debug does
+func Enable() error {
+	return Configure(Options{Level: slog.LevelDebug})
+}
+
+// Disable turns off logging and closes the log file
+func Disable() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	logger.Store(nil)
+	if file != nil {
+		file.Close()
+		file = nil
 	}
+}
+
+// SetRedact sets the function that masks secrets in every record written
+func SetRedact(fn func(string) string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	redact = fn
+}
 
-	if l.file != nil {
-		l.file.Close()
-		l.file = nil
+// SetRequestID tags every record from now on with id, the request ID of the
+// prompt being handled, until it's set to ""
+func SetRequestID(id string) {
+	if id == "" {
+		requestID.Store(nil)
+		return
 	}
-	l.enabled = false
+	requestID.Store(&id)
+}
+
+// IsEnabled returns whether logging is on
+func IsEnabled() bool {
+	return logger.Load() != nil
 }
 
-// IsEnabled returns whether debug logging is enabled
-func (l *Logger) IsEnabled() bool {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	return l.enabled
+// ParseLevel returns the level named debug, info, warn, or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
 }
 
-// Printf writes formatted debug output if enabled
-func (l *Logger) Printf(format string, args ...interface{}) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
+// Debug logs msg with the key-value pairs in args at LevelDebug
+func Debug(msg string, args ...any) {
+	log(slog.LevelDebug, msg, args...)
+}
 
-	if l.enabled && l.file != nil {
-		fmt.Fprintf(l.file, format, args...)
+// Info logs msg with the key-value pairs in args at LevelInfo
+func Info(msg string, args ...any) {
+	log(slog.LevelInfo, msg, args...)
+}
+
+// Warn logs msg with the key-value pairs in args at LevelWarn
+func Warn(msg string, args ...any) {
+	log(slog.LevelWarn, msg, args...)
+}
+
+// Error logs msg with the key-value pairs in args at LevelError
+func Error(msg string, args ...any) {
+	log(slog.LevelError, msg, args...)
+}
+
+// Printf logs formatted text at LevelDebug, for call sites that predate
+// structured logging
+func Printf(format string, args ...interface{}) {
+	if IsEnabled() {
+		Debug(legacyMessage(fmt.Sprintf(format, args...)))
 	}
 }
 
-// Println writes debug output with newline if enabled
-func (l *Logger) Println(args ...interface{}) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
+// Println logs its arguments at LevelDebug, for call sites that predate
+// structured logging
+func Println(args ...interface{}) {
+	if IsEnabled() {
+		Debug(legacyMessage(fmt.Sprintln(args...)))
+	}
+}
+
+// legacyMessage drops the "[DEBUG]" prefix and trailing newline that Printf
+// call sites write, since the record carries its level and ends its line
+func legacyMessage(text string) string {
+	return strings.TrimSpace(strings.TrimPrefix(text, "[DEBUG] "))
+}
 
-	if l.enabled && l.file != nil {
-		fmt.Fprintln(l.file, args...)
+func log(level slog.Level, msg string, args ...any) {
+	if l := logger.Load(); l != nil {
+		if id := requestID.Load(); id != nil {
+			args = append(args, "request_id", *id)
+		}
+		l.Log(context.Background(), level, msg, args...)
 	}
 }
 
-// Package-level convenience functions
-func Enable() error {
-	return GetLogger().Enable()
+// currentRedact returns the function set with SetRedact, or nil
+func currentRedact() func(string) string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return redact
 }
 
-func Disable() {
-	GetLogger().Disable()
+// redactHandler masks secrets in the message and attributes of each record
+// before passing it on
+type redactHandler struct {
+	slog.Handler
 }
 
-func IsEnabled() bool {
-	return GetLogger().IsEnabled()
+func (h redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	fn := currentRedact()
+	if fn == nil {
+		return h.Handler.Handle(ctx, record)
+	}
+	masked := slog.NewRecord(record.Time, record.Level, fn(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		masked.AddAttrs(redactAttr(attr, fn))
+		return true
+	})
+	return h.Handler.Handle(ctx, masked)
 }
 
-func Printf(format string, args ...interface{}) {
-	GetLogger().Printf(format, args...)
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if fn := currentRedact(); fn != nil {
+		for i, attr := range attrs {
+			attrs[i] = redactAttr(attr, fn)
+		}
+	}
+	return redactHandler{h.Handler.WithAttrs(attrs)}
 }
 
-func Println(args ...interface{}) {
-	GetLogger().Println(args...)
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr returns attr with fn applied to its text, including that of
+// values such as errors that are written as text
+func redactAttr(attr slog.Attr, fn func(string) string) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString, slog.KindAny:
+		return slog.String(attr.Key, fn(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		masked := make([]any, len(group))
+		for i, member := range group {
+			masked[i] = redactAttr(member, fn)
+		}
+		return slog.Group(attr.Key, masked...)
+	}
+	return attr
 }
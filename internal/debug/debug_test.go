@@ -0,0 +1,138 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configure turns logging on to a temporary file with options, returning a
+// function reading what was written
+func configure(t *testing.T, options Options) func() string {
+	t.Helper()
+	options.Path = filepath.Join(t.TempDir(), "dcode.log")
+	if err := Configure(options); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		Disable()
+		SetRedact(nil)
+	})
+	return func() string {
+		data, err := os.ReadFile(options.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	read := configure(t, Options{Level: slog.LevelInfo})
+
+	Debug("hidden")
+	Printf("[DEBUG] also hidden %d\n", 1)
+	Info("reloaded config", "path", "/tmp/config.yaml")
+	Warn("control socket unavailable", "error", errors.New("permission denied"))
+
+	output := read()
+	if strings.Contains(output, "hidden") {
+		t.Errorf("Expected debug records to be dropped, got:\n%s", output)
+	}
+	for _, expected := range []string{
+		`level=INFO msg="reloaded config" path=/tmp/config.yaml`,
+		`level=WARN msg="control socket unavailable" error="permission denied"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPrintfShim(t *testing.T) {
+	read := configure(t, Options{Level: slog.LevelDebug})
+
+	Printf("[DEBUG] BufferedWriter: dropped %d bytes\n", 12)
+	Println("plain", "line")
+
+	output := read()
+	for _, expected := range []string{
+		`level=DEBUG msg="BufferedWriter: dropped 12 bytes"`,
+		`level=DEBUG msg="plain line"`,
+	} {
+		if !strings.Contains(output, expected+"\n") {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	read := configure(t, Options{Level: slog.LevelDebug, JSON: true})
+
+	Info("switched mode", "mode", "auto-reject")
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(read()), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %v", err)
+	}
+	if record["level"] != "INFO" || record["msg"] != "switched mode" || record["mode"] != "auto-reject" {
+		t.Errorf("Expected the record's fields, got %v", record)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	read := configure(t, Options{Level: slog.LevelDebug})
+	SetRedact(func(text string) string {
+		return strings.ReplaceAll(text, "hunter2", "****")
+	})
+
+	Printf("password hunter2\n")
+	Error("export failed", "error", errors.New("bad token hunter2"), slog.Group("request", "command", "login hunter2"))
+
+	if output := read(); strings.Contains(output, "hunter2") || strings.Count(output, "****") != 3 {
+		t.Errorf("Expected every secret to be masked, got:\n%s", output)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	Disable()
+	if IsEnabled() {
+		t.Fatal("Expected logging to be off")
+	}
+	// Nothing to write to, and nothing to fail
+	Printf("ignored\n")
+	Error("ignored")
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if level, err := ParseLevel(name); err != nil || level != expected {
+			t.Errorf("Expected %s to be %v, got %v, %v", name, expected, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	read := configure(t, Options{Level: slog.LevelInfo})
+	t.Cleanup(func() { SetRequestID("") })
+
+	SetRequestID("0123abcd")
+	Info("showed dialog")
+	SetRequestID("")
+	Info("idle")
+
+	output := read()
+	if !strings.Contains(output, `msg="showed dialog" request_id=0123abcd`) {
+		t.Errorf("Expected the record to be tagged with the request ID, got:\n%s", output)
+	}
+	if strings.Contains(output, `msg=idle request_id`) {
+		t.Errorf("Expected no request ID once it's cleared, got:\n%s", output)
+	}
+}
@@ -0,0 +1,170 @@
+// Package decisions logs the requests dcode answered without asking and what
+// decided each one, so a surprising decision can be traced with "dcode
+// explain". The log is a JSON Lines file shared by every dcode process.
+package decisions
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Decisions an Entry can record
+const (
+	Approved = "approved"
+	Rejected = "rejected"
+)
+
+// MaxLogBytes is how large the log may grow before its oldest entries are
+// dropped, keeping the newest half
+const MaxLogBytes = 1 << 20
+
+// Entry records one request answered without asking
+type Entry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Request  string    `json:"request"`          // Tool and command or files, with secrets masked
+	Risk     string    `json:"risk"`             // Rated risk and its reason, e.g. "high (force push)"
+	Decision string    `json:"decision"`         // Approved or Rejected
+	Rule     string    `json:"rule"`             // What decided, e.g. "deny rule 2" or "quiet_hours"
+	Detail   string    `json:"detail,omitempty"` // What the rule matched when it decided
+}
+
+// Log appends entries to a file
+type Log struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewLog returns a log stored at path. The file is created on the first entry.
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// DefaultPath returns ~/.cache/dcode/decisions.jsonl, honoring
+// $XDG_CACHE_HOME, or "" if the cache directory is unknown
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dcode", "decisions.jsonl")
+}
+
+// NewID returns a short random ID for an entry
+func NewID() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Add appends entry to the log
+func (l *Log) Add(entry Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return l.trim()
+}
+
+// Find returns the entry with id, or false if the log has none
+func (l *Log) Find(id string) (Entry, bool, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == id {
+			return entries[i], true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Entries returns every entry in the log, oldest first. A missing log has
+// none, and lines that can't be read are skipped.
+func (l *Log) Entries() ([]Entry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, MaxLogBytes)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && entry.ID != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// trim drops the oldest entries once the log is larger than MaxLogBytes,
+// writing the rest through a temporary file so other dcode processes never
+// read a partial log
+func (l *Log) trim() error {
+	info, err := os.Stat(l.path)
+	if err != nil || info.Size() <= MaxLogBytes {
+		return err
+	}
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+	// Keep whole lines only
+	start := len(data) - MaxLogBytes/2
+	if start > 0 && data[start-1] != '\n' {
+		start += bytes.IndexByte(data[start:], '\n') + 1
+	}
+	keep := data[start:]
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".decisions-*.jsonl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(keep); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
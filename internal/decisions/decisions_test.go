@@ -0,0 +1,85 @@
+package decisions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "dcode", "decisions.jsonl"))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if entries, err := log.Entries(); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected an empty log, got %v, %v", entries, err)
+	}
+
+	first := Entry{ID: "0000aaaa", Time: now, Request: "Bash: git push --force", Risk: "high (force push)", Decision: Rejected, Rule: "deny rule 1", Detail: "command='^git push'"}
+	second := Entry{ID: "0000bbbb", Time: now.Add(time.Minute), Request: "Read: /repo/go.mod", Risk: "low", Decision: Approved, Rule: "allow_read_only"}
+	for _, entry := range []Entry{first, second} {
+		if err := log.Add(entry); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	entries, err := log.Entries()
+	if err != nil || len(entries) != 2 || entries[0] != first || entries[1] != second {
+		t.Fatalf("Expected both entries in order, got %+v, %v", entries, err)
+	}
+	if entry, ok, err := log.Find("0000aaaa"); err != nil || !ok || entry != first {
+		t.Errorf("Expected to find the first entry, got %+v, %v, %v", entry, ok, err)
+	}
+	if _, ok, err := log.Find("ffffffff"); err != nil || ok {
+		t.Errorf("Expected no entry, got %v, %v", ok, err)
+	}
+}
+
+func TestLogSkipsUnreadableLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	if err := os.WriteFile(path, []byte("not json\n{\"id\":\"0000aaaa\",\"rule\":\"auto_reject\"}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := NewLog(path).Entries()
+	if err != nil || len(entries) != 1 || entries[0].Rule != "auto_reject" {
+		t.Errorf("Expected the readable entry, got %+v, %v", entries, err)
+	}
+}
+
+func TestLogTrimsOldEntries(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "decisions.jsonl"))
+	request := strings.Repeat("x", 1000)
+	count := MaxLogBytes/len(request) + 10
+	for i := 0; i < count; i++ {
+		if err := log.Add(Entry{ID: NewID(), Request: request}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	last := Entry{ID: "0000ffff", Request: request}
+	if err := log.Add(last); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := log.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) >= count || len(entries) < count/4 {
+		t.Errorf("Expected old entries to be dropped, got %d of %d", len(entries), count+1)
+	}
+	if entries[len(entries)-1] != last {
+		t.Errorf("Expected the newest entry to be kept, got %+v", entries[len(entries)-1])
+	}
+}
+
+func TestNewID(t *testing.T) {
+	id := NewID()
+	if len(id) != 8 || strings.Trim(id, "0123456789abcdef") != "" {
+		t.Errorf("Expected 8 hex digits, got %q", id)
+	}
+	if NewID() == id {
+		t.Error("Expected different IDs")
+	}
+}
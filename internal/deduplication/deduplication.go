@@ -2,7 +2,10 @@ package deduplication
 
 import (
 	"regexp"
+	"sort"
 	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
 )
 
 // NewDeduplicationManager creates a new deduplication manager with the given config
@@ -16,7 +19,7 @@ func NewDeduplicationManagerWithTimeProvider(config Config, timeProvider TimePro
 		processedPrompts: make(map[string]ProcessedEntry),
 		cooldownStates:   make(map[string]CooldownState),
 		config:           config,
-		ansiRegex:        regexp.MustCompile(`\x1b\[[0-9;?]*[mKHJhlABCDEFGPST]`),
+		ansiRegex:        regexp.MustCompile(parser.AnsiEscapePattern),
 		stopCleanup:      make(chan struct{}),
 		timeProvider:     timeProvider,
 	}
@@ -111,6 +114,39 @@ func (dm *DeduplicationManager) MarkPromptProcessed(prompt string) {
 	}
 }
 
+// ClaimAnswer reports whether an answer to the prompt with id and text may be
+// typed into Claude, claiming the prompt if so. A prompt is answered once:
+// claims for an id already answered fail for PromptDuplicationSeconds. Claims
+// for the same text also fail for ProcessingCooldownMs after an answer, so a
+// re-rendered prompt taken for a new one isn't answered twice. An empty id
+// only checks the text.
+func (dm *DeduplicationManager) ClaimAnswer(id, text string) bool {
+	idKey := answerKeyPrefix + id
+	textKey := answerKeyPrefix + dm.StripAnsi(text)
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	now := dm.timeProvider.Now()
+
+	if entry, answered := dm.processedPrompts[idKey]; id != "" && answered &&
+		now.Sub(entry.ProcessedAt) < time.Duration(dm.config.PromptDuplicationSeconds)*time.Second {
+		return false
+	}
+	if state, exists := dm.cooldownStates[textKey]; exists && now.Before(state.CooldownUntil) {
+		return false
+	}
+
+	if id != "" {
+		dm.processedPrompts[idKey] = ProcessedEntry{ProcessedAt: now, Count: 1}
+	}
+	dm.cooldownStates[textKey] = CooldownState{
+		LastProcessed: now,
+		JustShown:     true,
+		CooldownUntil: now.Add(time.Duration(dm.config.ProcessingCooldownMs) * time.Millisecond),
+	}
+	return true
+}
+
 // SetCooldown sets a cooldown state for a specific key
 func (dm *DeduplicationManager) SetCooldown(key string, duration time.Duration) {
 	dm.mutex.Lock()
@@ -152,6 +188,25 @@ func (dm *DeduplicationManager) GetStats() (processedCount, cooldownCount int) {
 	return len(dm.processedPrompts), len(dm.cooldownStates)
 }
 
+// Snapshot returns the ages and counts of the processed prompts and the
+// state of the cooldowns, for finding out why a prompt was skipped
+func (dm *DeduplicationManager) Snapshot() Stats {
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+	now := dm.timeProvider.Now()
+
+	stats := Stats{Prompts: []PromptStats{}, Cooldowns: []CooldownStats{}}
+	for key, entry := range dm.processedPrompts {
+		stats.Prompts = append(stats.Prompts, PromptStats{Key: key, Age: now.Sub(entry.ProcessedAt), Count: entry.Count})
+	}
+	for key, state := range dm.cooldownStates {
+		stats.Cooldowns = append(stats.Cooldowns, CooldownStats{Key: key, Age: now.Sub(state.LastProcessed), Remaining: max(state.CooldownUntil.Sub(now), 0)})
+	}
+	sort.Slice(stats.Prompts, func(i, j int) bool { return stats.Prompts[i].Key < stats.Prompts[j].Key })
+	sort.Slice(stats.Cooldowns, func(i, j int) bool { return stats.Cooldowns[i].Key < stats.Cooldowns[j].Key })
+	return stats
+}
+
 // ClearExpiredEntries removes expired entries from both processed prompts and cooldown states
 func (dm *DeduplicationManager) ClearExpiredEntries() {
 	dm.mutex.Lock()
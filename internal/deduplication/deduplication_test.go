@@ -79,6 +79,12 @@ func TestShouldProcessPrompt_AnsiStripping(t *testing.T) {
 	if stripped != promptWithoutAnsi {
 		t.Errorf("ANSI stripping failed: expected %q, got %q", promptWithoutAnsi, stripped)
 	}
+
+	// OSC titles and hyperlinks must not make the same prompt look new
+	promptWithOsc := "\x1b]0;Claude\x07\x1b]8;;file:///tmp\x1b\\Do you want to proceed?\x1b]8;;\x1b\\"
+	if stripped := dm.StripAnsi(promptWithOsc); stripped != promptWithoutAnsi {
+		t.Errorf("OSC stripping failed: expected %q, got %q", promptWithoutAnsi, stripped)
+	}
 }
 
 func TestCooldownMechanism(t *testing.T) {
@@ -329,3 +335,67 @@ func TestPeriodicCleanup(t *testing.T) {
 			processedCount, cooldownCount)
 	}
 }
+
+func TestClaimAnswer(t *testing.T) {
+	config := DefaultConfig()
+	config.ProcessingCooldownMs = 50
+
+	mockTime := NewMockTimeProvider(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+	dm := NewDeduplicationManagerWithTimeProvider(config, mockTime)
+	defer dm.Close()
+
+	if !dm.ClaimAnswer("1", "Do you want to proceed?") {
+		t.Fatal("First answer to a prompt should be allowed")
+	}
+	if dm.ClaimAnswer("1", "Do you want to proceed?") {
+		t.Error("Second answer to the same prompt should be refused")
+	}
+	if dm.ClaimAnswer("2", "\x1b[1mDo you want to proceed?\x1b[0m") {
+		t.Error("Answer to a re-rendered prompt should be refused during the cooldown")
+	}
+	if !dm.ClaimAnswer("3", "Do you want to make this edit?") {
+		t.Error("Answer to a different prompt should be allowed")
+	}
+
+	mockTime.AdvanceTime(time.Duration(config.ProcessingCooldownMs+20) * time.Millisecond)
+	if !dm.ClaimAnswer("4", "Do you want to proceed?") {
+		t.Error("Answer to a new prompt with the same text should be allowed after the cooldown")
+	}
+	if dm.ClaimAnswer("1", "Do you want to proceed again?") {
+		t.Error("Answered prompt should stay answered")
+	}
+	if !dm.ClaimAnswer("", "Press Enter to continue") || dm.ClaimAnswer("", "Press Enter to continue") {
+		t.Error("Answers without an id should only be limited by the cooldown")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	config := DefaultConfig()
+	config.DialogCooldownMs = 500
+
+	mockTime := NewMockTimeProvider(time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC))
+	dm := NewDeduplicationManagerWithTimeProvider(config, mockTime)
+	defer dm.Close()
+
+	dm.MarkPromptProcessed("\x1b[1mDo you want to proceed?\x1b[0m")
+	dm.MarkPromptProcessed("Do you want to proceed?")
+	dm.SetDialogCooldown("main_dialog")
+	mockTime.AdvanceTime(200 * time.Millisecond)
+	dm.SetProcessingCooldown("answer")
+	mockTime.AdvanceTime(400 * time.Millisecond)
+
+	stats := dm.Snapshot()
+	if len(stats.Prompts) != 1 || stats.Prompts[0] != (PromptStats{Key: "Do you want to proceed?", Age: 600 * time.Millisecond, Count: 2}) {
+		t.Errorf("Expected one prompt processed twice 600ms ago, got %+v", stats.Prompts)
+	}
+	expected := []CooldownStats{
+		{Key: "answer", Age: 400 * time.Millisecond, Remaining: 100 * time.Millisecond},
+		{Key: "main_dialog", Age: 600 * time.Millisecond},
+	}
+	if len(stats.Cooldowns) != 2 || stats.Cooldowns[0] != expected[0] || stats.Cooldowns[1] != expected[1] {
+		t.Errorf("Expected cooldowns %+v, got %+v", expected, stats.Cooldowns)
+	}
+	if stats.ActiveCooldowns() != 1 {
+		t.Errorf("Expected one active cooldown, got %d", stats.ActiveCooldowns())
+	}
+}
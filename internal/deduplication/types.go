@@ -72,6 +72,10 @@ func DefaultConfig() Config {
 	}
 }
 
+// answerKeyPrefix keeps the entries ClaimAnswer records apart from prompts and
+// cooldowns recorded under their own names
+const answerKeyPrefix = "answer|"
+
 // CooldownState tracks cooldown information for a specific key
 type CooldownState struct {
 	LastProcessed time.Time
@@ -85,6 +89,40 @@ type ProcessedEntry struct {
 	Count       int // How many times this prompt has been processed
 }
 
+// PromptStats describes a processed prompt or answer: how long ago it was
+// last processed and how often
+type PromptStats struct {
+	Key   string        `json:"key"`
+	Age   time.Duration `json:"age"`
+	Count int           `json:"count"`
+}
+
+// CooldownStats describes a cooldown: how long ago it started and how much
+// of it is left, or 0 once it has run out
+type CooldownStats struct {
+	Key       string        `json:"key"`
+	Age       time.Duration `json:"age"`
+	Remaining time.Duration `json:"remaining"`
+}
+
+// Stats is what a DeduplicationManager holds at one moment, each list sorted
+// by key
+type Stats struct {
+	Prompts   []PromptStats   `json:"prompts"`
+	Cooldowns []CooldownStats `json:"cooldowns"`
+}
+
+// ActiveCooldowns returns the number of cooldowns that haven't run out
+func (s Stats) ActiveCooldowns() int {
+	active := 0
+	for _, cooldown := range s.Cooldowns {
+		if cooldown.Remaining > 0 {
+			active++
+		}
+	}
+	return active
+}
+
 // DeduplicationManager manages prompt deduplication and cooldown logic
 type DeduplicationManager struct {
 	processedPrompts map[string]ProcessedEntry
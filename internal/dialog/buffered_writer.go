@@ -0,0 +1,124 @@
+package dialog
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/takahirom/dialog-code/internal/debug"
+)
+
+// BackpressurePolicy controls what BufferedWriter does when its buffer is full
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock makes Write wait until the underlying writer catches up
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDrop discards chunks that don't fit in the buffer
+	BackpressureDrop
+)
+
+// String returns the flag representation of the policy
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureDrop:
+		return "drop"
+	default:
+		return "block"
+	}
+}
+
+// ParseBackpressurePolicy parses a policy name ("block" or "drop")
+func ParseBackpressurePolicy(s string) (BackpressurePolicy, error) {
+	switch s {
+	case "block":
+		return BackpressureBlock, nil
+	case "drop":
+		return BackpressureDrop, nil
+	}
+	return BackpressureBlock, fmt.Errorf("unknown backpressure policy %q (must be block or drop)", s)
+}
+
+// BufferedWriter decouples the caller from a slow writer by queueing chunks
+// and writing them from a dedicated goroutine
+type BufferedWriter struct {
+	writer  io.Writer
+	policy  BackpressurePolicy
+	chunks  chan []byte
+	done    chan struct{}
+	mutex   sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// NewBufferedWriter creates a BufferedWriter holding up to size pending chunks
+func NewBufferedWriter(writer io.Writer, size int, policy BackpressurePolicy) *BufferedWriter {
+	if size < 1 {
+		size = 1
+	}
+	w := &BufferedWriter{
+		writer: writer,
+		policy: policy,
+		chunks: make(chan []byte, size),
+		done:   make(chan struct{}),
+	}
+	go w.drain()
+	return w
+}
+
+func (w *BufferedWriter) Write(p []byte) (n int, err error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	// Copy since callers are allowed to reuse p after Write returns
+	chunk := make([]byte, len(p))
+	copy(chunk, p)
+
+	if w.policy == BackpressureDrop {
+		select {
+		case w.chunks <- chunk:
+		default:
+			w.dropped.Add(1)
+			debug.Debug("display buffer full; dropped output", "bytes", len(p))
+		}
+		return len(p), nil
+	}
+
+	w.chunks <- chunk
+	return len(p), nil
+}
+
+// Close flushes pending chunks and stops the writer goroutine
+func (w *BufferedWriter) Close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.chunks)
+	w.mutex.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// Dropped returns the number of chunks discarded under BackpressureDrop
+func (w *BufferedWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// drain writes queued chunks to the underlying writer until closed
+func (w *BufferedWriter) drain() {
+	defer close(w.done)
+	for chunk := range w.chunks {
+		if err := writeAll(w.writer, chunk); err != nil {
+			debug.Warn("display write failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package dialog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter stalls every Write until release is closed
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestParseBackpressurePolicy(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected BackpressurePolicy
+		wantErr  bool
+	}{
+		{"block", BackpressureBlock, false},
+		{"drop", BackpressureDrop, false},
+		{"", BackpressureBlock, true},
+		{"skip", BackpressureBlock, true},
+	}
+
+	for _, tc := range testCases {
+		result, err := ParseBackpressurePolicy(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseBackpressurePolicy(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+		}
+		if result != tc.expected {
+			t.Errorf("ParseBackpressurePolicy(%q) = %v, want %v", tc.input, result, tc.expected)
+		}
+	}
+}
+
+func TestBufferedWriter_BlockDeliversAllInOrder(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewBufferedWriter(&buf, 2, BackpressureBlock)
+
+	for _, s := range []string{"Hello", ", ", "World", "!"} {
+		if _, err := writer.Write([]byte(s)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	writer.Close()
+
+	if buf.String() != "Hello, World!" {
+		t.Errorf("Expected %q, got %q", "Hello, World!", buf.String())
+	}
+}
+
+func TestBufferedWriter_DropDoesNotBlockOnStalledWriter(t *testing.T) {
+	stalled := &blockingWriter{release: make(chan struct{})}
+	writer := NewBufferedWriter(stalled, 1, BackpressureDrop)
+
+	finished := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			writer.Write([]byte("x"))
+		}
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked on a stalled writer with drop policy")
+	}
+
+	if writer.Dropped() == 0 {
+		t.Error("Expected some chunks to be dropped")
+	}
+
+	close(stalled.release)
+	writer.Close()
+}
+
+func TestBufferedWriter_CopiesCallerBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewBufferedWriter(&buf, 4, BackpressureBlock)
+
+	p := []byte("abc")
+	writer.Write(p)
+	copy(p, "xyz")
+	writer.Close()
+
+	if buf.String() != "abc" {
+		t.Errorf("Expected %q, got %q", "abc", buf.String())
+	}
+}
+
+func TestBufferedWriter_WriteAfterClose(t *testing.T) {
+	writer := NewBufferedWriter(io.Discard, 1, BackpressureBlock)
+	writer.Close()
+
+	if _, err := writer.Write([]byte("late")); err != io.ErrClosedPipe {
+		t.Errorf("Expected io.ErrClosedPipe, got %v", err)
+	}
+	// Closing twice should be harmless
+	if err := writer.Close(); err != nil {
+		t.Errorf("Expected nil error on second Close, got %v", err)
+	}
+}
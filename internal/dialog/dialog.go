@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"sync"
 	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
 )
 
 // Global variables for backward compatibility
@@ -51,11 +53,10 @@ type ColorStripWriter struct {
 
 // NewColorStripWriter creates a new ColorStripWriter
 func NewColorStripWriter(writer io.Writer) *ColorStripWriter {
-	// Pattern for stripping ANSI escape sequences
-	ansiEscape := `\x1b\[[0-9;?]*[mKHJhlABCDEFGPST]`
+	// Pattern for stripping ANSI escape sequences, including OSC titles and hyperlinks
 	return &ColorStripWriter{
 		Writer: writer,
-		regex:  regexp.MustCompile(ansiEscape),
+		regex:  regexp.MustCompile(parser.AnsiEscapePattern),
 	}
 }
 
@@ -0,0 +1,52 @@
+package dialog
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/takahirom/dialog-code/internal/debug"
+)
+
+// frontWindowBoundsScript prints the position and size of the frontmost
+// window, such as "612, 300, 420, 180"
+const frontWindowBoundsScript = `tell application "System Events" to tell (first process whose frontmost is true) to get {position, size} of front window`
+
+// CaptureWindow saves a PNG of the frontmost window, which is the dialog
+// while one is shown, to path with screencapture. It captures the main
+// display instead if the window's bounds can't be read, such as when
+// System Events isn't allowed to read them.
+func CaptureWindow(path string) error {
+	args := []string{"-x", "-t", "png"}
+	output, err := exec.Command("osascript", "-e", frontWindowBoundsScript).Output()
+	if bounds, ok := parseWindowBounds(string(output)); err == nil && ok {
+		args = append(args, "-R"+bounds)
+	} else {
+		debug.Debug("capturing the main display: frontmost window bounds unavailable", "output", strings.TrimSpace(string(output)), "error", err)
+	}
+	args = append(args, path)
+	if output, err := exec.Command("screencapture", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("screencapture failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// parseWindowBounds turns the output of frontWindowBoundsScript into the
+// "x,y,width,height" screencapture -R takes, reporting false for anything
+// else
+func parseWindowBounds(output string) (string, bool) {
+	fields := strings.Split(strings.TrimSpace(output), ",")
+	if len(fields) != 4 {
+		return "", false
+	}
+	numbers := make([]string, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || (i >= 2 && n <= 0) {
+			return "", false
+		}
+		numbers[i] = strconv.Itoa(n)
+	}
+	return strings.Join(numbers, ","), true
+}
@@ -0,0 +1,19 @@
+package dialog
+
+import "testing"
+
+func TestParseWindowBounds(t *testing.T) {
+	for output, expected := range map[string]string{
+		"612, 300, 420, 180\n": "612,300,420,180",
+		"-1440, 25, 420, 180":  "-1440,25,420,180",
+		"":                     "",
+		"612, 300":             "",
+		"612, 300, 0, 180":     "",
+		"missing value":        "",
+	} {
+		bounds, ok := parseWindowBounds(output)
+		if bounds != expected || ok != (expected != "") {
+			t.Errorf("parseWindowBounds(%q) = %q, %v, expected %q", output, bounds, ok, expected)
+		}
+	}
+}
@@ -9,16 +9,28 @@ import (
 	"github.com/takahirom/dialog-code/internal/debug"
 )
 
+// DefaultTitle is the title of dialogs and notifications unless one is set
+const DefaultTitle = "Claude Permission"
+
 // SimpleOSDialog provides pure OS dialog functionality without message processing
-type SimpleOSDialog struct{}
+type SimpleOSDialog struct {
+	Title string // Title of dialogs and notifications
+	App   string // Application that shows them, e.g. "Terminal", or "" for osascript itself
+}
 
 // NewSimpleOSDialog creates a new simple OS dialog
 func NewSimpleOSDialog() *SimpleOSDialog {
-	return &SimpleOSDialog{}
+	return &SimpleOSDialog{Title: DefaultTitle}
 }
 
 // Show displays a dialog with the given message and buttons, returns the selected button text
 func (d *SimpleOSDialog) Show(message string, buttons []string, defaultButton string) string {
+	return d.ShowTitled(d.Title, message, buttons, defaultButton)
+}
+
+// ShowTitled is Show with a title of its own, such as one marking the
+// request's risk
+func (d *SimpleOSDialog) ShowTitled(title, message string, buttons []string, defaultButton string) string {
 	if len(buttons) == 0 {
 		buttons = []string{"OK"}
 		defaultButton = "OK"
@@ -27,15 +39,15 @@ func (d *SimpleOSDialog) Show(message string, buttons []string, defaultButton st
 	// Choose between dialog types based on button count
 	if len(buttons) > 3 {
 		debug.Printf("[DEBUG] SimpleOSDialog: Using choose from list for %d buttons\n", len(buttons))
-		return d.executeChooseFromListDialog(message, buttons, defaultButton)
+		return d.executeChooseFromListDialog(title, message, buttons, defaultButton)
 	} else {
 		debug.Printf("[DEBUG] SimpleOSDialog: Using display dialog for %d buttons\n", len(buttons))
-		return d.executeAppleScriptDialog(message, buttons, defaultButton)
+		return d.executeAppleScriptDialog(title, message, buttons, defaultButton)
 	}
 }
 
 // executeAppleScriptDialog executes the actual AppleScript dialog
-func (d *SimpleOSDialog) executeAppleScriptDialog(message string, buttons []string, defaultButton string) string {
+func (d *SimpleOSDialog) executeAppleScriptDialog(title, message string, buttons []string, defaultButton string) string {
 	// Escape message for AppleScript
 	escapedMessage := d.escapeForAppleScript(message)
 	
@@ -51,8 +63,8 @@ func (d *SimpleOSDialog) executeAppleScriptDialog(message string, buttons []stri
 	buttonsStr := strings.Join(buttonStrings, ",")
 	
 	// Build AppleScript command
-	script := fmt.Sprintf(`display dialog "%s" with title "Claude Permission" buttons {%s} default button "%s"`,
-		escapedMessage, buttonsStr, d.escapeForAppleScript(defaultButton))
+	script := d.tell(fmt.Sprintf(`display dialog "%s" with title "%s" buttons {%s} default button "%s"`,
+		escapedMessage, d.escapeForAppleScript(title), buttonsStr, d.escapeForAppleScript(defaultButton)), true)
 	
 	debug.Printf("[DEBUG] SimpleOSDialog: Executing AppleScript: %s\n", script)
 	
@@ -70,6 +82,18 @@ func (d *SimpleOSDialog) executeAppleScriptDialog(message string, buttons []stri
 	return d.parseAppleScriptResult(string(output), buttons)
 }
 
+// tell returns the script that runs command in App, brought to the front
+// first if activate is set, or command itself if App isn't set
+func (d *SimpleOSDialog) tell(command string, activate bool) string {
+	if d.App == "" {
+		return command
+	}
+	if activate {
+		return fmt.Sprintf("tell application \"%s\"\nactivate\n%s\nend tell", d.escapeForAppleScript(d.App), command)
+	}
+	return fmt.Sprintf(`tell application "%s" to %s`, d.escapeForAppleScript(d.App), command)
+}
+
 // escapeForAppleScript escapes special characters for AppleScript strings
 func (d *SimpleOSDialog) escapeForAppleScript(text string) string {
 	// Replace quotes and backslashes
@@ -101,7 +125,7 @@ func (d *SimpleOSDialog) parseAppleScriptResult(output string, buttons []string)
 }
 
 // executeChooseFromListDialog executes AppleScript choose from list for many buttons
-func (d *SimpleOSDialog) executeChooseFromListDialog(message string, buttons []string, defaultButton string) string {
+func (d *SimpleOSDialog) executeChooseFromListDialog(title, message string, buttons []string, defaultButton string) string {
 	// Build button list for AppleScript
 	var buttonStrings []string
 	for _, button := range buttons {
@@ -127,8 +151,8 @@ func (d *SimpleOSDialog) executeChooseFromListDialog(message string, buttons []s
 	}
 	
 	// Build AppleScript command for choose from list
-	script := fmt.Sprintf(`choose from list {%s} with title "Claude Permission" with prompt "%s"%s`,
-		buttonsStr, d.escapeForAppleScript(message), defaultSelection)
+	script := d.tell(fmt.Sprintf(`choose from list {%s} with title "%s" with prompt "%s"%s`,
+		buttonsStr, d.escapeForAppleScript(title), d.escapeForAppleScript(message), defaultSelection), true)
 	
 	debug.Printf("[DEBUG] SimpleOSDialog: Executing choose from list: %s\n", script)
 	
@@ -179,4 +203,39 @@ func (d *SimpleOSDialog) parseChooseFromListResult(output string, buttons []stri
 	// Default to last button if no match found (most restrictive)
 	debug.Printf("[DEBUG] SimpleOSDialog: No button match found in choose from list, returning last button\n")
 	return fmt.Sprintf("%d", len(buttons))
-}
\ No newline at end of file
+}
+
+// Prompt displays a dialog with a text field and returns the entered text, or
+// false if the dialog was cancelled or couldn't be shown
+func (d *SimpleOSDialog) Prompt(message string) (string, bool) {
+	script := d.tell(fmt.Sprintf(`display dialog "%s" with title "%s" default answer "" buttons {"Cancel","Confirm"} default button "Confirm" cancel button "Cancel"`,
+		d.escapeForAppleScript(message), d.escapeForAppleScript(d.Title)), true)
+
+	debug.Printf("[DEBUG] SimpleOSDialog: Executing text prompt: %s\n", script)
+
+	// Cancel makes osascript exit with an error
+	output, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		debug.Printf("[DEBUG] SimpleOSDialog: Text prompt cancelled or failed: %v\n", err)
+		return "", false
+	}
+	return d.parseTextResult(string(output))
+}
+
+// parseTextResult extracts the entered text from display dialog output such as
+// "button returned:Confirm, text returned:approve"
+func (d *SimpleOSDialog) parseTextResult(output string) (string, bool) {
+	_, text, found := strings.Cut(strings.TrimRight(output, "\n"), "text returned:")
+	return text, found
+}
+
+// Notify posts a notification that doesn't wait for the user
+func (d *SimpleOSDialog) Notify(message string) {
+	script := d.tell(fmt.Sprintf(`display notification "%s" with title "%s"`, d.escapeForAppleScript(message), d.escapeForAppleScript(d.Title)), false)
+
+	debug.Printf("[DEBUG] SimpleOSDialog: Executing notification: %s\n", script)
+
+	if err := exec.Command("osascript", "-e", script).Run(); err != nil {
+		debug.Printf("[DEBUG] SimpleOSDialog: Notification failed: %v\n", err)
+	}
+}
@@ -201,4 +201,42 @@ func TestSimpleOSDialog_ButtonCountBranching(t *testing.T) {
 			t.Error("Show should return a non-empty result even on error")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestSimpleOSDialog_ParseTextResult(t *testing.T) {
+	dialog := NewSimpleOSDialog()
+
+	testCases := []struct {
+		output       string
+		expectedText string
+		expectedOK   bool
+	}{
+		{"button returned:Confirm, text returned:approve\n", "approve", true},
+		{"button returned:Confirm, text returned:\n", "", true},
+		{"button returned:Confirm, text returned:a, b\n", "a, b", true},
+		{"", "", false},
+	}
+
+	for _, tc := range testCases {
+		text, ok := dialog.parseTextResult(tc.output)
+		if text != tc.expectedText || ok != tc.expectedOK {
+			t.Errorf("parseTextResult(%q) = %q, %v, want %q, %v", tc.output, text, ok, tc.expectedText, tc.expectedOK)
+		}
+	}
+}
+
+func TestSimpleOSDialog_Tell(t *testing.T) {
+	dialog := NewSimpleOSDialog()
+	command := `display notification "hi" with title "Claude Permission"`
+	if script := dialog.tell(command, true); script != command {
+		t.Errorf("Expected the command alone without an app, got %q", script)
+	}
+
+	dialog.App = `My "App"`
+	if script := dialog.tell(command, false); script != `tell application "My \"App\"" to `+command {
+		t.Errorf("Unexpected script: %q", script)
+	}
+	if script := dialog.tell(command, true); script != "tell application \"My \\\"App\\\"\"\nactivate\n"+command+"\nend tell" {
+		t.Errorf("Unexpected script: %q", script)
+	}
+}
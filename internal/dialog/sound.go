@@ -0,0 +1,20 @@
+package dialog
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// systemSoundsDir holds the sounds macOS offers for alerts
+const systemSoundsDir = "/System/Library/Sounds"
+
+// PlaySound plays sound, the name of a macOS alert sound such as "Glass" or
+// the path of a sound file, with afplay, returning once it has played
+func PlaySound(sound string) error {
+	path := sound
+	if !strings.Contains(sound, "/") {
+		path = filepath.Join(systemSoundsDir, sound+".aiff")
+	}
+	return exec.Command("afplay", path).Run()
+}
@@ -0,0 +1,119 @@
+// Package events writes a stream of what dcode does with each prompt as
+// newline-delimited JSON, one object per event, for dashboards and tools such
+// as jq to follow.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Types of event
+const (
+	DialogDetected = "dialog_detected"   // A prompt started to appear
+	DialogShown    = "dialog_shown"      // A dialog asked the user about it
+	Suppressed     = "dialog_suppressed" // A prompt was skipped as a repeat of one just handled
+	Decision       = "decision"          // An answer was picked, by the user or without asking
+	Injection      = "injection"         // The answer was typed into Claude
+	Error          = "error"             // Something went wrong handling it
+	Anomaly        = "anomaly"           // Something that usually means dcode or Claude is stuck kept happening
+)
+
+// Kinds of anomaly
+const (
+	AnomalyRepeatedPrompt    = "repeated_prompt"    // The same request was asked again and again
+	AnomalyAnswerRetries     = "answer_retries"     // Answers failed to be typed or were sent again for a prompt already answered
+	AnomalySuppressedPrompts = "suppressed_prompts" // Prompt after prompt was skipped as a repeat
+)
+
+// Event is one line of the stream. Fields that don't apply to an event's
+// Type are left out.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Session   int       `json:"session"`              // Process ID of the dcode that wrote it
+	Prompt    int       `json:"prompt,omitempty"`     // Numbers the prompts of a session, tying their events together
+	RequestID string    `json:"request_id,omitempty"` // The prompt's ID in its dialog, the debug log, and the audit log
+	Kind      string    `json:"kind,omitempty"`       // Kind of prompt, e.g. "permission" or "folder_trust"
+	Tool      string    `json:"tool,omitempty"`       // Tool requested, e.g. "Bash"
+	Request   string    `json:"request,omitempty"`    // Tool and command or files, with secrets masked
+	Choice    string    `json:"choice,omitempty"`     // Choice number picked or sent
+	Label     string    `json:"label,omitempty"`      // The choice's text
+	Action    string    `json:"action,omitempty"`     // approved, rejected, or answered
+	DecidedBy string    `json:"decided_by,omitempty"` // user, or the rule or option that answered without asking
+	LatencyMs int64     `json:"latency_ms,omitempty"` // From the prompt appearing to the answer being sent
+	Message   string    `json:"message,omitempty"`    // What went wrong, for an Error or Anomaly, or why a prompt was Suppressed
+	Anomaly   string    `json:"anomaly,omitempty"`    // Kind of Anomaly
+	Count     int       `json:"count,omitempty"`      // How many times an Anomaly happened
+	Dedup     *Dedup    `json:"dedup,omitempty"`      // What the deduplication held when a prompt was Suppressed
+}
+
+// Dedup counts what dcode remembers to tell repeated prompts from new ones
+type Dedup struct {
+	Prompts         int `json:"prompts"`          // Prompts and answers seen recently
+	Cooldowns       int `json:"cooldowns"`        // Cooldowns held, including ones run out
+	ActiveCooldowns int `json:"active_cooldowns"` // Cooldowns still running
+}
+
+// Stream writes events to a file or descriptor. The methods of a nil Stream
+// do nothing, so callers needn't check whether the stream is on.
+type Stream struct {
+	w     io.Writer
+	mutex sync.Mutex
+}
+
+// NewStream returns a stream writing to w
+func NewStream(w io.Writer) *Stream {
+	return &Stream{w: w}
+}
+
+// Open returns a stream appending to target: a file descriptor number the
+// process was started with, such as "3", or else a file path
+func Open(target string) (*Stream, error) {
+	if fd, err := strconv.Atoi(target); err == nil && fd > 0 {
+		return NewStream(os.NewFile(uintptr(fd), "fd "+target)), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(file), nil
+}
+
+// Emit writes event as one line, filling in its time if unset
+func (s *Stream) Emit(event Event) error {
+	if s == nil {
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file or descriptor the stream writes to
+func (s *Stream) Close() error {
+	if s == nil {
+		return nil
+	}
+	if closer, ok := s.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
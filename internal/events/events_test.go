@@ -0,0 +1,63 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmitWritesOneObjectPerLine(t *testing.T) {
+	var out bytes.Buffer
+	stream := NewStream(&out)
+	now := time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	stream.Emit(Event{Time: now, Type: DialogDetected, Session: 42, Prompt: 1, Kind: "permission"})
+	stream.Emit(Event{Time: now, Type: Decision, Session: 42, Prompt: 1, Choice: "1", Action: "approved", DecidedBy: "user"})
+
+	expected := `{"time":"2025-01-01T09:30:00Z","type":"dialog_detected","session":42,"prompt":1,"kind":"permission"}` + "\n" +
+		`{"time":"2025-01-01T09:30:00Z","type":"decision","session":42,"prompt":1,"choice":"1","action":"approved","decided_by":"user"}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestEmitFillsInTime(t *testing.T) {
+	var out bytes.Buffer
+	NewStream(&out).Emit(Event{Type: Error, Message: "failed"})
+
+	var event Event
+	if err := json.Unmarshal(out.Bytes(), &event); err != nil || event.Time.IsZero() || event.Message != "failed" {
+		t.Errorf("Expected an error event with its time, got %+v, %v", event, err)
+	}
+}
+
+func TestNilStream(t *testing.T) {
+	var stream *Stream
+	if err := stream.Emit(Event{Type: Error}); err != nil {
+		t.Errorf("Expected a nil stream to do nothing, got %v", err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("Expected a nil stream to close, got %v", err)
+	}
+}
+
+func TestOpenAppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dcode", "events.ndjson")
+	for i := 0; i < 2; i++ {
+		stream, err := Open(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		stream.Emit(Event{Type: Injection})
+		stream.Close()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || strings.Count(string(data), `"type":"injection"`) != 2 {
+		t.Errorf("Expected two events, got %q, %v", data, err)
+	}
+}
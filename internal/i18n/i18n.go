@@ -0,0 +1,168 @@
+// Package i18n holds the text dcode writes itself, in dialogs and to
+// Claude, in each language it speaks. Claude's own prompts and choices are
+// shown as Claude wrote them.
+package i18n
+
+import (
+	"slices"
+	"strings"
+)
+
+// Languages dcode speaks, and Auto, which picks one from the environment
+const (
+	English  = "en"
+	Japanese = "ja"
+	Auto     = "auto"
+)
+
+// Messages is the text dcode writes in one language. Formats take the
+// arguments their comments name.
+type Messages struct {
+	AutoReject              string // Sent to Claude for a request rejected by auto_reject or auto_reject_wait
+	DenyRule                string // Sent to Claude for a deny rule without its own message
+	ForbidRule              string // Sent to Claude for a forbid rule without its own message
+	ToolPolicy              string // Sent to Claude for a tool denied by tool_policy; format of the tool's name
+	Panic                   string // Sent to Claude for every request after dcode panic
+	RemoteTimeout           string // Sent to Claude when nobody answers a request sent to the remote backend
+	EditScope               string // Sent to Claude for an edit outside edit_scope; format of the allowed directories
+	RiskReject              string // Sent to Claude for a request rejected by the risk policy; format of its risk level and reason
+	QuietHours              string // Sent to Claude for a request rejected during quiet hours without its own message
+	RejectedCommand         string // Starts a rejection sent to Claude; format of the rejected command
+	RejectedBy              string // Ends a rejection sent to Claude; format of the rule that rejected it
+	RejectedByExplained     string // RejectedBy for a logged decision; format of the rule and the decision log ID
+	AutoRejectCountdown     string // Ends a dialog answered by auto_reject_wait; format of its seconds
+	AutoApproveCountdown    string // Ends a dialog answered by auto_approve_wait; format of its seconds
+	TemporaryApprovalButton string // Format of temporary_approval_minutes
+	NeverAllowButton        string
+	AskSomeoneElseButton    string
+	DelegatedPrefix         string // Starts the remote message of a request forwarded with AskSomeoneElseButton
+	RememberButton          string
+	NotNowButton            string
+	RememberApproval        string // Asks whether to add an approve rule; format of the request and the config file
+	RememberRejection       string // Asks whether to add a deny rule; format of the request and the config file
+	TrustFolder             string // Asks whether to trust the folder of Claude's folder trust prompt
+	TrustFolderNote         string // Follows TrustFolder and the folder
+	RiskLevel               string // Format of a risk level, e.g. high
+	TypedConfirmation       string // Asks for a typed approval; format of the risk and the phrase to type
+	RejectionLoop           string // Notification of a stopped rejection loop; format of the request and how many times it was rejected
+	Panicked                string // Notification that dcode panic is rejecting every request
+	AutoApprovalLimit       string // Starts the dialog shown at --max-auto-approvals; format of how many requests were approved
+	AutoApprovalsEarlier    string // Counts approvals left out of AutoApprovalLimit's list; format of how many
+	AutoApprovalContinue    string // Ends the dialog shown at --max-auto-approvals
+}
+
+// bundles holds the messages of each language
+var bundles = map[string]Messages{
+	English: {
+		AutoReject:              "The command was automatically rejected. If using Task tools, please restart them. Otherwise, try a different command.",
+		DenyRule:                "The command matched a deny rule and was rejected. Do not retry it; try a different approach.",
+		ForbidRule:              "The command is forbidden by policy and can never be approved. Do not retry it or work around it; tell the user it is forbidden.",
+		ToolPolicy:              "The %s tool is not allowed in this session. Do not retry it; try a different approach.",
+		Panic:                   "The user stopped all work with dcode panic, and every request is rejected. Stop now, don't retry anything, and wait for the user.",
+		RemoteTimeout:           "The command was rejected because nobody approved it remotely in time. Try a different approach, or wait for the user.",
+		EditScope:               "The edit was rejected because the file is outside the directories you may edit (%s). Keep changes inside them.",
+		RiskReject:              "The command was automatically rejected as %s risk (%s). Try a different approach.",
+		QuietHours:              "The command was automatically rejected because nobody is available to approve it right now. Continue with work that doesn't need permission, or stop and summarize what is left.",
+		RejectedCommand:         "Rejected command:\n%s",
+		RejectedBy:              "Rejected by %s.",
+		RejectedByExplained:     "Rejected by %s (run `dcode explain %s` for details).",
+		AutoRejectCountdown:     "This will auto-reject in %d seconds...",
+		AutoApproveCountdown:    "This will auto-approve in %d seconds...",
+		TemporaryApprovalButton: "Approve for %d minutes",
+		NeverAllowButton:        "No, never allow",
+		AskSomeoneElseButton:    "Ask someone else",
+		DelegatedPrefix:         "Asked to decide by the user at the computer.\n\n",
+		RememberButton:          "Remember",
+		NotNowButton:            "Not now",
+		RememberApproval:        "Always approve this request without asking?\n\n%s\n\nAn approve rule will be added to %s.",
+		RememberRejection:       "Always reject this request without asking?\n\n%s\n\nA deny rule will be added to %s.",
+		TrustFolder:             "Do you trust the files in this folder?",
+		TrustFolderNote:         "Claude Code may read and execute files in this folder.",
+		RiskLevel:               "%s risk",
+		TypedConfirmation:       "This action is %s.\n\nType \"%s\" to approve it.",
+		RejectionLoop:           "Stopped a rejection loop: %s was rejected %d times in a row. Claude is waiting for you in the terminal, and dialogs are shown until you switch modes with dcode mode.",
+		Panicked:                "dcode is rejecting every request until you run dcode resume",
+		AutoApprovalLimit:       "dcode approved %d requests in a row without asking (--max-auto-approvals):",
+		AutoApprovalsEarlier:    "… %d earlier",
+		AutoApprovalContinue:    "Answer this request to continue.",
+	},
+	Japanese: {
+		AutoReject:              "コマンドは自動的に拒否されました。Task ツールを使っている場合は再起動してください。そうでなければ、別のコマンドを試してください。",
+		DenyRule:                "コマンドは拒否ルールに一致したため拒否されました。再試行せず、別の方法を試してください。",
+		ForbidRule:              "コマンドはポリシーで禁止されており、承認されることはありません。再試行も回避もせず、禁止されていることをユーザーに伝えてください。",
+		ToolPolicy:              "このセッションでは %s ツールは許可されていません。再試行せず、別の方法を試してください。",
+		Panic:                   "ユーザーが dcode panic ですべての作業を止めたため、すべてのリクエストが拒否されます。すぐに中止し、何も再試行せず、ユーザーを待ってください。",
+		RemoteTimeout:           "時間内にリモートで承認されなかったため、コマンドは拒否されました。別の方法を試すか、ユーザーを待ってください。",
+		EditScope:               "ファイルが編集を許可されたディレクトリ (%s) の外にあるため、編集は拒否されました。変更はその中に留めてください。",
+		RiskReject:              "コマンドは %s リスク (%s) と判定されたため自動的に拒否されました。別の方法を試してください。",
+		QuietHours:              "今は承認できる人がいないため、コマンドは自動的に拒否されました。許可の要らない作業を続けるか、作業を止めて残りをまとめてください。",
+		RejectedCommand:         "拒否されたコマンド:\n%s",
+		RejectedBy:              "%s により拒否されました。",
+		RejectedByExplained:     "%s により拒否されました (詳しくは `dcode explain %s` を実行してください)。",
+		AutoRejectCountdown:     "%d 秒後に自動的に拒否します...",
+		AutoApproveCountdown:    "%d 秒後に自動的に承認します...",
+		TemporaryApprovalButton: "%d 分間承認する",
+		NeverAllowButton:        "いいえ、今後も許可しない",
+		AskSomeoneElseButton:    "他の人に確認する",
+		DelegatedPrefix:         "コンピューターの前にいるユーザーから判断を依頼されました。\n\n",
+		RememberButton:          "記憶する",
+		NotNowButton:            "今はしない",
+		RememberApproval:        "今後このリクエストを確認せずに承認しますか?\n\n%s\n\n%s に approve ルールが追加されます。",
+		RememberRejection:       "今後このリクエストを確認せずに拒否しますか?\n\n%s\n\n%s に deny ルールが追加されます。",
+		TrustFolder:             "このフォルダー内のファイルを信頼しますか?",
+		TrustFolderNote:         "Claude Code はこのフォルダー内のファイルを読み込み、実行することがあります。",
+		RiskLevel:               "%s リスク",
+		TypedConfirmation:       "この操作は%sです。\n\n承認するには \"%s\" と入力してください。",
+		RejectionLoop:           "拒否の繰り返しを止めました: %s が %d 回続けて拒否されました。Claude はターミナルであなたを待っています。dcode mode でモードを切り替えるまでダイアログが表示されます。",
+		Panicked:                "dcode resume を実行するまで、dcode はすべてのリクエストを拒否します",
+		AutoApprovalLimit:       "dcode は確認せずに %d 件のリクエストを続けて承認しました (--max-auto-approvals):",
+		AutoApprovalsEarlier:    "… ほか %d 件",
+		AutoApprovalContinue:    "続けるにはこのリクエストに答えてください。",
+	},
+}
+
+// Languages returns the languages dcode speaks
+func Languages() []string {
+	languages := make([]string, 0, len(bundles))
+	for language := range bundles {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// Valid reports whether language is one dcode speaks, or Auto
+func Valid(language string) bool {
+	_, found := bundles[language]
+	return found || language == Auto
+}
+
+// Get returns the messages of language, picking one from getenv for Auto.
+// Languages dcode doesn't speak get English.
+func Get(language string, getenv func(string) string) Messages {
+	if language == Auto {
+		language = Detect(getenv)
+	}
+	if messages, found := bundles[language]; found {
+		return messages
+	}
+	return bundles[English]
+}
+
+// Detect returns the language the POSIX locale variables in getenv name,
+// such as ja for LANG=ja_JP.UTF-8, or English if dcode doesn't speak it
+func Detect(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		language, _, _ := strings.Cut(value, "_")
+		language, _, _ = strings.Cut(language, ".")
+		if _, found := bundles[language]; found {
+			return language
+		}
+		return English
+	}
+	return English
+}
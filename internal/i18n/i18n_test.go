@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBundlesAreComplete(t *testing.T) {
+	english := reflect.ValueOf(bundles[English])
+	for _, language := range Languages() {
+		messages := reflect.ValueOf(bundles[language])
+		for i := 0; i < messages.NumField(); i++ {
+			name := messages.Type().Field(i).Name
+			value := messages.Field(i).String()
+			if value == "" {
+				t.Errorf("%s has no %s", language, name)
+			}
+			if strings.Count(value, "%") != strings.Count(english.Field(i).String(), "%") {
+				t.Errorf("%s %s takes different arguments from English: %q", language, name, value)
+			}
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(name string) string { return values[name] }
+	}
+	testCases := []struct {
+		language string
+		env      map[string]string
+		expected string
+	}{
+		{English, map[string]string{"LANG": "ja_JP.UTF-8"}, English},
+		{Japanese, nil, Japanese},
+		{"fr", nil, English},
+		{Auto, map[string]string{"LANG": "ja_JP.UTF-8"}, Japanese},
+		{Auto, map[string]string{"LANG": "ja"}, Japanese},
+		{Auto, map[string]string{"LC_ALL": "C", "LANG": "ja_JP.UTF-8"}, English},
+		{Auto, map[string]string{"LC_MESSAGES": "ja_JP.UTF-8", "LANG": "en_US.UTF-8"}, Japanese},
+		{Auto, nil, English},
+	}
+	for _, tc := range testCases {
+		if messages := Get(tc.language, env(tc.env)); messages != bundles[tc.expected] {
+			t.Errorf("Get(%q) with %v gave the wrong language, expected %s", tc.language, tc.env, tc.expected)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for language, expected := range map[string]bool{English: true, Japanese: true, Auto: true, "fr": false, "": false} {
+		if Valid(language) != expected {
+			t.Errorf("Valid(%q) = %v, expected %v", language, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package logfile keeps log files from growing without bound. A log is
+// rotated by renaming it aside with the time, once it reaches a size or a new
+// day starts, and the oldest rotated copies are deleted beyond a count or age.
+package logfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeFormat stamps rotated copies, sorting them oldest first by name
+const timeFormat = "20060102-150405.000"
+
+// Rotation says when a log is rotated and which rotated copies are kept. Its
+// zero value never rotates.
+type Rotation struct {
+	MaxBytes   int64         // Rotate before a write would take the log past this size, if positive
+	Daily      bool          // Rotate before the first write of each local day
+	MaxBackups int           // Delete the oldest rotated copies beyond this many, if positive
+	MaxAge     time.Duration // Delete rotated copies last written longer ago than this, if positive
+}
+
+// Rotate renames the log at path aside if writing incoming more bytes to it
+// at now is due a rotation, then deletes the rotated copies r doesn't keep.
+// It reports whether the log was rotated.
+func Rotate(path string, r Rotation, now time.Time, incoming int) (bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !due(info, r, now, incoming) {
+		return false, nil
+	}
+
+	backup := backupPath(path, now)
+	// Never overwrite a copy rotated within the same millisecond
+	for stamp := now; fileExists(backup); {
+		stamp = stamp.Add(time.Millisecond)
+		backup = backupPath(path, stamp)
+	}
+	if err := os.Rename(path, backup); err != nil {
+		return false, err
+	}
+	return true, prune(path, r, now)
+}
+
+// due reports whether the log described by info is rotated before incoming
+// more bytes are written at now
+func due(info fs.FileInfo, r Rotation, now time.Time, incoming int) bool {
+	if info.Size() == 0 {
+		return false
+	}
+	if r.MaxBytes > 0 && info.Size()+int64(incoming) > r.MaxBytes {
+		return true
+	}
+	if r.Daily {
+		y1, m1, d1 := info.ModTime().Local().Date()
+		y2, m2, d2 := now.Local().Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
+
+// backupPath returns where the log at path is renamed when rotated at now,
+// e.g. debug_output-20250101-093012.000.log
+func backupPath(path string, now time.Time) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), now.Local().Format(timeFormat), ext)
+}
+
+// fileExists reports whether there's a file at path
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// Backups returns the rotated copies of the log at path, oldest first
+func Backups(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(path, ext) + "-"
+	matches, err := filepath.Glob(escapeGlob(prefix) + "*" + escapeGlob(ext))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		if _, err := time.ParseInLocation(timeFormat, stamp, time.Local); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// escapeGlob escapes the characters filepath.Match treats specially
+func escapeGlob(path string) string {
+	var escaped strings.Builder
+	for _, c := range path {
+		if strings.ContainsRune(`*?[\`, c) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
+
+// prune deletes the rotated copies of the log at path beyond r.MaxBackups or
+// older than r.MaxAge at now
+func prune(path string, r Rotation, now time.Time) error {
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	for i, backup := range backups {
+		expired := r.MaxBackups > 0 && i < len(backups)-r.MaxBackups
+		if !expired && r.MaxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && now.Sub(info.ModTime()) > r.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Writer appends to a log file, rotating it as its Rotation says
+type Writer struct {
+	path     string
+	rotation Rotation
+	file     *os.File
+	now      func() time.Time
+	mutex    sync.Mutex
+}
+
+// Open opens the log at path for appending, creating it and its directory if
+// needed
+func Open(path string, r Rotation) (*Writer, error) {
+	w := &Writer{path: path, rotation: r, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends b to the log, first rotating it if it's due
+func (w *Writer) Write(b []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return 0, fs.ErrClosed
+	}
+	rotated, err := Rotate(w.path, w.rotation, w.now(), len(b))
+	if err != nil {
+		return 0, err
+	}
+	if rotated {
+		w.file.Close()
+		if err := w.open(); err != nil {
+			w.file = nil
+			return 0, err
+		}
+	}
+	return w.file.Write(b)
+}
+
+// Close closes the log
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the file at w.path for appending. The caller holds the mutex.
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	return nil
+}
@@ -0,0 +1,142 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLog writes content to path, last modified at modified
+func writeLog(t *testing.T, path, content string, modified time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug_output.log")
+	now := time.Date(2025, 1, 1, 9, 30, 12, 0, time.Local)
+	writeLog(t, path, "0123456789", now)
+	r := Rotation{MaxBytes: 16}
+
+	if rotated, err := Rotate(path, r, now, 6); err != nil || rotated {
+		t.Fatalf("Expected no rotation while the log fits, got %v, %v", rotated, err)
+	}
+	if rotated, err := Rotate(path, r, now, 7); err != nil || !rotated {
+		t.Fatalf("Expected a rotation past the size, got %v, %v", rotated, err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the log to be moved aside, got %v", err)
+	}
+	backups, err := Backups(path)
+	if err != nil || len(backups) != 1 || filepath.Base(backups[0]) != "debug_output-20250101-093012.000.log" {
+		t.Fatalf("Expected one stamped backup, got %v, %v", backups, err)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != "0123456789" {
+		t.Errorf("Expected the backup to hold the log, got %q", data)
+	}
+}
+
+func TestRotateDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	evening := time.Date(2025, 1, 1, 23, 0, 0, 0, time.Local)
+	writeLog(t, path, "{}\n", evening)
+	r := Rotation{Daily: true}
+
+	if rotated, err := Rotate(path, r, evening.Add(30*time.Minute), 3); err != nil || rotated {
+		t.Errorf("Expected no rotation the same day, got %v, %v", rotated, err)
+	}
+	if rotated, err := Rotate(path, r, evening.Add(2*time.Hour), 3); err != nil || !rotated {
+		t.Errorf("Expected a rotation the next day, got %v, %v", rotated, err)
+	}
+}
+
+func TestRotateSkipsEmptyAndMissingLogs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug_output.log")
+	r := Rotation{MaxBytes: 1, Daily: true}
+	if rotated, err := Rotate(path, r, time.Now(), 10); err != nil || rotated {
+		t.Errorf("Expected a missing log to be left alone, got %v, %v", rotated, err)
+	}
+	writeLog(t, path, "", time.Now().AddDate(0, 0, -2))
+	if rotated, err := Rotate(path, r, time.Now(), 10); err != nil || rotated {
+		t.Errorf("Expected an empty log to be left alone, got %v, %v", rotated, err)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "debug_output.log")
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.Local)
+	for day := 1; day <= 5; day++ {
+		modified := time.Date(2025, 1, day, 12, 0, 0, 0, time.Local)
+		writeLog(t, backupPath(path, modified), "old", modified)
+	}
+	// Not a backup, so never deleted
+	writeLog(t, filepath.Join(dir, "debug_output-notes.log"), "keep", now.AddDate(-1, 0, 0))
+	writeLog(t, path, "0123456789", now)
+
+	r := Rotation{MaxBytes: 1, MaxBackups: 5, MaxAge: 7 * 24 * time.Hour}
+	if rotated, err := Rotate(path, r, now, 1); err != nil || !rotated {
+		t.Fatalf("Expected a rotation, got %v, %v", rotated, err)
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, backup := range backups {
+		names = append(names, filepath.Base(backup))
+	}
+	// Five kept by count, of which the one from January 2 is over a week old
+	expected := "debug_output-20250103-120000.000.log debug_output-20250104-120000.000.log debug_output-20250105-120000.000.log debug_output-20250110-120000.000.log"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("Expected %s, got %v", expected, names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "debug_output-notes.log")); err != nil {
+		t.Errorf("Expected other files to be kept, got %v", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "debug_output.log")
+	w, err := Open(path, Rotation{MaxBytes: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.Local)
+	w.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("closed\n")); err == nil {
+		t.Error("Expected an error writing to a closed log")
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "third\n" {
+		t.Errorf("Expected the log to hold the last line, got %q", data)
+	}
+	backups, err := Backups(path)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("Expected two backups, got %v, %v", backups, err)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != "first\n" {
+		t.Errorf("Expected the oldest backup first, got %q", data)
+	}
+}
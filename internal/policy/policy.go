@@ -0,0 +1,109 @@
+// Package policy fetches an organization-wide policy, a set of rules a team
+// publishes for every member's dcode, and checks its signature so that a
+// tampered or spoofed copy is never applied.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+
+	"github.com/takahirom/dialog-code/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Namespace is the ssh-keygen -Y namespace policies are signed in, so a
+// signature made for something else can't be reused for a policy
+const Namespace = "dcode-policy"
+
+// maxDownloadBytes bounds the policy and signature downloads
+const maxDownloadBytes = 1 << 20
+
+// Policy holds the rules of an organization policy. Its YAML uses the same
+// keys as the config file.
+type Policy struct {
+	Approve    []config.Rule     `yaml:"approve"`
+	Deny       []config.DenyRule `yaml:"deny"`
+	Forbid     []config.DenyRule `yaml:"forbid"`
+	ToolPolicy config.ToolPolicy `yaml:"tool_policy"`
+}
+
+// Fetch downloads the policy described by source and its signature with
+// client, or http.DefaultClient if nil, and returns the policy if the
+// signature verifies
+func Fetch(ctx context.Context, client *http.Client, source config.PolicySource) (Policy, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	data, err := download(ctx, client, source.URL)
+	if err != nil {
+		return Policy{}, err
+	}
+	signature, err := download(ctx, client, source.SignatureLocation())
+	if err != nil {
+		return Policy{}, err
+	}
+	if err := Verify(source.PublicKey, Namespace, data, signature); err != nil {
+		return Policy{}, fmt.Errorf("policy signature verification failed: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads a policy, reporting unknown keys and invalid rules
+func Parse(data []byte) (Policy, error) {
+	var p Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return Policy{}, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	cfg := config.Default()
+	p.Apply(&cfg)
+	if err := cfg.Validate(); err != nil {
+		return Policy{}, fmt.Errorf("invalid policy: %w", err)
+	}
+	return p, nil
+}
+
+// Apply adds the policy's rules to cfg after the rules already there. Its
+// tool policy entries replace cfg's for the same tools.
+func (p Policy) Apply(cfg *config.Config) {
+	cfg.Approve = append(cfg.Approve, p.Approve...)
+	cfg.Deny = append(cfg.Deny, p.Deny...)
+	cfg.Forbid = append(cfg.Forbid, p.Forbid...)
+	if len(p.ToolPolicy) > 0 {
+		merged := make(config.ToolPolicy, len(cfg.ToolPolicy)+len(p.ToolPolicy))
+		maps.Copy(merged, cfg.ToolPolicy)
+		maps.Copy(merged, p.ToolPolicy)
+		cfg.ToolPolicy = merged
+	}
+}
+
+// download returns the body of a GET of url
+func download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadBytes {
+		return nil, fmt.Errorf("fetching %s: larger than %d bytes", url, maxDownloadBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/config"
+)
+
+// A policy signed with "ssh-keygen -Y sign -f key -n dcode-policy policy.yaml"
+const (
+	testPolicy    = "forbid:\n  - command: ^terraform destroy\n"
+	testPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIICYk46hxIlF3l5sws2Bo14TghmZTgwPD4vusee/+q39 security@example.com"
+	testSignature = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAggJiTjqHEiUXeXmzCzYGjXhOCGZ
+lODA8Pi+6x57/6rf0AAAAMZGNvZGUtcG9saWN5AAAAAAAAAAZzaGE1MTIAAABTAAAAC3Nz
+aC1lZDI1NTE5AAAAQH8pTxHdu2JzH4kvwWDNUQLoXzAnZ9orkepzqMK1RVM0HKJRAA7MK9
++7cxSdRpcfkG9h+jt/rCUBWDt+JaofxwI=
+-----END SSH SIGNATURE-----
+`
+)
+
+// otherPublicKey returns a freshly generated ssh-ed25519 key line
+func otherPublicKey(t *testing.T) string {
+	t.Helper()
+	pk, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blob bytes.Buffer
+	writeSSHString(&blob, []byte(keyTypeEd25519))
+	writeSSHString(&blob, pk)
+	return keyTypeEd25519 + " " + base64.StdEncoding.EncodeToString(blob.Bytes())
+}
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		name      string
+		publicKey string
+		namespace string
+		message   string
+		signature string
+		valid     bool
+	}{
+		{"valid", testPublicKey, Namespace, testPolicy, testSignature, true},
+		{"modified policy", testPublicKey, Namespace, testPolicy + "approve: [{tool: Bash}]\n", testSignature, false},
+		{"other namespace", testPublicKey, "file", testPolicy, testSignature, false},
+		{"other key", otherPublicKey(t), Namespace, testPolicy, testSignature, false},
+		{"RSA key", "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ", Namespace, testPolicy, testSignature, false},
+		{"not armored", testPublicKey, Namespace, testPolicy, "U1NIU0lH", false},
+		{"truncated", testPublicKey, Namespace, testPolicy, strings.Replace(testSignature, "+7cxSdRpcfkG9h+jt/rCUBWDt+JaofxwI=\n", "", 1), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.publicKey, tc.namespace, []byte(tc.message), []byte(tc.signature))
+			if tc.valid && err != nil {
+				t.Errorf("Expected a valid signature, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("Expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	files := map[string]string{
+		"/policy.yaml":       testPolicy,
+		"/policy.yaml.sig":   testSignature,
+		"/tampered.yaml":     testPolicy + "approve: [{tool: Bash}]\n",
+		"/tampered.yaml.sig": testSignature,
+		"/unsigned.yaml":     testPolicy,
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	t.Run("signed policy", func(t *testing.T) {
+		p, err := Fetch(context.Background(), server.Client(), config.PolicySource{URL: server.URL + "/policy.yaml", PublicKey: testPublicKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Forbid) != 1 || p.Forbid[0].Command != "^terraform destroy" {
+			t.Errorf("Expected the terraform destroy forbid rule, got %+v", p.Forbid)
+		}
+	})
+
+	t.Run("signature URL", func(t *testing.T) {
+		source := config.PolicySource{URL: server.URL + "/unsigned.yaml", SignatureURL: server.URL + "/policy.yaml.sig", PublicKey: testPublicKey}
+		if _, err := Fetch(context.Background(), server.Client(), source); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	failures := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"tampered policy", "/tampered.yaml", "signature verification failed"},
+		{"missing signature", "/unsigned.yaml", "404"},
+		{"missing policy", "/missing.yaml", "404"},
+	}
+	for _, tc := range failures {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Fetch(context.Background(), server.Client(), config.PolicySource{URL: server.URL + tc.path, PublicKey: testPublicKey})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name  string
+		data  string
+		valid bool
+	}{
+		{"empty", "", true},
+		{"rules", "approve:\n  - command: ^go test\ndeny:\n  - tool: WebFetch\ntool_policy:\n  Bash: ask\n", true},
+		{"unknown key", "auto_approve: true\n", false},
+		{"invalid regex", "deny:\n  - command: \"(\"\n", false},
+		{"invalid tool policy", "tool_policy:\n  Bash: sometimes\n", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if tc.valid && err != nil {
+				t.Errorf("Expected a valid policy, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	cfg := config.Default()
+	cfg.Deny = []config.DenyRule{{Rule: config.Rule{Tool: "WebFetch"}}}
+	cfg.ToolPolicy = config.ToolPolicy{"Bash": config.ToolPolicyAllow, "Read": config.ToolPolicyAllow}
+	userToolPolicy := cfg.ToolPolicy
+
+	Policy{
+		Deny:       []config.DenyRule{{Rule: config.Rule{Command: "^curl"}}},
+		ToolPolicy: config.ToolPolicy{"Bash": config.ToolPolicyAsk},
+	}.Apply(&cfg)
+
+	if len(cfg.Deny) != 2 || cfg.Deny[0].Tool != "WebFetch" || cfg.Deny[1].Command != "^curl" {
+		t.Errorf("Expected the policy's deny rule after the user's, got %+v", cfg.Deny)
+	}
+	if cfg.ToolPolicy["Bash"] != config.ToolPolicyAsk || cfg.ToolPolicy["Read"] != config.ToolPolicyAllow {
+		t.Errorf("Expected the policy to override Bash only, got %v", cfg.ToolPolicy)
+	}
+	if userToolPolicy["Bash"] != config.ToolPolicyAllow {
+		t.Error("Expected the original tool policy map to be left unchanged")
+	}
+}
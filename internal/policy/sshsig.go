@@ -0,0 +1,157 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sshsigMagic starts every SSH signature and the data it signs
+const sshsigMagic = "SSHSIG"
+
+// keyTypeEd25519 is the only key type signatures are verified with
+const keyTypeEd25519 = "ssh-ed25519"
+
+// Verify checks that armored, a detached signature made with
+// "ssh-keygen -Y sign -n NAMESPACE", signs message in namespace with
+// publicKey, an "ssh-ed25519 AAAA..." line as found in a .pub file
+func Verify(publicKey, namespace string, message, armored []byte) error {
+	trusted, err := parsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	blob, err := unarmor(armored)
+	if err != nil {
+		return err
+	}
+	r := sshReader{data: blob}
+	magic := r.bytes(len(sshsigMagic))
+	version := r.uint32()
+	signer := r.string()
+	signedNamespace := r.string()
+	reserved := r.string()
+	hashAlgorithm := r.string()
+	signature := r.string()
+	if r.err != nil || string(magic) != sshsigMagic || version != 1 {
+		return errors.New("invalid SSH signature")
+	}
+
+	if !bytes.Equal(signer, trusted) {
+		return errors.New("signed with a different key")
+	}
+	if string(signedNamespace) != namespace {
+		return fmt.Errorf("signed for namespace %q instead of %q", signedNamespace, namespace)
+	}
+
+	var digest []byte
+	switch string(hashAlgorithm) {
+	case "sha512":
+		sum := sha512.Sum512(message)
+		digest = sum[:]
+	case "sha256":
+		sum := sha256.Sum256(message)
+		digest = sum[:]
+	default:
+		return fmt.Errorf("unsupported signature hash: %s", hashAlgorithm)
+	}
+
+	sig := sshReader{data: signature}
+	sigType := sig.string()
+	sigBytes := sig.string()
+	if sig.err != nil || string(sigType) != keyTypeEd25519 || len(sigBytes) != ed25519.SignatureSize {
+		return errors.New("unsupported signature type; sign with an Ed25519 key")
+	}
+
+	var signed bytes.Buffer
+	signed.WriteString(sshsigMagic)
+	writeSSHString(&signed, signedNamespace)
+	writeSSHString(&signed, reserved)
+	writeSSHString(&signed, hashAlgorithm)
+	writeSSHString(&signed, digest)
+	if !ed25519.Verify(ed25519.PublicKey(trusted[len(trusted)-ed25519.PublicKeySize:]), signed.Bytes(), sigBytes) {
+		return errors.New("signature doesn't match the policy")
+	}
+	return nil
+}
+
+// parsePublicKey returns the wire format of an "ssh-ed25519 AAAA... comment" key
+func parsePublicKey(line string) ([]byte, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != keyTypeEd25519 {
+		return nil, errors.New("invalid public key: must be an ssh-ed25519 key")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	r := sshReader{data: blob}
+	keyType := r.string()
+	key := r.string()
+	if r.err != nil || string(keyType) != keyTypeEd25519 || len(key) != ed25519.PublicKeySize || len(r.data) != 0 {
+		return nil, errors.New("invalid public key: must be an ssh-ed25519 key")
+	}
+	return blob, nil
+}
+
+// unarmor decodes the base64 between the BEGIN and END SSH SIGNATURE lines
+func unarmor(armored []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(armored))
+	const begin, end = "-----BEGIN SSH SIGNATURE-----", "-----END SSH SIGNATURE-----"
+	if !strings.HasPrefix(text, begin) || !strings.HasSuffix(text, end) {
+		return nil, errors.New("invalid SSH signature: missing BEGIN or END line")
+	}
+	body := strings.Join(strings.Fields(text[len(begin):len(text)-len(end)]), "")
+	blob, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH signature: %w", err)
+	}
+	return blob, nil
+}
+
+// sshReader reads the SSH wire format, remembering the first error
+type sshReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sshReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data) < n {
+		r.err = errors.New("truncated")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *sshReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if n > uint32(len(r.data)) {
+		r.err = errors.New("truncated")
+		return nil
+	}
+	return r.bytes(int(n))
+}
+
+func writeSSHString(buf *bytes.Buffer, s []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(s)))
+	buf.Write(n[:])
+	buf.Write(s)
+}
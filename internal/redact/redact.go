@@ -0,0 +1,123 @@
+// Package redact masks secrets such as API keys, tokens, and passwords in text
+// before dcode shows it in a dialog or writes it to a log.
+package redact
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces every secret found
+const Mask = "[REDACTED]"
+
+// secretGroup names the part of a pattern's match that is the secret. Patterns
+// without it mask their whole match.
+const secretGroup = "secret"
+
+// builtinPatterns find common secret formats. Assignment-like patterns keep
+// the name and mask only the value, so the dialog still shows what is set.
+var builtinPatterns = []string{
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                                      // AWS access key ID
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,                                     // GitHub token
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,                                   // GitHub fine-grained token
+	`\bglpat-[A-Za-z0-9_-]{20,}\b`,                                       // GitLab token
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`,                                   // Slack token
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}\b`,                           // Anthropic and OpenAI API keys
+	`\bAIza[0-9A-Za-z_-]{35}\b`,                                          // Google API key
+	`\b[rs]k_(?:live|test)_[0-9A-Za-z]{16,}\b`,                           // Stripe key
+	`\bnpm_[A-Za-z0-9]{36}\b`,                                            // npm token
+	`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`, // JSON Web Token
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,                                 // PEM private key
+	`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/-]{16,}=*)`,                // Authorization header
+	`://[^/\s:@]+:(?P<secret>[^/\s@]+)@`,                                 // Password in a URL
+	`(?i)\b[A-Za-z0-9_-]*(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)[A-Za-z0-9_-]*["']?\s*[:=]\s*(?P<secret>"[^"]+"|'[^']+'|[^\s"',;]+)`, // KEY=value
+}
+
+// rule masks the matches of pattern with mask
+type rule struct {
+	pattern *regexp.Regexp
+	mask    string
+}
+
+// Redactor masks the secrets matched by its patterns
+type Redactor struct {
+	rules []rule
+}
+
+// New returns a redactor for the built-in secret formats and the regular
+// expressions in extra. A pattern may mark the secret part of its match with
+// a (?P<secret>...) group.
+func New(extra []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range builtinPatterns {
+		r.rules = append(r.rules, rule{regexp.MustCompile(pattern), Mask})
+	}
+	for _, pattern := range extra {
+		if err := r.Add(pattern, Mask); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add masks the matches of pattern with mask as well, such as customer IDs
+// with "[CUSTOMER]". Like the patterns given to New, pattern may mark the
+// part to mask with a (?P<secret>...) group.
+func (r *Redactor) Add(pattern, mask string) error {
+	if pattern == "" {
+		return errors.New("empty secret pattern")
+	}
+	if mask == "" {
+		return errors.New("empty mask")
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	r.rules = append(r.rules, rule{compiled, mask})
+	return nil
+}
+
+// Redact returns text with every secret replaced by its rule's mask
+func (r *Redactor) Redact(text string) string {
+	for _, rule := range r.rules {
+		text = redactMatches(rule.pattern, rule.mask, text)
+	}
+	return text
+}
+
+// RedactAll redacts each of texts
+func (r *Redactor) RedactAll(texts []string) []string {
+	redacted := make([]string, len(texts))
+	for i, text := range texts {
+		redacted[i] = r.Redact(text)
+	}
+	return redacted
+}
+
+// redactMatches replaces the secret group of each match of pattern in text,
+// or the whole match if pattern has no secret group, with mask
+func redactMatches(pattern *regexp.Regexp, mask, text string) string {
+	group := pattern.SubexpIndex(secretGroup)
+
+	var b strings.Builder
+	last, found := 0, false
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if group > 0 {
+			start, end = match[2*group], match[2*group+1]
+		}
+		if start < 0 || start < last {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(mask)
+		last, found = end, true
+	}
+	if !found {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
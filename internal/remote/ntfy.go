@@ -0,0 +1,244 @@
+// Package remote asks someone away from the computer to answer a permission
+// dialog, through a push notification service such as ntfy, and tells them
+// what happened, there or in a Slack channel.
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxActions is how many buttons an ntfy notification can have
+const maxActions = 3
+
+// ReplySuffix is appended to the topic to get the topic answers are sent on
+const ReplySuffix = "-reply"
+
+// Ntfy asks through an ntfy topic (https://ntfy.sh, or a self-hosted server).
+// Each choice is an action button that posts the answer to the reply topic.
+type Ntfy struct {
+	TopicURL string // e.g. https://ntfy.sh/a-long-random-topic
+	Token    string // Access token for protected topics, or ""
+	Client   *http.Client
+}
+
+// ntfyMessage is the JSON publish request
+type ntfyMessage struct {
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title"`
+	Message  string       `json:"message"`
+	Priority int          `json:"priority"`
+	Tags     []string     `json:"tags"`
+	Actions  []ntfyAction `json:"actions"`
+	Email    string       `json:"email,omitempty"` // Address the server also emails the message to
+}
+
+type ntfyAction struct {
+	Action string `json:"action"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+	Method string `json:"method"`
+	Body   string `json:"body"`
+	Clear  bool   `json:"clear"`
+}
+
+// ntfyEvent is one line of a topic's JSON stream
+type ntfyEvent struct {
+	Event   string `json:"event"`
+	Message string `json:"message"`
+}
+
+// Ask posts message with a button for each of buttons and waits for one to
+// be pressed, returning its 1-based number. A notification has at most three
+// buttons, so with more only the first two and the last are offered. Ask
+// returns ctx's error if ctx ends first.
+func (n Ntfy) Ask(ctx context.Context, title, message string, buttons []string) (int, error) {
+	server, topic, reply, err := n.endpoints()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := requestID()
+	if err != nil {
+		return 0, err
+	}
+
+	// Subscribe first so an answer can't arrive before anyone listens
+	since := strconv.FormatInt(time.Now().Unix(), 10)
+	subscribeURL := reply.String() + "/json?since=" + since
+	request, err := n.newRequest(ctx, http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	response, err := n.client().Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to subscribe to ntfy replies: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to subscribe to ntfy replies: %s", response.Status)
+	}
+
+	publish := ntfyMessage{
+		Topic:    topic,
+		Title:    title,
+		Message:  message,
+		Priority: 4,
+		Tags:     []string{"lock"},
+	}
+	for _, number := range offeredButtons(len(buttons)) {
+		publish.Actions = append(publish.Actions, ntfyAction{
+			Action: "http",
+			Label:  buttons[number-1],
+			URL:    reply.String(),
+			Method: http.MethodPost,
+			Body:   id + " " + strconv.Itoa(number),
+			Clear:  true,
+		})
+	}
+	if err := n.publish(ctx, server.String(), publish); err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var event ntfyEvent
+		if json.Unmarshal(scanner.Bytes(), &event) != nil || event.Event != "message" {
+			continue
+		}
+		answer, found := strings.CutPrefix(event.Message, id+" ")
+		if !found {
+			continue
+		}
+		if number, err := strconv.Atoi(answer); err == nil && number >= 1 && number <= len(buttons) {
+			return number, nil
+		}
+	}
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("lost the ntfy reply stream: %w", err)
+	}
+	return 0, fmt.Errorf("lost the ntfy reply stream")
+}
+
+// Notify posts message without buttons at the highest priority, for
+// something that can't wait
+func (n Ntfy) Notify(ctx context.Context, title, message string) error {
+	server, topic, _, err := n.endpoints()
+	if err != nil {
+		return err
+	}
+	return n.publish(ctx, server.String(), ntfyMessage{
+		Topic:    topic,
+		Title:    title,
+		Message:  message,
+		Priority: 5,
+		Tags:     []string{"rotating_light"},
+	})
+}
+
+// Summary posts a long message at default priority, such as a daily digest,
+// which the server also emails to email unless it's ""
+func (n Ntfy) Summary(ctx context.Context, title, message, email string) error {
+	server, topic, _, err := n.endpoints()
+	if err != nil {
+		return err
+	}
+	return n.publish(ctx, server.String(), ntfyMessage{
+		Topic:    topic,
+		Title:    title,
+		Message:  message,
+		Priority: 3,
+		Tags:     []string{"memo"},
+		Email:    email,
+	})
+}
+
+// endpoints returns the server of the topic, the topic's name, and the
+// topic answers come back on
+func (n Ntfy) endpoints() (url.URL, string, url.URL, error) {
+	u, err := url.Parse(n.TopicURL)
+	if err != nil {
+		return url.URL{}, "", url.URL{}, fmt.Errorf("invalid ntfy topic URL: %w", err)
+	}
+	base, topic := path.Split(strings.TrimSuffix(u.Path, "/"))
+	server := *u
+	server.Path = base
+	reply := *u
+	reply.Path = base + topic + ReplySuffix
+	return server, topic, reply, nil
+}
+
+// publish posts message to the server at serverURL
+func (n Ntfy) publish(ctx context.Context, serverURL string, message ntfyMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	request, err := n.newRequest(ctx, http.MethodPost, serverURL, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := n.client().Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to post to ntfy: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to post to ntfy: %s", response.Status)
+	}
+	return nil
+}
+
+func (n Ntfy) newRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if n.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	return request, nil
+}
+
+func (n Ntfy) client() *http.Client {
+	if n.Client != nil {
+		return n.Client
+	}
+	return http.DefaultClient
+}
+
+// offeredButtons returns the 1-based numbers of the buttons to offer: all of
+// them if they fit, or else the first ones and the last, which rejects
+func offeredButtons(count int) []int {
+	var numbers []int
+	for number := 1; number <= count; number++ {
+		if count <= maxActions || number < maxActions || number == count {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// requestID returns a random ID matching answers to the request they answer
+func requestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
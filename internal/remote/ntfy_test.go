@@ -0,0 +1,172 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNtfy is an ntfy server that answers each published request by pressing
+// the button labeled press
+type fakeNtfy struct {
+	press     string
+	published chan ntfyMessage
+	token     string
+}
+
+func (f *fakeNtfy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != f.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/":
+		var message ntfyMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.published <- message
+	case r.Method == http.MethodGet && r.URL.Path == "/dcode-topic-reply/json":
+		w.(http.Flusher).Flush()
+		fmt.Fprintln(w, `{"event":"open"}`)
+		w.(http.Flusher).Flush()
+		var message ntfyMessage
+		select {
+		case message = <-f.published:
+		case <-r.Context().Done():
+			return
+		}
+		// Another request's answer comes first and must be ignored
+		fmt.Fprintln(w, `{"event":"message","message":"0123456789abcdef 1"}`)
+		for _, action := range message.Actions {
+			if action.Label == f.press {
+				fmt.Fprintf(w, `{"event":"message","message":%q}`+"\n", action.Body)
+			}
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeNtfy(t *testing.T, press string) (*fakeNtfy, string) {
+	fake := &fakeNtfy{press: press, published: make(chan ntfyMessage, 1)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server.URL + "/dcode-topic"
+}
+
+func TestNtfyAsk(t *testing.T) {
+	_, topicURL := newFakeNtfy(t, "No")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	number, err := Ntfy{TopicURL: topicURL}.Ask(ctx, "Claude Permission", "rm -rf build", []string{"Yes", "Yes, don't ask again", "No"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if number != 3 {
+		t.Errorf("Expected button 3, got %d", number)
+	}
+}
+
+func TestNtfyAskSendsToken(t *testing.T) {
+	fake, topicURL := newFakeNtfy(t, "Yes")
+	fake.token = "Bearer tk_secret"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := (Ntfy{TopicURL: topicURL}).Ask(ctx, "t", "m", []string{"Yes", "No"}); err == nil {
+		t.Error("Expected an error without the token")
+	}
+	if number, err := (Ntfy{TopicURL: topicURL, Token: "tk_secret"}).Ask(ctx, "t", "m", []string{"Yes", "No"}); err != nil || number != 1 {
+		t.Errorf("Expected button 1, got %d, %v", number, err)
+	}
+}
+
+func TestNtfyAskTimesOut(t *testing.T) {
+	_, topicURL := newFakeNtfy(t, "Nobody presses this")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := Ntfy{TopicURL: topicURL}.Ask(ctx, "t", "m", []string{"Yes", "No"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+}
+
+func TestOfferedButtons(t *testing.T) {
+	testCases := []struct {
+		count    int
+		expected []int
+	}{
+		{2, []int{1, 2}},
+		{3, []int{1, 2, 3}},
+		{5, []int{1, 2, 5}},
+	}
+	for _, tc := range testCases {
+		if result := offeredButtons(tc.count); !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("Expected %v for %d buttons, got %v", tc.expected, tc.count, result)
+		}
+	}
+}
+
+func TestNtfyAskPublishesButtons(t *testing.T) {
+	fake, topicURL := newFakeNtfy(t, "")
+	published := make(chan ntfyMessage, 1)
+	fake.published = published
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	go Ntfy{TopicURL: topicURL}.Ask(ctx, "Claude Permission", "git push --force", []string{"Yes", "No"})
+	var message ntfyMessage
+	select {
+	case message = <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a published message")
+	}
+
+	if message.Topic != "dcode-topic" || message.Message != "git push --force" || len(message.Actions) != 2 {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+	if action := message.Actions[1]; action.Label != "No" || !strings.HasSuffix(action.URL, "/dcode-topic-reply") || !strings.HasSuffix(action.Body, " 2") {
+		t.Errorf("Unexpected action: %+v", action)
+	}
+}
+
+func TestNtfyNotify(t *testing.T) {
+	fake, topicURL := newFakeNtfy(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := (Ntfy{TopicURL: topicURL}).Notify(ctx, "Claude Permission", "A dialog has waited 1m0s"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	message := <-fake.published
+	if message.Topic != "dcode-topic" || message.Message != "A dialog has waited 1m0s" || message.Priority != 5 || len(message.Actions) != 0 {
+		t.Errorf("Expected an urgent message without buttons, got %+v", message)
+	}
+}
+
+func TestNtfySummary(t *testing.T) {
+	fake, topicURL := newFakeNtfy(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := (Ntfy{TopicURL: topicURL}).Summary(ctx, "dcode digest", "12 answers", "team@example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	message := <-fake.published
+	if message.Message != "12 answers" || message.Priority != 3 || message.Email != "team@example.com" {
+		t.Errorf("Expected a default priority message emailed to the team, got %+v", message)
+	}
+}
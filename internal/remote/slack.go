@@ -0,0 +1,42 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Slack posts messages to a channel through a Slack incoming webhook
+// (https://api.slack.com/messaging/webhooks)
+type Slack struct {
+	WebhookURL string // e.g. https://hooks.slack.com/services/T000/B000/XXXX
+	Client     *http.Client
+}
+
+// Post posts text to the webhook's channel
+func (s Slack) Post(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to post to Slack: %s", response.Status)
+	}
+	return nil
+}
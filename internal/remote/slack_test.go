@@ -0,0 +1,34 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlackPost(t *testing.T) {
+	var posted map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	if err := (Slack{WebhookURL: server.URL}).Post(context.Background(), "12 answers"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if posted["text"] != "12 answers" {
+		t.Errorf("Expected the text to be posted, got %v", posted)
+	}
+
+	gone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer gone.Close()
+	if err := (Slack{WebhookURL: gone.URL}).Post(context.Background(), "12 answers"); err == nil {
+		t.Error("Expected an error from a removed webhook")
+	}
+}
@@ -0,0 +1,90 @@
+// Package state keeps what a running dcode session changed, such as a mode
+// switched with "dcode mode", in a file per project directory, so restarting
+// dcode in the middle of a session picks up where it left off instead of
+// reverting to the configured defaults.
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaxAge is how long after it was saved a state is still restored. An older
+// one is taken to belong to a session that has ended.
+const MaxAge = 8 * time.Hour
+
+// State is what a session changed while it ran
+type State struct {
+	SavedAt            time.Time            `json:"saved_at"`
+	Mode               string               `json:"mode,omitempty"`                // Set with "dcode mode", as config.Mode.String
+	AutoPaused         bool                 `json:"auto_paused,omitempty"`         // A rejection loop paused the auto mode
+	TemporaryApprovals map[string]time.Time `json:"temporary_approvals,omitempty"` // Expiry by approvals.Scope
+	AutoApproved       []string             `json:"auto_approved,omitempty"`       // Requests approved in a row, for max_auto_approvals
+	RejectedRequest    string               `json:"rejected_request,omitempty"`    // Request rejected in a row, for rejection_loop_limit
+	RejectionCount     int                  `json:"rejection_count,omitempty"`
+}
+
+// DefaultPath returns the state file for the project in dir under
+// ~/.cache/dcode/sessions, honoring $XDG_CACHE_HOME, or "" if the cache
+// directory is unknown
+func DefaultPath(dir string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(dir)))
+	return filepath.Join(cacheDir, "dcode", "sessions", hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load reads the state saved at path, returning an empty state if there is
+// none or it is older than MaxAge at now
+func Load(path string, now time.Time) (State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, err
+	}
+	if now.Sub(state.SavedAt) > MaxAge {
+		return State{}, nil
+	}
+	return state, nil
+}
+
+// Save writes state to path through a temporary file, so a restart never
+// reads a partial state
+func Save(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "project.json")
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if state, err := Load(path, now); err != nil || !reflect.DeepEqual(state, State{}) {
+		t.Fatalf("Expected an empty state without a file, got %+v, %v", state, err)
+	}
+
+	saved := State{
+		SavedAt:            now,
+		Mode:               "auto-reject-wait=30",
+		TemporaryApprovals: map[string]time.Time{"Bash: go test": now.Add(15 * time.Minute)},
+		AutoApproved:       []string{"Bash: make"},
+		RejectedRequest:    "Bash: git push --force",
+		RejectionCount:     2,
+	}
+	if err := Save(path, saved); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		at       time.Time
+		expected State
+	}{
+		{"restarted soon after", now.Add(time.Minute), saved},
+		{"restarted the next day", now.Add(MaxAge + time.Minute), State{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, err := Load(path, tc.at)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(state, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, state)
+			}
+		})
+	}
+}
+
+func TestLoadCorruptState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, time.Now()); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if DefaultPath("/repo/a") == DefaultPath("/repo/b") {
+		t.Error("Expected a state file per directory")
+	}
+	if DefaultPath("/repo/a") != DefaultPath("/repo/a/") {
+		t.Error("Expected the same state file for the same directory")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package systemlog
+
+import (
+	"errors"
+	"runtime"
+)
+
+// dial fails, since there's no syslog to connect to
+func dial(code int) (Writer, error) {
+	return nil, errors.New("syslog is not available on " + runtime.GOOS)
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !plan9
+
+package systemlog
+
+import "log/syslog"
+
+// dial connects to the local syslog daemon, logging under the facility with code
+func dial(code int) (Writer, error) {
+	return syslog.New(syslog.Priority(code<<3)|syslog.LOG_INFO, Tag)
+}
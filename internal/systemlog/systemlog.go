@@ -0,0 +1,133 @@
+// Package systemlog mirrors answered prompts to the system log, so
+// administrators can collect them with their existing log pipelines. On
+// Linux and the BSDs the messages go to syslog; on macOS, syslogd passes them
+// on to the unified log, where "log show" and "log stream" find them.
+package systemlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// Tag names dcode in each message
+const Tag = "dcode"
+
+// facilities are the syslog facility names, at their codes from RFC 5424.
+// Codes 12 to 15 have no name here.
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ValidFacility reports whether name is a syslog facility, such as "user" or
+// "local0"
+func ValidFacility(name string) bool {
+	_, ok := facilities[name]
+	return ok
+}
+
+// Writer sends messages to the system log at a severity. The *syslog.Writer
+// from log/syslog is one.
+type Writer interface {
+	Info(message string) error
+	Notice(message string) error
+	Warning(message string) error
+	Close() error
+}
+
+// Logger mirrors answered prompts to a Writer
+type Logger struct {
+	w Writer
+}
+
+// New returns a logger sending to w
+func New(w Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Dial returns a logger sending to the local system log under facility. It
+// fails where the platform has no syslog, such as on Windows.
+func Dial(facility string) (*Logger, error) {
+	code, ok := facilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+	w, err := dial(code)
+	if err != nil {
+		return nil, err
+	}
+	return New(w), nil
+}
+
+// Log sends entry to the system log: rejections as warnings, approvals as
+// notices, and other answers as information
+func (l *Logger) Log(entry audit.Entry) error {
+	message := Format(entry)
+	switch entry.Action {
+	case audit.Rejected:
+		return l.w.Warning(message)
+	case audit.Approved:
+		return l.w.Notice(message)
+	}
+	return l.w.Info(message)
+}
+
+// Close closes the connection to the system log
+func (l *Logger) Close() error {
+	return l.w.Close()
+}
+
+// Format returns entry as a line of key=value pairs, quoting values with
+// spaces, e.g.
+//
+//	decision=rejected tool=Bash request="Bash: git push --force" decided_by="deny rule 2" ...
+//
+// The dialog is left out; the audit log keeps it.
+func Format(entry audit.Entry) string {
+	fields := []struct{ key, value string }{
+		{"decision", entry.Action},
+		{"tool", entry.Tool},
+		{"request", entry.Request},
+		{"choice", entry.Choice},
+		{"decided_by", entry.DecidedBy},
+		{"risk", entry.Risk},
+		{"mode", entry.Mode},
+		{"prompt", entry.Prompt},
+		{"dir", entry.Dir},
+		{"session", strconv.Itoa(entry.Session)},
+		{"latency_ms", strconv.FormatInt(entry.LatencyMs, 10)},
+		{"wait_ms", strconv.FormatInt(entry.WaitMs, 10)},
+		{"id", entry.ID},
+	}
+
+	var line strings.Builder
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(field.key)
+		line.WriteByte('=')
+		line.WriteString(quote(field.value))
+	}
+	return line.String()
+}
+
+// quote returns value quoted if it's not a single plain word
+func quote(value string) string {
+	plain := !strings.ContainsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsPrint(r) || strings.ContainsRune(`"=\`, r)
+	})
+	if plain {
+		return value
+	}
+	return strconv.Quote(value)
+}
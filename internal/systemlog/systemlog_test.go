@@ -0,0 +1,74 @@
+package systemlog
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/audit"
+)
+
+// fakeWriter records each message with its severity
+type fakeWriter struct {
+	messages []string
+	closed   bool
+}
+
+func (w *fakeWriter) Info(message string) error    { return w.add("info", message) }
+func (w *fakeWriter) Notice(message string) error  { return w.add("notice", message) }
+func (w *fakeWriter) Warning(message string) error { return w.add("warning", message) }
+func (w *fakeWriter) Close() error                 { w.closed = true; return nil }
+
+func (w *fakeWriter) add(severity, message string) error {
+	w.messages = append(w.messages, severity+": "+message)
+	return nil
+}
+
+func TestLoggerSeverity(t *testing.T) {
+	w := &fakeWriter{}
+	logger := New(w)
+
+	for _, action := range []string{audit.Rejected, audit.Approved, audit.Answered} {
+		if err := logger.Log(audit.Entry{Action: action, ID: "0000aaaa"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	logger.Close()
+
+	expected := []string{
+		"warning: decision=rejected session=0 latency_ms=0 wait_ms=0 id=0000aaaa",
+		"notice: decision=approved session=0 latency_ms=0 wait_ms=0 id=0000aaaa",
+		"info: decision=answered session=0 latency_ms=0 wait_ms=0 id=0000aaaa",
+	}
+	if !reflect.DeepEqual(w.messages, expected) || !w.closed {
+		t.Errorf("Expected %q, got %q (closed %v)", expected, w.messages, w.closed)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	entry := audit.Entry{
+		ID: "7c01d2aa", Session: 4242, Dir: "/home/me/app", Prompt: "permission", Tool: "Bash",
+		Request: `Bash: echo "a=b"`, Risk: "high (force push)", Choice: "3", Action: audit.Rejected,
+		DecidedBy: "deny rule 2", Mode: "dialog", LatencyMs: 812, WaitMs: 640, Dialog: []string{"│ Bash command │"},
+	}
+
+	expected := `decision=rejected tool=Bash request="Bash: echo \"a=b\"" choice=3 decided_by="deny rule 2" risk="high (force push)" mode=dialog prompt=permission dir=/home/me/app session=4242 latency_ms=812 wait_ms=640 id=7c01d2aa`
+	if got := Format(entry); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestFacilities(t *testing.T) {
+	for _, name := range []string{"user", "auth", "local0", "local7"} {
+		if !ValidFacility(name) {
+			t.Errorf("Expected %q to be a facility", name)
+		}
+	}
+	for _, name := range []string{"", "local8", "USER"} {
+		if ValidFacility(name) {
+			t.Errorf("Expected %q not to be a facility", name)
+		}
+	}
+	if _, err := Dial("local8"); err == nil {
+		t.Error("Expected an unknown facility to fail")
+	}
+}
@@ -0,0 +1,156 @@
+// Package telemetry counts how dcode is used during a session, without
+// recording what was asked, and sends the counts to a URL the user chose when
+// the session ends. Nothing is counted or sent unless telemetry is turned on.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+// SendTimeout is how long sending a report may take before it's abandoned
+const SendTimeout = 5 * time.Second
+
+// Categories of error
+const (
+	ErrorCrash     = "crash"     // dcode crashed handling a prompt
+	ErrorInjection = "injection" // An answer couldn't be typed into Claude
+	ErrorOther     = "other"
+)
+
+// Report is everything sent: counts only, with no commands, paths, rule
+// names, or anything else that identifies the user or their project
+type Report struct {
+	OS           string         `json:"os"`
+	Arch         string         `json:"arch"`
+	Backends     []string       `json:"backends"`      // How dialogs could be answered, e.g. "osascript" or "ntfy"
+	Prompts      map[string]int `json:"prompts"`       // Prompts detected, by kind
+	DialogsShown int            `json:"dialogs_shown"` // Prompts the user was asked about
+	Suppressed   int            `json:"suppressed"`    // Prompts skipped as repeats
+	UnknownTools int            `json:"unknown_tools"` // Dialogs whose tool couldn't be read
+	Decisions    map[string]int `json:"decisions"`     // Answers, by action
+	DecidedBy    map[string]int `json:"decided_by"`    // Answers, by who or what gave them, without rule names or numbers
+	Modes        map[string]int `json:"modes"`         // Answers, by the mode dcode was in
+	Errors       map[string]int `json:"errors"`        // Errors, by category
+}
+
+// Counters counts events of a session. The methods of a nil Counters do
+// nothing, so callers needn't check whether telemetry is on.
+type Counters struct {
+	report Report
+	mutex  sync.Mutex
+}
+
+// NewCounters returns counters for a session whose dialogs can be answered
+// by backends
+func NewCounters(backends []string) *Counters {
+	return &Counters{report: Report{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Backends:  backends,
+		Prompts:   map[string]int{},
+		Decisions: map[string]int{},
+		DecidedBy: map[string]int{},
+		Modes:     map[string]int{},
+		Errors:    map[string]int{},
+	}}
+}
+
+// Record counts event, which happened in mode
+func (c *Counters) Record(event events.Event, mode string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	switch event.Type {
+	case events.DialogDetected:
+		c.report.Prompts[event.Kind]++
+	case events.DialogShown:
+		c.report.DialogsShown++
+		if event.Tool == "" {
+			c.report.UnknownTools++
+		}
+	case events.Suppressed:
+		c.report.Suppressed++
+	case events.Decision:
+		c.report.Decisions[event.Action]++
+		c.report.DecidedBy[Category(event.DecidedBy)]++
+		c.report.Modes[mode]++
+	case events.Error:
+		c.report.Errors[errorCategory(event)]++
+	}
+}
+
+// Report returns a copy of the counts so far
+func (c *Counters) Report() Report {
+	if c == nil {
+		return Report{}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	report := c.report
+	report.Backends = slices.Clone(c.report.Backends)
+	report.Prompts = maps.Clone(c.report.Prompts)
+	report.Decisions = maps.Clone(c.report.Decisions)
+	report.DecidedBy = maps.Clone(c.report.DecidedBy)
+	report.Modes = maps.Clone(c.report.Modes)
+	report.Errors = maps.Clone(c.report.Errors)
+	return report
+}
+
+// Category returns who or what answered a prompt without the rule's number
+// or name, e.g. "deny rule" for "deny rule 2 'no force push'"
+func Category(decidedBy string) string {
+	if i := strings.IndexAny(decidedBy, "0123456789'"); i >= 0 {
+		decidedBy = decidedBy[:i]
+	}
+	return strings.TrimSpace(decidedBy)
+}
+
+// errorCategory returns the category of an error event, without its message
+func errorCategory(event events.Event) string {
+	switch {
+	case strings.HasPrefix(event.Message, "crashed"):
+		return ErrorCrash
+	case event.Choice != "":
+		return ErrorInjection
+	default:
+		return ErrorOther
+	}
+}
+
+// Send posts report as JSON to endpoint
+func Send(ctx context.Context, endpoint string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry endpoint replied %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+func TestCounters(t *testing.T) {
+	counters := NewCounters([]string{"osascript"})
+	counters.Record(events.Event{Type: events.DialogDetected, Kind: "permission"}, "normal")
+	counters.Record(events.Event{Type: events.DialogShown, Tool: "Bash", Request: "Bash: git push"}, "normal")
+	counters.Record(events.Event{Type: events.DialogShown}, "normal")
+	counters.Record(events.Event{Type: events.Suppressed, Message: "repeat"}, "normal")
+	counters.Record(events.Event{Type: events.Decision, Action: "rejected", DecidedBy: "deny rule 2 'no force push'"}, "auto-reject")
+	counters.Record(events.Event{Type: events.Decision, Action: "approved", DecidedBy: "user"}, "normal")
+	counters.Record(events.Event{Type: events.Error, Choice: "1", Message: "write /dev/ptmx: broken pipe"}, "normal")
+	counters.Record(events.Event{Type: events.Error, Message: "crashed: index out of range"}, "normal")
+
+	report := counters.Report()
+	if report.Prompts["permission"] != 1 || report.DialogsShown != 2 || report.UnknownTools != 1 || report.Suppressed != 1 {
+		t.Errorf("Unexpected prompt counts: %+v", report)
+	}
+	if report.Decisions["rejected"] != 1 || report.Decisions["approved"] != 1 || report.DecidedBy["deny rule"] != 1 || report.DecidedBy["user"] != 1 {
+		t.Errorf("Unexpected decision counts: %+v", report)
+	}
+	if report.Modes["auto-reject"] != 1 || report.Modes["normal"] != 1 {
+		t.Errorf("Unexpected mode counts: %v", report.Modes)
+	}
+	if report.Errors[ErrorInjection] != 1 || report.Errors[ErrorCrash] != 1 {
+		t.Errorf("Unexpected error counts: %v", report.Errors)
+	}
+
+	payload, _ := json.Marshal(report)
+	for _, private := range []string{"git push", "no force push", "broken pipe", "index out of range"} {
+		if strings.Contains(string(payload), private) {
+			t.Errorf("Expected the report to leave out %q, got %s", private, payload)
+		}
+	}
+}
+
+func TestNilCounters(t *testing.T) {
+	var counters *Counters
+	counters.Record(events.Event{Type: events.DialogShown}, "normal")
+}
+
+func TestCategory(t *testing.T) {
+	for decidedBy, expected := range map[string]string{
+		"user":                         "user",
+		"approve rule 3":               "approve rule",
+		"forbid rule 1 'prod deploys'": "forbid rule",
+		"risk policy for high risk":    "risk policy for high risk",
+		"auto_reject_wait":             "auto_reject_wait",
+	} {
+		if category := Category(decidedBy); category != expected {
+			t.Errorf("Category(%q) = %q, expected %q", decidedBy, category, expected)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("Expected a JSON report, got %s", body)
+		}
+	}))
+	defer server.Close()
+
+	counters := NewCounters([]string{"osascript", "ntfy"})
+	counters.Record(events.Event{Type: events.DialogShown, Tool: "Bash"}, "normal")
+	if err := Send(context.Background(), server.URL, counters.Report()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if received.DialogsShown != 1 || len(received.Backends) != 2 {
+		t.Errorf("Unexpected report received: %+v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := Send(context.Background(), failing.URL, counters.Report()); err == nil {
+		t.Error("Expected an error when the endpoint fails")
+	}
+}
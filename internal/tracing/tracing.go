@@ -0,0 +1,212 @@
+// Package tracing records how long each step of answering a prompt takes, as
+// one trace per prompt, and exports the traces to an OpenTelemetry collector
+// with OTLP over HTTP, in its JSON encoding.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceName is the service.name of exported spans
+const ServiceName = "dcode"
+
+// ExportTimeout is how long an export may take before it's abandoned
+const ExportTimeout = 5 * time.Second
+
+// Span is one timed step of a trace
+type Span struct {
+	TraceID    string // 32 hex digits shared by the spans of a trace
+	SpanID     string // 16 hex digits
+	ParentID   string // SpanID of the root span, or "" for the root itself
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+}
+
+// Trace collects the spans of one prompt under a root span. The methods of a
+// nil Trace do nothing, so callers needn't check whether tracing is on.
+type Trace struct {
+	root     Span
+	children []Span
+	mutex    sync.Mutex
+}
+
+// NewTrace starts a trace whose root span, name, starts at start
+func NewTrace(name string, start time.Time) *Trace {
+	return &Trace{root: Span{
+		TraceID:    newID(16),
+		SpanID:     newID(8),
+		Name:       name,
+		Start:      start,
+		Attributes: map[string]string{},
+	}}
+}
+
+// Record adds a step, name, that ran from start to end
+func (t *Trace) Record(name string, start, end time.Time) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.children = append(t.children, Span{
+		TraceID:  t.root.TraceID,
+		SpanID:   newID(8),
+		ParentID: t.root.SpanID,
+		Name:     name,
+		Start:    start,
+		End:      end,
+	})
+}
+
+// SetAttribute sets an attribute of the root span, such as the tool requested
+func (t *Trace) SetAttribute(key, value string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.root.Attributes[key] = value
+}
+
+// End ends the root span at end and returns every span of the trace, root first
+func (t *Trace) End(end time.Time) []Span {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.root.End = end
+	return append([]Span{t.root}, t.children...)
+}
+
+// newID returns n random bytes as hex digits
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%0*x", n*2, time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Exporter sends spans to an OTLP/HTTP endpoint
+type Exporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewExporter returns an exporter for the collector at endpoint, such as
+// "http://localhost:4318". Spans are posted to its /v1/traces path.
+func NewExporter(endpoint string) *Exporter {
+	return &Exporter{
+		url:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		client: &http.Client{Timeout: ExportTimeout},
+	}
+}
+
+// Export sends spans to the collector
+func (e *Exporter) Export(ctx context.Context, spans []Span) error {
+	body, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("collector replied %s", response.Status)
+	}
+	return nil
+}
+
+// OTLP JSON encoding of an export request, as in
+// opentelemetry/proto/collector/trace/v1/trace_service.proto
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// spanKindInternal is SPAN_KIND_INTERNAL
+const spanKindInternal = 1
+
+// encode returns spans as an OTLP export request
+func encode(spans []Span) exportRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		encoded = append(encoded, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        attributes(span.Attributes),
+		})
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: attributes(map[string]string{"service.name": ServiceName})},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: ServiceName}, Spans: encoded}},
+	}}}
+}
+
+// attributes returns values as OTLP attributes, sorted by key
+func attributes(values map[string]string) []keyValue {
+	var result []keyValue
+	for key, value := range values {
+		result = append(result, keyValue{Key: key, Value: anyValue{StringValue: value}})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrace(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace := NewTrace("prompt", start)
+	trace.Record("detect", start, start.Add(200*time.Millisecond))
+	trace.Record("inject", start.Add(time.Second), start.Add(time.Second+time.Millisecond))
+	trace.SetAttribute("tool", "Bash")
+
+	spans := trace.End(start.Add(2 * time.Second))
+	if len(spans) != 3 {
+		t.Fatalf("Expected the root and two steps, got %+v", spans)
+	}
+	root := spans[0]
+	if root.Name != "prompt" || root.ParentID != "" || len(root.TraceID) != 32 || len(root.SpanID) != 16 ||
+		!root.End.Equal(start.Add(2*time.Second)) || root.Attributes["tool"] != "Bash" {
+		t.Errorf("Expected the root span, got %+v", root)
+	}
+	for _, step := range spans[1:] {
+		if step.TraceID != root.TraceID || step.ParentID != root.SpanID || step.SpanID == root.SpanID {
+			t.Errorf("Expected a step of the root, got %+v", step)
+		}
+	}
+	if spans[1].Name != "detect" || spans[2].Name != "inject" {
+		t.Errorf("Expected the steps in order, got %+v", spans[1:])
+	}
+}
+
+func TestNilTrace(t *testing.T) {
+	var trace *Trace
+	trace.Record("detect", time.Now(), time.Now())
+	trace.SetAttribute("tool", "Bash")
+	if spans := trace.End(time.Now()); spans != nil {
+		t.Errorf("Expected no spans, got %+v", spans)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var path, contentType string
+	var request exportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("Expected JSON, got %s", body)
+		}
+	}))
+	defer server.Close()
+
+	start := time.Unix(1700000000, 0)
+	trace := NewTrace("prompt", start)
+	trace.Record("decide", start, start.Add(time.Millisecond))
+	trace.SetAttribute("decision", "approved")
+	if err := NewExporter(server.URL+"/").Export(context.Background(), trace.End(start.Add(time.Second))); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if path != "/v1/traces" || contentType != "application/json" {
+		t.Errorf("Expected a JSON post to /v1/traces, got %s %s", contentType, path)
+	}
+	if len(request.ResourceSpans) != 1 || len(request.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope, got %+v", request)
+	}
+	resource := request.ResourceSpans[0]
+	if attrs := resource.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || attrs[0].Value.StringValue != ServiceName {
+		t.Errorf("Expected the service name, got %+v", attrs)
+	}
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected two spans, got %+v", spans)
+	}
+	if spans[0].StartTimeUnixNano != "1700000000000000000" || spans[0].EndTimeUnixNano != "1700000001000000000" ||
+		len(spans[0].Attributes) != 1 || spans[0].Attributes[0].Value.StringValue != "approved" {
+		t.Errorf("Expected the root span, got %+v", spans[0])
+	}
+	if spans[1].Name != "decide" || spans[1].ParentSpanID != spans[0].SpanID {
+		t.Errorf("Expected the step under the root, got %+v", spans[1])
+	}
+}
+
+func TestExportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := NewExporter(server.URL).Export(context.Background(), nil); err == nil {
+		t.Error("Expected an error for a rejected export")
+	}
+}
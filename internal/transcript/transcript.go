@@ -0,0 +1,144 @@
+// Package transcript saves what Claude printed in a session as plain text,
+// with a marker line wherever dcode detected a prompt and for what it
+// decided, so an unattended run can be reviewed afterwards as one narrative.
+package transcript
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+// MarkerPrefix starts every line dcode adds, telling it apart from Claude's
+// output
+const MarkerPrefix = ">>> dcode"
+
+// Transcript writes a session's output and markers. The methods of a nil
+// Transcript do nothing, so callers needn't check whether it is on.
+type Transcript struct {
+	w        io.Writer
+	lastLine string
+	mutex    sync.Mutex
+}
+
+// New returns a transcript writing to w
+func New(w io.Writer) *Transcript {
+	return &Transcript{w: w}
+}
+
+// Path returns the file a transcript at target is written to: target
+// itself, or if it is a directory or ends with a slash, a file in it named
+// after the session started at start with process ID pid
+func Path(target string, start time.Time, pid int) string {
+	if info, err := os.Stat(target); strings.HasSuffix(target, "/") || (err == nil && info.IsDir()) {
+		return filepath.Join(target, fmt.Sprintf("transcript-%s-%d.txt", start.Format("20060102-150405"), pid))
+	}
+	return target
+}
+
+// Open returns a transcript appending to the file at path, creating it and
+// its directory if needed
+func Open(path string) (*Transcript, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return New(file), nil
+}
+
+// Start marks the start of the session with process ID pid in dir
+func (t *Transcript) Start(now time.Time, pid int, dir string) error {
+	return t.mark(now, fmt.Sprintf("session %d started in %s", pid, dir))
+}
+
+// Line adds a line of Claude's output, with escape sequences already
+// stripped. Blank lines and a line repeating the one before, as when Claude
+// redraws the screen, are left out.
+func (t *Transcript) Line(line string) error {
+	if t == nil {
+		return nil
+	}
+	line = strings.TrimRight(line, " \t\r")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if line == t.lastLine {
+		return nil
+	}
+	t.lastLine = line
+	_, err := fmt.Fprintln(t.w, line)
+	return err
+}
+
+// Event adds a marker describing event, for the event types a reader of
+// the transcript cares about
+func (t *Transcript) Event(event events.Event) error {
+	text := Describe(event)
+	if text == "" {
+		return nil
+	}
+	return t.mark(event.Time, text)
+}
+
+// Describe returns the marker text for event, or "" if it isn't marked
+func Describe(event events.Event) string {
+	var text string
+	switch event.Type {
+	case events.DialogDetected:
+		text = fmt.Sprintf("prompt %d detected (%s)", event.Prompt, event.Kind)
+	case events.DialogShown:
+		text = fmt.Sprintf("prompt %d: asked the user about %s", event.Prompt, event.Request)
+	case events.Suppressed:
+		text = "skipped a prompt as a repeat: " + event.Message
+	case events.Decision:
+		text = fmt.Sprintf("prompt %d: %s by %s with choice %s", event.Prompt, event.Action, event.DecidedBy, event.Choice)
+		if event.Label != "" {
+			text += fmt.Sprintf(" (%s)", event.Label)
+		}
+		if event.Request != "" {
+			text += ": " + event.Request
+		}
+	case events.Error:
+		text = fmt.Sprintf("prompt %d: error: %s", event.Prompt, event.Message)
+	}
+	return text
+}
+
+// End marks the end of the session and closes the file the transcript
+// writes to
+func (t *Transcript) End(now time.Time) error {
+	if t == nil {
+		return nil
+	}
+	err := t.mark(now, "session ended")
+	if closer, ok := t.w.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// mark adds a marker line with text at now
+func (t *Transcript) mark(now time.Time, text string) error {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lastLine = ""
+	_, err := fmt.Fprintf(t.w, "%s %s: %s\n", MarkerPrefix, now.Format("15:04:05"), text)
+	return err
+}
@@ -0,0 +1,72 @@
+package transcript
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/internal/events"
+)
+
+func TestTranscript(t *testing.T) {
+	var buffer bytes.Buffer
+	transcript := New(&buffer)
+	start := time.Date(2025, 3, 10, 9, 30, 0, 0, time.Local)
+
+	transcript.Start(start, 4242, "/work/app")
+	for _, line := range []string{"⏺ Running the tests", "⏺ Running the tests", "", "   ", "╭─────╮"} {
+		transcript.Line(line)
+	}
+	transcript.Event(events.Event{Time: start.Add(time.Second), Type: events.DialogDetected, Prompt: 1, Kind: "permission"})
+	transcript.Line("╭─────╮")
+	transcript.Event(events.Event{Time: start.Add(time.Second), Type: events.Injection, Prompt: 1, Choice: "1"})
+	transcript.Event(events.Event{Time: start.Add(2 * time.Second), Type: events.Decision, Prompt: 1, Choice: "3", Label: "No", Action: "rejected", DecidedBy: "deny rule 2"})
+	transcript.End(start.Add(time.Minute))
+
+	expected := `>>> dcode 09:30:00: session 4242 started in /work/app
+⏺ Running the tests
+╭─────╮
+>>> dcode 09:30:01: prompt 1 detected (permission)
+╭─────╮
+>>> dcode 09:30:02: prompt 1: rejected by deny rule 2 with choice 3 (No)
+>>> dcode 09:31:00: session ended
+`
+	if buffer.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buffer.String())
+	}
+}
+
+func TestNilTranscript(t *testing.T) {
+	var transcript *Transcript
+	if transcript.Line("text") != nil || transcript.Event(events.Event{Type: events.DialogShown}) != nil || transcript.End(time.Now()) != nil {
+		t.Error("Expected a nil transcript to do nothing")
+	}
+}
+
+func TestPath(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 3, 10, 9, 30, 5, 0, time.Local)
+
+	if path := Path(dir, start, 42); path != filepath.Join(dir, "transcript-20250310-093005-42.txt") {
+		t.Errorf("Expected a file named after the session in a directory, got %s", path)
+	}
+	if path := Path(filepath.Join(dir, "runs")+"/", start, 42); path != filepath.Join(dir, "runs", "transcript-20250310-093005-42.txt") {
+		t.Errorf("Expected a path ending with a slash to be a directory, got %s", path)
+	}
+	if path := Path(filepath.Join(dir, "run.txt"), start, 42); path != filepath.Join(dir, "run.txt") {
+		t.Errorf("Expected a file path to be kept, got %s", path)
+	}
+
+	transcript, err := Open(filepath.Join(dir, "runs", "run.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript.Line("hello")
+	transcript.End(start)
+	if data, _ := os.ReadFile(filepath.Join(dir, "runs", "run.txt")); !strings.HasPrefix(string(data), "hello\n>>> dcode ") {
+		t.Errorf("Expected the transcript in the file, got %q", data)
+	}
+}
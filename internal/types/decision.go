@@ -0,0 +1,75 @@
+package types
+
+import (
+	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// ConfirmationWindowSeconds is how long after a dialog is answered that an
+// "Are you sure?" prompt, or a dialog asking about the same request again, is
+// treated as a follow-up to it
+const ConfirmationWindowSeconds = 10
+
+// Decision records how a dialog was answered, so a follow-up confirmation can
+// be handled as part of the same request
+type Decision struct {
+	Serial        int               // Serial of the answered prompt; only a confirmation before the next prompt follows it
+	Key           string            // LastLine of the answered prompt, shared by its confirmations
+	Request       string            // What the answered dialog asked to do, as an approvals.Key
+	TriggerReason string            // Reason shown for the answered prompt
+	Choice        string            // Choice number that was sent
+	Kind          parser.ChoiceKind // What the chosen answer does
+	At            time.Time         // When the answer was sent
+}
+
+// RecordDecision remembers the most recent answer to a dialog
+func (state *AppState) RecordDecision(decision Decision) {
+	state.decisionMutex.Lock()
+	defer state.decisionMutex.Unlock()
+	state.lastDecision = decision
+}
+
+// RecentDecision returns the most recent answer if it was sent within
+// ConfirmationWindowSeconds of now
+func (state *AppState) RecentDecision(now time.Time) (Decision, bool) {
+	state.decisionMutex.Lock()
+	defer state.decisionMutex.Unlock()
+	decision := state.lastDecision
+	if decision.At.IsZero() || now.Sub(decision.At) > ConfirmationWindowSeconds*time.Second {
+		return Decision{}, false
+	}
+	return decision, true
+}
+
+// PendingConfirmation returns the most recent answer if an "Are you sure?"
+// prompt appearing now confirms it: it was sent within
+// ConfirmationWindowSeconds of now, and no other prompt has started since
+func (state *AppState) PendingConfirmation(now time.Time) (Decision, bool) {
+	decision, ok := state.RecentDecision(now)
+	if !ok || decision.Serial != state.Prompt.Serial {
+		return Decision{}, false
+	}
+	return decision, true
+}
+
+// ShouldProcessConfirmation reports whether a confirmation following decision
+// should be processed. The dialog cooldown is skipped, since it is still
+// running right after the answer; instead confirmations are deduplicated on
+// the decision's key so a redrawn confirmation box is only handled once.
+func (state *AppState) ShouldProcessConfirmation(decision Decision) bool {
+	key := decision.Key + "|confirmation"
+	if !state.Deduplicator.ShouldProcessPrompt(key) {
+		return false
+	}
+	state.Deduplicator.MarkPromptProcessed(key)
+	return true
+}
+
+// StartConfirmationCollection starts collecting choices for a confirmation
+// that follows decision, keeping the key and reason of the original prompt
+func (state *AppState) StartConfirmationCollection(prompt string, decision Decision, context []string) {
+	state.StartPromptCollectionWithContext(prompt, decision.Key, context)
+	state.Prompt.TriggerReason = decision.TriggerReason
+	state.Prompt.DialogType = DialogTypeConfirmation
+}
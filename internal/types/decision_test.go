@@ -0,0 +1,88 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+func TestRecentDecision(t *testing.T) {
+	state := NewAppState()
+	answeredAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := state.RecentDecision(answeredAt); ok {
+		t.Fatal("Expected no decision before one is recorded")
+	}
+
+	state.RecordDecision(Decision{Key: "prompt", Choice: "1", Kind: parser.ChoiceApproveOnce, At: answeredAt})
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{"immediately", answeredAt, true},
+		{"within the window", answeredAt.Add(ConfirmationWindowSeconds * time.Second), true},
+		{"after the window", answeredAt.Add((ConfirmationWindowSeconds + 1) * time.Second), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, ok := state.RecentDecision(tc.now)
+			if ok != tc.expected {
+				t.Fatalf("Expected ok=%v, got %v", tc.expected, ok)
+			}
+			if ok && decision.Kind != parser.ChoiceApproveOnce {
+				t.Errorf("Expected the recorded decision, got %+v", decision)
+			}
+		})
+	}
+}
+
+func TestPendingConfirmation(t *testing.T) {
+	state := NewAppState()
+	answeredAt := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	state.StartPromptCollection("Do you want to proceed?")
+	state.RecordDecision(Decision{Serial: state.Prompt.Serial, Key: "prompt", At: answeredAt})
+
+	if _, ok := state.PendingConfirmation(answeredAt); !ok {
+		t.Error("Expected a confirmation right after the answer to follow it")
+	}
+	state.StartPromptCollection("Do you want to make this edit?")
+	if _, ok := state.PendingConfirmation(answeredAt); ok {
+		t.Error("Expected a confirmation after another prompt not to follow the answer")
+	}
+}
+
+func TestShouldProcessConfirmation(t *testing.T) {
+	state := NewAppState()
+	decision := Decision{Key: "prompt", At: time.Now()}
+
+	// The dialog cooldown set after answering doesn't block the confirmation
+	state.Deduplicator.SetDialogCooldown("main_dialog")
+
+	if !state.ShouldProcessConfirmation(decision) {
+		t.Error("Expected the first confirmation to be processed")
+	}
+	if state.ShouldProcessConfirmation(decision) {
+		t.Error("Expected a redrawn confirmation of the same decision to be skipped")
+	}
+	if !state.ShouldProcessConfirmation(Decision{Key: "other prompt", At: time.Now()}) {
+		t.Error("Expected a confirmation of another decision to be processed")
+	}
+}
+
+func TestStartConfirmationCollection(t *testing.T) {
+	state := NewAppState()
+	decision := Decision{Key: "prompt", TriggerReason: "Bash command execution"}
+
+	state.StartConfirmationCollection("Are you sure?", decision, nil)
+
+	if state.Prompt.DialogType != DialogTypeConfirmation {
+		t.Errorf("Expected confirmation dialog type, got %q", state.Prompt.DialogType)
+	}
+	if state.Prompt.LastLine != "prompt" || state.Prompt.TriggerReason != "Bash command execution" {
+		t.Errorf("Expected the original prompt's key and reason, got %q and %q", state.Prompt.LastLine, state.Prompt.TriggerReason)
+	}
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the locale whose pattern pack is always enabled, since
+// parts of Claude Code's UI are not translated
+const DefaultLocale = "en"
+
+// PatternPack holds the localized phrases used to detect Claude Code prompts.
+// Each entry is a regular expression fragment.
+type PatternPack struct {
+	Permit         []string `yaml:"permit"`
+	TrustPrompt    []string `yaml:"trust_prompt"`
+	PlanPrompt     []string `yaml:"plan_prompt"`
+	ConfirmPrompt  []string `yaml:"confirm_prompt"`
+	ContinuePrompt []string `yaml:"continue_prompt"`
+}
+
+// PatternPacks maps locale names to their pattern packs
+var PatternPacks = map[string]PatternPack{
+	"en": {
+		Permit:         []string{`Do you want to`, `Would you like to proceed`},
+		TrustPrompt:    []string{`Do you trust the files in this folder\?`},
+		PlanPrompt:     []string{`Would you like to proceed\?`},
+		ConfirmPrompt:  []string{`Are you sure`},
+		ContinuePrompt: []string{`(?i)press (enter|return|any key) to continue|press any key`},
+	},
+	"ja": {
+		Permit:         []string{`続行しますか`, `実行しますか`, `許可しますか`},
+		TrustPrompt:    []string{`このフォルダ(内)?のファイルを信頼しますか`},
+		ConfirmPrompt:  []string{`よろしいですか`, `本当に`},
+		ContinuePrompt: []string{`(?i)(enter|return)\s*キーを押して続行`, `何かキーを押して`},
+	},
+}
+
+// Locales returns the names of all available pattern packs in sorted order
+func Locales() []string {
+	var locales []string
+	for locale := range PatternPacks {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// NewRegexPatternsForLocales creates regex patterns that detect prompts in any
+// of the given locales in addition to English
+func NewRegexPatternsForLocales(locales ...string) (*RegexPatterns, error) {
+	return NewRegexPatternsWithPack(PatternPack{}, locales...)
+}
+
+// NewRegexPatternsWithPack creates regex patterns like NewRegexPatternsForLocales,
+// also detecting the user-supplied phrases in extra
+func NewRegexPatternsWithPack(extra PatternPack, locales ...string) (*RegexPatterns, error) {
+	for _, fragments := range [][]string{extra.Permit, extra.TrustPrompt, extra.PlanPrompt, extra.ConfirmPrompt, extra.ContinuePrompt} {
+		for _, fragment := range fragments {
+			// An empty fragment would match every line
+			if fragment == "" {
+				return nil, errors.New("empty pattern")
+			}
+			if _, err := regexp.Compile(fragment); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", fragment, err)
+			}
+		}
+	}
+
+	packs := []PatternPack{PatternPacks[DefaultLocale], extra}
+	for _, locale := range locales {
+		locale = strings.TrimSpace(locale)
+		if locale == "" || locale == DefaultLocale {
+			continue
+		}
+		pack, ok := PatternPacks[locale]
+		if !ok {
+			return nil, fmt.Errorf("unknown locale %q (available: %s)", locale, strings.Join(Locales(), ", "))
+		}
+		packs = append(packs, pack)
+	}
+
+	var permit, trust, plan, confirm, continuePrompt []string
+	for _, pack := range packs {
+		permit = append(permit, pack.Permit...)
+		trust = append(trust, pack.TrustPrompt...)
+		plan = append(plan, pack.PlanPrompt...)
+		confirm = append(confirm, pack.ConfirmPrompt...)
+		continuePrompt = append(continuePrompt, pack.ContinuePrompt...)
+	}
+
+	patterns := NewRegexPatterns()
+	patterns.Permit = compileAlternatives(permit)
+	patterns.TrustPrompt = compileAlternatives(trust)
+	patterns.PlanPrompt = compileAlternatives(plan)
+	patterns.ConfirmPrompt = compileAlternatives(confirm)
+	patterns.ContinuePrompt = compileAlternatives(continuePrompt)
+	return patterns, nil
+}
+
+// compileAlternatives compiles fragments into one regex matching any of them.
+// Each fragment is grouped so its flags don't leak into the others.
+func compileAlternatives(fragments []string) *regexp.Regexp {
+	grouped := make([]string, len(fragments))
+	for i, fragment := range fragments {
+		grouped[i] = "(?:" + fragment + ")"
+	}
+	return regexp.MustCompile(strings.Join(grouped, "|"))
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/takahirom/dialog-code/pkg/parser"
+)
+
+// TriggerClassifier determines what triggered a dialog from its prompt line
+// and the terminal lines leading up to it
+type TriggerClassifier interface {
+	Classify(prompt string, context []string) string
+}
+
+// DefaultTriggerClassifier recognizes Claude Code's built-in dialogs and tools
+type DefaultTriggerClassifier struct{}
+
+// Classify returns the reason shown in the dialog, e.g. "Bash command execution"
+func (DefaultTriggerClassifier) Classify(prompt string, context []string) string {
+	// Combine prompt and context for analysis
+	fullContext := prompt
+	for _, line := range context {
+		fullContext += " " + line
+	}
+
+	// The folder trust prompt is identified by its own question
+	if strings.Contains(prompt, "Do you trust the files in this folder") {
+		return "Folder trust confirmation"
+	}
+
+	// Plan mode asks before leaving the plan and starting to code
+	if strings.Contains(prompt, "Would you like to proceed") {
+		return "Plan approval"
+	}
+
+	// Check for specific function call patterns first
+	if strings.Contains(fullContext, "Write(") {
+		return "Write() function call"
+	}
+	if strings.Contains(fullContext, "Bash(") || (strings.Contains(fullContext, "⏺") && strings.Contains(fullContext, "Bash")) {
+		// Extract command from Bash() call if present
+		for _, line := range context {
+			if strings.Contains(line, "⏺") && strings.Contains(line, "Bash(") {
+				return "Bash command execution"
+			}
+		}
+		return "Bash() function call"
+	}
+	// Check for operation permission patterns with record symbol
+	if strings.Contains(fullContext, "⏺") && strings.Contains(fullContext, "Write") {
+		return "Write operation permission"
+	}
+	// Name the tool when the dialog header identifies one
+	if toolType := parser.LastDialog(context).ToolType; toolType != "" {
+		if strings.HasPrefix(toolType, "mcp__") {
+			return "MCP tool " + toolType
+		}
+		return toolType + " tool permission"
+	}
+	// Check for general permission patterns
+	if strings.Contains(fullContext, "requires permission") {
+		return "General permission requirement"
+	}
+	if strings.Contains(fullContext, "needs your approval") {
+		return "Approval request"
+	}
+	if strings.Contains(fullContext, "Permissions:") {
+		return "Permission list dialog"
+	}
+	if strings.Contains(fullContext, "Do you want to proceed") {
+		return "Proceed confirmation"
+	}
+	return "Unknown trigger"
+}
+
+// TriggerRule maps prompts or context matching Pattern to Reason
+type TriggerRule struct {
+	Pattern *regexp.Regexp
+	Reason  string
+}
+
+// RuleTriggerClassifier tries its rules in order against the prompt and
+// context, deferring to Fallback (the default classifier if nil) when none match
+type RuleTriggerClassifier struct {
+	Rules    []TriggerRule
+	Fallback TriggerClassifier
+}
+
+// Classify returns the reason of the first matching rule
+func (c RuleTriggerClassifier) Classify(prompt string, context []string) string {
+	fullContext := prompt + "\n" + strings.Join(context, "\n")
+	for _, rule := range c.Rules {
+		if rule.Pattern.MatchString(fullContext) {
+			return rule.Reason
+		}
+	}
+	if c.Fallback == nil {
+		return DefaultTriggerClassifier{}.Classify(prompt, context)
+	}
+	return c.Fallback.Classify(prompt, context)
+}
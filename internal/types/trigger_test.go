@@ -0,0 +1,73 @@
+package types
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestDefaultTriggerClassifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prompt   string
+		context  []string
+		expected string
+	}{
+		{"folder trust", "Do you trust the files in this folder?", nil, "Folder trust confirmation"},
+		{"plan approval", "Would you like to proceed?", nil, "Plan approval"},
+		{"bash call", "Do you want to proceed?", []string{"⏺ Bash(ls)"}, "Bash command execution"},
+		{"write call", "Do you want to proceed?", []string{"⏺ Write(main.go)"}, "Write() function call"},
+		{
+			"tool from dialog header",
+			"Do you want to proceed?",
+			[]string{"⏺ Fetch(https://example.com)", "╭──────────╮", "│ Fetch    │", "│ Do you want to proceed? │"},
+			"WebFetch tool permission",
+		},
+		{
+			"MCP tool",
+			"Do you want to proceed?",
+			[]string{"╭──────────╮", "│ Tool use │", "│   github - create_issue(title: \"x\") (MCP) │"},
+			"MCP tool mcp__github__create_issue",
+		},
+		{"plain proceed", "Do you want to proceed?", nil, "Proceed confirmation"},
+		{"unknown", "something", nil, "Unknown trigger"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := (DefaultTriggerClassifier{}).Classify(tc.prompt, tc.context); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRuleTriggerClassifier(t *testing.T) {
+	classifier := RuleTriggerClassifier{
+		Rules: []TriggerRule{
+			{Pattern: regexp.MustCompile(`Bash\(terraform`), Reason: "Infrastructure change"},
+		},
+	}
+
+	if result := classifier.Classify("Do you want to proceed?", []string{"⏺ Bash(terraform apply)"}); result != "Infrastructure change" {
+		t.Errorf("Expected the matching rule's reason, got %q", result)
+	}
+	if result := classifier.Classify("Do you want to proceed?", []string{"⏺ Bash(ls)"}); result != "Bash command execution" {
+		t.Errorf("Expected the default classifier's reason, got %q", result)
+	}
+}
+
+type fixedClassifier string
+
+func (f fixedClassifier) Classify(prompt string, context []string) string {
+	return string(f)
+}
+
+func TestAppStateUsesTriggerClassifier(t *testing.T) {
+	state := NewAppState()
+	state.TriggerClassifier = fixedClassifier("Custom reason")
+
+	state.StartPromptCollectionWithContext("Do you want to proceed?", "id", []string{"⏺ Bash(ls)"})
+	if state.Prompt.TriggerReason != "Custom reason" {
+		t.Errorf("Expected the custom classifier's reason, got %q", state.Prompt.TriggerReason)
+	}
+}
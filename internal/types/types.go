@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/takahirom/dialog-code/internal/deduplication"
+	"github.com/takahirom/dialog-code/pkg/parser"
 )
 
 const (
@@ -17,6 +18,20 @@ const (
 	DefaultContextLines        = 10
 )
 
+// DialogCooldownKey is the cooldown that holds off new prompts while a dialog
+// was just shown
+const DialogCooldownKey = "main_dialog"
+
+// DialogType identifies the kind of dialog being collected
+type DialogType string
+
+const (
+	DialogTypePermission   DialogType = "permission"    // Tool permission dialog
+	DialogTypeFolderTrust  DialogType = "folder_trust"  // "Do you trust the files in this folder?" startup dialog
+	DialogTypePlanApproval DialogType = "plan_approval" // Plan mode "Would you like to proceed?" dialog
+	DialogTypeConfirmation DialogType = "confirmation"  // "Are you sure?" follow-up to a dialog just answered
+)
+
 // DialogState holds the state for permission dialogs
 type DialogState struct {
 	Mutex     sync.Mutex
@@ -28,9 +43,11 @@ type DialogState struct {
 
 // PromptState holds the state for prompt processing
 type PromptState struct {
+	Serial           int // Numbers each prompt collected, so its answer can be sent only once
 	LastLine         string
 	Started          bool
 	CollectedChoices map[string]string
+	DefaultChoice    string // Choice number under the ❯ cursor
 	Processed        map[string]time.Time
 	JustShown        bool
 	Cooldown         time.Time
@@ -38,20 +55,28 @@ type PromptState struct {
 	ContextLines     int      // Number of context lines to collect
 	TriggerReason    string   // What triggered this dialog (e.g., "Write()", "Bash()", etc.)
 	TriggerLine      string   // The exact line that triggered the dialog
+	DialogType       DialogType
+	Info             parser.DialogInfo // Parsed dialog box, set once the box is complete
+	DetectedAt       time.Time         // When the prompt appeared, for the audit log
+	RequestID        string            // Identifies the prompt in its dialog, the logs, the audit log, and events
 }
 
 // AppState holds the global application state
 type AppState struct {
-	Dialog           *DialogState
-	Prompt           *PromptState
-	WaitingForChoice bool
-	ChoiceResponse   string
-	OutputTimer      *time.Timer
-	OutputMutex      sync.Mutex
-	Ptmx             *os.File
-	AutoApprove      bool
-	StripColors      bool
-	Deduplicator     *deduplication.DeduplicationManager
+	Dialog            *DialogState
+	Prompt            *PromptState
+	WaitingForChoice  bool
+	ChoiceResponse    string
+	OutputTimer       *time.Timer
+	OutputMutex       sync.Mutex
+	Ptmx              *os.File
+	AutoApprove       bool
+	StripColors       bool
+	Deduplicator      *deduplication.DeduplicationManager
+	TriggerClassifier TriggerClassifier // Produces the "Reason:" shown in dialogs
+
+	decisionMutex sync.Mutex
+	lastDecision  Decision
 }
 
 // NewAppState creates a new application state
@@ -71,8 +96,10 @@ func NewAppState() *AppState {
 			Processed:        make(map[string]time.Time),
 			Context:          make([]string, 0),
 			ContextLines:     DefaultContextLines,
+			DialogType:       DialogTypePermission,
 		},
-		Deduplicator: deduplication.NewDeduplicationManager(config),
+		Deduplicator:      deduplication.NewDeduplicationManager(config),
+		TriggerClassifier: DefaultTriggerClassifier{},
 	}
 }
 
@@ -84,18 +111,27 @@ type RegexPatterns struct {
 	ChoiceNo            *regexp.Regexp
 	ChoiceAny           *regexp.Regexp
 	AnsiEscape          *regexp.Regexp
+	ContinuePrompt      *regexp.Regexp
+	TrustPrompt         *regexp.Regexp
+	PlanPrompt          *regexp.Regexp
+	ConfirmPrompt       *regexp.Regexp
 }
 
 // NewRegexPatterns creates a new instance of regex patterns
+// with the English prompt phrases; see NewRegexPatternsForLocales for others
 func NewRegexPatterns() *RegexPatterns {
+	english := PatternPacks[DefaultLocale]
 	return &RegexPatterns{
-		Permit: regexp.MustCompile(
-			`Do you want to`),
+		Permit:              compileAlternatives(english.Permit),
 		ChoiceYes:           regexp.MustCompile(`.*?([0-9]+)\.\s+(.*(Allow|Yes|Approve).*)`),
 		ChoiceYesAndDontAsk: regexp.MustCompile(`.*?([0-9]+)\.\s+(.*(Allow|Yes).*don't ask.*)`),
 		ChoiceNo:            regexp.MustCompile(`.*?([0-9]+)\.\s+(.*(Deny|No|Cancel).*)`),
 		ChoiceAny:           regexp.MustCompile(`[│\s]*[❯\s]*([0-9]+)\.\s+(.+?)(?:\s*│)?$`),
-		AnsiEscape:          regexp.MustCompile(`\x1b\[[0-9;?]*[mKHJhlABCDEFGPST]`),
+		AnsiEscape:          regexp.MustCompile(parser.AnsiEscapePattern),
+		ContinuePrompt:      compileAlternatives(english.ContinuePrompt),
+		TrustPrompt:         compileAlternatives(english.TrustPrompt),
+		PlanPrompt:          compileAlternatives(english.PlanPrompt),
+		ConfirmPrompt:       compileAlternatives(english.ConfirmPrompt),
 	}
 }
 
@@ -106,7 +142,7 @@ func (r *RegexPatterns) StripAnsi(s string) string {
 
 // ShouldProcessPrompt determines if a prompt should be processed based on cooldown and duplicate detection
 func (state *AppState) ShouldProcessPrompt(prompt string, regexPatterns *RegexPatterns) bool {
-	cooldownKey := "main_dialog"
+	cooldownKey := DialogCooldownKey
 
 	// Check cooldown first (short-term dialog spacing)
 	cooldownStates := state.Deduplicator.GetCooldownStates()
@@ -145,63 +181,37 @@ func (state *AppState) AddContextLine(line string, regexPatterns *RegexPatterns)
 
 // StartPromptCollection starts collecting choices for a new prompt
 func (state *AppState) StartPromptCollection(prompt string) {
+	state.Prompt.Serial++
 	state.Prompt.LastLine = prompt
 	state.Prompt.Started = true
 	state.Prompt.CollectedChoices = make(map[string]string) // Reset choices
+	state.Prompt.DefaultChoice = ""
 	state.Prompt.TriggerLine = prompt
 	state.Prompt.TriggerReason = state.identifyTriggerReason(prompt, state.Prompt.Context)
+	state.Prompt.DialogType = DialogTypePermission
+	state.Prompt.Info = parser.DialogInfo{}
 }
 
 // StartPromptCollectionWithContext starts collecting choices with context identifier
 func (state *AppState) StartPromptCollectionWithContext(prompt string, contextIdentifier string, context []string) {
+	state.Prompt.Serial++
 	state.Prompt.LastLine = contextIdentifier // Use context identifier instead of just prompt
 	state.Prompt.Started = true
 	state.Prompt.CollectedChoices = make(map[string]string) // Reset choices
+	state.Prompt.DefaultChoice = ""
 	state.Prompt.Context = context // Set the context
 	state.Prompt.TriggerLine = prompt
 	state.Prompt.TriggerReason = state.identifyTriggerReason(prompt, context)
+	state.Prompt.DialogType = DialogTypePermission
+	state.Prompt.Info = parser.DialogInfo{}
 }
 
-// identifyTriggerReason determines what triggered the dialog based on the prompt line and context
+// identifyTriggerReason determines what triggered the dialog using the state's classifier
 func (state *AppState) identifyTriggerReason(prompt string, context []string) string {
-	// Combine prompt and context for analysis
-	fullContext := prompt
-	for _, line := range context {
-		fullContext += " " + line
-	}
-
-
-	// Check for specific function call patterns first
-	if strings.Contains(fullContext, "Write(") {
-		return "Write() function call"
+	if state.TriggerClassifier == nil {
+		return DefaultTriggerClassifier{}.Classify(prompt, context)
 	}
-	if strings.Contains(fullContext, "Bash(") || (strings.Contains(fullContext, "⏺") && strings.Contains(fullContext, "Bash")) {
-		// Extract command from Bash() call if present
-		for _, line := range context {
-			if strings.Contains(line, "⏺") && strings.Contains(line, "Bash(") {
-				return "Bash command execution"
-			}
-		}
-		return "Bash() function call"
-	}
-	// Check for operation permission patterns with record symbol
-	if strings.Contains(fullContext, "⏺") && strings.Contains(fullContext, "Write") {
-		return "Write operation permission"
-	}
-	// Check for general permission patterns
-	if strings.Contains(fullContext, "requires permission") {
-		return "General permission requirement"
-	}
-	if strings.Contains(fullContext, "needs your approval") {
-		return "Approval request"
-	}
-	if strings.Contains(fullContext, "Permissions:") {
-		return "Permission list dialog"
-	}
-	if strings.Contains(fullContext, "Do you want to proceed") {
-		return "Proceed confirmation"
-	}
-	return "Unknown trigger"
+	return state.TriggerClassifier.Classify(prompt, context)
 }
 
 // AddChoice adds a choice to the current prompt collection
@@ -223,6 +233,10 @@ func (state *AppState) AddChoice(choiceLine string, regexPatterns *RegexPatterns
 		// Reconstruct the choice line with cleaned text
 		cleanedChoice := num + ". " + choiceText
 		state.Prompt.CollectedChoices[num] = cleanedChoice
+		// The ❯ cursor marks the choice Claude selects on plain Enter
+		if loc := regexPatterns.ChoiceAny.FindStringSubmatchIndex(cleanLine); strings.Contains(cleanLine[:loc[2]], "❯") {
+			state.Prompt.DefaultChoice = num
+		}
 	}
 }
 
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzAddChoice(f *testing.F) {
+	f.Add("│ ❯ 1. Yes                    │")
+	f.Add("│   2. No, and tell Claude what to do differently (esc) │")
+	f.Add("\x1b[1m3.\x1b[0m \xff")
+	f.Add("99999999999999999999. x")
+
+	patterns := NewRegexPatterns()
+	f.Fuzz(func(t *testing.T, line string) {
+		state := NewAppState()
+		state.StartPromptCollection("Do you want to proceed?")
+		state.AddChoice(line, patterns)
+
+		for num, choice := range state.Prompt.CollectedChoices {
+			if !strings.HasPrefix(choice, num+". ") {
+				t.Errorf("Choice %q doesn't start with its number %q", choice, num)
+			}
+		}
+	})
+}
@@ -65,6 +65,9 @@ func TestStripAnsi(t *testing.T) {
 		{"\x1b[1;32mBold Green\x1b[0m", "Bold Green"},
 		{"No ANSI codes", "No ANSI codes"},
 		{"\x1b[2K\x1b[1GParsing...", "Parsing..."},
+		{"\x1b]0;✳ Claude Code\x07Window title", "Window title"},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\ text", "link text"},
+		{"\x1bPq#0;2;0;0;0\x1b\\Sixel", "Sixel"},
 	}
 
 	for _, tc := range testCases {
@@ -170,3 +173,99 @@ func TestChoiceDialogInterface(t *testing.T) {
 		t.Errorf("Expected 'Test message', got %q", mock.LastMsg)
 	}
 }
+
+func TestNewRegexPatternsForLocales(t *testing.T) {
+	t.Run("Japanese prompts are detected alongside English", func(t *testing.T) {
+		patterns, err := NewRegexPatternsForLocales("ja")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		testCases := []struct {
+			input    string
+			expected bool
+		}{
+			{"続行しますか？", true},
+			{"このフォルダ内のファイルを信頼しますか？", false},
+			{"Do you want to proceed?", true},
+			{"通常のテキスト", false},
+		}
+
+		for _, tc := range testCases {
+			if result := patterns.Permit.MatchString(tc.input); result != tc.expected {
+				t.Errorf("Permit pattern for %q: expected %v, got %v", tc.input, tc.expected, result)
+			}
+		}
+
+		if !patterns.TrustPrompt.MatchString("このフォルダ内のファイルを信頼しますか？") {
+			t.Error("Expected Japanese trust prompt to match")
+		}
+		if !patterns.ContinuePrompt.MatchString("Enterキーを押して続行") {
+			t.Error("Expected Japanese continue prompt to match")
+		}
+		if !patterns.ConfirmPrompt.MatchString("本当に削除してもよろしいですか？") {
+			t.Error("Expected Japanese confirmation prompt to match")
+		}
+	})
+
+	t.Run("English only by default", func(t *testing.T) {
+		if NewRegexPatterns().Permit.MatchString("続行しますか？") {
+			t.Error("Expected Japanese prompt not to match the default patterns")
+		}
+	})
+
+	t.Run("Flags stay scoped to their fragment", func(t *testing.T) {
+		patterns, err := NewRegexPatternsForLocales("ja")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !patterns.ContinuePrompt.MatchString("PRESS ENTER TO CONTINUE") {
+			t.Error("Expected case-insensitive English continue prompt to match")
+		}
+	})
+
+	t.Run("Unknown locale", func(t *testing.T) {
+		if _, err := NewRegexPatternsForLocales("xx"); err == nil {
+			t.Error("Expected an error for an unknown locale")
+		}
+	})
+}
+
+func TestAddChoiceDefaultChoice(t *testing.T) {
+	state := NewAppState()
+	patterns := NewRegexPatterns()
+	state.StartPromptCollection("Do you want to proceed?")
+
+	state.AddChoice("│   1. Yes                      │", patterns)
+	state.AddChoice("│ ❯ 2. No, and tell Claude what to do differently │", patterns)
+	state.AddChoice("│   3. Option mentioning 2. again │", patterns)
+
+	if state.Prompt.DefaultChoice != "2" {
+		t.Errorf("Expected default choice 2, got %q", state.Prompt.DefaultChoice)
+	}
+
+	state.StartPromptCollection("Do you want to proceed?")
+	if state.Prompt.DefaultChoice != "" {
+		t.Errorf("Expected default choice to be reset, got %q", state.Prompt.DefaultChoice)
+	}
+}
+
+func TestAddChoiceDoubleDigit(t *testing.T) {
+	state := NewAppState()
+	patterns := NewRegexPatterns()
+	state.StartPromptCollection("Do you want to proceed?")
+
+	state.AddChoice("│   9. Option nine                │", patterns)
+	state.AddChoice("│ ❯ 10. Option ten                │", patterns)
+	state.AddChoice("│   11. Option eleven             │", patterns)
+
+	if state.Prompt.CollectedChoices["10"] != "10. Option ten" {
+		t.Errorf("Expected choice 10 to be collected, got %q", state.Prompt.CollectedChoices)
+	}
+	if state.Prompt.CollectedChoices["11"] != "11. Option eleven" {
+		t.Errorf("Expected choice 11 to be collected, got %q", state.Prompt.CollectedChoices)
+	}
+	if state.Prompt.DefaultChoice != "10" {
+		t.Errorf("Expected default choice 10, got %q", state.Prompt.DefaultChoice)
+	}
+}
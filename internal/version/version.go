@@ -0,0 +1,109 @@
+// Package version identifies the running dcode build, so bug reports, debug
+// logs, and dialogs can name it. Release builds set Version, Commit, and Date
+// with the linker:
+//
+//	go build -ldflags "-X github.com/takahirom/dialog-code/internal/version.Version=1.2.0 -X github.com/takahirom/dialog-code/internal/version.Commit=$(git rev-parse HEAD) -X github.com/takahirom/dialog-code/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/dcode
+//
+// Otherwise they are read from what the Go toolchain recorded in the binary:
+// the module version with go install, and the commit when built in a git
+// checkout.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Dev is the version of a build that isn't a release
+const Dev = "dev"
+
+// Set with -ldflags "-X ..."; "" for what the toolchain recorded
+var (
+	Version = "" // Release version, e.g. "1.2.0"
+	Commit  = "" // Commit built
+	Date    = "" // When it was built, in RFC 3339
+)
+
+// Info describes a build
+type Info struct {
+	Version  string // Release version without a "v", or Dev
+	Commit   string // Commit built, or "" if unknown
+	Date     string // When it was built, or the commit's time, or "" if unknown
+	Modified bool   // Built from a checkout with uncommitted changes
+}
+
+// Get returns the running build's Info
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info = fill(info, build)
+	}
+	info.Version = strings.TrimPrefix(info.Version, "v")
+	if info.Version == "" {
+		info.Version = Dev
+	}
+	return info
+}
+
+// fill completes what the linker didn't set in info from build
+func fill(info Info, build *debug.BuildInfo) Info {
+	if info.Version == "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// Short returns the version with a short commit, e.g. "1.2.0" for a release
+// or "dev+9fceb02d0ae5" for another build
+func (i Info) Short() string {
+	if i.Version != Dev || i.Commit == "" {
+		return i.Version
+	}
+	short := i.Version + "+" + shortCommit(i.Commit)
+	if i.Modified {
+		short += "-dirty"
+	}
+	return short
+}
+
+// String returns everything known about the build on one line, e.g.
+// "dcode 1.2.0 (commit 9fceb02d0ae5, built 2025-01-01T09:30:12Z, go1.24.1 darwin/arm64)"
+func (i Info) String() string {
+	var details []string
+	if i.Commit != "" {
+		commit := "commit " + shortCommit(i.Commit)
+		if i.Modified {
+			commit += " with uncommitted changes"
+		}
+		details = append(details, commit)
+	}
+	if i.Date != "" {
+		details = append(details, "built "+i.Date)
+	}
+	details = append(details, fmt.Sprintf("%s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH))
+	return fmt.Sprintf("dcode %s (%s)", i.Version, strings.Join(details, ", "))
+}
+
+// shortCommit returns the first 12 characters of commit
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,60 @@
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestFill(t *testing.T) {
+	build := &debug.BuildInfo{
+		Main: debug.Module{Path: "github.com/takahirom/dialog-code", Version: "v1.2.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "9fceb02d0ae598e95dc970b74767f19372d61af8"},
+			{Key: "vcs.time", Value: "2025-01-01T09:30:12Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	info := fill(Info{}, build)
+	expected := Info{Version: "v1.2.0", Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8", Date: "2025-01-01T09:30:12Z", Modified: true}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+
+	info = fill(Info{Version: "1.3.0", Commit: "abc123", Date: "2025-02-01T00:00:00Z"}, build)
+	if info.Version != "1.3.0" || info.Commit != "abc123" || info.Date != "2025-02-01T00:00:00Z" {
+		t.Errorf("Expected what the linker set to be kept, got %+v", info)
+	}
+
+	build.Main.Version = "(devel)"
+	if info := fill(Info{}, build); info.Version != "" {
+		t.Errorf("Expected no version for a development build, got %q", info.Version)
+	}
+}
+
+func TestShort(t *testing.T) {
+	for _, test := range []struct {
+		info     Info
+		expected string
+	}{
+		{Info{Version: "1.2.0", Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8"}, "1.2.0"},
+		{Info{Version: Dev, Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8"}, "dev+9fceb02d0ae5"},
+		{Info{Version: Dev, Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8", Modified: true}, "dev+9fceb02d0ae5-dirty"},
+		{Info{Version: Dev}, "dev"},
+	} {
+		if short := test.info.Short(); short != test.expected {
+			t.Errorf("Short() of %+v = %q, expected %q", test.info, short, test.expected)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	text := Info{Version: "1.2.0", Commit: "9fceb02d0ae598e95dc970b74767f19372d61af8", Date: "2025-01-01T09:30:12Z"}.String()
+	if !strings.HasPrefix(text, "dcode 1.2.0 (commit 9fceb02d0ae5, built 2025-01-01T09:30:12Z, go") {
+		t.Errorf("Unexpected version: %q", text)
+	}
+	if text := (Info{Version: Dev}).String(); !strings.HasPrefix(text, "dcode dev (go") {
+		t.Errorf("Unexpected version without a commit: %q", text)
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	asciiHorizontalBorder = regexp.MustCompile(`^(\s*)\+(-{3,})\+(\s*)$`)
+	asciiSideBorders      = regexp.MustCompile(`^(\s*)\|(.*)\|(\s*)$`)
+)
+
+// BorderNormalizer converts ASCII-art box borders (+, -, |) to the Unicode box
+// characters (╭, ─, │, ╰) Claude Code normally draws. ASCII top and bottom
+// borders look identical, so the normalizer tracks whether a box is open.
+type BorderNormalizer struct {
+	inBox bool
+}
+
+// Normalize returns line with ASCII box borders replaced by Unicode ones.
+// Lines that aren't part of an ASCII box are returned unchanged.
+func (n *BorderNormalizer) Normalize(line string) string {
+	stripped := StripAnsi(line)
+
+	if matches := asciiHorizontalBorder.FindStringSubmatch(stripped); matches != nil {
+		dashes := strings.Repeat("─", len(matches[2]))
+		if n.inBox {
+			n.inBox = false
+			return matches[1] + "╰" + dashes + "╯" + matches[3]
+		}
+		n.inBox = true
+		return matches[1] + "╭" + dashes + "╮" + matches[3]
+	}
+
+	if n.inBox {
+		if matches := asciiSideBorders.FindStringSubmatch(stripped); matches != nil {
+			return matches[1] + "│" + matches[2] + "│" + matches[3]
+		}
+	}
+
+	return line
+}
+
+// NormalizeBorders converts ASCII-art box borders in lines to Unicode ones
+func NormalizeBorders(lines []string) []string {
+	normalizer := &BorderNormalizer{}
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = normalizer.Normalize(line)
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeBorders(t *testing.T) {
+	lines := []string{
+		"| not in a box |",
+		"+----------------+",
+		"| Bash command   |",
+		"|   ls -la       |",
+		"+----------------+",
+		"| after the box  |",
+	}
+
+	expected := []string{
+		"| not in a box |",
+		"╭────────────────╮",
+		"│ Bash command   │",
+		"│   ls -la       │",
+		"╰────────────────╯",
+		"| after the box  |",
+	}
+
+	if result := NormalizeBorders(lines); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, result)
+	}
+}
+
+func TestNormalizeBorders_LeavesUnicodeAndTablesAlone(t *testing.T) {
+	lines := []string{
+		"╭──────╮",
+		"│ Task │",
+		"╰──────╯",
+		"+-----+-----+",
+		"| a   | b   |",
+	}
+
+	if result := NormalizeBorders(lines); !reflect.DeepEqual(result, lines) {
+		t.Errorf("Expected lines to be unchanged, got:\n%q", result)
+	}
+}
+
+func TestParseDialog_AsciiBox(t *testing.T) {
+	lines := []string{
+		"+------------------------------+",
+		"| Bash command                 |",
+		"|                              |",
+		"|   rm test-file               |",
+		"|                              |",
+		"| Do you want to proceed?      |",
+		"| ❯ 1. Yes                     |",
+		"|   2. No                      |",
+		"+------------------------------+",
+	}
+
+	info := ParseDialog(lines)
+
+	if info.ToolType != "Bash" {
+		t.Errorf("ToolType: expected %q, got %q", "Bash", info.ToolType)
+	}
+	if !reflect.DeepEqual(info.CommandLines, []string{"rm test-file"}) {
+		t.Errorf("CommandLines: expected %q, got %q", []string{"rm test-file"}, info.CommandLines)
+	}
+	if !reflect.DeepEqual(info.Choices, map[string]string{"1": "Yes", "2": "No"}) {
+		t.Errorf("Choices: unexpected %q", info.Choices)
+	}
+}
@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BoxKind says what a box drawn by Claude Code is for
+type BoxKind int
+
+const (
+	// BoxInformational shows output or a message and expects no answer
+	BoxInformational BoxKind = iota
+	// BoxPermission asks a question answered by picking a numbered choice
+	BoxPermission
+	// BoxInput is the prompt the user types into
+	BoxInput
+)
+
+// String returns the lowercase name of the box kind
+func (k BoxKind) String() string {
+	switch k {
+	case BoxPermission:
+		return "permission"
+	case BoxInput:
+		return "input"
+	}
+	return "informational"
+}
+
+// ClassifyBox classifies the first box in lines from its structure. lines may
+// end partway through a box that is still being drawn.
+//
+// A box with a question and numbered choices is a permission dialog, even if
+// it also holds a text field for feedback. Otherwise a "> " prompt line or a braille cursor
+// cell marks an input box, so text typed or pasted into it is never mistaken
+// for a question. Any other box with a question is a permission dialog whose
+// choices haven't been drawn yet.
+func ClassifyBox(lines []string) BoxKind {
+	hasChoices, hasPrompt, hasQuestion := false, false, false
+	for _, line := range boxLines(NormalizeBorders(lines)) {
+		if isPromptLine(line) || hasBrailleCursor(line) {
+			hasPrompt = true
+		}
+		cleanLine := CleanLine(line)
+		if choiceLine.MatchString(cleanLine) {
+			hasChoices = true
+		} else if isQuestion(cleanLine) {
+			hasQuestion = true
+		}
+	}
+
+	switch {
+	case hasQuestion && hasChoices:
+		return BoxPermission
+	case hasPrompt:
+		return BoxInput
+	case hasQuestion:
+		return BoxPermission
+	}
+	return BoxInformational
+}
+
+// isPromptLine reports whether a box line starts with the ">" prompt of an
+// input box, right after the border's one-column margin. Claude Code may draw
+// the margin as a non-breaking space. Indented ">" lines, such as quoted
+// notes under a command, are not prompts.
+func isPromptLine(line string) bool {
+	content := StripAnsi(line)
+	idx := strings.Index(content, "│")
+	if idx < 0 {
+		return false
+	}
+	content = content[idx+len("│"):]
+	if r, size := utf8.DecodeRuneInString(content); unicode.IsSpace(r) {
+		content = content[size:]
+	}
+	return strings.HasPrefix(content, ">")
+}
+
+// hasBrailleCursor reports whether line contains a braille pattern cell, which
+// input boxes draw as a placeholder cursor
+func hasBrailleCursor(line string) bool {
+	return strings.ContainsFunc(line, func(r rune) bool {
+		return r >= '⠀' && r <= '⣿'
+	})
+}
@@ -0,0 +1,193 @@
+package parser
+
+import "testing"
+
+func TestClassifyBox(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lines    []string
+		expected BoxKind
+	}{
+		{
+			name: "permission dialog with choices",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   ls -la                     │",
+				"│ Do you want to proceed?      │",
+				"│ ❯ 1. Yes                     │",
+				"│   2. No                      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "permission dialog still being drawn",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   ls -la                     │",
+				"│ Do you want to proceed?      │",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "permission dialog with quoted notes under the command",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   rm -rf build               │",
+				"│   > Use with caution         │",
+				"│ Do you want to proceed?      │",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "permission dialog with a feedback field",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Do you want to proceed?      │",
+				"│   1. Yes                     │",
+				"│ ❯ 2. No, and tell Claude     │",
+				"│ > use make instead           │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "folder trust dialog",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Do you trust the files in this folder? │",
+				"│ ❯ 1. Yes, proceed            │",
+				"│   2. No, exit                │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "Japanese permission dialog",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ 続行しますか？               │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "ASCII permission dialog",
+			lines: []string{
+				"+------------------------------+",
+				"| Do you want to proceed?      |",
+				"| 1. Yes                       |",
+				"+------------------------------+",
+			},
+			expected: BoxPermission,
+		},
+		{
+			name: "empty input box",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ >                            │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInput,
+		},
+		{
+			name: "input box with a typed question",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ > Do you want to proceed?    │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInput,
+		},
+		{
+			name: "input box with non-breaking spaces",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│\u00a0>\u00a0Do you want to edit      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInput,
+		},
+		{
+			name: "input box with a question on a continuation line",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ > Rejected command:          │",
+				"│ Do you want to make this edit? │",
+			},
+			expected: BoxInput,
+		},
+		{
+			name: "input box with colored prompt",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ \x1b[2m>\x1b[0m Do you want to proceed? │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInput,
+		},
+		{
+			name: "input box with braille cursor",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ ⠀ Would you like to proceed? │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInput,
+		},
+		{
+			name: "tool output box",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ ✻ Welcome to Claude Code!    │",
+				"│   cwd: /tmp/project          │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInformational,
+		},
+		{
+			name: "numbered list without a question",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Plan                         │",
+				"│ 1. Add tests                 │",
+				"╰──────────────────────────────╯",
+			},
+			expected: BoxInformational,
+		},
+		{
+			name:     "no box",
+			lines:    []string{"Do you want to proceed?"},
+			expected: BoxInformational,
+		},
+		{
+			name:     "empty",
+			lines:    nil,
+			expected: BoxInformational,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := ClassifyBox(tc.lines); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestBoxKindString(t *testing.T) {
+	testCases := map[BoxKind]string{
+		BoxInformational: "informational",
+		BoxPermission:    "permission",
+		BoxInput:         "input",
+	}
+	for kind, expected := range testCases {
+		if result := kind.String(); result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package parser
+
+import "strings"
+
+// ChoiceKind is the semantic meaning of a dialog choice
+type ChoiceKind string
+
+const (
+	ChoiceUnknown       ChoiceKind = "unknown"
+	ChoiceApproveOnce   ChoiceKind = "approve_once"   // Approves this request only
+	ChoiceApproveAlways ChoiceKind = "approve_always" // Approves this and future requests ("don't ask again")
+	ChoiceReject        ChoiceKind = "reject"
+)
+
+var (
+	approvePrefixes = []string{"yes", "allow", "approve", "proceed", "ok", "はい", "許可"}
+	rejectPrefixes  = []string{"no", "deny", "reject", "cancel", "exit", "いいえ", "拒否", "キャンセル"}
+	alwaysMarkers   = []string{"don't ask again", "do not ask again", "auto-accept", "always", "during this session", "for this session", "今後", "確認しない", "このセッション"}
+)
+
+// ClassifyChoice determines the semantic kind of a choice label such as
+// "Yes, and don't ask again for rm commands"
+func ClassifyChoice(label string) ChoiceKind {
+	lower := strings.ToLower(strings.TrimSpace(label))
+
+	if hasWordPrefix(lower, rejectPrefixes) {
+		return ChoiceReject
+	}
+	if hasWordPrefix(lower, approvePrefixes) {
+		for _, marker := range alwaysMarkers {
+			if strings.Contains(lower, marker) {
+				return ChoiceApproveAlways
+			}
+		}
+		return ChoiceApproveOnce
+	}
+	return ChoiceUnknown
+}
+
+// hasWordPrefix reports whether s starts with one of the words, followed by a
+// word boundary, so "no" matches "No, exit" but not "None"
+func hasWordPrefix(s string, words []string) bool {
+	for _, word := range words {
+		if !strings.HasPrefix(s, word) {
+			continue
+		}
+		rest := []rune(s[len(word):])
+		if len(rest) == 0 || strings.ContainsRune(" ,.(、。（", rest[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChoiceKinds returns the semantic kind of each choice, keyed by choice number
+func (d DialogInfo) ChoiceKinds() map[string]ChoiceKind {
+	kinds := make(map[string]ChoiceKind, len(d.Choices))
+	for num, label := range d.Choices {
+		kinds[num] = ClassifyChoice(label)
+	}
+	return kinds
+}
+
+// FirstChoice returns the lowest-numbered choice of the given kind, or "" if none
+func (d DialogInfo) FirstChoice(kind ChoiceKind) string {
+	for _, num := range d.ChoiceNumbers() {
+		if ClassifyChoice(d.Choices[num]) == kind {
+			return num
+		}
+	}
+	return ""
+}
+
+// LastChoice returns the highest-numbered choice of the given kind, or "" if none
+func (d DialogInfo) LastChoice(kind ChoiceKind) string {
+	numbers := d.ChoiceNumbers()
+	for i := len(numbers) - 1; i >= 0; i-- {
+		if ClassifyChoice(d.Choices[numbers[i]]) == kind {
+			return numbers[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyChoice(t *testing.T) {
+	testCases := []struct {
+		label    string
+		expected ChoiceKind
+	}{
+		{"Yes", ChoiceApproveOnce},
+		{"Yes, proceed", ChoiceApproveOnce},
+		{"Yes, and manually approve edits", ChoiceApproveOnce},
+		{"Allow this action", ChoiceApproveOnce},
+		{"Yes, and don't ask again for rm commands in /Users/test", ChoiceApproveAlways},
+		{"Yes, allow all edits during this session", ChoiceApproveAlways},
+		{"Yes, and auto-accept edits", ChoiceApproveAlways},
+		{"No, and tell Claude what to do differently (esc)", ChoiceReject},
+		{"No, exit", ChoiceReject},
+		{"No, keep planning", ChoiceReject},
+		{"Deny this action", ChoiceReject},
+		{"Add a new rule", ChoiceUnknown},
+		{"Nothing else", ChoiceUnknown},
+		{"Yesterday", ChoiceUnknown},
+		{"はい", ChoiceApproveOnce},
+		{"はい、今後このコマンドは確認しない", ChoiceApproveAlways},
+		{"いいえ、Claudeに別の方法を伝える (esc)", ChoiceReject},
+	}
+
+	for _, tc := range testCases {
+		if result := ClassifyChoice(tc.label); result != tc.expected {
+			t.Errorf("ClassifyChoice(%q) = %q, want %q", tc.label, result, tc.expected)
+		}
+	}
+}
+
+func TestDialogInfo_ChoiceKinds(t *testing.T) {
+	info := DialogInfo{Choices: map[string]string{
+		"1": "Yes",
+		"2": "Yes, and don't ask again",
+		"3": "No",
+	}}
+
+	expected := map[string]ChoiceKind{
+		"1": ChoiceApproveOnce,
+		"2": ChoiceApproveAlways,
+		"3": ChoiceReject,
+	}
+	if kinds := info.ChoiceKinds(); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Expected %v, got %v", expected, kinds)
+	}
+
+	if num := info.FirstChoice(ChoiceApproveOnce); num != "1" {
+		t.Errorf("FirstChoice(approve once) = %q, want %q", num, "1")
+	}
+	if num := info.LastChoice(ChoiceReject); num != "3" {
+		t.Errorf("LastChoice(reject) = %q, want %q", num, "3")
+	}
+	if num := info.FirstChoice(ChoiceUnknown); num != "" {
+		t.Errorf("FirstChoice(unknown) = %q, want none", num)
+	}
+}
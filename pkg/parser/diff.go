@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	numberedDiffLine   = regexp.MustCompile(`^(\d+)\s([ +-])\s?(.*)$`)
+	unnumberedDiffLine = regexp.MustCompile(`^([+-])\s(.*)$`)
+)
+
+// DiffOp identifies whether a diff line was added, removed, or is unchanged context
+type DiffOp byte
+
+const (
+	DiffContext DiffOp = ' '
+	DiffAdded   DiffOp = '+'
+	DiffRemoved DiffOp = '-'
+)
+
+// DiffLine is a single line of an Edit dialog's diff preview
+type DiffLine struct {
+	Op     DiffOp
+	Number int // Line number shown in the preview, or 0 if none was shown
+	Text   string
+}
+
+// DiffHunk is a run of consecutive diff lines
+type DiffHunk struct {
+	StartLine int // First line number in the hunk, or 0 if unknown
+	Lines     []DiffLine
+}
+
+// parseDiffLine parses a cleaned line from a nested preview box into a diff line
+func parseDiffLine(cleanLine string) (DiffLine, bool) {
+	if matches := numberedDiffLine.FindStringSubmatch(cleanLine); matches != nil {
+		number, _ := strconv.Atoi(matches[1])
+		return DiffLine{Op: DiffOp(matches[2][0]), Number: number, Text: strings.TrimRight(matches[3], " ")}, true
+	}
+	if matches := unnumberedDiffLine.FindStringSubmatch(cleanLine); matches != nil {
+		return DiffLine{Op: DiffOp(matches[1][0]), Text: strings.TrimRight(matches[2], " ")}, true
+	}
+	return DiffLine{}, false
+}
+
+// addDiffLine appends line to the last hunk, starting a new hunk when the
+// line numbers jump past the previous line
+func (d *DialogInfo) addDiffLine(line DiffLine) {
+	if len(d.Diff) > 0 {
+		hunk := &d.Diff[len(d.Diff)-1]
+		last := hunk.Lines[len(hunk.Lines)-1]
+		if line.Number == 0 || last.Number == 0 || line.Number <= last.Number+1 {
+			hunk.Lines = append(hunk.Lines, line)
+			return
+		}
+	}
+	d.Diff = append(d.Diff, DiffHunk{StartLine: line.Number, Lines: []DiffLine{line}})
+}
+
+// FormatDiff renders hunks as readable unified-diff style lines
+func FormatDiff(hunks []DiffHunk) []string {
+	var result []string
+	for _, hunk := range hunks {
+		if len(hunks) > 1 && hunk.StartLine > 0 {
+			result = append(result, fmt.Sprintf("@@ line %d @@", hunk.StartLine))
+		}
+		for _, line := range hunk.Lines {
+			result = append(result, strings.TrimRight(string(line.Op)+" "+line.Text, " "))
+		}
+	}
+	return result
+}
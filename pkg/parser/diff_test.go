@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+var editDialogLines = []string{
+	"╭──────────────────────────────────────────────╮",
+	"│ Edit file                                    │",
+	"│ ╭──────────────────────────────────────────╮ │",
+	"│ │ main.go                                  │ │",
+	"│ │                                          │ │",
+	"│ │ 10  func answer() int {                  │ │",
+	"│ │ 11 -  return 41                          │ │",
+	"│ │ 11 +  return 42                          │ │",
+	"│ │ 12  }                                    │ │",
+	"│ │ 40 -// TODO: remove                      │ │",
+	"│ ╰──────────────────────────────────────────╯ │",
+	"│ Do you want to make this edit to main.go?    │",
+	"│ ❯ 1. Yes                                     │",
+	"│   2. No                                      │",
+	"╰──────────────────────────────────────────────╯",
+}
+
+func TestParseDialog_Diff(t *testing.T) {
+	info := ParseDialog(editDialogLines)
+
+	expected := []DiffHunk{
+		{StartLine: 10, Lines: []DiffLine{
+			{Op: DiffContext, Number: 10, Text: "func answer() int {"},
+			{Op: DiffRemoved, Number: 11, Text: " return 41"},
+			{Op: DiffAdded, Number: 11, Text: " return 42"},
+			{Op: DiffContext, Number: 12, Text: "}"},
+		}},
+		{StartLine: 40, Lines: []DiffLine{
+			{Op: DiffRemoved, Number: 40, Text: "// TODO: remove"},
+		}},
+	}
+	if !reflect.DeepEqual(info.Diff, expected) {
+		t.Errorf("Diff: expected %+v, got %+v", expected, info.Diff)
+	}
+
+	// Diff lines are not command lines; only the file name remains
+	if !reflect.DeepEqual(info.CommandLines, []string{"main.go"}) {
+		t.Errorf("CommandLines: expected only the file name, got %q", info.CommandLines)
+	}
+	if info.Question != "Do you want to make this edit to main.go?" {
+		t.Errorf("Question: unexpected %q", info.Question)
+	}
+}
+
+func TestParseDialog_NoDiffOutsideNestedBox(t *testing.T) {
+	lines := []string{
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"│   - not a diff               │",
+		"│ Do you want to proceed?      │",
+		"╰──────────────────────────────╯",
+	}
+
+	if info := ParseDialog(lines); len(info.Diff) != 0 {
+		t.Errorf("Expected no diff, got %+v", info.Diff)
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	expected := []string{
+		"@@ line 10 @@",
+		"  func answer() int {",
+		"-  return 41",
+		"+  return 42",
+		"  }",
+		"@@ line 40 @@",
+		"- // TODO: remove",
+	}
+
+	if result := FormatDiff(ParseDialog(editDialogLines).Diff); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, result)
+	}
+}
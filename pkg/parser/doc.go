@@ -0,0 +1,15 @@
+// Package parser parses the permission dialogs Claude Code draws in the
+// terminal into structured data.
+//
+// ParseDialog and ParseDialogs accept raw terminal lines (ANSI escapes and
+// ASCII-art borders are handled) and return DialogInfo values with the tool
+// type, command lines, choices, file paths, and any diff preview.
+// CleanMessage renders the most recent dialog as the plain-text message
+// dcode shows in its native dialogs.
+//
+//	info := parser.ParseDialog(lines)
+//	if info.ToolType == parser.ToolBash {
+//		reject := info.LastChoice(parser.ChoiceReject)
+//		...
+//	}
+package parser
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// The parser processes untrusted terminal output, so it must never panic.
+// Go's regexp package guarantees linear-time matching, so fuzzing only needs
+// to look for crashes and broken invariants.
+
+func addDialogSeeds(f *testing.F) {
+	f.Add(strings.Join(editDialogLines, "\n"))
+	f.Add("╭──╮\n│ Bash command │\n│   ls │\n│ ❯ 1. Yes │\n╰──╯")
+	f.Add("+----+\n| Task |\n|   2. No |\n+----+")
+	f.Add("│ │ 11 -  x │ │\n╰")
+	f.Add("╭╭╭\n╰╰╰╰\n│")
+	f.Add("\x1b[31m│ 日本語 │\x1b[0m\n\xff\xfe")
+}
+
+func FuzzParseDialog(f *testing.F) {
+	addDialogSeeds(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		lines := strings.Split(input, "\n")
+
+		info := ParseDialog(lines)
+		if len(info.RawContent) > len(lines) {
+			t.Errorf("RawContent has %d lines, more than the %d input lines", len(info.RawContent), len(lines))
+		}
+		for _, num := range info.ChoiceNumbers() {
+			if _, ok := info.Choices[num]; !ok {
+				t.Errorf("ChoiceNumbers returned unknown choice %q", num)
+			}
+		}
+		info.ChoiceKinds()
+		FormatDiff(info.Diff)
+
+		for _, dialog := range ParseDialogs(lines) {
+			if len(dialog.RawContent) == 0 {
+				t.Error("ParseDialogs returned a dialog without content")
+			}
+		}
+	})
+}
+
+func FuzzCleanMessage(f *testing.F) {
+	addDialogSeeds(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		message := CleanMessage(strings.Split(input, "\n"), input, "reason", "123")
+		if !strings.Contains(message, MessageSeparator) {
+			t.Errorf("Message is missing the separator: %q", message)
+		}
+	})
+}
+
+func FuzzDisplayWidth(f *testing.F) {
+	f.Add("abc")
+	f.Add("日本語")
+	f.Add("\xff")
+	f.Fuzz(func(t *testing.T, input string) {
+		if width := DisplayWidth(input); width < 0 || width > 2*len(input) {
+			t.Errorf("DisplayWidth(%q) = %d, out of range", input, width)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package parser
+
+import "strings"
+
+// MessageSeparator separates the trigger information from the dialog content in a clean message
+const MessageSeparator = "───────────────────────────────────"
+
+// DefaultQuestion is shown when the dialog's question could not be found
+const DefaultQuestion = "Do you want to proceed?"
+
+// CleanMessage builds a readable message for a permission dialog from the
+// terminal lines leading up to it: the tool call that triggered it, the
+// timestamp and reason, warnings for high-risk actions and truncated commands,
+// then the header, command lines, diff, and question of the most recent dialog
+// box in context.
+func CleanMessage(context []string, triggerLine, triggerReason, timestamp string) string {
+	var messageParts []string
+
+	if triggerText := TriggerText(context, triggerLine); triggerText != "" {
+		messageParts = append(messageParts, "Trigger text: "+triggerText)
+	}
+	if timestamp != "" {
+		messageParts = append(messageParts, "Trigger timestamp: "+timestamp)
+	}
+	if triggerReason != "" {
+		messageParts = append(messageParts, "Reason: "+triggerReason)
+	}
+
+	info := LastDialog(context)
+	if info.Risk == RiskHigh {
+		messageParts = append(messageParts, "⚠️ Risk: high ("+info.RiskReason+")")
+	}
+	if info.Truncated {
+		messageParts = append(messageParts, "⚠️ Truncated: the full command is not shown")
+	}
+
+	messageParts = append(messageParts, MessageSeparator)
+	if info.Header != "" {
+		messageParts = append(messageParts, info.Header, "")
+	}
+
+	for _, commandLine := range info.CommandLines {
+		messageParts = append(messageParts, "  "+commandLine)
+	}
+	if len(info.CommandLines) > 0 {
+		messageParts = append(messageParts, "")
+	}
+
+	diff := FormatDiff(info.Diff)
+	for _, diffLine := range diff {
+		messageParts = append(messageParts, "  "+diffLine)
+	}
+	if len(diff) > 0 {
+		messageParts = append(messageParts, "")
+	}
+
+	question := info.Question
+	if question == "" {
+		question = DefaultQuestion
+	}
+	messageParts = append(messageParts, question)
+
+	return strings.Join(messageParts, "\n")
+}
+
+// TriggerText returns the tool call line ("⏺ Bash(...)") that led to the dialog,
+// falling back to the cleaned triggerLine if context doesn't contain one
+func TriggerText(context []string, triggerLine string) string {
+	for _, line := range context {
+		cleanLine := strings.TrimSpace(StripAnsi(line))
+		if strings.HasPrefix(cleanLine, "⏺") {
+			return cleanLine
+		}
+	}
+	return CleanLine(triggerLine)
+}
+
+// LastDialog parses the most recent top-level dialog box in lines. Lines
+// without box characters, such as interleaved status output, are ignored.
+func LastDialog(lines []string) DialogInfo {
+	var boxed []string
+	for _, line := range NormalizeBorders(lines) {
+		if strings.Contains(line, "╭") || strings.Contains(line, "│") || strings.Contains(line, "╰") {
+			boxed = append(boxed, line)
+		}
+	}
+
+	if dialogs := ParseDialogs(boxed); len(dialogs) > 0 {
+		return dialogs[len(dialogs)-1]
+	}
+	// Without a top border, treat all box lines as one dialog
+	return ParseDialog(boxed)
+}
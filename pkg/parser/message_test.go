@@ -0,0 +1,103 @@
+package parser
+
+import "testing"
+
+func TestCleanMessage(t *testing.T) {
+	context := []string{
+		"⏺ Bash(rm test-file)",
+		"  ⎿  Running…",
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"│                              │",
+		"│   rm test-file               │",
+		"│                              │",
+		"│ Do you want to proceed?      │",
+		"╰──────────────────────────────╯",
+	}
+
+	expected := `Trigger text: ⏺ Bash(rm test-file)
+Trigger timestamp: 123
+Reason: Bash command execution
+───────────────────────────────────
+Bash command
+
+  rm test-file
+
+Do you want to proceed?`
+
+	if result := CleanMessage(context, "", "Bash command execution", "123"); result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestCleanMessage_Truncated(t *testing.T) {
+	context := []string{
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"│   find . -name '*.go' -exec… │",
+		"│ Do you want to proceed?      │",
+		"╰──────────────────────────────╯",
+	}
+
+	expected := `Reason: Bash command execution
+⚠️ Truncated: the full command is not shown
+───────────────────────────────────
+Bash command
+
+  find . -name '*.go' -exec…
+
+Do you want to proceed?`
+
+	if result := CleanMessage(context, "", "Bash command execution", ""); result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestCleanMessage_Empty(t *testing.T) {
+	expected := MessageSeparator + "\n" + DefaultQuestion
+
+	if result := CleanMessage(nil, "", "", ""); result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestLastDialog(t *testing.T) {
+	lines := []string{
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"│   ls                         │",
+		"╰──────────────────────────────╯",
+		"⏺ Bash(rm test-file)",
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"  ⎿  Running…",
+		"│   rm test-file               │",
+		"╰──────────────────────────────╯",
+	}
+
+	info := LastDialog(lines)
+	if len(info.CommandLines) != 1 || info.CommandLines[0] != "rm test-file" {
+		t.Errorf("Expected the most recent dialog's command, got %q", info.CommandLines)
+	}
+}
+
+func TestTriggerText(t *testing.T) {
+	testCases := []struct {
+		name        string
+		context     []string
+		triggerLine string
+		expected    string
+	}{
+		{"tool call in context", []string{"other", "\x1b[1m⏺ Bash(ls)\x1b[0m"}, "│ ls │", "⏺ Bash(ls)"},
+		{"falls back to trigger line", []string{"other"}, "│   ls -la   │", "ls -la"},
+		{"nothing found", nil, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := TriggerText(tc.context, tc.triggerLine); result != tc.expected {
+				t.Errorf("TriggerText = %q, want %q", result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,528 @@
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnsiEscapePattern matches terminal escape sequences: CSI sequences such as
+// colors and cursor movement, OSC sequences such as window titles and ESC]8
+// hyperlinks (terminated by BEL or ST), and DCS/SOS/PM/APC strings
+const AnsiEscapePattern = `\x1b\[[0-9;?]*[mKHJhlABCDEFGPST]` +
+	`|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)` +
+	`|\x1b[P^_X][^\x1b]*\x1b\\`
+
+var (
+	ansiEscape   = regexp.MustCompile(AnsiEscapePattern)
+	choiceLine   = regexp.MustCompile(`^(❯\s*)?([0-9]+)\.\s+(.+)$`)
+	filePathArg  = regexp.MustCompile(`file_path:\s*(\S+)`)
+	questionPath = regexp.MustCompile(`(?:edit to|create|overwrite|write to)\s+(.+?)\?$`)
+	barePath     = regexp.MustCompile(`^(?:/|~/|[A-Za-z]:[\\/])\S*$`)
+	mcpToolLine  = regexp.MustCompile(`^(\S+) - (\S+?)\(.*\)\s*\(MCP\)$`)
+	sgrSequence  = regexp.MustCompile(`^\x1b\[([0-9;]*)m`)
+)
+
+// DialogInfo holds the structured content of a Claude Code dialog box
+type DialogInfo struct {
+	RawContent    []string          // Box lines including borders, cropped to the dialog if it was nested
+	ToolType      string            // Tool detected from the header ("Bash", "Edit", ...), "mcp__server__tool", or empty
+	Header        string            // First line inside the box, e.g. "Bash command"
+	Question      string            // The question line, e.g. "Do you want to proceed?"
+	Choices       map[string]string // Choice number → label
+	DefaultChoice string            // Choice number under the ❯ cursor, selected by plain Enter
+	CommandLines  []string          // Command and description lines between header and question
+	Described     bool              // The last of CommandLines is Claude's description of the command, drawn in its own style
+	FilePaths     []string          // Target file paths mentioned in the dialog
+	Diff          []DiffHunk        // Diff preview shown in a nested box by Edit dialogs
+	Risk          RiskLevel         // How dangerous the requested action looks
+	RiskReason    string            // Why Risk was raised above RiskLow, e.g. "recursive delete"
+	Truncated     bool              // The command was cut off with "…" or at the terminal width
+}
+
+// ChoiceNumbers returns the choice numbers in ascending numeric order
+func (d DialogInfo) ChoiceNumbers() []string {
+	numbers := make([]string, 0, len(d.Choices))
+	for num := range d.Choices {
+		numbers = append(numbers, num)
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		a, _ := strconv.Atoi(numbers[i])
+		b, _ := strconv.Atoi(numbers[j])
+		return a < b
+	})
+	return numbers
+}
+
+// Command returns the lines of the command itself: CommandLines without the
+// description, split at any newlines they hold. Unless the description could
+// be told apart by its style, it's taken to be part of the command, so the
+// command is never mistaken for less than it is.
+func (d DialogInfo) Command() []string {
+	lines := d.CommandLines
+	if d.Described {
+		lines = lines[:len(lines)-1]
+	}
+	var command []string
+	for _, line := range lines {
+		command = append(command, strings.Split(line, "\n")...)
+	}
+	return command
+}
+
+// CommandText returns the whole command, its lines joined with newlines
+func (d DialogInfo) CommandText() string {
+	return strings.Join(d.Command(), "\n")
+}
+
+// StripAnsi removes ANSI escape sequences from a string
+func StripAnsi(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// CleanLine removes box borders, unicode whitespace, and decorations from a dialog line
+func CleanLine(line string) string {
+	cleanLine := strings.Trim(StripAnsi(line), "│ \t")
+	cleanLine = strings.TrimRight(cleanLine, "│ \t\r\n\u00A0\u2000\u2001\u2002\u2003\u2004\u2005\u2006\u2007\u2008\u2009\u200A\u200B\u202F\u205F\u3000◯○◉●>─━┌┐└┘├┤┬┴┼╭╮╯╰╠╣╦╩╬⧉")
+	return strings.TrimSpace(cleanLine)
+}
+
+// ParseDialog extracts structured information from the first dialog box in lines.
+// Use ParseDialogs when lines may contain several dialogs.
+// Lines outside the box are ignored; if no top border is present, every line
+// containing a box character is treated as part of the box. ASCII-art borders
+// are accepted as well as Unicode ones.
+func ParseDialog(lines []string) DialogInfo {
+	info := DialogInfo{
+		Choices: make(map[string]string),
+	}
+
+	box := innermostDialog(boxLines(NormalizeBorders(lines)))
+	innerWidth := boxInnerWidth(box)
+
+	// Track the previous physical command line to re-join wrapped lines
+	lastCommandIndent, lastCommandWidth := -1, 0
+
+	// Every line except choices and diffs is checked for risky content
+	var riskLines []string
+
+	// The style each command line starts in, to tell the description apart
+	var commandStyles []string
+
+	depth := 0
+	for _, line := range box {
+		info.RawContent = append(info.RawContent, line)
+
+		depth += strings.Count(line, "╭")
+		nested := depth > 1
+		depth -= strings.Count(line, "╰")
+
+		cleanLine := CleanLine(line)
+		if cleanLine == "" || isBorder(cleanLine) {
+			lastCommandIndent = -1
+			continue
+		}
+
+		// Diff lines only appear in the preview box nested inside an Edit dialog
+		if nested && hasDiffPreview(info.ToolType) {
+			if diffLine, ok := parseDiffLine(cleanLine); ok {
+				info.addDiffLine(diffLine)
+				lastCommandIndent = -1
+				continue
+			}
+		}
+
+		indent := lineIndent(line)
+		if lastCommandIndent == indent && isWrapped(lastCommandWidth, cleanLine, innerWidth) {
+			last := len(info.CommandLines) - 1
+			if lastCommandWidth >= innerWidth {
+				// Hard-wrapped in the middle of a word
+				info.CommandLines[last] += cleanLine
+			} else {
+				info.CommandLines[last] += " " + cleanLine
+			}
+			lastCommandWidth = indent + DisplayWidth(cleanLine)
+			continue
+		}
+		lastCommandIndent = -1
+
+		if matches := choiceLine.FindStringSubmatch(cleanLine); matches != nil {
+			info.Choices[matches[2]] = strings.TrimSpace(matches[3])
+			if matches[1] != "" {
+				info.DefaultChoice = matches[2]
+			}
+			continue
+		}
+		riskLines = append(riskLines, cleanLine)
+
+		if isQuestion(cleanLine) {
+			info.Question = cleanLine
+			if matches := questionPath.FindStringSubmatch(cleanLine); matches != nil {
+				info.addFilePath(matches[1])
+			}
+			continue
+		}
+
+		if info.Header == "" && info.Question == "" {
+			info.Header = cleanLine
+			info.ToolType = detectToolType(cleanLine)
+			continue
+		}
+
+		// Skip bullets and trailing hints once the question has been asked
+		if strings.HasPrefix(cleanLine, "•") || info.Question != "" {
+			if info.ToolType != ToolBash && barePath.MatchString(cleanLine) {
+				info.addFilePath(cleanLine)
+			}
+			continue
+		}
+
+		// MCP dialogs have a generic "Tool use" header; the tool is named on the next line
+		if info.ToolType == "" {
+			info.ToolType = mcpToolName(cleanLine)
+		}
+
+		info.CommandLines = append(info.CommandLines, cleanLine)
+		commandStyles = append(commandStyles, lineStyle(line))
+		lastCommandIndent, lastCommandWidth = indent, indent+DisplayWidth(cleanLine)
+	}
+
+	info.Truncated = isTruncated(box, info.Header, info.CommandLines)
+
+	// Claude draws its description of a Bash command below it, dimmed
+	last := len(info.CommandLines) - 1
+	info.Described = info.ToolType == ToolBash && last > 0 &&
+		commandStyles[last] != "" && commandStyles[last] != commandStyles[0]
+
+	// A Bash command is free text: a path in it, or a "file_path:" it echoes,
+	// says nothing about what it does, so file rules never match it
+	if info.ToolType != ToolBash {
+		for _, commandLine := range info.CommandLines {
+			if matches := filePathArg.FindStringSubmatch(commandLine); matches != nil {
+				info.addFilePath(matches[1])
+			} else if barePath.MatchString(commandLine) {
+				info.addFilePath(commandLine)
+			}
+		}
+	}
+
+	// Re-joined command lines catch risky commands split across wrapped lines
+	info.rateRisk(append(riskLines, info.CommandLines...))
+
+	return info
+}
+
+// ParseDialogs extracts structured information from every top-level dialog box
+// in lines, in the order they appear. Boxes nested inside another box (such as
+// the diff preview in an Edit dialog) belong to their enclosing dialog.
+func ParseDialogs(lines []string) []DialogInfo {
+	var dialogs []DialogInfo
+	var current []string
+	depth := 0
+
+	for _, line := range NormalizeBorders(lines) {
+		opens := strings.Count(line, "╭")
+		closes := strings.Count(line, "╰")
+		if depth == 0 && opens == 0 {
+			continue
+		}
+
+		current = append(current, line)
+		depth += opens - closes
+		if depth <= 0 {
+			dialogs = append(dialogs, ParseDialog(current))
+			current, depth = nil, 0
+		}
+	}
+
+	// An unterminated box at the end is still parsed, like ParseDialog does
+	if len(current) > 0 {
+		dialogs = append(dialogs, ParseDialog(current))
+	}
+	return dialogs
+}
+
+// isTruncated reports whether the header or command of a dialog was cut off:
+// Claude ends shortened lines with "…", and a box wider than the terminal
+// loses its right edge, leaving a top border without its ╮ corner
+func isTruncated(box []string, header string, commandLines []string) bool {
+	if len(box) > 0 && strings.Contains(box[0], "╭") && !strings.Contains(box[0], "╮") {
+		return true
+	}
+	for _, line := range append([]string{header}, commandLines...) {
+		if strings.HasSuffix(line, "…") {
+			return true
+		}
+	}
+	return false
+}
+
+// boxInnerWidth returns the number of columns available for content inside
+// the box, derived from its top border, or 0 if there is no top border
+func boxInnerWidth(box []string) int {
+	if len(box) == 0 || !strings.Contains(box[0], "╭") {
+		return 0
+	}
+	// "│ " and " │" surround the content
+	return DisplayWidth(strings.TrimSpace(StripAnsi(box[0]))) - 4
+}
+
+// lineIndent returns the number of spaces between the left border and the content
+func lineIndent(line string) int {
+	content := StripAnsi(line)
+	if idx := strings.Index(content, "│"); idx >= 0 {
+		content = content[idx+len("│"):]
+	}
+	// The first space is the box margin
+	content = strings.TrimPrefix(content, " ")
+	return len(content) - len(strings.TrimLeft(content, " "))
+}
+
+// lineStyle returns the colors and styles set before the content of line
+// starts, leaving out resets, e.g. "2" for a dimmed line or "" for a plain one
+func lineStyle(line string) string {
+	if idx := strings.Index(line, "│"); idx >= 0 {
+		line = line[idx+len("│"):]
+	}
+	var styles []string
+	for line != "" {
+		if line[0] == ' ' {
+			line = line[1:]
+			continue
+		}
+		matches := sgrSequence.FindStringSubmatch(line)
+		if matches == nil {
+			break
+		}
+		line = line[len(matches[0]):]
+		switch matches[1] {
+		case "", "0", "22", "39", "49":
+		default:
+			styles = append(styles, matches[1])
+		}
+	}
+	return strings.Join(styles, ";")
+}
+
+// isWrapped reports whether next continues the previous line: either the
+// previous line filled the box, or next's first word would not have fit on it
+func isWrapped(previousWidth int, next string, innerWidth int) bool {
+	if innerWidth <= 0 {
+		return false
+	}
+	firstWord := next
+	if idx := strings.IndexAny(next, " \t"); idx >= 0 {
+		firstWord = next[:idx]
+	}
+	return previousWidth >= innerWidth || previousWidth+1+DisplayWidth(firstWord) > innerWidth
+}
+
+// boxLines returns the lines from the first top border through its matching
+// bottom border, keeping any nested boxes inside it
+func boxLines(lines []string) []string {
+	start := -1
+	for i, line := range lines {
+		if strings.Contains(line, "╭") {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		var result []string
+		for _, line := range lines {
+			if strings.Contains(line, "│") || strings.Contains(line, "╰") {
+				result = append(result, line)
+			}
+		}
+		return result
+	}
+
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		depth += strings.Count(lines[i], "╭") - strings.Count(lines[i], "╰")
+		if depth <= 0 {
+			return lines[start : i+1]
+		}
+	}
+	return lines[start:]
+}
+
+// innermostDialog narrows box to the most deeply nested box containing a
+// dialog question, so a permission dialog drawn inside another box (such as
+// Task tool output) is parsed on its own. Borders of enclosing boxes are
+// cropped away so the dialog looks as if it were drawn at the top level.
+func innermostDialog(box []string) []string {
+	var openBoxes []int
+	start := 0
+	for i, line := range box {
+		for n := strings.Count(line, "╭"); n > 0; n-- {
+			openBoxes = append(openBoxes, i)
+		}
+		if len(openBoxes) > 1 && isQuestion(CleanLine(line)) {
+			start = openBoxes[len(openBoxes)-1]
+		}
+		for n := strings.Count(line, "╰"); n > 0 && len(openBoxes) > 0; n-- {
+			openBoxes = openBoxes[:len(openBoxes)-1]
+		}
+	}
+
+	dialog := boxLines(box[start:])
+	return cropToTopBorder(dialog)
+}
+
+// cropToTopBorder removes the columns outside the top border of box, i.e. the
+// side borders of any enclosing boxes. Boxes drawn at the top level are
+// returned unchanged.
+func cropToTopBorder(box []string) []string {
+	if len(box) == 0 {
+		return box
+	}
+	top := StripAnsi(box[0])
+	idx := strings.Index(top, "╭")
+	if idx < 0 || !strings.Contains(top[:idx], "│") {
+		return box
+	}
+
+	left := DisplayWidth(top[:idx])
+	width := DisplayWidth(strings.TrimRight(top[idx:], " │"))
+	cropped := make([]string, len(box))
+	for i, line := range box {
+		cropped[i] = cropColumns(StripAnsi(line), left, width)
+	}
+	return cropped
+}
+
+// cropColumns returns the part of s that occupies columns [left, left+width)
+func cropColumns(s string, left, width int) string {
+	var b strings.Builder
+	column := 0
+	for _, r := range s {
+		if column >= left && column < left+width {
+			b.WriteRune(r)
+		}
+		column += runeWidth(r)
+	}
+	return b.String()
+}
+
+// isBorder reports whether a cleaned line consists only of box drawing characters
+func isBorder(cleanLine string) bool {
+	return strings.Trim(cleanLine, "─━┌┐└┘├┤┬┴┼╭╮╯╰╠╣╦╩╬ ") == ""
+}
+
+// isQuestion reports whether a cleaned line is the dialog question
+func isQuestion(cleanLine string) bool {
+	return strings.HasPrefix(cleanLine, "Do you want to") ||
+		strings.HasPrefix(cleanLine, "Do you trust") ||
+		strings.HasPrefix(cleanLine, "Would you like to") ||
+		strings.HasPrefix(cleanLine, "Are you sure") ||
+		strings.HasSuffix(cleanLine, "proceed?") ||
+		strings.HasSuffix(cleanLine, "continue?") ||
+		strings.HasSuffix(cleanLine, "ますか？") ||
+		strings.HasSuffix(cleanLine, "ますか?")
+}
+
+// Tool names as used in Claude Code permission rules
+const (
+	ToolBash         = "Bash"
+	ToolEdit         = "Edit"
+	ToolMultiEdit    = "MultiEdit"
+	ToolWrite        = "Write"
+	ToolRead         = "Read"
+	ToolGrep         = "Grep"
+	ToolGlob         = "Glob"
+	ToolLS           = "LS"
+	ToolWebFetch     = "WebFetch"
+	ToolWebSearch    = "WebSearch"
+	ToolNotebookEdit = "NotebookEdit"
+	ToolTask         = "Task"
+)
+
+// readOnlyTools only look at files or the web and never change anything
+var readOnlyTools = map[string]bool{
+	ToolRead:      true,
+	ToolGrep:      true,
+	ToolGlob:      true,
+	ToolLS:        true,
+	ToolWebSearch: true,
+}
+
+// fileEditTools create or change the files named in their dialog
+var fileEditTools = map[string]bool{
+	ToolWrite:        true,
+	ToolEdit:         true,
+	ToolMultiEdit:    true,
+	ToolNotebookEdit: true,
+}
+
+// toolHeaders maps dialog header prefixes to tools. More specific prefixes
+// come first so "Edit notebook" isn't detected as Edit.
+var toolHeaders = []struct {
+	prefix string
+	tool   string
+}{
+	{"Bash", ToolBash},
+	{"Edit notebook", ToolNotebookEdit},
+	{"NotebookEdit", ToolNotebookEdit},
+	{"MultiEdit", ToolMultiEdit},
+	{"Edit", ToolEdit},
+	{"Create file", ToolWrite},
+	{"Write", ToolWrite},
+	{"Read", ToolRead},
+	{"Grep", ToolGrep},
+	{"Glob", ToolGlob},
+	{"LS", ToolLS},
+	{"Fetch", ToolWebFetch},
+	{"WebFetch", ToolWebFetch},
+	{"Web Search", ToolWebSearch},
+	{"WebSearch", ToolWebSearch},
+	{"Task", ToolTask},
+}
+
+// detectToolType maps a dialog header to the tool that requested permission
+func detectToolType(header string) string {
+	for _, toolHeader := range toolHeaders {
+		if strings.HasPrefix(header, toolHeader.prefix) {
+			return toolHeader.tool
+		}
+	}
+	return ""
+}
+
+// IsReadOnlyTool reports whether tool only reads files or searches and never
+// changes anything
+func IsReadOnlyTool(tool string) bool {
+	return readOnlyTools[tool]
+}
+
+// IsFileEditTool reports whether tool creates or changes the files in its dialog
+func IsFileEditTool(tool string) bool {
+	return fileEditTools[tool]
+}
+
+// mcpToolName returns the permission rule name ("mcp__server__tool") for an
+// MCP tool line such as "github - create_issue(title: "x") (MCP)"
+func mcpToolName(cleanLine string) string {
+	matches := mcpToolLine.FindStringSubmatch(cleanLine)
+	if matches == nil {
+		return ""
+	}
+	return "mcp__" + matches[1] + "__" + matches[2]
+}
+
+// hasDiffPreview reports whether a tool's dialog shows a diff of the change
+func hasDiffPreview(toolType string) bool {
+	return toolType == ToolEdit || toolType == ToolMultiEdit
+}
+
+// addFilePath records a file path once
+func (d *DialogInfo) addFilePath(path string) {
+	for _, existing := range d.FilePaths {
+		if existing == path {
+			return
+		}
+	}
+	d.FilePaths = append(d.FilePaths, path)
+}
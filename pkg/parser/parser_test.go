@@ -0,0 +1,586 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseDialog_BashCommand(t *testing.T) {
+	lines := []string{
+		"⏺ Bash(rm test-file)",
+		"  ⎿  Running…",
+		"╭─────────────────────────────────────────────────────────────────────────────╮",
+		"│ Bash command                                                                │",
+		"│                                                                             │",
+		"│   rm test-file                                                              │",
+		"│   Remove test file                                                          │",
+		"│                                                                             │",
+		"│ Do you want to proceed?                                                     │",
+		"│ ❯ 1. Yes                                                                    │",
+		"│   2. Yes, and don't ask again for rm commands in /Users/test/git/dialog-code │",
+		"│   3. No, and tell Claude what to do differently (esc)                       │",
+		"╰─────────────────────────────────────────────────────────────────────────────╯",
+	}
+
+	info := ParseDialog(lines)
+
+	if info.Header != "Bash command" {
+		t.Errorf("Header: expected %q, got %q", "Bash command", info.Header)
+	}
+	if info.ToolType != "Bash" {
+		t.Errorf("ToolType: expected %q, got %q", "Bash", info.ToolType)
+	}
+	if info.Question != "Do you want to proceed?" {
+		t.Errorf("Question: expected %q, got %q", "Do you want to proceed?", info.Question)
+	}
+	expectedCommand := []string{"rm test-file", "Remove test file"}
+	if !reflect.DeepEqual(info.CommandLines, expectedCommand) {
+		t.Errorf("CommandLines: expected %q, got %q", expectedCommand, info.CommandLines)
+	}
+	expectedChoices := map[string]string{
+		"1": "Yes",
+		"2": "Yes, and don't ask again for rm commands in /Users/test/git/dialog-code",
+		"3": "No, and tell Claude what to do differently (esc)",
+	}
+	if !reflect.DeepEqual(info.Choices, expectedChoices) {
+		t.Errorf("Choices: expected %q, got %q", expectedChoices, info.Choices)
+	}
+	if info.DefaultChoice != "1" {
+		t.Errorf("DefaultChoice: expected %q, got %q", "1", info.DefaultChoice)
+	}
+	if len(info.RawContent) != 11 {
+		t.Errorf("RawContent: expected 11 box lines, got %d", len(info.RawContent))
+	}
+	if len(info.FilePaths) != 0 {
+		t.Errorf("FilePaths: expected none, got %q", info.FilePaths)
+	}
+}
+
+func TestParseDialog_Description(t *testing.T) {
+	dialog := func(lines ...string) []string {
+		box := []string{
+			"╭──────────────────────────────────────╮",
+			"│ Bash command                         │",
+		}
+		for _, line := range lines {
+			box = append(box, "│   "+line+" │")
+		}
+		return append(box,
+			"│ Do you want to proceed?              │",
+			"│ ❯ 1. Yes                             │",
+			"│   2. No                              │",
+			"╰──────────────────────────────────────╯",
+		)
+	}
+
+	testCases := []struct {
+		name      string
+		lines     []string
+		described bool
+		command   []string
+	}{
+		{"dimmed description", []string{"go test ./...                     ", "\x1b[2mRun the tests\x1b[22m                     "}, true, []string{"go test ./..."}},
+		{"gray description", []string{"\x1b[39mgo test ./...                     ", "\x1b[38;5;246mRun the tests\x1b[39m                     "}, true, []string{"go test ./..."}},
+		{"plain lines", []string{"go test ./...                     ", "Run the tests                     "}, false, []string{"go test ./...", "Run the tests"}},
+		{"lines in the same style", []string{"\x1b[2mgo test ./...\x1b[22m                     ", "\x1b[2mrm -rf ~\x1b[22m                          "}, false, []string{"go test ./...", "rm -rf ~"}},
+		{"command of several lines", []string{"go test ./...                     ", "rm -rf ~                          ", "\x1b[2mRun the tests\x1b[22m                     "}, true, []string{"go test ./...", "rm -rf ~"}},
+		{"single line", []string{"\x1b[2mgo test ./...\x1b[22m                     "}, false, []string{"go test ./..."}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := ParseDialog(dialog(tc.lines...))
+			if info.Described != tc.described {
+				t.Errorf("Described: expected %v, got %v (command lines %q)", tc.described, info.Described, info.CommandLines)
+			}
+			if command := info.Command(); !reflect.DeepEqual(command, tc.command) {
+				t.Errorf("Command: expected %q, got %q", tc.command, command)
+			}
+		})
+	}
+}
+
+func TestDialogInfo_CommandSplitsLines(t *testing.T) {
+	info := DialogInfo{ToolType: ToolBash, CommandLines: []string{"go test ./...\nrm -rf ~"}}
+	if text := info.CommandText(); text != "go test ./...\nrm -rf ~" {
+		t.Errorf("CommandText: expected both lines, got %q", text)
+	}
+	if command := info.Command(); len(command) != 2 {
+		t.Errorf("Command: expected 2 lines, got %q", command)
+	}
+}
+
+func TestParseDialog_FilePaths(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lines    []string
+		expected []string
+	}{
+		{
+			name: "file_path argument",
+			lines: []string{
+				"╭─────────────────────────────────────╮",
+				"│ Edit command                        │",
+				"│   file_path: /test/file.txt         │",
+				"│ Do you want to proceed?             │",
+				"╰─────────────────────────────────────╯",
+			},
+			expected: []string{"/test/file.txt"},
+		},
+		{
+			name: "edit question",
+			lines: []string{
+				"╭─────────────────────────────────────╮",
+				"│ Edit file                           │",
+				"│ Do you want to make this edit to main.go? │",
+				"╰─────────────────────────────────────╯",
+			},
+			expected: []string{"main.go"},
+		},
+		{
+			name: "folder trust path",
+			lines: []string{
+				"╭─────────────────────────────────────╮",
+				"│ Do you trust the files in this folder? │",
+				"│                                     │",
+				"│ /Users/test/git/dialog-code         │",
+				"│ ❯ 1. Yes, proceed                   │",
+				"╰─────────────────────────────────────╯",
+			},
+			expected: []string{"/Users/test/git/dialog-code"},
+		},
+		{
+			name: "bash command naming a path",
+			lines: []string{
+				"╭─────────────────────────────────────╮",
+				"│ Bash command                        │",
+				"│   echo file_path: /Users/me/proj/a  │",
+				"│   /Users/me/proj/run.sh             │",
+				"│ Do you want to proceed?             │",
+				"╰─────────────────────────────────────╯",
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := ParseDialog(tc.lines)
+			if !reflect.DeepEqual(info.FilePaths, tc.expected) {
+				t.Errorf("FilePaths: expected %q, got %q", tc.expected, info.FilePaths)
+			}
+		})
+	}
+}
+
+func TestParseDialog_ToolType(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{"Bash command", "Bash"},
+		{"Edit file", "Edit"},
+		{"MultiEdit", "MultiEdit"},
+		{"Create file", "Write"},
+		{"Read file", "Read"},
+		{"Grep", "Grep"},
+		{"Glob", "Glob"},
+		{"LS", "LS"},
+		{"Fetch", "WebFetch"},
+		{"Web Search", "WebSearch"},
+		{"Edit notebook", "NotebookEdit"},
+		{"Task", "Task"},
+		{"Tool use", ""},
+	}
+
+	for _, tc := range testCases {
+		info := ParseDialog([]string{"╭──╮", "│ " + tc.header + " │", "╰──╯"})
+		if info.ToolType != tc.expected {
+			t.Errorf("ToolType for header %q: expected %q, got %q", tc.header, tc.expected, info.ToolType)
+		}
+	}
+}
+
+func TestIsReadOnlyTool(t *testing.T) {
+	testCases := map[string]bool{
+		ToolRead:                     true,
+		ToolGrep:                     true,
+		ToolGlob:                     true,
+		ToolLS:                       true,
+		ToolWebSearch:                true,
+		ToolWebFetch:                 false,
+		ToolBash:                     false,
+		ToolEdit:                     false,
+		ToolWrite:                    false,
+		"mcp__github__search_issues": false,
+		"":                           false,
+	}
+	for tool, expected := range testCases {
+		if result := IsReadOnlyTool(tool); result != expected {
+			t.Errorf("IsReadOnlyTool(%q): expected %v, got %v", tool, expected, result)
+		}
+	}
+}
+
+func TestIsFileEditTool(t *testing.T) {
+	testCases := map[string]bool{
+		ToolWrite:        true,
+		ToolEdit:         true,
+		ToolMultiEdit:    true,
+		ToolNotebookEdit: true,
+		ToolRead:         false,
+		ToolBash:         false,
+		"":               false,
+	}
+	for tool, expected := range testCases {
+		if result := IsFileEditTool(tool); result != expected {
+			t.Errorf("IsFileEditTool(%q): expected %v, got %v", tool, expected, result)
+		}
+	}
+}
+
+func TestParseDialog_MCPToolType(t *testing.T) {
+	lines := []string{
+		"╭──────────────────────────────────────────────────────────╮",
+		"│ Tool use                                                 │",
+		"│                                                          │",
+		"│   github - create_issue(title: \"Fix login\") (MCP)      │",
+		"│   Create a new issue in a GitHub repository              │",
+		"│                                                          │",
+		"│ Do you want to proceed?                                  │",
+		"│ ❯ 1. Yes                                                 │",
+		"╰──────────────────────────────────────────────────────────╯",
+	}
+
+	if info := ParseDialog(lines); info.ToolType != "mcp__github__create_issue" {
+		t.Errorf("ToolType: expected %q, got %q", "mcp__github__create_issue", info.ToolType)
+	}
+}
+
+func TestParseDialog_IgnoresLinesOutsideBox(t *testing.T) {
+	lines := []string{
+		"1. Not a choice",
+		"╭──────────────────╮",
+		"│ Task             │",
+		"│ ❯ 1. Yes         │",
+		"╰──────────────────╯",
+		"2. Also not a choice",
+	}
+
+	info := ParseDialog(lines)
+	if !reflect.DeepEqual(info.Choices, map[string]string{"1": "Yes"}) {
+		t.Errorf("Expected only the boxed choice, got %q", info.Choices)
+	}
+}
+
+func TestDialogInfo_ChoiceNumbers(t *testing.T) {
+	info := DialogInfo{Choices: map[string]string{"3": "c", "1": "a", "2": "b"}}
+
+	if numbers := info.ChoiceNumbers(); !reflect.DeepEqual(numbers, []string{"1", "2", "3"}) {
+		t.Errorf("Expected sorted choice numbers, got %q", numbers)
+	}
+}
+
+func TestParseDialog_DoubleDigitChoices(t *testing.T) {
+	lines := []string{
+		"╭──────────────────────────────────────╮",
+		"│ Tool use                             │",
+		"│ Do you want to proceed?              │",
+	}
+	for i := 1; i <= 12; i++ {
+		cursor := "  "
+		if i == 11 {
+			cursor = "❯ "
+		}
+		lines = append(lines, fmt.Sprintf("│ %s%d. Option %d                       │", cursor, i, i))
+	}
+	lines = append(lines, "╰──────────────────────────────────────╯")
+
+	info := ParseDialog(lines)
+	if len(info.Choices) != 12 {
+		t.Fatalf("Expected 12 choices, got %d: %q", len(info.Choices), info.Choices)
+	}
+	if info.Choices["10"] != "Option 10" || info.Choices["12"] != "Option 12" {
+		t.Errorf("Expected double-digit choices to keep their labels, got %q", info.Choices)
+	}
+	if info.DefaultChoice != "11" {
+		t.Errorf("Expected default choice 11, got %q", info.DefaultChoice)
+	}
+
+	numbers := info.ChoiceNumbers()
+	if numbers[8] != "9" || numbers[9] != "10" || numbers[11] != "12" {
+		t.Errorf("Expected numeric order, got %q", numbers)
+	}
+}
+
+func TestParseDialog_Truncated(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lines    []string
+		expected bool
+	}{
+		{
+			name: "complete command",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   ls -la                     │",
+				"│ Do you want to proceed?      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: false,
+		},
+		{
+			name: "command ending with an ellipsis",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   find . -name '*.go' -exec… │",
+				"│ Do you want to proceed?      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: true,
+		},
+		{
+			name: "box cut off at the terminal width",
+			lines: []string{
+				"╭───────────────────────",
+				"│ Bash command          ",
+				"│   rm -rf build && make",
+				"│ Do you want to proceed?",
+				"╰───────────────────────",
+			},
+			expected: true,
+		},
+		{
+			name: "ellipsis in a choice",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   ls                         │",
+				"│ Do you want to proceed?      │",
+				"│ ❯ 1. Yes, and don't ask…     │",
+				"╰──────────────────────────────╯",
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if info := ParseDialog(tc.lines); info.Truncated != tc.expected {
+				t.Errorf("Expected Truncated=%v, got %v", tc.expected, info.Truncated)
+			}
+		})
+	}
+}
+
+func TestParseDialog_WrappedLines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lines    []string
+		expected []string
+	}{
+		{
+			name: "hard wrap in the middle of a long path",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   cat /very/long/path/that/w │",
+				"│   raps/file.txt              │",
+				"│ Do you want to proceed?      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: []string{"cat /very/long/path/that/wraps/file.txt"},
+		},
+		{
+			name: "soft wrap at a word boundary",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   npm run build --           │",
+				"│   --production               │",
+				"│ Do you want to proceed?      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: []string{"npm run build -- --production"},
+		},
+		{
+			name: "double-width characters are measured in columns",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   echo 日本語のテキストです │",
+				"│   続き                       │",
+				"│ Do you want to proceed?      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: []string{"echo 日本語のテキストです 続き"},
+		},
+		{
+			name: "separate short lines are not joined",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Bash command                 │",
+				"│   rm test-file               │",
+				"│   Remove test file           │",
+				"│ Do you want to proceed?      │",
+				"╰──────────────────────────────╯",
+			},
+			expected: []string{"rm test-file", "Remove test file"},
+		},
+		{
+			name: "different indentation is not joined",
+			lines: []string{
+				"╭──────────────────────────────╮",
+				"│ Task                         │",
+				"│   description: a long descr  │",
+				"│       nested detail          │",
+				"╰──────────────────────────────╯",
+			},
+			expected: []string{"description: a long descr", "nested detail"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := ParseDialog(tc.lines)
+			if !reflect.DeepEqual(info.CommandLines, tc.expected) {
+				t.Errorf("CommandLines: expected %q, got %q", tc.expected, info.CommandLines)
+			}
+		})
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int
+	}{
+		{"abc", 3},
+		{"日本語", 6},
+		{"한국어", 6},
+		{"a日b", 4},
+		{"", 0},
+	}
+
+	for _, tc := range testCases {
+		if result := DisplayWidth(tc.input); result != tc.expected {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tc.input, result, tc.expected)
+		}
+	}
+}
+
+func TestParseDialogs(t *testing.T) {
+	lines := []string{
+		"⏺ Bash(ls)",
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"│   ls                         │",
+		"│ Do you want to proceed?      │",
+		"│ ❯ 1. Yes                     │",
+		"│   2. No                      │",
+		"╰──────────────────────────────╯",
+		"⏺ Update(main.go)",
+		"╭──────────────────────────────╮",
+		"│ Edit file                    │",
+		"│ ╭──────────────────────────╮ │",
+		"│ │ main.go                  │ │",
+		"│ ╰──────────────────────────╯ │",
+		"│ Do you want to make this edit to main.go? │",
+		"│ ❯ 1. Yes                     │",
+		"╰──────────────────────────────╯",
+		"some trailing output",
+	}
+
+	dialogs := ParseDialogs(lines)
+	if len(dialogs) != 2 {
+		t.Fatalf("Expected 2 dialogs, got %d", len(dialogs))
+	}
+
+	if dialogs[0].ToolType != "Bash" || !reflect.DeepEqual(dialogs[0].CommandLines, []string{"ls"}) {
+		t.Errorf("First dialog: unexpected %+v", dialogs[0])
+	}
+	if len(dialogs[0].Choices) != 2 {
+		t.Errorf("First dialog: expected 2 choices, got %q", dialogs[0].Choices)
+	}
+
+	if dialogs[1].ToolType != "Edit" {
+		t.Errorf("Second dialog: expected Edit tool type, got %q", dialogs[1].ToolType)
+	}
+	if len(dialogs[1].RawContent) != 8 {
+		t.Errorf("Second dialog: expected nested box to stay inside, got %d lines", len(dialogs[1].RawContent))
+	}
+}
+
+func TestParseDialogs_NoDialogs(t *testing.T) {
+	if dialogs := ParseDialogs([]string{"plain output", "no boxes here"}); len(dialogs) != 0 {
+		t.Errorf("Expected no dialogs, got %d", len(dialogs))
+	}
+}
+
+var taskNestedDialogLines = []string{
+	"⏺ Task(Clean up test files)",
+	"╭──────────────────────────────────────────────╮",
+	"│ Task output                                  │",
+	"│   Looking for leftover files                 │",
+	"│ ╭──────────────────────────────────────────╮ │",
+	"│ │ Bash command                             │ │",
+	"│ │                                          │ │",
+	"│ │   rm test-file                           │ │",
+	"│ │                                          │ │",
+	"│ │ Do you want to proceed?                  │ │",
+	"│ │ ❯ 1. Yes                                 │ │",
+	"│ │   2. No                                  │ │",
+	"│ ╰──────────────────────────────────────────╯ │",
+	"╰──────────────────────────────────────────────╯",
+}
+
+func TestParseDialog_NestedPermissionDialog(t *testing.T) {
+	testCases := []struct {
+		name  string
+		lines []string
+	}{
+		{"complete output", taskNestedDialogLines},
+		{"outer box still open", taskNestedDialogLines[:13]},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := ParseDialog(tc.lines)
+
+			if info.Header != "Bash command" || info.ToolType != "Bash" {
+				t.Errorf("Expected the inner Bash dialog, got header %q", info.Header)
+			}
+			if !reflect.DeepEqual(info.CommandLines, []string{"rm test-file"}) {
+				t.Errorf("CommandLines: expected %q, got %q", []string{"rm test-file"}, info.CommandLines)
+			}
+			if !reflect.DeepEqual(info.Choices, map[string]string{"1": "Yes", "2": "No"}) {
+				t.Errorf("Choices: unexpected %q", info.Choices)
+			}
+			if len(info.RawContent) != 9 || info.RawContent[0] != "╭──────────────────────────────────────────╮" {
+				t.Errorf("RawContent: expected the cropped inner box, got %q", info.RawContent)
+			}
+		})
+	}
+}
+
+func TestStripAnsi(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"\x1b[31mRed\x1b[0m", "Red"},
+		{"\x1b]0;title\x07text", "text"},
+		{"\x1b]0;title\x1b\\text", "text"},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"\x1bPpayload\x1b\\text", "text"},
+		{"\x1b]unterminated text", "\x1b]unterminated text"},
+	}
+
+	for _, tc := range testCases {
+		if result := StripAnsi(tc.input); result != tc.expected {
+			t.Errorf("StripAnsi(%q) = %q, want %q", tc.input, result, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// requestChoices are offered by a request described with ParseRequest, like
+// a typical permission dialog
+var requestChoices = map[string]string{
+	"1": "Yes",
+	"2": "No, and tell Claude what to do differently (esc)",
+}
+
+// ParseRequest describes a request written as "Tool: argument", such as
+// "Bash: rm -rf build" or "Edit: /repo/main.go", as if Claude had asked about
+// it in a dialog. The argument is the target file for tools that read or edit
+// files and for absolute paths, and the command otherwise. The request is
+// rated for risk like a dialog would be.
+func ParseRequest(request string) (DialogInfo, error) {
+	tool, argument, _ := strings.Cut(request, ":")
+	tool, argument = strings.TrimSpace(tool), strings.TrimSpace(argument)
+	if tool == "" || strings.ContainsAny(tool, " \t") {
+		return DialogInfo{}, errors.New(`request must look like "Tool: command or file", e.g. "Bash: rm -rf build"`)
+	}
+
+	info := DialogInfo{
+		ToolType:      tool,
+		Choices:       make(map[string]string, len(requestChoices)),
+		DefaultChoice: "1",
+	}
+	for num, label := range requestChoices {
+		info.Choices[num] = label
+	}
+	if argument != "" {
+		if fileEditTools[tool] || tool == ToolRead || (tool != ToolBash && barePath.MatchString(argument)) {
+			info.addFilePath(argument)
+		} else {
+			info.CommandLines = []string{argument}
+		}
+	}
+
+	info.rateRisk(info.CommandLines)
+	return info, nil
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	testCases := []struct {
+		name         string
+		request      string
+		tool         string
+		commandLines []string
+		filePaths    []string
+		risk         RiskLevel
+	}{
+		{"command", "Bash: rm -rf build", ToolBash, []string{"rm -rf build"}, nil, RiskHigh},
+		{"command with colon", "Bash: echo a:b", ToolBash, []string{"echo a:b"}, nil, RiskLow},
+		{"file", "Edit: /repo/main.go", ToolEdit, nil, []string{"/repo/main.go"}, RiskMedium},
+		{"relative file", "Write: src/new.go", ToolWrite, nil, []string{"src/new.go"}, RiskMedium},
+		{"credentials", "Read: ~/.ssh/id_rsa", ToolRead, nil, []string{"~/.ssh/id_rsa"}, RiskHigh},
+		{"MCP tool", "mcp__github__create_issue: title: x", "mcp__github__create_issue", []string{"title: x"}, nil, RiskMedium},
+		{"tool only", "WebSearch", ToolWebSearch, nil, nil, RiskLow},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := ParseRequest(tc.request)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if info.ToolType != tc.tool {
+				t.Errorf("Expected tool %q, got %q", tc.tool, info.ToolType)
+			}
+			if !reflect.DeepEqual(info.CommandLines, tc.commandLines) {
+				t.Errorf("Expected command lines %q, got %q", tc.commandLines, info.CommandLines)
+			}
+			if !reflect.DeepEqual(info.FilePaths, tc.filePaths) {
+				t.Errorf("Expected file paths %q, got %q", tc.filePaths, info.FilePaths)
+			}
+			if info.Risk != tc.risk {
+				t.Errorf("Expected %s risk, got %s", tc.risk, info.Risk)
+			}
+			if info.FirstChoice(ChoiceApproveOnce) != "1" {
+				t.Errorf("Expected choice 1 to approve, got choices %v", info.Choices)
+			}
+		})
+	}
+
+	for _, request := range []string{"", ": rm -rf /", "run rm: -rf /"} {
+		if _, err := ParseRequest(request); err == nil {
+			t.Errorf("Expected an error for %q", request)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RiskLevel rates how dangerous the action requested by a dialog looks
+type RiskLevel int
+
+const (
+	RiskLow RiskLevel = iota
+	RiskMedium
+	RiskHigh
+)
+
+// String returns the lowercase name of the risk level
+func (r RiskLevel) String() string {
+	switch r {
+	case RiskMedium:
+		return "medium"
+	case RiskHigh:
+		return "high"
+	}
+	return "low"
+}
+
+// riskRule flags dialog content matching pattern with a risk level
+type riskRule struct {
+	level   RiskLevel
+	reason  string
+	pattern *regexp.Regexp
+}
+
+// riskRules are checked in order; the highest matching level wins
+var riskRules = []riskRule{
+	// Claude's own warnings inside the dialog box
+	{RiskHigh, "Claude warning", regexp.MustCompile(`(?im)^(⚠|warning:)`)},
+
+	{RiskHigh, "recursive delete", regexp.MustCompile(`\brm\s+(-[a-zA-Z]*[rR][a-zA-Z]*|--recursive)\b`)},
+	{RiskHigh, "runs as root", regexp.MustCompile(`(?m)(^|[;&|]\s*)sudo\s`)},
+	{RiskHigh, "pipes a download into a shell", regexp.MustCompile(`\b(curl|wget)\b.*\|\s*(ba|z)?sh\b`)},
+	{RiskHigh, "force push", regexp.MustCompile(`\bgit\s+push\b.*(\s--force\b|\s-f\b)`)},
+	{RiskHigh, "discards git history", regexp.MustCompile(`\bgit\s+(reset\s+--hard|clean\s+-[a-zA-Z]*f)`)},
+	{RiskHigh, "world-writable permissions", regexp.MustCompile(`\bchmod\s+(-R\s+)?0?777\b`)},
+	{RiskHigh, "writes to a disk device", regexp.MustCompile(`\b(mkfs(\.\w+)?|dd\s+.*of=/dev/)|>\s*/dev/sd`)},
+	{RiskHigh, "drops database objects", regexp.MustCompile(`(?i)\b(drop\s+(table|database)|truncate\s+table)\b`)},
+
+	{RiskMedium, "deletes files", regexp.MustCompile(`\brm\s`)},
+	{RiskMedium, "publishes or pushes changes", regexp.MustCompile(`\b(git\s+push|npm\s+publish|docker\s+push)\b`)},
+	{RiskMedium, "network access", regexp.MustCompile(`\b(curl|wget|ssh|scp)\s`)},
+}
+
+// pathRiskRules rate the target paths of a dialog
+var pathRiskRules = []riskRule{
+	{RiskHigh, "system path", regexp.MustCompile(`^/(etc|usr|bin|sbin|boot|System|Library)/`)},
+	{RiskHigh, "credentials", regexp.MustCompile(`(^|/)\.(ssh|aws|gnupg)(/|$)|(^|/)\.env(\.[\w.-]+)?$`)},
+	{RiskMedium, "git internals", regexp.MustCompile(`(^|/)\.git/`)},
+}
+
+// toolRisks rate tools that change something whatever they are given.
+// Read-only tools are low risk unless their target paths say otherwise.
+var toolRisks = map[string]riskRule{
+	ToolWrite:        {level: RiskMedium, reason: "modifies files"},
+	ToolEdit:         {level: RiskMedium, reason: "modifies files"},
+	ToolMultiEdit:    {level: RiskMedium, reason: "modifies files"},
+	ToolNotebookEdit: {level: RiskMedium, reason: "modifies files"},
+	ToolWebFetch:     {level: RiskMedium, reason: "network access"},
+}
+
+// rateRisk rates the dialog from the command lines and warnings in lines, its
+// tool, and its target paths, keeping the highest level found
+func (d *DialogInfo) rateRisk(lines []string) {
+	d.Risk, d.RiskReason = detectRisk(lines)
+	if rule, ok := toolRisks[d.ToolType]; ok {
+		d.raiseRisk(rule.level, rule.reason)
+	}
+	if strings.HasPrefix(d.ToolType, "mcp__") {
+		d.raiseRisk(RiskMedium, "MCP tool")
+	}
+	for _, path := range d.FilePaths {
+		for _, rule := range pathRiskRules {
+			if rule.pattern.MatchString(path) {
+				d.raiseRisk(rule.level, rule.reason)
+			}
+		}
+	}
+}
+
+// raiseRisk sets the risk level and reason if level is higher than the current one
+func (d *DialogInfo) raiseRisk(level RiskLevel, reason string) {
+	if level > d.Risk {
+		d.Risk, d.RiskReason = level, reason
+	}
+}
+
+// detectRisk rates the command lines and warnings of a dialog, returning the
+// highest matching level and the reason for it
+func detectRisk(lines []string) (RiskLevel, string) {
+	level, reason := RiskLow, ""
+	content := strings.Join(lines, "\n")
+	for _, rule := range riskRules {
+		if rule.level > level && rule.pattern.MatchString(content) {
+			level, reason = rule.level, rule.reason
+		}
+	}
+	return level, reason
+}
@@ -0,0 +1,135 @@
+package parser
+
+import "testing"
+
+func TestParseDialog_Risk(t *testing.T) {
+	testCases := []struct {
+		name           string
+		command        string
+		expectedLevel  RiskLevel
+		expectedReason string
+	}{
+		{"harmless command", "ls -la", RiskLow, ""},
+		{"plain delete", "rm test-file", RiskMedium, "deletes files"},
+		{"recursive delete", "rm -rf build", RiskHigh, "recursive delete"},
+		{"recursive delete with split flags", "rm -r -f build", RiskHigh, "recursive delete"},
+		{"sudo", "sudo apt install foo", RiskHigh, "runs as root"},
+		{"sudo after another command", "cd /tmp && sudo make install", RiskHigh, "runs as root"},
+		{"curl piped to shell", "curl -fsSL https://example.com/install.sh | bash", RiskHigh, "pipes a download into a shell"},
+		{"plain curl", "curl https://example.com", RiskMedium, "network access"},
+		{"force push", "git push --force origin main", RiskHigh, "force push"},
+		{"plain push", "git push origin main", RiskMedium, "publishes or pushes changes"},
+		{"hard reset", "git reset --hard HEAD~3", RiskHigh, "discards git history"},
+		{"chmod 777", "chmod -R 777 /var/www", RiskHigh, "world-writable permissions"},
+		{"dd to device", "dd if=image.iso of=/dev/sdb", RiskHigh, "writes to a disk device"},
+		{"drop table", "psql -c 'DROP TABLE users'", RiskHigh, "drops database objects"},
+		{"word containing rm", "npm run format", RiskLow, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := ParseDialog([]string{
+				"╭──────────────────────────────────────────────────────────────╮",
+				"│ Bash command                                                 │",
+				"│   " + tc.command,
+				"│ Do you want to proceed?                                      │",
+				"│ ❯ 1. Yes                                                     │",
+				"│   2. Yes, and don't ask again for rm commands                │",
+				"╰──────────────────────────────────────────────────────────────╯",
+			})
+			if info.Risk != tc.expectedLevel || info.RiskReason != tc.expectedReason {
+				t.Errorf("Expected %s (%q), got %s (%q)", tc.expectedLevel, tc.expectedReason, info.Risk, info.RiskReason)
+			}
+		})
+	}
+}
+
+func TestParseDialog_RiskFromToolAndPaths(t *testing.T) {
+	testCases := []struct {
+		name           string
+		header         string
+		target         string // The question, or a path shown on its own line
+		expectedLevel  RiskLevel
+		expectedReason string
+	}{
+		{"read", "Read file", "/repo/README.md", RiskLow, ""},
+		{"edit", "Edit file", "Do you want to make this edit to main.go?", RiskMedium, "modifies files"},
+		{"write", "Create file", "Do you want to create notes.md?", RiskMedium, "modifies files"},
+		{"fetch", "Fetch", "Do you want to allow Claude to fetch this content?", RiskMedium, "network access"},
+		{"system path", "Edit file", "Do you want to make this edit to /etc/hosts?", RiskHigh, "system path"},
+		{"ssh key", "Read file", "~/.ssh/id_rsa", RiskHigh, "credentials"},
+		{"env file", "Create file", "Do you want to create app/.env.local?", RiskHigh, "credentials"},
+		{"git internals", "Read file", "/repo/.git/config", RiskMedium, "git internals"},
+		{"env in a file name", "Edit file", "Do you want to make this edit to docs/env.md?", RiskMedium, "modifies files"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := ParseDialog([]string{
+				"╭──────────────────────────────────────────────────────────────╮",
+				"│ " + tc.header,
+				"│ " + tc.target,
+				"│ Do you want to proceed?                                      │",
+				"│ ❯ 1. Yes                                                     │",
+				"│   2. No                                                      │",
+				"╰──────────────────────────────────────────────────────────────╯",
+			})
+			if info.Risk != tc.expectedLevel || info.RiskReason != tc.expectedReason {
+				t.Errorf("Expected %s (%q), got %s (%q) for paths %q", tc.expectedLevel, tc.expectedReason, info.Risk, info.RiskReason, info.FilePaths)
+			}
+		})
+	}
+}
+
+func TestParseDialog_MCPToolRisk(t *testing.T) {
+	info := ParseDialog([]string{
+		"╭──────────────────────────────────────────────────────────────╮",
+		"│ Tool use                                                     │",
+		"│   github - create_issue(title: \"x\") (MCP)                    │",
+		"│ Do you want to proceed?                                      │",
+		"╰──────────────────────────────────────────────────────────────╯",
+	})
+	if info.Risk != RiskMedium || info.RiskReason != "MCP tool" {
+		t.Errorf("Expected medium risk for an MCP tool, got %s (%q)", info.Risk, info.RiskReason)
+	}
+}
+
+func TestParseDialog_ClaudeWarning(t *testing.T) {
+	info := ParseDialog([]string{
+		"╭──────────────────────────────────────────────╮",
+		"│ Bash command                                 │",
+		"│   ./cleanup.sh                               │",
+		"│   ⚠ This command may be destructive          │",
+		"│ Do you want to proceed?                      │",
+		"╰──────────────────────────────────────────────╯",
+	})
+
+	if info.Risk != RiskHigh || info.RiskReason != "Claude warning" {
+		t.Errorf("Expected high risk from Claude's warning, got %s (%q)", info.Risk, info.RiskReason)
+	}
+}
+
+func TestCleanMessage_HighRiskWarning(t *testing.T) {
+	message := CleanMessage([]string{
+		"⏺ Bash(rm -rf build)",
+		"╭──────────────────────────────╮",
+		"│ Bash command                 │",
+		"│   rm -rf build               │",
+		"│ Do you want to proceed?      │",
+		"╰──────────────────────────────╯",
+	}, "", "Bash command execution", "123")
+
+	expected := `Trigger text: ⏺ Bash(rm -rf build)
+Trigger timestamp: 123
+Reason: Bash command execution
+⚠️ Risk: high (recursive delete)
+───────────────────────────────────
+Bash command
+
+  rm -rf build
+
+Do you want to proceed?`
+	if message != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, message)
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+// wideRanges lists code point ranges rendered two columns wide by terminals
+// (East Asian Wide/Fullwidth characters and emoji)
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x2E80, 0x303E},   // CJK Radicals, Kangxi, CJK Symbols and Punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, Bopomofo, CJK Compatibility
+	{0x3400, 0x4DBF},   // CJK Unified Ideographs Extension A
+	{0x4E00, 0x9FFF},   // CJK Unified Ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul Syllables
+	{0xF900, 0xFAFF},   // CJK Compatibility Ideographs
+	{0xFE30, 0xFE4F},   // CJK Compatibility Forms
+	{0xFF00, 0xFF60},   // Fullwidth Forms
+	{0xFFE0, 0xFFE6},   // Fullwidth Signs
+	{0x1F300, 0x1F64F}, // Misc Symbols and Pictographs, Emoticons
+	{0x1F900, 0x1F9FF}, // Supplemental Symbols and Pictographs
+	{0x20000, 0x3FFFD}, // CJK Unified Ideographs Extension B and beyond
+}
+
+// runeWidth returns the number of terminal columns r occupies
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || r == 0x200B:
+		return 0
+	case r < 0x1100:
+		return 1
+	}
+	for _, wideRange := range wideRanges {
+		if r >= wideRange[0] && r <= wideRange[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// DisplayWidth returns the number of terminal columns s occupies
+func DisplayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}